@@ -0,0 +1,146 @@
+package pim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// AdminHandler serves runtime control of a logger over HTTP. Mount it under
+// a prefix with http.StripPrefix, e.g.
+//
+//	mux.Handle("/debug/log/", http.StripPrefix("/debug/log", pim.NewAdminHandler(logger)))
+//
+// Endpoints:
+//
+//	GET  /level       current level as {"level": "info"}
+//	PUT  /level       set the level from {"level": "debug"}
+//	GET  /stats       async buffer usage, dropped entries and hook count
+//	GET  /state       sampler and metrics state (see LoggerState)
+//	GET  /provenance  recent entry traces in provenance mode (see EnableProvenance)
+//	POST /dryrun      run a JSON array of entries through the hooks (see HookManager.DryRun)
+//
+// Further endpoints, such as a /metrics exporter, are added with Handle.
+type AdminHandler struct {
+	logger *LoggerCore
+	mux    *http.ServeMux
+}
+
+// AdminStats is the body of GET /stats
+type AdminStats struct {
+	Level               string `json:"level"`
+	AsyncBufferLength   int    `json:"async_buffer_length"`
+	AsyncBufferCapacity int    `json:"async_buffer_capacity"`
+	AsyncDropped        uint64 `json:"async_dropped"`
+	Writers             int    `json:"writers"`
+	Hooks               int    `json:"hooks"`
+}
+
+// adminLevel is the body of the /level endpoints
+type adminLevel struct {
+	Level string `json:"level"`
+}
+
+// NewAdminHandler creates an admin handler for logger
+func NewAdminHandler(logger *LoggerCore) *AdminHandler {
+	h := &AdminHandler{logger: logger, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /level", h.getLevel)
+	h.mux.HandleFunc("PUT /level", h.putLevel)
+	h.mux.HandleFunc("GET /stats", h.getStats)
+	h.mux.HandleFunc("GET /state", h.getState)
+	h.mux.HandleFunc("GET /provenance", h.getProvenance)
+	h.mux.HandleFunc("POST /dryrun", h.postDryRun)
+	return h
+}
+
+// Handle adds an endpoint to the handler
+func (h *AdminHandler) Handle(pattern string, handler http.Handler) {
+	h.mux.Handle(pattern, handler)
+}
+
+// ServeHTTP implements http.Handler
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// getLevel serves GET /level
+func (h *AdminHandler) getLevel(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, http.StatusOK, adminLevel{Level: h.logger.GetLevel().Name()})
+}
+
+// putLevel serves PUT /level
+func (h *AdminHandler) putLevel(w http.ResponseWriter, r *http.Request) {
+	var body adminLevel
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeAdminError(w, http.StatusBadRequest, fmt.Errorf("invalid level request: %w", err))
+		return
+	}
+	level, err := ParseLevel(body.Level)
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, err)
+		return
+	}
+	h.logger.SetLevel(level)
+	writeAdminJSON(w, http.StatusOK, adminLevel{Level: level.Name()})
+}
+
+// getStats serves GET /stats
+func (h *AdminHandler) getStats(w http.ResponseWriter, r *http.Request) {
+	length, capacity := h.logger.AsyncBufferStats()
+
+	h.logger.mu.RLock()
+	writers := len(h.logger.writers)
+	h.logger.mu.RUnlock()
+
+	writeAdminJSON(w, http.StatusOK, AdminStats{
+		Level:               h.logger.GetLevel().Name(),
+		AsyncBufferLength:   length,
+		AsyncBufferCapacity: capacity,
+		AsyncDropped:        h.logger.AsyncDropped(),
+		Writers:             writers,
+		Hooks:               h.logger.GetHookCount(),
+	})
+}
+
+// getState serves GET /state
+func (h *AdminHandler) getState(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, http.StatusOK, h.logger.State())
+}
+
+// writeAdminJSON writes v as a JSON response
+func writeAdminJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write admin response: %v\n", err)
+	}
+}
+
+// writeAdminError writes err as a JSON error response
+func writeAdminError(w http.ResponseWriter, status int, err error) {
+	writeAdminJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// getProvenance serves GET /provenance
+func (h *AdminHandler) getProvenance(w http.ResponseWriter, r *http.Request) {
+	records := h.logger.Provenance()
+	if records == nil {
+		records = []Provenance{}
+	}
+	writeAdminJSON(w, http.StatusOK, records)
+}
+
+// postDryRun serves POST /dryrun
+func (h *AdminHandler) postDryRun(w http.ResponseWriter, r *http.Request) {
+	var entries []CoreLogEntry
+	if err := json.NewDecoder(r.Body).Decode(&entries); err != nil {
+		writeAdminError(w, http.StatusBadRequest, fmt.Errorf("invalid dry run request: %w", err))
+		return
+	}
+	if h.logger.hookManager == nil {
+		writeAdminError(w, http.StatusNotFound, fmt.Errorf("logger has no hook manager"))
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, h.logger.hookManager.DryRun(entries))
+}
@@ -0,0 +1,72 @@
+package pim
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdminHandlerLevel(t *testing.T) {
+	config := DefaultLoggerConfig
+	config.EnableConsole = false
+	logger := NewLoggerCore(config)
+	handler := http.StripPrefix("/debug/log", NewAdminHandler(logger))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("PUT", "/debug/log/level", strings.NewReader(`{"level": "debug"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if logger.GetLevel() != DebugLevel {
+		t.Errorf("Expected debug level, got %v", logger.GetLevel())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/log/level", nil))
+	var body adminLevel
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Level != "debug" {
+		t.Errorf("Expected level debug, got %q (%v)", rec.Body, err)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("PUT", "/debug/log/level", strings.NewReader(`{"level": "loud"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown level, got %d", rec.Code)
+	}
+}
+
+func TestAdminHandlerStatsAndExtraEndpoints(t *testing.T) {
+	config := DefaultLoggerConfig
+	config.EnableConsole = false
+	logger := NewLoggerCore(config)
+	logger.AddWriter(NewBufferWriter(config, 10))
+
+	handler := NewAdminHandler(logger)
+	handler.Handle("GET /metrics", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("up 1\n"))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/stats", nil))
+	var stats AdminStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Failed to decode stats: %v", err)
+	}
+	if stats.Writers != 1 || stats.Level != logger.GetLevel().Name() {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Body.String() != "up 1\n" {
+		t.Errorf("Expected added endpoint, got %q", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("DELETE", "/level", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,32 @@
+package pim
+
+// Check logs a warning with caller information when cond is false and
+// returns cond, standardizing "should never happen" logging for inline use:
+//
+//	if !logger.Check(len(items) > 0, "empty item list", "order_id", id) {
+//		return
+//	}
+func (l *LoggerCore) Check(cond bool, msg string, kv ...interface{}) bool {
+	if !cond {
+		fields := kvToMap(kv...)
+		fields["assertion"] = "check"
+		l.logWithContext(1, WarningLevel, WarningPrefix, msg, fields, nil)
+	}
+	return cond
+}
+
+// Expect logs an error with caller information when err is non-nil and
+// returns err unchanged for inline use:
+//
+//	if err := logger.Expect(db.Ping(), "database unreachable"); err != nil {
+//		return err
+//	}
+func (l *LoggerCore) Expect(err error, msg string, kv ...interface{}) error {
+	if err != nil {
+		fields := kvToMap(kv...)
+		fields["assertion"] = "expect"
+		fields["error"] = err.Error()
+		l.logWithContext(1, ErrorLevel, ErrorPrefix, msg, fields, nil)
+	}
+	return err
+}
@@ -0,0 +1,57 @@
+package pim
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.Level = InfoLevel
+	})
+
+	if !logger.Check(true, "never logged") {
+		t.Error("Expected Check to return true for a passing condition")
+	}
+	if logger.Check(1 > 2, "impossible ordering", "left", 1, "right", 2) {
+		t.Error("Expected Check to return false for a failing condition")
+	}
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	entry := entries[0]
+	if entry.Level != WarningLevel || entry.Message != "impossible ordering" {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	if entry.Context["left"] != 1 || entry.Context["assertion"] != "check" {
+		t.Errorf("Expected fields and assertion marker, got: %v", entry.Context)
+	}
+}
+
+func TestExpect(t *testing.T) {
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.Level = InfoLevel
+	})
+
+	if err := logger.Expect(nil, "never logged"); err != nil {
+		t.Errorf("Expected nil error to pass through, got %v", err)
+	}
+
+	cause := errors.New("connection refused")
+	if err := logger.Expect(cause, "database unreachable", "host", "db1"); err != cause {
+		t.Errorf("Expected original error to be returned, got %v", err)
+	}
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	if entries[0].Level != ErrorLevel || entries[0].Context["error"] != "connection refused" {
+		t.Errorf("Unexpected entry: %+v", entries[0])
+	}
+	if entries[0].Context["host"] != "db1" {
+		t.Errorf("Expected fields to be attached, got: %v", entries[0].Context)
+	}
+}
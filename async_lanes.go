@@ -0,0 +1,117 @@
+package pim
+
+// priorityBufferSize returns the capacity of the Error and Panic lane
+func (c LoggerConfig) priorityBufferSize() int {
+	if c.PriorityBufferSize > 0 {
+		return c.PriorityBufferSize
+	}
+	if size := c.BufferSize / 4; size > 0 {
+		return size
+	}
+	return 1
+}
+
+// isPriorityEntry reports whether an entry belongs in the priority lane
+func isPriorityEntry(entry CoreLogEntry) bool {
+	return entry.Level <= ErrorLevel
+}
+
+// enqueueAsync queues an entry for the async worker. Without priority lanes
+// a full buffer falls back to a synchronous write. With priority lanes,
+// Error and Panic entries fall back to a synchronous write when their lane
+// is full, and other entries are dropped so bursts of verbose logging
+// cannot delay critical ones. With a persistent queue the entry is
+// written to disk first.
+func (l *LoggerCore) enqueueAsync(entry CoreLogEntry) {
+	owner := l.asyncOwner
+	if owner == nil || owner.asyncCtx.Err() != nil {
+		// No worker, or it was stopped by Flush
+		l.writeToWriters(entry)
+		return
+	}
+	if owner != l {
+		// Child loggers queue on the buffer of the logger they were derived
+		// from, which writes the entry with the child's writers
+		entry.origin = l
+	}
+	var full bool
+	if owner.persistQueue != nil {
+		entry, full = owner.persistQueue.append(entry, owner.pushAsync)
+	} else {
+		_, full = owner.pushAsync(entry)
+	}
+	if full {
+		// Lane full, fall back to a synchronous write
+		owner.writeQueued(entry)
+		owner.persisted(entry)
+	}
+}
+
+// writeQueued writes an entry taken from the async buffer with the writers
+// of the logger that queued it
+func (l *LoggerCore) writeQueued(entry CoreLogEntry) {
+	if origin := entry.origin; origin != nil {
+		entry.origin = nil
+		origin.writeToWriters(entry)
+		return
+	}
+	l.writeToWriters(entry)
+}
+
+// pushAsync queues an entry in its lane, see enqueueAsync. It reports
+// whether the entry was queued and, if not, whether the caller must write
+// it synchronously because its lane is full; otherwise it was dropped.
+func (l *LoggerCore) pushAsync(entry CoreLogEntry) (queued, full bool) {
+	if l.asyncPriority != nil && isPriorityEntry(entry) {
+		if l.asyncPriority.TryPush(entry) {
+			l.asyncWorker.notify()
+			return true, false
+		}
+		return false, true
+	}
+
+	switch {
+	case l.asyncBuffer.TryPush(entry):
+		l.asyncWorker.notify()
+		return true, false
+	case l.asyncPriority != nil:
+		l.asyncDropped.Add(1)
+		l.samplingReport.dropped(entry.Level, entry.Message, DropReasonAsyncFull)
+		entry.provenance.add(ProvenanceStep{Stage: StageAsync, Outcome: OutcomeDropped})
+		return false, false
+	default:
+		// Buffer full
+		return false, true
+	}
+}
+
+// persisted reports to the persistent queue that an entry queued by the
+// async worker was written
+func (l *LoggerCore) persisted(entry CoreLogEntry) {
+	if l.persistQueue != nil && entry.persistSeq != 0 {
+		l.persistQueue.written(entry.persistSeq)
+	}
+}
+
+// drainPriority writes every queued Error and Panic entry and reports
+// whether there were any
+func (w *asyncWorker) drainPriority() bool {
+	if w.logger.asyncPriority == nil {
+		return false
+	}
+	wrote := false
+	for {
+		entry, ok := w.logger.asyncPriority.TryPop()
+		if !ok {
+			return wrote
+		}
+		w.processEntry(entry)
+		wrote = true
+	}
+}
+
+// AsyncDropped returns the number of best-effort entries dropped because the
+// async buffer was full; only loggers with PriorityLanes drop entries
+func (l *LoggerCore) AsyncDropped() uint64 {
+	return l.asyncDropped.Load()
+}
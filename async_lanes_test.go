@@ -0,0 +1,135 @@
+package pim
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedWriter blocks its first Write until the gate is opened and records
+// the messages it writes
+type gatedWriter struct {
+	gate    chan struct{}
+	started chan struct{}
+	once    sync.Once
+	mu      sync.Mutex
+	written []string
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{gate: make(chan struct{}), started: make(chan struct{})}
+}
+
+func (w *gatedWriter) Write(entry CoreLogEntry) error {
+	w.once.Do(func() {
+		close(w.started)
+		<-w.gate
+	})
+	w.mu.Lock()
+	w.written = append(w.written, entry.Message)
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *gatedWriter) Close() error { return nil }
+func (w *gatedWriter) Flush() error { return nil }
+
+func (w *gatedWriter) messages() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.written...)
+}
+
+// withPriorityLanes configures a small async buffer with priority lanes
+func withPriorityLanes(config *LoggerConfig) {
+	config.Level = DebugLevel
+	config.Async = true
+	config.BufferSize = 4
+	config.PriorityLanes = true
+	config.PriorityBufferSize = 2
+	config.FlushInterval = time.Hour
+}
+
+func TestPriorityLanesDropBestEffortOnly(t *testing.T) {
+	writer := newGatedWriter()
+	logger, _ := newTestLogger(t, withPriorityLanes)
+	logger.AddWriter(writer)
+
+	logger.Info("first")
+	<-writer.started // The worker is now blocked inside Write
+
+	for i := 0; i < 10; i++ {
+		logger.Debug("verbose")
+	}
+	logger.Error("e1")
+	logger.Error("e2")
+
+	if dropped := logger.AsyncDropped(); dropped != 6 {
+		t.Errorf("Expected 6 dropped best-effort entries, got %d", dropped)
+	}
+	if length, capacity := logger.AsyncBufferStats(); length != 6 || capacity != 6 {
+		t.Errorf("Expected 6/6 queued entries, got %d/%d", length, capacity)
+	}
+
+	close(writer.gate)
+	logger.Flush()
+
+	messages := writer.messages()
+	if len(messages) != 7 {
+		t.Fatalf("Expected 7 written entries, got %d: %v", len(messages), messages)
+	}
+	// Errors are written before the queued verbose entries
+	if messages[1] != "e1" || messages[2] != "e2" {
+		t.Errorf("Expected errors right after the first entry, got %v", messages)
+	}
+}
+
+func TestPriorityLaneFullWritesSynchronously(t *testing.T) {
+	writer := newGatedWriter()
+	logger, _ := newTestLogger(t, withPriorityLanes)
+	logger.AddWriter(writer)
+
+	logger.Info("first")
+	<-writer.started
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		logger.Error("e1")
+		logger.Error("e2")
+		logger.Error("e3") // Lane is full: written synchronously, never dropped
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	close(writer.gate)
+	<-done
+	logger.Flush()
+
+	if dropped := logger.AsyncDropped(); dropped != 0 {
+		t.Errorf("Expected no dropped entries, got %d", dropped)
+	}
+	if messages := writer.messages(); len(messages) != 4 {
+		t.Errorf("Expected all 4 entries written, got %v", messages)
+	}
+}
+
+func TestAsyncWithoutLanesNeverDrops(t *testing.T) {
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.Async = true
+		config.BufferSize = 1
+		config.FlushInterval = time.Hour
+	})
+
+	for i := 0; i < 20; i++ {
+		logger.Info("entry")
+	}
+	logger.Flush()
+	logger.Info("after flush")
+
+	if logger.AsyncDropped() != 0 {
+		t.Errorf("Expected no dropped entries, got %d", logger.AsyncDropped())
+	}
+	if got := buffer.GetBufferSize(); got != 21 {
+		t.Errorf("Expected 21 entries, got %d", got)
+	}
+}
@@ -0,0 +1,105 @@
+package pim
+
+import "sync/atomic"
+
+// asyncBatchSize is the number of entries the async worker dequeues at once
+const asyncBatchSize = 64
+
+// entryRing is a bounded lock-free multi-producer single-consumer queue of
+// log entries (Vyukov's array queue). Producers claim a slot with one CAS on
+// the tail; the single consumer reads slots in order without atomics on the
+// tail. The capacity is rounded up to a power of two.
+type entryRing struct {
+	slots []ringSlot
+	mask  uint64
+
+	_    [64]byte // Keep producer and consumer counters on separate cache lines
+	tail atomic.Uint64
+	_    [56]byte
+	head atomic.Uint64 // Written by the consumer only; atomic for Len
+}
+
+// ringSlot holds one entry. seq equals the slot's position when it is free
+// for that position and position+1 once the entry is published.
+type ringSlot struct {
+	seq   atomic.Uint64
+	entry CoreLogEntry
+}
+
+// newEntryRing creates a ring holding at least size entries. The minimum
+// capacity is two: with one slot a freed sequence would read as published.
+func newEntryRing(size int) *entryRing {
+	capacity := 2
+	for capacity < size {
+		capacity <<= 1
+	}
+	r := &entryRing{
+		slots: make([]ringSlot, capacity),
+		mask:  uint64(capacity - 1),
+	}
+	for i := range r.slots {
+		r.slots[i].seq.Store(uint64(i))
+	}
+	return r
+}
+
+// TryPush adds an entry and reports false if the ring is full. It is safe
+// for concurrent use.
+func (r *entryRing) TryPush(entry CoreLogEntry) bool {
+	pos := r.tail.Load()
+	for {
+		slot := &r.slots[pos&r.mask]
+		switch diff := int64(slot.seq.Load() - pos); {
+		case diff == 0:
+			if r.tail.CompareAndSwap(pos, pos+1) {
+				slot.entry = entry
+				slot.seq.Store(pos + 1)
+				return true
+			}
+			pos = r.tail.Load()
+		case diff < 0:
+			// The slot still holds the entry from one lap ago
+			return false
+		default:
+			// Another producer claimed this position first
+			pos = r.tail.Load()
+		}
+	}
+}
+
+// TryPop removes the oldest entry. It must only be called by the consumer.
+func (r *entryRing) TryPop() (CoreLogEntry, bool) {
+	head := r.head.Load()
+	slot := &r.slots[head&r.mask]
+	if slot.seq.Load() != head+1 {
+		return CoreLogEntry{}, false
+	}
+	entry := slot.entry
+	slot.entry = CoreLogEntry{} // Release references held by the entry
+	slot.seq.Store(head + r.mask + 1)
+	r.head.Store(head + 1)
+	return entry, true
+}
+
+// PopBatch appends up to cap(batch)-len(batch) entries to batch. It must
+// only be called by the consumer.
+func (r *entryRing) PopBatch(batch []CoreLogEntry) []CoreLogEntry {
+	for len(batch) < cap(batch) {
+		entry, ok := r.TryPop()
+		if !ok {
+			break
+		}
+		batch = append(batch, entry)
+	}
+	return batch
+}
+
+// Len returns the number of queued entries, including ones being published
+func (r *entryRing) Len() int {
+	return int(r.tail.Load() - r.head.Load())
+}
+
+// Cap returns the capacity of the ring
+func (r *entryRing) Cap() int {
+	return len(r.slots)
+}
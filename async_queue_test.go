@@ -0,0 +1,175 @@
+package pim
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEntryRingOrderAndCapacity(t *testing.T) {
+	ring := newEntryRing(3)
+	if ring.Cap() != 4 {
+		t.Fatalf("Expected capacity rounded up to 4, got %d", ring.Cap())
+	}
+
+	for lap := 0; lap < 3; lap++ {
+		for i := 0; i < 4; i++ {
+			if !ring.TryPush(CoreLogEntry{Message: fmt.Sprint(i)}) {
+				t.Fatalf("lap %d: push %d failed", lap, i)
+			}
+		}
+		if ring.TryPush(CoreLogEntry{}) {
+			t.Fatalf("lap %d: expected push to a full ring to fail", lap)
+		}
+		if ring.Len() != 4 {
+			t.Errorf("lap %d: expected length 4, got %d", lap, ring.Len())
+		}
+
+		batch := ring.PopBatch(make([]CoreLogEntry, 0, 3))
+		entry, _ := ring.TryPop()
+		batch = append(batch, entry)
+		for i, entry := range batch {
+			if entry.Message != fmt.Sprint(i) {
+				t.Errorf("lap %d: expected entry %d, got %q", lap, i, entry.Message)
+			}
+		}
+		if _, ok := ring.TryPop(); ok {
+			t.Errorf("lap %d: expected empty ring", lap)
+		}
+	}
+}
+
+func TestEntryRingMinimumCapacity(t *testing.T) {
+	ring := newEntryRing(1)
+	ring.TryPush(CoreLogEntry{Message: "a"})
+	ring.TryPop()
+	if _, ok := ring.TryPop(); ok {
+		t.Error("Expected a popped slot not to read as published")
+	}
+}
+
+func TestEntryRingConcurrentProducers(t *testing.T) {
+	const producers, perProducer = 8, 2000
+	ring := newEntryRing(64)
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < perProducer; i++ {
+				for !ring.TryPush(CoreLogEntry{Line: p, PID: i}) {
+					time.Sleep(time.Microsecond)
+				}
+			}
+		}(p)
+	}
+
+	// Entries of each producer must arrive in order and exactly once
+	next := make([]int, producers)
+	batch := make([]CoreLogEntry, 0, asyncBatchSize)
+	for received := 0; received < producers*perProducer; {
+		batch = ring.PopBatch(batch[:0])
+		for _, entry := range batch {
+			if entry.PID != next[entry.Line] {
+				t.Fatalf("producer %d: expected %d, got %d", entry.Line, next[entry.Line], entry.PID)
+			}
+			next[entry.Line]++
+		}
+		received += len(batch)
+	}
+	wg.Wait()
+}
+
+func TestAsyncLoggerWritesAllEntries(t *testing.T) {
+	buffer := NewBufferWriter(DefaultLoggerConfig, 10000)
+	config := DefaultLoggerConfig
+	config.EnableConsole = false
+	config.Async = true
+	config.BufferSize = 16
+	config.FlushInterval = time.Hour
+	logger := NewLoggerCore(config)
+	logger.AddWriter(buffer)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				logger.Info("entry")
+			}
+		}()
+	}
+	wg.Wait()
+	logger.Flush()
+
+	if got := buffer.GetBufferSize(); got != 2000 {
+		t.Errorf("Expected 2000 entries, got %d", got)
+	}
+}
+
+// benchmarkQueue measures parallel producers against one draining consumer
+func benchmarkQueue(b *testing.B, push func(CoreLogEntry) bool, drain func() int) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if drain() == 0 {
+				runtime.Gosched()
+			}
+		}
+	}()
+
+	entry := CoreLogEntry{Message: "benchmark", Level: InfoLevel}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			for !push(entry) {
+				runtime.Gosched()
+			}
+		}
+	})
+	close(done)
+	<-stopped
+}
+
+func BenchmarkAsyncQueue(b *testing.B) {
+	b.Run("ring", func(b *testing.B) {
+		ring := newEntryRing(1024)
+		batch := make([]CoreLogEntry, 0, asyncBatchSize)
+		benchmarkQueue(b, ring.TryPush, func() int {
+			batch = ring.PopBatch(batch[:0])
+			return len(batch)
+		})
+	})
+	b.Run("channel", func(b *testing.B) {
+		ch := make(chan CoreLogEntry, 1024)
+		benchmarkQueue(b, func(entry CoreLogEntry) bool {
+			select {
+			case ch <- entry:
+				return true
+			default:
+				return false
+			}
+		}, func() int {
+			n := 0
+			for ; n < asyncBatchSize; n++ {
+				select {
+				case <-ch:
+				default:
+					return n
+				}
+			}
+			return n
+		})
+	})
+}
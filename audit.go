@@ -0,0 +1,235 @@
+package pim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// AuditSchemaVersion is the version of the audit event schema
+const AuditSchemaVersion = 1
+
+// AuditKey is the context key holding the audit event of an entry
+const AuditKey = "audit"
+
+// AuditPrefix is the prefix of audit entries
+const AuditPrefix = "AUDIT"
+
+// AuditOutcome is the result of an audited action
+type AuditOutcome string
+
+const (
+	AuditSuccess AuditOutcome = "success"
+	AuditFailure AuditOutcome = "failure"
+	AuditDenied  AuditOutcome = "denied"
+)
+
+// ErrInvalidAuditEvent is returned by Record for events missing mandatory fields
+var ErrInvalidAuditEvent = errors.New("invalid audit event")
+
+// AuditEvent is one audited action. Actor, Action, Resource and Outcome
+// are mandatory, and Reason is mandatory unless the outcome is success.
+// Before and After hold the state of the resource around the action and
+// must be JSON-serializable.
+type AuditEvent struct {
+	Actor    string       `json:"actor"`
+	Action   string       `json:"action"`
+	Resource string       `json:"resource"`
+	Outcome  AuditOutcome `json:"outcome"`
+	Reason   string       `json:"reason,omitempty"`
+	Before   interface{}  `json:"before,omitempty"`
+	After    interface{}  `json:"after,omitempty"`
+}
+
+// Validate checks the mandatory fields
+func (e AuditEvent) Validate() error {
+	var missing []string
+	if e.Actor == "" {
+		missing = append(missing, "actor")
+	}
+	if e.Action == "" {
+		missing = append(missing, "action")
+	}
+	if e.Resource == "" {
+		missing = append(missing, "resource")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: missing %v", ErrInvalidAuditEvent, missing)
+	}
+	switch e.Outcome {
+	case AuditSuccess:
+	case AuditFailure, AuditDenied:
+		if e.Reason == "" {
+			return fmt.Errorf("%w: %s outcome requires a reason", ErrInvalidAuditEvent, e.Outcome)
+		}
+	default:
+		return fmt.Errorf("%w: unknown outcome %q", ErrInvalidAuditEvent, e.Outcome)
+	}
+	return nil
+}
+
+// AuditConfig configures an AuditLogger
+type AuditConfig struct {
+	// LoggerConfig supplies the service name and metadata of audit entries;
+	// its level, sampling and async settings are ignored
+	LoggerConfig LoggerConfig `json:"logger"`
+
+	// Path of the audit file, written by a WORM file writer (see
+	// NewWORMFileWriter); empty to only use writers added with AddWriter
+	Path     string         `json:"path"`
+	Rotation RotationConfig `json:"rotation"`
+
+	// SigningKey signs the audit file with chained HMACs so VerifyLogFile
+	// detects modification (see NewSignedFileWriter)
+	SigningKey []byte `json:"-"`
+}
+
+// AuditLogger records audit events on a stream separate from application
+// logging: it has its own LoggerCore without hooks, sampling or level
+// filtering, so audit events cannot be dropped by accident, and Record
+// writes synchronously and reports write failures.
+type AuditLogger struct {
+	core    *LoggerCore
+	mu      sync.Mutex
+	writers []LogWriter
+}
+
+// NewAuditLogger creates an audit logger, opening the audit file if
+// config.Path is set
+func NewAuditLogger(config AuditConfig) (*AuditLogger, error) {
+	coreConfig := config.LoggerConfig
+	coreConfig.EnableConsole = false
+	coreConfig.EnableSampling = false
+	coreConfig.Async = false
+	coreConfig.Level = TraceLevel
+	coreConfig.EnableJSON = true
+	coreConfig.PropagateContext = true
+	coreConfig.LogStartupFingerprint = false
+	coreConfig.StateFile = ""
+
+	audit := &AuditLogger{core: NewLoggerCore(coreConfig)}
+	audit.core.unregisterShutdown() // Closed by the AuditLogger owner
+
+	if config.Path != "" {
+		var writer *FileWriter
+		var err error
+		if len(config.SigningKey) > 0 {
+			writer, err = NewSignedFileWriter(config.Path, coreConfig, wormRotation(config.Rotation), SigningConfig{Key: config.SigningKey})
+			if writer != nil {
+				writer.readOnlyRotated = true
+			}
+		} else {
+			writer, err = NewWORMFileWriter(config.Path, coreConfig, config.Rotation)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit file: %w", err)
+		}
+		audit.writers = append(audit.writers, writer)
+	}
+	return audit, nil
+}
+
+// AddWriter adds a destination for audit events
+func (a *AuditLogger) AddWriter(writer LogWriter) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.writers = append(a.writers, writer)
+}
+
+// WithContext returns an audit logger that adds fields, e.g. a request ID,
+// to every event; it shares the writers of a
+func (a *AuditLogger) WithContext(fields map[string]interface{}) *AuditLogger {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return &AuditLogger{core: a.core.WithContext(fields), writers: a.writers}
+}
+
+// Record validates event and writes it to every writer. Before and After
+// are copied, so later changes to them do not alter the record. It returns
+// an error wrapping ErrInvalidAuditEvent for invalid events, and the write
+// errors of the writers.
+func (a *AuditLogger) Record(event AuditEvent) error {
+	return a.record(event)
+}
+
+// record builds and writes the entry. It must only be called from Record:
+// the entry skips both frames so caller information points at the caller
+// of Record.
+func (a *AuditLogger) record(event AuditEvent) error {
+	if err := event.Validate(); err != nil {
+		return err
+	}
+
+	// A JSON round trip freezes the event, including Before and After
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAuditEvent, err)
+	}
+	var frozen map[string]interface{}
+	if err := json.Unmarshal(data, &frozen); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAuditEvent, err)
+	}
+	frozen["schema_version"] = AuditSchemaVersion
+
+	entry := a.core.createLogEntry(2, InfoLevel, AuditPrefix, fmt.Sprintf("%s %s: %s", event.Actor, event.Action, event.Resource))
+	entry.addFields(map[string]interface{}{AuditKey: frozen})
+
+	a.mu.Lock()
+	writers := append([]LogWriter(nil), a.writers...)
+	a.mu.Unlock()
+	if len(writers) == 0 {
+		return errors.New("audit logger has no writers")
+	}
+
+	var errs []error
+	for _, writer := range writers {
+		if err := writer.Write(entry); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", writerTypeName(writer), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close flushes and closes the audit writers
+func (a *AuditLogger) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var errs []error
+	for _, writer := range a.writers {
+		if err := writer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NewWORMFileWriter creates an append-only (write once, read many) file
+// writer: files are never truncated, compressed or deleted by cleanup,
+// and rotated files are made read-only. Only MaxSize and RotateTime of
+// rotationConfig are used.
+func NewWORMFileWriter(filename string, config LoggerConfig, rotationConfig RotationConfig) (*FileWriter, error) {
+	writer, err := NewFileWriter(filename, config, wormRotation(rotationConfig))
+	if err != nil {
+		return nil, err
+	}
+	writer.readOnlyRotated = true
+	return writer, nil
+}
+
+// wormRotation drops the settings that delete or rewrite rotated files
+func wormRotation(rotation RotationConfig) RotationConfig {
+	return RotationConfig{
+		MaxSize:      rotation.MaxSize,
+		RotateTime:   rotation.RotateTime,
+		CloseTimeout: rotation.CloseTimeout,
+	}
+}
+
+// makeReadOnly removes write permission from a rotated WORM file
+func makeReadOnly(path string) {
+	if err := os.Chmod(path, 0444); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to make rotated log file read-only: %v\n", err)
+	}
+}
@@ -0,0 +1,152 @@
+package pim
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditEventValidate(t *testing.T) {
+	valid := AuditEvent{Actor: "alice", Action: "delete", Resource: "user/42", Outcome: AuditSuccess}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("valid event rejected: %v", err)
+	}
+
+	tests := []AuditEvent{
+		{Action: "delete", Resource: "user/42", Outcome: AuditSuccess},
+		{Actor: "alice", Action: "delete", Resource: "user/42"},
+		{Actor: "alice", Action: "delete", Resource: "user/42", Outcome: AuditDenied},
+		{Actor: "alice", Action: "delete", Resource: "user/42", Outcome: "maybe"},
+	}
+	for _, event := range tests {
+		if err := event.Validate(); !errors.Is(err, ErrInvalidAuditEvent) {
+			t.Errorf("Validate(%+v) = %v, want ErrInvalidAuditEvent", event, err)
+		}
+	}
+}
+
+func TestAuditLoggerRecord(t *testing.T) {
+	audit, err := NewAuditLogger(AuditConfig{LoggerConfig: DefaultLoggerConfig})
+	if err != nil {
+		t.Fatalf("NewAuditLogger failed: %v", err)
+	}
+	if err := audit.Record(AuditEvent{Actor: "alice", Action: "read", Resource: "doc", Outcome: AuditSuccess}); err == nil {
+		t.Error("expected an error without writers")
+	}
+
+	buffer := NewBufferWriter(DefaultLoggerConfig, 10)
+	audit.AddWriter(buffer)
+
+	before := map[string]interface{}{"role": "user"}
+	event := AuditEvent{
+		Actor:    "alice",
+		Action:   "update",
+		Resource: "user/42",
+		Outcome:  AuditSuccess,
+		Before:   before,
+		After:    map[string]interface{}{"role": "admin"},
+	}
+	if err := audit.WithContext(map[string]interface{}{"request_id": "r1"}).Record(event); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	before["role"] = "changed" // Must not alter the record
+
+	if err := audit.Record(AuditEvent{Actor: "alice"}); !errors.Is(err, ErrInvalidAuditEvent) {
+		t.Errorf("expected ErrInvalidAuditEvent, got %v", err)
+	}
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	record, ok := entries[0].Context[AuditKey].(map[string]interface{})
+	if !ok {
+		t.Fatalf("audit field missing: %v", entries[0].Context)
+	}
+	if record["actor"] != "alice" || record["outcome"] != "success" || record["schema_version"] != AuditSchemaVersion {
+		t.Errorf("unexpected record: %v", record)
+	}
+	if role := record["before"].(map[string]interface{})["role"]; role != "user" {
+		t.Errorf("before was not copied, got role %v", role)
+	}
+	if entries[0].Context["request_id"] != "r1" {
+		t.Errorf("context missing: %v", entries[0].Context)
+	}
+	if entries[0].Prefix != AuditPrefix {
+		t.Errorf("prefix = %q", entries[0].Prefix)
+	}
+}
+
+func TestAuditLoggerRecordsCaller(t *testing.T) {
+	config := DefaultLoggerConfig
+	config.CallerInfoConfig = NewCallerInfoConfig()
+	config.CallerInfoConfig.IncludeTest = true
+	audit, err := NewAuditLogger(AuditConfig{LoggerConfig: config})
+	if err != nil {
+		t.Fatalf("NewAuditLogger failed: %v", err)
+	}
+	buffer := NewBufferWriter(config, 10)
+	audit.AddWriter(buffer)
+
+	if err := audit.Record(AuditEvent{Actor: "alice", Action: "read", Resource: "doc", Outcome: AuditSuccess}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	entries := buffer.GetBuffer()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	if entries[0].File != "audit_test.go" || !strings.HasSuffix(entries[0].Function, "TestAuditLoggerRecordsCaller") {
+		t.Errorf("expected the caller of Record, got %s:%d %s", entries[0].File, entries[0].Line, entries[0].Function)
+	}
+}
+
+func TestWORMFileWriter(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+	if err := os.WriteFile(path, []byte("existing\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultLoggerConfig
+	config.EnableConsole = false
+	writer, err := NewWORMFileWriter(path, config, RotationConfig{
+		MaxSize:  1,
+		MaxAge:   time.Nanosecond,
+		MaxFiles: 1,
+		Compress: true,
+	})
+	if err != nil {
+		t.Fatalf("NewWORMFileWriter failed: %v", err)
+	}
+	if writer.rotationConfig.MaxAge != 0 || writer.rotationConfig.MaxFiles != 0 || writer.rotationConfig.Compress {
+		t.Errorf("deleting rotation settings kept: %+v", writer.rotationConfig)
+	}
+
+	logger := NewLoggerCore(config)
+	logger.AddWriter(writer)
+	for i := 0; i < 3; i++ {
+		logger.Info("audit entry")
+		logger.Flush()
+	}
+	logger.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "audit*"))
+	if len(files) < 2 {
+		t.Fatalf("expected rotated files, got %v", files)
+	}
+	for _, file := range files {
+		if file == path {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm()&0222 != 0 {
+			t.Errorf("rotated file %s is writable: %v", file, info.Mode())
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package pim
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// BatchEncryptionEncoding is the Content-Encoding used for encrypted batches
+const BatchEncryptionEncoding = "pim-aes-gcm"
+
+// batchEnvelopeVersion is the version byte of the encrypted batch envelope
+const batchEnvelopeVersion byte = 1
+
+// ErrBatchDecryption is returned when an encrypted batch cannot be decrypted
+var ErrBatchDecryption = errors.New("failed to decrypt batch")
+
+// BatchKeyring holds AES-GCM keys for encrypting shipped batches and log
+// files (see NewEncryptedFileWriter).
+// New batches are sealed with the active key; all keys in the ring can open
+// batches, so senders and receivers can rotate keys without downtime.
+//
+// Envelope layout: version (1 byte) | key ID length (1 byte) | key ID | nonce | ciphertext
+type BatchKeyring struct {
+	keys   map[string]cipher.AEAD
+	active string
+	mu     sync.RWMutex
+}
+
+// NewBatchKeyring creates an empty keyring
+func NewBatchKeyring() *BatchKeyring {
+	return &BatchKeyring{
+		keys: make(map[string]cipher.AEAD),
+	}
+}
+
+// AddKey adds a 16, 24 or 32 byte AES key under the given ID. AES-GCM is
+// FIPS-approved, so keyrings are allowed in FIPS mode.
+// The first key added becomes the active key.
+func (k *BatchKeyring) AddKey(id string, key []byte) error {
+	if id == "" || len(id) > 255 {
+		return fmt.Errorf("key ID must be 1-255 bytes, got %d", len(id))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return fmt.Errorf("invalid key %q: %w", id, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return fmt.Errorf("invalid key %q: %w", id, err)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[id] = aead
+	if k.active == "" {
+		k.active = id
+	}
+	return nil
+}
+
+// SetActiveKey selects the key used to encrypt new batches
+func (k *BatchKeyring) SetActiveKey(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, exists := k.keys[id]; !exists {
+		return fmt.Errorf("key %q not found", id)
+	}
+	k.active = id
+	return nil
+}
+
+// ActiveKey returns the ID of the key used to encrypt new batches
+func (k *BatchKeyring) ActiveKey() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.active
+}
+
+// RemoveKey retires a key. The active key cannot be removed.
+func (k *BatchKeyring) RemoveKey(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if id == k.active {
+		return fmt.Errorf("cannot remove active key %q", id)
+	}
+	delete(k.keys, id)
+	return nil
+}
+
+// KeyIDs returns the IDs of all keys in the ring
+func (k *BatchKeyring) KeyIDs() []string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Encrypt seals a batch payload with the active key
+func (k *BatchKeyring) Encrypt(plaintext []byte) ([]byte, error) {
+	k.mu.RLock()
+	id := k.active
+	aead, exists := k.keys[id]
+	k.mu.RUnlock()
+	if !exists {
+		return nil, errors.New("keyring has no active key")
+	}
+
+	header := make([]byte, 0, 2+len(id))
+	header = append(header, batchEnvelopeVersion, byte(len(id)))
+	header = append(header, id...)
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	out := make([]byte, 0, len(header)+len(nonce)+len(plaintext)+aead.Overhead())
+	out = append(out, header...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, plaintext, header), nil
+}
+
+// Decrypt opens a batch payload sealed with any key in the ring
+func (k *BatchKeyring) Decrypt(envelope []byte) ([]byte, error) {
+	if len(envelope) < 2 || envelope[0] != batchEnvelopeVersion {
+		return nil, fmt.Errorf("%w: unknown envelope version", ErrBatchDecryption)
+	}
+	idLen := int(envelope[1])
+	if len(envelope) < 2+idLen {
+		return nil, fmt.Errorf("%w: truncated envelope", ErrBatchDecryption)
+	}
+	header := envelope[:2+idLen]
+	id := string(header[2:])
+
+	k.mu.RLock()
+	aead, exists := k.keys[id]
+	k.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrBatchDecryption, id)
+	}
+
+	rest := envelope[len(header):]
+	if len(rest) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: truncated envelope", ErrBatchDecryption)
+	}
+	nonce, ciphertext := rest[:aead.NonceSize()], rest[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBatchDecryption, err)
+	}
+	return plaintext, nil
+}
+
+// DecodeBatchRequest decodes a batch from an HTTP request, decrypting it
+// with keyring when the sender encrypted the payload
+func DecodeBatchRequest(r *http.Request, keyring *BatchKeyring) ([]CoreLogEntry, error) {
+	body := io.Reader(r.Body)
+
+	if r.Header.Get("Content-Encoding") == BatchEncryptionEncoding {
+		if keyring == nil {
+			return nil, fmt.Errorf("%w: encrypted batch but no keyring configured", ErrUnsupportedBatchFormat)
+		}
+		envelope, err := io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read batch: %w", err)
+		}
+		plaintext, err := keyring.Decrypt(envelope)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(plaintext)
+	}
+
+	return DecodeBatch(r.Header.Get("Content-Type"), body)
+}
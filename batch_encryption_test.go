@@ -0,0 +1,99 @@
+package pim
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestBatchKeyringRoundTripAndRotation(t *testing.T) {
+	keyring := NewBatchKeyring()
+	if err := keyring.AddKey("k1", testKey(1)); err != nil {
+		t.Fatalf("Failed to add key: %v", err)
+	}
+
+	sealedOld, err := keyring.Encrypt([]byte(`[{"message":"old"}]`))
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	// Rotate to a new key; batches sealed with the old key still open
+	if err := keyring.AddKey("k2", testKey(2)); err != nil {
+		t.Fatalf("Failed to add key: %v", err)
+	}
+	if err := keyring.SetActiveKey("k2"); err != nil {
+		t.Fatalf("Failed to rotate key: %v", err)
+	}
+	if err := keyring.RemoveKey("k2"); err == nil {
+		t.Error("Expected removing the active key to fail")
+	}
+
+	plaintext, err := keyring.Decrypt(sealedOld)
+	if err != nil {
+		t.Fatalf("Failed to decrypt old batch: %v", err)
+	}
+	if string(plaintext) != `[{"message":"old"}]` {
+		t.Errorf("Unexpected plaintext: %s", plaintext)
+	}
+
+	// Retired keys can no longer open batches
+	keyring.RemoveKey("k1")
+	if _, err := keyring.Decrypt(sealedOld); !errors.Is(err, ErrBatchDecryption) {
+		t.Errorf("Expected decryption error for retired key, got: %v", err)
+	}
+
+	// Tampering is detected
+	sealed, _ := keyring.Encrypt([]byte("payload"))
+	sealed[len(sealed)-1] ^= 0xff
+	if _, err := keyring.Decrypt(sealed); !errors.Is(err, ErrBatchDecryption) {
+		t.Errorf("Expected decryption error for tampered batch, got: %v", err)
+	}
+}
+
+func TestBatchKeyringRejectsInvalidKeys(t *testing.T) {
+	keyring := NewBatchKeyring()
+	if err := keyring.AddKey("short", []byte("too-short")); err == nil {
+		t.Error("Expected invalid AES key length to be rejected")
+	}
+	if _, err := keyring.Encrypt([]byte("data")); err == nil {
+		t.Error("Expected encryption without an active key to fail")
+	}
+}
+
+func TestEncryptedRemoteWriterToRelay(t *testing.T) {
+	senderKeys := NewBatchKeyring()
+	senderKeys.AddKey("2024-q1", testKey(7))
+	receiverKeys := NewBatchKeyring()
+	receiverKeys.AddKey("2024-q1", testKey(7))
+
+	relay := NewRelayServer("127.0.0.1:0")
+	relay.SetKeyring(receiverKeys)
+	buffer := NewBufferWriter(LoggerConfig{}, 10)
+	relay.AddWriter(buffer)
+
+	server := httptest.NewServer(relay)
+	defer server.Close()
+
+	remote := NewRemoteWriter(LoggerConfig{EnableJSON: true}, RemoteWriterConfig{
+		Endpoint:   server.URL,
+		BatchDelay: time.Hour,
+		Keyring:    senderKeys,
+	})
+	defer remote.Close()
+
+	remote.Write(CoreLogEntry{Level: InfoLevel, Message: "secret payload"})
+	if err := remote.Flush(); err != nil {
+		t.Fatalf("Failed to flush remote writer: %v", err)
+	}
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 1 || entries[0].Message != "secret payload" {
+		t.Errorf("Expected decrypted entry to be relayed, got: %+v", entries)
+	}
+}
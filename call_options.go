@@ -0,0 +1,206 @@
+package pim
+
+import (
+	"fmt"
+	"os"
+)
+
+// CallOption changes how a single log call is handled. Options are passed
+// among the arguments of the logging methods and are not used for message
+// formatting, e.g.
+//
+//	logger.Info("user deleted", pim.Fields(fields), pim.ToWritersOnly("audit"), pim.ForceFlush())
+type CallOption func(*callOptions)
+
+// callOptions holds the per-call overrides collected from CallOptions
+type callOptions struct {
+	writers        []string
+	forceFlush     bool
+	bypassSampling bool
+	level          *LogLevel
+	fields         map[string]interface{}
+	capture        *CoreLogEntry // Receives the built entry, see captureEntry
+	localized      *LocalizedMessage
+	coloredMessage string // Console rendering of the message, see withColoredMessage
+}
+
+// ToWritersOnly sends the entry only to the named writers (see AddNamedWriter)
+func ToWritersOnly(names ...string) CallOption {
+	return func(o *callOptions) {
+		o.writers = append(o.writers, names...)
+	}
+}
+
+// ForceFlush flushes the receiving writers right after the entry is written
+func ForceFlush() CallOption {
+	return func(o *callOptions) {
+		o.forceFlush = true
+	}
+}
+
+// BypassSampling writes the entry even if sampling would drop it
+func BypassSampling() CallOption {
+	return func(o *callOptions) {
+		o.bypassSampling = true
+	}
+}
+
+// WithLevelOverride uses level instead of the logger level to decide whether
+// this entry is logged, e.g. to emit one debug entry from an info logger
+func WithLevelOverride(level LogLevel) CallOption {
+	return func(o *callOptions) {
+		o.level = &level
+	}
+}
+
+// Fields adds structured fields to the entry
+func Fields(fields map[string]interface{}) CallOption {
+	return func(o *callOptions) {
+		if o.fields == nil {
+			o.fields = make(map[string]interface{}, len(fields))
+		}
+		for k, v := range fields {
+			o.fields[k] = v
+		}
+	}
+}
+
+// extractCallOptions separates CallOptions from formatting arguments
+func extractCallOptions(args []interface{}) (callOptions, []interface{}) {
+	var opts callOptions
+	if len(args) == 0 {
+		return opts, args
+	}
+
+	formatArgs := args[:0:0]
+	for _, arg := range args {
+		if option, ok := arg.(CallOption); ok {
+			if option != nil {
+				option(&opts)
+			}
+			continue
+		}
+		formatArgs = append(formatArgs, arg)
+	}
+	return opts, formatArgs
+}
+
+// belowLevel reports whether an entry logged from the call site skip frames
+// above its caller at level is below the logger level and no CallOption
+// among args could override that. It runs before the options are parsed so
+// disabled calls return without allocating.
+func (l *LoggerCore) belowLevel(skip int, level LogLevel, message string, args []interface{}) bool {
+	if level <= l.effectiveLevel(skip+1) {
+		return false
+	}
+	for _, arg := range args {
+		if _, ok := arg.(CallOption); ok {
+			return false
+		}
+	}
+	l.provenance.dropped(level, StageLevel)
+	return true
+}
+
+// enabled reports whether an entry logged from the call site skip frames
+// above its caller at level passes the level check and sampling
+func (l *LoggerCore) enabled(skip int, level LogLevel, message string, opts callOptions) bool {
+	threshold := l.effectiveLevel(skip + 1)
+	if opts.level != nil {
+		threshold = *opts.level
+	}
+	if level > threshold {
+		l.provenance.dropped(level, StageLevel)
+		return false
+	}
+	if !opts.bypassSampling && !l.shouldSampleLevel(level) {
+		l.provenance.dropped(level, StageSampling)
+		l.samplingReport.dropped(level, message, DropReasonSampling)
+		l.counters.sampledOut()
+		return false
+	}
+	return true
+}
+
+// dispatch writes a processed entry, honoring per-call writer targeting and flushing
+func (l *LoggerCore) dispatch(entry CoreLogEntry, opts callOptions) {
+	entry.resolveLazy()
+	if quarantine := entry.quarantine; quarantine != nil {
+		// Marked by a ValidationHook; written now that every hook ran
+		entry.quarantine = nil
+		if err := quarantine.Write(entry); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to quarantine log entry: %v\n", err)
+		}
+		return
+	}
+	if len(opts.writers) == 0 && !opts.forceFlush {
+		// Write to all writers (async or sync)
+		if l.config.Async {
+			l.enqueueAsync(entry)
+		} else {
+			l.writeToWriters(entry)
+		}
+		return
+	}
+
+	// One-shot overrides are written synchronously so a flush covers the entry
+	if len(opts.writers) == 0 {
+		l.writeToWriters(entry)
+	} else {
+		l.writeEntry(entry, opts.writers...)
+	}
+
+	if opts.forceFlush {
+		l.flushWriters(opts.writers)
+	}
+}
+
+// flushWriters flushes the named writers, or all writers if names is empty
+func (l *LoggerCore) flushWriters(names []string) {
+	l.mu.RLock()
+	var writers []LogWriter
+	if len(names) == 0 {
+		writers = make([]LogWriter, len(l.writers))
+		copy(writers, l.writers)
+	} else {
+		for _, name := range names {
+			if writer, exists := l.namedWriters[name]; exists {
+				writers = append(writers, writer)
+			}
+		}
+	}
+	l.mu.RUnlock()
+
+	for _, writer := range writers {
+		if err := writer.Flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to flush log writer: %v\n", err)
+		}
+	}
+}
+
+// AddNamedWriter adds a writer that can also be targeted by name with ToWritersOnly
+func (l *LoggerCore) AddNamedWriter(name string, writer LogWriter) {
+	if limited, ok := writer.(*RateLimitedWriter); ok {
+		limited.ReportTo(l)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.writers = append(l.writers, writer)
+	if l.namedWriters == nil {
+		l.namedWriters = make(map[string]LogWriter)
+	}
+	l.namedWriters[name] = writer
+}
+
+// addFields merges fields into the entry context, flattening multi-errors
+func (e *CoreLogEntry) addFields(fields map[string]interface{}) {
+	if len(fields) == 0 {
+		return
+	}
+	if e.Context == nil {
+		e.Context = make(map[string]interface{})
+	}
+	for k, v := range fields {
+		e.Context[k] = normalizeFieldValue(v)
+	}
+}
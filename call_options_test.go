@@ -0,0 +1,84 @@
+package pim
+
+import (
+	"testing"
+	"time"
+)
+
+type flushRecordingWriter struct {
+	*BufferWriter
+	flushed int
+}
+
+func (w *flushRecordingWriter) Flush() error {
+	w.flushed++
+	return nil
+}
+
+func TestCallOptionsTargetWritersAndFlush(t *testing.T) {
+	logger := NewLoggerCore(LoggerConfig{Level: InfoLevel})
+	app := NewBufferWriter(LoggerConfig{}, 10)
+	audit := &flushRecordingWriter{BufferWriter: NewBufferWriter(LoggerConfig{}, 10)}
+	logger.AddWriter(app)
+	logger.AddNamedWriter("audit", audit)
+
+	logger.Info("user %s deleted", "alice", Fields(map[string]interface{}{"actor": "admin"}), ToWritersOnly("audit"), ForceFlush())
+	logger.Info("regular entry")
+
+	if app.GetBufferSize() != 1 {
+		t.Errorf("Expected only the regular entry in the app writer, got %d", app.GetBufferSize())
+	}
+	entries := audit.GetBuffer()
+	if len(entries) != 2 {
+		t.Fatalf("Expected both entries in the audit writer, got %d", len(entries))
+	}
+	if entries[0].Message != "user alice deleted" || entries[0].Context["actor"] != "admin" {
+		t.Errorf("Expected formatted message with fields, got: %+v", entries[0])
+	}
+	if audit.flushed != 1 {
+		t.Errorf("Expected audit writer to be flushed once, got %d", audit.flushed)
+	}
+}
+
+func TestCallOptionsLevelOverrideAndSampling(t *testing.T) {
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.Level = InfoLevel
+		config.EnableSampling = true
+		config.SampleRate = 0.0001
+	})
+
+	logger.Debug("hidden")
+	logger.Debug("one-off diagnostics", WithLevelOverride(DebugLevel), BypassSampling())
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 1 || entries[0].Message != "one-off diagnostics" {
+		t.Errorf("Expected only the overridden entry, got: %+v", entries)
+	}
+}
+
+type countingObserver struct {
+	entries int
+	writes  int
+}
+
+func (o *countingObserver) EntryLogged(CoreLogEntry)                   { o.entries++ }
+func (o *countingObserver) HookProcessed(string, time.Duration)        {}
+func (o *countingObserver) WriterWritten(string, time.Duration, error) { o.writes++ }
+
+func TestTargetedWritersShareTheWritePipeline(t *testing.T) {
+	logger := NewLoggerCore(LoggerConfig{Level: InfoLevel, FieldCase: FieldCaseCamel})
+	observer := &countingObserver{}
+	logger.SetPipelineObserver(observer)
+	audit := NewBufferWriter(LoggerConfig{}, 10)
+	logger.AddNamedWriter("audit", audit)
+
+	logger.Info("user deleted", Fields(map[string]interface{}{"user_id": 7}), ToWritersOnly("audit"))
+
+	entries := audit.GetBuffer()
+	if len(entries) != 1 || entries[0].Context["userId"] != 7 {
+		t.Fatalf("Expected the field case to apply to targeted writers, got %+v", entries)
+	}
+	if observer.entries != 1 || observer.writes != 1 {
+		t.Errorf("Expected the observer to see the targeted entry, got %+v", observer)
+	}
+}
@@ -1,11 +1,14 @@
 package pim
 
 import (
+	"bytes"
 	"fmt"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 // CallerInfoConfig defines configuration for caller information
@@ -376,10 +379,31 @@ func (c *CallerInfoFormatter) formatWithTemplate(info CallerInfo) string {
 
 // getGoroutineID returns the current goroutine ID
 func (c *CallerInfoFormatter) getGoroutineID() string {
-	buf := make([]byte, 64)
-	n := runtime.Stack(buf, false)
-	id := strings.Fields(strings.TrimPrefix(string(buf[:n]), "goroutine "))[0]
-	return fmt.Sprintf("(goroutine %s)", id)
+	return goroutineID()
+}
+
+// stackBufferPool holds scratch buffers for reading the goroutine header
+var stackBufferPool = sync.Pool{
+	New: func() interface{} { return new([64]byte) },
+}
+
+// goroutineID returns "(goroutine N)" for the current goroutine
+func goroutineID() string {
+	return "(goroutine " + strconv.FormatUint(currentGoroutineID(), 10) + ")"
+}
+
+// currentGoroutineID returns the numeric ID of the calling goroutine
+func currentGoroutineID() uint64 {
+	buf := stackBufferPool.Get().(*[64]byte)
+	defer stackBufferPool.Put(buf)
+
+	// The stack starts with "goroutine N [status]:"
+	header := bytes.TrimPrefix(buf[:runtime.Stack(buf[:], false)], []byte("goroutine "))
+	if end := bytes.IndexByte(header, ' '); end >= 0 {
+		header = header[:end]
+	}
+	id, _ := strconv.ParseUint(string(header), 10, 64)
+	return id
 }
 
 // ClearCache clears the formatter cache
@@ -445,3 +469,14 @@ func NewProductionCallerInfo() *CallerInfoFormatter {
 	config.Format = "{file}:{line} {function}"
 	return NewCallerInfoFormatter(config)
 }
+
+// packagePath returns the import path of the package declaring a function,
+// given its runtime name, e.g. "github.com/acme/db.(*Conn).Query" yields
+// "github.com/acme/db"
+func packagePath(funcName string) string {
+	lastSlash := strings.LastIndex(funcName, "/")
+	if dot := strings.Index(funcName[lastSlash+1:], "."); dot >= 0 {
+		return funcName[:lastSlash+1+dot]
+	}
+	return funcName
+}
@@ -0,0 +1,131 @@
+package pim
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"mime"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// BatchCBORMediaType is the media type of CBOR-encoded batches. CBOR
+// batches always use the WireFormatV2 envelope, e.g.
+// "application/vnd.pim.batch+cbor; version=2".
+const BatchCBORMediaType = "application/vnd.pim.batch+cbor"
+
+// cborEncMode encodes entries with their JSON field names; timestamps keep
+// nanosecond precision
+var cborEncMode = mustCBOREncMode()
+
+// cborDecMode decodes nested maps as map[string]interface{} like encoding/json
+var cborDecMode = mustCBORDecMode()
+
+func mustCBOREncMode() cbor.EncMode {
+	mode, err := cbor.EncOptions{Time: cbor.TimeRFC3339Nano}.EncMode()
+	if err != nil {
+		panic(err)
+	}
+	return mode
+}
+
+func mustCBORDecMode() cbor.DecMode {
+	mode, err := cbor.DecOptions{
+		DefaultMapType: reflect.TypeOf(map[string]interface{}(nil)),
+	}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+	return mode
+}
+
+// EncodeEntryCBOR encodes an entry as a single CBOR data item
+func EncodeEntryCBOR(entry CoreLogEntry) ([]byte, error) {
+	data, err := cborEncMode.Marshal(entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode log entry as CBOR: %w", err)
+	}
+	return data, nil
+}
+
+// DecodeEntryCBOR decodes an entry encoded by EncodeEntryCBOR
+func DecodeEntryCBOR(data []byte) (CoreLogEntry, error) {
+	var entry CoreLogEntry
+	if err := cborDecMode.Unmarshal(data, &entry); err != nil {
+		return CoreLogEntry{}, fmt.Errorf("failed to decode CBOR log entry: %w", err)
+	}
+	return entry, nil
+}
+
+// ReadCBOREntries reads a CBOR sequence of entries, as written by FileWriter
+// with EnableCBOR, until EOF
+func ReadCBOREntries(r io.Reader) iter.Seq2[CoreLogEntry, error] {
+	return func(yield func(CoreLogEntry, error) bool) {
+		decoder := cborDecMode.NewDecoder(r)
+		for {
+			var entry CoreLogEntry
+			err := decoder.Decode(&entry)
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(CoreLogEntry{}, fmt.Errorf("failed to decode CBOR log entry: %w", err))
+				return
+			}
+			if !yield(entry, nil) {
+				return
+			}
+		}
+	}
+}
+
+// CBORContentType returns the Content-Type of a CBOR batch in the given version
+func CBORContentType(version int) string {
+	return fmt.Sprintf("%s; version=%d", BatchCBORMediaType, version)
+}
+
+// EncodeCBORBatch encodes entries as a CBOR batch and returns the payload
+// with its Content-Type. Versions before WireFormatV2 are encoded as
+// WireFormatV2 since CBOR batches always carry the envelope.
+func EncodeCBORBatch(version int, entries []CoreLogEntry) ([]byte, string, error) {
+	if version < WireFormatV2 {
+		version = WireFormatV2
+	}
+	data, err := cborEncMode.Marshal(batchEnvelope{
+		Version: version,
+		SentAt:  time.Now().UTC(),
+		Count:   len(entries),
+		Entries: entries,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode CBOR batch: %w", err)
+	}
+	return data, CBORContentType(version), nil
+}
+
+// isCBORBatch reports whether a Content-Type denotes a CBOR batch
+func isCBORBatch(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == BatchCBORMediaType
+}
+
+// acceptsCBOR reports whether a receiver's Accept header lists CBOR batches
+func acceptsCBOR(accept string) bool {
+	return strings.Contains(accept, BatchCBORMediaType)
+}
+
+// decodeCBORBatch decodes a CBOR batch body in the given wire format version
+func decodeCBORBatch(version int, body io.Reader) ([]CoreLogEntry, error) {
+	var envelope batchEnvelope
+	if err := cborDecMode.NewDecoder(body).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode batch: %w", err)
+	}
+	if envelope.Version != version {
+		return nil, fmt.Errorf("batch version %d does not match content type version %d", envelope.Version, version)
+	}
+	return envelope.Entries, nil
+}
@@ -0,0 +1,155 @@
+package pim
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCBOREntryRoundTrip(t *testing.T) {
+	entry := CoreLogEntry{
+		Timestamp:   time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC),
+		Level:       WarningLevel,
+		LevelString: "warning",
+		Message:     "disk almost full",
+		StackTrace:  []StackFrame{{File: "disk.go", Line: 7, Function: "check"}},
+		Context:     map[string]interface{}{"free": "2%", "nested": map[string]interface{}{"mount": "/"}},
+	}
+
+	data, err := EncodeEntryCBOR(entry)
+	if err != nil {
+		t.Fatalf("EncodeEntryCBOR failed: %v", err)
+	}
+	decoded, err := DecodeEntryCBOR(data)
+	if err != nil {
+		t.Fatalf("DecodeEntryCBOR failed: %v", err)
+	}
+
+	if !decoded.Timestamp.Equal(entry.Timestamp) || decoded.Level != entry.Level || decoded.Message != entry.Message {
+		t.Errorf("Round trip mismatch: %+v", decoded)
+	}
+	if len(decoded.StackTrace) != 1 || decoded.StackTrace[0].Line != 7 {
+		t.Errorf("Expected stack trace to survive, got %+v", decoded.StackTrace)
+	}
+	if nested, ok := decoded.Context["nested"].(map[string]interface{}); !ok || nested["mount"] != "/" {
+		t.Errorf("Expected nested context map, got %#v", decoded.Context["nested"])
+	}
+}
+
+func TestFileWriterCBOR(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.cbor")
+	writer, err := NewFileWriter(path, LoggerConfig{EnableCBOR: true}, RotationConfig{})
+	if err != nil {
+		t.Fatalf("NewFileWriter failed: %v", err)
+	}
+	for _, msg := range []string{"one", "two", "three"} {
+		if err := writer.Write(CoreLogEntry{Level: InfoLevel, Message: msg}); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	writer.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	var messages []string
+	for entry, err := range ReadCBOREntries(bytes.NewReader(data)) {
+		if err != nil {
+			t.Fatalf("ReadCBOREntries failed: %v", err)
+		}
+		messages = append(messages, entry.Message)
+	}
+	if len(messages) != 3 || messages[0] != "one" || messages[2] != "three" {
+		t.Errorf("Unexpected entries: %v", messages)
+	}
+}
+
+func TestCBORBatchRoundTrip(t *testing.T) {
+	data, contentType, err := EncodeCBORBatch(CurrentWireVersion, []CoreLogEntry{{Level: InfoLevel, Message: "hello"}})
+	if err != nil {
+		t.Fatalf("EncodeCBORBatch failed: %v", err)
+	}
+	decoded, err := DecodeBatch(contentType, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("DecodeBatch failed: %v", err)
+	}
+	if len(decoded) != 1 || decoded[0].Message != "hello" {
+		t.Errorf("Unexpected entries: %+v", decoded)
+	}
+	if !acceptsCBOR(AcceptedBatchFormats) {
+		t.Errorf("Expected receivers to advertise CBOR, got %q", AcceptedBatchFormats)
+	}
+}
+
+func TestRemoteWriterCBOR(t *testing.T) {
+	var contentTypes []string
+	var received []CoreLogEntry
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentTypes = append(contentTypes, r.Header.Get("Content-Type"))
+		entries, err := DecodeBatchRequest(r, nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		received = append(received, entries...)
+	}))
+	defer server.Close()
+
+	remote := NewRemoteWriter(LoggerConfig{EnableCBOR: true}, RemoteWriterConfig{
+		Endpoint:   server.URL,
+		BatchDelay: time.Hour,
+	})
+	defer remote.Close()
+
+	remote.Write(CoreLogEntry{Level: InfoLevel, Message: "compact"})
+	if err := remote.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+
+	if len(contentTypes) != 1 || !isCBORBatch(contentTypes[0]) {
+		t.Errorf("Expected a CBOR batch, got %v", contentTypes)
+	}
+	if len(received) != 1 || received[0].Message != "compact" {
+		t.Errorf("Unexpected entries: %+v", received)
+	}
+}
+
+func TestRemoteWriterCBORFallsBackToJSON(t *testing.T) {
+	var received []CoreLogEntry
+	// A receiver that predates CBOR support
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType == BatchCBORMediaType {
+			w.Header().Set("Accept", WireContentType(CurrentWireVersion))
+			http.Error(w, "unsupported", http.StatusUnsupportedMediaType)
+			return
+		}
+		entries, err := DecodeBatchRequest(r, nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		received = append(received, entries...)
+	}))
+	defer server.Close()
+
+	remote := NewRemoteWriter(LoggerConfig{EnableCBOR: true}, RemoteWriterConfig{
+		Endpoint:   server.URL,
+		BatchDelay: time.Hour,
+	})
+	defer remote.Close()
+
+	remote.Write(CoreLogEntry{Level: InfoLevel, Message: "fallback"})
+	if err := remote.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	if len(received) != 1 || received[0].Message != "fallback" {
+		t.Errorf("Expected batch to be delivered as JSON, got: %+v", received)
+	}
+}
@@ -0,0 +1,221 @@
+package pim
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by CircuitBreakerWriter while the circuit is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of a CircuitBreakerWriter
+type CircuitState int
+
+const (
+	CircuitClosed   CircuitState = iota // Writes go through
+	CircuitOpen                         // Writes are diverted without calling the writer
+	CircuitHalfOpen                     // One probe write is let through to test the writer
+)
+
+// String returns the state name
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half_open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerConfig configures a CircuitBreakerWriter
+type CircuitBreakerConfig struct {
+	FailureThreshold int           `json:"failure_threshold"` // Consecutive failures that open the circuit (default: 5)
+	OpenTimeout      time.Duration `json:"open_timeout"`      // Time open before a probe is let through (default: 30 seconds)
+	SuccessThreshold int           `json:"success_threshold"` // Successful probes that close the circuit (default: 1)
+
+	// DeadLetter receives entries diverted while the circuit is open, e.g.
+	// with a FileWriter as its Writer to buffer them to disk for Replay.
+	// Diverted entries are dropped if nil.
+	DeadLetter *DeadLetterQueue `json:"-"`
+
+	// OnStateChange is called after each state transition
+	OnStateChange func(from, to CircuitState) `json:"-"`
+}
+
+// CircuitBreakerStats is a snapshot of a circuit breaker for metrics
+type CircuitBreakerStats struct {
+	State               CircuitState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	Opens               uint64       `json:"opens"`    // Times the circuit opened
+	Diverted            uint64       `json:"diverted"` // Entries not sent to the writer while open
+}
+
+// CircuitBreakerWriter protects a network writer such as RemoteWriter from
+// a failing destination. After FailureThreshold consecutive failed writes
+// or flushes the circuit opens: entries go to the dead-letter queue (or are
+// dropped) without waiting on the writer's retries and timeouts. After
+// OpenTimeout one write is let through as a probe; SuccessThreshold
+// successful probes close the circuit, a failed one reopens it.
+type CircuitBreakerWriter struct {
+	writer LogWriter
+	config CircuitBreakerConfig
+
+	mu        sync.Mutex
+	stats     CircuitBreakerStats
+	successes int               // Successful probes while half-open
+	openedAt  time.Time         // When the circuit last opened
+	probing   bool              // A half-open probe is in flight
+	changes   [][2]CircuitState // Transitions to report once unlocked
+	now       func() time.Time
+}
+
+// NewCircuitBreakerWriter wraps writer with a circuit breaker
+func NewCircuitBreakerWriter(writer LogWriter, config CircuitBreakerConfig) *CircuitBreakerWriter {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 5
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = 30 * time.Second
+	}
+	if config.SuccessThreshold <= 0 {
+		config.SuccessThreshold = 1
+	}
+	return &CircuitBreakerWriter{writer: writer, config: config, now: time.Now}
+}
+
+// Write implements LogWriter interface. While the circuit is open it
+// returns nil if the entry was added to the dead-letter queue and
+// ErrCircuitOpen if it was dropped.
+func (w *CircuitBreakerWriter) Write(entry CoreLogEntry) error {
+	if !w.allow() {
+		return w.divert(entry)
+	}
+	err := w.writer.Write(entry)
+	w.record(err)
+	return err
+}
+
+// Flush implements LogWriter interface. Flushes count as probes and
+// failures like writes; while open the writer is not flushed.
+func (w *CircuitBreakerWriter) Flush() error {
+	if !w.allow() {
+		return ErrCircuitOpen
+	}
+	err := w.writer.Flush()
+	w.record(err)
+	return err
+}
+
+// Close implements LogWriter interface
+func (w *CircuitBreakerWriter) Close() error {
+	return w.writer.Close()
+}
+
+// State returns the current state
+func (w *CircuitBreakerWriter) State() CircuitState {
+	return w.Stats().State
+}
+
+// Stats returns a snapshot of the breaker state and counters
+func (w *CircuitBreakerWriter) Stats() CircuitBreakerStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stats
+}
+
+// allow reports whether a call may go to the writer, moving an open
+// circuit to half-open once OpenTimeout has passed
+func (w *CircuitBreakerWriter) allow() bool {
+	w.mu.Lock()
+	defer w.unlock()
+
+	switch w.stats.State {
+	case CircuitClosed:
+		return true
+	case CircuitOpen:
+		if w.now().Sub(w.openedAt) < w.config.OpenTimeout {
+			return false
+		}
+		w.transition(CircuitHalfOpen)
+	}
+
+	// Half-open: one probe at a time
+	if w.probing {
+		return false
+	}
+	w.probing = true
+	return true
+}
+
+// record updates the state with the outcome of a call to the writer
+func (w *CircuitBreakerWriter) record(err error) {
+	w.mu.Lock()
+	defer w.unlock()
+
+	halfOpen := w.stats.State == CircuitHalfOpen
+	w.probing = false
+	if err != nil {
+		w.stats.ConsecutiveFailures++
+		if halfOpen || w.stats.ConsecutiveFailures >= w.config.FailureThreshold {
+			w.open()
+		}
+		return
+	}
+
+	w.stats.ConsecutiveFailures = 0
+	if halfOpen {
+		w.successes++
+		if w.successes >= w.config.SuccessThreshold {
+			w.transition(CircuitClosed)
+		}
+	}
+}
+
+// open opens the circuit; the caller holds w.mu
+func (w *CircuitBreakerWriter) open() {
+	w.openedAt = w.now()
+	w.stats.Opens++
+	w.transition(CircuitOpen)
+}
+
+// transition changes the state; the caller holds w.mu
+func (w *CircuitBreakerWriter) transition(to CircuitState) {
+	from := w.stats.State
+	w.stats.State = to
+	w.successes = 0
+	if from != to {
+		w.changes = append(w.changes, [2]CircuitState{from, to})
+	}
+}
+
+// unlock releases w.mu and then reports transitions to OnStateChange, so
+// the callback may use the breaker
+func (w *CircuitBreakerWriter) unlock() {
+	changes := w.changes
+	w.changes = nil
+	w.mu.Unlock()
+
+	if w.config.OnStateChange != nil {
+		for _, change := range changes {
+			w.config.OnStateChange(change[0], change[1])
+		}
+	}
+}
+
+// divert hands an entry rejected while open to the dead-letter queue
+func (w *CircuitBreakerWriter) divert(entry CoreLogEntry) error {
+	w.mu.Lock()
+	w.stats.Diverted++
+	w.mu.Unlock()
+
+	if w.config.DeadLetter == nil {
+		return ErrCircuitOpen
+	}
+	w.config.DeadLetter.Add(writerTypeName(w.writer), []CoreLogEntry{entry}, ErrCircuitOpen)
+	return nil
+}
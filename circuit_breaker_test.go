@@ -0,0 +1,88 @@
+package pim
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	config := DefaultLoggerConfig
+	config.EnableConsole = false
+	inner := &flakyWriter{BufferWriter: NewBufferWriter(config, 10), down: true}
+	queue := NewDeadLetterQueue(DeadLetterConfig{})
+
+	var transitions []string
+	breaker := NewCircuitBreakerWriter(inner, CircuitBreakerConfig{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+		DeadLetter:       queue,
+		OnStateChange: func(from, to CircuitState) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+	})
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	breaker.now = func() time.Time { return now }
+
+	breaker.Write(CoreLogEntry{Message: "a"})
+	if breaker.State() != CircuitClosed {
+		t.Fatal("Expected the circuit closed after one failure")
+	}
+	breaker.Write(CoreLogEntry{Message: "b"})
+	if breaker.State() != CircuitOpen {
+		t.Fatal("Expected the circuit open after two failures")
+	}
+
+	// Open: entries are diverted without calling the writer
+	inner.down = false
+	if err := breaker.Write(CoreLogEntry{Message: "c"}); err != nil {
+		t.Errorf("Expected diverted entry to be accepted by the queue, got %v", err)
+	}
+	if inner.GetBufferSize() != 0 || queue.Len() != 1 {
+		t.Errorf("Expected the entry dead-lettered, got %d written and %d queued", inner.GetBufferSize(), queue.Len())
+	}
+
+	// After the timeout a failed probe reopens the circuit
+	now = now.Add(time.Minute)
+	inner.down = true
+	breaker.Write(CoreLogEntry{Message: "probe 1"})
+	if breaker.State() != CircuitOpen {
+		t.Fatalf("Expected a failed probe to reopen the circuit, got %v", breaker.State())
+	}
+
+	// A successful probe closes it
+	now = now.Add(time.Minute)
+	inner.down = false
+	if err := breaker.Write(CoreLogEntry{Message: "probe 2"}); err != nil {
+		t.Fatalf("Expected the probe to succeed, got %v", err)
+	}
+	if breaker.State() != CircuitClosed {
+		t.Fatalf("Expected the circuit closed, got %v", breaker.State())
+	}
+
+	stats := breaker.Stats()
+	if stats.Opens != 2 || stats.Diverted != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	want := []string{"closed->open", "open->half_open", "half_open->open", "open->half_open", "half_open->closed"}
+	if len(transitions) != len(want) {
+		t.Fatalf("Expected transitions %v, got %v", want, transitions)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Errorf("Expected transitions %v, got %v", want, transitions)
+			break
+		}
+	}
+}
+
+func TestCircuitBreakerDropsWithoutDeadLetter(t *testing.T) {
+	breaker := NewCircuitBreakerWriter(rejectingWriter{}, CircuitBreakerConfig{FailureThreshold: 1})
+	breaker.Write(CoreLogEntry{})
+	if err := breaker.Write(CoreLogEntry{}); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if err := breaker.Flush(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected flush to be skipped while open, got %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package pim
+
+import (
+	"fmt"
+	"time"
+)
+
+// Clock is the time source of a logger, see LoggerConfig.Clock
+type Clock interface {
+	Now() time.Time
+}
+
+// IDGenerator generates the IDs a logger assigns, see LoggerConfig.IDGenerator
+type IDGenerator interface {
+	NewID() string
+}
+
+// ClockFunc adapts a function to the Clock interface
+type ClockFunc func() time.Time
+
+// Now implements Clock
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// IDGeneratorFunc adapts a function to the IDGenerator interface
+type IDGeneratorFunc func() string
+
+// NewID implements IDGenerator
+func (f IDGeneratorFunc) NewID() string {
+	return f()
+}
+
+// now returns the current time of the configured clock, or time.Now
+func (c LoggerConfig) now() time.Time {
+	if c.Clock != nil {
+		return c.Clock.Now()
+	}
+	return time.Now()
+}
+
+// newRequestID returns an ID from the configured generator, or one derived
+// from the clock, e.g. "req_1700000000000000000"
+func (c LoggerConfig) newRequestID() string {
+	if c.IDGenerator != nil {
+		return c.IDGenerator.NewID()
+	}
+	return fmt.Sprintf("req_%d", c.now().UnixNano())
+}
@@ -0,0 +1,100 @@
+package pim
+
+import "time"
+
+// Context keys used for receive-time stamping
+const (
+	ReceivedAtKey        = "received_at"
+	OriginalTimestampKey = "original_timestamp"
+	ClockSkewKey         = "clock_skew_ms"
+	ClockSkewDetectedKey = "clock_skew_detected"
+)
+
+// ClockSkewConfig holds configuration for ingest-side receive-time stamping
+type ClockSkewConfig struct {
+	HookConfig
+	Threshold      time.Duration    `json:"threshold"`        // Deviation that flags an entry (default: 5 minutes)
+	UseReceiveTime bool             `json:"use_receive_time"` // Replace Timestamp with the receive time
+	Now            func() time.Time `json:"-"`                // Receive clock (default: time.Now)
+}
+
+// ClockSkewHook records when an entry was received and flags entries whose
+// timestamps deviate from the receive time beyond a threshold. It is meant
+// for ingest pipelines (RelayServer, receiver) aggregating logs from devices
+// with unreliable clocks.
+type ClockSkewHook struct {
+	config ClockSkewConfig
+}
+
+// NewClockSkewHook creates a new receive-time stamping hook
+func NewClockSkewHook(config ClockSkewConfig) *ClockSkewHook {
+	if config.Threshold == 0 {
+		config.Threshold = 5 * time.Minute
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &ClockSkewHook{config: config}
+}
+
+// Process implements LogHook interface
+func (h *ClockSkewHook) Process(entry CoreLogEntry) (CoreLogEntry, error) {
+	if !h.config.Enabled {
+		return entry, nil
+	}
+
+	received := h.config.Now().UTC()
+	if entry.Context == nil {
+		entry.Context = make(map[string]interface{})
+	}
+	entry.Context[ReceivedAtKey] = received.Format(time.RFC3339Nano)
+
+	if !entry.Timestamp.IsZero() {
+		skew := received.Sub(entry.Timestamp)
+		entry.Context[OriginalTimestampKey] = entry.Timestamp.UTC().Format(time.RFC3339Nano)
+		entry.Context[ClockSkewKey] = skew.Milliseconds()
+
+		if skew < 0 {
+			skew = -skew
+		}
+		if skew > h.config.Threshold {
+			entry.Context[ClockSkewDetectedKey] = true
+		}
+	}
+
+	if h.config.UseReceiveTime {
+		entry.Timestamp = received
+	}
+
+	return entry, nil
+}
+
+// GetConfig implements EnhancedLogHook interface
+func (h *ClockSkewHook) GetConfig() HookConfig {
+	return h.config.HookConfig
+}
+
+// GetType implements EnhancedLogHook interface
+func (h *ClockSkewHook) GetType() HookType {
+	return h.config.Type
+}
+
+// IsEnabled implements EnhancedLogHook interface
+func (h *ClockSkewHook) IsEnabled() bool {
+	return h.config.Enabled
+}
+
+// SetEnabled implements EnhancedLogHook interface
+func (h *ClockSkewHook) SetEnabled(enabled bool) {
+	h.config.Enabled = enabled
+}
+
+// GetPriority implements EnhancedLogHook interface
+func (h *ClockSkewHook) GetPriority() int {
+	return h.config.Priority
+}
+
+// SetPriority implements EnhancedLogHook interface
+func (h *ClockSkewHook) SetPriority(priority int) {
+	h.config.Priority = priority
+}
@@ -0,0 +1,58 @@
+package pim
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockSkewHook(t *testing.T) {
+	received := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	hook := NewClockSkewHook(ClockSkewConfig{
+		HookConfig: HookConfig{
+			Type:    HookTypeEnrich,
+			Name:    "clock_skew",
+			Enabled: true,
+		},
+		Threshold: time.Minute,
+		Now:       func() time.Time { return received },
+	})
+
+	// Within threshold
+	result, _ := hook.Process(CoreLogEntry{Message: "ok", Timestamp: received.Add(-10 * time.Second)})
+	if result.Context[ReceivedAtKey] != received.Format(time.RFC3339Nano) {
+		t.Errorf("Expected received_at to be stamped, got: %v", result.Context[ReceivedAtKey])
+	}
+	if result.Context[ClockSkewKey] != int64(10000) {
+		t.Errorf("Expected skew of 10000ms, got: %v", result.Context[ClockSkewKey])
+	}
+	if _, flagged := result.Context[ClockSkewDetectedKey]; flagged {
+		t.Error("Expected entry within threshold not to be flagged")
+	}
+
+	// Device clock running ahead
+	result, _ = hook.Process(CoreLogEntry{Message: "future", Timestamp: received.Add(time.Hour)})
+	if result.Context[ClockSkewDetectedKey] != true {
+		t.Error("Expected entry from the future to be flagged")
+	}
+	if !result.Timestamp.Equal(received.Add(time.Hour)) {
+		t.Error("Expected original timestamp to be preserved")
+	}
+}
+
+func TestClockSkewHookUseReceiveTime(t *testing.T) {
+	received := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	original := received.Add(-24 * time.Hour)
+	hook := NewClockSkewHook(ClockSkewConfig{
+		HookConfig:     HookConfig{Enabled: true},
+		UseReceiveTime: true,
+		Now:            func() time.Time { return received },
+	})
+
+	result, _ := hook.Process(CoreLogEntry{Message: "old", Timestamp: original})
+	if !result.Timestamp.Equal(received) {
+		t.Errorf("Expected timestamp to be replaced by receive time, got: %v", result.Timestamp)
+	}
+	if result.Context[OriginalTimestampKey] != original.Format(time.RFC3339Nano) {
+		t.Errorf("Expected original timestamp to be recorded, got: %v", result.Context[OriginalTimestampKey])
+	}
+}
@@ -0,0 +1,91 @@
+package pim
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// stepClock is a Clock whose time tests set directly
+type stepClock struct{ now time.Time }
+
+func (c *stepClock) Now() time.Time { return c.now }
+
+func TestLoggerClockAndIDGenerator(t *testing.T) {
+	clock := &stepClock{now: time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))}
+	ids := 0
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.Clock = clock
+		config.IDGenerator = IDGeneratorFunc(func() string {
+			ids++
+			return "id-" + string(rune('0'+ids))
+		})
+	})
+	logger.AddRequestIDEnrichHook()
+
+	logger.Info("first")
+	clock.now = clock.now.Add(time.Minute)
+	logger.Info("second")
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if !entries[0].Timestamp.Equal(time.Date(2024, 1, 2, 2, 4, 5, 0, time.UTC)) || entries[0].Timestamp.Location() != time.UTC {
+		t.Errorf("Expected the clock's time in UTC, got %v", entries[0].Timestamp)
+	}
+	if entries[1].Timestamp.Sub(entries[0].Timestamp) != time.Minute {
+		t.Errorf("Expected entries a minute apart, got %v", entries[1].Timestamp.Sub(entries[0].Timestamp))
+	}
+	if entries[0].Context["request_id"] != "id-1" || entries[1].Context["request_id"] != "id-2" {
+		t.Errorf("Expected generated request IDs, got %v and %v", entries[0].Context["request_id"], entries[1].Context["request_id"])
+	}
+}
+
+func TestSamplingWithClock(t *testing.T) {
+	clock := &stepClock{now: time.Unix(0, 10)}
+	config := DefaultLoggerConfig
+	config.EnableConsole = false
+	config.EnableSampling = true
+	config.SampleRate = 0.5
+	config.Clock = clock
+	logger := NewLoggerCore(config)
+	defer logger.Close()
+
+	if !logger.shouldSampleLevel(InfoLevel) {
+		t.Error("Expected an entry at 10ns past the hundred to be kept at rate 0.5")
+	}
+	clock.now = time.Unix(0, 70)
+	if logger.shouldSampleLevel(InfoLevel) {
+		t.Error("Expected an entry at 70ns past the hundred to be dropped at rate 0.5")
+	}
+}
+
+func TestFileRotationWithClock(t *testing.T) {
+	dir := t.TempDir()
+	clock := &stepClock{now: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	config := LoggerConfig{TimestampFormat: "15:04:05", Clock: clock}
+	writer, err := NewFileWriter(filepath.Join(dir, "app.log"), config, RotationConfig{RotateTime: time.Hour})
+	if err != nil {
+		t.Fatalf("NewFileWriter failed: %v", err)
+	}
+	defer writer.Close()
+
+	if writer.shouldRotate() {
+		t.Error("Expected no rotation before the interval")
+	}
+	clock.now = clock.now.Add(time.Hour)
+	if !writer.shouldRotate() {
+		t.Error("Expected rotation once the clock passes the interval")
+	}
+	if err := writer.rotateFile(); err != nil {
+		t.Fatalf("rotateFile failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app.2024-01-02_04-04-05.log")); err != nil {
+		t.Errorf("Expected the rotated file named after the clock: %v", err)
+	}
+	if writer.shouldRotate() {
+		t.Error("Expected the interval to restart at the rotation")
+	}
+}
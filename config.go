@@ -2,6 +2,7 @@ package pim
 
 import (
 	"github.com/fatih/color"
+	"github.com/refactorroom/pim/core"
 )
 
 // Color constants for direct ANSI usage when needed
@@ -84,6 +85,9 @@ var (
 	URLColor     = Blue.Add(color.Underline)
 )
 
+// applyColorEnv applies FORCE_COLOR before the prefixes below are rendered
+var _ = applyColorEnv()
+
 // Log prefixes using the color package
 var (
 	// Standard log prefixes
@@ -106,22 +110,23 @@ var (
 	ModelPrefix = HiBlue.Sprint("📊 MODEL    ")
 )
 
-// LogLevel type for controlling log output
-type LogLevel int
+// LogLevel type for controlling log output. It is shared with the minimal
+// core package so both layers use the same levels.
+type LogLevel = core.Level
 
 const (
 	// PanicLevel logs and then calls panic()
-	PanicLevel LogLevel = iota
+	PanicLevel = core.PanicLevel
 	// ErrorLevel indicates error conditions
-	ErrorLevel
+	ErrorLevel = core.ErrorLevel
 	// WarningLevel indicates potentially harmful situations
-	WarningLevel
+	WarningLevel = core.WarningLevel
 	// InfoLevel indicates general operational information
-	InfoLevel
+	InfoLevel = core.InfoLevel
 	// DebugLevel indicates detailed debug information
-	DebugLevel
+	DebugLevel = core.DebugLevel
 	// TraceLevel indicates the most detailed debugging information
-	TraceLevel
+	TraceLevel = core.TraceLevel
 )
 
 var (
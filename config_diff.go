@@ -0,0 +1,129 @@
+package pim
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ConfigReloadedMessage is the message of the entry logged when a watched
+// configuration file is reloaded
+const ConfigReloadedMessage = "Log configuration reloaded"
+
+// ConfigChange is one setting changed by a configuration reload. Field is
+// the JSON path of the setting, e.g. "logger.level" or "hooks[0].redact.fields".
+type ConfigChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old,omitempty"`
+	New   interface{} `json:"new,omitempty"`
+}
+
+// ConfigChangeEvent describes a configuration reload applied by a ConfigWatcher
+type ConfigChangeEvent struct {
+	Path         string         `json:"path"`
+	Hash         string         `json:"sha256"`          // SHA-256 of the new file content
+	PreviousHash string         `json:"previous_sha256"` // SHA-256 of the replaced content
+	Changes      []ConfigChange `json:"changes"`         // Sorted by field
+	Old          *FileConfig    `json:"-"`               // Configuration before the reload
+	New          *FileConfig    `json:"-"`               // Configuration now applied
+}
+
+// maskedConfigValue replaces changed values of sensitive settings
+const maskedConfigValue = "[REDACTED]"
+
+// DiffConfigs lists the settings that differ between two configurations.
+// Settings are compared by their JSON form, so only serialized fields count.
+// Header values, e.g. the Authorization header of a remote writer, are
+// masked, as changes are logged and credentials must not end up in logs.
+func DiffConfigs(old, new *FileConfig) ([]ConfigChange, error) {
+	oldFields, err := flattenConfig(old)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := flattenConfig(new)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]bool, len(newFields))
+	for key := range oldFields {
+		keys[key] = true
+	}
+	for key := range newFields {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var changes []ConfigChange
+	for _, key := range sorted {
+		o, n := oldFields[key], newFields[key]
+		if !reflect.DeepEqual(o, n) {
+			if sensitiveConfigField(key) {
+				o, n = maskConfigValue(o), maskConfigValue(n)
+			}
+			changes = append(changes, ConfigChange{Field: key, Old: o, New: n})
+		}
+	}
+	return changes, nil
+}
+
+// sensitiveConfigField reports whether the setting at path may hold a
+// credential: any value in a headers map
+func sensitiveConfigField(path string) bool {
+	return strings.Contains(path, ".headers.") || strings.HasPrefix(path, "headers.")
+}
+
+// maskConfigValue masks a set value, keeping unset values unset
+func maskConfigValue(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	return maskedConfigValue
+}
+
+// flattenConfig returns the leaf values of the JSON form of config keyed
+// by their path
+func flattenConfig(config *FileConfig) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	if config == nil {
+		return fields, nil
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	var tree interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+	flattenValue("", tree, fields)
+	return fields, nil
+}
+
+// flattenValue adds the leaves of value under path to fields
+func flattenValue(path string, value interface{}, fields map[string]interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if path == "" {
+				flattenValue(key, child, fields)
+			} else {
+				flattenValue(path+"."+key, child, fields)
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			flattenValue(fmt.Sprintf("%s[%d]", path, i), child, fields)
+		}
+	case nil:
+		// Unset; a change from or to nil shows as a missing old or new value
+	default:
+		fields[path] = v
+	}
+}
@@ -0,0 +1,376 @@
+package pim
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConfigSchemaID is the $id of the configuration file schema
+const ConfigSchemaID = "https://github.com/refactorroom/pim/config.schema.json"
+
+// schemaEnums lists the values of the string types ParseConfig accepts a
+// fixed set of
+var schemaEnums = map[reflect.Type][]interface{}{
+	reflect.TypeOf(FieldCase("")):        {"", string(FieldCaseSnake), string(FieldCaseCamel), string(FieldCasePascal)},
+	reflect.TypeOf(HashAlgorithm("")):    {"", string(HashSHA256), string(HashSHA384), string(HashSHA512), string(HashSHA1), string(HashMD5)},
+	reflect.TypeOf(SyncPolicy("")):       {"", string(SyncAlways), string(SyncInterval), string(SyncNever)},
+	reflect.TypeOf(RedactStrategy("")):   {"", string(RedactReplace), string(RedactMaskLast4), string(RedactHash), string(RedactTokenize)},
+	reflect.TypeOf(PIIDetector("")):      piiDetectorNames(),
+	reflect.TypeOf(ValidationAction("")): {"", string(ValidationAnnotate), string(ValidationDrop), string(ValidationQuarantine)},
+}
+
+// piiDetectorNames returns the names of the built-in PII detectors
+func piiDetectorNames() []interface{} {
+	names := make([]interface{}, len(AllPIIDetectors))
+	for i, detector := range AllPIIDetectors {
+		names[i] = string(detector)
+	}
+	return names
+}
+
+// ConfigSchema returns a JSON Schema (draft 2020-12) of configuration files
+// read by LoadConfig: the logger settings, hooks, writers with rotation and
+// custom themes. Durations are integer nanoseconds and levels integers, as
+// in LoggerConfig's JSON form. Checks that need more than the schema, such
+// as compiling regex patterns, are done by ValidateConfig.
+func ConfigSchema() ([]byte, error) {
+	generator := &schemaGenerator{defs: make(map[string]interface{})}
+	schema := map[string]interface{}{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id":     ConfigSchemaID,
+		"title":   "pim logging configuration",
+	}
+	for key, value := range generator.structSchema(reflect.TypeOf(FileConfig{})) {
+		schema[key] = value
+	}
+	schema["$defs"] = generator.defs
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// schemaGenerator derives JSON schemas from Go types following the rules of
+// encoding/json. Named struct types become $defs entries.
+type schemaGenerator struct {
+	defs map[string]interface{}
+}
+
+// schema returns the schema of values of type t
+func (g *schemaGenerator) schema(t reflect.Type) map[string]interface{} {
+	if t == reflect.TypeOf(time.Duration(0)) {
+		return map[string]interface{}{"type": "integer", "description": "Duration in nanoseconds"}
+	}
+	if t == reflect.TypeOf(LogLevel(0)) {
+		return map[string]interface{}{"type": "integer", "minimum": 0, "description": "Log level: 0 panic, 1 error, 2 warning, 3 info, 4 debug, 5 trace"}
+	}
+	if enum, ok := schemaEnums[t]; ok {
+		return map[string]interface{}{"type": "string", "enum": enum}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Ptr:
+		return map[string]interface{}{"anyOf": []interface{}{g.schema(t.Elem()), map[string]interface{}{"type": "null"}}}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		schema := map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+		if kind := t.Key().Kind(); kind >= reflect.Int && kind <= reflect.Uint64 {
+			schema["propertyNames"] = map[string]interface{}{"pattern": "^-?[0-9]+$"}
+		}
+		return schema
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		if _, ok := g.defs[t.Name()]; !ok {
+			g.defs[t.Name()] = true // Placeholder for recursive types
+			g.defs[t.Name()] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+	}
+	return map[string]interface{}{} // Interfaces accept any value
+}
+
+// structSchema returns the object schema of struct type t. Unknown keys are
+// rejected, as ParseConfig does.
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	g.addFields(t, properties)
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+// addFields adds the JSON fields of struct type t to properties, flattening
+// untagged embedded structs like encoding/json
+func (g *schemaGenerator) addFields(t reflect.Type, properties map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.addFields(embedded, properties)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		switch field.Type.Kind() {
+		case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = g.schema(field.Type)
+	}
+}
+
+// ConfigSchemaError is a configuration value that does not match ConfigSchema
+type ConfigSchemaError struct {
+	Path    string // JSON path of the value, e.g. "logger.buffer_size"
+	Message string
+}
+
+// Error implements error interface
+func (e *ConfigSchemaError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// ValidateConfig checks a configuration in the format given by the file
+// extension (".json", ".yaml" or ".yml") against ConfigSchema, then the
+// checks of ParseConfig such as regex patterns and the crypto policy. All
+// schema violations are returned as *ConfigSchemaError values joined with
+// errors.Join, so CI can report every problem at once.
+func ValidateConfig(data []byte, ext string) error {
+	data, err := configJSON(data, ext)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	schemaData, err := ConfigSchema()
+	if err != nil {
+		return err
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(schemaData, &schema); err != nil {
+		return err
+	}
+
+	validator := &schemaValidator{root: schema}
+	validator.validate(schema, document, "")
+	if len(validator.errs) > 0 {
+		return errors.Join(validator.errs...)
+	}
+
+	_, err = ParseConfig(data, ".json")
+	return err
+}
+
+// ValidateConfigFile validates a JSON or YAML configuration file, see
+// ValidateConfig
+func ValidateConfigFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	return ValidateConfig(data, filepath.Ext(path))
+}
+
+// schemaValidator checks documents against the subset of JSON Schema that
+// ConfigSchema uses
+type schemaValidator struct {
+	root map[string]interface{}
+	errs []error
+}
+
+// fail records a violation at path
+func (v *schemaValidator) fail(path, format string, args ...interface{}) {
+	v.errs = append(v.errs, &ConfigSchemaError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// validate checks value against schema and reports whether it matched
+func (v *schemaValidator) validate(schema map[string]interface{}, value interface{}, path string) bool {
+	errCount := len(v.errs)
+
+	if ref, ok := schema["$ref"].(string); ok {
+		defs, _ := v.root["$defs"].(map[string]interface{})
+		target, _ := defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]interface{})
+		return v.validate(target, value, path)
+	}
+
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		for _, option := range anyOf {
+			trial := &schemaValidator{root: v.root}
+			if trial.validate(option.(map[string]interface{}), value, path) {
+				return true
+			}
+		}
+		// Report against the first option, which is the non-null one
+		return v.validate(anyOf[0].(map[string]interface{}), value, path)
+	}
+
+	if kind, ok := schema["type"].(string); ok && !schemaTypeMatches(kind, value) {
+		v.fail(path, "expected %s, got %s", kind, jsonTypeName(value))
+		return false
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if allowed == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.fail(path, "%v is not one of %v", value, enum)
+		}
+	}
+
+	if minimum, ok := schema["minimum"].(float64); ok {
+		if n, ok := value.(json.Number); ok {
+			if f, err := n.Float64(); err == nil && f < minimum {
+				v.fail(path, "%v is less than %v", n, minimum)
+			}
+		}
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			keyPath := joinSchemaPath(path, key)
+			if names, ok := schema["propertyNames"].(map[string]interface{}); ok {
+				if pattern, ok := names["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(key) {
+					v.fail(keyPath, "key does not match %s", pattern)
+				}
+			}
+			if property, ok := properties[key].(map[string]interface{}); ok {
+				v.validate(property, value[key], keyPath)
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					v.fail(keyPath, "unknown field")
+				}
+			case map[string]interface{}:
+				v.validate(additional, value[key], keyPath)
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range value {
+				v.validate(items, item, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	}
+
+	return len(v.errs) == errCount
+}
+
+// schemaTypeMatches reports whether value has the JSON Schema type kind
+func schemaTypeMatches(kind string, value interface{}) bool {
+	switch kind {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := n.Int64()
+		return err == nil
+	}
+	return true
+}
+
+// jsonTypeName names the JSON type of a decoded value
+func jsonTypeName(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// joinSchemaPath appends a key to a JSON path
+func joinSchemaPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,108 @@
+package pim
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigSchema(t *testing.T) {
+	data, err := ConfigSchema()
+	if err != nil {
+		t.Fatalf("ConfigSchema failed: %v", err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("schema is not JSON: %v", err)
+	}
+
+	defs := schema["$defs"].(map[string]interface{})
+	for _, name := range []string{"LoggerConfig", "HookDefinition", "RedactConfig", "WriterDefinition", "RotationConfig", "Theme"} {
+		if _, ok := defs[name]; !ok {
+			t.Errorf("schema lacks $defs/%s", name)
+		}
+	}
+
+	// Embedded HookConfig fields are flattened; json:"-" fields are left out
+	redact := defs["RedactConfig"].(map[string]interface{})["properties"].(map[string]interface{})
+	if _, ok := redact["name"]; !ok {
+		t.Error("embedded hook fields missing from RedactConfig")
+	}
+	if _, ok := redact["HashSalt"]; ok {
+		t.Error("json:\"-\" field in schema")
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	if err := ValidateConfig([]byte(testYAMLConfig), ".yaml"); err != nil {
+		t.Errorf("valid config rejected: %v", err)
+	}
+
+	invalid := `{
+		"logger": {"buffer_size": "big", "field_case": "kebab", "levle": 1},
+		"hooks": [{"redact": {"name": "r", "strategy": "shuffle"}}],
+		"writers": [{"file": {"path": "app.log", "rotation": {"max_size": 1.5}}}]
+	}`
+	err := ValidateConfig([]byte(invalid), ".json")
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	var paths []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var schemaErr *ConfigSchemaError
+		if !errors.As(e, &schemaErr) {
+			t.Fatalf("unexpected error type %T", e)
+		}
+		paths = append(paths, schemaErr.Path)
+	}
+	want := []string{ // Keys are checked in sorted order
+		"hooks[0].redact.strategy",
+		"logger.buffer_size",
+		"logger.field_case",
+		"logger.levle",
+		"writers[0].file.rotation.max_size",
+	}
+	if strings.Join(paths, " ") != strings.Join(want, " ") {
+		t.Errorf("error paths = %v, want %v", paths, want)
+	}
+
+	// Checks beyond the schema come from ParseConfig
+	if err := ValidateConfig([]byte(`{"hooks": [{"redact": {"name": "r", "patterns": {"message": "("}}}]}`), ".json"); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+	if err := ValidateConfig([]byte(`{"writers": [{"name": "w"}]}`), ".json"); err == nil {
+		t.Error("expected error for writer without file or remote")
+	}
+}
+
+func TestFileConfigWriters(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pim.json")
+	logPath := filepath.Join(dir, "app.log")
+	content := `{"logger": {"enable_console": false, "enable_json": true}, "writers": [{"name": "app", "file": {"path": "` + filepath.ToSlash(logPath) + `"}}]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateConfigFile(path); err != nil {
+		t.Fatalf("ValidateConfigFile failed: %v", err)
+	}
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	logger, err := config.NewLogger()
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+	logger.Info("to file", ToWritersOnly("app"))
+	logger.Close()
+
+	data, err := os.ReadFile(logPath)
+	if err != nil || !strings.Contains(string(data), "to file") {
+		t.Errorf("file writer did not receive the entry: %q, %v", data, err)
+	}
+}
@@ -0,0 +1,91 @@
+//go:build js && wasm
+
+package pim
+
+import (
+	"fmt"
+	"syscall/js"
+)
+
+// BrowserConsoleWriter writes log entries to the JavaScript console so they
+// show up in browser devtools with the matching severity. Entries are passed
+// to console.debug/info/warn/error; with EnableJSON the entry is passed as an
+// object that can be expanded in the console, and with an Encoder as its
+// encoded text.
+type BrowserConsoleWriter struct {
+	config  LoggerConfig
+	console js.Value
+}
+
+// NewBrowserConsoleWriter creates a new console.log-backed writer
+func NewBrowserConsoleWriter(config LoggerConfig) *BrowserConsoleWriter {
+	return &BrowserConsoleWriter{
+		config:  config,
+		console: js.Global().Get("console"),
+	}
+}
+
+// Write implements LogWriter interface for browser console output
+func (w *BrowserConsoleWriter) Write(entry CoreLogEntry) error {
+	if w.console.IsUndefined() {
+		return fmt.Errorf("javascript console is not available")
+	}
+
+	method := consoleMethod(entry.Level)
+	if w.console.Get(method).IsUndefined() {
+		method = "log"
+	}
+
+	if w.config.EnableJSON {
+		jsonData, err := w.config.FieldCase.marshalEntry(entry)
+		if err != nil {
+			return fmt.Errorf("failed to marshal log entry: %w", err)
+		}
+		object := js.Global().Get("JSON").Call("parse", string(jsonData))
+		w.console.Call(method, object)
+		return nil
+	}
+
+	if w.config.Encoder != nil {
+		data, err := w.config.Encoder.EncodeEntry(entry)
+		if err != nil {
+			return err
+		}
+		w.console.Call(method, string(data))
+		return nil
+	}
+
+	line := entry.Message
+	if entry.Prefix != "" {
+		line = entry.Prefix + " " + line
+	}
+	if len(entry.Context) > 0 {
+		line += " " + fmt.Sprint(entry.Context)
+	}
+	w.console.Call(method, line)
+	return nil
+}
+
+// consoleMethod maps a log level to the console method with the same severity
+func consoleMethod(level LogLevel) string {
+	switch level {
+	case PanicLevel, ErrorLevel:
+		return "error"
+	case WarningLevel:
+		return "warn"
+	case InfoLevel:
+		return "info"
+	default:
+		return "debug"
+	}
+}
+
+// Close implements LogWriter interface
+func (w *BrowserConsoleWriter) Close() error {
+	return nil
+}
+
+// Flush implements LogWriter interface
+func (w *BrowserConsoleWriter) Flush() error {
+	return nil
+}
@@ -0,0 +1,41 @@
+//go:build js && wasm
+
+package pim
+
+import (
+	"syscall/js"
+	"testing"
+)
+
+func TestBrowserConsoleWriterUsesLevelMethods(t *testing.T) {
+	console := js.Global().Get("console")
+	var calls []string
+	for _, method := range []string{"info", "warn", "error"} {
+		method := method
+		original := console.Get(method)
+		callback := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			calls = append(calls, method+":"+args[0].String())
+			return nil
+		})
+		console.Set(method, callback)
+		defer func() {
+			console.Set(method, original)
+			callback.Release()
+		}()
+	}
+
+	writer := NewBrowserConsoleWriter(LoggerConfig{})
+	writer.Write(CoreLogEntry{Level: InfoLevel, Message: "ready"})
+	writer.Write(CoreLogEntry{Level: WarningLevel, Message: "slow"})
+	writer.Write(CoreLogEntry{Level: ErrorLevel, Message: "failed"})
+
+	expected := []string{"info:ready", "warn:slow", "error:failed"}
+	if len(calls) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Errorf("Expected %s, got %s", expected[i], calls[i])
+		}
+	}
+}
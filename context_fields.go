@@ -0,0 +1,99 @@
+package pim
+
+import (
+	"context"
+)
+
+// contextFieldsKey is the context key for request-scoped fields
+type contextFieldsKey struct{}
+
+// NewContext returns a copy of ctx carrying logger, for retrieval with FromContext
+func NewContext(ctx context.Context, logger *LoggerCore) context.Context {
+	return context.WithValue(ctx, requestLoggerKey{}, logger)
+}
+
+// FromContext returns the logger stored by NewContext or HTTPMiddleware,
+// or nil if ctx carries none
+func FromContext(ctx context.Context) *LoggerCore {
+	if ctx == nil {
+		return nil
+	}
+	logger, _ := ctx.Value(requestLoggerKey{}).(*LoggerCore)
+	return logger
+}
+
+// ContextWithFields returns a copy of ctx carrying fields in addition to any
+// fields already attached. They are merged into entries logged with the *Ctx
+// methods, so request-scoped values travel with ctx through the call stack.
+func ContextWithFields(ctx context.Context, fields map[string]interface{}) context.Context {
+	merged := make(map[string]interface{})
+	for k, v := range ContextFields(ctx) {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, contextFieldsKey{}, merged)
+}
+
+// ContextFields returns the fields attached to ctx with ContextWithFields.
+// The returned map must not be modified.
+func ContextFields(ctx context.Context) map[string]interface{} {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(contextFieldsKey{}).(map[string]interface{})
+	return fields
+}
+
+// LogCtx logs like Log, merging the fields attached to ctx into the entry
+func (l *LoggerCore) LogCtx(ctx context.Context, level LogLevel, prefix, message string, args ...interface{}) {
+	l.logCtx(1, ctx, level, prefix, message, args, false)
+}
+
+// TraceCtx logs a trace message with the fields attached to ctx
+func (l *LoggerCore) TraceCtx(ctx context.Context, msg string, args ...interface{}) {
+	l.logCtx(1, ctx, TraceLevel, TracePrefix, msg, args, false)
+}
+
+// DebugCtx logs a debug message with the fields attached to ctx
+func (l *LoggerCore) DebugCtx(ctx context.Context, msg string, args ...interface{}) {
+	l.logCtx(1, ctx, DebugLevel, DebugPrefix, msg, args, false)
+}
+
+// InfoCtx logs an info message with the fields attached to ctx
+func (l *LoggerCore) InfoCtx(ctx context.Context, msg string, args ...interface{}) {
+	l.logCtx(1, ctx, InfoLevel, InfoPrefix, msg, args, false)
+}
+
+// SuccessCtx logs a success message with the fields attached to ctx
+func (l *LoggerCore) SuccessCtx(ctx context.Context, msg string, args ...interface{}) {
+	l.logCtx(1, ctx, InfoLevel, SuccessPrefix, msg, args, false)
+}
+
+// WarningCtx logs a warning message with the fields attached to ctx
+func (l *LoggerCore) WarningCtx(ctx context.Context, msg string, args ...interface{}) {
+	l.logCtx(1, ctx, WarningLevel, WarningPrefix, msg, args, false)
+}
+
+// ErrorCtx logs an error message with a stack trace and the fields attached to ctx
+func (l *LoggerCore) ErrorCtx(ctx context.Context, msg string, args ...interface{}) {
+	l.logCtx(1, ctx, ErrorLevel, ErrorPrefix, msg, args, true)
+}
+
+// logCtx implements the *Ctx methods for the call site skip frames above
+// its caller, see log. The level is checked before the context fields are
+// looked up, and args is copied so the caller's slice is left alone.
+func (l *LoggerCore) logCtx(skip int, ctx context.Context, level LogLevel, prefix, message string, args []interface{}, stackTrace bool) {
+	if l.belowLevel(skip+1, level, message, args) {
+		return
+	}
+	withFields := make([]interface{}, len(args), len(args)+1)
+	copy(withFields, args)
+	withFields = append(withFields, Fields(ContextFields(ctx)))
+	if stackTrace {
+		l.logWithStackTrace(skip+1, level, prefix, message, withFields)
+		return
+	}
+	l.log(skip+1, level, prefix, message, withFields)
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLoggerWritesTextLines(t *testing.T) {
+	var out bytes.Buffer
+	logger := New(InfoLevel, NewTextWriter(&out))
+	logger.Now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	sensor := logger.With(String("sensor", "temp"))
+	sensor.Info("reading", Int("value", 21), Bool("calibrated", true))
+	sensor.Debug("dropped")
+	logger.Error("read failed", Err(errors.New("timeout")))
+
+	expected := "2024-01-02T03:04:05Z info reading sensor=\"temp\" value=21 calibrated=true\n" +
+		"2024-01-02T03:04:05Z error read failed error=\"timeout\"\n"
+	if out.String() != expected {
+		t.Errorf("Unexpected output:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+}
+
+func TestLoggerWritesFloatFields(t *testing.T) {
+	var out bytes.Buffer
+	logger := New(InfoLevel, NewTextWriter(&out))
+	logger.Now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	logger.Info("reading", Float("ratio", 0.5))
+
+	expected := "2024-01-02T03:04:05Z info reading ratio=0.5\n"
+	if out.String() != expected {
+		t.Errorf("Unexpected output:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+}
+
+func TestFieldValues(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 600, time.UTC)
+	tests := map[string]Field{
+		"2024-01-02T03:04:05.0000006Z": Time("at", at),
+		"1.5":                          Float("ratio", 1.5),
+		"timeout":                      Any("cause", errors.New("timeout")),
+		"1m0s":                         Any("wait", time.Minute),
+		"?":                            Any("data", []int{1}),
+	}
+	for expected, field := range tests {
+		if got := field.Value(); got != expected {
+			t.Errorf("%s: expected %q, got %q", field.Key, expected, got)
+		}
+	}
+	if !Time("at", at).Time().Equal(at) {
+		t.Error("Expected time field to round trip")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for level := PanicLevel; level <= TraceLevel; level++ {
+		parsed, ok := ParseLevel(level.Name())
+		if !ok || parsed != level {
+			t.Errorf("Expected %s to round trip, got %v", level.Name(), parsed)
+		}
+	}
+	if _, ok := ParseLevel("verbose"); ok {
+		t.Error("Expected unknown level name to be rejected")
+	}
+}
+
+func TestRegisterLevel(t *testing.T) {
+	const audit Level = -10
+	if err := RegisterLevel(audit, "audit", "security"); err != nil {
+		t.Fatalf("RegisterLevel failed: %v", err)
+	}
+	if audit.Name() != "audit" {
+		t.Errorf("Expected the registered name, got %q", audit.Name())
+	}
+	for _, name := range []string{"audit", "security"} {
+		if parsed, ok := ParseLevel(name); !ok || parsed != audit {
+			t.Errorf("Expected %q to parse as the registered level, got %v", name, parsed)
+		}
+	}
+
+	// Registering again replaces the aliases
+	if err := RegisterLevel(audit, "audit", "sec"); err != nil {
+		t.Fatalf("RegisterLevel failed to re-register: %v", err)
+	}
+	if _, ok := ParseLevel("security"); ok {
+		t.Error("Expected the replaced alias to be removed")
+	}
+
+	invalid := []struct {
+		level Level
+		name  string
+	}{
+		{InfoLevel, "notice"},
+		{-11, "error"},
+		{-11, "warn"},
+		{-11, "Fatal"},
+		{-11, "has space"},
+		{-11, ""},
+		{-11, "audit"},
+		{audit, "other"},
+	}
+	for _, tt := range invalid {
+		if err := RegisterLevel(tt.level, tt.name); err == nil {
+			t.Errorf("Expected RegisterLevel(%d, %q) to fail", tt.level, tt.name)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package core
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// FieldKind identifies the type of a field value
+type FieldKind uint8
+
+const (
+	StringField FieldKind = iota
+	IntField
+	BoolField
+	FloatField
+	TimeField
+	AnyField
+)
+
+// Field is a typed key/value pair. Fields are built with String, Int, Bool,
+// Float, Time and Err so no reflection is needed to encode them. Any holds
+// other values for encoders that support them; text output shows them only
+// if they implement Stringer or error.
+type Field struct {
+	Key  string
+	Kind FieldKind
+	Str  string
+	Int  int64
+	Any  interface{}
+}
+
+// String creates a string field
+func String(key, value string) Field {
+	return Field{Key: key, Kind: StringField, Str: value}
+}
+
+// Int creates an integer field
+func Int(key string, value int64) Field {
+	return Field{Key: key, Kind: IntField, Int: value}
+}
+
+// Bool creates a boolean field
+func Bool(key string, value bool) Field {
+	f := Field{Key: key, Kind: BoolField}
+	if value {
+		f.Int = 1
+	}
+	return f
+}
+
+// Float creates a floating-point field
+func Float(key string, value float64) Field {
+	return Field{Key: key, Kind: FloatField, Int: int64(math.Float64bits(value))}
+}
+
+// Float returns the value of a FloatField
+func (f Field) Float() float64 {
+	return math.Float64frombits(uint64(f.Int))
+}
+
+// Time creates a time field. The time is kept as UTC nanoseconds.
+func Time(key string, value time.Time) Field {
+	return Field{Key: key, Kind: TimeField, Int: value.UnixNano()}
+}
+
+// Time returns the value of a TimeField
+func (f Field) Time() time.Time {
+	return time.Unix(0, f.Int).UTC()
+}
+
+// Any creates a field holding an arbitrary value
+func Any(key string, value interface{}) Field {
+	return Field{Key: key, Kind: AnyField, Any: value}
+}
+
+// Err creates an "error" field from err
+func Err(err error) Field {
+	if err == nil {
+		return String("error", "<nil>")
+	}
+	return String("error", err.Error())
+}
+
+// Value returns the field value as a string
+func (f Field) Value() string {
+	switch f.Kind {
+	case IntField:
+		return strconv.FormatInt(f.Int, 10)
+	case BoolField:
+		return strconv.FormatBool(f.Int != 0)
+	case FloatField:
+		return strconv.FormatFloat(f.Float(), 'g', -1, 64)
+	case TimeField:
+		return f.Time().Format(time.RFC3339Nano)
+	case AnyField:
+		switch v := f.Any.(type) {
+		case string:
+			return v
+		case error:
+			return v.Error()
+		case interface{ String() string }:
+			return v.String()
+		}
+		return "?"
+	default:
+		return f.Str
+	}
+}
+
+// Entry is a single log entry
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	Fields  []Field
+}
+
+// AppendText appends the entry in "time level message key=value" form to buf
+func (e *Entry) AppendText(buf []byte) []byte {
+	if !e.Time.IsZero() {
+		buf = e.Time.AppendFormat(buf, time.RFC3339)
+		buf = append(buf, ' ')
+	}
+	buf = append(buf, e.Level.Name()...)
+	buf = append(buf, ' ')
+	buf = append(buf, e.Message...)
+	for _, field := range e.Fields {
+		buf = append(buf, ' ')
+		buf = append(buf, field.Key...)
+		buf = append(buf, '=')
+		if field.Kind == StringField {
+			buf = strconv.AppendQuote(buf, field.Str)
+		} else {
+			buf = append(buf, field.Value()...)
+		}
+	}
+	return buf
+}
@@ -0,0 +1,141 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Level controls which entries are logged
+type Level int
+
+const (
+	// PanicLevel logs and then calls panic()
+	PanicLevel Level = iota
+	// ErrorLevel indicates error conditions
+	ErrorLevel
+	// WarningLevel indicates potentially harmful situations
+	WarningLevel
+	// InfoLevel indicates general operational information
+	InfoLevel
+	// DebugLevel indicates detailed debug information
+	DebugLevel
+	// TraceLevel indicates the most detailed debugging information
+	TraceLevel
+)
+
+// Name returns the lowercase name of the level
+func (l Level) Name() string {
+	switch l {
+	case PanicLevel:
+		return "panic"
+	case ErrorLevel:
+		return "error"
+	case WarningLevel:
+		return "warning"
+	case InfoLevel:
+		return "info"
+	case DebugLevel:
+		return "debug"
+	case TraceLevel:
+		return "trace"
+	}
+	levelNamesMu.RLock()
+	name, ok := levelNames[l]
+	levelNamesMu.RUnlock()
+	if ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseLevel returns the level with the given lowercase name
+func ParseLevel(name string) (Level, bool) {
+	if level, ok := builtinLevel(name); ok {
+		return level, true
+	}
+	levelNamesMu.RLock()
+	level, ok := levelsByName[name]
+	levelNamesMu.RUnlock()
+	if ok {
+		return level, true
+	}
+	return InfoLevel, false
+}
+
+// levelNames and levelsByName hold the levels added with RegisterLevel
+var (
+	levelNames   = map[Level]string{}
+	levelsByName = map[string]Level{}
+	levelNamesMu sync.RWMutex
+)
+
+// RegisterLevel names a custom level so Name and ParseLevel know it, e.g.
+// a fatal level below PanicLevel. Levels are ordered by value: levels below
+// PanicLevel are more severe than panics and levels above TraceLevel more
+// verbose than traces. Names are lowercase letters, digits, '-' and '_';
+// aliases are other names ParseLevel accepts.
+//
+// The built-in levels and names can't be registered. Registering a level
+// again replaces its aliases but must use the same name.
+func RegisterLevel(level Level, name string, aliases ...string) error {
+	if level >= PanicLevel && level <= TraceLevel {
+		return fmt.Errorf("level %d is the built-in %s level", int(level), level.Name())
+	}
+	names := append([]string{name}, aliases...)
+	for _, n := range names {
+		if !validLevelName(n) {
+			return fmt.Errorf("invalid level name %q", n)
+		}
+		if builtin, ok := builtinLevel(n); ok {
+			return fmt.Errorf("level name %q is taken by the built-in %s level", n, builtin.Name())
+		}
+	}
+
+	levelNamesMu.Lock()
+	defer levelNamesMu.Unlock()
+	if previous, ok := levelNames[level]; ok && previous != name {
+		return fmt.Errorf("level %d is already registered as %q", int(level), previous)
+	}
+	for _, n := range names {
+		if other, ok := levelsByName[n]; ok && other != level {
+			return fmt.Errorf("level name %q is already registered for level %d", n, int(other))
+		}
+	}
+	for n, l := range levelsByName {
+		if l == level {
+			delete(levelsByName, n)
+		}
+	}
+	levelNames[level] = name
+	for _, n := range names {
+		levelsByName[n] = level
+	}
+	return nil
+}
+
+// builtinLevel returns the built-in level with the given name
+func builtinLevel(name string) (Level, bool) {
+	for level := PanicLevel; level <= TraceLevel; level++ {
+		if level.Name() == name {
+			return level, true
+		}
+	}
+	if name == "warn" {
+		return WarningLevel, true
+	}
+	return InfoLevel, false
+}
+
+// validLevelName reports whether name can name a level. Names end up in
+// file names and metric labels, so they are kept to a safe alphabet.
+func validLevelName(name string) bool {
+	if name == "" || name == "unknown" {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+// Package core is pim's minimal logging core: levels, entries and the writer
+// interface without reflection, templating or color dependencies. It compiles
+// under TinyGo for embedded targets; the full-featured pim package layers on
+// top of it and shares its levels.
+package core
+
+import (
+	"time"
+)
+
+// Logger writes entries at or above its level to its writers
+type Logger struct {
+	level   Level
+	writers []Writer
+	fields  []Field
+
+	// Now returns the entry time; set to nil on targets without a clock
+	Now func() time.Time
+}
+
+// New creates a new logger
+func New(level Level, writers ...Writer) *Logger {
+	return &Logger{
+		level:   level,
+		writers: writers,
+		Now:     time.Now,
+	}
+}
+
+// SetLevel sets the minimum level that is logged
+func (l *Logger) SetLevel(level Level) {
+	l.level = level
+}
+
+// Enabled reports whether entries at level are logged
+func (l *Logger) Enabled(level Level) bool {
+	return level <= l.level
+}
+
+// AddWriter adds an output destination
+func (l *Logger) AddWriter(writer Writer) {
+	l.writers = append(l.writers, writer)
+}
+
+// With returns a logger that adds fields to every entry
+func (l *Logger) With(fields ...Field) *Logger {
+	child := *l
+	child.fields = make([]Field, 0, len(l.fields)+len(fields))
+	child.fields = append(child.fields, l.fields...)
+	child.fields = append(child.fields, fields...)
+	return &child
+}
+
+// Log writes an entry to all writers; the first writer error is returned
+func (l *Logger) Log(level Level, msg string, fields ...Field) error {
+	if !l.Enabled(level) {
+		return nil
+	}
+
+	entry := Entry{Level: level, Message: msg}
+	if l.Now != nil {
+		entry.Time = l.Now()
+	}
+	if len(l.fields) > 0 {
+		entry.Fields = make([]Field, 0, len(l.fields)+len(fields))
+		entry.Fields = append(entry.Fields, l.fields...)
+		entry.Fields = append(entry.Fields, fields...)
+	} else {
+		entry.Fields = fields
+	}
+
+	var firstErr error
+	for _, writer := range l.writers {
+		if err := writer.Write(&entry); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if level == PanicLevel {
+		panic(msg)
+	}
+	return firstErr
+}
+
+// Error logs at ErrorLevel
+func (l *Logger) Error(msg string, fields ...Field) {
+	l.Log(ErrorLevel, msg, fields...)
+}
+
+// Warning logs at WarningLevel
+func (l *Logger) Warning(msg string, fields ...Field) {
+	l.Log(WarningLevel, msg, fields...)
+}
+
+// Info logs at InfoLevel
+func (l *Logger) Info(msg string, fields ...Field) {
+	l.Log(InfoLevel, msg, fields...)
+}
+
+// Debug logs at DebugLevel
+func (l *Logger) Debug(msg string, fields ...Field) {
+	l.Log(DebugLevel, msg, fields...)
+}
+
+// Trace logs at TraceLevel
+func (l *Logger) Trace(msg string, fields ...Field) {
+	l.Log(TraceLevel, msg, fields...)
+}
+
+// Flush flushes all writers
+func (l *Logger) Flush() error {
+	var firstErr error
+	for _, writer := range l.writers {
+		if err := writer.Flush(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"io"
+	"sync"
+)
+
+// Writer is an output destination for entries
+type Writer interface {
+	Write(entry *Entry) error
+	Close() error
+	Flush() error
+}
+
+// TextWriter writes entries as text lines to an io.Writer such as a UART
+type TextWriter struct {
+	out io.Writer
+	buf []byte
+	mu  sync.Mutex
+}
+
+// NewTextWriter creates a new text writer
+func NewTextWriter(out io.Writer) *TextWriter {
+	return &TextWriter{out: out, buf: make([]byte, 0, 128)}
+}
+
+// Write implements Writer interface
+func (w *TextWriter) Write(entry *Entry) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Reuse the line buffer to avoid allocating per entry
+	w.buf = entry.AppendText(w.buf[:0])
+	w.buf = append(w.buf, '\n')
+	_, err := w.out.Write(w.buf)
+	return err
+}
+
+// Close implements Writer interface
+func (w *TextWriter) Close() error {
+	if closer, ok := w.out.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Flush implements Writer interface
+func (w *TextWriter) Flush() error {
+	if flusher, ok := w.out.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
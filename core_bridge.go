@@ -0,0 +1,73 @@
+package pim
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/refactorroom/pim/core"
+)
+
+// ToCoreEntry converts an entry to the minimal core representation.
+// Context values become fields sorted by key; integers, floats, booleans and
+// times keep their kind and everything else is formatted as a string.
+func ToCoreEntry(entry CoreLogEntry) core.Entry {
+	keys := make([]string, 0, len(entry.Context))
+	for key := range entry.Context {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fields := make([]core.Field, 0, len(keys))
+	for _, key := range keys {
+		switch v := entry.Context[key].(type) {
+		case int:
+			fields = append(fields, core.Int(key, int64(v)))
+		case int64:
+			fields = append(fields, core.Int(key, v))
+		case float64:
+			fields = append(fields, core.Float(key, v))
+		case bool:
+			fields = append(fields, core.Bool(key, v))
+		case time.Time:
+			fields = append(fields, core.Time(key, v))
+		case string:
+			fields = append(fields, core.String(key, v))
+		default:
+			fields = append(fields, core.String(key, fmt.Sprint(v)))
+		}
+	}
+
+	return core.Entry{
+		Time:    entry.Timestamp,
+		Level:   entry.Level,
+		Message: entry.Message,
+		Fields:  fields,
+	}
+}
+
+// CoreWriter adapts a core.Writer so it can be added to a LoggerCore
+type CoreWriter struct {
+	writer core.Writer
+}
+
+// NewCoreWriter wraps a minimal core writer as a LogWriter
+func NewCoreWriter(writer core.Writer) *CoreWriter {
+	return &CoreWriter{writer: writer}
+}
+
+// Write implements LogWriter interface
+func (w *CoreWriter) Write(entry CoreLogEntry) error {
+	coreEntry := ToCoreEntry(entry)
+	return w.writer.Write(&coreEntry)
+}
+
+// Close implements LogWriter interface
+func (w *CoreWriter) Close() error {
+	return w.writer.Close()
+}
+
+// Flush implements LogWriter interface
+func (w *CoreWriter) Flush() error {
+	return w.writer.Flush()
+}
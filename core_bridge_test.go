@@ -0,0 +1,21 @@
+package pim
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/refactorroom/pim/core"
+)
+
+func TestCoreWriterAdapter(t *testing.T) {
+	var out bytes.Buffer
+	logger := NewLoggerCore(LoggerConfig{Level: InfoLevel})
+	logger.AddWriter(NewCoreWriter(core.NewTextWriter(&out)))
+
+	logger.InfoWithFields("device online", map[string]interface{}{"id": 7, "ok": true, "fw": "1.2"})
+
+	expected := `info device online fw="1.2" id=7 ok=true`
+	if !bytes.Contains(out.Bytes(), []byte(expected)) {
+		t.Errorf("Expected %q in core writer output, got: %q", expected, out.String())
+	}
+}
@@ -0,0 +1,116 @@
+package pim
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"sync/atomic"
+)
+
+// HashAlgorithm names the hash function used by pim's integrity and
+// pseudonymization features
+type HashAlgorithm string
+
+const (
+	HashSHA256 HashAlgorithm = "sha256" // Default
+	HashSHA384 HashAlgorithm = "sha384"
+	HashSHA512 HashAlgorithm = "sha512"
+	HashSHA1   HashAlgorithm = "sha1" // For compatibility with existing data only
+	HashMD5    HashAlgorithm = "md5"  // For compatibility with existing data only
+)
+
+// fipsHashes are the hash algorithms approved under FIPS 140-3
+var fipsHashes = map[HashAlgorithm]bool{
+	HashSHA256: true,
+	HashSHA384: true,
+	HashSHA512: true,
+}
+
+// FIPSApproved reports whether the algorithm is approved under FIPS 140-3
+// for new integrity data. The empty value means HashSHA256.
+func (h HashAlgorithm) FIPSApproved() bool {
+	return fipsHashes[h.orDefault()]
+}
+
+// Validate checks that the algorithm is known, and approved if FIPS mode is on
+func (h HashAlgorithm) Validate() error {
+	return h.validate(FIPSMode())
+}
+
+// validate checks that the algorithm is known, and approved if fips is set
+func (h HashAlgorithm) validate(fips bool) error {
+	switch h.orDefault() {
+	case HashSHA256, HashSHA384, HashSHA512, HashSHA1, HashMD5:
+	default:
+		return fmt.Errorf("unknown hash algorithm %q", h)
+	}
+	if fips && !h.FIPSApproved() {
+		return fmt.Errorf("hash algorithm %q is not FIPS-approved", h)
+	}
+	return nil
+}
+
+// New returns a new hash.Hash for the algorithm after validating it
+func (h HashAlgorithm) New() (hash.Hash, error) {
+	if err := h.Validate(); err != nil {
+		return nil, err
+	}
+	switch h.orDefault() {
+	case HashSHA384:
+		return sha512.New384(), nil
+	case HashSHA512:
+		return sha512.New(), nil
+	case HashSHA1:
+		return sha1.New(), nil
+	case HashMD5:
+		return md5.New(), nil
+	default:
+		return sha256.New(), nil
+	}
+}
+
+// orDefault returns HashSHA256 for the empty algorithm
+func (h HashAlgorithm) orDefault() HashAlgorithm {
+	if h == "" {
+		return HashSHA256
+	}
+	return h
+}
+
+// fipsMode is set by SetFIPSMode
+var fipsMode atomic.Bool
+
+// SetFIPSMode restricts pim's cryptographic features to FIPS-approved
+// algorithms: SHA-256/384/512 for hashing and AES-GCM for encryption.
+// Features then reject other algorithms when they are configured, so a
+// non-compliant setup fails at startup rather than producing data.
+//
+// FIPS mode is also on whenever the Go runtime runs its FIPS 140 module
+// (GODEBUG=fips140=on, Go 1.24+). pim only uses the standard library's
+// crypto packages, so builds with GOEXPERIMENT=boringcrypto route all of its
+// hashing and encryption through BoringCrypto without changes.
+func SetFIPSMode(enabled bool) {
+	fipsMode.Store(enabled)
+}
+
+// FIPSMode reports whether pim only allows FIPS-approved algorithms
+func FIPSMode() bool {
+	return fipsMode.Load() || runtimeFIPS()
+}
+
+// fipsMode reports whether features configured with c only allow
+// FIPS-approved algorithms: if c.FIPSMode or the process FIPS mode is on
+func (c LoggerConfig) fipsMode() bool {
+	return c.FIPSMode || FIPSMode()
+}
+
+// ValidateCrypto checks the cryptographic settings of the config, rejecting
+// non-approved algorithms if config.FIPSMode or the process FIPS mode is on.
+// ParseConfig and NewCheckedLoggerCore call it, and NewLoggerCore reports
+// its error on stderr.
+func (c LoggerConfig) ValidateCrypto() error {
+	return c.HashAlgorithm.validate(c.fipsMode())
+}
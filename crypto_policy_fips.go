@@ -0,0 +1,10 @@
+//go:build go1.24
+
+package pim
+
+import "crypto/fips140"
+
+// runtimeFIPS reports whether the Go FIPS 140 module is enabled
+func runtimeFIPS() bool {
+	return fips140.Enabled()
+}
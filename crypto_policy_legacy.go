@@ -0,0 +1,9 @@
+//go:build !go1.24
+
+package pim
+
+// runtimeFIPS reports whether the Go FIPS 140 module is enabled; it does not
+// exist before Go 1.24
+func runtimeFIPS() bool {
+	return false
+}
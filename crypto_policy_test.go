@@ -0,0 +1,126 @@
+package pim
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHashAlgorithmNew(t *testing.T) {
+	sizes := map[HashAlgorithm]int{
+		"":         32,
+		HashSHA256: 32,
+		HashSHA384: 48,
+		HashSHA512: 64,
+		HashSHA1:   20,
+		HashMD5:    16,
+	}
+	for algorithm, size := range sizes {
+		h, err := algorithm.New()
+		if err != nil {
+			t.Fatalf("%q: %v", algorithm, err)
+		}
+		if h.Size() != size {
+			t.Errorf("%q: expected size %d, got %d", algorithm, size, h.Size())
+		}
+	}
+
+	if _, err := HashAlgorithm("crc32").New(); err == nil {
+		t.Error("expected error for unknown algorithm")
+	}
+}
+
+func TestFIPSModeRejectsNonApproved(t *testing.T) {
+	if runtimeFIPS() {
+		t.Skip("Go FIPS 140 mode is on")
+	}
+	defer SetFIPSMode(false)
+
+	if err := HashSHA1.Validate(); err != nil {
+		t.Fatalf("sha1 should be allowed outside FIPS mode: %v", err)
+	}
+
+	SetFIPSMode(true)
+	if !FIPSMode() {
+		t.Fatal("expected FIPS mode on")
+	}
+	for _, algorithm := range []HashAlgorithm{HashSHA1, HashMD5} {
+		if _, err := algorithm.New(); err == nil || !strings.Contains(err.Error(), "not FIPS-approved") {
+			t.Errorf("%q: expected FIPS error, got %v", algorithm, err)
+		}
+	}
+	if _, err := HashSHA384.New(); err != nil {
+		t.Errorf("sha384 should be allowed in FIPS mode: %v", err)
+	}
+
+	if _, err := NewScrubber(ScrubConfig{Subject: "alice", HashSalt: "pepper", HashAlgorithm: HashMD5}); err == nil {
+		t.Error("expected scrubber to reject md5 in FIPS mode")
+	}
+	scrubber, err := NewScrubber(ScrubConfig{Subject: "alice", HashSalt: "pepper", HashAlgorithm: HashSHA512})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := scrubber.Report()
+	if report.HashAlgorithm != HashSHA512 || len(report.SubjectHash) != 128 {
+		t.Errorf("unexpected report hash %s %q", report.HashAlgorithm, report.SubjectHash)
+	}
+}
+
+func TestParseConfigFIPSMode(t *testing.T) {
+	if runtimeFIPS() {
+		t.Skip("Go FIPS 140 mode is on")
+	}
+	defer SetFIPSMode(false)
+
+	if _, err := ParseConfig([]byte(`{"logger": {"hash_algorithm": "md5"}}`), ".json"); err != nil {
+		t.Fatalf("md5 should be accepted without fips_mode: %v", err)
+	}
+	_, err := ParseConfig([]byte(`{"logger": {"fips_mode": true, "hash_algorithm": "md5"}}`), ".json")
+	if err == nil || !strings.Contains(err.Error(), "not FIPS-approved") {
+		t.Fatalf("expected FIPS error, got %v", err)
+	}
+	if _, err := ParseConfig([]byte(`{"logger": {"fips_mode": true, "hash_algorithm": "sha384"}}`), ".json"); err != nil {
+		t.Fatal(err)
+	}
+	if FIPSMode() {
+		t.Error("parsing a config must not change the process FIPS mode")
+	}
+
+	config := DefaultLoggerConfig
+	config.EnableConsole = false
+	config.FIPSMode = true
+	logger := NewLoggerCore(config)
+	defer logger.Close()
+	if FIPSMode() {
+		t.Error("a logger with FIPSMode must not change the process FIPS mode")
+	}
+	if err := logger.AddRedactHook(RedactConfig{Strategy: RedactHash, HashAlgorithm: HashSHA1, HashSalt: "pepper"}); err == nil || !strings.Contains(err.Error(), "not FIPS-approved") {
+		t.Errorf("expected the logger to reject sha1 redaction, got %v", err)
+	}
+	other := NewLoggerCore(DefaultLoggerConfig)
+	defer other.Close()
+	if err := other.AddRedactHook(RedactConfig{Strategy: RedactHash, HashAlgorithm: HashSHA1, HashSalt: "pepper"}); err != nil {
+		t.Errorf("expected other loggers to allow sha1 redaction, got %v", err)
+	}
+	if _, err := NewSignedFileWriter(filepath.Join(t.TempDir(), "app.log"), config, RotationConfig{}, SigningConfig{Key: []byte("k"), HashAlgorithm: HashSHA1}); err == nil {
+		t.Error("expected signed writers of a FIPSMode config to reject sha1")
+	}
+
+	// Non-approved algorithms are reported when the logger is created
+	stderr := os.Stderr
+	r, w, _ := os.Pipe()
+	os.Stderr = w
+	config.HashAlgorithm = HashMD5
+	if _, err := NewCheckedLoggerCore(config); err == nil || !strings.Contains(err.Error(), "not FIPS-approved") {
+		t.Errorf("expected NewCheckedLoggerCore to reject md5, got %v", err)
+	}
+	NewLoggerCore(config).Close()
+	w.Close()
+	os.Stderr = stderr
+	output, _ := io.ReadAll(r)
+	if !strings.Contains(string(output), "not FIPS-approved") {
+		t.Errorf("expected the FIPS error on stderr, got %q", output)
+	}
+}
@@ -0,0 +1,131 @@
+package pim
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+	"github.com/refactorroom/pim/core"
+)
+
+// LevelOptions describes how a level added with RegisterLevel is shown
+type LevelOptions struct {
+	Prefix    string       // Console prefix like InfoPrefix; defaults to Icon and the uppercase name in Color
+	Color     *color.Color // Level color of the theme formatters; defaults to the theme's Info color
+	Icon      string       // Icon of the theme formatters; defaults to ASCIIIcon
+	ASCIIIcon string       // Icon in IconModeASCII; defaults to the first letter, e.g. "[N]"
+	Aliases   []string     // Other names accepted by ParseLevel
+}
+
+// customLevel is a level added with RegisterLevel
+type customLevel struct {
+	name string
+	LevelOptions
+}
+
+// customLevels holds the levels added with RegisterLevel
+var (
+	customLevels   = map[LogLevel]customLevel{}
+	customLevelsMu sync.RWMutex
+)
+
+// RegisterLevel adds a level with the given name and value, e.g.
+//
+//	var Fatal, _ = pim.RegisterLevel("fatal", -1, pim.LevelOptions{Icon: "☠️", Color: pim.HiRed})
+//	var Verbose, _ = pim.RegisterLevel("verbose", 6, pim.LevelOptions{Aliases: []string{"v"}})
+//
+//	logger.LogAt(Fatal, "disk full")
+//
+// Levels are filtered by value like the built-in ones: values below
+// PanicLevel are more severe than panics, values above TraceLevel more
+// verbose than traces. The name is used for LevelString, ParseLevel and
+// the level flags, the options for the console prefix and the theme
+// formatters. Names are case-insensitive and limited to letters, digits,
+// '-' and '_'.
+//
+// The built-in levels and names can't be registered. Registering a level
+// again with the same name replaces its options.
+func RegisterLevel(name string, value LogLevel, opts LevelOptions) (LogLevel, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	aliases := make([]string, len(opts.Aliases))
+	for i, alias := range opts.Aliases {
+		aliases[i] = strings.ToLower(strings.TrimSpace(alias))
+	}
+	if err := core.RegisterLevel(value, name, aliases...); err != nil {
+		return value, err
+	}
+
+	upper := strings.ToUpper(name)
+	if opts.ASCIIIcon == "" {
+		opts.ASCIIIcon = "[" + upper[:1] + "]"
+	}
+	if opts.Icon == "" {
+		opts.Icon = opts.ASCIIIcon
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = colorize(opts.Color, fmt.Sprintf("%s %-9s", opts.Icon, upper))
+	}
+	opts.Aliases = aliases
+
+	customLevelsMu.Lock()
+	defer customLevelsMu.Unlock()
+	customLevels[value] = customLevel{name: name, LevelOptions: opts}
+	return value, nil
+}
+
+// lookupLevel returns the level added with RegisterLevel
+func lookupLevel(level LogLevel) (customLevel, bool) {
+	customLevelsMu.RLock()
+	defer customLevelsMu.RUnlock()
+	custom, ok := customLevels[level]
+	return custom, ok
+}
+
+// registeredLevels returns the levels added with RegisterLevel
+func registeredLevels() []customLevel {
+	customLevelsMu.RLock()
+	defer customLevelsMu.RUnlock()
+	levels := make([]customLevel, 0, len(customLevels))
+	for _, custom := range customLevels {
+		levels = append(levels, custom)
+	}
+	return levels
+}
+
+// LevelPrefix returns the console prefix of level: InfoPrefix for InfoLevel
+// and so on, or the prefix of a level added with RegisterLevel
+func LevelPrefix(level LogLevel) string {
+	switch level {
+	case PanicLevel:
+		return PanicPrefix
+	case ErrorLevel:
+		return ErrorPrefix
+	case WarningLevel:
+		return WarningPrefix
+	case InfoLevel:
+		return InfoPrefix
+	case DebugLevel:
+		return DebugPrefix
+	case TraceLevel:
+		return TracePrefix
+	}
+	if custom, ok := lookupLevel(level); ok {
+		return custom.Prefix
+	}
+	return ""
+}
+
+// LogAt logs a message at level with the level's prefix, see LevelPrefix.
+// It is mostly useful for levels added with RegisterLevel.
+func (l *LoggerCore) LogAt(level LogLevel, msg string, args ...interface{}) {
+	l.log(1, level, LevelPrefix(level), msg, args)
+}
+
+// colorize returns s in c, or s if c is nil
+func colorize(c *color.Color, s string) string {
+	if c == nil {
+		return s
+	}
+	return c.Sprint(s)
+}
@@ -0,0 +1,84 @@
+package pim
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestRegisterLevel(t *testing.T) {
+	fatal, err := RegisterLevel("Fatal", -1, LevelOptions{Icon: "☠️", Color: HiRed, Aliases: []string{"crit"}})
+	if err != nil {
+		t.Fatalf("RegisterLevel failed: %v", err)
+	}
+	chatty, err := RegisterLevel("chatty", TraceLevel+1, LevelOptions{})
+	if err != nil {
+		t.Fatalf("RegisterLevel failed: %v", err)
+	}
+
+	if fatal.Name() != "fatal" {
+		t.Errorf("expected the lowercase name, got %q", fatal.Name())
+	}
+	for _, name := range []string{"FATAL", "crit"} {
+		if level, err := ParseLevel(name); err != nil || level != fatal {
+			t.Errorf("expected %q to parse as the fatal level, got %v, %v", name, level, err)
+		}
+	}
+	if LevelPrefix(chatty) != "[C] CHATTY   " {
+		t.Errorf("unexpected default prefix %q", LevelPrefix(chatty))
+	}
+
+	if _, err := RegisterLevel("notice", InfoLevel, LevelOptions{}); err == nil {
+		t.Error("expected a built-in value to be rejected")
+	}
+	if _, err := RegisterLevel("warn", -2, LevelOptions{}); err == nil {
+		t.Error("expected a built-in name to be rejected")
+	}
+
+	logger, buffer := newTestLogger(t, nil)
+
+	if !logger.SetLevelFromString("fatal") {
+		t.Fatal("expected SetLevelFromString to accept a registered level")
+	}
+	logger.LogAt(fatal, "disk full")
+	logger.LogAt(chatty, "dropped")
+	logger.Error("dropped too")
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 1 {
+		t.Fatalf("expected only the fatal entry, got %d entries", len(entries))
+	}
+	if entries[0].LevelString != "fatal" || entries[0].Prefix != LevelPrefix(fatal) {
+		t.Errorf("unexpected entry %q %q", entries[0].LevelString, entries[0].Prefix)
+	}
+}
+
+func TestCustomLevelTheme(t *testing.T) {
+	originalNoColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = originalNoColor }()
+
+	audit, err := RegisterLevel("audit", -3, LevelOptions{Icon: "🛡️", ASCIIIcon: "[A]", Color: Purple})
+	if err != nil {
+		t.Fatalf("RegisterLevel failed: %v", err)
+	}
+
+	tm := NewThemeManager()
+	theme := tm.GetTheme()
+	entry := CoreLogEntry{Level: audit, LevelString: audit.Name(), Prefix: LevelPrefix(audit), Message: "role changed"}
+	if got := tm.levelLabel(entry, theme); got != "🛡️ AUDIT" {
+		t.Errorf("unexpected label %q", got)
+	}
+	if tm.getLevelColor(audit, theme) != Purple {
+		t.Error("expected the level color in themes")
+	}
+	tm.SetIconMode(IconModeASCII)
+	if got := tm.levelLabel(entry, theme); got != "[A] AUDIT" {
+		t.Errorf("unexpected ASCII label %q", got)
+	}
+
+	data, _ := NewTextEncoder(LoggerConfig{IconMode: IconModeASCII}, TextPrefix).EncodeEntry(entry)
+	if string(data) != "[A] AUDIT     role changed" {
+		t.Errorf("expected the prefix to be substituted, got %q", data)
+	}
+}
@@ -0,0 +1,173 @@
+package pim
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultDeadLetterCapacity is the number of failed entries kept in memory
+// by a DeadLetterQueue
+const DefaultDeadLetterCapacity = 10000
+
+// DeadLetter is an entry a writer failed to deliver
+type DeadLetter struct {
+	Entry    CoreLogEntry `json:"entry"`
+	Writer   string       `json:"writer"` // Type of the writer that failed
+	Error    string       `json:"error"`
+	FailedAt time.Time    `json:"failed_at"`
+}
+
+// DeadLetterConfig configures a dead-letter queue
+type DeadLetterConfig struct {
+	// Writer receives failed entries, e.g. a FileWriter, so they survive
+	// restarts. Its own failures are written to stderr.
+	Writer LogWriter `json:"-"`
+
+	// OnDeliveryFailure is called with each failed batch. It runs on the
+	// failing writer's goroutine, so it must not log through that writer.
+	OnDeliveryFailure func(entries []CoreLogEntry, err error) `json:"-"`
+
+	// Capacity is the number of failed entries kept for Entries and Replay;
+	// the oldest are discarded first (default: DefaultDeadLetterCapacity)
+	Capacity int `json:"capacity"`
+}
+
+// DeadLetterQueue collects entries that writers failed to deliver after
+// exhausting their retries, instead of losing them. Pass it to a
+// RemoteWriter with RemoteWriterConfig.DeadLetter or wrap any writer with
+// WithDeadLetter, then query the failures with Entries and resend them with
+// Replay once the destination is back.
+type DeadLetterQueue struct {
+	config  DeadLetterConfig
+	mu      sync.Mutex
+	letters []DeadLetter
+	dropped uint64 // Letters discarded because the queue was full
+}
+
+// NewDeadLetterQueue creates a dead-letter queue
+func NewDeadLetterQueue(config DeadLetterConfig) *DeadLetterQueue {
+	if config.Capacity <= 0 {
+		config.Capacity = DefaultDeadLetterCapacity
+	}
+	return &DeadLetterQueue{config: config}
+}
+
+// Add records entries that writer failed to deliver with err
+func (q *DeadLetterQueue) Add(writer string, entries []CoreLogEntry, err error) {
+	if len(entries) == 0 {
+		return
+	}
+	now := time.Now().UTC()
+
+	q.mu.Lock()
+	for _, entry := range entries {
+		q.letters = append(q.letters, DeadLetter{Entry: entry, Writer: writer, Error: err.Error(), FailedAt: now})
+	}
+	if excess := len(q.letters) - q.config.Capacity; excess > 0 {
+		q.letters = append(q.letters[:0:0], q.letters[excess:]...)
+		q.dropped += uint64(excess)
+	}
+	q.mu.Unlock()
+
+	if q.config.Writer != nil {
+		for _, entry := range entries {
+			if werr := q.config.Writer.Write(entry); werr != nil {
+				fmt.Fprintf(os.Stderr, "Failed to write dead letter: %v\n", werr)
+			}
+		}
+		if werr := q.config.Writer.Flush(); werr != nil {
+			fmt.Fprintf(os.Stderr, "Failed to flush dead letters: %v\n", werr)
+		}
+	}
+	if q.config.OnDeliveryFailure != nil {
+		q.config.OnDeliveryFailure(entries, err)
+	}
+}
+
+// Entries returns the kept failed entries, oldest first
+func (q *DeadLetterQueue) Entries() []DeadLetter {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	letters := make([]DeadLetter, len(q.letters))
+	copy(letters, q.letters)
+	return letters
+}
+
+// Len returns the number of kept failed entries
+func (q *DeadLetterQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.letters)
+}
+
+// Dropped returns the number of failed entries discarded because the queue
+// was full
+func (q *DeadLetterQueue) Dropped() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.dropped
+}
+
+// Replay writes the kept entries to writer, oldest first, and flushes it.
+// Entries are removed from the queue once written; replay stops at the
+// first error, leaving that entry and later ones queued. It returns the
+// number of entries replayed.
+func (q *DeadLetterQueue) Replay(writer LogWriter) (int, error) {
+	q.mu.Lock()
+	letters := q.letters
+	q.letters = nil
+	q.mu.Unlock()
+
+	replayed := 0
+	var err error
+	for _, letter := range letters {
+		if err = writer.Write(letter.Entry); err != nil {
+			break
+		}
+		replayed++
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+
+	if replayed < len(letters) {
+		// Put back what was not replayed ahead of letters added meanwhile
+		q.mu.Lock()
+		q.letters = append(letters[replayed:len(letters):len(letters)], q.letters...)
+		q.mu.Unlock()
+	}
+	if err != nil {
+		return replayed, fmt.Errorf("failed to replay dead letters: %w", err)
+	}
+	return replayed, nil
+}
+
+// Clear discards the kept entries
+func (q *DeadLetterQueue) Clear() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.letters = nil
+}
+
+// deadLetterWriter sends entries its writer fails to write to a queue
+type deadLetterWriter struct {
+	LogWriter
+	queue *DeadLetterQueue
+}
+
+// WithDeadLetter wraps writer so entries it fails to write are added to
+// queue. Write still returns the error so it is reported and counted.
+func WithDeadLetter(writer LogWriter, queue *DeadLetterQueue) LogWriter {
+	return &deadLetterWriter{LogWriter: writer, queue: queue}
+}
+
+// Write implements LogWriter interface
+func (w *deadLetterWriter) Write(entry CoreLogEntry) error {
+	err := w.LogWriter.Write(entry)
+	if err != nil {
+		w.queue.Add(writerTypeName(w.LogWriter), []CoreLogEntry{entry}, err)
+	}
+	return err
+}
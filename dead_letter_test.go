@@ -0,0 +1,108 @@
+package pim
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// flakyWriter fails while down is set
+type flakyWriter struct {
+	*BufferWriter
+	down bool
+}
+
+func (w *flakyWriter) Write(entry CoreLogEntry) error {
+	if w.down {
+		return errors.New("connection refused")
+	}
+	return w.BufferWriter.Write(entry)
+}
+
+func TestDeadLetterQueueReplay(t *testing.T) {
+	config := DefaultLoggerConfig
+	config.EnableConsole = false
+	primary := &flakyWriter{BufferWriter: NewBufferWriter(config, 10), down: true}
+	secondary := NewBufferWriter(config, 10)
+
+	var failures int
+	queue := NewDeadLetterQueue(DeadLetterConfig{
+		Writer:            secondary,
+		OnDeliveryFailure: func(entries []CoreLogEntry, err error) { failures += len(entries) },
+	})
+
+	logger := NewLoggerCore(config)
+	logger.AddWriter(WithDeadLetter(primary, queue))
+	logger.Info("one")
+	logger.Info("two")
+
+	letters := queue.Entries()
+	if len(letters) != 2 || letters[0].Entry.Message != "one" || letters[0].Error != "connection refused" {
+		t.Fatalf("Expected both entries dead-lettered, got %+v", letters)
+	}
+	if letters[0].Writer != "pim.flakyWriter" {
+		t.Errorf("Expected the failing writer recorded, got %q", letters[0].Writer)
+	}
+	if failures != 2 || secondary.GetBufferSize() != 2 {
+		t.Errorf("Expected callback and secondary writer to get 2 entries, got %d and %d", failures, secondary.GetBufferSize())
+	}
+
+	// Replay stops at the first failure and keeps the rest
+	if n, err := queue.Replay(primary); n != 0 || err == nil {
+		t.Errorf("Expected replay to fail while down, got %d, %v", n, err)
+	}
+	if queue.Len() != 2 {
+		t.Fatalf("Expected entries kept after failed replay, got %d", queue.Len())
+	}
+
+	primary.down = false
+	if n, err := queue.Replay(primary); n != 2 || err != nil {
+		t.Fatalf("Expected 2 entries replayed, got %d, %v", n, err)
+	}
+	if queue.Len() != 0 || primary.GetBufferSize() != 2 {
+		t.Errorf("Expected queue drained into the primary writer, got %d queued and %d written", queue.Len(), primary.GetBufferSize())
+	}
+}
+
+func TestDeadLetterQueueCapacity(t *testing.T) {
+	queue := NewDeadLetterQueue(DeadLetterConfig{Capacity: 2})
+	queue.Add("w", []CoreLogEntry{{Message: "a"}, {Message: "b"}, {Message: "c"}}, errors.New("down"))
+
+	letters := queue.Entries()
+	if len(letters) != 2 || letters[0].Entry.Message != "b" || queue.Dropped() != 1 {
+		t.Errorf("Expected the oldest entry discarded, got %+v, %d dropped", letters, queue.Dropped())
+	}
+}
+
+func TestRemoteWriterDeadLetter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	queue := NewDeadLetterQueue(DeadLetterConfig{})
+	writer := NewRemoteWriter(LoggerConfig{EnableJSON: true}, RemoteWriterConfig{
+		Endpoint:   server.URL,
+		BatchSize:  2,
+		BatchDelay: time.Hour,
+		DeadLetter: queue,
+	})
+	defer writer.Close()
+
+	writer.Write(CoreLogEntry{Message: "one"})
+	if err := writer.Write(CoreLogEntry{Message: "two"}); err == nil {
+		t.Fatal("Expected the batch to fail")
+	}
+
+	if queue.Len() != 2 {
+		t.Fatalf("Expected the failed batch dead-lettered, got %d entries", queue.Len())
+	}
+	writer.mu.Lock()
+	buffered := len(writer.buffer)
+	writer.mu.Unlock()
+	if buffered != 0 {
+		t.Errorf("Expected the buffer cleared, got %d entries", buffered)
+	}
+}
@@ -0,0 +1,204 @@
+package pim
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RepeatCountKey is the context key of dedup summary entries holding the
+// number of duplicates suppressed after the first entry
+const RepeatCountKey = "repeat_count"
+
+// DedupConfig configures a DedupWriter
+type DedupConfig struct {
+	Window  time.Duration `json:"window"`           // How long duplicates are collapsed after the first entry (default: 10 seconds)
+	Fields  []string      `json:"fields,omitempty"` // Context keys that, with the level and message, identify duplicates
+	MaxKeys int           `json:"max_keys"`         // Distinct entries tracked at once; further ones pass through (default: 10000)
+}
+
+// DedupStats is a snapshot of a dedup writer for metrics
+type DedupStats struct {
+	Windows    int    `json:"windows"`    // Open windows
+	Suppressed uint64 `json:"suppressed"` // Duplicates not written
+	Summaries  uint64 `json:"summaries"`  // Summary entries written
+}
+
+// dedupWindow tracks the duplicates of one entry
+type dedupWindow struct {
+	first   CoreLogEntry
+	start   time.Time
+	last    time.Time
+	repeats uint64
+}
+
+// DedupWriter collapses error storms: the first entry with a given level,
+// prefix, message and Fields values is written, and identical entries within the
+// next Window are counted instead. When the window closes, a summary entry
+// is written for it: a copy of the first entry, timestamped with the last
+// duplicate, with RepeatCountKey in its context. Windows without duplicates
+// close silently. Open windows are summarized on Flush and Close.
+type DedupWriter struct {
+	writer LogWriter
+	config DedupConfig
+
+	mu      sync.Mutex
+	windows map[string]*dedupWindow
+	stats   DedupStats
+	now     func() time.Time
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewDedupWriter wraps writer with duplicate suppression. A goroutine
+// closes expired windows until Close is called.
+func NewDedupWriter(writer LogWriter, config DedupConfig) *DedupWriter {
+	if config.Window <= 0 {
+		config.Window = 10 * time.Second
+	}
+	if config.MaxKeys <= 0 {
+		config.MaxKeys = 10000
+	}
+	w := &DedupWriter{
+		writer:  writer,
+		config:  config,
+		windows: make(map[string]*dedupWindow),
+		now:     time.Now,
+		stopCh:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// run closes expired windows until the writer is closed
+func (w *DedupWriter) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(max(w.config.Window/4, 10*time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stopCh:
+			return
+		case <-ticker.C:
+			w.sweep(false)
+		}
+	}
+}
+
+// Write implements LogWriter interface
+func (w *DedupWriter) Write(entry CoreLogEntry) error {
+	key := w.key(entry)
+	now := w.now()
+
+	w.mu.Lock()
+	var summary *CoreLogEntry
+	if window, ok := w.windows[key]; ok {
+		if now.Sub(window.start) < w.config.Window {
+			window.repeats++
+			window.last = now
+			w.stats.Suppressed++
+			w.mu.Unlock()
+			return nil
+		}
+		summary = w.summarize(window)
+		delete(w.windows, key)
+	}
+	if len(w.windows) < w.config.MaxKeys {
+		w.windows[key] = &dedupWindow{first: entry, start: now, last: now}
+	}
+	w.mu.Unlock()
+
+	if summary != nil {
+		if err := w.writer.Write(*summary); err != nil {
+			return err
+		}
+	}
+	return w.writer.Write(entry)
+}
+
+// Flush implements LogWriter interface. Open windows are summarized first,
+// so no suppressed duplicates go unreported.
+func (w *DedupWriter) Flush() error {
+	return errors.Join(w.sweep(true), w.writer.Flush())
+}
+
+// Close implements LogWriter interface
+func (w *DedupWriter) Close() error {
+	w.stopOnce.Do(func() { close(w.stopCh) })
+	<-w.done
+	return errors.Join(w.sweep(true), w.writer.Close())
+}
+
+// Stats returns a snapshot of the dedup counters
+func (w *DedupWriter) Stats() DedupStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	stats := w.stats
+	stats.Windows = len(w.windows)
+	return stats
+}
+
+// key identifies the duplicates of entry
+func (w *DedupWriter) key(entry CoreLogEntry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d\x00%s\x00%s", entry.Level, entry.Prefix, entry.Message)
+	for _, field := range w.config.Fields {
+		b.WriteByte(0)
+		if value, ok := entry.Context[field]; ok {
+			fmt.Fprint(&b, value)
+		}
+	}
+	return b.String()
+}
+
+// sweep closes the expired windows, or all of them if all is true, and
+// writes their summaries
+func (w *DedupWriter) sweep(all bool) error {
+	now := w.now()
+	var summaries []CoreLogEntry
+
+	w.mu.Lock()
+	for key, window := range w.windows {
+		if !all && now.Sub(window.start) < w.config.Window {
+			continue
+		}
+		if summary := w.summarize(window); summary != nil {
+			summaries = append(summaries, *summary)
+		}
+		delete(w.windows, key)
+	}
+	w.mu.Unlock()
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Timestamp.Before(summaries[j].Timestamp) })
+
+	var errs []error
+	for _, summary := range summaries {
+		if err := w.writer.Write(summary); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// summarize returns the summary entry of a closing window, or nil if it
+// saw no duplicates; the caller holds w.mu
+func (w *DedupWriter) summarize(window *dedupWindow) *CoreLogEntry {
+	if window.repeats == 0 {
+		return nil
+	}
+	w.stats.Summaries++
+
+	summary := window.first
+	summary.Timestamp = window.last
+	summary.Context = make(map[string]interface{}, len(window.first.Context)+1)
+	for k, v := range window.first.Context {
+		summary.Context[k] = v
+	}
+	summary.Context[RepeatCountKey] = window.repeats
+	return &summary
+}
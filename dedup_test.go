@@ -0,0 +1,167 @@
+package pim
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestDedupWriter returns a dedup writer over a buffer with a clock the
+// test advances
+func newTestDedupWriter(config DedupConfig) (*DedupWriter, *BufferWriter, *time.Time) {
+	buffer := NewBufferWriter(LoggerConfig{}, 100)
+	writer := NewDedupWriter(buffer, config)
+	now := time.Now()
+	writer.now = func() time.Time { return now }
+	return writer, buffer, &now
+}
+
+func TestDedupWriterCollapsesDuplicates(t *testing.T) {
+	writer, buffer, now := newTestDedupWriter(DedupConfig{Window: time.Hour})
+	defer writer.Close()
+
+	entry := CoreLogEntry{Level: ErrorLevel, Message: "connection refused", Context: map[string]interface{}{"host": "db1"}}
+	writer.Write(entry)
+	for i := 0; i < 4; i++ {
+		*now = now.Add(time.Second)
+		writer.Write(entry)
+	}
+	writer.Write(CoreLogEntry{Level: WarningLevel, Message: "connection refused"})
+
+	if size := buffer.GetBufferSize(); size != 2 {
+		t.Fatalf("Expected the first entry and the other level only, got %d entries", size)
+	}
+
+	last := *now
+	*now = now.Add(time.Hour)
+	writer.sweep(false)
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 3 {
+		t.Fatalf("Expected a summary after the window closed, got %d entries", len(entries))
+	}
+	summary := entries[2]
+	if summary.Message != "connection refused" || summary.Context[RepeatCountKey] != uint64(4) {
+		t.Errorf("Expected summary with 4 repeats, got %+v", summary)
+	}
+	if summary.Context["host"] != "db1" || !summary.Timestamp.Equal(last) {
+		t.Errorf("Expected summary to keep the fields and the last duplicate time, got %+v", summary)
+	}
+	if _, ok := entry.Context[RepeatCountKey]; ok {
+		t.Error("Expected the original entry's context to be unchanged")
+	}
+
+	stats := writer.Stats()
+	if stats.Suppressed != 4 || stats.Summaries != 1 || stats.Windows != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestDedupWriterFields(t *testing.T) {
+	writer, buffer, _ := newTestDedupWriter(DedupConfig{Window: time.Hour, Fields: []string{"host"}})
+	defer writer.Close()
+
+	writer.Write(CoreLogEntry{Message: "down", Context: map[string]interface{}{"host": "db1", "attempt": 1}})
+	writer.Write(CoreLogEntry{Message: "down", Context: map[string]interface{}{"host": "db1", "attempt": 2}})
+	writer.Write(CoreLogEntry{Message: "down", Context: map[string]interface{}{"host": "db2"}})
+
+	if size := buffer.GetBufferSize(); size != 2 {
+		t.Errorf("Expected entries to be distinguished by host only, got %d entries", size)
+	}
+}
+
+func TestDedupWriterNewWindowAfterExpiry(t *testing.T) {
+	writer, buffer, now := newTestDedupWriter(DedupConfig{Window: time.Minute})
+	defer writer.Close()
+
+	entry := CoreLogEntry{Message: "retrying"}
+	writer.Write(entry)
+	writer.Write(entry)
+	*now = now.Add(2 * time.Minute)
+	writer.Write(entry) // Closes the old window before the sweep does
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 3 {
+		t.Fatalf("Expected first entry, summary and new first entry, got %d entries", len(entries))
+	}
+	if entries[1].Context[RepeatCountKey] != uint64(1) || entries[2].Context != nil {
+		t.Errorf("Expected summary before the new entry, got %+v", entries[1:])
+	}
+}
+
+func TestDedupWriterMaxKeys(t *testing.T) {
+	writer, buffer, _ := newTestDedupWriter(DedupConfig{Window: time.Hour, MaxKeys: 1})
+	defer writer.Close()
+
+	writer.Write(CoreLogEntry{Message: "a"})
+	writer.Write(CoreLogEntry{Message: "b"})
+	writer.Write(CoreLogEntry{Message: "b"})
+
+	if size := buffer.GetBufferSize(); size != 3 {
+		t.Errorf("Expected untracked entries to pass through, got %d entries", size)
+	}
+}
+
+// unclosedBuffer is a BufferWriter whose Close keeps the entries
+type unclosedBuffer struct {
+	*BufferWriter
+}
+
+func (w unclosedBuffer) Close() error { return nil }
+
+func TestDedupWriterSummarizesOnFlushAndClose(t *testing.T) {
+	buffer := NewBufferWriter(LoggerConfig{}, 100)
+	writer := NewDedupWriter(unclosedBuffer{buffer}, DedupConfig{Window: time.Hour})
+
+	writer.Write(CoreLogEntry{Message: "a"})
+	writer.Write(CoreLogEntry{Message: "a"})
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if size := buffer.GetBufferSize(); size != 2 {
+		t.Fatalf("Expected a summary on flush, got %d entries", size)
+	}
+
+	writer.Write(CoreLogEntry{Message: "b"})
+	writer.Write(CoreLogEntry{Message: "b"})
+	writer.Write(CoreLogEntry{Message: "b"})
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	entries := buffer.GetBuffer()
+	if len(entries) != 4 || entries[3].Context[RepeatCountKey] != uint64(2) {
+		t.Errorf("Expected a summary on close, got %+v", entries)
+	}
+}
+
+func TestDedupWriterSweepsInBackground(t *testing.T) {
+	buffer := NewBufferWriter(LoggerConfig{}, 100)
+	writer := NewDedupWriter(buffer, DedupConfig{Window: 20 * time.Millisecond})
+	defer writer.Close()
+
+	writer.Write(CoreLogEntry{Message: "a"})
+	writer.Write(CoreLogEntry{Message: "a"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for buffer.GetBufferSize() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if size := buffer.GetBufferSize(); size != 2 {
+		t.Errorf("Expected the window to be summarized when it expired, got %d entries", size)
+	}
+}
+
+func TestFileConfigDedupWriter(t *testing.T) {
+	config, err := ParseConfig([]byte(`{"writers": [{"file": {"path": "`+t.TempDir()+`/app.log"}, "dedup": {"window": 1000000000}}]}`), ".json")
+	if err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+	writers, err := config.BuildWriters()
+	if err != nil {
+		t.Fatalf("BuildWriters failed: %v", err)
+	}
+	defer writers[0].Close()
+
+	if writer, ok := writers[0].(*DedupWriter); !ok || writer.config.Window != time.Second {
+		t.Errorf("Expected a dedup writer with a 1s window, got %T", writers[0])
+	}
+}
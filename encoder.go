@@ -0,0 +1,421 @@
+package pim
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Encoder serializes a log entry for a writer. The result is one record
+// without a trailing newline; writers add their own separators.
+//
+// Writers pick an encoder from their LoggerConfig: LoggerConfig.Encoder if
+// set, otherwise JSON with EnableJSON or their usual text layout.
+type Encoder interface {
+	EncodeEntry(entry CoreLogEntry) ([]byte, error)
+}
+
+// EncoderFunc is a function adapter for Encoder
+type EncoderFunc func(entry CoreLogEntry) ([]byte, error)
+
+// EncodeEntry implements Encoder interface
+func (f EncoderFunc) EncodeEntry(entry CoreLogEntry) ([]byte, error) {
+	return f(entry)
+}
+
+// JSONEncoder encodes entries as single-line JSON objects
+type JSONEncoder struct {
+	FieldCase  FieldCase // Casing of field names (default: snake_case)
+	NestGroups bool      // Write dotted context keys as nested objects
+}
+
+// NewJSONEncoder creates a JSON encoder using the field casing and group
+// nesting of config
+func NewJSONEncoder(config LoggerConfig) *JSONEncoder {
+	return &JSONEncoder{FieldCase: config.FieldCase, NestGroups: config.NestGroups}
+}
+
+// EncodeEntry implements Encoder interface
+func (e *JSONEncoder) EncodeEntry(entry CoreLogEntry) ([]byte, error) {
+	if e.NestGroups {
+		entry.Context = nestGroups(entry.Context)
+	}
+	data, err := e.FieldCase.marshalEntry(entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal log entry: %w", err)
+	}
+	return data, nil
+}
+
+// TextFields selects the parts of an entry written by a TextEncoder
+type TextFields uint16
+
+const (
+	TextPrefix      TextFields = 1 << iota // Level prefix such as the emoji of Info
+	TextTimestamp                          // [timestamp]
+	TextLevel                              // [LEVEL]
+	TextService                            // [service]
+	TextLogger                             // [logger name]
+	TextCaller                             // [file:package.function:Lline]
+	TextGoroutine                          // (goroutine N)
+	TextStackTrace                         // Stack frames on the following lines
+	TextErrorLists                         // Flattened multi-errors as lists on the following lines
+	TextCauseChains                        // Wrapped errors as "caused by" lists on the following lines
+	TextSparklines                         // Colored sparklines instead of Series fields, see Sparkline
+)
+
+// Text layouts used by the built-in writers
+const (
+	ConsoleTextFields = TextPrefix | TextTimestamp | TextLogger | TextCaller | TextGoroutine | TextErrorLists | TextCauseChains | TextStackTrace | TextSparklines
+	StderrTextFields  = TextPrefix | TextTimestamp | TextLogger | TextCaller | TextGoroutine | TextStackTrace | TextSparklines
+	FileTextFields    = TextTimestamp | TextLevel | TextService | TextLogger | TextCaller | TextGoroutine
+	RemoteTextFields  = TextTimestamp | TextLevel | TextService | TextLogger
+	SyslogTextFields  = TextService | TextLogger
+)
+
+// TextEncoder encodes entries as human-readable lines, e.g.
+//
+//	[2024-01-02 03:04:05.000 UTC] [INFO] [billing] main.go:main.run:L42 charged {amount=10}
+type TextEncoder struct {
+	config     LoggerConfig
+	fields     TextFields
+	timestamps *TimestampFormatter
+	prefixes   map[string]string // Built-in prefixes without emoji, see IconMode
+}
+
+// NewTextEncoder creates a text encoder writing fields, formatted with the
+// timestamp format, field casing, caller options and icon mode of config
+func NewTextEncoder(config LoggerConfig, fields TextFields) *TextEncoder {
+	return &TextEncoder{
+		config:     config,
+		fields:     fields,
+		timestamps: NewTimestampFormatter(config.TimestampFormat, config.TimestampResolution),
+		prefixes:   prefixSubstitutes(config),
+	}
+}
+
+// EncodeEntry implements Encoder interface
+func (e *TextEncoder) EncodeEntry(entry CoreLogEntry) ([]byte, error) {
+	return e.AppendEntry(nil, entry)
+}
+
+// AppendEntry appends the encoded entry to dst and returns the extended
+// buffer, so writers can encode into a reused buffer without allocating
+func (e *TextEncoder) AppendEntry(dst []byte, entry CoreLogEntry) ([]byte, error) {
+	return e.appendFormat(dst, entry), nil
+}
+
+// format formats an entry as text
+func (e *TextEncoder) format(entry CoreLogEntry) string {
+	return string(e.appendFormat(nil, entry))
+}
+
+// appendFormat appends an entry formatted as text to dst. Parts of the
+// first line are separated by spaces, and error lists, cause chains and
+// stack traces follow on their own lines.
+func (e *TextEncoder) appendFormat(dst []byte, entry CoreLogEntry) []byte {
+	start := len(dst)
+	bracketed := func(parts ...string) {
+		if len(dst) > start {
+			dst = append(dst, ' ')
+		}
+		dst = append(dst, '[')
+		for _, part := range parts {
+			dst = append(dst, part...)
+		}
+		dst = append(dst, ']')
+	}
+
+	// Add prefix
+	if e.fields&TextPrefix != 0 && entry.Prefix != "" {
+		if substitute, ok := e.prefixes[entry.Prefix]; ok {
+			dst = append(dst, substitute...)
+		} else {
+			dst = append(dst, entry.Prefix...)
+		}
+	}
+
+	// Add timestamp
+	if e.fields&TextTimestamp != 0 {
+		bracketed(e.timestamps.Format(entry.Timestamp))
+	}
+
+	// Add level
+	if e.fields&TextLevel != 0 {
+		bracketed(strings.ToUpper(entry.LevelString))
+	}
+
+	// Add service name
+	if e.fields&TextService != 0 && entry.ServiceName != "" {
+		bracketed(entry.ServiceName)
+	}
+
+	// Add logger name
+	if e.fields&TextLogger != 0 && entry.LoggerName != "" {
+		bracketed(entry.LoggerName)
+	}
+
+	// Add file/line info
+	if e.fields&TextCaller != 0 && entry.File != "" {
+		if len(dst) > start {
+			dst = append(dst, ' ')
+		}
+		dst = append(dst, '[')
+		dst = append(dst, entry.File...)
+		if entry.Function != "" {
+			dst = append(dst, ':')
+			if e.config.ShowPackageName && entry.Package != "" {
+				dst = append(dst, entry.Package...)
+				dst = append(dst, '.')
+			}
+			dst = append(dst, entry.Function...)
+		}
+		dst = append(dst, ":L"...)
+		dst = strconv.AppendInt(dst, int64(entry.Line), 10)
+		dst = append(dst, ']')
+	}
+
+	// Add goroutine ID
+	if e.fields&TextGoroutine != 0 && entry.GoroutineID != "" {
+		if len(dst) > start {
+			dst = append(dst, ' ')
+		}
+		dst = append(dst, entry.GoroutineID...)
+	}
+
+	// Add message
+	if len(dst) > start {
+		dst = append(dst, ' ')
+	}
+	if e.fields&TextSparklines != 0 {
+		dst = append(dst, entry.consoleMessage()...)
+	} else {
+		dst = append(dst, entry.Message...)
+	}
+
+	// Add context if present
+	dst = e.appendContext(dst, entry.Context, " {", "}")
+
+	// Add flattened multi-errors as indented lists
+	if e.fields&TextErrorLists != 0 {
+		for _, key := range errorListKeys(entry.Context) {
+			dst = append(dst, '\n')
+			dst = append(dst, formatErrorList(key, entry.Context[key].([]ErrorInfo))...)
+		}
+	}
+
+	// Add wrapped errors as cause chains
+	if e.fields&TextCauseChains != 0 {
+		for _, key := range causeChainKeys(entry.Context) {
+			dst = append(dst, '\n')
+			dst = append(dst, formatCauseChain(key, entry.Context[key].(error))...)
+		}
+	}
+
+	// Add stack trace if present
+	if e.fields&TextStackTrace != 0 {
+		if stackStr := e.formatStackTrace(entry.StackTrace); stackStr != "" {
+			dst = append(dst, '\n')
+			dst = append(dst, stackStr...)
+		}
+	}
+
+	return dst
+}
+
+// formatContext formats context fields as comma-separated key=value pairs
+func (e *TextEncoder) formatContext(context map[string]interface{}) string {
+	return string(e.appendContext(nil, context, "", ""))
+}
+
+// appendContext appends context fields as comma-separated key=value pairs
+// between open and close, or nothing if there are no fields to write
+func (e *TextEncoder) appendContext(dst []byte, context map[string]interface{}, open, close string) []byte {
+	written := false
+	for k, v := range context {
+		if _, ok := v.([]ErrorInfo); ok && e.fields&TextErrorLists != 0 {
+			continue // Written as a list below the entry
+		}
+		if err, ok := v.(error); ok && isCauseChain(err) && e.fields&TextCauseChains != 0 {
+			continue // Written as a cause chain below the entry
+		}
+		if _, ok := v.(Series); ok && e.fields&TextSparklines != 0 {
+			continue // Shown as a sparkline in the message
+		}
+		if written {
+			dst = append(dst, ", "...)
+		} else {
+			dst = append(dst, open...)
+			written = true
+		}
+		dst = append(dst, e.config.FieldCase.Convert(k)...)
+		dst = append(dst, '=')
+		dst = fmt.Appendf(dst, "%v", v)
+	}
+	if written {
+		dst = append(dst, close...)
+	}
+	return dst
+}
+
+// formatStackTrace formats stack frames as indented lines
+func (e *TextEncoder) formatStackTrace(frames []StackFrame) string {
+	if len(frames) == 0 {
+		return ""
+	}
+
+	var lines []string
+	for i, frame := range frames {
+		indent := strings.Repeat("  ", i)
+		parts := []string{frame.File}
+
+		if e.config.ShowFunctionName && frame.Function != "" {
+			if e.config.ShowPackageName && frame.Package != "" {
+				parts = append(parts, fmt.Sprintf("%s.%s", frame.Package, frame.Function))
+			} else {
+				parts = append(parts, frame.Function)
+			}
+		}
+		parts = append(parts, fmt.Sprintf("L%d", frame.Line))
+
+		line := fmt.Sprintf("%s↳ %s", indent, strings.Join(parts, ":"))
+		lines = append(lines, line)
+		lines = append(lines, formatSource(frame.Source, indent+"    ")...)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// LogfmtEncoder encodes entries as logfmt lines with context keys sorted, e.g.
+//
+//	time=2024-01-02T03:04:05Z level=info service=billing msg="charged card" amount=10
+type LogfmtEncoder struct {
+	config     LoggerConfig
+	timestamps *TimestampFormatter
+}
+
+// NewLogfmtEncoder creates a logfmt encoder using the timestamp format and
+// field casing of config
+func NewLogfmtEncoder(config LoggerConfig) *LogfmtEncoder {
+	return &LogfmtEncoder{
+		config:     config,
+		timestamps: NewTimestampFormatter(config.TimestampFormat, config.TimestampResolution),
+	}
+}
+
+// EncodeEntry implements Encoder interface
+func (e *LogfmtEncoder) EncodeEntry(entry CoreLogEntry) ([]byte, error) {
+	var b strings.Builder
+	pair := func(key, value string) {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(logfmtValue(value))
+	}
+
+	pair("time", e.timestamps.Format(entry.Timestamp))
+	pair("level", entry.LevelString)
+	optional := []struct{ key, value string }{
+		{"service", entry.ServiceName},
+		{"logger", entry.LoggerName},
+		{"trace_id", entry.TraceID},
+		{"span_id", entry.SpanID},
+		{"request_id", entry.RequestID},
+	}
+	for _, field := range optional {
+		if field.value != "" {
+			pair(e.config.FieldCase.Convert(field.key), field.value)
+		}
+	}
+	if entry.File != "" {
+		pair("caller", fmt.Sprintf("%s:%d", entry.File, entry.Line))
+	}
+	pair("msg", entry.Message)
+
+	keys := make([]string, 0, len(entry.Context))
+	for k := range entry.Context {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		pair(logfmtKey(e.config.FieldCase.Convert(k)), fmt.Sprint(entry.Context[k]))
+	}
+
+	return []byte(b.String()), nil
+}
+
+// logfmtKey replaces characters that would end a logfmt key
+func logfmtKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// logfmtValue quotes a value if it is empty or contains spaces, quotes,
+// equal signs or control characters
+func logfmtValue(value string) string {
+	if value == "" {
+		return `""`
+	}
+	for _, r := range value {
+		if r <= ' ' || r == '=' || r == '"' || r == 0x7f {
+			return strconv.Quote(value)
+		}
+	}
+	return value
+}
+
+// TemplateEncoder encodes entries with a named format of a ThemeManager:
+// a built-in format such as "colorful", a registered template or formatter
+type TemplateEncoder struct {
+	themes     *ThemeManager
+	formatName string
+}
+
+// NewTemplateEncoder creates an encoder for the theme, format and template
+// settings of config. config.CustomFormat is registered as "custom".
+func NewTemplateEncoder(config LoggerConfig) *TemplateEncoder {
+	themes := NewThemeManager()
+	themes.applyConfigTheme(config)
+
+	// Apply template limits, then register custom format if provided
+	themes.SetTemplateSandbox(config.TemplateSandbox)
+	if config.CustomFormat != "" {
+		themes.RegisterTemplate("custom", config.CustomFormat)
+	}
+	if config.LevelPrefixes != nil {
+		themes.SetLevelPrefixes(config.LevelPrefixes)
+	}
+	themes.SetIconMode(config.IconMode)
+
+	return &TemplateEncoder{themes: themes, formatName: config.FormatName}
+}
+
+// ThemeManager returns the theme manager used for formatting, for
+// registering further templates and formatters
+func (e *TemplateEncoder) ThemeManager() *ThemeManager {
+	return e.themes
+}
+
+// EncodeEntry implements Encoder interface
+func (e *TemplateEncoder) EncodeEntry(entry CoreLogEntry) ([]byte, error) {
+	return []byte(e.themes.Format(entry, e.formatName)), nil
+}
+
+// encoderFor returns config.Encoder, or a JSON encoder with EnableJSON, or
+// text with the given layout
+func encoderFor(config LoggerConfig, fields TextFields) Encoder {
+	if config.Encoder != nil {
+		return config.Encoder
+	}
+	if config.EnableJSON {
+		return NewJSONEncoder(config)
+	}
+	return NewTextEncoder(config, fields)
+}
@@ -0,0 +1,142 @@
+package pim
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func encoderTestEntry() CoreLogEntry {
+	return CoreLogEntry{
+		Timestamp:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Level:       InfoLevel,
+		LevelString: "info",
+		Message:     "charged card",
+		Prefix:      InfoPrefix,
+		ServiceName: "billing",
+		File:        "main.go",
+		Line:        42,
+		Function:    "run",
+		Package:     "main",
+		Context:     map[string]interface{}{"amount": 10, "user_id": "u 1"},
+	}
+}
+
+func TestJSONEncoder(t *testing.T) {
+	data, err := NewJSONEncoder(LoggerConfig{FieldCase: FieldCaseCamel}).EncodeEntry(encoderTestEntry())
+	if err != nil {
+		t.Fatalf("EncodeEntry failed: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Invalid JSON %s: %v", data, err)
+	}
+	if decoded["message"] != "charged card" || decoded["serviceName"] != "billing" {
+		t.Errorf("Unexpected JSON: %s", data)
+	}
+}
+
+func TestTextEncoderLayouts(t *testing.T) {
+	config := LoggerConfig{TimestampFormat: time.RFC3339, ShowPackageName: true}
+	entry := encoderTestEntry()
+	entry.Context = map[string]interface{}{"amount": 10}
+
+	tests := []struct {
+		fields   TextFields
+		expected string
+	}{
+		{FileTextFields, "[2024-01-02T03:04:05Z] [INFO] [billing] [main.go:main.run:L42] charged card {amount=10}"},
+		{RemoteTextFields, "[2024-01-02T03:04:05Z] [INFO] [billing] charged card {amount=10}"},
+		{SyslogTextFields, "[billing] charged card {amount=10}"},
+		{TextLevel, "[INFO] charged card {amount=10}"},
+	}
+	for _, test := range tests {
+		data, err := NewTextEncoder(config, test.fields).EncodeEntry(entry)
+		if err != nil {
+			t.Fatalf("EncodeEntry failed: %v", err)
+		}
+		if string(data) != test.expected {
+			t.Errorf("Expected %q, got %q", test.expected, data)
+		}
+	}
+}
+
+func TestTextEncoderStackTrace(t *testing.T) {
+	config := LoggerConfig{TimestampFormat: time.RFC3339, ShowFunctionName: true}
+	entry := encoderTestEntry()
+	entry.Context = nil
+	entry.StackTrace = []StackFrame{{File: "a.go", Line: 1, Function: "f"}}
+
+	data, _ := NewTextEncoder(config, StderrTextFields).EncodeEntry(entry)
+	lines := strings.Split(string(data), "\n")
+	if len(lines) != 2 || lines[1] != "↳ a.go:f:L1" {
+		t.Errorf("Expected the stack trace on its own line, got %q", data)
+	}
+
+	data, _ = NewTextEncoder(config, FileTextFields).EncodeEntry(entry)
+	if strings.Contains(string(data), "\n") {
+		t.Errorf("Expected no stack trace in the file layout, got %q", data)
+	}
+}
+
+func TestLogfmtEncoder(t *testing.T) {
+	config := LoggerConfig{TimestampFormat: time.RFC3339}
+	data, err := NewLogfmtEncoder(config).EncodeEntry(encoderTestEntry())
+	if err != nil {
+		t.Fatalf("EncodeEntry failed: %v", err)
+	}
+	expected := `time=2024-01-02T03:04:05Z level=info service=billing caller=main.go:42 msg="charged card" amount=10 user_id="u 1"`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+
+	if got := logfmtValue(`say "hi"`); got != `"say \"hi\""` {
+		t.Errorf("Expected quoted value, got %s", got)
+	}
+	if got := logfmtKey("a b=c"); got != "a_b_c" {
+		t.Errorf("Expected sanitized key, got %s", got)
+	}
+}
+
+func TestTemplateEncoder(t *testing.T) {
+	config := DefaultLoggerConfig
+	config.FormatName = "custom"
+	config.CustomFormat = "{{.Level}}: {{.Message}}"
+
+	data, err := NewTemplateEncoder(config).EncodeEntry(encoderTestEntry())
+	if err != nil {
+		t.Fatalf("EncodeEntry failed: %v", err)
+	}
+	if string(data) != "info: charged card" {
+		t.Errorf("Unexpected output %q", data)
+	}
+}
+
+func TestWritersUseConfigEncoder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	config := DefaultLoggerConfig
+	config.Encoder = EncoderFunc(func(entry CoreLogEntry) ([]byte, error) {
+		return []byte("custom " + entry.Message), nil
+	})
+
+	writer, err := NewFileWriter(path, config, RotationConfig{})
+	if err != nil {
+		t.Fatalf("NewFileWriter failed: %v", err)
+	}
+	writer.Write(encoderTestEntry())
+	writer.Write(encoderTestEntry())
+	writer.Close()
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "custom charged card\ncustom charged card\n" {
+		t.Errorf("Unexpected file content %q", data)
+	}
+
+	message, _ := NewSyslogWriter(config, "app").encoder.EncodeEntry(encoderTestEntry())
+	if string(message) != "custom charged card" {
+		t.Errorf("Expected syslog writer to use the config encoder, got %q", message)
+	}
+}
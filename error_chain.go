@@ -0,0 +1,122 @@
+package pim
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrorCause is one layer of a wrapped error
+type ErrorCause struct {
+	Message string      `json:"message"`          // The layer's own message, without the wrapped error's
+	Type    string      `json:"type"`             // Go type of the layer
+	Origin  *StackFrame `json:"origin,omitempty"` // Where the layer was created, if it records a stack
+}
+
+// ErrorChain is the JSON form of a wrapped error in an entry context
+type ErrorChain struct {
+	Error  string       `json:"error"`  // Full message
+	Causes []ErrorCause `json:"causes"` // Outermost layer first
+}
+
+// CauseChain splits an error wrapped with %w (or any single Unwrap) into its
+// layers, outermost first. Layers that only add a stack, such as
+// errors.WithStack, are merged into the error they wrap.
+func CauseChain(err error) []ErrorCause {
+	var causes []ErrorCause
+	var origin *StackFrame
+	for err != nil {
+		next := errors.Unwrap(err)
+		if frame := originFrame(err); frame != nil && origin == nil {
+			origin = frame
+		}
+
+		message := err.Error()
+		if next != nil {
+			message = strings.TrimRight(strings.TrimSuffix(message, next.Error()), ": ")
+		}
+		if message != "" || next == nil {
+			causes = append(causes, ErrorCause{
+				Message: message,
+				Type:    fmt.Sprintf("%T", err),
+				Origin:  origin,
+			})
+			origin = nil
+		}
+		err = next
+	}
+	return causes
+}
+
+// isCauseChain reports whether err wraps another error. Multi-errors are
+// flattened into error lists instead.
+func isCauseChain(err error) bool {
+	return err != nil && errors.Unwrap(err) != nil
+}
+
+// originFrame returns the innermost frame of the stack recorded by err
+// itself, not by the errors it wraps
+func originFrame(err error) *StackFrame {
+	var stack []StackFrame
+	switch e := err.(type) {
+	case stackFramer:
+		stack = e.StackFrames()
+	case callersError:
+		stack = framesFromPCs(e.Callers())
+	}
+	if len(stack) == 0 {
+		return nil
+	}
+	return &stack[0]
+}
+
+// causeChainKeys returns the sorted context keys holding wrapped errors
+func causeChainKeys(context map[string]interface{}) []string {
+	var keys []string
+	for k, v := range context {
+		if err, ok := v.(error); ok && isCauseChain(err) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatCauseChain renders a wrapped error as an indented "caused by" list
+// for console output
+func formatCauseChain(key string, err error) string {
+	var lines []string
+	for i, cause := range CauseChain(err) {
+		if i == 0 {
+			lines = append(lines, fmt.Sprintf("  %s: %s", key, cause.Message))
+		} else {
+			lines = append(lines, fmt.Sprintf("    caused by: %s", cause.Message))
+		}
+		if frame := cause.Origin; frame != nil {
+			lines = append(lines, fmt.Sprintf("        ↳ %s:%s:L%d", frame.File, frame.Function, frame.Line))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// withErrorChains returns the entry with wrapped errors in its context
+// replaced by their ErrorChain, for JSON encoding. The context is copied
+// only if it holds a wrapped error.
+func withErrorChains(entry CoreLogEntry) CoreLogEntry {
+	keys := causeChainKeys(entry.Context)
+	if len(keys) == 0 {
+		return entry
+	}
+
+	context := make(map[string]interface{}, len(entry.Context))
+	for k, v := range entry.Context {
+		context[k] = v
+	}
+	for _, k := range keys {
+		err := context[k].(error)
+		context[k] = ErrorChain{Error: err.Error(), Causes: CauseChain(err)}
+	}
+	entry.Context = context
+	return entry
+}
@@ -0,0 +1,107 @@
+package pim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stackError records a fixed origin frame
+type stackError struct {
+	err error
+}
+
+func (e *stackError) Error() string { return e.err.Error() }
+func (e *stackError) Unwrap() error { return e.err }
+func (e *stackError) StackFrames() []StackFrame {
+	return []StackFrame{{File: "db.go", Line: 7, Function: "Open"}}
+}
+
+func TestCauseChain(t *testing.T) {
+	root := fs.ErrNotExist
+	err := fmt.Errorf("load config: %w", &stackError{fmt.Errorf("open app.yaml: %w", root)})
+
+	causes := CauseChain(err)
+	if len(causes) != 3 {
+		t.Fatalf("Expected 3 causes, got %+v", causes)
+	}
+	expected := []string{"load config", "open app.yaml", "file does not exist"}
+	for i, cause := range causes {
+		if cause.Message != expected[i] {
+			t.Errorf("cause %d: expected %q, got %q", i, expected[i], cause.Message)
+		}
+	}
+	// The stack-only layer is merged into the error it wraps
+	if causes[1].Origin == nil || causes[1].Origin.File != "db.go" {
+		t.Errorf("Expected origin on the wrapped layer, got %+v", causes[1])
+	}
+	if causes[0].Origin != nil || causes[2].Origin != nil {
+		t.Errorf("Unexpected origins: %+v", causes)
+	}
+	if causes[2].Type != fmt.Sprintf("%T", root) {
+		t.Errorf("Unexpected root type %q", causes[2].Type)
+	}
+
+	if got := CauseChain(errors.New("plain")); len(got) != 1 || got[0].Message != "plain" {
+		t.Errorf("Expected a single cause for a plain error, got %+v", got)
+	}
+}
+
+func TestConsoleRendersCauseChain(t *testing.T) {
+	entry := CoreLogEntry{
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Message:   "startup failed",
+		Context: map[string]interface{}{
+			"error": fmt.Errorf("load config: %w", &stackError{errors.New("permission denied")}),
+			"plain": errors.New("kept inline"),
+		},
+	}
+	config := LoggerConfig{TimestampFormat: time.RFC3339}
+
+	data, _ := NewTextEncoder(config, ConsoleTextFields).EncodeEntry(entry)
+	expected := "[2024-01-02T03:04:05Z] startup failed {plain=kept inline}\n" +
+		"  error: load config\n" +
+		"    caused by: permission denied\n" +
+		"        ↳ db.go:Open:L7"
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, data)
+	}
+
+	// Other layouts keep the one-line form
+	data, _ = NewTextEncoder(config, FileTextFields).EncodeEntry(entry)
+	if !strings.Contains(string(data), "error=load config: permission denied") {
+		t.Errorf("Expected inline error in the file layout, got %s", data)
+	}
+}
+
+func TestJSONEncodesCauseChain(t *testing.T) {
+	wrapped := fmt.Errorf("query users: %w", errors.New("timeout"))
+	entry := CoreLogEntry{Message: "failed", Context: map[string]interface{}{"error": wrapped}}
+
+	data, err := DefaultLoggerConfig.FieldCase.marshalEntry(entry)
+	if err != nil {
+		t.Fatalf("marshalEntry failed: %v", err)
+	}
+	var decoded struct {
+		Context map[string]ErrorChain `json:"context"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Invalid JSON %s: %v", data, err)
+	}
+	chain := decoded.Context["error"]
+	if chain.Error != "query users: timeout" || len(chain.Causes) != 2 || chain.Causes[1].Message != "timeout" {
+		t.Errorf("Unexpected chain: %+v", chain)
+	}
+	if entry.Context["error"] != wrapped {
+		t.Error("Expected the entry context to be left unchanged")
+	}
+
+	batch, _, err := EncodeBatch(CurrentWireVersion, []CoreLogEntry{entry})
+	if err != nil || !strings.Contains(string(batch), `"causes":[`) {
+		t.Errorf("Expected the batch to contain the cause chain, got %s (%v)", batch, err)
+	}
+}
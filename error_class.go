@@ -0,0 +1,257 @@
+package pim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"syscall"
+)
+
+// Context keys set by the error classification hook
+const (
+	ErrorClassKey = "error_class"
+	RetryableKey  = "retryable"
+)
+
+// ErrorClass categorizes an error for alerting and retry decisions
+type ErrorClass string
+
+const (
+	// ErrorClassTransient errors are expected to succeed on retry (timeouts, unavailable dependencies)
+	ErrorClassTransient ErrorClass = "transient"
+	// ErrorClassPermanent errors are system faults that will not go away on retry
+	ErrorClassPermanent ErrorClass = "permanent"
+	// ErrorClassUser errors are caused by the caller (invalid input, missing permissions)
+	ErrorClassUser ErrorClass = "user"
+)
+
+// ClassificationRule assigns a class to errors it matches. A rule matches if
+// any of its conditions does; rules are evaluated in order.
+type ClassificationRule struct {
+	Class    ErrorClass                        `json:"class"`
+	Errors   []error                           `json:"-"`        // Matched with errors.Is
+	Types    []string                          `json:"types"`    // Error type names as printed by %T, e.g. "*net.OpError"
+	Patterns []string                          `json:"patterns"` // Regular expressions matched against the error text
+	Match    func(err error, text string) bool `json:"-"`        // Custom condition; err is nil when only text is known
+
+	// Retryable overrides the retryable flag, which defaults to true for
+	// transient errors only
+	Retryable *bool `json:"retryable,omitempty"`
+
+	compiled []*regexp.Regexp
+}
+
+// DefaultClassificationRules recognize common transient and user errors
+var DefaultClassificationRules = []ClassificationRule{
+	{
+		Class:  ErrorClassTransient,
+		Errors: []error{context.DeadlineExceeded, syscall.ECONNREFUSED, syscall.ECONNRESET, syscall.EPIPE},
+		Match: func(err error, text string) bool {
+			var netErr net.Error
+			return errors.As(err, &netErr) && netErr.Timeout()
+		},
+		Patterns: []string{`(?i)timeout|timed out|temporar|connection (refused|reset)|too many requests|unavailable|try again`},
+	},
+	{
+		Class:    ErrorClassUser,
+		Patterns: []string{`(?i)invalid|not found|unauthori[sz]ed|forbidden|permission denied|bad request|validation`},
+	},
+}
+
+// ErrorClassificationConfig holds configuration for error classification hooks
+type ErrorClassificationConfig struct {
+	HookConfig
+	Rules        []ClassificationRule `json:"rules"`         // Evaluated in order (default: DefaultClassificationRules)
+	DefaultClass ErrorClass           `json:"default_class"` // Class when no rule matches (default: permanent)
+	ErrorFields  []string             `json:"error_fields"`  // Context fields holding the error (default: error, err, errors)
+}
+
+// ErrorClassificationHook tags error entries with an error class and a
+// retryable flag so alert policies can page only on permanent system errors
+type ErrorClassificationHook struct {
+	config ErrorClassificationConfig
+}
+
+// NewErrorClassificationHook creates a new error classification hook
+func NewErrorClassificationHook(config ErrorClassificationConfig) (*ErrorClassificationHook, error) {
+	if config.Rules == nil {
+		config.Rules = DefaultClassificationRules
+	}
+	if config.DefaultClass == "" {
+		config.DefaultClass = ErrorClassPermanent
+	}
+	if config.ErrorFields == nil {
+		config.ErrorFields = []string{"error", "err", "errors"}
+	}
+
+	rules := make([]ClassificationRule, len(config.Rules))
+	for i, rule := range config.Rules {
+		rule.compiled = nil
+		for _, pattern := range rule.Patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid classification pattern %q: %w", pattern, err)
+			}
+			rule.compiled = append(rule.compiled, re)
+		}
+		rules[i] = rule
+	}
+	config.Rules = rules
+
+	return &ErrorClassificationHook{config: config}, nil
+}
+
+// Process implements LogHook interface
+func (h *ErrorClassificationHook) Process(entry CoreLogEntry) (CoreLogEntry, error) {
+	if !h.config.Enabled || entry.Level > ErrorLevel {
+		return entry, nil
+	}
+
+	class, retryable := h.Classify(entry)
+
+	if entry.Context == nil {
+		entry.Context = make(map[string]interface{})
+	}
+	entry.Context[ErrorClassKey] = string(class)
+	entry.Context[RetryableKey] = retryable
+
+	return entry, nil
+}
+
+// Classify returns the class and retryable flag of an entry's error without
+// modifying it. The error is taken from the configured error fields, falling
+// back to the message.
+func (h *ErrorClassificationHook) Classify(entry CoreLogEntry) (ErrorClass, bool) {
+	err, texts := h.entryError(entry)
+
+	for _, rule := range h.config.Rules {
+		if rule.matches(err, texts) {
+			return rule.Class, rule.retryable()
+		}
+	}
+
+	rule := ClassificationRule{Class: h.config.DefaultClass}
+	return rule.Class, rule.retryable()
+}
+
+// entryError finds the error of an entry and the texts to match patterns against
+func (h *ErrorClassificationHook) entryError(entry CoreLogEntry) (error, []string) {
+	for _, field := range h.config.ErrorFields {
+		switch v := entry.Context[field].(type) {
+		case error:
+			return v, []string{fmt.Sprintf("%T", v), v.Error()}
+		case []ErrorInfo:
+			// A flattened multi-error matches if any of its errors does
+			var texts []string
+			for _, info := range v {
+				texts = append(texts, info.Type, info.Message)
+			}
+			return nil, texts
+		case string:
+			if v != "" {
+				return nil, []string{v}
+			}
+		}
+	}
+	return nil, []string{entry.Message}
+}
+
+// matches reports whether the rule applies to err or texts
+func (r ClassificationRule) matches(err error, texts []string) bool {
+	if err != nil {
+		for _, target := range r.Errors {
+			if errors.Is(err, target) {
+				return true
+			}
+		}
+		for e := err; e != nil; e = errors.Unwrap(e) {
+			for _, typeName := range r.Types {
+				if fmt.Sprintf("%T", e) == typeName {
+					return true
+				}
+			}
+		}
+	} else {
+		// Only type names are known for flattened errors
+		for _, text := range texts {
+			for _, typeName := range r.Types {
+				if text == typeName {
+					return true
+				}
+			}
+		}
+	}
+
+	for _, text := range texts {
+		for _, re := range r.compiled {
+			if re.MatchString(text) {
+				return true
+			}
+		}
+		if r.Match != nil && r.Match(err, text) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryable returns the retryable flag for errors matched by the rule
+func (r ClassificationRule) retryable() bool {
+	if r.Retryable != nil {
+		return *r.Retryable
+	}
+	return r.Class == ErrorClassTransient
+}
+
+// GetConfig implements EnhancedLogHook interface
+func (h *ErrorClassificationHook) GetConfig() HookConfig {
+	return h.config.HookConfig
+}
+
+// GetType implements EnhancedLogHook interface
+func (h *ErrorClassificationHook) GetType() HookType {
+	return h.config.Type
+}
+
+// IsEnabled implements EnhancedLogHook interface
+func (h *ErrorClassificationHook) IsEnabled() bool {
+	return h.config.Enabled
+}
+
+// SetEnabled implements EnhancedLogHook interface
+func (h *ErrorClassificationHook) SetEnabled(enabled bool) {
+	h.config.Enabled = enabled
+}
+
+// GetPriority implements EnhancedLogHook interface
+func (h *ErrorClassificationHook) GetPriority() int {
+	return h.config.Priority
+}
+
+// SetPriority implements EnhancedLogHook interface
+func (h *ErrorClassificationHook) SetPriority(priority int) {
+	h.config.Priority = priority
+}
+
+// AddErrorClassificationHook adds a hook that tags error entries with
+// error_class and retryable. Custom rules are evaluated before the defaults.
+// It runs before severity scoring so FieldWeights can use error_class.
+func (l *LoggerCore) AddErrorClassificationHook(rules ...ClassificationRule) error {
+	hook, err := NewErrorClassificationHook(ErrorClassificationConfig{
+		HookConfig: HookConfig{
+			Type:        HookTypeEnrich,
+			Name:        "error_classification",
+			Description: "Tags error entries as transient, permanent or user errors",
+			Enabled:     true,
+			Priority:    40,
+		},
+		Rules: append(append([]ClassificationRule{}, rules...), DefaultClassificationRules...),
+	})
+	if err != nil {
+		return err
+	}
+	l.AddEnhancedHook(hook)
+	return nil
+}
@@ -0,0 +1,87 @@
+package pim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// errQuotaExceeded is a sentinel classified by a custom rule
+var errQuotaExceeded = errors.New("quota exceeded")
+
+func TestErrorClassificationHook(t *testing.T) {
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.Level = InfoLevel
+	})
+
+	notRetryable := false
+	err := logger.AddErrorClassificationHook(ClassificationRule{
+		Class:     ErrorClassUser,
+		Errors:    []error{errQuotaExceeded},
+		Retryable: &notRetryable,
+	})
+	if err != nil {
+		t.Fatalf("Failed to add hook: %v", err)
+	}
+
+	logger.ErrorKV("upstream call failed", "error", fmt.Errorf("fetch: %w", context.DeadlineExceeded))
+	logger.ErrorKV("request rejected", "error", fmt.Errorf("upload: %w", errQuotaExceeded))
+	logger.ErrorKV("validation", "error", "invalid email address")
+	logger.ErrorKV("disk write failed", "error", errors.New("checksum mismatch"))
+	logger.Info("no classification")
+
+	expected := []struct {
+		class     ErrorClass
+		retryable bool
+	}{
+		{ErrorClassTransient, true},
+		{ErrorClassUser, false},
+		{ErrorClassUser, false},
+		{ErrorClassPermanent, false},
+	}
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 5 {
+		t.Fatalf("Expected 5 entries, got %d", len(entries))
+	}
+	for i, want := range expected {
+		if entries[i].Context[ErrorClassKey] != string(want.class) || entries[i].Context[RetryableKey] != want.retryable {
+			t.Errorf("Entry %q: expected %s/%v, got %v/%v", entries[i].Message, want.class, want.retryable,
+				entries[i].Context[ErrorClassKey], entries[i].Context[RetryableKey])
+		}
+	}
+	if _, ok := entries[4].Context[ErrorClassKey]; ok {
+		t.Error("Expected info entries not to be classified")
+	}
+}
+
+func TestErrorClassificationTypesAndMultiErrors(t *testing.T) {
+	hook, err := NewErrorClassificationHook(ErrorClassificationConfig{
+		HookConfig: HookConfig{Enabled: true},
+		Rules: []ClassificationRule{
+			{Class: ErrorClassTransient, Types: []string{"*pim.retryError"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create hook: %v", err)
+	}
+
+	entry := CoreLogEntry{Level: ErrorLevel, Context: map[string]interface{}{}}
+	entry.addFields(map[string]interface{}{"errors": errors.Join(errors.New("a"), &retryError{})})
+
+	if class, retryable := hook.Classify(entry); class != ErrorClassTransient || !retryable {
+		t.Errorf("Expected flattened error type to match, got %s/%v", class, retryable)
+	}
+
+	if _, err := NewErrorClassificationHook(ErrorClassificationConfig{
+		Rules: []ClassificationRule{{Patterns: []string{"("}}},
+	}); err == nil {
+		t.Error("Expected an invalid pattern to be rejected")
+	}
+}
+
+// retryError is an error type matched by name
+type retryError struct{}
+
+func (*retryError) Error() string { return "retry later" }
@@ -0,0 +1,72 @@
+package pim
+
+// Event is a logged entry that later entries link to as children, so
+// multi-step workflows can be rendered as trees. Each event has a random
+// event_id; its children carry it as parent_event_id.
+//
+//	order := logger.Event(InfoLevel, "order received")
+//	payment := order.Child(InfoLevel, "payment authorized")
+//	payment.Child(InfoLevel, "receipt sent")
+//	order.Logger().Warning("stock low") // Linked to order without an event_id
+type Event struct {
+	id       string
+	parentID string
+	base     *LoggerCore // The logger the event was created from
+	logger   *LoggerCore // base with event_id and parent_event_id
+}
+
+// Event logs an entry with a new event ID and returns it for linking child
+// entries; if the logger has a parent event (see WithParentEvent), the new
+// event is its child. The event is returned even if the entry is below the
+// level.
+// Event IDs are taken from the context, so they are only attached with
+// PropagateContext.
+func (l *LoggerCore) Event(level LogLevel, message string, args ...interface{}) *Event {
+	event := newEvent(l, "")
+	event.logger.log(1, level, getPrefixForLevel(level), message, args)
+	return event
+}
+
+// Child logs an entry with a new event ID whose parent is e and returns it
+func (e *Event) Child(level LogLevel, message string, args ...interface{}) *Event {
+	event := newEvent(e.base, e.id)
+	event.logger.log(1, level, getPrefixForLevel(level), message, args)
+	return event
+}
+
+// ID returns the event ID
+func (e *Event) ID() string {
+	return e.id
+}
+
+// ParentID returns the ID of the parent event, empty for a root event
+func (e *Event) ParentID() string {
+	return e.parentID
+}
+
+// Logger returns a logger whose entries are children of e
+func (e *Event) Logger() *LoggerCore {
+	return e.base.WithParentEvent(e.id)
+}
+
+// WithParentEvent returns a new logger whose entries are children of the
+// event with the given ID, e.g. one received from another service
+func (l *LoggerCore) WithParentEvent(eventID string) *LoggerCore {
+	return l.WithContext(map[string]interface{}{"parent_event_id": eventID})
+}
+
+// newEvent creates an event with a new ID below parentID, or below the
+// parent event of the logger (see WithParentEvent) if parentID is empty
+func newEvent(base *LoggerCore, parentID string) *Event {
+	event := &Event{id: newRandomID(), parentID: parentID, base: base}
+	fields := map[string]interface{}{"event_id": event.id}
+	if parentID != "" {
+		fields["parent_event_id"] = parentID
+	} else {
+		base.mu.RLock()
+		event.parentID, _ = base.context["parent_event_id"].(string)
+		base.mu.RUnlock()
+	}
+	event.logger = base.WithContext(fields)
+	return event
+}
@@ -0,0 +1,75 @@
+package pim
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestEventLinking(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+
+	order := logger.Event(InfoLevel, "order received")
+	payment := order.Child(InfoLevel, "payment authorized")
+	payment.Child(WarningLevel, "receipt delayed")
+	order.Logger().Info("note")
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 4 {
+		t.Fatalf("Expected 4 entries, got %d", len(entries))
+	}
+	if entries[0].EventID != order.ID() || entries[0].ParentEventID != "" || order.ParentID() != "" {
+		t.Errorf("Unexpected root entry: %+v", entries[0])
+	}
+	if entries[1].EventID != payment.ID() || entries[1].ParentEventID != order.ID() || payment.ParentID() != order.ID() {
+		t.Errorf("Unexpected child entry: %+v", entries[1])
+	}
+	if entries[2].ParentEventID != payment.ID() || entries[2].EventID == "" || entries[2].Level != WarningLevel {
+		t.Errorf("Unexpected grandchild entry: %+v", entries[2])
+	}
+	if entries[3].ParentEventID != order.ID() || entries[3].EventID != "" {
+		t.Errorf("Expected a plain child entry, got %+v", entries[3])
+	}
+
+	data, _ := json.Marshal(entries[1])
+	var decoded map[string]interface{}
+	json.Unmarshal(data, &decoded)
+	if decoded["event_id"] != payment.ID() || decoded["parent_event_id"] != order.ID() {
+		t.Errorf("Expected the event fields in JSON, got %s", data)
+	}
+}
+
+func TestEventUnderRemoteParent(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+
+	event := logger.WithParentEvent("remote").Event(InfoLevel, "continued")
+	if event.ParentID() != "remote" {
+		t.Errorf("Expected the remote parent, got %q", event.ParentID())
+	}
+	if entry := buffer.GetBuffer()[0]; entry.ParentEventID != "remote" || entry.EventID != event.ID() {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+}
+
+func TestEventKeepsCallerAndHooks(t *testing.T) {
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.CallerInfoConfig = NewCallerInfoConfig()
+		config.CallerInfoConfig.IncludeTest = true
+	})
+	logger.AddHookFunc(func(entry CoreLogEntry) (CoreLogEntry, error) {
+		entry.Message = "hooked " + entry.Message
+		return entry, nil
+	})
+
+	order := logger.Event(InfoLevel, "order received")
+	order.Child(InfoLevel, "payment authorized")
+
+	for _, entry := range buffer.GetBuffer() {
+		if entry.File != "event_link_test.go" {
+			t.Errorf("Expected the caller in this file, got %s:%d", entry.File, entry.Line)
+		}
+		if !strings.HasPrefix(entry.Message, "hooked ") {
+			t.Errorf("Expected the logger's hooks to run, got %q", entry.Message)
+		}
+	}
+}
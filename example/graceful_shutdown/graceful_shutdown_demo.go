@@ -23,8 +23,12 @@ func main() {
 	}
 
 	logger := pim.NewLoggerCore(config)
+	logger.RegisterShutdown(pim.ShutdownOptions{Timeout: 2 * time.Second})
 	defer logger.Close()
 
+	// The exit handler only sees signals; log the panic and flush here
+	defer pim.RecoverAndLogWithConfig(logger, pim.RecoverConfig{RePanic: true})
+
 	fmt.Println("Logging messages...")
 	for i := 1; i <= 5; i++ {
 		logger.InfoKV("Message before panic", "sequence", i)
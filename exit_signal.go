@@ -0,0 +1,14 @@
+//go:build !js && !wasip1
+
+package pim
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyExitSignals relays termination signals to ch
+func notifyExitSignals(ch chan os.Signal) {
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+}
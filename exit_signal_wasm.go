@@ -0,0 +1,8 @@
+//go:build js || wasip1
+
+package pim
+
+import "os"
+
+// notifyExitSignals is a no-op: WASM hosts do not deliver process signals
+func notifyExitSignals(ch chan os.Signal) {}
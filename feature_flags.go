@@ -0,0 +1,204 @@
+package pim
+
+import (
+	"sync"
+	"time"
+)
+
+// FeatureFlagProvider supplies the feature flags that were evaluated while serving a request
+type FeatureFlagProvider interface {
+	// EvaluatedFlags returns the flags evaluated for the given request ID.
+	// The boolean result reports whether any flag influenced the request.
+	EvaluatedFlags(requestID string) (map[string]interface{}, bool)
+}
+
+// FeatureFlagProviderFunc is a function type that implements FeatureFlagProvider
+type FeatureFlagProviderFunc func(requestID string) (map[string]interface{}, bool)
+
+// EvaluatedFlags implements FeatureFlagProvider interface for FeatureFlagProviderFunc
+func (f FeatureFlagProviderFunc) EvaluatedFlags(requestID string) (map[string]interface{}, bool) {
+	return f(requestID)
+}
+
+// FeatureFlagConfig holds configuration for feature flag enrichment hooks
+type FeatureFlagConfig struct {
+	HookConfig
+	Provider  FeatureFlagProvider `json:"-"`                   // Source of evaluated flags
+	Allowlist []string            `json:"allowlist,omitempty"` // Flags allowed into entries (empty = all)
+	FieldName string              `json:"field_name"`          // Context key for the snapshot (default: "feature_flags")
+	CacheTTL  time.Duration       `json:"cache_ttl"`           // How long a request's snapshot is cached (default: 1 minute)
+	CacheSize int                 `json:"cache_size"`          // Maximum number of cached requests (default: 1000)
+}
+
+// featureFlagSnapshot is a cached, allowlist-filtered flag snapshot
+type featureFlagSnapshot struct {
+	flags   map[string]interface{}
+	expires time.Time
+}
+
+// FeatureFlagHook snapshots evaluated feature flags into log entries
+type FeatureFlagHook struct {
+	config    FeatureFlagConfig
+	allowlist map[string]bool
+	cache     map[string]featureFlagSnapshot
+	mu        sync.Mutex
+}
+
+// NewFeatureFlagHook creates a new feature flag enrichment hook
+func NewFeatureFlagHook(config FeatureFlagConfig) *FeatureFlagHook {
+	if config.FieldName == "" {
+		config.FieldName = "feature_flags"
+	}
+	if config.CacheTTL == 0 {
+		config.CacheTTL = time.Minute
+	}
+	if config.CacheSize == 0 {
+		config.CacheSize = 1000
+	}
+
+	allowlist := make(map[string]bool, len(config.Allowlist))
+	for _, name := range config.Allowlist {
+		allowlist[name] = true
+	}
+
+	return &FeatureFlagHook{
+		config:    config,
+		allowlist: allowlist,
+		cache:     make(map[string]featureFlagSnapshot),
+	}
+}
+
+// Process implements LogHook interface
+func (h *FeatureFlagHook) Process(entry CoreLogEntry) (CoreLogEntry, error) {
+	if !h.config.Enabled || h.config.Provider == nil {
+		return entry, nil
+	}
+
+	requestID := entry.RequestID
+	if requestID == "" && entry.Context != nil {
+		if s, ok := entry.Context["request_id"].(string); ok {
+			requestID = s
+		}
+	}
+	if requestID == "" {
+		return entry, nil
+	}
+
+	flags := h.snapshot(requestID)
+	if len(flags) == 0 {
+		return entry, nil
+	}
+
+	if entry.Context == nil {
+		entry.Context = make(map[string]interface{})
+	}
+	entry.Context[h.config.FieldName] = flags
+
+	return entry, nil
+}
+
+// snapshot returns the cached flag snapshot for a request, querying the provider on a miss
+func (h *FeatureFlagHook) snapshot(requestID string) map[string]interface{} {
+	now := time.Now()
+
+	h.mu.Lock()
+	if cached, ok := h.cache[requestID]; ok && now.Before(cached.expires) {
+		h.mu.Unlock()
+		return cached.flags
+	}
+	h.mu.Unlock()
+
+	evaluated, influenced := h.config.Provider.EvaluatedFlags(requestID)
+	var flags map[string]interface{}
+	if influenced {
+		flags = h.filter(evaluated)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.cache) >= h.config.CacheSize {
+		h.evictExpired(now)
+		if len(h.cache) >= h.config.CacheSize {
+			h.cache = make(map[string]featureFlagSnapshot)
+		}
+	}
+	h.cache[requestID] = featureFlagSnapshot{
+		flags:   flags,
+		expires: now.Add(h.config.CacheTTL),
+	}
+
+	return flags
+}
+
+// filter copies the evaluated flags, keeping only allowlisted names
+func (h *FeatureFlagHook) filter(evaluated map[string]interface{}) map[string]interface{} {
+	flags := make(map[string]interface{}, len(evaluated))
+	for name, value := range evaluated {
+		if len(h.allowlist) > 0 && !h.allowlist[name] {
+			continue
+		}
+		flags[name] = value
+	}
+	return flags
+}
+
+// evictExpired removes expired snapshots from the cache
+func (h *FeatureFlagHook) evictExpired(now time.Time) {
+	for requestID, cached := range h.cache {
+		if !now.Before(cached.expires) {
+			delete(h.cache, requestID)
+		}
+	}
+}
+
+// ClearCache removes all cached flag snapshots
+func (h *FeatureFlagHook) ClearCache() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cache = make(map[string]featureFlagSnapshot)
+}
+
+// GetConfig implements EnhancedLogHook interface
+func (h *FeatureFlagHook) GetConfig() HookConfig {
+	return h.config.HookConfig
+}
+
+// GetType implements EnhancedLogHook interface
+func (h *FeatureFlagHook) GetType() HookType {
+	return h.config.Type
+}
+
+// IsEnabled implements EnhancedLogHook interface
+func (h *FeatureFlagHook) IsEnabled() bool {
+	return h.config.Enabled
+}
+
+// SetEnabled implements EnhancedLogHook interface
+func (h *FeatureFlagHook) SetEnabled(enabled bool) {
+	h.config.Enabled = enabled
+}
+
+// GetPriority implements EnhancedLogHook interface
+func (h *FeatureFlagHook) GetPriority() int {
+	return h.config.Priority
+}
+
+// SetPriority implements EnhancedLogHook interface
+func (h *FeatureFlagHook) SetPriority(priority int) {
+	h.config.Priority = priority
+}
+
+// AddFeatureFlagHook adds a hook that snapshots allowlisted feature flags into entries
+func (l *LoggerCore) AddFeatureFlagHook(provider FeatureFlagProvider, allowlist ...string) {
+	l.AddEnhancedHook(NewFeatureFlagHook(FeatureFlagConfig{
+		HookConfig: HookConfig{
+			Type:        HookTypeEnrich,
+			Name:        "feature_flags",
+			Description: "Snapshots evaluated feature flags into log entries",
+			Enabled:     true,
+			Priority:    30,
+		},
+		Provider:  provider,
+		Allowlist: allowlist,
+	}))
+}
@@ -0,0 +1,90 @@
+package pim
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestFeatureFlagHook(provider FeatureFlagProvider, allowlist ...string) *FeatureFlagHook {
+	return NewFeatureFlagHook(FeatureFlagConfig{
+		HookConfig: HookConfig{
+			Type:     HookTypeEnrich,
+			Name:     "test_flags",
+			Enabled:  true,
+			Priority: 30,
+		},
+		Provider:  provider,
+		Allowlist: allowlist,
+	})
+}
+
+func TestFeatureFlagHookSnapshot(t *testing.T) {
+	hook := newTestFeatureFlagHook(FeatureFlagProviderFunc(func(requestID string) (map[string]interface{}, bool) {
+		return map[string]interface{}{"new_checkout": true, "experiment_id": "exp-42"}, true
+	}), "new_checkout")
+
+	entry := CoreLogEntry{Level: InfoLevel, Message: "checkout", RequestID: "req-1"}
+	result, err := hook.Process(entry)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	flags, ok := result.Context["feature_flags"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected feature_flags in context, got: %v", result.Context)
+	}
+	if flags["new_checkout"] != true {
+		t.Errorf("Expected new_checkout flag, got: %v", flags)
+	}
+	if _, exists := flags["experiment_id"]; exists {
+		t.Error("Expected non-allowlisted flag to be dropped")
+	}
+}
+
+func TestFeatureFlagHookSkipsUninfluencedRequests(t *testing.T) {
+	hook := newTestFeatureFlagHook(FeatureFlagProviderFunc(func(requestID string) (map[string]interface{}, bool) {
+		return nil, false
+	}))
+
+	result, _ := hook.Process(CoreLogEntry{Level: InfoLevel, Message: "msg", RequestID: "req-1"})
+	if _, exists := result.Context["feature_flags"]; exists {
+		t.Error("Expected no flags for request without flag influence")
+	}
+
+	// Entries without a request ID are left untouched
+	result, _ = hook.Process(CoreLogEntry{Level: InfoLevel, Message: "msg"})
+	if result.Context != nil {
+		t.Errorf("Expected context to stay nil, got: %v", result.Context)
+	}
+}
+
+func TestFeatureFlagHookCaching(t *testing.T) {
+	calls := 0
+	hook := newTestFeatureFlagHook(FeatureFlagProviderFunc(func(requestID string) (map[string]interface{}, bool) {
+		calls++
+		return map[string]interface{}{"flag": calls}, true
+	}))
+
+	entry := CoreLogEntry{
+		Level:   InfoLevel,
+		Message: "msg",
+		Context: map[string]interface{}{"request_id": "req-1"},
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := hook.Process(entry); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected provider to be called once, got %d", calls)
+	}
+
+	hook.config.CacheTTL = time.Nanosecond
+	hook.ClearCache()
+	hook.Process(entry)
+	time.Sleep(time.Millisecond)
+	hook.Process(entry)
+	if calls != 3 {
+		t.Errorf("Expected expired snapshots to be refreshed, got %d calls", calls)
+	}
+}
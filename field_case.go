@@ -0,0 +1,173 @@
+package pim
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// FieldCase is a naming convention for field names in writer output
+type FieldCase string
+
+// Supported field name conventions. The zero value keeps names unchanged.
+const (
+	FieldCaseSnake  FieldCase = "snake_case" // service_name
+	FieldCaseCamel  FieldCase = "camelCase"  // serviceName
+	FieldCasePascal FieldCase = "PascalCase" // ServiceName
+)
+
+// Convert rewrites name in the convention. Words are split on underscores,
+// hyphens, spaces and case changes, so names can be converted from any of
+// the supported conventions; dot-separated namespaces are converted segment
+// by segment, e.g. "http.status_code" becomes "http.statusCode".
+func (c FieldCase) Convert(name string) string {
+	if c == "" {
+		return name
+	}
+
+	segments := strings.Split(name, ".")
+	for i, segment := range segments {
+		words := splitWords(segment)
+		if len(words) == 0 {
+			continue
+		}
+		for j, word := range words {
+			word = strings.ToLower(word)
+			if c == FieldCasePascal || (c == FieldCaseCamel && j > 0) {
+				word = capitalize(word)
+			}
+			words[j] = word
+		}
+		if c == FieldCaseSnake {
+			segments[i] = strings.Join(words, "_")
+		} else {
+			segments[i] = strings.Join(words, "")
+		}
+	}
+	return strings.Join(segments, ".")
+}
+
+// Validate reports whether c is a supported convention
+func (c FieldCase) Validate() error {
+	switch c {
+	case "", FieldCaseSnake, FieldCaseCamel, FieldCasePascal:
+		return nil
+	}
+	return fmt.Errorf("unknown field case %q", string(c))
+}
+
+// splitWords splits a name into words at separators and case changes,
+// keeping acronyms together ("HTTPServer" -> "HTTP", "Server")
+func splitWords(s string) []string {
+	var words []string
+	runes := []rune(s)
+	start := -1
+
+	for i, r := range runes {
+		if r == '_' || r == '-' || r == ' ' {
+			if start >= 0 {
+				words = append(words, string(runes[start:i]))
+				start = -1
+			}
+			continue
+		}
+
+		if start >= 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			acronymEnd := unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || acronymEnd {
+				words = append(words, string(runes[start:i]))
+				start = i
+			}
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+
+	if start >= 0 {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+// capitalize upper-cases the first letter of a word
+func capitalize(word string) string {
+	runes := []rune(word)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// convertKeys returns a copy of context with keys in the convention
+func (c FieldCase) convertKeys(context map[string]interface{}) map[string]interface{} {
+	if c == "" || context == nil {
+		return context
+	}
+	converted := make(map[string]interface{}, len(context))
+	for k, v := range context {
+		converted[c.Convert(k)] = v
+	}
+	return converted
+}
+
+// caseField is an exported field of CoreLogEntry with its JSON name in a
+// convention
+type caseField struct {
+	index     int
+	name      string
+	omitEmpty bool
+}
+
+// caseFields caches the fields of CoreLogEntry per FieldCase
+var caseFields sync.Map
+
+// fields returns the JSON fields of CoreLogEntry named in the convention
+func (c FieldCase) fields() []caseField {
+	if cached, ok := caseFields.Load(c); ok {
+		return cached.([]caseField)
+	}
+	var fields []caseField
+	entryType := reflect.TypeOf(CoreLogEntry{})
+	for i := 0; i < entryType.NumField(); i++ {
+		field := entryType.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, caseField{
+			index:     i,
+			name:      c.Convert(name),
+			omitEmpty: strings.Contains(","+options+",", ",omitempty,"),
+		})
+	}
+	caseFields.Store(c, fields)
+	return fields
+}
+
+// marshalEntry encodes an entry as JSON with built-in and context field
+// names in the convention. Only top-level context keys are converted; the
+// keys of nested values are data, e.g. HTTP headers, and kept as they are.
+func (c FieldCase) marshalEntry(entry CoreLogEntry) ([]byte, error) {
+	entry = withErrorChains(entry)
+	if c == "" {
+		return json.Marshal(entry)
+	}
+	entry.Context = c.convertKeys(entry.Context)
+
+	value := reflect.ValueOf(entry)
+	object := make(map[string]interface{}, value.NumField())
+	for _, field := range c.fields() {
+		fieldValue := value.Field(field.index)
+		if field.omitEmpty && isEmptyJSONValue(fieldValue) {
+			continue
+		}
+		object[field.name] = fieldValue.Interface()
+	}
+	return json.Marshal(object)
+}
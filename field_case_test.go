@@ -0,0 +1,97 @@
+package pim
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFieldCaseConvert(t *testing.T) {
+	tests := []struct {
+		name   string
+		fc     FieldCase
+		expect string
+	}{
+		{"service_name", FieldCaseCamel, "serviceName"},
+		{"service_name", FieldCasePascal, "ServiceName"},
+		{"serviceName", FieldCaseSnake, "service_name"},
+		{"HTTPStatusCode", FieldCaseSnake, "http_status_code"},
+		{"user-agent", FieldCaseCamel, "userAgent"},
+		{"http.status_code", FieldCaseCamel, "http.statusCode"},
+		{"retry2Count", FieldCaseSnake, "retry2_count"},
+		{"unchanged_key", "", "unchanged_key"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.fc.Convert(tt.name); got != tt.expect {
+			t.Errorf("%q.Convert(%q) = %q, expected %q", tt.fc, tt.name, got, tt.expect)
+		}
+	}
+
+	if err := FieldCase("kebab").Validate(); err == nil {
+		t.Error("Expected unknown field case to be rejected")
+	}
+}
+
+func TestFileWriterFieldCase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	writer, err := NewFileWriter(path, LoggerConfig{EnableJSON: true, FieldCase: FieldCaseCamel}, RotationConfig{})
+	if err != nil {
+		t.Fatalf("NewFileWriter failed: %v", err)
+	}
+	writer.Write(CoreLogEntry{
+		Level:       InfoLevel,
+		LevelString: "info",
+		Message:     "hello",
+		ServiceName: "api",
+		Context:     map[string]interface{}{"request_path": "/", "payload": map[string]interface{}{"item_count": 2}},
+	})
+	writer.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", data, err)
+	}
+
+	if decoded["serviceName"] != "api" || decoded["levelString"] != "info" {
+		t.Errorf("Expected built-in fields in camelCase, got %v", decoded)
+	}
+	context, _ := decoded["context"].(map[string]interface{})
+	if context["requestPath"] != "/" {
+		t.Errorf("Expected context keys in camelCase, got %v", context)
+	}
+	if payload, _ := context["payload"].(map[string]interface{}); payload["item_count"] != float64(2) {
+		t.Errorf("Expected nested keys to be kept, got %v", context["payload"])
+	}
+	if _, ok := decoded["file"]; ok {
+		t.Errorf("Expected empty omitempty fields to be left out, got %v", decoded)
+	}
+}
+
+func TestLoggerFieldCase(t *testing.T) {
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.FieldCase = FieldCasePascal
+	})
+	logger.Info("hello", Fields(map[string]interface{}{"order_id": "o-1"}))
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	if entries[0].Context["OrderId"] != "o-1" {
+		t.Errorf("Expected context keys in PascalCase, got %v", entries[0].Context)
+	}
+}
+
+func TestParseConfigRejectsUnknownFieldCase(t *testing.T) {
+	_, err := ParseConfig([]byte(`{"logger": {"field_case": "kebab"}}`), ".json")
+	if err == nil || !strings.Contains(err.Error(), "field case") {
+		t.Errorf("Expected field case error, got %v", err)
+	}
+}
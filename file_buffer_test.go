@@ -0,0 +1,124 @@
+package pim
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBufferedFileWriterFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	writer, err := NewBufferedFileWriter(path, DefaultLoggerConfig, RotationConfig{}, FileBufferConfig{FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("NewBufferedFileWriter failed: %v", err)
+	}
+	defer writer.Close()
+
+	writer.Write(CoreLogEntry{Level: InfoLevel, LevelString: "info", Message: "buffered"})
+	if data, _ := os.ReadFile(path); len(data) != 0 {
+		t.Fatalf("Expected entry to stay buffered, file has %q", data)
+	}
+
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "buffered") {
+		t.Errorf("Expected entry after Flush, got %q", data)
+	}
+}
+
+func TestBufferedFileWriterInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	writer, err := NewBufferedFileWriter(path, DefaultLoggerConfig, RotationConfig{}, FileBufferConfig{
+		FlushInterval: 10 * time.Millisecond,
+		Sync:          true,
+	})
+	if err != nil {
+		t.Fatalf("NewBufferedFileWriter failed: %v", err)
+	}
+	defer writer.Close()
+
+	writer.Write(CoreLogEntry{Level: InfoLevel, LevelString: "info", Message: "tick"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, _ := os.ReadFile(path); strings.Contains(string(data), "tick") {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("Expected entry to be written by the flush interval")
+}
+
+func TestBufferedFileWriterRotationAndClose(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	writer, err := NewBufferedFileWriter(path, DefaultLoggerConfig, RotationConfig{}, FileBufferConfig{FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("NewBufferedFileWriter failed: %v", err)
+	}
+
+	writer.Write(CoreLogEntry{Level: InfoLevel, LevelString: "info", Message: "before rotation"})
+	if err := writer.rotateFile(); err != nil {
+		t.Fatalf("rotateFile failed: %v", err)
+	}
+	writer.Write(CoreLogEntry{Level: InfoLevel, LevelString: "info", Message: "after rotation"})
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "app.*.log"))
+	if len(files) != 1 {
+		t.Fatalf("Expected one rotated file, got %v", files)
+	}
+	if data, _ := os.ReadFile(files[0]); !strings.Contains(string(data), "before rotation") {
+		t.Errorf("Expected buffered entry to be flushed into the rotated file, got %q", data)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "after rotation") {
+		t.Errorf("Expected Close to flush the active file, got %q", data)
+	}
+}
+
+func benchmarkFileWriter(b *testing.B, buffered bool) {
+	filename := filepath.Join(b.TempDir(), "benchmark.log")
+	config := LoggerConfig{Level: InfoLevel, EnableJSON: true}
+
+	var writer *FileWriter
+	var err error
+	if buffered {
+		writer, err = NewBufferedFileWriter(filename, config, RotationConfig{}, FileBufferConfig{})
+	} else {
+		writer, err = NewFileWriter(filename, config, RotationConfig{})
+	}
+	if err != nil {
+		b.Fatalf("Failed to create FileWriter: %v", err)
+	}
+	defer writer.Close()
+
+	entry := CoreLogEntry{
+		Timestamp:   time.Now(),
+		Level:       InfoLevel,
+		Message:     "Benchmark message",
+		ServiceName: "benchmark",
+		Context:     map[string]interface{}{"request_id": "abc123"},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			writer.Write(entry)
+		}
+	})
+}
+
+func BenchmarkFileWriterParallel(b *testing.B) {
+	for _, buffered := range []bool{false, true} {
+		b.Run(fmt.Sprintf("buffered=%v", buffered), func(b *testing.B) {
+			benchmarkFileWriter(b, buffered)
+		})
+	}
+}
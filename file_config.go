@@ -0,0 +1,430 @@
+package pim
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileConfig is the content of a logger configuration file. Field names
+// follow the JSON tags of LoggerConfig and the hook configs in both JSON and
+// YAML files, e.g.
+//
+//	level: debug
+//	logger:
+//	  service_name: billing
+//	  enable_json: true
+//	hooks:
+//	  - redact:
+//	      name: secrets
+//	      enabled: true
+//	      fields: [password, card.number]
+//	      replacement: "[REDACTED]"
+//	writers:
+//	  - name: app
+//	    file:
+//	      path: /var/log/billing/app.log
+//	      rotation:
+//	        max_size: 104857600
+//
+// ConfigSchema describes the format as a JSON Schema.
+type FileConfig struct {
+	Logger  LoggerConfig       `json:"logger"`
+	Level   string             `json:"level,omitempty"` // Level name, overrides logger.level
+	Hooks   []HookDefinition   `json:"hooks,omitempty"`
+	Writers []WriterDefinition `json:"writers,omitempty"` // Applied at startup only
+}
+
+// HookDefinition declares one hook in a configuration file. Exactly one of
+// Filter, Redact, Enrich and Validate must be set; its hook type is filled
+// in. Validate hooks cannot use ValidationQuarantine, which needs a writer.
+type HookDefinition struct {
+	Filter   *FilterConfig     `json:"filter,omitempty"`
+	Redact   *RedactConfig     `json:"redact,omitempty"`
+	Enrich   *EnrichConfig     `json:"enrich,omitempty"`
+	Validate *ValidationConfig `json:"validate,omitempty"`
+}
+
+// WriterDefinition declares one writer in a configuration file. Exactly one
+// of File and Remote must be set. Named writers are added with
+// AddNamedWriter so entries can target them with ToWritersOnly. Dedup
+// wraps the writer in a DedupWriter.
+type WriterDefinition struct {
+	Name   string              `json:"name,omitempty"`
+	File   *FileWriterConfig   `json:"file,omitempty"`
+	Remote *RemoteWriterConfig `json:"remote,omitempty"`
+	Dedup  *DedupConfig        `json:"dedup,omitempty"`
+}
+
+// FileWriterConfig declares a file writer
+type FileWriterConfig struct {
+	Path     string         `json:"path"` // May contain tokens such as {hostname}, see ExpandFileName
+	Rotation RotationConfig `json:"rotation"`
+}
+
+// LoadConfig reads a JSON or YAML (.yaml, .yml) logger configuration file
+func LoadConfig(path string) (*FileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return ParseConfig(data, filepath.Ext(path))
+}
+
+// ParseConfig parses a configuration in the format given by the file
+// extension ext (".json", ".yaml" or ".yml")
+func ParseConfig(data []byte, ext string) (*FileConfig, error) {
+	data, err := configJSON(data, ext)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &FileConfig{Logger: DefaultLoggerConfig}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	if config.Level != "" {
+		level, err := ParseLevel(config.Level)
+		if err != nil {
+			return nil, err
+		}
+		config.Logger.Level = level
+	}
+	if err := config.Logger.FieldCase.Validate(); err != nil {
+		return nil, err
+	}
+	if err := config.Logger.ConsoleMode.Validate(); err != nil {
+		return nil, err
+	}
+	if err := config.Logger.IconMode.Validate(); err != nil {
+		return nil, err
+	}
+	if err := config.Logger.ValidateCrypto(); err != nil {
+		return nil, err
+	}
+	if config.Logger.ThemeFile != "" {
+		if _, err := LoadThemeFromFile(config.Logger.ThemeFile); err != nil {
+			return nil, err
+		}
+	}
+
+	// Validate hooks up front so a bad file never replaces a good one
+	if _, err := config.BuildHooks(); err != nil {
+		return nil, err
+	}
+	if err := config.validateWriters(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// configJSON returns a configuration in the format given by the file
+// extension as JSON
+func configJSON(data []byte, ext string) ([]byte, error) {
+	switch strings.ToLower(ext) {
+	case ".yaml", ".yml":
+		// Convert to JSON so YAML keys use the same names as JSON
+		var raw interface{}
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML config: %w", err)
+		}
+		converted, err := json.Marshal(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert YAML config: %w", err)
+		}
+		return converted, nil
+	case ".json", "":
+		return data, nil
+	default:
+		return nil, fmt.Errorf("unsupported config file format %q", ext)
+	}
+}
+
+// validateWriters checks the writer definitions
+func (c *FileConfig) validateWriters() error {
+	names := make(map[string]bool)
+	for i, def := range c.Writers {
+		switch {
+		case (def.File == nil) == (def.Remote == nil):
+			return fmt.Errorf("writer %d: exactly one of file or remote must be set", i)
+		case def.File != nil && def.File.Path == "":
+			return fmt.Errorf("writer %d: file path is required", i)
+		case def.Remote != nil && def.Remote.Endpoint == "":
+			return fmt.Errorf("writer %d: remote endpoint is required", i)
+		}
+		if def.Name != "" {
+			if names[def.Name] {
+				return fmt.Errorf("writer %d: duplicate name %q", i, def.Name)
+			}
+			names[def.Name] = true
+		}
+	}
+	return nil
+}
+
+// BuildWriters creates the writers defined in the configuration. On error
+// the writers created so far are closed.
+func (c *FileConfig) BuildWriters() ([]LogWriter, error) {
+	if err := c.validateWriters(); err != nil {
+		return nil, err
+	}
+	writers := make([]LogWriter, 0, len(c.Writers))
+	for i, def := range c.Writers {
+		var writer LogWriter
+		if def.Remote != nil {
+			writer = NewRemoteWriter(c.Logger, *def.Remote)
+		} else {
+			fileWriter, err := NewFileWriter(def.File.Path, c.Logger, def.File.Rotation)
+			if err != nil {
+				for _, created := range writers {
+					created.Close()
+				}
+				return nil, fmt.Errorf("writer %d: %w", i, err)
+			}
+			writer = fileWriter
+		}
+		if def.Dedup != nil {
+			writer = NewDedupWriter(writer, *def.Dedup)
+		}
+		writers = append(writers, writer)
+	}
+	return writers, nil
+}
+
+// BuildHooks creates the hooks defined in the configuration
+func (c *FileConfig) BuildHooks() ([]EnhancedLogHook, error) {
+	hooks := make([]EnhancedLogHook, 0, len(c.Hooks))
+	names := make(map[string]bool)
+
+	for i, def := range c.Hooks {
+		var hook EnhancedLogHook
+		set := 0
+		if def.Filter != nil {
+			config := *def.Filter
+			config.Type = HookTypeFilter
+			hook, set = NewFilterHook(config), set+1
+		}
+		if def.Redact != nil {
+			config := *def.Redact
+			config.Type = HookTypeRedact
+			if err := config.validate(c.Logger.fipsMode()); err != nil {
+				return nil, fmt.Errorf("hook %d: %w", i, err)
+			}
+			hook, set = NewRedactHook(config), set+1
+		}
+		if def.Enrich != nil {
+			config := *def.Enrich
+			config.Type = HookTypeEnrich
+			hook, set = NewEnrichHook(config), set+1
+		}
+		if def.Validate != nil {
+			config := *def.Validate
+			config.Type = HookTypeValidate
+			validation, err := NewValidationHook(config)
+			if err != nil {
+				return nil, fmt.Errorf("hook %d: %w", i, err)
+			}
+			hook, set = validation, set+1
+		}
+
+		if set != 1 {
+			return nil, fmt.Errorf("hook %d: exactly one of filter, redact, enrich or validate must be set", i)
+		}
+		name := hook.GetConfig().Name
+		if name == "" {
+			return nil, fmt.Errorf("hook %d: name is required", i)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("hook %d: duplicate name %q", i, name)
+		}
+		names[name] = true
+		hooks = append(hooks, hook)
+	}
+	return hooks, nil
+}
+
+// NewLogger creates a logger from the configuration with its hooks and
+// writers added
+func (c *FileConfig) NewLogger() (*LoggerCore, error) {
+	hooks, err := c.BuildHooks()
+	if err != nil {
+		return nil, err
+	}
+	writers, err := c.BuildWriters()
+	if err != nil {
+		return nil, err
+	}
+	logger, err := NewCheckedLoggerCore(c.Logger)
+	if err != nil {
+		return nil, err
+	}
+	for _, hook := range hooks {
+		logger.AddEnhancedHook(hook)
+	}
+	for i, writer := range writers {
+		if name := c.Writers[i].Name; name != "" {
+			logger.AddNamedWriter(name, writer)
+		} else {
+			logger.AddWriter(writer)
+		}
+	}
+	return logger, nil
+}
+
+// ConfigWatcher keeps a logger in sync with a configuration file
+type ConfigWatcher struct {
+	logger    *LoggerCore
+	path      string
+	mu        sync.Mutex
+	hookNames []string
+	lastHash  [sha256.Size]byte
+	badHash   [sha256.Size]byte // Last content that failed to load, reported once
+	current   *FileConfig       // Last applied configuration
+	callbacks []func(ConfigChangeEvent)
+
+	// OnError is called when a changed file cannot be loaded; the previous
+	// configuration stays active. Errors are written to stderr if nil.
+	OnError func(error)
+}
+
+// WatchConfigFile applies the configuration file to the logger and polls it
+// for changes until stopCh is closed. Reloads update the level, sampling
+// (when set) and the hooks defined in the file; other settings apply only at
+// startup. Hooks added in code are left alone.
+//
+// Each reload logs a ConfigReloadedMessage info entry, even if the new level
+// would drop it, with the changed settings (old and new values), the file
+// path and its SHA-256, and calls the callbacks registered with OnChange.
+func (l *LoggerCore) WatchConfigFile(path string, pollInterval time.Duration, stopCh <-chan struct{}) (*ConfigWatcher, error) {
+	watcher := &ConfigWatcher{logger: l, path: path}
+	if _, err := watcher.Reload(); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-time.After(pollInterval):
+				if _, err := watcher.Reload(); err != nil {
+					watcher.reportError(err)
+				}
+			}
+		}
+	}()
+
+	return watcher, nil
+}
+
+// OnChange registers a callback called after each reload that applied a
+// changed file, but not for the initial load
+func (w *ConfigWatcher) OnChange(callback func(ConfigChangeEvent)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callbacks = append(w.callbacks, callback)
+}
+
+// Reload applies the configuration file if it changed since the last load
+// and reports whether it was applied
+func (w *ConfigWatcher) Reload() (bool, error) {
+	event, callbacks, err := w.load()
+	if event == nil {
+		return false, err
+	}
+
+	w.logger.LogWithContext(InfoLevel, ConfigPrefix, ConfigReloadedMessage, map[string]interface{}{
+		"config_file":            event.Path,
+		"config_sha256":          event.Hash,
+		"config_previous_sha256": event.PreviousHash,
+		"config_changes":         event.Changes,
+	}, WithLevelOverride(InfoLevel), BypassSampling())
+	for _, callback := range callbacks {
+		callback(*event)
+	}
+	return true, nil
+}
+
+// load applies the configuration file if it changed. It returns the change
+// and the callbacks to notify for a reload, or a nil event if nothing was
+// applied or this was the initial load.
+func (w *ConfigWatcher) load() (*ConfigChangeEvent, []func(ConfigChangeEvent), error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	hash := sha256.Sum256(data)
+	if hash == w.lastHash || hash == w.badHash {
+		return nil, nil, nil
+	}
+
+	config, err := ParseConfig(data, filepath.Ext(w.path))
+	if err == nil {
+		var hooks []EnhancedLogHook
+		if hooks, err = config.BuildHooks(); err == nil {
+			w.apply(config, hooks)
+			previous, previousHash := w.current, w.lastHash
+			w.current, w.lastHash = config, hash
+			if previous == nil {
+				return nil, nil, nil
+			}
+
+			changes, err := DiffConfigs(previous, config)
+			if err != nil {
+				return nil, nil, err
+			}
+			event := &ConfigChangeEvent{
+				Path:         w.path,
+				Hash:         hex.EncodeToString(hash[:]),
+				PreviousHash: hex.EncodeToString(previousHash[:]),
+				Changes:      changes,
+				Old:          previous,
+				New:          config,
+			}
+			callbacks := append([]func(ConfigChangeEvent){}, w.callbacks...)
+			return event, callbacks, nil
+		}
+	}
+	w.badHash = hash
+	return nil, nil, err
+}
+
+// apply updates the logger from a loaded configuration
+func (w *ConfigWatcher) apply(config *FileConfig, hooks []EnhancedLogHook) {
+	w.logger.SetLevel(config.Logger.Level)
+	if config.Logger.SamplingByLevel != nil {
+		w.logger.SetSamplingByLevel(config.Logger.SamplingByLevel)
+	}
+	if w.logger.hookManager != nil {
+		w.logger.hookManager.ReplaceHooks(w.hookNames, hooks)
+	}
+
+	w.hookNames = w.hookNames[:0]
+	for _, hook := range hooks {
+		w.hookNames = append(w.hookNames, hook.GetConfig().Name)
+	}
+}
+
+// reportError passes a reload error to OnError or stderr
+func (w *ConfigWatcher) reportError(err error) {
+	if w.OnError != nil {
+		w.OnError(err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Failed to reload log config %s: %v\n", w.path, err)
+}
@@ -0,0 +1,193 @@
+package pim
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testYAMLConfig = `
+level: debug
+logger:
+  service_name: billing
+hooks:
+  - redact:
+      name: secrets
+      enabled: true
+      fields: [password]
+      replacement: "[REDACTED]"
+  - enrich:
+      name: region
+      enabled: true
+      fields:
+        region: eu-west-1
+`
+
+func TestLoadConfigYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pim.yaml")
+	os.WriteFile(path, []byte(testYAMLConfig), 0644)
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.Logger.Level != DebugLevel || config.Logger.ServiceName != "billing" {
+		t.Errorf("Unexpected logger config: level=%v service=%q", config.Logger.Level, config.Logger.ServiceName)
+	}
+	if config.Logger.TimestampFormat != DefaultLoggerConfig.TimestampFormat {
+		t.Error("Expected unset fields to keep their defaults")
+	}
+
+	logger, err := config.NewLogger()
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	buffer := NewBufferWriter(LoggerConfig{}, 10)
+	logger.AddWriter(buffer)
+
+	logger.InfoKV("login", "password", "hunter2")
+	entry := buffer.GetBuffer()[0]
+	if entry.Context["password"] != "[REDACTED]" || entry.Context["region"] != "eu-west-1" {
+		t.Errorf("Expected file-defined hooks to run, got %v", entry.Context)
+	}
+}
+
+func TestParseConfigRejectsInvalidHooks(t *testing.T) {
+	cases := map[string]string{
+		"unknown field": `{"logger": {"levle": 1}}`,
+		"bad level":     `{"level": "loud"}`,
+		"no kind":       `{"hooks": [{}]}`,
+		"no name":       `{"hooks": [{"filter": {"enabled": true}}]}`,
+		"bad pattern":   `{"hooks": [{"redact": {"name": "r", "patterns": {"message": "("}}}]}`,
+	}
+	for name, data := range cases {
+		if _, err := ParseConfig([]byte(data), ".json"); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestWatchConfigFileReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pim.json")
+	os.WriteFile(path, []byte(`{"level": "info", "hooks": [{"redact": {"name": "secrets", "enabled": true, "fields": ["token"], "replacement": "***"}}]}`), 0644)
+
+	logger := NewLoggerCore(LoggerConfig{Level: InfoLevel})
+	logger.AddEnhancedHook(NewRequestIDEnrichHook())
+	buffer := NewBufferWriter(LoggerConfig{}, 10)
+	logger.AddWriter(buffer)
+
+	watcher, err := logger.WatchConfigFile(path, time.Hour, make(chan struct{}))
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	var reloadErrors []error
+	watcher.OnError = func(err error) { reloadErrors = append(reloadErrors, err) }
+
+	os.WriteFile(path, []byte(`{"level": "debug", "hooks": [{"redact": {"name": "secrets", "enabled": true, "fields": ["token", "api_key"], "replacement": "***"}}]}`), 0644)
+	if applied, err := watcher.Reload(); !applied || err != nil {
+		t.Fatalf("Expected reload to apply, got %v, %v", applied, err)
+	}
+	if logger.GetLevel() != DebugLevel {
+		t.Errorf("Expected level to be reloaded, got %v", logger.GetLevel())
+	}
+	if logger.hookManager.GetHookCount() != 2 {
+		t.Errorf("Expected the file hook to be replaced and code hooks kept, got %d hooks", logger.hookManager.GetHookCount())
+	}
+
+	logger.DebugKV("call", "api_key", "k-1")
+	entries := buffer.GetBuffer()
+	if got := entries[len(entries)-1].Context["api_key"]; got != "***" {
+		t.Errorf("Expected reloaded redaction rule to apply, got %v", got)
+	}
+
+	// A broken file keeps the previous configuration
+	os.WriteFile(path, []byte(`{"level": `), 0644)
+	if applied, err := watcher.Reload(); applied || err == nil || !strings.Contains(err.Error(), "parse") {
+		t.Errorf("Expected broken config to be rejected, got %v, %v", applied, err)
+	}
+	if applied, err := watcher.Reload(); applied || err != nil {
+		t.Errorf("Expected unchanged broken config to be reported once, got %v, %v", applied, err)
+	}
+	if logger.GetLevel() != DebugLevel {
+		t.Error("Expected previous level to stay active")
+	}
+}
+
+func TestWatchConfigFileLogsChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pim.yaml")
+	os.WriteFile(path, []byte("level: info\nlogger:\n  service_name: billing\n"), 0644)
+
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.Level = InfoLevel
+	})
+
+	watcher, err := logger.WatchConfigFile(path, time.Hour, make(chan struct{}))
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	if buffer.GetBufferSize() != 0 {
+		t.Error("Expected no change entry for the initial load")
+	}
+	var events []ConfigChangeEvent
+	watcher.OnChange(func(event ConfigChangeEvent) { events = append(events, event) })
+
+	os.WriteFile(path, []byte("level: warning\nlogger:\n  service_name: billing\n"), 0644)
+	if applied, err := watcher.Reload(); !applied || err != nil {
+		t.Fatalf("Expected reload to apply, got %v, %v", applied, err)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("Expected one change event, got %d", len(events))
+	}
+	event := events[0]
+	if event.Path != path || len(event.Hash) != 64 || event.Hash == event.PreviousHash {
+		t.Errorf("Unexpected event source %+v", event)
+	}
+	want := []ConfigChange{
+		{Field: "level", Old: "info", New: "warning"},
+		{Field: "logger.level", Old: float64(InfoLevel), New: float64(WarningLevel)},
+	}
+	if !reflect.DeepEqual(event.Changes, want) {
+		t.Errorf("Expected changes %+v, got %+v", want, event.Changes)
+	}
+	if event.Old.Level != "info" || event.New.Level != "warning" {
+		t.Errorf("Expected old and new configs, got %q and %q", event.Old.Level, event.New.Level)
+	}
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 1 || entries[0].Message != ConfigReloadedMessage {
+		t.Fatalf("Expected a reload entry, got %+v", entries)
+	}
+	if got := entries[0].Context["config_changes"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected changes in the entry, got %v", got)
+	}
+	if entries[0].Context["config_sha256"] != event.Hash {
+		t.Error("Expected the file hash in the entry")
+	}
+}
+
+func TestDiffConfigsMasksHeaders(t *testing.T) {
+	old := &FileConfig{Writers: []WriterDefinition{{Remote: &RemoteWriterConfig{
+		Endpoint: "https://logs.example.com",
+		Headers:  map[string]string{"Authorization": "Bearer old-secret"},
+	}}}}
+	new := &FileConfig{Writers: []WriterDefinition{{Remote: &RemoteWriterConfig{
+		Endpoint: "https://logs.example.com",
+		Headers:  map[string]string{"Authorization": "Bearer new-secret", "X-Api-Key": "k-1"},
+	}}}}
+
+	changes, err := DiffConfigs(old, new)
+	if err != nil {
+		t.Fatalf("DiffConfigs failed: %v", err)
+	}
+	want := []ConfigChange{
+		{Field: "writers[0].remote.headers.Authorization", Old: "[REDACTED]", New: "[REDACTED]"},
+		{Field: "writers[0].remote.headers.X-Api-Key", New: "[REDACTED]"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Expected masked changes %+v, got %+v", want, changes)
+	}
+}
@@ -0,0 +1,337 @@
+package pim
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Encrypted log files are a sequence of frames. A header frame starts each
+// file, and each run of the writer appending to it, and whenever the active
+// key changes:
+//
+//	'H' | "PIMENC" | version (1 byte) | key ID length (1 byte) | key ID | nonce prefix (8 bytes)
+//
+// Each entry is a record frame sealed with AES-GCM under the key of the
+// preceding header. Its nonce is the header's nonce prefix followed by the
+// big-endian record counter since that header, and the header is the
+// additional data:
+//
+//	'R' | ciphertext length (4 bytes, big-endian) | ciphertext
+const (
+	encryptedFileMagic   = "PIMENC"
+	encryptedFileVersion = 1
+	noncePrefixSize      = 8
+	maxRecordsPerHeader  = 1<<32 - 1
+)
+
+// ErrFileDecryption is returned when an encrypted log file cannot be decrypted
+var ErrFileDecryption = errors.New("failed to decrypt log file")
+
+// fileCipher seals entries of an encrypting FileWriter; guarded by the
+// writer's mutex
+type fileCipher struct {
+	keyring *BatchKeyring
+	keyID   string
+	aead    cipher.AEAD
+	header  []byte // Current header frame; nil until the next write emits one
+	counter uint32
+}
+
+// NewEncryptedFileWriter creates a file writer that encrypts entries at rest
+// with the active key of keyring (AES-GCM). Changing the active key with
+// SetActiveKey takes effect at the next entry, so keys can be rotated
+// without reopening the file; keep retired keys in the ring used for
+// reading. Read the files with NewDecryptReader or DecryptLogFile.
+//
+// An existing file at filename is appended to only if it is encrypted.
+func NewEncryptedFileWriter(filename string, config LoggerConfig, rotationConfig RotationConfig, keyring *BatchKeyring) (*FileWriter, error) {
+	if keyring == nil || keyring.ActiveKey() == "" {
+		return nil, errors.New("encrypted file writer requires a keyring with an active key")
+	}
+	if err := checkEncryptedFile(filename); err != nil {
+		return nil, err
+	}
+
+	writer, err := NewFileWriter(filename, config, rotationConfig)
+	if err != nil {
+		return nil, err
+	}
+	writer.mu.Lock()
+	writer.cipher = &fileCipher{keyring: keyring}
+	writer.mu.Unlock()
+	return writer, nil
+}
+
+// checkEncryptedFile fails if path holds data that is not an encrypted log.
+// A frame left incomplete by a crash is cut off, so the frames appended
+// next are not read as its remainder.
+func checkEncryptedFile(path string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to open log file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	prefix := make([]byte, 1+len(encryptedFileMagic))
+	n, _ := io.ReadFull(file, prefix)
+	if n > 0 && !isEncryptedLog(prefix[:n]) {
+		return fmt.Errorf("log file %s exists and is not encrypted", path)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read log file %s: %w", path, err)
+	}
+
+	complete, size, err := completeFrames(bufio.NewReader(file))
+	if err != nil {
+		return fmt.Errorf("log file %s: %w", path, err)
+	}
+	if complete < size {
+		if err := os.Truncate(path, complete); err != nil {
+			return fmt.Errorf("failed to cut incomplete frame of log file %s: %w", path, err)
+		}
+		fmt.Fprintf(os.Stderr, "Cut %d bytes of an incomplete frame from log file %s\n", size-complete, path)
+	}
+	return nil
+}
+
+// completeFrames returns the length of the complete frames at the start of
+// r and the total length of r
+func completeFrames(r *bufio.Reader) (complete, size int64, err error) {
+	for {
+		tag, err := r.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return complete, size, nil
+		} else if err != nil {
+			return complete, size, err
+		}
+		size++
+
+		var frame int64
+		switch tag {
+		case 'H':
+			fixed := make([]byte, len(encryptedFileMagic)+2)
+			n, _ := io.ReadFull(r, fixed)
+			size += int64(n)
+			if n < len(fixed) {
+				return complete, size, nil
+			}
+			frame = int64(fixed[len(fixed)-1]) + noncePrefixSize
+		case 'R':
+			var length [4]byte
+			n, _ := io.ReadFull(r, length[:])
+			size += int64(n)
+			if n < len(length) {
+				return complete, size, nil
+			}
+			frame = int64(binary.BigEndian.Uint32(length[:]))
+		default:
+			return complete, size, fmt.Errorf("%w: unknown frame %q at offset %d", ErrFileDecryption, tag, size-1)
+		}
+
+		n, err := io.CopyN(io.Discard, r, frame)
+		size += n
+		if errors.Is(err, io.EOF) {
+			return complete, size, nil
+		} else if err != nil {
+			return complete, size, err
+		}
+		complete = size
+	}
+}
+
+// reset makes the next seal start with a header frame, e.g. for a new file
+func (c *fileCipher) reset() {
+	c.header = nil
+}
+
+// seal encrypts one encoded entry into a record frame, preceded by a header
+// frame when a new file was opened or the key changed
+func (c *fileCipher) seal(plaintext []byte) ([]byte, error) {
+	var out []byte
+	if c.header == nil || c.keyID != c.keyring.ActiveKey() || c.counter == maxRecordsPerHeader {
+		id, aead, err := c.keyring.activeAEAD()
+		if err != nil {
+			return nil, err
+		}
+		header := make([]byte, 0, 9+len(id)+noncePrefixSize)
+		header = append(header, 'H')
+		header = append(header, encryptedFileMagic...)
+		header = append(header, encryptedFileVersion, byte(len(id)))
+		header = append(header, id...)
+		prefix := make([]byte, noncePrefixSize)
+		if _, err := rand.Read(prefix); err != nil {
+			return nil, fmt.Errorf("failed to generate nonce prefix: %w", err)
+		}
+		header = append(header, prefix...)
+
+		c.keyID, c.aead, c.header, c.counter = id, aead, header, 0
+		out = append(out, header...)
+	}
+
+	nonce := recordNonce(c.header, c.counter)
+	c.counter++
+
+	start := len(out)
+	out = append(out, 'R', 0, 0, 0, 0)
+	out = c.aead.Seal(out, nonce, plaintext, c.header)
+	binary.BigEndian.PutUint32(out[start+1:start+5], uint32(len(out)-start-5))
+	return out, nil
+}
+
+// recordNonce returns the nonce of record counter under header
+func recordNonce(header []byte, counter uint32) []byte {
+	nonce := make([]byte, noncePrefixSize+4)
+	copy(nonce, header[len(header)-noncePrefixSize:])
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], counter)
+	return nonce
+}
+
+// activeAEAD returns the active key and its cipher
+func (k *BatchKeyring) activeAEAD() (string, cipher.AEAD, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	aead, exists := k.keys[k.active]
+	if !exists {
+		return "", nil, errors.New("keyring has no active key")
+	}
+	return k.active, aead, nil
+}
+
+// decryptReader decrypts the frames of an encrypted log file
+type decryptReader struct {
+	r       *bufio.Reader
+	keyring *BatchKeyring
+	header  []byte
+	aead    cipher.AEAD
+	counter uint32
+	pending []byte // Decrypted bytes not yet returned
+	err     error
+}
+
+// NewDecryptReader returns a reader of the plaintext of an encrypted log
+// file written by NewEncryptedFileWriter, e.g. to range over its entries
+// with ReadLogEntries or ReadCBOREntries. Every key used in the file must
+// be in keyring. A record cut short at the end, as left by a crash, is
+// reported as an error wrapping ErrFileDecryption and io.ErrUnexpectedEOF.
+func NewDecryptReader(r io.Reader, keyring *BatchKeyring) io.Reader {
+	return &decryptReader{r: bufio.NewReader(r), keyring: keyring}
+}
+
+// Read implements io.Reader
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.pending) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.pending, d.err = d.nextRecord()
+	}
+	n := copy(p, d.pending)
+	d.pending = d.pending[n:]
+	return n, nil
+}
+
+// nextRecord reads frames up to the next record and decrypts it
+func (d *decryptReader) nextRecord() ([]byte, error) {
+	for {
+		tag, err := d.r.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch tag {
+		case 'H':
+			if err := d.readHeader(); err != nil {
+				return nil, err
+			}
+		case 'R':
+			if d.aead == nil {
+				return nil, fmt.Errorf("%w: record before header", ErrFileDecryption)
+			}
+			var length [4]byte
+			if _, err := io.ReadFull(d.r, length[:]); err != nil {
+				return nil, fmt.Errorf("%w: truncated record: %w", ErrFileDecryption, io.ErrUnexpectedEOF)
+			}
+			ciphertext := make([]byte, binary.BigEndian.Uint32(length[:]))
+			if _, err := io.ReadFull(d.r, ciphertext); err != nil {
+				return nil, fmt.Errorf("%w: truncated record: %w", ErrFileDecryption, io.ErrUnexpectedEOF)
+			}
+			plaintext, err := d.aead.Open(ciphertext[:0], recordNonce(d.header, d.counter), ciphertext, d.header)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrFileDecryption, err)
+			}
+			d.counter++
+			if len(plaintext) > 0 {
+				return plaintext, nil
+			}
+		default:
+			return nil, fmt.Errorf("%w: unknown frame %q", ErrFileDecryption, tag)
+		}
+	}
+}
+
+// readHeader reads a header frame after its tag
+func (d *decryptReader) readHeader() error {
+	fixed := make([]byte, len(encryptedFileMagic)+2)
+	if _, err := io.ReadFull(d.r, fixed); err != nil {
+		return fmt.Errorf("%w: truncated header: %w", ErrFileDecryption, io.ErrUnexpectedEOF)
+	}
+	if string(fixed[:len(encryptedFileMagic)]) != encryptedFileMagic || fixed[len(encryptedFileMagic)] != encryptedFileVersion {
+		return fmt.Errorf("%w: unknown header", ErrFileDecryption)
+	}
+	rest := make([]byte, int(fixed[len(fixed)-1])+noncePrefixSize)
+	if _, err := io.ReadFull(d.r, rest); err != nil {
+		return fmt.Errorf("%w: truncated header: %w", ErrFileDecryption, io.ErrUnexpectedEOF)
+	}
+	id := string(rest[:len(rest)-noncePrefixSize])
+
+	d.keyring.mu.RLock()
+	aead, exists := d.keyring.keys[id]
+	d.keyring.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("%w: unknown key %q", ErrFileDecryption, id)
+	}
+
+	d.header = append(append([]byte{'H'}, fixed...), rest...)
+	d.aead = aead
+	d.counter = 0
+	return nil
+}
+
+// DecryptLogFile writes the plaintext of the encrypted log file at path to
+// w, decompressing it first if the name ends in .gz
+func DecryptLogFile(w io.Writer, path string, keyring *BatchKeyring) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("failed to decompress log file: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	_, err = io.Copy(w, NewDecryptReader(r, keyring))
+	return err
+}
+
+// isEncryptedLog reports whether data starts with an encrypted log header
+func isEncryptedLog(data []byte) bool {
+	return len(data) > len(encryptedFileMagic) && data[0] == 'H' && string(data[1:1+len(encryptedFileMagic)]) == encryptedFileMagic
+}
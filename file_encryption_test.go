@@ -0,0 +1,133 @@
+package pim
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func newFileKeyring(t *testing.T, ids ...string) *BatchKeyring {
+	t.Helper()
+	keyring := NewBatchKeyring()
+	for i, id := range ids {
+		if err := keyring.AddKey(id, bytes.Repeat([]byte{byte(i + 1)}, 32)); err != nil {
+			t.Fatalf("AddKey failed: %v", err)
+		}
+	}
+	return keyring
+}
+
+func writeEncrypted(t *testing.T, path string, keyring *BatchKeyring, messages ...string) {
+	t.Helper()
+	config := DefaultLoggerConfig
+	config.EnableJSON = true
+	writer, err := NewEncryptedFileWriter(path, config, RotationConfig{}, keyring)
+	if err != nil {
+		t.Fatalf("NewEncryptedFileWriter failed: %v", err)
+	}
+	defer writer.Close()
+	for _, message := range messages {
+		if message == "" {
+			keyring.SetActiveKey("k2")
+			continue
+		}
+		if err := writer.Write(CoreLogEntry{Level: InfoLevel, LevelString: "info", Message: message}); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+}
+
+func TestEncryptedFileWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	keyring := newFileKeyring(t, "k1", "k2")
+
+	writeEncrypted(t, path, keyring, "ssn 123-45-6789", "", "rotated key")
+	writeEncrypted(t, path, keyring, "after restart") // Appends a new header
+
+	data, _ := os.ReadFile(path)
+	if bytes.Contains(data, []byte("123-45-6789")) {
+		t.Fatal("Expected the file to be encrypted")
+	}
+
+	var plaintext bytes.Buffer
+	if err := DecryptLogFile(&plaintext, path, keyring); err != nil {
+		t.Fatalf("DecryptLogFile failed: %v", err)
+	}
+	var messages []string
+	for entry, err := range ReadLogEntries(&plaintext) {
+		if err != nil {
+			t.Fatalf("Unexpected parse error: %v", err)
+		}
+		messages = append(messages, entry.Message)
+	}
+	if want := []string{"ssn 123-45-6789", "rotated key", "after restart"}; !reflect.DeepEqual(messages, want) {
+		t.Errorf("Expected %q, got %q", want, messages)
+	}
+
+	// Reading requires every key used in the file
+	_, err := io.ReadAll(NewDecryptReader(bytes.NewReader(data), newFileKeyring(t, "k1")))
+	if !errors.Is(err, ErrFileDecryption) {
+		t.Errorf("Expected ErrFileDecryption for a missing key, got %v", err)
+	}
+}
+
+func TestEncryptedFileTruncatedAndTampered(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	keyring := newFileKeyring(t, "k1")
+	writeEncrypted(t, path, keyring, "one", "two")
+	data, _ := os.ReadFile(path)
+
+	_, err := io.ReadAll(NewDecryptReader(bytes.NewReader(data[:len(data)-3]), keyring))
+	if !errors.Is(err, io.ErrUnexpectedEOF) || !errors.Is(err, ErrFileDecryption) {
+		t.Errorf("Expected a truncation error, got %v", err)
+	}
+
+	tampered := bytes.Clone(data)
+	tampered[len(tampered)-1] ^= 1
+	_, err = io.ReadAll(NewDecryptReader(bytes.NewReader(tampered), keyring))
+	if !errors.Is(err, ErrFileDecryption) {
+		t.Errorf("Expected an authentication error, got %v", err)
+	}
+}
+
+func TestEncryptedFileWriterRefusesPlaintextFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	os.WriteFile(path, []byte("plain entry\n"), 0644)
+
+	_, err := NewEncryptedFileWriter(path, DefaultLoggerConfig, RotationConfig{}, newFileKeyring(t, "k1"))
+	if err == nil {
+		t.Error("Expected an error for an existing plaintext file")
+	}
+	if _, err := NewEncryptedFileWriter(path, DefaultLoggerConfig, RotationConfig{}, NewBatchKeyring()); err == nil {
+		t.Error("Expected an error for a keyring without keys")
+	}
+}
+
+func TestEncryptedFileWriterCutsIncompleteFrame(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	keyring := newFileKeyring(t, "k1")
+	writeEncrypted(t, path, keyring, "one", "two")
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, data[:len(data)-3], 0644) // Crash in the middle of "two"
+
+	writeEncrypted(t, path, keyring, "three")
+
+	var plaintext bytes.Buffer
+	if err := DecryptLogFile(&plaintext, path, keyring); err != nil {
+		t.Fatalf("DecryptLogFile failed: %v", err)
+	}
+	var messages []string
+	for entry, err := range ReadLogEntries(&plaintext) {
+		if err != nil {
+			t.Fatalf("Unexpected parse error: %v", err)
+		}
+		messages = append(messages, entry.Message)
+	}
+	if want := []string{"one", "three"}; !reflect.DeepEqual(messages, want) {
+		t.Errorf("Expected %q, got %q", want, messages)
+	}
+}
@@ -0,0 +1,55 @@
+package pim
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// fileNameTokenRegex matches the tokens of a file name template
+var fileNameTokenRegex = regexp.MustCompile(`\{(hostname|pid|service|env:[A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ExpandFileName expands the tokens of a file name template, so instances
+// sharing a volume write to their own files, e.g. "app-{hostname}-{pid}.log"
+// becomes "app-web1-4242.log". Tokens are {hostname}, {pid}, {service}
+// (config.ServiceName) and {env:NAME} (the environment variable NAME, such
+// as a pod name). Path separators in expanded values are replaced with "_",
+// and other text in braces is kept as it is. NewFileWriter expands its file
+// name with this when it is created.
+func ExpandFileName(template string, config LoggerConfig) string {
+	return fileNameTokenRegex.ReplaceAllStringFunc(template, func(token string) string {
+		var value string
+		switch name := token[1 : len(token)-1]; {
+		case name == "hostname":
+			value, _ = os.Hostname()
+		case name == "pid":
+			value = strconv.Itoa(os.Getpid())
+		case name == "service":
+			value = config.ServiceName
+		default:
+			value = os.Getenv(strings.TrimPrefix(name, "env:"))
+		}
+		return strings.NewReplacer("/", "_", `\`, "_").Replace(value)
+	})
+}
+
+// FileNameGlob returns a glob pattern matching the files of a file name
+// template for every instance, together with their rotated and compressed
+// files, e.g. "app-*-*.log*" for "app-{hostname}-{pid}.log". Use it to
+// collect the files of all instances, e.g. with filepath.Glob.
+func FileNameGlob(template string) string {
+	ext := filepath.Ext(template)
+	if fileNameTokenRegex.MatchString(ext) {
+		ext = "" // The extension is part of a token, e.g. "app.{pid}"
+	}
+	base := strings.TrimSuffix(template, ext)
+	return fileNameTokenRegex.ReplaceAllString(base, "*") + "*" + ext + "*"
+}
+
+// Path returns the path of the active log file, with the tokens of the
+// file name template expanded
+func (w *FileWriter) Path() string {
+	return w.filePath
+}
@@ -0,0 +1,85 @@
+package pim
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestExpandFileName(t *testing.T) {
+	hostname, _ := os.Hostname()
+	pid := strconv.Itoa(os.Getpid())
+	t.Setenv("PIM_TEST_POD", "pod/7")
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"app-{hostname}-{pid}.log", "app-" + hostname + "-" + pid + ".log"},
+		{"{service}/{env:PIM_TEST_POD}.log", "billing/pod_7.log"},
+		{"app-{unknown}.log", "app-{unknown}.log"},
+		{"app.log", "app.log"},
+	}
+	for _, tt := range tests {
+		if got := ExpandFileName(tt.template, LoggerConfig{ServiceName: "billing"}); got != tt.want {
+			t.Errorf("ExpandFileName(%q) = %q, expected %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestFileNameGlob(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"/var/log/app-{hostname}-{pid}.log", "/var/log/app-*-**.log*"},
+		{"app.{pid}", "app.***"},
+		{"app.log", "app*.log*"},
+	}
+	for _, tt := range tests {
+		if got := FileNameGlob(tt.template); got != tt.want {
+			t.Errorf("FileNameGlob(%q) = %q, expected %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestFileWriterFileNameTokens(t *testing.T) {
+	dir := t.TempDir()
+	template := filepath.Join(dir, "app-{service}-{pid}.log")
+	config := LoggerConfig{ServiceName: "billing", TimestampFormat: "15:04:05"}
+
+	writer, err := NewFileWriter(template, config, RotationConfig{})
+	if err != nil {
+		t.Fatalf("NewFileWriter failed: %v", err)
+	}
+	want := filepath.Join(dir, "app-billing-"+strconv.Itoa(os.Getpid())+".log")
+	if writer.Path() != want {
+		t.Errorf("Expected path %s, got %s", want, writer.Path())
+	}
+	writer.Write(CoreLogEntry{Message: "one"})
+	if err := writer.rotateFile(); err != nil {
+		t.Fatalf("rotateFile failed: %v", err)
+	}
+	writer.Write(CoreLogEntry{Message: "two"})
+	writer.Close()
+
+	// Another instance's file and an unrelated file
+	os.WriteFile(filepath.Join(dir, "app-billing-1.log"), nil, 0644)
+	os.WriteFile(filepath.Join(dir, "other.log"), nil, 0644)
+
+	matches, err := filepath.Glob(FileNameGlob(template))
+	if err != nil {
+		t.Fatalf("Glob failed: %v", err)
+	}
+	sort.Strings(matches)
+	if len(matches) != 3 {
+		t.Errorf("Expected the active, rotated and other instance files, got %v", matches)
+	}
+	for _, match := range matches {
+		if filepath.Base(match) == "other.log" {
+			t.Errorf("Expected unrelated files not to match, got %v", matches)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+package pim
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Signed log files interleave the log lines with signature lines:
+//
+//	#pim-hmac <algorithm> <sequence> <hex MAC>[ end]
+//
+// Each MAC is an HMAC over the previous MAC, the sequence number, an end
+// flag and the bytes written since the previous signature line, so the
+// signatures form a chain: changing, inserting, reordering or removing
+// lines breaks it. The "end" signature seals a file on rotation and Close;
+// a file cut short loses it. A file appended to by several runs has one
+// seal per run, chained like the blocks, so a file cut back to the seal of
+// an earlier run is only detected against the last seal recorded
+// elsewhere, see FileWriter.LastSeal and VerifyLogFileSeal.
+const signatureLinePrefix = "#pim-hmac "
+
+var (
+	// ErrLogModified is returned by VerifyLogFile when a signature does not match
+	ErrLogModified = errors.New("log file was modified")
+
+	// ErrLogTruncated is returned by VerifyLogFile when a file does not end
+	// with its seal
+	ErrLogTruncated = errors.New("log file is truncated or was not closed")
+)
+
+// SigningConfig configures the HMAC signatures of a signed FileWriter
+type SigningConfig struct {
+	Key           []byte        `json:"-"`
+	BlockLines    int           `json:"block_lines"`    // Lines per signature, 1 signs every line (default: 100)
+	HashAlgorithm HashAlgorithm `json:"hash_algorithm"` // Default: the LoggerConfig HashAlgorithm
+}
+
+// LogSeal identifies a seal of a signed log file
+type LogSeal struct {
+	Seq uint64 `json:"seq"` // Sequence number of the seal signature
+	MAC string `json:"mac"` // Hex MAC of the seal signature
+}
+
+// LogVerification describes a verified log file
+type LogVerification struct {
+	Lines      int     `json:"lines"`      // Log lines covered by signatures
+	Signatures int     `json:"signatures"` // Signature lines, including seals
+	Unsigned   int     `json:"unsigned"`   // Lines after the last signature
+	Sealed     bool    `json:"sealed"`     // The file ends with a seal
+	LastSeal   LogSeal `json:"last_seal"`  // The last seal in the file
+}
+
+// fileSigner appends chained signatures to a FileWriter; guarded by the
+// writer's mutex
+type fileSigner struct {
+	algorithm  HashAlgorithm
+	key        []byte
+	blockLines int
+	mac        hash.Hash
+	prev       []byte // Previous MAC, nil at the start of a chain
+	seq        uint64
+	lines      int     // Lines in the current block
+	lastSeal   LogSeal // Last seal written or resumed from, kept on reset
+}
+
+// NewSignedFileWriter creates a file writer that appends a chained HMAC
+// signature line after every BlockLines lines and when flushed, and seals
+// each file on rotation and Close, so VerifyLogFile can detect modified or
+// truncated files. ReadLogEntries and Follow skip the signature lines.
+// Appending to an existing file continues its chain. Signing is not
+// available with EnableCBOR.
+func NewSignedFileWriter(filename string, config LoggerConfig, rotationConfig RotationConfig, signing SigningConfig) (*FileWriter, error) {
+	if len(signing.Key) == 0 {
+		return nil, errors.New("signed file writer requires a key")
+	}
+	if config.EnableCBOR {
+		return nil, errors.New("signed file writer does not support CBOR output")
+	}
+	if signing.HashAlgorithm == "" {
+		signing.HashAlgorithm = config.HashAlgorithm
+	}
+	if err := signing.HashAlgorithm.validate(config.fipsMode()); err != nil {
+		return nil, err
+	}
+	if signing.BlockLines <= 0 {
+		signing.BlockLines = 100
+	}
+
+	signer := &fileSigner{
+		algorithm:  signing.HashAlgorithm.orDefault(),
+		key:        signing.Key,
+		blockLines: signing.BlockLines,
+	}
+	signer.reset()
+	if err := signer.resume(filename); err != nil {
+		return nil, err
+	}
+
+	writer, err := NewFileWriter(filename, config, rotationConfig)
+	if err != nil {
+		return nil, err
+	}
+	writer.mu.Lock()
+	writer.signer = signer
+	writer.mu.Unlock()
+	return writer, nil
+}
+
+// LastSeal returns the last seal the writer wrote or found when appending
+// to an existing file; ok is false for writers that are not signed or have
+// not sealed a file yet. Record it after Close, outside the log directory,
+// to detect files cut back to an earlier seal with VerifyLogFileSeal. After
+// a rotation it is the seal of the rotated file until the next seal.
+func (w *FileWriter) LastSeal() (seal LogSeal, ok bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.signer == nil || w.signer.lastSeal.MAC == "" {
+		return LogSeal{}, false
+	}
+	return w.signer.lastSeal, true
+}
+
+// newHMAC returns an HMAC of the algorithm, which must be valid
+func newHMAC(algorithm HashAlgorithm, key []byte) hash.Hash {
+	return hmac.New(func() hash.Hash {
+		h, _ := algorithm.New()
+		return h
+	}, key)
+}
+
+// reset starts a new chain, e.g. for a new file
+func (s *fileSigner) reset() {
+	s.mac = newHMAC(s.algorithm, s.key)
+	s.prev, s.seq, s.lines = nil, 0, 0
+	s.begin()
+}
+
+// begin starts the MAC of the next block
+func (s *fileSigner) begin() {
+	s.mac.Reset()
+	s.mac.Write(s.prev)
+	var seq [8]byte
+	binary.BigEndian.PutUint64(seq[:], s.seq)
+	s.mac.Write(seq[:])
+}
+
+// add covers data with the current block and returns a signature line when
+// the block is full
+func (s *fileSigner) add(data []byte) []byte {
+	s.mac.Write(data)
+	s.lines += bytes.Count(data, []byte{'\n'})
+	if s.lines < s.blockLines {
+		return nil
+	}
+	return s.sign(false)
+}
+
+// sign closes the current block and returns its signature line. An end
+// signature seals the file.
+func (s *fileSigner) sign(end bool) []byte {
+	flag := byte(0)
+	if end {
+		flag = 1
+	}
+	s.mac.Write([]byte{flag})
+	sum := s.mac.Sum(nil)
+
+	line := fmt.Sprintf("%s%s %d %s", signatureLinePrefix, s.algorithm, s.seq, hex.EncodeToString(sum))
+	if end {
+		line += " end"
+		s.lastSeal = LogSeal{Seq: s.seq, MAC: hex.EncodeToString(sum)}
+	}
+	s.prev, s.seq, s.lines = sum, s.seq+1, 0
+	s.begin()
+	return []byte(line + "\n")
+}
+
+// pending reports whether lines were written since the last signature
+func (s *fileSigner) pending() bool {
+	return s.lines > 0
+}
+
+// resume continues the chain of an existing file at path
+func (s *fileSigner) resume(path string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to open log file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if sig, ok := parseSignatureLine(line); ok {
+				if sig.algorithm != s.algorithm {
+					return fmt.Errorf("log file %s is signed with %s, not %s", path, sig.algorithm, s.algorithm)
+				}
+				s.prev, s.seq, s.lines = sig.mac, sig.seq+1, 0
+				if sig.end {
+					s.lastSeal = LogSeal{Seq: sig.seq, MAC: hex.EncodeToString(sig.mac)}
+				}
+				s.begin()
+			} else {
+				s.mac.Write(line)
+				s.lines += bytes.Count(line, []byte{'\n'})
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read log file %s: %w", path, err)
+		}
+	}
+}
+
+// signature is a parsed signature line
+type signature struct {
+	algorithm HashAlgorithm
+	seq       uint64
+	mac       []byte
+	end       bool
+}
+
+// parseSignatureLine parses a signature line; ok is false for other lines
+func parseSignatureLine(line []byte) (signature, bool) {
+	text := strings.TrimRight(string(line), "\r\n")
+	if !strings.HasPrefix(text, signatureLinePrefix) {
+		return signature{}, false
+	}
+	fields := strings.Fields(text[len(signatureLinePrefix):])
+	if len(fields) != 3 && (len(fields) != 4 || fields[3] != "end") {
+		return signature{}, false
+	}
+	seq, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return signature{}, false
+	}
+	mac, err := hex.DecodeString(fields[2])
+	if err != nil {
+		return signature{}, false
+	}
+	return signature{algorithm: HashAlgorithm(fields[0]), seq: seq, mac: mac, end: len(fields) == 4}, true
+}
+
+// yieldLogLine parses and yields a log line, skipping signature lines
+func yieldLogLine(yield func(CoreLogEntry, error) bool, line string) bool {
+	if strings.HasPrefix(line, signatureLinePrefix) {
+		return true
+	}
+	return yield(ParseLogLine(line))
+}
+
+// VerifyLogFile checks the signature chain of a log file written by
+// NewSignedFileWriter; files ending in .gz are decompressed. It returns
+// an error wrapping ErrLogModified if a line was changed, inserted,
+// reordered or removed, and ErrLogTruncated if the file does not end with
+// its seal, as for a cut-short file or the file still being written.
+func VerifyLogFile(path string, key []byte) (LogVerification, error) {
+	return verifyLogFile(path, key, nil)
+}
+
+// VerifyLogFileSeal is VerifyLogFile for a file whose last seal was
+// recorded elsewhere, e.g. from FileWriter.LastSeal or an earlier
+// verification. It also returns an error wrapping ErrLogTruncated if the
+// file ends before that seal, as for a file cut back to the seal of an
+// earlier run, and ErrLogModified if the file has another seal in its place.
+func VerifyLogFileSeal(path string, key []byte, seal LogSeal) (LogVerification, error) {
+	return verifyLogFile(path, key, &seal)
+}
+
+// verifyLogFile checks the signature chain of a log file, and that it
+// reaches seal if not nil
+func verifyLogFile(path string, key []byte, seal *LogSeal) (LogVerification, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return LogVerification{}, fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return LogVerification{}, fmt.Errorf("failed to decompress log file: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return verifyLog(r, key, seal)
+}
+
+// verifyLog checks the signature chain of a log read from r, and that it
+// reaches seal if not nil
+func verifyLog(r io.Reader, key []byte, seal *LogSeal) (LogVerification, error) {
+	var result LogVerification
+	var signer *fileSigner
+	var block [][]byte // Lines since the last signature, until the algorithm is known
+	lineNumber := 0
+
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			lineNumber++
+			sig, ok := parseSignatureLine(line)
+			switch {
+			case !ok:
+				result.Unsigned++
+				if signer != nil {
+					signer.mac.Write(line)
+				} else {
+					block = append(block, line)
+				}
+			default:
+				if signer == nil {
+					if err := sig.algorithm.Validate(); err != nil {
+						return result, fmt.Errorf("line %d: %w", lineNumber, err)
+					}
+					signer = &fileSigner{algorithm: sig.algorithm, key: key}
+					signer.reset()
+					for _, l := range block {
+						signer.mac.Write(l)
+					}
+				}
+				if sig.algorithm != signer.algorithm || sig.seq != signer.seq {
+					return result, fmt.Errorf("%w: unexpected signature at line %d", ErrLogModified, lineNumber)
+				}
+				expected := signer.sign(sig.end)
+				if want, _ := parseSignatureLine(expected); !hmac.Equal(want.mac, sig.mac) {
+					return result, fmt.Errorf("%w: signature mismatch at line %d", ErrLogModified, lineNumber)
+				}
+				result.Lines += result.Unsigned
+				result.Unsigned = 0
+				result.Signatures++
+				result.Sealed = sig.end
+				if sig.end {
+					result.LastSeal = signer.lastSeal
+				}
+				if seal != nil && sig.seq == seal.Seq && (!sig.end || result.LastSeal.MAC != seal.MAC) {
+					return result, fmt.Errorf("%w: line %d does not hold seal %d", ErrLogModified, lineNumber, seal.Seq)
+				}
+			}
+			if !ok {
+				result.Sealed = false
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return result, fmt.Errorf("failed to read log file: %w", err)
+		}
+	}
+
+	if !result.Sealed {
+		return result, fmt.Errorf("%w: %d unsigned lines after the last seal", ErrLogTruncated, result.Unsigned)
+	}
+	if seal != nil && result.LastSeal.Seq < seal.Seq {
+		return result, fmt.Errorf("%w: file ends before seal %d", ErrLogTruncated, seal.Seq)
+	}
+	return result, nil
+}
@@ -0,0 +1,156 @@
+package pim
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testSigningKey = []byte("0123456789abcdef0123456789abcdef")
+
+func writeSigned(t *testing.T, path string, blockLines int, messages ...string) {
+	t.Helper()
+	config := DefaultLoggerConfig
+	config.EnableJSON = true
+	writer, err := NewSignedFileWriter(path, config, RotationConfig{}, SigningConfig{Key: testSigningKey, BlockLines: blockLines})
+	if err != nil {
+		t.Fatalf("NewSignedFileWriter failed: %v", err)
+	}
+	for _, message := range messages {
+		if err := writer.Write(CoreLogEntry{Level: InfoLevel, LevelString: "info", Message: message}); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+}
+
+func TestSignedFileWriterVerifies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	writeSigned(t, path, 2, "one", "two", "three")
+	writeSigned(t, path, 2, "four") // Continues the chain
+
+	result, err := VerifyLogFile(path, testSigningKey)
+	if err != nil {
+		t.Fatalf("VerifyLogFile failed: %v", err)
+	}
+	// Blocks: [one two], seal [three], seal [four]
+	if result.Lines != 4 || result.Signatures != 3 || !result.Sealed {
+		t.Errorf("Unexpected verification: %+v", result)
+	}
+
+	var messages []string
+	for entry, err := range ReadLogFile(path) {
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		messages = append(messages, entry.Message)
+	}
+	if len(messages) != 4 {
+		t.Errorf("Expected signature lines to be skipped, got %q", messages)
+	}
+
+	if _, err := VerifyLogFile(path, []byte("wrong key")); !errors.Is(err, ErrLogModified) {
+		t.Errorf("Expected ErrLogModified with the wrong key, got %v", err)
+	}
+}
+
+func TestVerifyLogFileDetectsTampering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	writeSigned(t, path, 1, "alpha", "beta", "gamma")
+	data, _ := os.ReadFile(path)
+	lines := strings.SplitAfter(string(data), "\n")
+
+	tests := map[string]struct {
+		data string
+		want error
+	}{
+		"modified":  {strings.Replace(string(data), "beta", "BETA", 1), ErrLogModified},
+		"removed":   {strings.Join(append(append([]string{}, lines[:2]...), lines[4:]...), ""), ErrLogModified},
+		"truncated": {strings.Join(lines[:4], ""), ErrLogTruncated},
+		"appended":  {string(data) + "{\"message\":\"forged\"}\n", ErrLogTruncated},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			tampered := filepath.Join(t.TempDir(), "audit.log")
+			os.WriteFile(tampered, []byte(tt.data), 0644)
+			if _, err := VerifyLogFile(tampered, testSigningKey); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestSignedFileWriterFlushAndRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+	config := DefaultLoggerConfig
+	config.EnableJSON = true
+	writer, err := NewSignedFileWriter(path, config, RotationConfig{MaxSize: 1}, SigningConfig{Key: testSigningKey})
+	if err != nil {
+		t.Fatalf("NewSignedFileWriter failed: %v", err)
+	}
+	writer.Write(CoreLogEntry{Message: "first"})
+	writer.Flush()
+	data, _ := os.ReadFile(path)
+	if !bytes.Contains(data, []byte(signatureLinePrefix)) {
+		t.Error("Expected Flush to sign the partial block")
+	}
+
+	writer.Write(CoreLogEntry{Message: "second"}) // Rotates first
+	writer.Close()
+
+	matches, _ := filepath.Glob(filepath.Join(dir, "audit.*.log"))
+	if len(matches) != 1 {
+		t.Fatalf("Expected one rotated file, got %v", matches)
+	}
+	for _, file := range append(matches, path) {
+		if _, err := VerifyLogFile(file, testSigningKey); err != nil {
+			t.Errorf("VerifyLogFile(%s) failed: %v", filepath.Base(file), err)
+		}
+	}
+}
+
+func TestVerifyLogFileSealDetectsEarlierRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	writeSigned(t, path, 10, "one")
+	first, _ := os.ReadFile(path)
+
+	config := DefaultLoggerConfig
+	config.EnableJSON = true
+	writer, err := NewSignedFileWriter(path, config, RotationConfig{}, SigningConfig{Key: testSigningKey, BlockLines: 10})
+	if err != nil {
+		t.Fatalf("NewSignedFileWriter failed: %v", err)
+	}
+	if seal, ok := writer.LastSeal(); !ok || seal.Seq != 0 {
+		t.Errorf("Expected the seal of the first run, got %+v", seal)
+	}
+	writer.Write(CoreLogEntry{Message: "two"})
+	writer.Close()
+	seal, _ := writer.LastSeal()
+
+	result, err := VerifyLogFileSeal(path, testSigningKey, seal)
+	if err != nil || result.LastSeal != seal {
+		t.Fatalf("VerifyLogFileSeal failed: %+v, %v", result, err)
+	}
+
+	// Cut back to the seal of the first run, which still verifies on its own
+	os.WriteFile(path, first, 0644)
+	if _, err := VerifyLogFile(path, testSigningKey); err != nil {
+		t.Fatalf("Expected the first run to verify, got %v", err)
+	}
+	if _, err := VerifyLogFileSeal(path, testSigningKey, seal); !errors.Is(err, ErrLogTruncated) {
+		t.Errorf("Expected ErrLogTruncated against the recorded seal, got %v", err)
+	}
+
+	forged := seal
+	forged.MAC = strings.Repeat("0", len(seal.MAC))
+	writeSigned(t, path, 10, "two")
+	if _, err := VerifyLogFileSeal(path, testSigningKey, forged); !errors.Is(err, ErrLogModified) {
+		t.Errorf("Expected ErrLogModified for another seal, got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package pim
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// StartupFingerprintMessage is the message of the startup fingerprint entry
+const StartupFingerprintMessage = "logger started"
+
+// cgroupRoot is where cgroup limits are read from (overridden in tests)
+var cgroupRoot = "/sys/fs/cgroup"
+
+// EnvironmentFingerprint summarizes the runtime environment and logger
+// setup. Container limits are only reported when cgroups are available.
+func (l *LoggerCore) EnvironmentFingerprint() map[string]interface{} {
+	fingerprint := map[string]interface{}{
+		"go_version":  runtime.Version(),
+		"goos":        runtime.GOOS,
+		"goarch":      runtime.GOARCH,
+		"gomaxprocs":  runtime.GOMAXPROCS(0),
+		"num_cpu":     runtime.NumCPU(),
+		"config_hash": configHash(l.config),
+		"writers":     l.writerNames(),
+		"hooks":       l.hookNames(),
+	}
+
+	if cpus, ok := cgroupCPULimit(); ok {
+		fingerprint["cgroup_cpu_limit"] = cpus
+	}
+	if bytes, ok := cgroupMemoryLimit(); ok {
+		fingerprint["cgroup_memory_limit"] = bytes
+	}
+
+	return fingerprint
+}
+
+// LogStartupFingerprint writes the environment fingerprint entry now,
+// regardless of the logger level. With LoggerConfig.LogStartupFingerprint
+// it is written automatically before the first entry, so writers and hooks
+// registered right after construction are included.
+func (l *LoggerCore) LogStartupFingerprint() {
+	l.writeEntry(l.startupFingerprintEntry())
+}
+
+// startupFingerprintEntry builds the startup fingerprint entry
+func (l *LoggerCore) startupFingerprintEntry() CoreLogEntry {
+	entry := l.createLogEntry(2, InfoLevel, InfoPrefix, StartupFingerprintMessage)
+	if entry.Context == nil {
+		entry.Context = make(map[string]interface{})
+	}
+	for key, value := range l.EnvironmentFingerprint() {
+		entry.Context[key] = value
+	}
+	return entry
+}
+
+// writerNames returns the types of the logger's writers
+func (l *LoggerCore) writerNames() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	names := make([]string, len(l.writers))
+	for i, writer := range l.writers {
+		names[i] = writerTypeName(writer)
+	}
+	return names
+}
+
+// writerTypeName returns the type name of a writer, e.g. "pim.FileWriter"
+func writerTypeName(writer LogWriter) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", writer), "*")
+}
+
+// hookNames returns the names of the logger's enabled enhanced hooks
+func (l *LoggerCore) hookNames() []string {
+	names := make([]string, 0)
+	if l.hookManager == nil {
+		return names
+	}
+
+	l.hookManager.mu.RLock()
+	defer l.hookManager.mu.RUnlock()
+	for _, hook := range l.hookManager.hooks {
+		if hook.IsEnabled() {
+			names = append(names, hook.GetConfig().Name)
+		}
+	}
+	return names
+}
+
+// configHash returns a short stable hash of the logger configuration
+func configHash(config LoggerConfig) string {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:6])
+}
+
+// cgroupCPULimit returns the CPU quota in cores (cgroup v2, then v1)
+func cgroupCPULimit() (float64, bool) {
+	if data, err := os.ReadFile(cgroupRoot + "/cpu.max"); err == nil {
+		fields := strings.Fields(string(data))
+		if len(fields) == 2 && fields[0] != "max" {
+			quota, err1 := strconv.ParseFloat(fields[0], 64)
+			period, err2 := strconv.ParseFloat(fields[1], 64)
+			if err1 == nil && err2 == nil && period > 0 {
+				return quota / period, true
+			}
+		}
+		return 0, false
+	}
+
+	quota, err1 := readCgroupInt(cgroupRoot + "/cpu/cpu.cfs_quota_us")
+	period, err2 := readCgroupInt(cgroupRoot + "/cpu/cpu.cfs_period_us")
+	if err1 != nil || err2 != nil || quota <= 0 || period <= 0 {
+		return 0, false
+	}
+	return float64(quota) / float64(period), true
+}
+
+// cgroupMemoryLimit returns the memory limit in bytes (cgroup v2, then v1)
+func cgroupMemoryLimit() (int64, bool) {
+	if data, err := os.ReadFile(cgroupRoot + "/memory.max"); err == nil {
+		limit, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+		return limit, err == nil
+	}
+
+	limit, err := readCgroupInt(cgroupRoot + "/memory/memory.limit_in_bytes")
+	// cgroup v1 reports "no limit" as a huge page-aligned number
+	if err != nil || limit <= 0 || limit >= 1<<62 {
+		return 0, false
+	}
+	return limit, true
+}
+
+// readCgroupInt reads a single integer from a cgroup file
+func readCgroupInt(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
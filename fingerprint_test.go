@@ -0,0 +1,77 @@
+package pim
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestStartupFingerprintWrittenOnce(t *testing.T) {
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.Level = InfoLevel
+		config.LogStartupFingerprint = true
+	})
+	logger.AddSensitiveDataRedactHook()
+
+	logger.Info("first")
+	logger.WithField("k", "v").Info("second")
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 3 {
+		t.Fatalf("Expected fingerprint plus 2 entries, got %d", len(entries))
+	}
+
+	fingerprint := entries[0]
+	if fingerprint.Message != StartupFingerprintMessage {
+		t.Fatalf("Expected fingerprint first, got %q", fingerprint.Message)
+	}
+	if fingerprint.Context["go_version"] != runtime.Version() {
+		t.Errorf("Expected go version, got: %v", fingerprint.Context["go_version"])
+	}
+	if writers := fingerprint.Context["writers"].([]string); len(writers) != 1 || writers[0] != "pim.BufferWriter" {
+		t.Errorf("Expected writer list, got: %v", writers)
+	}
+	if hooks := fingerprint.Context["hooks"].([]string); len(hooks) != 1 {
+		t.Errorf("Expected hook list, got: %v", hooks)
+	}
+	if hash, _ := fingerprint.Context["config_hash"].(string); len(hash) != 12 {
+		t.Errorf("Expected short config hash, got: %q", hash)
+	}
+}
+
+func TestStartupFingerprintDisabledByDefault(t *testing.T) {
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.Level = InfoLevel
+	})
+
+	logger.Info("only entry")
+	if buffer.GetBufferSize() != 1 {
+		t.Errorf("Expected no fingerprint entry, got %d entries", buffer.GetBufferSize())
+	}
+}
+
+func TestCgroupLimits(t *testing.T) {
+	original := cgroupRoot
+	defer func() { cgroupRoot = original }()
+
+	cgroupRoot = t.TempDir()
+	os.WriteFile(filepath.Join(cgroupRoot, "cpu.max"), []byte("150000 100000\n"), 0644)
+	os.WriteFile(filepath.Join(cgroupRoot, "memory.max"), []byte("536870912\n"), 0644)
+
+	if cpus, ok := cgroupCPULimit(); !ok || cpus != 1.5 {
+		t.Errorf("Expected 1.5 CPUs, got %v (%v)", cpus, ok)
+	}
+	if mem, ok := cgroupMemoryLimit(); !ok || mem != 512<<20 {
+		t.Errorf("Expected 512MiB, got %v (%v)", mem, ok)
+	}
+
+	os.WriteFile(filepath.Join(cgroupRoot, "cpu.max"), []byte("max 100000\n"), 0644)
+	os.WriteFile(filepath.Join(cgroupRoot, "memory.max"), []byte("max\n"), 0644)
+	if _, ok := cgroupCPULimit(); ok {
+		t.Error("Expected unlimited CPU to be omitted")
+	}
+	if _, ok := cgroupMemoryLimit(); ok {
+		t.Error("Expected unlimited memory to be omitted")
+	}
+}
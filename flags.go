@@ -0,0 +1,188 @@
+package pim
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/refactorroom/pim/core"
+)
+
+// Environment variables read by RegisterFlags. Child processes started with
+// LogFlags.Environ inherit the parent's logging setup through them.
+const (
+	EnvLogLevel  = "PIM_LOG_LEVEL"
+	EnvLogFormat = "PIM_LOG_FORMAT"
+	EnvLogFile   = "PIM_LOG_FILE"
+)
+
+// ParseLevel parses a level name such as "info" or "warn" (case-insensitive)
+func ParseLevel(name string) (LogLevel, error) {
+	level, ok := core.ParseLevel(strings.ToLower(strings.TrimSpace(name)))
+	if !ok {
+		return InfoLevel, fmt.Errorf("unknown log level %q", name)
+	}
+	return level, nil
+}
+
+// LevelValue is a flag value holding a log level. It implements flag.Value
+// and, through Type, pflag.Value, so it can be passed to either FlagSet.Var.
+type LevelValue struct {
+	level *LogLevel
+}
+
+// NewLevelValue returns a flag value that stores into p
+func NewLevelValue(p *LogLevel) *LevelValue {
+	return &LevelValue{level: p}
+}
+
+// String implements flag.Value
+func (v *LevelValue) String() string {
+	if v == nil || v.level == nil {
+		return ""
+	}
+	return v.level.Name()
+}
+
+// Set implements flag.Value
+func (v *LevelValue) Set(s string) error {
+	level, err := ParseLevel(s)
+	if err != nil {
+		return err
+	}
+	*v.level = level
+	return nil
+}
+
+// Type implements pflag.Value
+func (v *LevelValue) Type() string {
+	return "level"
+}
+
+// LevelFlag defines a log level flag on the command-line flag set
+func LevelFlag(name string, value LogLevel, usage string) *LogLevel {
+	p := new(LogLevel)
+	*p = value
+	flag.CommandLine.Var(NewLevelValue(p), name, usage)
+	return p
+}
+
+// formatFlagValue maps -log-format onto a LoggerConfig: "json", "cbor" and
+// "text" select the encoding, any other value selects a named format
+type formatFlagValue struct {
+	config *LoggerConfig
+}
+
+// String implements flag.Value
+func (v *formatFlagValue) String() string {
+	if v == nil || v.config == nil {
+		return ""
+	}
+	if v.config.EnableCBOR {
+		return "cbor"
+	}
+	if v.config.EnableJSON {
+		return "json"
+	}
+	if v.config.FormatName != "" {
+		return v.config.FormatName
+	}
+	return "text"
+}
+
+// Set implements flag.Value
+func (v *formatFlagValue) Set(s string) error {
+	switch s = strings.ToLower(strings.TrimSpace(s)); s {
+	case "json":
+		v.config.EnableJSON, v.config.EnableCBOR = true, false
+	case "cbor":
+		v.config.EnableJSON, v.config.EnableCBOR = false, true
+	case "text":
+		v.config.EnableJSON, v.config.EnableCBOR = false, false
+	case "":
+		return fmt.Errorf("empty log format")
+	default:
+		v.config.EnableJSON, v.config.EnableCBOR = false, false
+		v.config.FormatName = s
+	}
+	return nil
+}
+
+// Type implements pflag.Value
+func (v *formatFlagValue) Type() string {
+	return "format"
+}
+
+// LogFlags holds the configuration bound by RegisterFlags
+type LogFlags struct {
+	Config *LoggerConfig
+	File   string
+}
+
+// RegisterFlags binds -log-level, -log-format and -log-file to config on fs.
+// PIM_LOG_LEVEL, PIM_LOG_FORMAT and PIM_LOG_FILE provide the defaults, so
+// flags override the environment which overrides config. Invalid environment
+// values are reported on stderr. For pflag, register on a flag.FlagSet and
+// add it with pflag's FlagSet.AddGoFlagSet.
+func RegisterFlags(fs *flag.FlagSet, config *LoggerConfig) *LogFlags {
+	flags := &LogFlags{Config: config}
+	if err := flags.applyEnv(); err != nil {
+		fmt.Fprintf(os.Stderr, "pim: %v\n", err)
+	}
+
+	fs.Var(NewLevelValue(&config.Level), "log-level", "log level (panic, error, warning, info, debug, trace)")
+	fs.Var(&formatFlagValue{config: config}, "log-format", "log format (text, json or a named format such as compact)")
+	fs.StringVar(&flags.File, "log-file", flags.File, "also write logs to this file")
+	return flags
+}
+
+// applyEnv applies PIM_* environment defaults. Invalid values are skipped
+// and returned as one error.
+func (f *LogFlags) applyEnv() error {
+	var errs []error
+	if value := os.Getenv(EnvLogLevel); value != "" {
+		if err := NewLevelValue(&f.Config.Level).Set(value); err != nil {
+			errs = append(errs, fmt.Errorf("ignoring %s: %w", EnvLogLevel, err))
+		}
+	}
+	if value := os.Getenv(EnvLogFormat); value != "" {
+		if err := (&formatFlagValue{config: f.Config}).Set(value); err != nil {
+			errs = append(errs, fmt.Errorf("ignoring %s: %w", EnvLogFormat, err))
+		}
+	}
+	if value := os.Getenv(EnvLogFile); value != "" {
+		f.File = value
+	}
+	return errors.Join(errs...)
+}
+
+// Environ returns PIM_* variables describing the parsed configuration, for
+// passing to child processes (e.g. append to exec.Cmd.Env)
+func (f *LogFlags) Environ() []string {
+	env := []string{
+		EnvLogLevel + "=" + f.Config.Level.Name(),
+		EnvLogFormat + "=" + (&formatFlagValue{config: f.Config}).String(),
+	}
+	if f.File != "" {
+		env = append(env, EnvLogFile+"="+f.File)
+	}
+	return env
+}
+
+// NewLogger creates a logger from the parsed flags, adding a file writer if -log-file was set
+func (f *LogFlags) NewLogger() (*LoggerCore, error) {
+	logger, err := NewCheckedLoggerCore(*f.Config)
+	if err != nil {
+		return nil, err
+	}
+	if f.File != "" {
+		writer, err := NewFileWriter(f.File, *f.Config, RotationConfig{})
+		if err != nil {
+			return nil, err
+		}
+		logger.AddWriter(writer)
+	}
+	return logger, nil
+}
@@ -0,0 +1,85 @@
+package pim
+
+import (
+	"flag"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRegisterFlags(t *testing.T) {
+	config := DefaultLoggerConfig
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	flags := RegisterFlags(fs, &config)
+
+	logFile := filepath.Join(t.TempDir(), "app.log")
+	if err := fs.Parse([]string{"-log-level", "DEBUG", "-log-format", "json", "-log-file", logFile}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	if config.Level != DebugLevel || !config.EnableJSON || flags.File != logFile {
+		t.Errorf("Unexpected config after parsing: level=%v json=%v file=%q", config.Level, config.EnableJSON, flags.File)
+	}
+	if err := fs.Parse([]string{"-log-level", "loud"}); err == nil {
+		t.Error("Expected invalid level to be rejected")
+	}
+
+	logger, err := flags.NewLogger()
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	defer logger.Close()
+	if logger.GetLevel() != DebugLevel {
+		t.Errorf("Expected logger level debug, got %v", logger.GetLevel())
+	}
+}
+
+func TestRegisterFlagsEnvDefaults(t *testing.T) {
+	t.Setenv(EnvLogLevel, "warn")
+	t.Setenv(EnvLogFormat, "compact")
+
+	config := LoggerConfig{Level: InfoLevel}
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	flags := RegisterFlags(fs, &config)
+
+	if err := fs.Parse(nil); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	if config.Level != WarningLevel || config.FormatName != "compact" {
+		t.Errorf("Expected environment defaults, got level=%v format=%q", config.Level, config.FormatName)
+	}
+
+	// Flags win over the environment
+	fs.Parse([]string{"-log-level", "trace"})
+	if config.Level != TraceLevel {
+		t.Errorf("Expected flag to override environment, got %v", config.Level)
+	}
+
+	env := flags.Environ()
+	if len(env) != 2 || env[0] != "PIM_LOG_LEVEL=trace" || env[1] != "PIM_LOG_FORMAT=compact" {
+		t.Errorf("Unexpected child environment: %v", env)
+	}
+}
+
+func TestApplyEnvReportsInvalidValues(t *testing.T) {
+	t.Setenv(EnvLogLevel, "loud")
+	t.Setenv(EnvLogFormat, " ")
+
+	config := LoggerConfig{Level: InfoLevel}
+	err := (&LogFlags{Config: &config}).applyEnv()
+	if err == nil || !strings.Contains(err.Error(), EnvLogLevel) || !strings.Contains(err.Error(), EnvLogFormat) {
+		t.Fatalf("Expected both variables to be reported, got %v", err)
+	}
+	if config.Level != InfoLevel {
+		t.Errorf("Expected the invalid level to be ignored, got %v", config.Level)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	if level, err := ParseLevel(" Warning "); err != nil || level != WarningLevel {
+		t.Errorf("Expected warning level, got %v (%v)", level, err)
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("Expected unknown level to fail")
+	}
+}
@@ -0,0 +1,236 @@
+package pim
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"strings"
+	"time"
+)
+
+// followPollInterval is how often Follow checks for new data and rotations
+var followPollInterval = 250 * time.Millisecond
+
+// Follow tails the log file at path like tail -F, yielding each entry as it
+// is written. When FileWriter rotates the file, the rest of the old file is
+// read before switching to the new one, so no entries are lost even if the
+// rotated file is compressed and removed meanwhile. If fromEnd is true, only
+// entries written after Follow starts are yielded.
+//
+// Iteration ends when ctx is done or the loop breaks. Lines that cannot be
+// parsed are yielded with an error alongside a best-effort entry.
+func Follow(ctx context.Context, path string, fromEnd bool) iter.Seq2[CoreLogEntry, error] {
+	return func(yield func(CoreLogEntry, error) bool) {
+		f := &follower{path: path}
+		defer f.close()
+
+		if err := f.open(ctx, fromEnd); err != nil {
+			if ctx.Err() == nil {
+				yield(CoreLogEntry{}, err)
+			}
+			return
+		}
+
+		for {
+			line, err := f.readLine()
+			if err == nil {
+				if !yieldLogLine(yield, line) {
+					return
+				}
+				continue
+			}
+			if !errors.Is(err, io.EOF) {
+				yield(CoreLogEntry{}, fmt.Errorf("failed to read log file: %w", err))
+				return
+			}
+
+			rotated, err := f.rotated()
+			if err != nil {
+				yield(CoreLogEntry{}, err)
+				return
+			}
+			if rotated {
+				// Drain what was written before the rename, then switch files
+				for {
+					line, err := f.readLine()
+					if err != nil {
+						break
+					}
+					if !yieldLogLine(yield, line) {
+						return
+					}
+				}
+				if f.partial != "" {
+					line := f.partial
+					f.partial = ""
+					if !yieldLogLine(yield, line) {
+						return
+					}
+				}
+				f.close()
+				if err := f.open(ctx, false); err != nil {
+					if ctx.Err() == nil {
+						yield(CoreLogEntry{}, err)
+					}
+					return
+				}
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(followPollInterval):
+			}
+		}
+	}
+}
+
+// follower holds the state of a Follow iteration
+type follower struct {
+	path    string
+	file    *os.File
+	info    os.FileInfo
+	reader  *bufio.Reader
+	offset  int64
+	partial string
+}
+
+// open opens path, waiting for it to be created if necessary
+func (f *follower) open(ctx context.Context, fromEnd bool) error {
+	for {
+		file, err := os.Open(f.path)
+		if err == nil {
+			info, err := file.Stat()
+			if err != nil {
+				file.Close()
+				return fmt.Errorf("failed to stat log file: %w", err)
+			}
+
+			f.file, f.info, f.offset = file, info, 0
+			if fromEnd {
+				if f.offset, err = file.Seek(0, io.SeekEnd); err != nil {
+					file.Close()
+					return fmt.Errorf("failed to seek log file: %w", err)
+				}
+			}
+			f.reader = bufio.NewReader(file)
+			return nil
+		}
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(followPollInterval):
+		}
+	}
+}
+
+// readLine returns the next complete line. Partial lines are kept until the
+// writer finishes them.
+func (f *follower) readLine() (string, error) {
+	for {
+		chunk, err := f.reader.ReadString('\n')
+		f.offset += int64(len(chunk))
+		if err != nil {
+			f.partial += chunk
+			return "", err
+		}
+
+		line := strings.TrimRight(f.partial+chunk, "\r\n")
+		f.partial = ""
+		if line == "" {
+			continue
+		}
+		return line, nil
+	}
+}
+
+// rotated reports whether path now refers to a different file than the one
+// being read, or the file was truncated in place
+func (f *follower) rotated() (bool, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Between rename and reopen; keep reading the old file
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	if !os.SameFile(f.info, info) {
+		return true, nil
+	}
+	if info.Size() < f.offset {
+		// Truncated in place: start over from the beginning
+		if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+			return false, fmt.Errorf("failed to seek log file: %w", err)
+		}
+		f.reader.Reset(f.file)
+		f.offset = 0
+		f.partial = ""
+	}
+	return false, nil
+}
+
+// close closes the current file
+func (f *follower) close() {
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+}
+
+// ParseLogLine parses a line written by FileWriter. JSON lines decode into
+// the full entry; text lines yield the timestamp, level and message when
+// they use the default layout, or the raw line as the message otherwise.
+func ParseLogLine(line string) (CoreLogEntry, error) {
+	if strings.HasPrefix(line, "{") {
+		var entry CoreLogEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return CoreLogEntry{Message: line}, fmt.Errorf("failed to parse log line: %w", err)
+		}
+		return entry, nil
+	}
+
+	entry := CoreLogEntry{Message: line, Level: InfoLevel}
+
+	timestamp, rest, ok := cutBracket(line)
+	if !ok {
+		return entry, nil
+	}
+	if t, err := time.Parse(DefaultLoggerConfig.TimestampFormat, timestamp); err == nil {
+		entry.Timestamp = t
+	}
+
+	levelName, rest, ok := cutBracket(rest)
+	if !ok {
+		return entry, nil
+	}
+	if level, err := ParseLevel(levelName); err == nil {
+		entry.Level = level
+		entry.LevelString = strings.ToLower(levelName)
+		entry.Message = rest
+	}
+	return entry, nil
+}
+
+// cutBracket splits "[value] rest" into value and rest
+func cutBracket(s string) (string, string, bool) {
+	if !strings.HasPrefix(s, "[") {
+		return "", s, false
+	}
+	value, rest, found := strings.Cut(s[1:], "]")
+	if !found {
+		return "", s, false
+	}
+	return value, strings.TrimPrefix(rest, " "), true
+}
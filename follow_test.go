@@ -0,0 +1,133 @@
+package pim
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFollowAcrossRotation(t *testing.T) {
+	defer func(interval time.Duration) { followPollInterval = interval }(followPollInterval)
+	followPollInterval = 10 * time.Millisecond
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	config := DefaultLoggerConfig
+	config.EnableJSON = true
+	writer, err := NewFileWriter(path, config, RotationConfig{Compress: true})
+	if err != nil {
+		t.Fatalf("NewFileWriter failed: %v", err)
+	}
+	defer writer.Close()
+
+	write := func(msg string) {
+		if err := writer.Write(CoreLogEntry{Timestamp: time.Now(), Level: InfoLevel, LevelString: "info", Message: msg}); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	write("before-follow")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	messages := make(chan string)
+	go func() {
+		defer close(messages)
+		for entry, err := range Follow(ctx, path, true) {
+			if err != nil {
+				t.Errorf("Follow yielded error: %v", err)
+				return
+			}
+			messages <- entry.Message
+		}
+	}()
+
+	// Give the follower time to open the file and seek to the end
+	time.Sleep(50 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		write(fmt.Sprintf("old-%d", i))
+	}
+	if err := writer.rotateFile(); err != nil {
+		t.Fatalf("rotateFile failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		write(fmt.Sprintf("new-%d", i))
+	}
+
+	expected := []string{"old-0", "old-1", "old-2", "new-0", "new-1", "new-2"}
+	for _, want := range expected {
+		select {
+		case got := <-messages:
+			if got != want {
+				t.Fatalf("Expected %q, got %q", want, got)
+			}
+		case <-ctx.Done():
+			t.Fatalf("Timed out waiting for %q", want)
+		}
+	}
+
+	cancel()
+	for range messages {
+	}
+}
+
+func TestFollowWaitsForFile(t *testing.T) {
+	defer func(interval time.Duration) { followPollInterval = interval }(followPollInterval)
+	followPollInterval = 10 * time.Millisecond
+
+	path := filepath.Join(t.TempDir(), "late.log")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		config := DefaultLoggerConfig
+		config.EnableJSON = true
+		writer, err := NewFileWriter(path, config, RotationConfig{})
+		if err != nil {
+			t.Errorf("NewFileWriter failed: %v", err)
+			return
+		}
+		defer writer.Close()
+		writer.Write(CoreLogEntry{Level: WarningLevel, LevelString: "warning", Message: "hello"})
+	}()
+
+	for entry, err := range Follow(ctx, path, false) {
+		if err != nil {
+			t.Fatalf("Follow yielded error: %v", err)
+		}
+		if entry.Message != "hello" || entry.Level != WarningLevel {
+			t.Errorf("Unexpected entry: %+v", entry)
+		}
+		break
+	}
+	if ctx.Err() != nil {
+		t.Fatal("Timed out waiting for the file to appear")
+	}
+}
+
+func TestParseLogLineText(t *testing.T) {
+	entry, err := ParseLogLine("[2024-05-01 12:30:00.250 UTC] [ERROR] [svc] [main.go:main.run:L10] something broke")
+	if err != nil {
+		t.Fatalf("ParseLogLine failed: %v", err)
+	}
+	if entry.Level != ErrorLevel || entry.LevelString != "error" {
+		t.Errorf("Expected error level, got %v %q", entry.Level, entry.LevelString)
+	}
+	if entry.Timestamp.IsZero() || entry.Timestamp.Minute() != 30 {
+		t.Errorf("Expected parsed timestamp, got %v", entry.Timestamp)
+	}
+	if entry.Message != "[svc] [main.go:main.run:L10] something broke" {
+		t.Errorf("Unexpected message %q", entry.Message)
+	}
+
+	entry, err = ParseLogLine("plain text")
+	if err != nil || entry.Message != "plain text" {
+		t.Errorf("Expected raw line as message, got %q (%v)", entry.Message, err)
+	}
+
+	if _, err := ParseLogLine("{not json"); err == nil {
+		t.Error("Expected error for malformed JSON line")
+	}
+}
@@ -0,0 +1,171 @@
+package pim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Special fields recognized by the Cloud Logging agent in JSON log lines
+const (
+	gcpTraceKey          = "logging.googleapis.com/trace"
+	gcpSpanIDKey         = "logging.googleapis.com/spanId"
+	gcpSourceLocationKey = "logging.googleapis.com/sourceLocation"
+	gcpLabelsKey         = "logging.googleapis.com/labels"
+	gcpReportedErrorType = "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"
+)
+
+// GCPSeverities maps log levels to Cloud Logging severities
+var GCPSeverities = map[LogLevel]string{
+	PanicLevel:   "CRITICAL",
+	ErrorLevel:   "ERROR",
+	WarningLevel: "WARNING",
+	InfoLevel:    "INFO",
+	DebugLevel:   "DEBUG",
+	TraceLevel:   "DEBUG",
+}
+
+// GCPConfig configures the Google Cloud Logging encoder
+type GCPConfig struct {
+	// ProjectID qualifies trace IDs as projects/<id>/traces/<trace>, which
+	// Cloud Logging needs to link entries to Cloud Trace
+	ProjectID string `json:"project_id"`
+
+	Labels         map[string]string `json:"labels,omitempty"`          // Static labels added to every entry
+	ServiceVersion string            `json:"service_version,omitempty"` // Version reported to Error Reporting
+
+	// ErrorReporting marks Error and Panic entries as ReportedErrorEvents
+	// with a Go-style stack trace so Error Reporting groups them
+	ErrorReporting bool `json:"error_reporting"`
+}
+
+// GCPEncoder encodes entries as JSON lines in the structured logging format
+// of Google Cloud Logging, as parsed from stdout by GKE, Cloud Run and the
+// Ops Agent: severity, message and time are mapped to their Cloud Logging
+// fields, trace and span IDs to logging.googleapis.com/trace and spanId,
+// the caller to sourceLocation, and service, logger and host names to
+// labels. Context fields become top-level fields of jsonPayload.
+//
+// Use it on a console writer so pods log in a format Cloud Logging parses:
+//
+//	config.Encoder = pim.NewGCPEncoder(pim.GCPConfig{ProjectID: "my-project"})
+type GCPEncoder struct {
+	config GCPConfig
+}
+
+// NewGCPEncoder creates a Google Cloud Logging encoder
+func NewGCPEncoder(config GCPConfig) *GCPEncoder {
+	return &GCPEncoder{config: config}
+}
+
+// EncodeEntry implements Encoder interface
+func (e *GCPEncoder) EncodeEntry(entry CoreLogEntry) ([]byte, error) {
+	entry = withErrorChains(entry)
+	record := make(map[string]interface{}, len(entry.Context)+8)
+	for k, v := range entry.Context {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		record[k] = v
+	}
+
+	// Special fields take precedence over context fields of the same name
+	record["severity"] = gcpSeverity(entry.Level)
+	record["message"] = entry.Message
+	record["time"] = entry.Timestamp.Format(time.RFC3339Nano)
+
+	if entry.TraceID != "" {
+		if e.config.ProjectID != "" {
+			record[gcpTraceKey] = fmt.Sprintf("projects/%s/traces/%s", e.config.ProjectID, entry.TraceID)
+		} else {
+			record[gcpTraceKey] = entry.TraceID
+		}
+	}
+	if entry.SpanID != "" {
+		record[gcpSpanIDKey] = entry.SpanID
+	}
+
+	if entry.File != "" {
+		location := map[string]string{
+			"file": entry.File,
+			"line": strconv.Itoa(entry.Line),
+		}
+		if entry.Function != "" {
+			location["function"] = qualifiedFunction(entry.Package, entry.Function)
+		}
+		record[gcpSourceLocationKey] = location
+	}
+
+	if labels := e.labels(entry); len(labels) > 0 {
+		record[gcpLabelsKey] = labels
+	}
+
+	if e.config.ErrorReporting && entry.Level <= ErrorLevel {
+		record["@type"] = gcpReportedErrorType
+		record["serviceContext"] = map[string]string{
+			"service": entry.ServiceName,
+			"version": e.config.ServiceVersion,
+		}
+		if stack := gcpStackTrace(entry.StackTrace); stack != "" {
+			record["stack_trace"] = entry.Message + "\n\n" + stack
+		}
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal log entry: %w", err)
+	}
+	return data, nil
+}
+
+// labels returns the static labels merged with the entry's names
+func (e *GCPEncoder) labels(entry CoreLogEntry) map[string]string {
+	labels := make(map[string]string, len(e.config.Labels)+4)
+	for k, v := range e.config.Labels {
+		labels[k] = v
+	}
+	optional := []struct{ key, value string }{
+		{"service", entry.ServiceName},
+		{"logger", entry.LoggerName},
+		{"hostname", entry.Hostname},
+		{"request_id", entry.RequestID},
+	}
+	for _, label := range optional {
+		if label.value != "" {
+			labels[label.key] = label.value
+		}
+	}
+	return labels
+}
+
+// gcpSeverity returns the Cloud Logging severity of level
+func gcpSeverity(level LogLevel) string {
+	if severity, ok := GCPSeverities[level]; ok {
+		return severity
+	}
+	return "DEFAULT"
+}
+
+// qualifiedFunction joins a package and function name
+func qualifiedFunction(pkg, function string) string {
+	if pkg == "" {
+		return function
+	}
+	return pkg + "." + function
+}
+
+// gcpStackTrace formats frames like a Go panic trace, which Error Reporting
+// recognizes
+func gcpStackTrace(frames []StackFrame) string {
+	if len(frames) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("goroutine 1 [running]:")
+	for _, frame := range frames {
+		fmt.Fprintf(&b, "\n%s()\n\t%s:%d", qualifiedFunction(frame.Package, frame.Function), frame.File, frame.Line)
+	}
+	return b.String()
+}
@@ -0,0 +1,89 @@
+package pim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestGCPEncoderFields(t *testing.T) {
+	entry := encoderTestEntry()
+	entry.TraceID = "abc123"
+	entry.SpanID = "span-1"
+	entry.Context["message"] = "shadowed"
+
+	data, err := NewGCPEncoder(GCPConfig{ProjectID: "shop", Labels: map[string]string{"env": "prod"}}).EncodeEntry(entry)
+	if err != nil {
+		t.Fatalf("EncodeEntry failed: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Invalid JSON %s: %v", data, err)
+	}
+
+	expected := map[string]interface{}{
+		"severity":                      "INFO",
+		"message":                       "charged card",
+		"time":                          "2024-01-02T03:04:05Z",
+		"logging.googleapis.com/trace":  "projects/shop/traces/abc123",
+		"logging.googleapis.com/spanId": "span-1",
+		"amount":                        float64(10),
+	}
+	for key, want := range expected {
+		if decoded[key] != want {
+			t.Errorf("%s: expected %v, got %v", key, want, decoded[key])
+		}
+	}
+
+	location, _ := decoded["logging.googleapis.com/sourceLocation"].(map[string]interface{})
+	if location["file"] != "main.go" || location["line"] != "42" || location["function"] != "main.run" {
+		t.Errorf("Unexpected sourceLocation %v", location)
+	}
+	labels, _ := decoded["logging.googleapis.com/labels"].(map[string]interface{})
+	if labels["env"] != "prod" || labels["service"] != "billing" {
+		t.Errorf("Unexpected labels %v", labels)
+	}
+	if _, ok := decoded["@type"]; ok {
+		t.Error("Expected no error report for info entries")
+	}
+}
+
+func TestGCPEncoderErrorReporting(t *testing.T) {
+	entry := encoderTestEntry()
+	entry.Level = ErrorLevel
+	entry.Context = map[string]interface{}{"error": fmt.Errorf("charge: %w", errors.New("card declined"))}
+	entry.StackTrace = []StackFrame{{File: "main.go", Line: 42, Function: "run", Package: "main"}}
+
+	data, err := NewGCPEncoder(GCPConfig{ErrorReporting: true, ServiceVersion: "1.2.0"}).EncodeEntry(entry)
+	if err != nil {
+		t.Fatalf("EncodeEntry failed: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Invalid JSON %s: %v", data, err)
+	}
+
+	if decoded["severity"] != "ERROR" || decoded["@type"] != gcpReportedErrorType {
+		t.Errorf("Expected an error report, got %s", data)
+	}
+	service, _ := decoded["serviceContext"].(map[string]interface{})
+	if service["service"] != "billing" || service["version"] != "1.2.0" {
+		t.Errorf("Unexpected serviceContext %v", service)
+	}
+	stack, _ := decoded["stack_trace"].(string)
+	if !strings.Contains(stack, "goroutine 1 [running]:\nmain.run()\n\tmain.go:42") {
+		t.Errorf("Expected a Go-style stack trace, got %q", stack)
+	}
+	chain, _ := decoded["error"].(map[string]interface{})
+	if chain["error"] != "charge: card declined" {
+		t.Errorf("Expected the error chain, got %v", decoded["error"])
+	}
+}
+
+func TestGCPSeverityMapping(t *testing.T) {
+	if gcpSeverity(PanicLevel) != "CRITICAL" || gcpSeverity(TraceLevel) != "DEBUG" || gcpSeverity(LogLevel(42)) != "DEFAULT" {
+		t.Error("Unexpected severity mapping")
+	}
+}
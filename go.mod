@@ -2,10 +2,15 @@ module github.com/refactorroom/pim
 
 go 1.23.5
 
-require github.com/fatih/color v1.18.0
+require (
+	github.com/fatih/color v1.18.0
+	github.com/fxamacker/cbor/v2 v2.9.0
+	github.com/mattn/go-isatty v0.0.20
+	golang.org/x/sys v0.34.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/mattn/go-colorable v0.1.13 // indirect
-	github.com/mattn/go-isatty v0.0.20 // indirect
-	golang.org/x/sys v0.34.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
 )
@@ -0,0 +1,114 @@
+package pim
+
+import (
+	"sort"
+	"strings"
+)
+
+// ungroupedFields are correlation fields that keep their names in a group,
+// so entries stay linked to their traces, requests and tenants
+var ungroupedFields = map[string]bool{
+	"trace_id":          true,
+	"span_id":           true,
+	"request_id":        true,
+	"session_id":        true,
+	"correlation_id":    true,
+	"session_replay_id": true,
+	"client_event_id":   true,
+	"event_id":          true,
+	"parent_event_id":   true,
+	TenantIDKey:         true,
+}
+
+// WithGroup returns a child logger that qualifies the keys of fields added
+// afterwards with name, like slog groups: logger.WithGroup("db").
+// WithField("query", q) logs the field db.query. Fields added through the
+// child with WithField and its siblings and the per-call fields of its
+// entries are qualified, while fields added before stay as they are. Groups
+// nest, e.g. "db.pool.size", and correlation fields such as trace_id are
+// never qualified. Set NestGroups to write qualified keys as nested JSON
+// objects.
+func (l *LoggerCore) WithGroup(name string) *LoggerCore {
+	if name == "" {
+		return l
+	}
+	child := l.child()
+	if l.group != "" {
+		name = l.group + "." + name
+	}
+	child.group = name
+	return child
+}
+
+// groupKey returns key qualified with the logger's group
+func (l *LoggerCore) groupKey(key string) string {
+	if l.group == "" || ungroupedFields[key] {
+		return key
+	}
+	return l.group + "." + key
+}
+
+// grouped returns fields with their keys qualified with the logger's group
+func (l *LoggerCore) grouped(fields map[string]interface{}) map[string]interface{} {
+	if l.group == "" || len(fields) == 0 {
+		return fields
+	}
+	qualified := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		qualified[l.groupKey(k)] = v
+	}
+	return qualified
+}
+
+// nestGroups returns context with dotted keys expanded into nested maps,
+// e.g. {"db.query": q} becomes {"db": {"query": q}}. A key that conflicts
+// with a value at one of its path prefixes is kept as it is.
+func nestGroups(context map[string]interface{}) map[string]interface{} {
+	var dotted []string
+	for k := range context {
+		if strings.Contains(k, ".") {
+			dotted = append(dotted, k)
+		}
+	}
+	if len(dotted) == 0 {
+		return context
+	}
+	sort.Strings(dotted)
+
+	nested := make(map[string]interface{}, len(context))
+	for k, v := range context {
+		if !strings.Contains(k, ".") {
+			nested[k] = v
+		}
+	}
+	for _, key := range dotted {
+		path := strings.Split(key, ".")
+		parent := nested
+		for _, name := range path[:len(path)-1] {
+			child, exists := parent[name]
+			if !exists {
+				group := make(nestedGroup)
+				parent[name] = group
+				parent = group
+				continue
+			}
+			group, ok := child.(nestedGroup)
+			if !ok {
+				parent = nil
+				break
+			}
+			parent = group
+		}
+		last := path[len(path)-1]
+		if _, exists := parent[last]; parent == nil || exists {
+			nested[key] = context[key]
+			continue
+		}
+		parent[last] = context[key]
+	}
+	return nested
+}
+
+// nestedGroup is a group created by nestGroups; other maps in the context
+// are values and never merged into
+type nestedGroup map[string]interface{}
@@ -0,0 +1,23 @@
+package pim
+
+import "testing"
+
+// newTestLogger creates a logger that writes to a buffer instead of the
+// console. configure, if not nil, changes the config first. The logger is
+// closed and the level registry reset when the test ends.
+func newTestLogger(t *testing.T, configure func(config *LoggerConfig)) (*LoggerCore, *BufferWriter) {
+	t.Helper()
+	config := DefaultLoggerConfig
+	config.EnableConsole = false
+	if configure != nil {
+		configure(&config)
+	}
+	logger := NewLoggerCore(config)
+	buffer := NewBufferWriter(config, 100)
+	logger.AddWriter(buffer)
+	t.Cleanup(func() {
+		ResetLevels()
+		logger.Close()
+	})
+	return logger, buffer
+}
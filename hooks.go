@@ -28,6 +28,7 @@ const (
 	HookTypeTransform
 	HookTypeMetrics
 	HookTypeCustom
+	HookTypeValidate
 )
 
 // HookConfig holds configuration for a hook
@@ -59,6 +60,24 @@ type RedactConfig struct {
 	Patterns    map[string]string               `json:"patterns,omitempty"` // Regex patterns to redact
 	Replacement string                          `json:"replacement"`        // Replacement string
 	CustomFunc  func(CoreLogEntry) CoreLogEntry `json:"-"`                  // Custom redaction function
+
+	// Detectors find personal data in the message and in string values
+	// anywhere in the context, whatever their key
+	Detectors []PIIDetector `json:"detectors,omitempty"`
+
+	// Strategy replaces the values of Fields and Detectors matches
+	// (default: RedactReplace); Patterns always use Replacement.
+	// RedactHash hashes HashSalt and the value with HashAlgorithm (default:
+	// sha256, non-approved algorithms are rejected in FIPS mode); the salt
+	// is required, so hashes of guessable values cannot be looked up.
+	// HashSaltEnv names an environment variable holding the salt, for
+	// configuration files. RedactTokenize uses TokenVault, or an in-memory
+	// vault if nil.
+	Strategy      RedactStrategy `json:"strategy,omitempty"`
+	HashAlgorithm HashAlgorithm  `json:"hash_algorithm,omitempty"`
+	HashSalt      string         `json:"-"`
+	HashSaltEnv   string         `json:"hash_salt_env,omitempty"`
+	TokenVault    TokenVault     `json:"-"`
 }
 
 // EnrichConfig holds configuration for enrichment hooks
@@ -201,7 +220,7 @@ func (h *FilterHook) shouldFilter(entry CoreLogEntry) bool {
 	// Check context conditions
 	if len(h.config.Conditions) > 0 && entry.Context != nil {
 		for key, expectedValue := range h.config.Conditions {
-			if actualValue, exists := entry.Context[key]; !exists || actualValue != expectedValue {
+			if actualValue, exists := entry.Resolve(key); !exists || actualValue != expectedValue {
 				return true
 			}
 		}
@@ -242,12 +261,22 @@ func (h *FilterHook) SetPriority(priority int) {
 
 // RedactHook implements redaction functionality
 type RedactHook struct {
-	config RedactConfig
+	config   RedactConfig
+	rules    redactRules
+	redactor *redactor
 }
 
-// NewRedactHook creates a new redaction hook
+// NewRedactHook creates a new redaction hook.
+// Fields match keys at any depth of the context; dotted fields such as
+// "request.headers.authorization" match a nested key path. Configurations
+// failing Validate fall back to the static Replacement, and unknown
+// detectors are skipped; use AddRedactHook to get the error instead.
 func NewRedactHook(config RedactConfig) *RedactHook {
-	return &RedactHook{config: config}
+	return &RedactHook{
+		config:   config,
+		rules:    compileRedactRules(config.Fields),
+		redactor: newRedactor(config),
+	}
 }
 
 // Process implements LogHook interface
@@ -261,22 +290,25 @@ func (h *RedactHook) Process(entry CoreLogEntry) (CoreLogEntry, error) {
 		return h.config.CustomFunc(entry), nil
 	}
 
-	// Redact fields in context
+	// Redact fields in context, including nested maps, slices and structs
 	if entry.Context != nil {
-		for _, field := range h.config.Fields {
-			if _, exists := entry.Context[field]; exists {
-				entry.Context[field] = h.config.Replacement
-			}
+		if redacted, changed := h.redactMap(nil, entry.Context); changed {
+			entry.Context = redacted.(map[string]interface{})
 		}
 	}
 
-	// Redact patterns in message
+	// Redact the message and the arguments it was localized from
+	entry.Message = h.redactText(entry.Message)
+	entry.Localized = entry.Localized.redactArgs(h.redactText)
+
+	// Redact patterns in context fields
 	if len(h.config.Patterns) > 0 {
 		for field, pattern := range h.config.Patterns {
+			if field == "message" {
+				continue
+			}
 			if regex, err := regexp.Compile(pattern); err == nil {
-				if field == "message" {
-					entry.Message = regex.ReplaceAllString(entry.Message, h.config.Replacement)
-				} else if entry.Context != nil {
+				if entry.Context != nil {
 					if value, exists := entry.Context[field]; exists {
 						if str, ok := value.(string); ok {
 							entry.Context[field] = regex.ReplaceAllString(str, h.config.Replacement)
@@ -290,6 +322,25 @@ func (h *RedactHook) Process(entry CoreLogEntry) (CoreLogEntry, error) {
 	return entry, nil
 }
 
+// redactText redacts detected values and the "message" pattern in s
+func (h *RedactHook) redactText(s string) string {
+	if len(h.redactor.detectors) > 0 {
+		s, _ = h.redactor.scan(s)
+	}
+	if pattern, ok := h.config.Patterns["message"]; ok {
+		if regex, err := regexp.Compile(pattern); err == nil {
+			s = regex.ReplaceAllString(s, h.config.Replacement)
+		}
+	}
+	return s
+}
+
+// TokenVault returns the vault of a RedactTokenize hook, e.g. the in-memory
+// vault created for it, or nil for other strategies
+func (h *RedactHook) TokenVault() TokenVault {
+	return h.redactor.vault
+}
+
 // GetConfig implements EnhancedLogHook interface
 func (h *RedactHook) GetConfig() HookConfig {
 	return h.config.HookConfig
@@ -564,9 +615,10 @@ func (m *MetricsHook) SetPriority(priority int) {
 
 // HookManager manages all hooks with priority ordering
 type HookManager struct {
-	hooks   []EnhancedLogHook
-	mu      sync.RWMutex
-	enabled bool
+	hooks    []EnhancedLogHook
+	mu       sync.RWMutex
+	enabled  bool
+	observer PipelineObserver // Receives hook latencies, if set
 }
 
 // NewHookManager creates a new hook manager
@@ -599,6 +651,27 @@ func (hm *HookManager) RemoveHook(name string) {
 	}
 }
 
+// ReplaceHooks atomically removes the hooks with the given names and adds
+// hooks, so entries never see a partially updated hook set
+func (hm *HookManager) ReplaceHooks(remove []string, hooks []EnhancedLogHook) {
+	hm.mu.Lock()
+	defer hm.mu.Unlock()
+
+	removed := make(map[string]bool, len(remove))
+	for _, name := range remove {
+		removed[name] = true
+	}
+
+	kept := make([]EnhancedLogHook, 0, len(hm.hooks)+len(hooks))
+	for _, hook := range hm.hooks {
+		if !removed[hook.GetConfig().Name] {
+			kept = append(kept, hook)
+		}
+	}
+	hm.hooks = append(kept, hooks...)
+	hm.sortHooks()
+}
+
 // GetHook returns a hook by name
 func (hm *HookManager) GetHook(name string) EnhancedLogHook {
 	hm.mu.RLock()
@@ -635,11 +708,31 @@ func (hm *HookManager) ProcessHooks(entry CoreLogEntry) (CoreLogEntry, error) {
 	hm.mu.RLock()
 	hooks := make([]EnhancedLogHook, len(hm.hooks))
 	copy(hooks, hm.hooks)
+	observer := hm.observer
 	hm.mu.RUnlock()
 
+	resolved := false
 	for _, hook := range hooks {
 		if hook.IsEnabled() {
-			if modifiedEntry, err := hook.Process(entry); err != nil {
+			// Filter hooks run before lazy fields are computed, see Lazy
+			if !resolved && hook.GetType() != HookTypeFilter {
+				entry.resolveLazy()
+				resolved = true
+			}
+			var start time.Time
+			if observer != nil {
+				start = time.Now()
+			}
+			var before CoreLogEntry
+			if entry.provenance != nil {
+				before = copyEntry(entry)
+			}
+			modifiedEntry, err := hook.Process(entry)
+			if observer != nil {
+				observer.HookProcessed(hook.GetConfig().Name, time.Since(start))
+			}
+			traceHook(entry.provenance, hook.GetConfig().Name, before, modifiedEntry, err)
+			if err != nil {
 				// If error is a filter error, return empty entry
 				if strings.Contains(err.Error(), "filtered by hook") {
 					return CoreLogEntry{}, err
@@ -717,6 +810,12 @@ func NewSensitiveDataRedactHook() *RedactHook {
 
 // NewRequestIDEnrichHook creates a hook to add request IDs
 func NewRequestIDEnrichHook() *EnrichHook {
+	return NewRequestIDEnrichHookFor(LoggerConfig{})
+}
+
+// NewRequestIDEnrichHookFor creates a hook to add request IDs generated
+// with the IDGenerator and Clock of config
+func NewRequestIDEnrichHookFor(config LoggerConfig) *EnrichHook {
 	return NewEnrichHook(EnrichConfig{
 		HookConfig: HookConfig{
 			Type:        HookTypeEnrich,
@@ -729,7 +828,7 @@ func NewRequestIDEnrichHook() *EnrichHook {
 			// Generate a simple request ID if not present
 			if entry.RequestID == "" {
 				return map[string]interface{}{
-					"request_id": fmt.Sprintf("req_%d", time.Now().UnixNano()),
+					"request_id": config.newRequestID(),
 				}
 			}
 			return nil
@@ -0,0 +1,146 @@
+package pim
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// FieldChange describes how a hook changed one field of an entry. Entry
+// fields use their JSON names ("message", "level"); context fields are
+// prefixed with "context.".
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+	Kind   string      `json:"kind"` // "added", "removed" or "modified"
+}
+
+// HookDecision records what a single hook did to an entry during a dry run
+type HookDecision struct {
+	Hook     string        `json:"hook"`
+	Filtered bool          `json:"filtered"`
+	Error    string        `json:"error,omitempty"`
+	Changes  []FieldChange `json:"changes,omitempty"`
+}
+
+// DryRunResult is the outcome of running the hook pipeline on one entry
+type DryRunResult struct {
+	Input      CoreLogEntry   `json:"input"`
+	Output     CoreLogEntry   `json:"output"`
+	Filtered   bool           `json:"filtered"`
+	FilteredBy string         `json:"filtered_by,omitempty"`
+	Decisions  []HookDecision `json:"decisions"`
+}
+
+// DryRun runs the enabled hooks on each entry and reports per-hook decisions
+// without writing anything. Entries are copied so the inputs are left
+// intact, but hooks with side effects of their own (such as metrics) still
+// observe the entries.
+func (hm *HookManager) DryRun(entries []CoreLogEntry) []DryRunResult {
+	hm.mu.RLock()
+	hooks := make([]EnhancedLogHook, len(hm.hooks))
+	copy(hooks, hm.hooks)
+	enabled := hm.enabled
+	hm.mu.RUnlock()
+
+	results := make([]DryRunResult, 0, len(entries))
+	for _, input := range entries {
+		result := DryRunResult{Input: copyEntry(input)}
+		entry := copyEntry(input)
+
+		if enabled {
+			for _, hook := range hooks {
+				if !hook.IsEnabled() {
+					continue
+				}
+
+				decision := HookDecision{Hook: hook.GetConfig().Name}
+				modified, err := hook.Process(copyEntry(entry))
+				if err != nil {
+					decision.Error = err.Error()
+					if strings.Contains(err.Error(), "filtered by hook") {
+						decision.Filtered = true
+						result.Filtered = true
+						result.FilteredBy = decision.Hook
+						result.Decisions = append(result.Decisions, decision)
+						break
+					}
+				} else {
+					decision.Changes = diffEntries(entry, modified)
+					entry = modified
+				}
+				result.Decisions = append(result.Decisions, decision)
+			}
+		}
+
+		if !result.Filtered {
+			result.Output = entry
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// copyEntry copies an entry so a hook mutating its context in place does
+// not affect the caller's copy
+func copyEntry(entry CoreLogEntry) CoreLogEntry {
+	if entry.Context != nil {
+		context := make(map[string]interface{}, len(entry.Context))
+		for key, value := range entry.Context {
+			context[key] = value
+		}
+		entry.Context = context
+	}
+	return entry
+}
+
+// diffEntries lists the fields that differ between two entries
+func diffEntries(before, after CoreLogEntry) []FieldChange {
+	var changes []FieldChange
+
+	bv, av := reflect.ValueOf(before), reflect.ValueOf(after)
+	entryType := bv.Type()
+	for i := 0; i < entryType.NumField(); i++ {
+		field := entryType.Field(i)
+		if field.Name == "Context" || !field.IsExported() {
+			continue
+		}
+		b, a := bv.Field(i).Interface(), av.Field(i).Interface()
+		if !reflect.DeepEqual(b, a) {
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "" {
+				name = field.Name
+			}
+			changes = append(changes, FieldChange{Field: name, Before: b, After: a, Kind: "modified"})
+		}
+	}
+
+	keys := make(map[string]bool)
+	for key := range before.Context {
+		keys[key] = true
+	}
+	for key := range after.Context {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	for _, key := range sorted {
+		b, hadBefore := before.Context[key]
+		a, hasAfter := after.Context[key]
+		switch {
+		case !hadBefore:
+			changes = append(changes, FieldChange{Field: "context." + key, After: a, Kind: "added"})
+		case !hasAfter:
+			changes = append(changes, FieldChange{Field: "context." + key, Before: b, Kind: "removed"})
+		case !reflect.DeepEqual(b, a):
+			changes = append(changes, FieldChange{Field: "context." + key, Before: b, After: a, Kind: "modified"})
+		}
+	}
+
+	return changes
+}
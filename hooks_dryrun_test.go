@@ -0,0 +1,41 @@
+package pim
+
+import "testing"
+
+func TestHookManagerDryRun(t *testing.T) {
+	manager := NewHookManager()
+	manager.AddHook(NewEnrichHook(EnrichConfig{
+		HookConfig: HookConfig{Name: "env", Enabled: true, Priority: 1},
+		Fields:     map[string]interface{}{"env": "prod"},
+	}))
+	manager.AddHook(NewFilterHook(FilterConfig{
+		HookConfig: HookConfig{Name: "drop_health", Enabled: true, Priority: 2},
+		CustomFunc: func(entry CoreLogEntry) bool { return entry.Message == "health" },
+	}))
+
+	inputs := []CoreLogEntry{
+		{Message: "order placed", Context: map[string]interface{}{"id": 1}},
+		{Message: "health"},
+	}
+	results := manager.DryRun(inputs)
+
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	kept := results[0]
+	if kept.Filtered || kept.Output.Context["env"] != "prod" {
+		t.Errorf("Expected first entry to be enriched and kept, got: %+v", kept)
+	}
+	changes := kept.Decisions[0].Changes
+	if len(changes) != 1 || changes[0].Field != "context.env" || changes[0].Kind != "added" {
+		t.Errorf("Expected env to be reported as added, got: %+v", changes)
+	}
+	if _, mutated := inputs[0].Context["env"]; mutated {
+		t.Error("Expected dry run not to mutate input entries")
+	}
+
+	dropped := results[1]
+	if !dropped.Filtered || dropped.FilteredBy != "drop_health" || len(dropped.Decisions) != 2 {
+		t.Errorf("Expected second entry to be filtered by drop_health, got: %+v", dropped)
+	}
+}
@@ -0,0 +1,188 @@
+package pim
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HTTPClientConfig configures the logging RoundTripper
+type HTTPClientConfig struct {
+	Level      LogLevel            `json:"level"`       // Level for successful requests
+	HostLevels map[string]LogLevel `json:"host_levels"` // Per-host overrides of Level, keyed by host name
+
+	// RedactQueryParams lists query parameters whose values are redacted
+	// from logged URLs (case-insensitive). Defaults to DefaultRedactedQueryParams.
+	RedactQueryParams []string `json:"redact_query_params"`
+
+	Retries      int           `json:"retries"`       // Retries after network errors and 5xx responses
+	RetryBackoff time.Duration `json:"retry_backoff"` // Delay before the first retry, doubled per attempt (default: 100ms)
+}
+
+// DefaultRedactedQueryParams are query parameters redacted from logged URLs by default
+var DefaultRedactedQueryParams = []string{
+	"token", "access_token", "refresh_token", "api_key", "apikey", "key",
+	"password", "secret", "signature", "sig",
+}
+
+// DefaultHTTPClientConfig provides sensible defaults
+var DefaultHTTPClientConfig = HTTPClientConfig{
+	Level:             DebugLevel,
+	RedactQueryParams: DefaultRedactedQueryParams,
+}
+
+// LoggingTransport is an http.RoundTripper that logs outgoing requests.
+// Requests carrying a request-scoped logger from HTTPMiddleware are logged
+// through it so client calls share the server request's correlation fields.
+type LoggingTransport struct {
+	base   http.RoundTripper
+	logger *LoggerCore
+	config HTTPClientConfig
+	redact map[string]bool
+}
+
+// NewLoggingTransport wraps base (http.DefaultTransport if nil) with request logging
+func NewLoggingTransport(base http.RoundTripper, logger *LoggerCore, config HTTPClientConfig) *LoggingTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if config.RedactQueryParams == nil {
+		config.RedactQueryParams = DefaultRedactedQueryParams
+	}
+	if config.RetryBackoff == 0 {
+		config.RetryBackoff = 100 * time.Millisecond
+	}
+
+	redact := make(map[string]bool, len(config.RedactQueryParams))
+	for _, param := range config.RedactQueryParams {
+		redact[strings.ToLower(param)] = true
+	}
+
+	return &LoggingTransport{
+		base:   base,
+		logger: logger,
+		config: config,
+		redact: redact,
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *LoggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+
+	var resp *http.Response
+	var err error
+	retries := 0
+	attemptReq := req
+	for attempt := 0; ; attempt++ {
+		resp, err = t.base.RoundTrip(attemptReq)
+		if attempt >= t.config.Retries || !shouldRetry(resp, err) {
+			break
+		}
+
+		// Keep the last response if the request cannot be retried
+		next, rewindErr := rewindRequest(req)
+		if rewindErr != nil || !t.wait(req, attempt) {
+			break
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		attemptReq = next
+		retries++
+	}
+
+	t.log(req, resp, err, retries, time.Since(start))
+	return resp, err
+}
+
+// rewindRequest returns a copy of req with a fresh body for a retry
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, errors.New("request body cannot be replayed")
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		clone.Body = body
+	}
+	return clone, nil
+}
+
+// shouldRetry reports whether a response or error is worth retrying
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= 500
+}
+
+// wait sleeps before the next attempt, returning false if the request was cancelled
+func (t *LoggingTransport) wait(req *http.Request, attempt int) bool {
+	timer := time.NewTimer(t.config.RetryBackoff << attempt)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-req.Context().Done():
+		return false
+	}
+}
+
+// log writes one entry for a completed request
+func (t *LoggingTransport) log(req *http.Request, resp *http.Response, err error, retries int, duration time.Duration) {
+	logger := RequestLogger(req)
+	if logger == nil {
+		logger = t.logger
+	}
+	if logger == nil {
+		return
+	}
+
+	level := t.config.Level
+	if hostLevel, ok := t.config.HostLevels[req.URL.Hostname()]; ok {
+		level = hostLevel
+	}
+
+	fields := map[string]interface{}{
+		"method":      req.Method,
+		"url":         t.RedactURL(req.URL),
+		"host":        req.URL.Host,
+		"duration_ms": duration.Milliseconds(),
+		"retries":     retries,
+	}
+
+	switch {
+	case err != nil:
+		level = ErrorLevel
+		fields["error"] = err.Error()
+	case resp.StatusCode >= 500:
+		level = ErrorLevel
+		fields["status"] = resp.StatusCode
+	case resp.StatusCode >= 400:
+		if level > WarningLevel {
+			level = WarningLevel
+		}
+		fields["status"] = resp.StatusCode
+	default:
+		fields["status"] = resp.StatusCode
+	}
+
+	logger.LogWithContext(level, getPrefixForLevel(level), "HTTP client request", fields)
+}
+
+// RedactURL returns u as a string with credentials and sensitive query values redacted
+func (t *LoggingTransport) RedactURL(u *url.URL) string {
+	return redactURL(u, t.redact)
+}
+
+// NewLoggingHTTPClient returns an http.Client whose requests are logged through logger
+func NewLoggingHTTPClient(logger *LoggerCore, config HTTPClientConfig) *http.Client {
+	return &http.Client{Transport: NewLoggingTransport(nil, logger, config)}
+}
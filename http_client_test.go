@@ -0,0 +1,88 @@
+package pim
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoggingTransportLogsRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.Level = TraceLevel
+	})
+
+	client := NewLoggingHTTPClient(logger, DefaultHTTPClientConfig)
+	resp, err := client.Get(server.URL + "/items?page=2&access_token=abc123")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	resp, _ = client.Get(server.URL + "/missing")
+	resp.Body.Close()
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+
+	first := entries[0]
+	if first.Level != DebugLevel || first.Context["status"] != http.StatusOK || first.Context["method"] != http.MethodGet {
+		t.Errorf("Unexpected entry for successful request: %+v", first)
+	}
+	loggedURL := first.Context["url"].(string)
+	if strings.Contains(loggedURL, "abc123") || !strings.Contains(loggedURL, "access_token=[REDACTED]") || !strings.Contains(loggedURL, "page=2") {
+		t.Errorf("Expected token to be redacted from URL, got: %s", loggedURL)
+	}
+	if entries[1].Level != WarningLevel || entries[1].Context["status"] != http.StatusNotFound {
+		t.Errorf("Expected 404 to be logged as warning, got: %+v", entries[1])
+	}
+}
+
+func TestLoggingTransportRetriesAndHostLevels(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.Level = TraceLevel
+	})
+
+	client := NewLoggingHTTPClient(logger, HTTPClientConfig{
+		Level:        DebugLevel,
+		HostLevels:   map[string]LogLevel{serverURL.Hostname(): InfoLevel},
+		Retries:      3,
+		RetryBackoff: time.Millisecond,
+	})
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 1 {
+		t.Fatalf("Expected one entry per logical request, got %d", len(entries))
+	}
+	if entries[0].Context["retries"] != 2 || entries[0].Context["status"] != http.StatusOK {
+		t.Errorf("Expected 2 retries ending in 200, got: %v", entries[0].Context)
+	}
+	if entries[0].Level != InfoLevel {
+		t.Errorf("Expected host level override, got: %v", entries[0].Level)
+	}
+}
@@ -0,0 +1,315 @@
+package pim
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// DefaultDumpHeader is the request header that turns on payload dumps
+const DefaultDumpHeader = "X-Debug-Dump"
+
+// DefaultDumpRedactedHeaders are the headers whose values are never dumped
+var DefaultDumpRedactedHeaders = []string{
+	"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Auth-Token",
+}
+
+// HTTPDumpConfig configures payload dumps of single requests, see
+// DumpRequest. Dumps are debug entries written even if the logger level or
+// sampling would drop them, so payloads can be captured in production for
+// the requests that ask for it.
+type HTTPDumpConfig struct {
+	// Header turns on the dump of a request when set to a value other than
+	// "0" or "false" (default: DefaultDumpHeader). Handlers can also turn
+	// it on with WithDebugDump.
+	Header string `json:"header"`
+	// Token, if set, is the value Header must carry, so clients cannot
+	// trigger dumps without knowing it
+	Token string `json:"-"`
+
+	MaxBodyBytes  int      `json:"max_body_bytes"` // Body snapshot size (default: 4096)
+	RedactHeaders []string `json:"redact_headers"` // Defaults to DefaultDumpRedactedHeaders
+	RedactFields  []string `json:"redact_fields"`  // JSON keys and form fields redacted in bodies (default: DefaultRedactedQueryParams)
+
+	// MaxEntropy caps the randomness of the dumped text: words of 16 or
+	// more characters with more bits of Shannon entropy per character, such
+	// as API keys and session tokens, are redacted (default: 4.0). The cap
+	// is relative to the 64 characters of base64; hex words, which cannot
+	// exceed 4 bits per character, are held to two thirds of it.
+	MaxEntropy float64 `json:"max_entropy"`
+}
+
+// debugDumpKey is the context key set by WithDebugDump
+type debugDumpKey struct{}
+
+// WithDebugDump returns a context that turns on payload dumps for the
+// request it is attached to, e.g. from an auth middleware for flagged users
+func WithDebugDump(ctx context.Context) context.Context {
+	return context.WithValue(ctx, debugDumpKey{}, true)
+}
+
+// DebugDumpRequested reports whether r asks for a payload dump through its
+// context or the dump header
+func DebugDumpRequested(r *http.Request, config HTTPDumpConfig) bool {
+	if on, _ := r.Context().Value(debugDumpKey{}).(bool); on {
+		return true
+	}
+	header := config.Header
+	if header == "" {
+		header = DefaultDumpHeader
+	}
+	value := r.Header.Get(header)
+	if config.Token != "" {
+		return subtle.ConstantTimeCompare([]byte(value), []byte(config.Token)) == 1
+	}
+	return value != "" && value != "0" && !strings.EqualFold(value, "false")
+}
+
+// DumpRequest logs the method, URL, headers and a bounded, sanitized body
+// snapshot of r through logger if r asks for a dump (see
+// DebugDumpRequested). The body is restored, so handlers read it in full.
+func DumpRequest(logger *LoggerCore, r *http.Request, config HTTPDumpConfig) {
+	if !DebugDumpRequested(r, config) {
+		return
+	}
+	newHTTPDumper(config).dumpRequest(logger, r)
+}
+
+// DumpResponse logs the status, headers and a bounded, sanitized body
+// snapshot of resp through logger if its request asks for a dump. The body
+// is restored, so callers read it in full.
+func DumpResponse(logger *LoggerCore, resp *http.Response, config HTTPDumpConfig) {
+	if resp.Request == nil || !DebugDumpRequested(resp.Request, config) {
+		return
+	}
+	newHTTPDumper(config).dumpResponse(logger, resp)
+}
+
+// httpDumper formats dumps with the redactions of a config
+type httpDumper struct {
+	config        HTTPDumpConfig
+	redactHeaders map[string]bool
+	redactQuery   map[string]bool
+	jsonFields    *regexp.Regexp // "key": "value" in JSON
+	formFields    *regexp.Regexp // key=value in forms
+}
+
+var dumpWordRegex = regexp.MustCompile(`[A-Za-z0-9+/=_.-]{16,}`)
+
+// newHTTPDumper applies the defaults of config and compiles its redactions
+func newHTTPDumper(config HTTPDumpConfig) *httpDumper {
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = 4096
+	}
+	if config.RedactHeaders == nil {
+		config.RedactHeaders = DefaultDumpRedactedHeaders
+	}
+	if config.RedactFields == nil {
+		config.RedactFields = DefaultRedactedQueryParams
+	}
+	if config.MaxEntropy <= 0 {
+		config.MaxEntropy = 4.0
+	}
+
+	d := &httpDumper{
+		config:        config,
+		redactHeaders: make(map[string]bool),
+		redactQuery:   make(map[string]bool),
+	}
+	for _, header := range config.RedactHeaders {
+		d.redactHeaders[http.CanonicalHeaderKey(header)] = true
+	}
+	if config.Token != "" {
+		header := config.Header
+		if header == "" {
+			header = DefaultDumpHeader
+		}
+		d.redactHeaders[http.CanonicalHeaderKey(header)] = true
+	}
+
+	names := make([]string, len(config.RedactFields))
+	for i, field := range config.RedactFields {
+		d.redactQuery[strings.ToLower(field)] = true
+		names[i] = regexp.QuoteMeta(field)
+	}
+	if len(names) > 0 {
+		alternation := strings.Join(names, "|")
+		d.jsonFields = regexp.MustCompile(`(?i)("(?:` + alternation + `)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+		d.formFields = regexp.MustCompile(`(?i)(^|[&?\s])((?:` + alternation + `)=)[^&\s]*`)
+	}
+	return d
+}
+
+// dumpRequest logs the dump of r
+func (d *httpDumper) dumpRequest(logger *LoggerCore, r *http.Request) {
+	snapshot, truncated := peekBody(&r.Body, d.config.MaxBodyBytes)
+	fields := map[string]interface{}{
+		"method":  r.Method,
+		"url":     redactURL(r.URL, d.redactQuery),
+		"headers": d.headers(r.Header),
+	}
+	d.addBody(fields, snapshot, truncated, r.Header.Get("Content-Type"))
+	logger.LogWithContext(DebugLevel, DebugPrefix, "HTTP request dump", fields, WithLevelOverride(DebugLevel), BypassSampling())
+}
+
+// dumpResponse logs the dump of resp
+func (d *httpDumper) dumpResponse(logger *LoggerCore, resp *http.Response) {
+	snapshot, truncated := peekBody(&resp.Body, d.config.MaxBodyBytes)
+	if resp.ContentLength > int64(len(snapshot)) {
+		truncated = true
+	}
+	fields := map[string]interface{}{
+		"status":  resp.StatusCode,
+		"headers": d.headers(resp.Header),
+	}
+	d.addBody(fields, snapshot, truncated, resp.Header.Get("Content-Type"))
+	logger.LogWithContext(DebugLevel, DebugPrefix, "HTTP response dump", fields, WithLevelOverride(DebugLevel), BypassSampling())
+}
+
+// headers returns the headers with the redacted ones masked
+func (d *httpDumper) headers(header http.Header) map[string]string {
+	dumped := make(map[string]string, len(header))
+	for name, values := range header {
+		if d.redactHeaders[http.CanonicalHeaderKey(name)] {
+			dumped[name] = "[REDACTED]"
+			continue
+		}
+		dumped[name] = d.sanitize(strings.Join(values, ", "))
+	}
+	return dumped
+}
+
+// addBody adds the sanitized body snapshot to fields. Bodies that are not
+// text are described by their type only.
+func (d *httpDumper) addBody(fields map[string]interface{}, snapshot []byte, truncated bool, contentType string) {
+	if len(snapshot) == 0 {
+		return
+	}
+	fields["body_truncated"] = truncated
+	if !textContentType(contentType) {
+		fields["body"] = "[" + contentType + " body not dumped]"
+		return
+	}
+	fields["body"] = d.sanitize(string(snapshot))
+}
+
+// sanitize redacts sensitive fields and high-entropy words in s
+func (d *httpDumper) sanitize(s string) string {
+	if d.jsonFields != nil {
+		s = d.jsonFields.ReplaceAllString(s, `$1"[REDACTED]"`)
+		s = d.formFields.ReplaceAllString(s, `$1$2[REDACTED]`)
+	}
+	return dumpWordRegex.ReplaceAllStringFunc(s, func(word string) string {
+		limit := d.config.MaxEntropy
+		if hexWord(word) {
+			limit = limit * 4 / 6 // log2(16) / log2(64)
+		}
+		if shannonEntropy(word) > limit {
+			return "[REDACTED]"
+		}
+		return word
+	})
+}
+
+// hexWord reports whether word is made of hex digits, with both letters
+// and numbers so plain numbers are not taken for hex
+func hexWord(word string) bool {
+	letters, digits := false, false
+	for _, c := range word {
+		switch {
+		case c >= '0' && c <= '9':
+			digits = true
+		case c >= 'a' && c <= 'f', c >= 'A' && c <= 'F':
+			letters = true
+		default:
+			return false
+		}
+	}
+	return letters && digits
+}
+
+// textContentType reports whether bodies of contentType are readable text;
+// an empty type is assumed to be text
+func textContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "application/x-www-form-urlencoded",
+		strings.HasSuffix(mediaType, "json"),
+		strings.HasSuffix(mediaType, "xml"),
+		strings.HasSuffix(mediaType, "javascript"):
+		return true
+	}
+	return false
+}
+
+// shannonEntropy returns the bits of entropy per character of s
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	n := 0
+	for _, c := range s {
+		counts[c]++
+		n++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(n)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// replayBody is a body whose start was read for a snapshot
+type replayBody struct {
+	io.Reader
+	io.Closer
+}
+
+// peekBody reads up to limit bytes of *body and replaces it with a body
+// that yields them again, followed by the rest
+func peekBody(body *io.ReadCloser, limit int) ([]byte, bool) {
+	if *body == nil || *body == http.NoBody {
+		return nil, false
+	}
+	buf, _ := io.ReadAll(io.LimitReader(*body, int64(limit)+1))
+	*body = replayBody{Reader: io.MultiReader(bytes.NewReader(buf), *body), Closer: *body}
+	if len(buf) > limit {
+		return buf[:limit], true
+	}
+	return buf, false
+}
+
+// redactURL returns u as a string with credentials and the values of the
+// query parameters in redact (lowercase) redacted
+func redactURL(u *url.URL, redact map[string]bool) string {
+	redacted := *u
+	if u.RawQuery != "" {
+		// Rewrite pairs in place to keep the original parameter order
+		pairs := strings.Split(u.RawQuery, "&")
+		for i, pair := range pairs {
+			key, _, hasValue := strings.Cut(pair, "=")
+			name, err := url.QueryUnescape(key)
+			if err != nil {
+				name = key
+			}
+			if hasValue && redact[strings.ToLower(name)] {
+				pairs[i] = key + "=[REDACTED]"
+			}
+		}
+		redacted.RawQuery = strings.Join(pairs, "&")
+	}
+	return redacted.Redacted()
+}
@@ -0,0 +1,174 @@
+package pim
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPMiddlewareDump(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+
+	const requestBody = `{"user":"alice","password":"hunter2","session":"dGhpcyBpcyBhIHNlY3JldCBzZXNzaW9uIHRva2Vu"}`
+	config := DefaultHTTPMiddlewareConfig
+	config.LogRequests = false
+	config.Dump = &HTTPDumpConfig{MaxBodyBytes: 100}
+	handler := HTTPMiddleware(logger, config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != requestBody {
+			t.Errorf("Expected the handler to read the whole body, got %q", body)
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, strings.Repeat("ok ", 50))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/login?api_key=abc&page=2", strings.NewReader(requestBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer abc")
+	req.Header.Set(DefaultDumpHeader, "1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 2 {
+		t.Fatalf("Expected request and response dumps, got %d entries", len(entries))
+	}
+
+	request := entries[0]
+	if request.Message != "HTTP request dump" || request.Level != DebugLevel {
+		t.Fatalf("Unexpected request dump: %+v", request)
+	}
+	if url := request.Context["url"]; url != "/login?api_key=[REDACTED]&page=2" {
+		t.Errorf("Expected redacted URL, got %v", url)
+	}
+	headers := request.Context["headers"].(map[string]string)
+	if headers["Authorization"] != "[REDACTED]" || headers["Content-Type"] != "application/json" {
+		t.Errorf("Unexpected headers: %v", headers)
+	}
+	body := request.Context["body"].(string)
+	if body != `{"user":"alice","password":"[REDACTED]","session":"[REDACTED]"}` || request.Context["body_truncated"] != false {
+		t.Errorf("Expected a sanitized body, got %q (truncated: %v)", body, request.Context["body_truncated"])
+	}
+
+	response := entries[1]
+	if response.Message != "HTTP response dump" || response.Context["status"] != http.StatusAccepted {
+		t.Fatalf("Unexpected response dump: %+v", response)
+	}
+	if body := response.Context["body"].(string); len(body) != 100 || response.Context["body_truncated"] != true {
+		t.Errorf("Expected a 100 byte truncated body, got %q", body)
+	}
+}
+
+func TestHTTPMiddlewareDumpOnlyWhenRequested(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+
+	config := DefaultHTTPMiddlewareConfig
+	config.LogRequests = false
+	config.Dump = &HTTPDumpConfig{Header: "X-Dump", Token: "s3cret"}
+	handler := HTTPMiddleware(logger, config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, value := range []string{"", "1", "wrong"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if value != "" {
+			req.Header.Set("X-Dump", value)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if size := buffer.GetBufferSize(); size != 0 {
+		t.Fatalf("Expected no dumps without the token, got %d entries", size)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Dump", "s3cret")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	entries := buffer.GetBuffer()
+	if len(entries) != 2 {
+		t.Fatalf("Expected dumps with the token, got %d entries", len(entries))
+	}
+	if headers := entries[0].Context["headers"].(map[string]string); headers["X-Dump"] != "[REDACTED]" {
+		t.Errorf("Expected the token header to be redacted, got %v", headers)
+	}
+}
+
+func TestDumpRequestContextFlag(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("\x89PNG"))
+	req.Header.Set("Content-Type", "image/png")
+	DumpRequest(logger, req, HTTPDumpConfig{})
+	if size := buffer.GetBufferSize(); size != 0 {
+		t.Fatalf("Expected no dump without the flag, got %d entries", size)
+	}
+
+	DumpRequest(logger, req.WithContext(WithDebugDump(req.Context())), HTTPDumpConfig{})
+	entries := buffer.GetBuffer()
+	if len(entries) != 1 || entries[0].Context["body"] != "[image/png body not dumped]" {
+		t.Errorf("Expected a dump without the binary body, got %+v", entries)
+	}
+}
+
+func TestDumpResponse(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(DefaultDumpHeader, "true")
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Set-Cookie": {"id=1"}},
+		Body:       io.NopCloser(strings.NewReader("token=abc&name=bob")),
+		Request:    req,
+	}
+	DumpResponse(logger, resp, HTTPDumpConfig{})
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "token=abc&name=bob" {
+		t.Errorf("Expected the body to be restored, got %q", body)
+	}
+	entries := buffer.GetBuffer()
+	if len(entries) != 1 || entries[0].Context["body"] != "token=[REDACTED]&name=bob" || entries[0].Context["body_truncated"] != false {
+		t.Fatalf("Unexpected response dump: %+v", entries)
+	}
+	if headers := entries[0].Context["headers"].(map[string]string); headers["Set-Cookie"] != "[REDACTED]" {
+		t.Errorf("Expected cookies to be redacted, got %v", headers)
+	}
+}
+
+func TestHTTPDumpRedactsHexSecretsAndRunsHooks(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+	logger.AddHookFunc(func(entry CoreLogEntry) (CoreLogEntry, error) {
+		entry.Context["hooked"] = true
+		return entry, nil
+	})
+
+	config := DefaultHTTPMiddlewareConfig
+	config.LogRequests = false
+	config.Dump = &HTTPDumpConfig{}
+	handler := HTTPMiddleware(logger, config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	const body = `{"etag":"7c4a8d09ca3762af61e59520943dc26494f8941b","id":"12345678901234567890"}`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(DefaultDumpHeader, "1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	request := buffer.GetBuffer()[0]
+	if got := request.Context["body"]; got != `{"etag":"[REDACTED]","id":"12345678901234567890"}` {
+		t.Errorf("Expected the hex key redacted and the number kept, got %q", got)
+	}
+	if request.Context["hooked"] != true {
+		t.Errorf("Expected the logger's hooks to run on dumps, got %v", request.Context)
+	}
+}
+
+func TestShannonEntropy(t *testing.T) {
+	if e := shannonEntropy("aaaaaaaaaaaaaaaa"); e != 0 {
+		t.Errorf("Expected no entropy for a repeated character, got %f", e)
+	}
+	if e := shannonEntropy("internationalization"); e > 4 {
+		t.Errorf("Expected a word to stay below the default cap, got %f", e)
+	}
+	if e := shannonEntropy("Zx9Qw2Lk7Vb4Np1Ts8Hm"); e <= 4 {
+		t.Errorf("Expected a random token to exceed the default cap, got %f", e)
+	}
+}
@@ -0,0 +1,181 @@
+package pim
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// HTTPMiddlewareConfig configures the HTTP server middleware
+type HTTPMiddlewareConfig struct {
+	// CorrelationHeaders maps request headers to context fields, e.g.
+	// "X-Session-Replay-ID" -> "session_replay_id". Defaults to DefaultCorrelationHeaders.
+	CorrelationHeaders map[string]string `json:"correlation_headers"`
+
+	LogRequests bool     `json:"log_requests"` // Log one entry per completed request
+	Level       LogLevel `json:"level"`        // Level for request entries (5xx responses are logged as errors)
+
+	// Dump turns on request and response payload dumps for requests that
+	// ask for them, see DumpRequest
+	Dump *HTTPDumpConfig `json:"dump,omitempty"`
+}
+
+// DefaultCorrelationHeaders are the headers extracted into context fields by default
+var DefaultCorrelationHeaders = map[string]string{
+	"X-Request-ID":        "request_id",
+	"X-Trace-ID":          "trace_id",
+	"X-Session-ID":        "session_id",
+	"X-Session-Replay-ID": "session_replay_id",
+	"X-Client-Event-ID":   "client_event_id",
+}
+
+// DefaultHTTPMiddlewareConfig provides sensible defaults
+var DefaultHTTPMiddlewareConfig = HTTPMiddlewareConfig{
+	CorrelationHeaders: DefaultCorrelationHeaders,
+	LogRequests:        true,
+	Level:              InfoLevel,
+}
+
+// requestLoggerKey is the context key for the request-scoped logger (see NewContext)
+type requestLoggerKey struct{}
+
+// statusRecorder captures the response status code
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+
+	// Start of the body, kept for response dumps
+	snapshot      []byte
+	snapshotLimit int
+}
+
+// WriteHeader records the status code
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the number of bytes written
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	if room := r.snapshotLimit - len(r.snapshot); room > 0 {
+		r.snapshot = append(r.snapshot, b[:min(n, room)]...)
+	}
+	return n, err
+}
+
+// Flush implements http.Flusher if the wrapped writer does, so streaming
+// handlers such as server-sent events work behind the middleware
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		if r.status == 0 {
+			r.status = http.StatusOK
+		}
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker if the wrapped writer does, so handlers
+// can take over the connection, e.g. for websockets
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("pim: %T does not implement http.Hijacker", r.ResponseWriter)
+	}
+	if r.status == 0 {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// HTTPMiddleware returns middleware that extracts correlation fields from
+// request headers, attaches a request-scoped logger to the request context
+// and optionally logs each completed request and dumps payloads
+func HTTPMiddleware(logger *LoggerCore, config HTTPMiddlewareConfig) func(http.Handler) http.Handler {
+	if config.CorrelationHeaders == nil {
+		config.CorrelationHeaders = DefaultCorrelationHeaders
+	}
+	var dumper *httpDumper
+	if config.Dump != nil {
+		dumper = newHTTPDumper(*config.Dump)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			fields := make(map[string]interface{})
+			for header, field := range config.CorrelationHeaders {
+				if value := r.Header.Get(header); value != "" {
+					fields[field] = value
+				}
+			}
+
+			requestLogger := logger
+			if len(fields) > 0 {
+				requestLogger = logger.WithContext(fields)
+			}
+
+			ctx := NewContext(ContextWithFields(r.Context(), fields), requestLogger)
+			recorder := &statusRecorder{ResponseWriter: w}
+			dump := dumper != nil && DebugDumpRequested(r, dumper.config)
+			if dump {
+				dumper.dumpRequest(requestLogger, r)
+				recorder.snapshotLimit = dumper.config.MaxBodyBytes
+			}
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+
+			status := recorder.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			if dump {
+				dumper.dumpResponse(requestLogger, &http.Response{
+					StatusCode:    status,
+					Header:        recorder.Header(),
+					Body:          io.NopCloser(bytes.NewReader(recorder.snapshot)),
+					ContentLength: int64(recorder.bytes),
+				})
+			}
+
+			if !config.LogRequests {
+				return
+			}
+
+			level, prefix := config.Level, getPrefixForLevel(config.Level)
+			if status >= 500 {
+				level, prefix = ErrorLevel, ErrorPrefix
+			}
+
+			requestLogger.LogWithContext(level, prefix, "HTTP request", map[string]interface{}{
+				"method":      r.Method,
+				"path":        r.URL.Path,
+				"status":      status,
+				"bytes":       recorder.bytes,
+				"duration_ms": time.Since(start).Milliseconds(),
+				"remote_addr": r.RemoteAddr,
+			})
+		})
+	}
+}
+
+// RequestLogger returns the request-scoped logger attached by HTTPMiddleware,
+// or nil if the request did not pass through the middleware
+func RequestLogger(r *http.Request) *LoggerCore {
+	return FromContext(r.Context())
+}
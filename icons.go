@@ -0,0 +1,149 @@
+package pim
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// IconMode selects the icons of console output: the emoji of the level
+// prefixes and theme formats, ASCII substitutes for consoles that render
+// emoji as mojibake, such as Windows consoles without a UTF-8 code page,
+// or no icons
+type IconMode string
+
+const (
+	IconModeAuto  IconMode = ""      // Emoji, unless the console can't render them (see Terminal.Unicode)
+	IconModeEmoji IconMode = "emoji" // The emoji prefixes and theme Icons
+	IconModeASCII IconMode = "ascii" // The theme's ASCIIIcons, e.g. "[x] ERROR"
+	IconModeNone  IconMode = "none"  // Level names only
+)
+
+// Validate reports whether m is a supported icon mode
+func (m IconMode) Validate() error {
+	switch m {
+	case IconModeAuto, IconModeEmoji, IconModeASCII, IconModeNone:
+		return nil
+	}
+	return fmt.Errorf("unknown icon mode %q", string(m))
+}
+
+// DefaultASCIIIcons are the ASCII icons of themes that don't set their own
+// ASCIIIcons
+var DefaultASCIIIcons = ThemeIcons{
+	Panic:   "[!!]",
+	Error:   "[x]",
+	Warning: "[!]",
+	Info:    "[i]",
+	Success: "[+]",
+	Debug:   "[?]",
+	Trace:   "[.]",
+	Config:  "[*]",
+}
+
+// forLevel returns the icon of level
+func (icons ThemeIcons) forLevel(level LogLevel) string {
+	switch level {
+	case PanicLevel:
+		return icons.Panic
+	case ErrorLevel:
+		return icons.Error
+	case WarningLevel:
+		return icons.Warning
+	case InfoLevel:
+		return icons.Info
+	case DebugLevel:
+		return icons.Debug
+	case TraceLevel:
+		return icons.Trace
+	default:
+		return icons.Info
+	}
+}
+
+// asciiIcons returns the theme's ASCIIIcons, with DefaultASCIIIcons for the
+// icons it doesn't set
+func (t *Theme) asciiIcons() ThemeIcons {
+	icons := DefaultASCIIIcons
+	mergeStrings(&icons, t.ASCIIIcons)
+	return icons
+}
+
+// builtinPrefix describes a built-in prefix such as InfoPrefix, so its
+// emoji can be substituted
+type builtinPrefix struct {
+	name  string
+	color *color.Color
+	icon  func(icons ThemeIcons) string
+}
+
+// fixedIcon returns an icon getter for prefixes without a theme icon
+func fixedIcon(icon string) func(ThemeIcons) string {
+	return func(ThemeIcons) string { return icon }
+}
+
+// builtinPrefixes are the built-in prefixes with emoji
+var builtinPrefixes = map[string]builtinPrefix{
+	InfoPrefix:    {"INFO", Cyan, func(i ThemeIcons) string { return i.Info }},
+	SuccessPrefix: {"SUCCESS", Green, func(i ThemeIcons) string { return i.Success }},
+	InitPrefix:    {"INIT", Blue, fixedIcon("[>]")},
+	ConfigPrefix:  {"CONFIG", Purple, func(i ThemeIcons) string { return i.Config }},
+	WarningPrefix: {"WARNING", Yellow, func(i ThemeIcons) string { return i.Warning }},
+	ErrorPrefix:   {"ERROR", Red, func(i ThemeIcons) string { return i.Error }},
+	DebugPrefix:   {"DEBUG", White, func(i ThemeIcons) string { return i.Debug }},
+	TracePrefix:   {"TRACE", HiBlue, func(i ThemeIcons) string { return i.Trace }},
+	PanicPrefix:   {"PANIC", HiRed, func(i ThemeIcons) string { return i.Panic }},
+	MetricPrefix:  {"METRIC", HiPurple, fixedIcon("[#]")},
+	JsonPrefix:    {"JSON", HiCyan, fixedIcon("[{]")},
+	DataPrefix:    {"DATA", HiGreen, fixedIcon("[=]")},
+	ModelPrefix:   {"MODEL", HiBlue, fixedIcon("[m]")},
+}
+
+// prefixSubstitutes returns the replacements of the built-in prefixes and
+// the prefixes of registered levels for the icon mode of config, keeping
+// their colors and alignment, e.g. "[x] ERROR    " for ErrorPrefix in
+// ASCII mode. It returns nil when the prefixes are used as they are.
+func prefixSubstitutes(config LoggerConfig) map[string]string {
+	if config.IconMode != IconModeASCII && config.IconMode != IconModeNone {
+		return nil
+	}
+	icons := themeOf(config).asciiIcons()
+
+	substitutes := make(map[string]string, len(builtinPrefixes))
+	for prefix, builtin := range builtinPrefixes {
+		label := fmt.Sprintf("%-9s", builtin.name)
+		if config.IconMode == IconModeASCII {
+			label = builtin.icon(icons) + " " + label
+		}
+		substitutes[prefix] = builtin.color.Sprint(label)
+	}
+	for _, custom := range registeredLevels() {
+		label := fmt.Sprintf("%-9s", strings.ToUpper(custom.name))
+		if config.IconMode == IconModeASCII {
+			label = custom.ASCIIIcon + " " + label
+		}
+		substitutes[custom.Prefix] = colorize(custom.Color, label)
+	}
+	return substitutes
+}
+
+// themeOf returns the theme config selects, like applyConfigTheme, or the
+// default theme. Theme files that fail to load are reported by the theme
+// manager, so they fall back to the default theme silently here.
+func themeOf(config LoggerConfig) *Theme {
+	switch {
+	case config.CustomTheme != nil:
+		return config.CustomTheme
+	case config.ThemeFile != "":
+		if theme, err := LoadThemeFromFile(config.ThemeFile); err == nil {
+			return theme
+		}
+	case config.ThemeName != "":
+		if theme, ok := lookupTheme(config.ThemeName); ok {
+			return &theme
+		}
+	}
+	theme := builtinThemes["default"]
+	return &theme
+}
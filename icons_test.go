@@ -0,0 +1,116 @@
+package pim
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestIconModeValidate(t *testing.T) {
+	for _, mode := range []IconMode{IconModeAuto, IconModeEmoji, IconModeASCII, IconModeNone} {
+		if err := mode.Validate(); err != nil {
+			t.Errorf("expected %q to be valid, got %v", mode, err)
+		}
+	}
+	if err := IconMode("unicode").Validate(); err == nil {
+		t.Error("expected an unknown mode to be rejected")
+	}
+}
+
+func TestTextEncoderIconModes(t *testing.T) {
+	originalNoColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = originalNoColor }()
+
+	entry := encoderTestEntry()
+	entry.Context = nil
+	entry.Prefix = ErrorPrefix
+
+	tests := []struct {
+		mode     IconMode
+		expected string
+	}{
+		{IconModeEmoji, ErrorPrefix + " charged card"},
+		{IconModeASCII, "[x] ERROR     charged card"},
+		{IconModeNone, "ERROR     charged card"},
+	}
+	for _, tt := range tests {
+		data, err := NewTextEncoder(LoggerConfig{IconMode: tt.mode}, TextPrefix).EncodeEntry(entry)
+		if err != nil {
+			t.Fatalf("EncodeEntry failed: %v", err)
+		}
+		if string(data) != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.mode, tt.expected, data)
+		}
+	}
+
+	// Custom prefixes are not substituted
+	entry.Prefix = "[custom]"
+	data, _ := NewTextEncoder(LoggerConfig{IconMode: IconModeASCII}, TextPrefix).EncodeEntry(entry)
+	if !strings.HasPrefix(string(data), "[custom] ") {
+		t.Errorf("expected the custom prefix to be kept, got %q", data)
+	}
+}
+
+func TestThemeManagerIconModes(t *testing.T) {
+	tm := NewThemeManager()
+	entry := CoreLogEntry{Level: WarningLevel, LevelString: "warning"}
+	theme := tm.GetTheme()
+
+	if got := tm.levelLabel(entry, theme); got != theme.Icons.Warning+" WARNING" {
+		t.Errorf("expected the emoji label, got %q", got)
+	}
+	tm.SetIconMode(IconModeASCII)
+	if got := tm.levelLabel(entry, theme); got != "[!] WARNING" {
+		t.Errorf("expected the ASCII label, got %q", got)
+	}
+	tm.SetIconMode(IconModeNone)
+	if got := tm.levelLabel(entry, theme); got != "WARNING" {
+		t.Errorf("expected no icon, got %q", got)
+	}
+}
+
+func TestThemeFileASCIIIcons(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "theme.yaml")
+	data := "name: retro\nascii_icons:\n  warning: \"(!)\"\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	theme, err := LoadThemeFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadThemeFromFile failed: %v", err)
+	}
+	icons := theme.asciiIcons()
+	if icons.Warning != "(!)" || icons.Error != DefaultASCIIIcons.Error {
+		t.Errorf("expected the theme icon with defaults for the rest, got %+v", icons)
+	}
+
+	originalNoColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = originalNoColor }()
+	entry := CoreLogEntry{Prefix: WarningPrefix, Message: "low disk"}
+	config := LoggerConfig{IconMode: IconModeASCII, ThemeFile: path}
+	out, _ := NewTextEncoder(config, TextPrefix).EncodeEntry(entry)
+	if string(out) != "(!) WARNING   low disk" {
+		t.Errorf("expected the theme's ASCII icon, got %q", out)
+	}
+}
+
+func TestConsoleConfigIconMode(t *testing.T) {
+	t.Setenv("LANG", "C")
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_CTYPE", "")
+
+	config, _, _ := consoleConfig(LoggerConfig{ConsoleMode: ConsoleModeColor}, os.Stdout, ConsoleTextFields)
+	if config.IconMode != IconModeASCII {
+		t.Errorf("expected ASCII icons for a C locale, got %q", config.IconMode)
+	}
+
+	config, _, _ = consoleConfig(LoggerConfig{IconMode: IconModeEmoji}, os.Stdout, ConsoleTextFields)
+	if config.IconMode != IconModeEmoji {
+		t.Errorf("expected an explicit mode to be kept, got %q", config.IconMode)
+	}
+}
@@ -0,0 +1,103 @@
+package pim
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"strings"
+)
+
+// All returns an iterator over the buffered entries, oldest first. It ranges
+// over the entries buffered when iteration starts without copying them;
+// entries written meanwhile are not included.
+func (w *BufferWriter) All() iter.Seq[CoreLogEntry] {
+	return func(yield func(CoreLogEntry) bool) {
+		w.mu.RLock()
+		entries := w.buffer // Write and ClearBuffer never modify a published slice
+		w.mu.RUnlock()
+
+		for _, entry := range entries {
+			if !yield(entry) {
+				return
+			}
+		}
+	}
+}
+
+// Query returns an iterator over the buffered entries that match, oldest
+// first, see All
+func (w *BufferWriter) Query(match func(CoreLogEntry) bool) iter.Seq[CoreLogEntry] {
+	return FilterEntries(w.All(), match)
+}
+
+// FilterEntries returns an iterator over the entries of seq that match.
+// Entries are evaluated lazily, so breaking out of the loop stops reading
+// seq.
+func FilterEntries(seq iter.Seq[CoreLogEntry], match func(CoreLogEntry) bool) iter.Seq[CoreLogEntry] {
+	return func(yield func(CoreLogEntry) bool) {
+		for entry := range seq {
+			if match(entry) && !yield(entry) {
+				return
+			}
+		}
+	}
+}
+
+// ReadLogEntries reads lines written by FileWriter from r until EOF,
+// parsing each with ParseLogLine. Lines that cannot be parsed are yielded
+// with an error alongside a best-effort entry, and iteration continues.
+func ReadLogEntries(r io.Reader) iter.Seq2[CoreLogEntry, error] {
+	return func(yield func(CoreLogEntry, error) bool) {
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadString('\n')
+			if line = strings.TrimRight(line, "\r\n"); line != "" {
+				if !yieldLogLine(yield, line) {
+					return
+				}
+			}
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(CoreLogEntry{}, fmt.Errorf("failed to read log: %w", err))
+				return
+			}
+		}
+	}
+}
+
+// ReadLogFile reads the entries of a log file, see ReadLogEntries. Files
+// ending in .gz, such as compressed rotations, are decompressed. The file
+// is read as the loop advances and closed when it ends.
+func ReadLogFile(path string) iter.Seq2[CoreLogEntry, error] {
+	return func(yield func(CoreLogEntry, error) bool) {
+		file, err := os.Open(path)
+		if err != nil {
+			yield(CoreLogEntry{}, fmt.Errorf("failed to open log file: %w", err))
+			return
+		}
+		defer file.Close()
+
+		var r io.Reader = file
+		if strings.HasSuffix(path, ".gz") {
+			gz, err := gzip.NewReader(file)
+			if err != nil {
+				yield(CoreLogEntry{}, fmt.Errorf("failed to decompress log file: %w", err))
+				return
+			}
+			defer gz.Close()
+			r = gz
+		}
+
+		for entry, err := range ReadLogEntries(r) {
+			if !yield(entry, err) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package pim
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBufferWriterAll(t *testing.T) {
+	config := DefaultLoggerConfig
+	writer := NewBufferWriter(config, 3)
+	for _, message := range []string{"a", "b", "c", "d"} {
+		writer.Write(CoreLogEntry{Message: message, Level: InfoLevel})
+	}
+
+	var messages []string
+	for entry := range writer.All() {
+		messages = append(messages, entry.Message)
+		writer.ClearBuffer() // Does not affect the running iteration
+		writer.Write(CoreLogEntry{Message: "new"})
+	}
+	if want := []string{"b", "c", "d"}; !reflect.DeepEqual(messages, want) {
+		t.Errorf("Expected %v, got %v", want, messages)
+	}
+}
+
+func TestBufferWriterQuery(t *testing.T) {
+	writer := NewBufferWriter(DefaultLoggerConfig, 10)
+	writer.Write(CoreLogEntry{Message: "ok", Level: InfoLevel})
+	writer.Write(CoreLogEntry{Message: "bad", Level: ErrorLevel})
+	writer.Write(CoreLogEntry{Message: "worse", Level: ErrorLevel})
+
+	var messages []string
+	for entry := range writer.Query(func(e CoreLogEntry) bool { return e.Level == ErrorLevel }) {
+		messages = append(messages, entry.Message)
+		break
+	}
+	if want := []string{"bad"}; !reflect.DeepEqual(messages, want) {
+		t.Errorf("Expected %v, got %v", want, messages)
+	}
+}
+
+func TestReadLogEntries(t *testing.T) {
+	input := `{"message":"json","level":3}
+[2024-01-02 03:04:05.000 UTC] [ERROR] text
+{broken
+`
+	var messages []string
+	errs := 0
+	for entry, err := range ReadLogEntries(strings.NewReader(input)) {
+		if err != nil {
+			errs++
+		}
+		messages = append(messages, entry.Message)
+	}
+	if want := []string{"json", "text", "{broken"}; !reflect.DeepEqual(messages, want) {
+		t.Errorf("Expected %q, got %q", want, messages)
+	}
+	if errs != 1 {
+		t.Errorf("Expected 1 parse error, got %d", errs)
+	}
+}
+
+func TestReadLogFileGzip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.gz")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(file)
+	gz.Write([]byte("{\"message\":\"one\"}\n{\"message\":\"two\"}"))
+	gz.Close()
+	file.Close()
+
+	var messages []string
+	for entry, err := range ReadLogFile(path) {
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		messages = append(messages, entry.Message)
+	}
+	if want := []string{"one", "two"}; !reflect.DeepEqual(messages, want) {
+		t.Errorf("Expected %v, got %v", want, messages)
+	}
+
+	for _, err := range ReadLogFile(filepath.Join(t.TempDir(), "missing.log")) {
+		if err == nil {
+			t.Error("Expected an error for a missing file")
+		}
+	}
+}
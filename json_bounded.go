@@ -0,0 +1,524 @@
+package pim
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PrettyLimits bounds the JSON that Json and JsonSerializer produce for
+// huge values, so one giant map can't allocate hundreds of MB. Elided
+// parts are replaced with markers, and the output stays valid JSON.
+type PrettyLimits struct {
+	MaxBytes    int // Output size after which the remaining values are elided (0: unlimited)
+	MaxElements int // Array elements and object keys written per container (0: unlimited)
+	MaxDepth    int // Nesting depth below which containers are elided (0: unlimited)
+}
+
+// DefaultPrettyLimits are the limits of DefaultJsonOptions and
+// DefaultSerializerOptions
+var DefaultPrettyLimits = PrettyLimits{
+	MaxBytes:    1 << 20,
+	MaxElements: 1000,
+	MaxDepth:    64,
+}
+
+// enabled reports whether any limit is set
+func (l PrettyLimits) enabled() bool {
+	return l.MaxBytes > 0 || l.MaxElements > 0 || l.MaxDepth > 0
+}
+
+// Markers of elided parts. Containers cut at MaxElements end with
+// "... N more" (arrays) or "...": "N more" (objects).
+const (
+	elidedMarker    = "..."
+	truncatedMarker = "truncated"
+	maxDepthMarker  = "max depth"
+)
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// cycleCheckDepth is the nesting depth from which pointers, maps and slices
+// are checked for cycles, like encoding/json; shallower values are not
+// tracked, so the check costs nothing for typical values
+const cycleCheckDepth = 1000
+
+// MarshalBounded encodes value as JSON like json.MarshalIndent with indent,
+// or json.Marshal if indent is empty, within limits. Values are written as
+// they are visited, and writing stops once MaxBytes is reached, so the
+// output is never much larger than the limit; the output of a
+// json.Marshaler that doesn't fit is replaced with a marker, and that of an
+// encoding.TextMarshaler is cut like other strings. Maps are written with
+// sorted keys; when a map has more than MaxElements keys, the smallest
+// are kept without sorting all of them. Cyclic values are an error, as
+// with encoding/json, unless MaxDepth cuts them first.
+func MarshalBounded(value interface{}, indent string, limits PrettyLimits) ([]byte, error) {
+	e := &boundedEncoder{indent: indent, limits: limits}
+	if err := e.encode(reflect.ValueOf(value), 0); err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+// boundedEncoder writes the JSON of MarshalBounded
+type boundedEncoder struct {
+	buf       bytes.Buffer
+	indent    string
+	limits    PrettyLimits
+	truncated bool               // MaxBytes was reached; enclosing containers are closed
+	seen      map[visit]struct{} // Containers on the current path, see cycleCheckDepth
+}
+
+// visit identifies a pointer, map or slice while its contents are encoded
+type visit struct {
+	ptr    uintptr
+	length int
+}
+
+// full reports whether the output reached MaxBytes
+func (e *boundedEncoder) full() bool {
+	if !e.truncated && e.limits.MaxBytes > 0 && e.buf.Len() >= e.limits.MaxBytes {
+		e.truncated = true
+	}
+	return e.truncated
+}
+
+// newline starts a new line indented to depth, in indented output
+func (e *boundedEncoder) newline(depth int) {
+	if e.indent == "" {
+		return
+	}
+	e.buf.WriteByte('\n')
+	for i := 0; i < depth; i++ {
+		e.buf.WriteString(e.indent)
+	}
+}
+
+// string writes s as a JSON string, cut to the remaining MaxBytes
+func (e *boundedEncoder) string(s string) {
+	if e.limits.MaxBytes > 0 {
+		if remaining := e.limits.MaxBytes - e.buf.Len(); len(s) > remaining {
+			s = strings.ToValidUTF8(s[:max(remaining, 0)], "") + elidedMarker
+			e.truncated = true
+		}
+	}
+	data, _ := json.Marshal(s) // Strings always marshal
+	e.buf.Write(data)
+}
+
+// marker writes the marker s of an elided part as a JSON string, regardless
+// of MaxBytes
+func (e *boundedEncoder) marker(s string) {
+	data, _ := json.Marshal(s)
+	e.buf.Write(data)
+}
+
+// raw writes data, JSON encoded by a marshaler, compacted or reindented to
+// depth. Output that would pass MaxBytes is replaced with a marker before
+// it is copied.
+func (e *boundedEncoder) raw(data []byte, depth int) error {
+	if e.limits.MaxBytes > 0 && e.buf.Len()+len(data) > e.limits.MaxBytes {
+		e.marker(elidedMarker + " " + truncatedMarker)
+		e.truncated = true
+		return nil
+	}
+	var escaped bytes.Buffer
+	json.HTMLEscape(&escaped, data) // Like encoding/json
+	if e.indent == "" {
+		return json.Compact(&e.buf, escaped.Bytes())
+	}
+	prefix := ""
+	if depth > 0 {
+		prefix = strings.Repeat(e.indent, depth)
+	}
+	return json.Indent(&e.buf, escaped.Bytes(), prefix, e.indent)
+}
+
+// marshal writes v with its MarshalJSON or MarshalText method
+func (e *boundedEncoder) marshal(v reflect.Value, depth int) error {
+	if marshaler, ok := v.Interface().(json.Marshaler); ok {
+		data, err := marshaler.MarshalJSON()
+		if err == nil {
+			err = e.raw(data, depth)
+		}
+		if err != nil {
+			return fmt.Errorf("json: error calling MarshalJSON for type %s: %w", v.Type(), err)
+		}
+		return nil
+	}
+	text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+	if err != nil {
+		return fmt.Errorf("json: error calling MarshalText for type %s: %w", v.Type(), err)
+	}
+	e.string(string(text))
+	return nil
+}
+
+// quoted writes v, a scalar field tagged with the ",string" option, as a
+// JSON string holding its JSON encoding, like encoding/json
+func (e *boundedEncoder) quoted(v reflect.Value) error {
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return err
+	}
+	e.string(string(data))
+	return nil
+}
+
+// enter records a pointer, map or slice v on the current path from
+// cycleCheckDepth on, and returns the function that removes it again
+func (e *boundedEncoder) enter(v reflect.Value, depth int) (func(), error) {
+	if depth < cycleCheckDepth {
+		return func() {}, nil
+	}
+	key := visit{ptr: v.Pointer()}
+	if v.Kind() == reflect.Slice {
+		key.length = v.Len()
+	}
+	if _, ok := e.seen[key]; ok {
+		return nil, &json.UnsupportedValueError{Value: v, Str: fmt.Sprintf("encountered a cycle via %s", v.Type())}
+	}
+	if e.seen == nil {
+		e.seen = make(map[visit]struct{})
+	}
+	e.seen[key] = struct{}{}
+	return func() { delete(e.seen, key) }, nil
+}
+
+// encode writes v at nesting depth
+func (e *boundedEncoder) encode(v reflect.Value, depth int) error {
+	if !v.IsValid() {
+		e.buf.WriteString("null")
+		return nil
+	}
+
+	if v.CanInterface() && (v.Type().Implements(jsonMarshalerType) || v.Type().Implements(textMarshalerType)) {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		return e.marshal(v, depth)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		if v.Kind() == reflect.Ptr {
+			leave, err := e.enter(v, depth)
+			if err != nil {
+				return err
+			}
+			defer leave()
+		}
+		return e.encode(v.Elem(), depth)
+	case reflect.Bool:
+		e.buf.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.buf.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.buf.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32:
+		data, err := json.Marshal(float32(v.Float()))
+		if err != nil {
+			return err
+		}
+		e.buf.Write(data)
+	case reflect.Float64:
+		data, err := json.Marshal(v.Float())
+		if err != nil {
+			return err
+		}
+		e.buf.Write(data)
+	case reflect.String:
+		e.string(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if e.limits.MaxBytes > 0 && e.buf.Len()+base64.StdEncoding.EncodedLen(v.Len()) > e.limits.MaxBytes {
+				e.marker(elidedMarker + " " + truncatedMarker)
+				e.truncated = true
+				return nil
+			}
+			e.string(base64.StdEncoding.EncodeToString(v.Bytes())) // Like encoding/json
+			return nil
+		}
+		leave, err := e.enter(v, depth)
+		if err != nil {
+			return err
+		}
+		defer leave()
+		return e.array(v, depth)
+	case reflect.Array:
+		return e.array(v, depth)
+	case reflect.Map:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		leave, err := e.enter(v, depth)
+		if err != nil {
+			return err
+		}
+		defer leave()
+		return e.object(e.mapFields(v), depth)
+	case reflect.Struct:
+		return e.object(e.structFields(v), depth)
+	default:
+		e.string(fmt.Sprintf("%v", v)) // Channels, functions and complex numbers
+	}
+	return nil
+}
+
+// array writes the elements of a slice or array v at depth
+func (e *boundedEncoder) array(v reflect.Value, depth int) error {
+	n := v.Len()
+	if n == 0 {
+		e.buf.WriteString("[]")
+		return nil
+	}
+	if e.limits.MaxDepth > 0 && depth >= e.limits.MaxDepth {
+		e.marker(elidedMarker + " " + maxDepthMarker)
+		return nil
+	}
+
+	e.buf.WriteByte('[')
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			e.buf.WriteByte(',')
+		}
+		e.newline(depth + 1)
+		if e.full() {
+			e.marker(elidedMarker + " " + truncatedMarker)
+			break
+		}
+		if e.limits.MaxElements > 0 && i >= e.limits.MaxElements {
+			e.marker(fmt.Sprintf("%s %d more", elidedMarker, n-i))
+			break
+		}
+		if err := e.encode(v.Index(i), depth+1); err != nil {
+			return err
+		}
+	}
+	e.newline(depth)
+	e.buf.WriteByte(']')
+	return nil
+}
+
+// objectField is a key and value of an object
+type objectField struct {
+	key    string
+	value  reflect.Value
+	quoted bool // Tagged with the ",string" option, see quotable
+}
+
+// objectFields are the fields of an object and the number of fields cut
+// at MaxElements
+type objectFields struct {
+	fields  []objectField
+	omitted int
+}
+
+// object writes fields as an object at depth
+func (e *boundedEncoder) object(o objectFields, depth int) error {
+	if len(o.fields) == 0 && o.omitted == 0 {
+		e.buf.WriteString("{}")
+		return nil
+	}
+	if e.limits.MaxDepth > 0 && depth >= e.limits.MaxDepth {
+		e.marker(elidedMarker + " " + maxDepthMarker)
+		return nil
+	}
+
+	colon := ":"
+	if e.indent != "" {
+		colon = ": "
+	}
+	marker := func(value string) {
+		e.marker(elidedMarker)
+		e.buf.WriteString(colon)
+		e.marker(value)
+	}
+
+	e.buf.WriteByte('{')
+	for i, field := range o.fields {
+		if i > 0 {
+			e.buf.WriteByte(',')
+		}
+		e.newline(depth + 1)
+		if e.full() {
+			marker(truncatedMarker)
+			o.omitted = 0
+			break
+		}
+		e.string(field.key)
+		e.buf.WriteString(colon)
+		encode := e.encode
+		if field.quoted {
+			encode = func(v reflect.Value, _ int) error { return e.quoted(v) }
+		}
+		if err := encode(field.value, depth+1); err != nil {
+			return err
+		}
+	}
+	if o.omitted > 0 {
+		if len(o.fields) > 0 {
+			e.buf.WriteByte(',')
+		}
+		e.newline(depth + 1)
+		marker(fmt.Sprintf("%d more", o.omitted))
+	}
+	e.newline(depth)
+	e.buf.WriteByte('}')
+	return nil
+}
+
+// mapFields returns the entries of map v sorted by key. Maps larger than
+// MaxElements keep their smallest keys, selected in one pass with memory
+// for MaxElements entries only.
+func (e *boundedEncoder) mapFields(v reflect.Value) objectFields {
+	limit := v.Len()
+	if e.limits.MaxElements > 0 && limit > e.limits.MaxElements {
+		limit = e.limits.MaxElements
+	}
+
+	fields := make([]objectField, 0, limit)
+	iter := v.MapRange()
+	if limit == v.Len() {
+		for iter.Next() {
+			fields = append(fields, objectField{key: mapKeyString(iter.Key()), value: iter.Value()})
+		}
+		sort.Slice(fields, func(i, j int) bool { return fields[i].key < fields[j].key })
+		return objectFields{fields: fields}
+	}
+	for iter.Next() {
+		field := objectField{key: mapKeyString(iter.Key()), value: iter.Value()}
+		i := sort.Search(len(fields), func(i int) bool { return fields[i].key > field.key })
+		if len(fields) == limit {
+			if i == limit {
+				continue // Larger than every kept key
+			}
+			fields = fields[:limit-1]
+		}
+		fields = append(fields, objectField{})
+		copy(fields[i+1:], fields[i:])
+		fields[i] = field
+	}
+	return objectFields{fields: fields, omitted: v.Len() - len(fields)}
+}
+
+// mapKeyString returns the JSON object key of a map key, like encoding/json
+func mapKeyString(key reflect.Value) string {
+	if key.Kind() == reflect.String {
+		return key.String()
+	}
+	if key.CanInterface() {
+		if marshaler, ok := key.Interface().(encoding.TextMarshaler); ok {
+			if text, err := marshaler.MarshalText(); err == nil {
+				return string(text)
+			}
+		}
+	}
+	return fmt.Sprint(key)
+}
+
+// structFields returns the fields of struct v encoding/json would write:
+// exported fields named by their json tags, skipping "-" and empty
+// omitempty fields, with embedded structs flattened
+func (e *boundedEncoder) structFields(v reflect.Value) objectFields {
+	var o objectFields
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		value := v.Field(i)
+
+		if field.Anonymous && name == "" {
+			embedded := value
+			if embedded.Kind() == reflect.Ptr {
+				if embedded.IsNil() {
+					continue
+				}
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				inner := e.structFields(embedded)
+				o.fields = append(o.fields, inner.fields...)
+				o.omitted += inner.omitted
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if strings.Contains(options, "omitempty") && isEmptyJSONValue(value) {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if e.limits.MaxElements > 0 && len(o.fields) >= e.limits.MaxElements {
+			o.omitted++
+			continue
+		}
+		quoted := quotable(value) && strings.Contains(","+options+",", ",string,")
+		o.fields = append(o.fields, objectField{key: name, value: value, quoted: quoted})
+	}
+	return o
+}
+
+// quotable reports whether the ",string" option applies to v: a bool,
+// number or string, or a non-nil pointer to one
+func quotable(v reflect.Value) bool {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// isEmptyJSONValue reports whether omitempty omits v
+func isEmptyJSONValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
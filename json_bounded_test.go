@@ -0,0 +1,190 @@
+package pim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type boundedBase struct {
+	ID int `json:"id"`
+}
+
+type boundedSample struct {
+	boundedBase
+	Name     string            `json:"name"`
+	Tags     []string          `json:"tags,omitempty"`
+	Labels   map[string]int    `json:"labels"`
+	Created  time.Time         `json:"created"`
+	Payload  []byte            `json:"payload"`
+	Ratio    float64           `json:"ratio"`
+	Skipped  string            `json:"-"`
+	Nested   *boundedSample    `json:"nested,omitempty"`
+	Empty    []int             `json:"empty"`
+	Untagged bool              //
+	Any      interface{}       `json:"any"`
+	Escaped  string            `json:"escaped"`
+	Meta     map[string]string `json:"meta,omitempty"`
+	internal int
+}
+
+func TestMarshalBoundedMatchesEncodingJSON(t *testing.T) {
+	value := boundedSample{
+		boundedBase: boundedBase{ID: 7},
+		Name:        "order",
+		Labels:      map[string]int{"b": 2, "a": 1},
+		Created:     time.Date(2024, 3, 15, 9, 30, 0, 0, time.UTC),
+		Payload:     []byte("hello"),
+		Ratio:       0.000001,
+		Skipped:     "x",
+		Nested:      &boundedSample{Name: "child", Tags: []string{"t"}},
+		Untagged:    true,
+		Any:         []interface{}{1.5, "s", nil, map[string]interface{}{"k": true}},
+		Escaped:     "<a & b>",
+	}
+
+	for _, indent := range []string{"", "  ", "\t"} {
+		got, err := MarshalBounded(value, indent, DefaultPrettyLimits)
+		if err != nil {
+			t.Fatalf("MarshalBounded failed: %v", err)
+		}
+		var want []byte
+		if indent == "" {
+			want, err = json.Marshal(value)
+		} else {
+			want, err = json.MarshalIndent(value, "", indent)
+		}
+		if err != nil {
+			t.Fatalf("encoding/json failed: %v", err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("indent %q: output differs from encoding/json\ngot:  %s\nwant: %s", indent, got, want)
+		}
+	}
+}
+
+func TestMarshalBoundedElements(t *testing.T) {
+	limits := PrettyLimits{MaxElements: 3}
+
+	got, _ := MarshalBounded([]int{1, 2, 3, 4, 5, 6}, "", limits)
+	if string(got) != `[1,2,3,"... 3 more"]` {
+		t.Errorf("unexpected array %s", got)
+	}
+
+	large := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		large[fmt.Sprintf("k%02d", i)] = i
+	}
+	got, _ = MarshalBounded(large, "", limits)
+	if string(got) != `{"k00":0,"k01":1,"k02":2,"...":"97 more"}` {
+		t.Errorf("unexpected object %s", got)
+	}
+
+	got, _ = MarshalBounded([]int{1, 2, 3}, "", limits)
+	if string(got) != `[1,2,3]` {
+		t.Errorf("expected containers at the limit to be complete, got %s", got)
+	}
+}
+
+func TestMarshalBoundedDepth(t *testing.T) {
+	value := map[string]interface{}{"a": map[string]interface{}{"b": []int{1}}}
+	got, _ := MarshalBounded(value, "", PrettyLimits{MaxDepth: 2})
+	if string(got) != `{"a":{"b":"... max depth"}}` {
+		t.Errorf("unexpected output %s", got)
+	}
+}
+
+func TestMarshalBoundedBytes(t *testing.T) {
+	huge := make(map[string]string, 100000)
+	for i := 0; i < 100000; i++ {
+		huge[fmt.Sprintf("key-%06d", i)] = strings.Repeat("v", 100)
+	}
+	limits := PrettyLimits{MaxBytes: 4096}
+
+	for _, indent := range []string{"", "  "} {
+		got, err := MarshalBounded(huge, indent, limits)
+		if err != nil {
+			t.Fatalf("MarshalBounded failed: %v", err)
+		}
+		if len(got) > limits.MaxBytes+256 {
+			t.Errorf("expected about %d bytes, got %d", limits.MaxBytes, len(got))
+		}
+		if !json.Valid(got) {
+			t.Errorf("expected valid JSON, got %s", got)
+		}
+		if !strings.Contains(string(got), `"...": "truncated"`) && !strings.Contains(string(got), `"...":"truncated"`) {
+			t.Errorf("expected a truncation marker, got %s", got[len(got)-64:])
+		}
+	}
+
+	got, _ := MarshalBounded([]string{strings.Repeat("x", 10000)}, "", limits)
+	if len(got) > limits.MaxBytes+64 || !json.Valid(got) {
+		t.Errorf("expected a long string to be cut to valid JSON, got %d bytes", len(got))
+	}
+}
+
+func TestSerializerLimits(t *testing.T) {
+	values := make([]int, 5000)
+	serializer := NewJsonSerializer()
+	data, err := serializer.Marshal(values)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"... 4000 more"`) {
+		t.Errorf("expected the default limits to cut the array")
+	}
+
+	serializer.SetLimits(PrettyLimits{})
+	data, _ = serializer.Marshal(values)
+	if strings.Contains(string(data), "more") {
+		t.Errorf("expected no limits after SetLimits with the zero value")
+	}
+}
+
+type boundedQuoted struct {
+	Count int      `json:"count,string"`
+	Name  string   `json:"name,string"`
+	Ratio *float64 `json:"ratio,omitempty,string"`
+	Tags  []string `json:"tags,string"`
+}
+
+type hugeMarshaler struct{}
+
+func (hugeMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + strings.Repeat("x", 10000) + `"`), nil
+}
+
+type boundedNode struct {
+	Next *boundedNode `json:"next"`
+}
+
+func TestMarshalBoundedStringOption(t *testing.T) {
+	ratio := 0.5
+	value := boundedQuoted{Count: 3, Name: "a<b", Ratio: &ratio, Tags: []string{"t"}}
+	got, err := MarshalBounded(value, "", DefaultPrettyLimits)
+	want, _ := json.Marshal(value)
+	if err != nil || string(got) != string(want) {
+		t.Errorf("expected %s, got %s (%v)", want, got, err)
+	}
+}
+
+func TestMarshalBoundedMarshalerSize(t *testing.T) {
+	got, err := MarshalBounded([]interface{}{hugeMarshaler{}}, "", PrettyLimits{MaxBytes: 100})
+	if err != nil || string(got) != `["... truncated"]` {
+		t.Errorf("expected the marshaler output elided, got %s (%v)", got, err)
+	}
+}
+
+func TestMarshalBoundedCycle(t *testing.T) {
+	node := &boundedNode{}
+	node.Next = node
+	if _, err := MarshalBounded(node, "", PrettyLimits{}); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected a cycle error, got %v", err)
+	}
+	got, err := MarshalBounded(node, "", PrettyLimits{MaxDepth: 2})
+	if err != nil || string(got) != `{"next":{"next":"... max depth"}}` {
+		t.Errorf("expected MaxDepth to cut the cycle, got %s (%v)", got, err)
+	}
+}
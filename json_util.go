@@ -12,6 +12,9 @@ type JsonOptions struct {
 	Colored bool // Whether to use colors in the output
 	Indent  int  // Number of spaces for indentation
 	Raw     bool // Whether to print raw (unformatted) JSON
+
+	// Limits bounds the output for huge values (zero value: unlimited)
+	Limits PrettyLimits
 }
 
 // DefaultJsonOptions provides default formatting options
@@ -19,6 +22,7 @@ var DefaultJsonOptions = JsonOptions{
 	Colored: true,
 	Indent:  2,
 	Raw:     false,
+	Limits:  DefaultPrettyLimits,
 }
 
 // Json prints any value as beautifully formatted JSON
@@ -32,7 +36,13 @@ func Json(value interface{}, opts ...JsonOptions) error {
 	var jsonData []byte
 	var err error
 
-	if options.Raw {
+	if options.Limits.enabled() {
+		indent := ""
+		if !options.Raw && options.Indent > 0 {
+			indent = strings.Repeat(" ", options.Indent)
+		}
+		jsonData, err = MarshalBounded(value, indent, options.Limits)
+	} else if options.Raw {
 		jsonData, err = json.Marshal(value)
 	} else if options.Indent > 0 {
 		jsonData, err = json.MarshalIndent(value, "", strings.Repeat(" ", options.Indent))
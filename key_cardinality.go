@@ -0,0 +1,202 @@
+package pim
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// DroppedKeysKey is the context key listing keys removed by the cardinality guard
+const DroppedKeysKey = "dropped_keys"
+
+// KeyLimitAction is what the cardinality guard does with keys beyond the cap
+type KeyLimitAction string
+
+const (
+	// KeyLimitWarn keeps new keys and reports that the cap was exceeded
+	KeyLimitWarn KeyLimitAction = "warn"
+	// KeyLimitDrop removes new keys from the entry and lists them under DroppedKeysKey
+	KeyLimitDrop KeyLimitAction = "drop"
+)
+
+// KeyCardinalityConfig holds configuration for context key cardinality guards
+type KeyCardinalityConfig struct {
+	HookConfig
+	MaxKeys     int              `json:"max_keys"`     // Distinct keys allowed before the action applies (default: 1000)
+	Action      KeyLimitAction   `json:"action"`       // Default: KeyLimitWarn
+	AllowedKeys []string         `json:"allowed_keys"` // Keys always accepted without counting against the cap
+	OnLimit     func(key string) `json:"-"`            // Called for each key beyond the cap; defaults to one stderr warning
+}
+
+// KeyCardinalityHook tracks the distinct context keys seen and stops dynamic
+// key names (e.g. "user_12345": true) from causing mapping explosions in
+// indexed stores downstream. Keys seen before the cap was reached are always
+// accepted; once it is reached, new keys are reported or dropped. Give it a
+// high priority so it sees the keys added by other hooks.
+type KeyCardinalityHook struct {
+	config  KeyCardinalityConfig
+	allowed map[string]bool
+
+	mu       sync.Mutex
+	seen     map[string]struct{}
+	overflow int
+	warned   bool
+}
+
+// NewKeyCardinalityHook creates a new key cardinality guard
+func NewKeyCardinalityHook(config KeyCardinalityConfig) *KeyCardinalityHook {
+	if config.MaxKeys <= 0 {
+		config.MaxKeys = 1000
+	}
+	if config.Action == "" {
+		config.Action = KeyLimitWarn
+	}
+
+	allowed := make(map[string]bool, len(config.AllowedKeys)+1)
+	for _, key := range config.AllowedKeys {
+		allowed[key] = true
+	}
+	allowed[DroppedKeysKey] = true
+
+	return &KeyCardinalityHook{
+		config:  config,
+		allowed: allowed,
+		seen:    make(map[string]struct{}),
+	}
+}
+
+// Process implements LogHook interface
+func (h *KeyCardinalityHook) Process(entry CoreLogEntry) (CoreLogEntry, error) {
+	if !h.config.Enabled || len(entry.Context) == 0 {
+		return entry, nil
+	}
+
+	var rejected []string
+	h.mu.Lock()
+	for key := range entry.Context {
+		if h.allowed[key] {
+			continue
+		}
+		if _, ok := h.seen[key]; ok {
+			continue
+		}
+		if len(h.seen) < h.config.MaxKeys {
+			h.seen[key] = struct{}{}
+			continue
+		}
+		h.overflow++
+		rejected = append(rejected, key)
+	}
+	warn := len(rejected) > 0 && !h.warned && h.config.OnLimit == nil
+	if warn {
+		h.warned = true
+	}
+	h.mu.Unlock()
+
+	if len(rejected) == 0 {
+		return entry, nil
+	}
+	sort.Strings(rejected)
+
+	if h.config.OnLimit != nil {
+		for _, key := range rejected {
+			h.config.OnLimit(key)
+		}
+	} else if warn {
+		outcome := "kept"
+		if h.config.Action == KeyLimitDrop {
+			outcome = "dropped"
+		}
+		fmt.Fprintf(os.Stderr, "Context key limit of %d reached; new keys such as %q are %s\n",
+			h.config.MaxKeys, rejected[0], outcome)
+	}
+
+	if h.config.Action != KeyLimitDrop {
+		return entry, nil
+	}
+
+	// Copy so the caller's context map is left intact
+	context := make(map[string]interface{}, len(entry.Context))
+	for k, v := range entry.Context {
+		context[k] = v
+	}
+	for _, key := range rejected {
+		delete(context, key)
+	}
+	context[DroppedKeysKey] = rejected
+	entry.Context = context
+	return entry, nil
+}
+
+// KeyCount returns the number of distinct keys counted against the cap
+func (h *KeyCardinalityHook) KeyCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.seen)
+}
+
+// Overflow returns how many times a key beyond the cap was seen
+func (h *KeyCardinalityHook) Overflow() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.overflow
+}
+
+// Reset forgets the keys seen so far
+func (h *KeyCardinalityHook) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seen = make(map[string]struct{})
+	h.overflow = 0
+	h.warned = false
+}
+
+// GetConfig implements EnhancedLogHook interface
+func (h *KeyCardinalityHook) GetConfig() HookConfig {
+	return h.config.HookConfig
+}
+
+// GetType implements EnhancedLogHook interface
+func (h *KeyCardinalityHook) GetType() HookType {
+	return h.config.Type
+}
+
+// IsEnabled implements EnhancedLogHook interface
+func (h *KeyCardinalityHook) IsEnabled() bool {
+	return h.config.Enabled
+}
+
+// SetEnabled implements EnhancedLogHook interface
+func (h *KeyCardinalityHook) SetEnabled(enabled bool) {
+	h.config.Enabled = enabled
+}
+
+// GetPriority implements EnhancedLogHook interface
+func (h *KeyCardinalityHook) GetPriority() int {
+	return h.config.Priority
+}
+
+// SetPriority implements EnhancedLogHook interface
+func (h *KeyCardinalityHook) SetPriority(priority int) {
+	h.config.Priority = priority
+}
+
+// AddKeyCardinalityHook adds a guard allowing at most maxKeys distinct
+// context keys. It runs after default-priority hooks so keys they add are
+// counted too.
+func (l *LoggerCore) AddKeyCardinalityHook(maxKeys int, action KeyLimitAction) *KeyCardinalityHook {
+	hook := NewKeyCardinalityHook(KeyCardinalityConfig{
+		HookConfig: HookConfig{
+			Type:        HookTypeTransform,
+			Name:        "key_cardinality",
+			Description: "Limits the number of distinct context keys",
+			Enabled:     true,
+			Priority:    100,
+		},
+		MaxKeys: maxKeys,
+		Action:  action,
+	})
+	l.AddEnhancedHook(hook)
+	return hook
+}
@@ -0,0 +1,91 @@
+package pim
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newKeyCardinalityTestHook(action KeyLimitAction, onLimit func(string)) *KeyCardinalityHook {
+	return NewKeyCardinalityHook(KeyCardinalityConfig{
+		HookConfig:  HookConfig{Type: HookTypeTransform, Name: "keys", Enabled: true},
+		MaxKeys:     2,
+		Action:      action,
+		AllowedKeys: []string{"request_id"},
+		OnLimit:     onLimit,
+	})
+}
+
+func TestKeyCardinalityHookDrop(t *testing.T) {
+	var reported []string
+	hook := newKeyCardinalityTestHook(KeyLimitDrop, func(key string) { reported = append(reported, key) })
+
+	hook.Process(CoreLogEntry{Context: map[string]interface{}{"a": 1, "b": 2}})
+
+	original := map[string]interface{}{"a": 1, "user_1": true, "user_2": true, "request_id": "r"}
+	entry, err := hook.Process(CoreLogEntry{Context: original})
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	if _, ok := entry.Context["user_1"]; ok {
+		t.Error("Expected key beyond the cap to be dropped")
+	}
+	if entry.Context["a"] != 1 || entry.Context["request_id"] != "r" {
+		t.Errorf("Expected known and allowed keys to be kept, got %v", entry.Context)
+	}
+	if dropped := entry.Context[DroppedKeysKey]; !reflect.DeepEqual(dropped, []string{"user_1", "user_2"}) {
+		t.Errorf("Expected dropped keys to be listed, got %v", dropped)
+	}
+	if len(original) != 4 {
+		t.Error("Expected caller's context map to be left intact")
+	}
+	if !reflect.DeepEqual(reported, []string{"user_1", "user_2"}) {
+		t.Errorf("Expected OnLimit for each rejected key, got %v", reported)
+	}
+	if hook.KeyCount() != 2 || hook.Overflow() != 2 {
+		t.Errorf("Unexpected stats: %d keys, %d overflow", hook.KeyCount(), hook.Overflow())
+	}
+}
+
+func TestKeyCardinalityHookWarn(t *testing.T) {
+	var reported []string
+	hook := newKeyCardinalityTestHook(KeyLimitWarn, func(key string) { reported = append(reported, key) })
+
+	hook.Process(CoreLogEntry{Context: map[string]interface{}{"a": 1, "b": 2}})
+	entry, _ := hook.Process(CoreLogEntry{Context: map[string]interface{}{"c": 3}})
+
+	if entry.Context["c"] != 3 {
+		t.Error("Expected warn action to keep new keys")
+	}
+	if _, ok := entry.Context[DroppedKeysKey]; ok {
+		t.Error("Expected no dropped keys in warn mode")
+	}
+	if !reflect.DeepEqual(reported, []string{"c"}) {
+		t.Errorf("Expected new key to be reported, got %v", reported)
+	}
+
+	hook.Reset()
+	hook.Process(CoreLogEntry{Context: map[string]interface{}{"c": 3}})
+	if hook.KeyCount() != 1 || hook.Overflow() != 0 {
+		t.Errorf("Expected Reset to clear counts, got %d keys, %d overflow", hook.KeyCount(), hook.Overflow())
+	}
+}
+
+func TestAddKeyCardinalityHook(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+
+	hook := logger.AddKeyCardinalityHook(1, KeyLimitDrop)
+	logger.Info("first", Fields(map[string]interface{}{"known": 1}))
+	logger.Info("second", Fields(map[string]interface{}{"known": 2, "session_98765": true}))
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if _, ok := entries[1].Context["session_98765"]; ok || entries[1].Context["known"] != 2 {
+		t.Errorf("Expected dynamic key to be dropped, got %v", entries[1].Context)
+	}
+	if hook.Overflow() != 1 {
+		t.Errorf("Expected 1 overflow, got %d", hook.Overflow())
+	}
+}
@@ -0,0 +1,80 @@
+package pim
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/refactorroom/pim/core"
+)
+
+// LazyValue is a field value computed only for entries that are written,
+// see Lazy
+type LazyValue func() interface{}
+
+// Lazy wraps fn as a field value that is computed only if the entry passes
+// the level check, sampling and filter hooks, e.g.
+//
+//	logger.Debug("cache state", pim.Fields(map[string]interface{}{"stats": pim.Lazy(cache.Stats)}))
+//
+// A lazy value is computed once per entry: before the first hook that is
+// not a filter hook, or before the entry is written. Filter hooks see the
+// LazyValue and can compute it with CoreLogEntry.Resolve. Lazy values in
+// a logger's context, e.g. WithField("goroutines", Lazy(...)), are computed
+// again for each entry, and lazy format arguments when the message is
+// formatted.
+func Lazy(fn func() interface{}) LazyValue {
+	return LazyValue(fn)
+}
+
+// LazyField creates a typed field whose value is computed lazily
+func LazyField(key string, fn func() interface{}) Field {
+	return core.Any(key, Lazy(fn))
+}
+
+// String computes the value for entries formatted before it was resolved
+func (v LazyValue) String() string {
+	return fmt.Sprint(v())
+}
+
+// MarshalJSON computes the value for entries encoded before it was resolved
+func (v LazyValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v())
+}
+
+// Resolve returns the context value of key, computing and storing it in
+// the entry's context first if it is lazy
+func (e CoreLogEntry) Resolve(key string) (interface{}, bool) {
+	value, ok := e.Context[key]
+	if lazy, isLazy := value.(LazyValue); isLazy {
+		value = normalizeFieldValue(lazy())
+		e.Context[key] = value
+	}
+	return value, ok
+}
+
+// resolveLazy computes the lazy values of the entry's context
+func (e *CoreLogEntry) resolveLazy() {
+	for k, v := range e.Context {
+		if lazy, ok := v.(LazyValue); ok {
+			e.Context[k] = normalizeFieldValue(lazy())
+		}
+	}
+}
+
+// resolveLazyArgs returns args with lazy values computed
+func resolveLazyArgs(args []interface{}) []interface{} {
+	for i, arg := range args {
+		if _, ok := arg.(LazyValue); !ok {
+			continue
+		}
+		resolved := make([]interface{}, len(args))
+		copy(resolved, args)
+		for j := i; j < len(args); j++ {
+			if lazy, ok := args[j].(LazyValue); ok {
+				resolved[j] = lazy()
+			}
+		}
+		return resolved
+	}
+	return args
+}
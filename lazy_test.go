@@ -0,0 +1,105 @@
+package pim
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// lazyCounter returns a lazy value counting its evaluations
+func lazyCounter(calls *int, value interface{}) LazyValue {
+	return Lazy(func() interface{} {
+		*calls++
+		return value
+	})
+}
+
+func TestLazyFieldsSkippedBelowLevel(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+
+	calls := 0
+	logger.Debug("state", Fields(map[string]interface{}{"stats": lazyCounter(&calls, 1)}))
+	logger.Debug("count {n}", lazyCounter(&calls, 2))
+	logger.DebugFields("state", LazyField("stats", func() interface{} { calls++; return 3 }))
+	if calls != 0 {
+		t.Errorf("Expected no evaluation below the level, got %d", calls)
+	}
+
+	logger.Info("state", Fields(map[string]interface{}{"stats": lazyCounter(&calls, 1)}))
+	logger.Info("count {n}", lazyCounter(&calls, 2))
+	logger.InfoFields("state", LazyField("stats", func() interface{} { calls++; return 3 }))
+	if calls != 3 {
+		t.Errorf("Expected one evaluation per written field, got %d", calls)
+	}
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 3 || entries[0].Context["stats"] != 1 || entries[1].Message != "count 2" || entries[2].Context["stats"] != 3 {
+		t.Errorf("Expected resolved values, got %+v", entries)
+	}
+}
+
+func TestLazyFieldsSkippedWhenFiltered(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+
+	enriched := 0
+	logger.AddEnhancedHook(NewFilterHook(FilterConfig{
+		HookConfig: HookConfig{Name: "noise", Type: HookTypeFilter, Enabled: true, Priority: 1},
+		CustomFunc: func(entry CoreLogEntry) bool { return entry.Message == "noise" },
+	}))
+	logger.AddEnhancedHook(NewEnrichHook(EnrichConfig{
+		HookConfig: HookConfig{Name: "check", Type: HookTypeEnrich, Enabled: true, Priority: 2},
+		DynamicFunc: func(entry CoreLogEntry) map[string]interface{} {
+			if _, ok := entry.Context["payload"].(LazyValue); !ok {
+				enriched++
+			}
+			return nil
+		},
+	}))
+
+	calls := 0
+	logger.Info("noise", Fields(map[string]interface{}{"payload": lazyCounter(&calls, "big")}))
+	if calls != 0 {
+		t.Errorf("Expected no evaluation for filtered entries, got %d", calls)
+	}
+
+	logger.Info("signal", Fields(map[string]interface{}{"payload": lazyCounter(&calls, "big")}))
+	if calls != 1 || enriched != 1 {
+		t.Errorf("Expected one evaluation before the enrich hook, got %d calls and %d resolved", calls, enriched)
+	}
+	if entries := buffer.GetBuffer(); len(entries) != 1 || entries[0].Context["payload"] != "big" {
+		t.Errorf("Expected the resolved payload, got %+v", entries)
+	}
+}
+
+func TestLazyLoggerContext(t *testing.T) {
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.PropagateContext = true
+	})
+
+	calls := 0
+	counted := logger.WithField("calls", Lazy(func() interface{} { calls++; return calls }))
+	counted.Info("a")
+	counted.Info("b")
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 2 || entries[0].Context["calls"] != 1 || entries[1].Context["calls"] != 2 {
+		t.Errorf("Expected the value computed per entry, got %+v", entries)
+	}
+}
+
+func TestCoreLogEntryResolve(t *testing.T) {
+	calls := 0
+	entry := CoreLogEntry{Context: map[string]interface{}{"n": lazyCounter(&calls, 5)}}
+	for i := 0; i < 2; i++ {
+		if value, ok := entry.Resolve("n"); !ok || value != 5 {
+			t.Errorf("Expected 5, got %v", value)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected the value to be stored after the first call, got %d calls", calls)
+	}
+
+	data, err := json.Marshal(map[string]interface{}{"n": Lazy(func() interface{} { return []int{1} })})
+	if err != nil || string(data) != `{"n":[1]}` {
+		t.Errorf("Expected unresolved values to marshal, got %s, %v", data, err)
+	}
+}
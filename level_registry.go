@@ -0,0 +1,175 @@
+package pim
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// levelRegistry holds minimum levels that override a logger's level for
+// entries logged from given packages or by loggers with a given name
+type levelRegistry struct {
+	mu       sync.RWMutex
+	packages map[string]LogLevel
+	loggers  map[string]LogLevel
+	cache    map[uintptr]levelOverride // Resolved package override by caller PC
+	active   atomic.Bool               // Whether any override is set
+}
+
+// levelOverride is a cached package level lookup
+type levelOverride struct {
+	level LogLevel
+	ok    bool
+}
+
+// levels is the process-wide level registry
+var levels = &levelRegistry{
+	packages: make(map[string]LogLevel),
+	loggers:  make(map[string]LogLevel),
+	cache:    make(map[uintptr]levelOverride),
+}
+
+// SetPackageLevel sets the minimum level for entries logged from pkg and
+// its subpackages, e.g. SetPackageLevel("github.com/acme/db", DebugLevel)
+// while loggers stay at Info. The most specific package wins, and package
+// levels take precedence over logger levels.
+func SetPackageLevel(pkg string, level LogLevel) {
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	levels.packages[strings.TrimSuffix(pkg, "/")] = level
+	levels.changed()
+}
+
+// ClearPackageLevel removes the level set for pkg
+func ClearPackageLevel(pkg string) {
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	delete(levels.packages, strings.TrimSuffix(pkg, "/"))
+	levels.changed()
+}
+
+// SetLoggerLevel sets the minimum level for loggers whose service name is
+// name, or for Named loggers called name and their descendants
+func SetLoggerLevel(name string, level LogLevel) {
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	levels.loggers[name] = level
+	levels.changed()
+}
+
+// ClearLoggerLevel removes the level set for loggers named name
+func ClearLoggerLevel(name string) {
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	delete(levels.loggers, name)
+	levels.changed()
+}
+
+// ResetLevels removes all package and logger levels
+func ResetLevels() {
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	levels.packages = make(map[string]LogLevel)
+	levels.loggers = make(map[string]LogLevel)
+	levels.changed()
+}
+
+// PackageLevels returns a copy of the package levels currently set
+func PackageLevels() map[string]LogLevel {
+	levels.mu.RLock()
+	defer levels.mu.RUnlock()
+	result := make(map[string]LogLevel, len(levels.packages))
+	for pkg, level := range levels.packages {
+		result[pkg] = level
+	}
+	return result
+}
+
+// changed drops cached lookups; the caller holds the lock
+func (r *levelRegistry) changed() {
+	r.cache = make(map[uintptr]levelOverride)
+	r.active.Store(len(r.packages) > 0 || len(r.loggers) > 0)
+}
+
+// packageLevel returns the level of the most specific registered package
+// containing pkg
+func (r *levelRegistry) packageLevel(pkg string) (LogLevel, bool) {
+	var best LogLevel
+	bestLen := -1
+	for prefix, level := range r.packages {
+		if len(prefix) > bestLen && (pkg == prefix || strings.HasPrefix(pkg, prefix+"/")) {
+			best, bestLen = level, len(prefix)
+		}
+	}
+	return best, bestLen >= 0
+}
+
+// callerLevel resolves the package override for the function skip frames
+// above the caller of callerLevel, caching the result by program counter
+func (r *levelRegistry) callerLevel(skip int) (LogLevel, bool) {
+	r.mu.RLock()
+	empty := len(r.packages) == 0
+	r.mu.RUnlock()
+	if empty {
+		return 0, false
+	}
+
+	var pcs [1]uintptr
+	if runtime.Callers(skip+2, pcs[:]) == 0 {
+		return 0, false
+	}
+	pc := pcs[0]
+
+	r.mu.RLock()
+	cached, hit := r.cache[pc]
+	r.mu.RUnlock()
+	if hit {
+		return cached.level, cached.ok
+	}
+
+	frame, _ := runtime.CallersFrames(pcs[:]).Next()
+	pkg := packagePath(frame.Function)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	level, ok := r.packageLevel(pkg)
+	r.cache[pc] = levelOverride{level: level, ok: ok}
+	return level, ok
+}
+
+// effectiveLevel returns the minimum level for an entry logged by l from
+// the function skip frames above the caller of effectiveLevel
+func (l *LoggerCore) effectiveLevel(skip int) LogLevel {
+	if !levels.active.Load() {
+		return l.GetLevel()
+	}
+	if level, ok := levels.callerLevel(skip + 1); ok {
+		return level
+	}
+
+	if level, ok := levels.loggerLevel(l.name, l.serviceName); ok {
+		return level
+	}
+	return l.GetLevel()
+}
+
+// loggerLevel returns the level set for the nearest named ancestor of a
+// logger, e.g. "http.server" then "http", falling back to its service name
+func (r *levelRegistry) loggerLevel(name, serviceName string) (LogLevel, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for name != "" {
+		if level, ok := r.loggers[name]; ok {
+			return level, true
+		}
+		dot := strings.LastIndex(name, ".")
+		if dot < 0 {
+			break
+		}
+		name = name[:dot]
+	}
+	level, ok := r.loggers[serviceName]
+	return level, ok
+}
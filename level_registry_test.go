@@ -0,0 +1,97 @@
+package pim
+
+import "testing"
+
+// pimPackage is the import path of this package, as seen by the registry
+const pimPackage = "github.com/refactorroom/pim"
+
+func TestSetPackageLevel(t *testing.T) {
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.ServiceName = "api"
+	})
+
+	logger.Debug("hidden")
+	SetPackageLevel(pimPackage, DebugLevel)
+	logger.Debug("visible")
+	SetPackageLevel("github.com/acme/db", TraceLevel)
+	logger.Trace("still hidden")
+	ClearPackageLevel(pimPackage)
+	logger.Debug("hidden again")
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 1 || entries[0].Message != "visible" {
+		t.Fatalf("Expected only the entry logged while the package level was set, got %+v", entries)
+	}
+}
+
+func TestPackageLevelAppliesToEveryEntryPoint(t *testing.T) {
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.ServiceName = "api"
+	})
+	SetPackageLevel(pimPackage, DebugLevel)
+
+	logger.Log(DebugLevel, DebugPrefix, "log")
+	logger.LogWithContext(DebugLevel, DebugPrefix, "log with context", map[string]interface{}{"key": "value"})
+	logger.LogWithStackTrace(DebugLevel, DebugPrefix, "log with stack trace")
+	logger.LogFields(DebugLevel, DebugPrefix, "log fields", String("key", "value"))
+	logger.LogAt(DebugLevel, "log at")
+	logger.Debug("debug")
+
+	var messages []string
+	for _, entry := range buffer.GetBuffer() {
+		messages = append(messages, entry.Message)
+	}
+	if len(messages) != 6 {
+		t.Fatalf("Expected every entry point to honor the package level, got %v", messages)
+	}
+}
+
+func TestPackageLevelOverridesLoggerLevel(t *testing.T) {
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.ServiceName = "worker"
+	})
+
+	SetLoggerLevel("worker", ErrorLevel)
+	logger.Info("dropped by logger level")
+	SetPackageLevel(pimPackage, InfoLevel)
+	logger.Info("kept by package level")
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 1 || entries[0].Message != "kept by package level" {
+		t.Fatalf("Unexpected entries: %+v", entries)
+	}
+}
+
+func TestPackageLevelMostSpecificWins(t *testing.T) {
+	defer ResetLevels()
+	SetPackageLevel("github.com/acme", WarningLevel)
+	SetPackageLevel("github.com/acme/db/", TraceLevel)
+
+	tests := map[string]LogLevel{
+		"github.com/acme/api":         WarningLevel,
+		"github.com/acme/db":          TraceLevel,
+		"github.com/acme/db/postgres": TraceLevel,
+	}
+	for pkg, expected := range tests {
+		if level, ok := levels.packageLevel(pkg); !ok || level != expected {
+			t.Errorf("packageLevel(%q) = %v, %v; expected %v", pkg, level, ok, expected)
+		}
+	}
+	if _, ok := levels.packageLevel("github.com/acmecorp/api"); ok {
+		t.Error("Expected prefix match to respect path boundaries")
+	}
+}
+
+func TestPackagePath(t *testing.T) {
+	tests := map[string]string{
+		"github.com/acme/db.(*Conn).Query": "github.com/acme/db",
+		"github.com/acme/db.Open.func1":    "github.com/acme/db",
+		"main.main":                        "main",
+		"net/http.(*Server).Serve":         "net/http",
+	}
+	for name, expected := range tests {
+		if got := packagePath(name); got != expected {
+			t.Errorf("packagePath(%q) = %q, expected %q", name, got, expected)
+		}
+	}
+}
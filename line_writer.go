@@ -0,0 +1,76 @@
+package pim
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxLineLength is the length at which LineWriter logs an unterminated line
+const maxLineLength = 64 << 10
+
+// LineWriter is an io.Writer that logs each line written to it as an entry,
+// so output of libraries and tools that write to an io.Writer ends up in
+// the logger. Blank lines are skipped and a trailing carriage return is
+// removed. Lines longer than 64 KiB are split.
+type LineWriter struct {
+	logger *LoggerCore
+	level  LogLevel
+	prefix string
+	fields map[string]interface{}
+	mu     sync.Mutex
+	buf    []byte
+}
+
+// NewLineWriter returns a LineWriter logging at level with fields on every
+// entry. Call Close to log a final line without a newline.
+func NewLineWriter(logger *LoggerCore, level LogLevel, fields map[string]interface{}) *LineWriter {
+	return &LineWriter{logger: logger, level: level, prefix: getPrefixForLevel(level), fields: fields}
+}
+
+// Write implements io.Writer
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.buf = append(w.buf, p...)
+			if len(w.buf) >= maxLineLength {
+				w.logLine(w.buf)
+				w.buf = w.buf[:0]
+			}
+			break
+		}
+		if len(w.buf) > 0 {
+			w.buf = append(w.buf, p[:i]...)
+			w.logLine(w.buf)
+			w.buf = w.buf[:0]
+		} else {
+			w.logLine(p[:i])
+		}
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// Close logs the buffered line, if any
+func (w *LineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.logLine(w.buf)
+		w.buf = w.buf[:0]
+	}
+	return nil
+}
+
+// logLine logs one line; the caller holds w.mu
+func (w *LineWriter) logLine(line []byte) {
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	if len(bytes.TrimSpace(line)) == 0 {
+		return
+	}
+	w.logger.LogWithContext(w.level, w.prefix, string(line), w.fields)
+}
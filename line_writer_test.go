@@ -0,0 +1,41 @@
+package pim
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLineWriter(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+
+	writer := NewLineWriter(logger, WarningLevel, map[string]interface{}{"source": "tool"})
+	writer.Write([]byte("first line\r\nsec"))
+	writer.Write([]byte("ond line\n\n  \nunterminated"))
+
+	if size := buffer.GetBufferSize(); size != 2 {
+		t.Fatalf("Expected 2 entries before Close, got %d", size)
+	}
+	writer.Close()
+
+	var messages []string
+	for _, entry := range buffer.GetBuffer() {
+		messages = append(messages, entry.Message)
+		if entry.Level != WarningLevel || entry.Context["source"] != "tool" {
+			t.Errorf("Unexpected entry: %+v", entry)
+		}
+	}
+	if want := []string{"first line", "second line", "unterminated"}; !reflect.DeepEqual(messages, want) {
+		t.Errorf("Expected %q, got %q", want, messages)
+	}
+}
+
+func TestLineWriterSplitsLongLines(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+
+	writer := NewLineWriter(logger, InfoLevel, nil)
+	writer.Write([]byte(strings.Repeat("x", maxLineLength+10)))
+	if size := buffer.GetBufferSize(); size != 1 {
+		t.Errorf("Expected the long line to be logged without a newline, got %d entries", size)
+	}
+}
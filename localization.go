@@ -113,19 +113,24 @@ func (lm *LocalizationManager) GetCurrentLocale() Locale {
 
 // updateFallbackChain updates the fallback chain for the current locale
 func (lm *LocalizationManager) updateFallbackChain() {
-	chain := []Locale{lm.currentLocale}
+	lm.fallbackChain = lm.chainFor(lm.currentLocale)
+}
+
+// chainFor returns the fallback chain of locale
+func (lm *LocalizationManager) chainFor(locale Locale) []Locale {
+	chain := []Locale{locale}
 
 	// Add language-only fallback if we have a region
-	if lm.currentLocale.Region != "" {
-		chain = append(chain, Locale{Language: lm.currentLocale.Language})
+	if locale.Region != "" {
+		chain = append(chain, Locale{Language: locale.Language})
 	}
 
 	// Add default locale as final fallback
-	if lm.currentLocale.String() != lm.defaultLocale.String() {
+	if locale.String() != lm.defaultLocale.String() {
 		chain = append(chain, lm.defaultLocale)
 	}
 
-	lm.fallbackChain = chain
+	return chain
 }
 
 // AddCatalog adds a message catalog for a locale
@@ -144,22 +149,35 @@ func (lm *LocalizationManager) GetCatalog(locale Locale) *MessageCatalog {
 
 // Translate translates a message key to the current locale
 func (lm *LocalizationManager) Translate(key string, args ...interface{}) string {
+	message, _, _ := lm.translate(key, args...)
+	return message
+}
+
+// translate translates a message key to the current locale and returns the
+// locale of the catalog used and whether the key was found
+func (lm *LocalizationManager) translate(key string, args ...interface{}) (string, Locale, bool) {
 	lm.mu.RLock()
 	fallbackChain := make([]Locale, len(lm.fallbackChain))
 	copy(fallbackChain, lm.fallbackChain)
 	lm.mu.RUnlock()
 
+	return lm.translateIn(fallbackChain, key, args...)
+}
+
+// translateIn translates a message key with the first catalog of the
+// fallback chain that has it
+func (lm *LocalizationManager) translateIn(fallbackChain []Locale, key string, args ...interface{}) (string, Locale, bool) {
 	// Try each locale in the fallback chain
 	for _, locale := range fallbackChain {
 		if catalog := lm.GetCatalog(locale); catalog != nil {
 			if message, exists := catalog.GetMessage(key); exists {
-				return lm.formatMessage(message, args...)
+				return lm.formatMessage(message, args...), locale, true
 			}
 		}
 	}
 
 	// Return the key if no translation found
-	return key
+	return key, Locale{}, false
 }
 
 // formatMessage formats a message with arguments
@@ -377,64 +395,86 @@ func (l *LocalizedLogger) GetLocale() Locale {
 	return l.localization.GetCurrentLocale()
 }
 
-// T translates and logs a message
+// T translates and logs a message. The key and arguments are recorded in
+// the entry's Localized field, so it can be re-rendered in another language
+// later, see LocalizationManager.Rerender.
 func (l *LocalizedLogger) T(level LogLevel, key string, args ...interface{}) {
-	message := l.localization.Translate(key, args...)
-	l.Log(level, getPrefixForLevel(level), message)
+	l.logTranslated(1, level, getPrefixForLevel(level), key, nil, args)
+}
+
+// logTranslated translates key and logs the message with context for the
+// call site skip frames above its caller, see LoggerCore.log. It returns
+// the translated message.
+func (l *LocalizedLogger) logTranslated(skip int, level LogLevel, prefix, key string, context map[string]interface{}, args []interface{}, opts ...interface{}) string {
+	message, localized := l.translate(key, args)
+	l.logWithContext(skip+1, level, prefix, message, context, append(opts, withLocalizedMessage(localized)))
+	return message
+}
+
+// translate translates key and records how the message was rendered
+func (l *LocalizedLogger) translate(key string, args []interface{}) (string, *LocalizedMessage) {
+	message, locale, found := l.localization.translate(key, args...)
+	used := ""
+	if found {
+		used = locale.String()
+	}
+	return message, newLocalizedMessage(key, args, used)
 }
 
 // TInfo translates and logs an info message
 func (l *LocalizedLogger) TInfo(key string, args ...interface{}) {
-	l.T(InfoLevel, key, args...)
+	l.logTranslated(1, InfoLevel, InfoPrefix, key, nil, args)
 }
 
 // TSuccess translates and logs a success message
 func (l *LocalizedLogger) TSuccess(key string, args ...interface{}) {
-	message := l.localization.Translate(key, args...)
-	l.Log(InfoLevel, SuccessPrefix, message)
+	l.logTranslated(1, InfoLevel, SuccessPrefix, key, nil, args)
 }
 
 // TWarning translates and logs a warning message
 func (l *LocalizedLogger) TWarning(key string, args ...interface{}) {
-	l.T(WarningLevel, key, args...)
+	l.logTranslated(1, WarningLevel, WarningPrefix, key, nil, args)
 }
 
 // TError translates and logs an error message
 func (l *LocalizedLogger) TError(key string, args ...interface{}) {
-	l.T(ErrorLevel, key, args...)
+	l.logTranslated(1, ErrorLevel, ErrorPrefix, key, nil, args)
 }
 
 // TDebug translates and logs a debug message
 func (l *LocalizedLogger) TDebug(key string, args ...interface{}) {
-	l.T(DebugLevel, key, args...)
+	l.logTranslated(1, DebugLevel, DebugPrefix, key, nil, args)
 }
 
 // TTrace translates and logs a trace message
 func (l *LocalizedLogger) TTrace(key string, args ...interface{}) {
-	l.T(TraceLevel, key, args...)
+	l.logTranslated(1, TraceLevel, TracePrefix, key, nil, args)
 }
 
-// TPanic translates and logs a panic message
+// TPanic translates and logs a panic message, then panics with a
+// *PanicError like LoggerCore.Panic
 func (l *LocalizedLogger) TPanic(key string, args ...interface{}) {
-	message := l.localization.Translate(key, args...)
-	l.Log(PanicLevel, PanicPrefix, message)
-	panic(message)
+	var entry CoreLogEntry
+	message := l.logTranslated(1, PanicLevel, PanicPrefix, key, nil, args, captureEntry(&entry))
+	if entry.Message == "" {
+		entry = CoreLogEntry{Level: PanicLevel, LevelString: PanicLevel.Name(), Message: message}
+	}
+	panic(panicErrorOf(entry, args))
 }
 
 // TWithContext translates and logs a message with context
 func (l *LocalizedLogger) TWithContext(level LogLevel, key string, context map[string]interface{}, args ...interface{}) {
-	message := l.localization.Translate(key, args...)
-	l.LogWithContext(level, getPrefixForLevel(level), message, context)
+	l.logTranslated(1, level, getPrefixForLevel(level), key, context, args)
 }
 
 // TInfoWithContext translates and logs an info message with context
 func (l *LocalizedLogger) TInfoWithContext(key string, context map[string]interface{}, args ...interface{}) {
-	l.TWithContext(InfoLevel, key, context, args...)
+	l.logTranslated(1, InfoLevel, InfoPrefix, key, context, args)
 }
 
 // TErrorWithContext translates and logs an error message with context
 func (l *LocalizedLogger) TErrorWithContext(key string, context map[string]interface{}, args ...interface{}) {
-	l.TWithContext(ErrorLevel, key, context, args...)
+	l.logTranslated(1, ErrorLevel, ErrorPrefix, key, context, args)
 }
 
 // AddCustomMessage adds a custom message to the current locale
@@ -0,0 +1,127 @@
+package pim
+
+import (
+	"fmt"
+	"iter"
+	"math"
+	"strconv"
+)
+
+// LocalizedMessage records the catalog key and arguments a LocalizedLogger
+// message was rendered from, so the entry can be re-rendered in another
+// language later, see LocalizationManager.Rerender. Redaction hooks redact
+// string arguments like the message, so a redacted secret does not come
+// back on re-rendering.
+type LocalizedMessage struct {
+	Key    string        `json:"key"`
+	Args   []interface{} `json:"args,omitempty"`
+	Locale string        `json:"locale,omitempty"` // Locale of the catalog used; empty if the key was missing
+}
+
+// newLocalizedMessage records key and args rendered in locale. Arguments
+// other than strings, booleans and numbers, such as errors, are recorded
+// as their text, so the record survives JSON and CBOR round trips.
+func newLocalizedMessage(key string, args []interface{}, locale string) *LocalizedMessage {
+	message := &LocalizedMessage{Key: key, Locale: locale}
+	if len(args) > 0 {
+		message.Args = make([]interface{}, len(args))
+		for i, arg := range args {
+			switch arg.(type) {
+			case string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+				message.Args[i] = arg
+			default:
+				message.Args[i] = fmt.Sprintf("%v", arg)
+			}
+		}
+	}
+	return message
+}
+
+// redactArgs returns m with redact applied to its string arguments. m is
+// shared with other copies of the entry, so a changed copy is returned.
+func (m *LocalizedMessage) redactArgs(redact func(string) string) *LocalizedMessage {
+	if m == nil {
+		return nil
+	}
+	var args []interface{}
+	for i, arg := range m.Args {
+		s, ok := arg.(string)
+		if !ok {
+			continue
+		}
+		if redacted := redact(s); redacted != s {
+			if args == nil {
+				args = append([]interface{}(nil), m.Args...)
+			}
+			args[i] = redacted
+		}
+	}
+	if args == nil {
+		return m
+	}
+	redacted := *m
+	redacted.Args = args
+	return &redacted
+}
+
+// replayArgs returns the recorded arguments for rendering. Numbers decoded
+// from JSON are float64, so whole numbers are turned back into integers to
+// render as they did originally, e.g. "42" rather than "4.2e+01".
+func (m *LocalizedMessage) replayArgs() []interface{} {
+	args := make([]interface{}, len(m.Args))
+	for i, arg := range m.Args {
+		if f, ok := arg.(float64); ok && f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			args[i] = strconv.FormatInt(int64(f), 10)
+			continue
+		}
+		args[i] = arg
+	}
+	return args
+}
+
+// withLocalizedMessage records message on the entry of a log call
+func withLocalizedMessage(message *LocalizedMessage) CallOption {
+	return func(o *callOptions) {
+		o.localized = message
+	}
+}
+
+// Rerender returns entry with its message rendered in locale from the
+// recorded LocalizedMessage, e.g. to read Spanish logs in English:
+//
+//	lm := pim.NewLocalizationManager(pim.Locale{Language: "en", Region: "US"})
+//	pim.LoadBuiltinCatalogs(lm)
+//	for entry, err := range pim.ReadLogFile("app.log") {
+//		entry = lm.Rerender(entry, pim.Locale{Language: "en", Region: "US"})
+//		...
+//	}
+//
+// The locale falls back to its language and the default locale like
+// SetCurrentLocale. Entries without a recorded message, or whose key is
+// in none of the catalogs, are returned unchanged.
+func (lm *LocalizationManager) Rerender(entry CoreLogEntry, locale Locale) CoreLogEntry {
+	if entry.Localized == nil {
+		return entry
+	}
+	message, used, ok := lm.translateIn(lm.chainFor(locale), entry.Localized.Key, entry.Localized.replayArgs()...)
+	if !ok {
+		return entry
+	}
+	localized := *entry.Localized
+	localized.Locale = used.String()
+	entry.Message = message
+	entry.Localized = &localized
+	return entry
+}
+
+// RerenderEntries returns an iterator over the entries of seq re-rendered
+// in locale, see Rerender. Errors of seq are passed through.
+func (lm *LocalizationManager) RerenderEntries(seq iter.Seq2[CoreLogEntry, error], locale Locale) iter.Seq2[CoreLogEntry, error] {
+	return func(yield func(CoreLogEntry, error) bool) {
+		for entry, err := range seq {
+			if !yield(lm.Rerender(entry, locale), err) {
+				return
+			}
+		}
+	}
+}
@@ -23,10 +23,11 @@ type CallInfo struct {
 
 // StackFrame represents a single frame in the call stack
 type StackFrame struct {
-	File     string `json:"file"`
-	Line     int    `json:"line"`
-	Function string `json:"function"`
-	Package  string `json:"package"`
+	File     string       `json:"file"`
+	Line     int          `json:"line"`
+	Function string       `json:"function"`
+	Package  string       `json:"package"`
+	Source   []SourceLine `json:"source,omitempty"` // Source around Line, if enabled
 }
 
 // getCallInfo retrieves detailed information about the calling function
@@ -216,6 +217,8 @@ func LogWithTimestamp(Prefix, msg string, level LogLevel) {
 		logMsg += fmt.Sprintf(" %s", goroutineInfo)
 	}
 	logMsg += fmt.Sprintf(" %s", msg)
+	scope, tags := scopeSuffix()
+	logMsg += scope
 
 	fmt.Println(logMsg)
 
@@ -226,6 +229,7 @@ func LogWithTimestamp(Prefix, msg string, level LogLevel) {
 		Message:     msg,
 		File:        fileInfo,
 		GoroutineID: goroutineInfo,
+		Tags:        tags,
 	}
 
 	if err := writeLogEntry(entry, level); err != nil {
@@ -253,6 +257,8 @@ func LogWithStackTrace(Prefix, msg string, level LogLevel) {
 		logMsg += fmt.Sprintf(" %s", goroutineInfo)
 	}
 	logMsg += fmt.Sprintf(" %s", msg)
+	scope, tags := scopeSuffix()
+	logMsg += scope
 
 	fmt.Println(logMsg)
 
@@ -271,6 +277,7 @@ func LogWithStackTrace(Prefix, msg string, level LogLevel) {
 		Message:     msg,
 		File:        fileInfo,
 		GoroutineID: goroutineInfo,
+		Tags:        tags,
 	}
 
 	if err := writeLogEntry(entry, level); err != nil {
@@ -342,13 +349,22 @@ func Trace(msg string, args ...interface{}) {
 	LogWithTimestamp(TracePrefix, logMsg, TraceLevel)
 }
 
+// Panic logs msg with a stack trace and panics with a *PanicError carrying
+// the message as logged and the current scope fields
 func Panic(msg string, args ...interface{}) {
 	logMsg := msg
 	if len(args) > 0 {
 		logMsg += ": " + fmt.Sprint(args...)
 	}
 	LogWithStackTrace(PanicPrefix, logMsg, PanicLevel)
-	panic(logMsg)
+	panic(panicErrorOf(CoreLogEntry{
+		Timestamp:   time.Now(),
+		Level:       PanicLevel,
+		LevelString: PanicLevel.Name(),
+		Prefix:      PanicPrefix,
+		Message:     logMsg,
+		Context:     ScopeFields(),
+	}, args))
 }
 
 func Metric(name string, value interface{}, tags ...string) {
@@ -4,12 +4,11 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
-	"syscall"
+	"sync/atomic"
 	"time"
 )
 
@@ -33,22 +32,40 @@ type LoggerCore struct {
 	hostname        string
 	pid             int
 	serviceName     string
-	rateCounters    map[LogLevel]int     // for rate-based sampling
-	themeManager    *ThemeManager        // Theme manager for formatting
-	callerFormatter *CallerInfoFormatter // Enhanced caller info formatter
+	rateCounters    map[LogLevel]int        // for rate-based sampling
+	themeManager    *ThemeManager           // Theme manager for formatting
+	callerFormatter *CallerInfoFormatter    // Enhanced caller info formatter
+	namedWriters    map[string]LogWriter    // Writers addressable by ToWritersOnly
+	name            string                  // Hierarchical logger name, see Named
+	group           string                  // Qualifies the keys of added fields, see WithGroup
+	startupOnce     *sync.Once              // Emits the startup fingerprint; shared with child loggers
+	observer        PipelineObserver        // Receives pipeline measurements, if set
+	pendingMetrics  map[string]MetricsState // Restored metrics for MetricsHooks not added yet
+	provenance      *provenanceLog          // Entry traces in provenance mode; shared with child loggers
+	samplingReport  *samplingReporter       // Counts dropped entries, see ReportSampling; shared with child loggers
+	counters        *pipelineCounters       // Counts entries for Snapshot; shared with child loggers
+	asyncOwner      *LoggerCore             // Logger running the async worker; shared with child loggers
 
 	// Async logging fields
-	asyncBuffer chan CoreLogEntry
-	asyncWorker *asyncWorker
-	asyncCtx    context.Context
-	asyncCancel context.CancelFunc
-	asyncWg     sync.WaitGroup
+	asyncBuffer   *entryRing
+	asyncPriority *entryRing    // Error and Panic lane when PriorityLanes is set
+	asyncDropped  atomic.Uint64 // Best-effort entries dropped on a full buffer
+	asyncWorker   *asyncWorker
+	persistQueue  *persistentQueue // Write-ahead log of queued entries, if configured
+	asyncCtx      context.Context
+	asyncCancel   context.CancelFunc
+	asyncWg       sync.WaitGroup
 }
 
 // asyncWorker handles background log processing
 type asyncWorker struct {
-	logger *LoggerCore
-	ctx    context.Context
+	logger   *LoggerCore
+	ctx      context.Context
+	wake     chan struct{}      // Signalled by producers while the worker sleeps
+	drains   chan chan struct{} // Requests to write everything queued, see drain
+	sleeping atomic.Bool
+	batch    []CoreLogEntry
+	replayed bool // The persistent queue of the previous run was replayed
 }
 
 // newAsyncWorker creates a new async worker
@@ -56,6 +73,9 @@ func newAsyncWorker(logger *LoggerCore, ctx context.Context) *asyncWorker {
 	return &asyncWorker{
 		logger: logger,
 		ctx:    ctx,
+		wake:   make(chan struct{}, 1),
+		drains: make(chan chan struct{}),
+		batch:  make([]CoreLogEntry, 0, asyncBatchSize),
 	}
 }
 
@@ -73,59 +93,117 @@ func (w *asyncWorker) run() {
 	defer ticker.Stop()
 
 	for {
-		select {
-		case entry, ok := <-w.logger.asyncBuffer:
-			if !ok {
-				// Channel closed, flush remaining entries
-				w.flushRemaining()
-				return
-			}
-			w.processEntry(entry)
+		if w.flushBuffer() {
+			continue
+		}
+		w.acknowledge()
+
+		// Announce sleep, then check again so a concurrent push either is
+		// seen here or sees sleeping and signals wake
+		w.sleeping.Store(true)
+		if w.pending() {
+			w.sleeping.Store(false)
+			continue
+		}
 
+		select {
+		case <-w.wake:
+		case done := <-w.drains:
+			w.sleeping.Store(false)
+			w.flushRemaining()
+			close(done)
 		case <-ticker.C:
 			// Periodic flush
-			w.flushBuffer()
-
 		case <-w.ctx.Done():
 			// Context cancelled, flush and exit
+			w.sleeping.Store(false)
 			w.flushRemaining()
 			return
 		}
+		w.sleeping.Store(false)
 	}
 }
 
+// notify wakes the worker if it is waiting for entries
+func (w *asyncWorker) notify() {
+	if w.sleeping.Load() && w.sleeping.CompareAndSwap(true, false) {
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// drain waits until the worker has written every entry queued before the
+// call; unlike Flush the worker keeps running
+func (w *asyncWorker) drain() {
+	done := make(chan struct{})
+	select {
+	case w.drains <- done:
+		<-done
+	case <-w.ctx.Done():
+		// Stopped by Flush, which writes the remaining entries
+	}
+}
+
+// pending reports whether any lane has queued entries
+func (w *asyncWorker) pending() bool {
+	if w.logger.asyncPriority != nil && w.logger.asyncPriority.Len() > 0 {
+		return true
+	}
+	return w.logger.asyncBuffer.Len() > 0
+}
+
 // processEntry processes a single log entry
 func (w *asyncWorker) processEntry(entry CoreLogEntry) {
-	w.logger.writeToWriters(entry)
+	w.logger.writeQueued(entry)
+	w.logger.persisted(entry)
 }
 
-// flushBuffer flushes all entries currently in the buffer
-func (w *asyncWorker) flushBuffer() {
-	for {
-		select {
-		case entry, ok := <-w.logger.asyncBuffer:
-			if !ok {
-				return
-			}
-			w.processEntry(entry)
-		default:
-			return
-		}
+// flushBuffer writes one batch of queued entries, Error and Panic entries
+// first, and reports whether there was anything to write
+func (w *asyncWorker) flushBuffer() bool {
+	if !w.replayed && w.pending() {
+		w.replay()
+	}
+	wrote := w.drainPriority()
+
+	w.batch = w.logger.asyncBuffer.PopBatch(w.batch[:0])
+	for i := range w.batch {
+		w.drainPriority()
+		w.processEntry(w.batch[i])
+		w.batch[i] = CoreLogEntry{}
+	}
+	if w.logger.persistQueue != nil && len(w.batch) > 0 {
+		w.logger.persistQueue.ack(false)
+	}
+	return wrote || len(w.batch) > 0
+}
+
+// replay writes the entries left in the persistent queue by the previous
+// run, so they come before the first entry of this run
+func (w *asyncWorker) replay() {
+	w.replayed = true
+	if w.logger.persistQueue == nil {
+		return
+	}
+	if _, err := w.logger.persistQueue.replay(w.logger.writeToWriters); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to replay persistent queue: %v\n", err)
 	}
 }
 
 // flushRemaining flushes all remaining entries in the buffer
 func (w *asyncWorker) flushRemaining() {
-	for {
-		select {
-		case entry, ok := <-w.logger.asyncBuffer:
-			if !ok {
-				return
-			}
-			w.processEntry(entry)
-		default:
-			return
-		}
+	for w.flushBuffer() {
+	}
+	w.acknowledge()
+}
+
+// acknowledge checkpoints the persistent queue up to the entries written
+// so far
+func (w *asyncWorker) acknowledge() {
+	if w.logger.persistQueue != nil {
+		w.logger.persistQueue.ack(true)
 	}
 }
 
@@ -144,6 +222,7 @@ type CoreLogEntry struct {
 	StackTrace  []StackFrame           `json:"stack_trace,omitempty"`
 	Context     map[string]interface{} `json:"context,omitempty"`
 	ServiceName string                 `json:"service_name,omitempty"`
+	LoggerName  string                 `json:"logger,omitempty"` // Hierarchical name of a Named logger
 	TraceID     string                 `json:"trace_id,omitempty"`
 	SpanID      string                 `json:"span_id,omitempty"`
 	UserID      string                 `json:"user_id,omitempty"`
@@ -151,6 +230,31 @@ type CoreLogEntry struct {
 	SessionID   string                 `json:"session_id,omitempty"`
 	Hostname    string                 `json:"hostname,omitempty"`
 	PID         int                    `json:"pid,omitempty"`
+
+	// Frontend correlation for linking backend logs to session-replay tools
+	SessionReplayID string `json:"session_replay_id,omitempty"`
+	ClientEventID   string `json:"client_event_id,omitempty"`
+
+	// Links between entries of a workflow, see LoggerCore.Event
+	EventID       string `json:"event_id,omitempty"`
+	ParentEventID string `json:"parent_event_id,omitempty"`
+
+	// Raw template of a message logged with named holes, such as
+	// "user {user_id} bought {item}", for grouping entries, see Log
+	MessageTemplate string `json:"message_template,omitempty"`
+
+	// Catalog key and arguments of LocalizedLogger messages, see
+	// LocalizationManager.Rerender
+	Localized *LocalizedMessage `json:"localized,omitempty"`
+
+	// Storage tier hint set by hooks, see RetentionHook and RetentionWriter
+	Retention Retention `json:"retention,omitempty"`
+
+	provenance     *provenanceTrace // Set in provenance mode, see EnableProvenance
+	coloredMessage string           // Message with colors for console layouts, see Sparkline
+	persistSeq     uint64           // Sequence number in the persistent queue, 0 if not persisted
+	origin         *LoggerCore      // Child logger that queued the entry on its parent's async buffer
+	quarantine     LogWriter        // Receives the entry instead of the writers, see ValidationQuarantine
 }
 
 // LogWriter defines the interface for log output destinations
@@ -179,6 +283,10 @@ type LoggerConfig struct {
 	Level           LogLevel `json:"level"`
 	ServiceName     string   `json:"service_name"`
 	TimestampFormat string   `json:"timestamp_format"`
+	// TimestampResolution is how long a formatted timestamp is reused by
+	// the text and logfmt encoders, see TimestampFormatter (default: the
+	// finest unit TimestampFormat shows)
+	TimestampResolution time.Duration `json:"timestamp_resolution,omitempty"`
 
 	// Caller information (legacy - for backward compatibility)
 	ShowFileLine     bool `json:"show_file_line"`
@@ -194,26 +302,114 @@ type LoggerConfig struct {
 	// Output settings
 	EnableColors  bool `json:"enable_colors"`
 	EnableJSON    bool `json:"enable_json"`
+	EnableCBOR    bool `json:"enable_cbor"` // Binary CBOR for file and remote writers (takes precedence over EnableJSON)
 	EnableConsole bool `json:"enable_console"`
 
+	// StrictJSON keeps JSON console lines free of color codes. Without it,
+	// EnableJSON with EnableColors colors whole console lines by level when
+	// colors are enabled for the terminal (see color.NoColor).
+	StrictJSON bool `json:"strict_json"`
+
+	// ConsoleMode adapts console and stderr output to where it goes:
+	// ConsoleModeAuto picks colors for color terminals, plain text for
+	// terminals without colors and CI logs, and JSON otherwise, honoring
+	// NO_COLOR and FORCE_COLOR (see DetectTerminal). Empty keeps the
+	// output as configured.
+	ConsoleMode ConsoleMode `json:"console_mode"`
+
+	// IconMode selects emoji, ASCII or no icons in console and stderr
+	// output. The default uses ASCII icons where emoji can't be rendered,
+	// see Terminal.Unicode.
+	IconMode IconMode `json:"icon_mode"`
+
+	// FieldCase renames fields to a backend's naming convention. On the
+	// logger it applies to context keys of every entry after hooks run; on a
+	// writer's config it also applies to built-in JSON fields. Remote and
+	// CBOR batches keep the canonical names so receivers can decode them.
+	FieldCase FieldCase `json:"field_case"`
+
+	// NestGroups writes dotted context keys, such as the fields of a
+	// WithGroup logger, as nested objects in JSON output
+	NestGroups bool `json:"nest_groups"`
+
+	// Cryptography. FIPSMode restricts integrity, pseudonymization and
+	// encryption features of this logger and of writers created with this
+	// config to FIPS-approved algorithms, see SetFIPSMode for the process-wide
+	// switch. HashAlgorithm is the hash those features use (default: sha256).
+	// NewCheckedLoggerCore rejects a non-approved HashAlgorithm.
+	FIPSMode      bool          `json:"fips_mode"`
+	HashAlgorithm HashAlgorithm `json:"hash_algorithm"`
+
+	// ProvenanceSize turns on provenance mode, keeping the traces of this
+	// many recent entries (see EnableProvenance). 0 disables it.
+	ProvenanceSize int `json:"provenance_size"`
+
 	// Theming and formatting
 	ThemeName    string `json:"theme_name"`    // Name of the theme to use
 	FormatName   string `json:"format_name"`   // Name of the format to use
 	CustomTheme  *Theme `json:"custom_theme"`  // Custom theme (overrides ThemeName)
+	ThemeFile    string `json:"theme_file"`    // JSON or YAML theme file (overrides ThemeName), see LoadThemeFromFile
 	CustomFormat string `json:"custom_format"` // Custom format template
 
+	// LevelPrefixes replaces the emoji prefixes of the given levels, e.g.
+	// {InfoLevel: "[INFO]", ErrorLevel: "[ERR]"}, without a custom theme:
+	// the label replaces the built-in prefix of text output and the icon
+	// and level name of the theme formats. Prefixes passed to Log directly
+	// are kept.
+	LevelPrefixes map[LogLevel]string `json:"level_prefixes,omitempty"`
+
+	// Encoder formats entries for the console, stderr, file, remote and
+	// syslog writers instead of their built-in text or JSON formats
+	Encoder Encoder `json:"-"`
+
+	// Clock is the time source for entry timestamps, sampling, request IDs
+	// and file rotation (default: time.Now), so tests can control time
+	Clock Clock `json:"-"`
+
+	// IDGenerator generates the request IDs of AddRequestIDEnrichHook
+	// (default: "req_" and the clock's Unix nanoseconds)
+	IDGenerator IDGenerator `json:"-"`
+
+	// TemplateSandbox limits format template execution (zero values use DefaultTemplateSandboxConfig)
+	TemplateSandbox TemplateSandboxConfig `json:"template_sandbox"`
+
+	// Stack traces of Error and Panic entries
+	StackTrace StackTraceConfig `json:"stack_trace"`
+
 	// Performance settings
 	Async         bool          `json:"async"`
-	BufferSize    int           `json:"buffer_size"`
+	BufferSize    int           `json:"buffer_size"` // Async queue capacity, rounded up to a power of two
 	FlushInterval time.Duration `json:"flush_interval"`
 
+	// PriorityLanes splits the async buffer: Error and Panic entries get a
+	// reserved lane that is written first and never dropped, while other
+	// entries are dropped when their lane is full
+	PriorityLanes      bool `json:"priority_lanes"`
+	PriorityBufferSize int  `json:"priority_buffer_size"` // Default: BufferSize/4, at least 1; rounded up like BufferSize
+
+	// PersistentQueue writes async entries to disk before queueing them so
+	// entries buffered when the process crashes are replayed on the next
+	// start, see ReplayPersistentQueue (requires Async)
+	PersistentQueue *PersistentQueueConfig `json:"persistent_queue,omitempty"`
+
 	// Sampling
 	EnableSampling  bool                        `json:"enable_sampling"`
 	SampleRate      float64                     `json:"sample_rate"`
 	SamplingByLevel map[LogLevel]SamplingConfig `json:"sampling_by_level"`
 
+	// SamplingReportInterval emits a report of the entries dropped by
+	// sampling or full async lanes at this interval, see ReportSampling
+	SamplingReportInterval time.Duration `json:"sampling_report_interval"`
+
 	// Context propagation
 	PropagateContext bool `json:"propagate_context"`
+
+	// Startup diagnostics
+	LogStartupFingerprint bool `json:"log_startup_fingerprint"` // Write one environment summary entry before the first entry
+
+	// StateFile persists rate-sampling counters and MetricsHook values: they
+	// are restored on creation and saved on Close (opt-in)
+	StateFile string `json:"state_file"`
 }
 
 // DefaultLoggerConfig provides sensible defaults
@@ -255,6 +451,10 @@ func NewLoggerCore(config LoggerConfig) *LoggerCore {
 		config.CallerInfoConfig.StackDepth = config.StackDepth
 	}
 
+	if err := config.ValidateCrypto(); err != nil {
+		fmt.Fprintf(os.Stderr, "pim: invalid crypto settings: %v\n", err)
+	}
+
 	hostname, _ := os.Hostname()
 
 	// Initialize caller formatter
@@ -273,26 +473,45 @@ func NewLoggerCore(config LoggerConfig) *LoggerCore {
 		rateCounters:    make(map[LogLevel]int),
 		themeManager:    NewThemeManager(),
 		callerFormatter: callerFormatter,
+		namedWriters:    make(map[string]LogWriter),
+		provenance:      &provenanceLog{},
+		counters:        &pipelineCounters{},
+	}
+	if config.ProvenanceSize > 0 {
+		logger.EnableProvenance(config.ProvenanceSize)
 	}
 
 	// Initialize theme manager
-	if config.CustomTheme != nil {
-		logger.themeManager.currentTheme = config.CustomTheme
-	} else if config.ThemeName != "" {
-		logger.themeManager.SetTheme(config.ThemeName)
-	}
+	logger.themeManager.applyConfigTheme(config)
+	logger.themeManager.SetIconMode(config.IconMode)
 
-	// Register custom format if provided
+	// Apply template limits, then register custom format if provided
+	logger.themeManager.SetTemplateSandbox(config.TemplateSandbox)
 	if config.CustomFormat != "" {
 		logger.themeManager.RegisterTemplate("custom", config.CustomFormat)
 	}
+	if config.LevelPrefixes != nil {
+		logger.themeManager.SetLevelPrefixes(config.LevelPrefixes)
+	}
 
 	// Initialize async logging if enabled
 	if config.Async {
 		logger.asyncCtx, logger.asyncCancel = context.WithCancel(context.Background())
-		logger.asyncBuffer = make(chan CoreLogEntry, config.BufferSize)
+		logger.asyncBuffer = newEntryRing(config.BufferSize)
+		if config.PriorityLanes {
+			logger.asyncPriority = newEntryRing(config.priorityBufferSize())
+		}
+		if config.PersistentQueue != nil {
+			queue, err := openPersistentQueue(*config.PersistentQueue)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to open persistent queue: %v\n", err)
+			} else {
+				logger.persistQueue = queue
+			}
+		}
 		logger.asyncWorker = newAsyncWorker(logger, logger.asyncCtx)
 		logger.asyncWorker.start()
+		logger.asyncOwner = logger
 	}
 
 	// Add default console writer if enabled
@@ -300,13 +519,45 @@ func NewLoggerCore(config LoggerConfig) *LoggerCore {
 		logger.AddWriter(NewConsoleWriter(config))
 	}
 
+	if config.LogStartupFingerprint {
+		logger.startupOnce = &sync.Once{}
+	}
+
+	if config.SamplingReportInterval > 0 {
+		logger.samplingReport = newSamplingReporter(logger, config.SamplingReportInterval)
+	}
+
+	// Restore sampler and metrics state from the previous run
+	if config.StateFile != "" {
+		if err := logger.LoadState(config.StateFile); err != nil {
+			fmt.Fprintf(os.Stderr, "pim: ignoring logger state: %v\n", err)
+		}
+	}
+
+	if logger.samplingReport != nil {
+		logger.samplingReport.start()
+	}
+
 	RegisterLoggerForShutdown(logger)
 
 	return logger
 }
 
+// NewCheckedLoggerCore is NewLoggerCore, but returns the error of
+// config.ValidateCrypto instead of reporting it on stderr, so a config that
+// is not FIPS-compliant fails at startup
+func NewCheckedLoggerCore(config LoggerConfig) (*LoggerCore, error) {
+	if err := config.ValidateCrypto(); err != nil {
+		return nil, err
+	}
+	return NewLoggerCore(config), nil
+}
+
 // AddWriter adds a new log writer
 func (l *LoggerCore) AddWriter(writer LogWriter) {
+	if limited, ok := writer.(*RateLimitedWriter); ok {
+		limited.ReportTo(l)
+	}
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	l.writers = append(l.writers, writer)
@@ -377,25 +628,41 @@ func (l *LoggerCore) ClearContext() {
 	l.context = make(map[string]interface{})
 }
 
-// Log creates and writes a log entry
+// Log creates and writes a log entry. A message with args is formatted
+// with fmt.Sprintf, unless it is a message template with named holes and
+// no % verbs: logger.Info("user {user_id} bought {item}", 42, "book")
+// logs "user 42 bought book" with the fields user_id=42 and item="book"
+// and the raw template in MessageTemplate. A hole written {$name} stores
+// its value as a string, and "{{" and "}}" are literal braces.
 func (l *LoggerCore) Log(level LogLevel, prefix, message string, args ...interface{}) {
-	if level > l.GetLevel() {
+	l.log(1, level, prefix, message, args)
+}
+
+// log implements Log for the call site skip frames above its caller, so
+// each public entry point resolves package levels (see SetPackageLevel)
+// and caller information for its own caller
+func (l *LoggerCore) log(skip int, level LogLevel, prefix, message string, args []interface{}) {
+	// Return before options are parsed or anything is allocated
+	if l.belowLevel(skip+1, level, message, args) {
 		return
 	}
 
-	// Apply sampling if enabled
-	if !l.shouldSampleLevel(level) {
+	opts, args := extractCallOptions(args)
+
+	// Apply level check and sampling unless overridden for this call
+	if !l.enabled(skip+1, level, message, opts) {
 		return
 	}
 
 	// Format message with args
-	formattedMessage := message
-	if len(args) > 0 {
-		formattedMessage = fmt.Sprintf(message, args...)
-	}
+	formattedMessage, templateFields := formatMessage(message, args)
 
 	// Create log entry
-	entry := l.createLogEntry(level, prefix, formattedMessage)
+	entry := l.createLogEntry(skip+1, level, prefix, formattedMessage)
+	entry.addTemplate(message, l.grouped(templateFields))
+	entry.addFields(l.grouped(opts.fields))
+	entry.Localized = opts.localized
+	entry.coloredMessage = opts.coloredMessage
 
 	// Apply hooks
 	entry = l.applyHooks(entry)
@@ -405,95 +672,88 @@ func (l *LoggerCore) Log(level LogLevel, prefix, message string, args ...interfa
 		return // Entry was filtered, don't log
 	}
 
-	// Write to all writers (async or sync)
-	if l.config.Async {
-		select {
-		case l.asyncBuffer <- entry:
-			// Successfully queued
-		default:
-			// Buffer full, fall back to synchronous logging
-			l.writeToWriters(entry)
-		}
-	} else {
-		l.writeToWriters(entry)
-	}
+	l.dispatch(entry, opts)
 }
 
 // LogWithContext creates and writes a log entry with additional context
 func (l *LoggerCore) LogWithContext(level LogLevel, prefix, message string, context map[string]interface{}, args ...interface{}) {
-	if level > l.GetLevel() {
+	l.logWithContext(1, level, prefix, message, context, args)
+}
+
+// logWithContext implements LogWithContext for the call site skip frames
+// above its caller, see log
+func (l *LoggerCore) logWithContext(skip int, level LogLevel, prefix, message string, context map[string]interface{}, args []interface{}) {
+	// Return before options are parsed or anything is allocated
+	if l.belowLevel(skip+1, level, message, args) {
 		return
 	}
 
-	// Apply sampling if enabled
-	if !l.shouldSampleLevel(level) {
+	opts, args := extractCallOptions(args)
+
+	// Apply level check and sampling unless overridden for this call
+	if !l.enabled(skip+1, level, message, opts) {
 		return
 	}
 
 	// Format message with args
-	formattedMessage := message
-	if len(args) > 0 {
-		formattedMessage = fmt.Sprintf(message, args...)
-	}
+	formattedMessage, templateFields := formatMessage(message, args)
 
 	// Create log entry
-	entry := l.createLogEntry(level, prefix, formattedMessage)
+	entry := l.createLogEntry(skip+1, level, prefix, formattedMessage)
+	entry.addTemplate(message, l.grouped(templateFields))
 
 	// Add context
-	if context != nil {
-		if entry.Context == nil {
-			entry.Context = make(map[string]interface{})
-		}
-		for k, v := range context {
-			entry.Context[k] = v
-		}
-	}
+	entry.addFields(l.grouped(context))
+	entry.addFields(l.grouped(opts.fields))
+	entry.Localized = opts.localized
+	entry.coloredMessage = opts.coloredMessage
 
 	// Apply hooks
 	entry = l.applyHooks(entry)
+	if opts.capture != nil {
+		*opts.capture = entry
+	}
 
 	// Check if entry was filtered out
 	if entry.Message == "" && entry.Level == 0 {
 		return // Entry was filtered, don't log
 	}
 
-	// Write to all writers (async or sync)
-	if l.config.Async {
-		select {
-		case l.asyncBuffer <- entry:
-			// Successfully queued
-		default:
-			// Buffer full, fall back to synchronous logging
-			l.writeToWriters(entry)
-		}
-	} else {
-		l.writeToWriters(entry)
-	}
+	l.dispatch(entry, opts)
 }
 
 // LogWithStackTrace creates and writes a log entry with stack trace
 func (l *LoggerCore) LogWithStackTrace(level LogLevel, prefix, message string, args ...interface{}) {
-	if level > l.GetLevel() {
+	l.logWithStackTrace(1, level, prefix, message, args)
+}
+
+// logWithStackTrace implements LogWithStackTrace for the call site skip
+// frames above its caller, see log
+func (l *LoggerCore) logWithStackTrace(skip int, level LogLevel, prefix, message string, args []interface{}) {
+	// Return before options are parsed or anything is allocated
+	if l.belowLevel(skip+1, level, message, args) {
 		return
 	}
 
-	// Apply sampling if enabled
-	if !l.shouldSampleLevel(level) {
+	opts, args := extractCallOptions(args)
+
+	// Apply level check and sampling unless overridden for this call
+	if !l.enabled(skip+1, level, message, opts) {
 		return
 	}
 
 	// Format message with args
-	formattedMessage := message
-	if len(args) > 0 {
-		formattedMessage = fmt.Sprintf(message, args...)
-	}
+	formattedMessage, templateFields := formatMessage(message, args)
 
 	// Create log entry with stack trace
-	entry := l.createLogEntry(level, prefix, formattedMessage)
+	entry := l.createLogEntry(skip+1, level, prefix, formattedMessage)
+	entry.addTemplate(message, l.grouped(templateFields))
+	entry.addFields(l.grouped(opts.fields))
 
 	// Get stack trace using enhanced formatter
+	var fullPaths []string
 	if l.callerFormatter != nil {
-		callerFrames := l.callerFormatter.GetStackTrace(4) // Skip 4 frames
+		callerFrames := l.callerFormatter.GetStackTrace(skip + 3)
 		// Convert CallerInfo to StackFrame for compatibility
 		for _, frame := range callerFrames {
 			entry.StackTrace = append(entry.StackTrace, StackFrame{
@@ -502,52 +762,49 @@ func (l *LoggerCore) LogWithStackTrace(level LogLevel, prefix, message string, a
 				Function: frame.Function,
 				Package:  frame.Package,
 			})
+			fullPaths = append(fullPaths, frame.FullPath)
 		}
 	} else {
 		// Fallback to legacy method
-		entry.StackTrace = l.getStackTrace(4) // Skip 4 frames
+		entry.StackTrace, fullPaths = l.getStackTrace(skip + 3)
 	}
+	l.config.StackTrace.attachSource(entry.StackTrace, fullPaths)
 
 	// Apply hooks
 	entry = l.applyHooks(entry)
+	if opts.capture != nil {
+		*opts.capture = entry
+	}
 
 	// Check if entry was filtered out
 	if entry.Message == "" && entry.Level == 0 {
 		return // Entry was filtered, don't log
 	}
 
-	// Write to all writers (async or sync)
-	if l.config.Async {
-		select {
-		case l.asyncBuffer <- entry:
-			// Successfully queued
-		default:
-			// Buffer full, fall back to synchronous logging
-			l.writeToWriters(entry)
-		}
-	} else {
-		l.writeToWriters(entry)
-	}
+	l.dispatch(entry, opts)
 }
 
-// createLogEntry creates a new log entry with all metadata
-func (l *LoggerCore) createLogEntry(level LogLevel, prefix, message string) CoreLogEntry {
-	now := time.Now().UTC()
+// createLogEntry creates a new log entry with all metadata, with the caller
+// information of the call site skip frames above its caller
+func (l *LoggerCore) createLogEntry(skip int, level LogLevel, prefix, message string) CoreLogEntry {
+	now := l.config.now().UTC()
 
 	entry := CoreLogEntry{
 		Timestamp:   now,
 		Level:       level,
 		LevelString: l.getLevelString(level),
 		Message:     message,
-		Prefix:      prefix,
+		Prefix:      l.levelPrefix(level, prefix),
 		ServiceName: l.serviceName,
+		LoggerName:  l.name,
 		Hostname:    l.hostname,
 		PID:         l.pid,
+		provenance:  l.provenance.start(level),
 	}
 
 	// Add caller information using enhanced formatter
 	if l.callerFormatter != nil {
-		callerInfo := l.callerFormatter.GetCallerInfo(3) // Skip 3 frames
+		callerInfo := l.callerFormatter.GetCallerInfo(skip + 1)
 		entry.File = callerInfo.File
 		entry.Line = callerInfo.Line
 		entry.Function = callerInfo.Function
@@ -556,7 +813,7 @@ func (l *LoggerCore) createLogEntry(level LogLevel, prefix, message string) Core
 	} else {
 		// Fallback to legacy method
 		if l.config.ShowFileLine {
-			callInfo := l.getCallInfo(3) // Skip 3 frames
+			callInfo := l.getCallInfo(skip + 1)
 			entry.File = callInfo.File
 			entry.Line = callInfo.Line
 			entry.Function = callInfo.Function
@@ -569,34 +826,64 @@ func (l *LoggerCore) createLogEntry(level LogLevel, prefix, message string) Core
 		}
 	}
 
-	// Add global context
-	if l.config.PropagateContext && len(l.context) > 0 {
-		entry.Context = make(map[string]interface{})
+	// Add global context and the fields of the current scope, see PushScope
+	fields := l.context
+	if scoped := ScopeFields(); scoped != nil {
+		fields = make(map[string]interface{}, len(l.context)+len(scoped))
 		for k, v := range l.context {
-			entry.Context[k] = v
+			fields[k] = v
+		}
+		for k, v := range scoped {
+			fields[k] = v
+		}
+	}
+	if l.config.PropagateContext && len(fields) > 0 {
+		entry.Context = make(map[string]interface{})
+		for k, v := range fields {
+			entry.Context[k] = normalizeFieldValue(v)
 		}
 		// Propagate trace/span/request/session/correlation IDs if present
-		if v, ok := l.context["trace_id"]; ok {
+		if v, ok := fields["trace_id"]; ok {
 			if s, ok := v.(string); ok {
 				entry.TraceID = s
 			}
 		}
-		if v, ok := l.context["span_id"]; ok {
+		if v, ok := fields["span_id"]; ok {
 			if s, ok := v.(string); ok {
 				entry.SpanID = s
 			}
 		}
-		if v, ok := l.context["request_id"]; ok {
+		if v, ok := fields["request_id"]; ok {
 			if s, ok := v.(string); ok {
 				entry.RequestID = s
 			}
 		}
-		if v, ok := l.context["session_id"]; ok {
+		if v, ok := fields["session_id"]; ok {
 			if s, ok := v.(string); ok {
 				entry.SessionID = s
 			}
 		}
-		if v, ok := l.context["correlation_id"]; ok {
+		if v, ok := fields["session_replay_id"]; ok {
+			if s, ok := v.(string); ok {
+				entry.SessionReplayID = s
+			}
+		}
+		if v, ok := fields["client_event_id"]; ok {
+			if s, ok := v.(string); ok {
+				entry.ClientEventID = s
+			}
+		}
+		if v, ok := fields["event_id"]; ok {
+			if s, ok := v.(string); ok {
+				entry.EventID = s
+			}
+		}
+		if v, ok := fields["parent_event_id"]; ok {
+			if s, ok := v.(string); ok {
+				entry.ParentEventID = s
+			}
+		}
+		if v, ok := fields["correlation_id"]; ok {
 			if s, ok := v.(string); ok {
 				// Prefer to set TraceID if not already set
 				if entry.TraceID == "" {
@@ -621,8 +908,18 @@ func (l *LoggerCore) applyHooks(entry CoreLogEntry) CoreLogEntry {
 	copy(hooks, l.hooks)
 	l.mu.RUnlock()
 
+	// Legacy hooks are not typed, so lazy fields are computed for them
+	if len(hooks) > 0 {
+		entry.resolveLazy()
+	}
 	for _, hook := range hooks {
-		if modifiedEntry, err := hook.Process(entry); err == nil {
+		var before CoreLogEntry
+		if entry.provenance != nil {
+			before = copyEntry(entry)
+		}
+		modifiedEntry, err := hook.Process(entry)
+		traceHook(entry.provenance, hookTypeName(hook), before, modifiedEntry, err)
+		if err == nil {
 			entry = modifiedEntry
 		}
 	}
@@ -632,6 +929,8 @@ func (l *LoggerCore) applyHooks(entry CoreLogEntry) CoreLogEntry {
 		if modifiedEntry, err := l.hookManager.ProcessHooks(entry); err == nil {
 			entry = modifiedEntry
 		} else if strings.Contains(err.Error(), "filtered by hook") {
+			l.counters.filteredOut()
+			entry.provenance.settle(nil)
 			// Return filtered entry to indicate filtering
 			return CoreLogEntry{
 				Message: "", // Empty message indicates filtering
@@ -640,18 +939,64 @@ func (l *LoggerCore) applyHooks(entry CoreLogEntry) CoreLogEntry {
 		}
 	}
 
+	entry.provenance.settle(&entry)
 	return entry
 }
 
 // writeToWriters writes the log entry to all registered writers
 func (l *LoggerCore) writeToWriters(entry CoreLogEntry) {
+	if l.startupOnce != nil {
+		l.startupOnce.Do(func() {
+			l.writeEntry(l.startupFingerprintEntry())
+		})
+	}
+	l.writeEntry(entry)
+}
+
+// writeEntry writes the log entry to all registered writers, or only to
+// the named writers if names are given (see ToWritersOnly)
+func (l *LoggerCore) writeEntry(entry CoreLogEntry, names ...string) {
 	l.mu.RLock()
-	writers := make([]LogWriter, len(l.writers))
-	copy(writers, l.writers)
+	var writers []LogWriter
+	var missing []string
+	if len(names) == 0 {
+		writers = make([]LogWriter, len(l.writers))
+		copy(writers, l.writers)
+	} else {
+		for _, name := range names {
+			if writer, exists := l.namedWriters[name]; exists {
+				writers = append(writers, writer)
+			} else {
+				missing = append(missing, name)
+			}
+		}
+	}
+	observer := l.observer
 	l.mu.RUnlock()
 
+	for _, name := range missing {
+		fmt.Fprintf(os.Stderr, "Failed to write log entry: no writer named %q\n", name)
+	}
+
+	entry.Context = l.config.FieldCase.convertKeys(entry.Context)
+
+	l.counters.written(entry.Level)
+	if observer != nil {
+		observer.EntryLogged(entry)
+	}
+
 	for _, writer := range writers {
-		if err := writer.Write(entry); err != nil {
+		var start time.Time
+		if observer != nil {
+			start = time.Now()
+		}
+		err := writer.Write(entry)
+		if observer != nil {
+			observer.WriterWritten(writerTypeName(writer), time.Since(start), err)
+		}
+		traceWriter(entry.provenance, writerTypeName(writer), err)
+		if err != nil {
+			l.counters.writeFailed()
 			// Log writer errors to stderr to avoid infinite loops
 			fmt.Fprintf(os.Stderr, "Failed to write log entry: %v\n", err)
 		}
@@ -672,7 +1017,7 @@ func (l *LoggerCore) shouldSampleLevel(level LogLevel) bool {
 		return true
 	}
 	if cfg.SampleRate > 0.0 && cfg.SampleRate < 1.0 {
-		return time.Now().UnixNano()%100 < int64(cfg.SampleRate*100)
+		return l.config.now().UnixNano()%100 < int64(cfg.SampleRate*100)
 	}
 	if cfg.Rate > 1 {
 		// Use an atomic counter per level
@@ -729,9 +1074,10 @@ func (l *LoggerCore) getCallInfo(skip int) CallInfo {
 	}
 }
 
-// getStackTrace returns a formatted stack trace
-func (l *LoggerCore) getStackTrace(skip int) []StackFrame {
+// getStackTrace returns a formatted stack trace and the full path of each frame's file
+func (l *LoggerCore) getStackTrace(skip int) ([]StackFrame, []string) {
 	var frames []StackFrame
+	var fullPaths []string
 
 	for i := skip; i < skip+l.config.StackDepth; i++ {
 		pc, file, line, ok := runtime.Caller(i)
@@ -754,6 +1100,7 @@ func (l *LoggerCore) getStackTrace(skip int) []StackFrame {
 			functionName = parts[len(parts)-1]
 		}
 
+		fullPaths = append(fullPaths, file)
 		if !l.config.ShowFullPath {
 			file = filepath.Base(file)
 		}
@@ -766,43 +1113,48 @@ func (l *LoggerCore) getStackTrace(skip int) []StackFrame {
 		})
 	}
 
-	return frames
+	return frames, fullPaths
 }
 
 // getGoroutineID returns the current goroutine ID
 func (l *LoggerCore) getGoroutineID() string {
-	buf := make([]byte, 64)
-	n := runtime.Stack(buf, false)
-	id := strings.Fields(strings.TrimPrefix(string(buf[:n]), "goroutine "))[0]
-	return fmt.Sprintf("(goroutine %s)", id)
+	return goroutineID()
 }
 
 // getLevelString returns the string representation of a log level
 func (l *LoggerCore) getLevelString(level LogLevel) string {
-	switch level {
-	case PanicLevel:
-		return "panic"
-	case ErrorLevel:
-		return "error"
-	case WarningLevel:
-		return "warning"
-	case InfoLevel:
-		return "info"
-	case DebugLevel:
-		return "debug"
-	case TraceLevel:
-		return "trace"
-	default:
-		return "unknown"
-	}
-}
-
-// Flush flushes all buffered log entries and writers
+	return level.Name()
+}
+
+// levelPrefix returns the LevelPrefixes label of level if prefix is one of
+// the built-in prefixes or the prefix of a registered level, or prefix
+// otherwise
+func (l *LoggerCore) levelPrefix(level LogLevel, prefix string) string {
+	label, ok := l.config.LevelPrefixes[level]
+	if !ok {
+		return prefix
+	}
+	switch prefix {
+	case InfoPrefix, SuccessPrefix, InitPrefix, ConfigPrefix, WarningPrefix, ErrorPrefix,
+		DebugPrefix, TracePrefix, PanicPrefix, MetricPrefix:
+		return label
+	}
+	if custom, ok := lookupLevel(level); ok && prefix == custom.Prefix {
+		return label
+	}
+	return prefix
+}
+
+// Flush flushes all buffered log entries and writers. It stops async
+// logging: later entries are written synchronously, see drain.
 func (l *LoggerCore) Flush() {
 	if l.config.Async && l.asyncWorker != nil {
 		l.asyncCancel()
 		l.asyncWg.Wait()
 	}
+	if l.persistQueue != nil {
+		l.persistQueue.sync()
+	}
 	// Flush all writers
 	l.mu.RLock()
 	for _, writer := range l.writers {
@@ -814,13 +1166,41 @@ func (l *LoggerCore) Flush() {
 	l.mu.RUnlock()
 }
 
-// Close closes all writers and stops async logging
+// drain writes the entries queued for the async worker and flushes the
+// writers like Flush, but keeps async logging running
+func (l *LoggerCore) drain() {
+	if owner := l.asyncOwner; owner != nil && owner.asyncWorker != nil {
+		owner.asyncWorker.drain()
+	}
+	if l.persistQueue != nil {
+		l.persistQueue.sync()
+	}
+	l.flushWriters(nil)
+}
+
+// Close closes all writers, stops async logging and unregisters the logger
+// from shutdown (see RegisterShutdown)
 func (l *LoggerCore) Close() error {
+	l.unregisterShutdown()
+	l.samplingReport.stop(l)
 	l.Flush()
+
+	var errors []error
+	if l.config.StateFile != "" {
+		if err := l.SaveState(l.config.StateFile); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	if l.persistQueue != nil {
+		if err := l.persistQueue.close(); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	var errors []error
 	for _, writer := range l.writers {
 		if err := writer.Close(); err != nil {
 			errors = append(errors, err)
@@ -833,84 +1213,45 @@ func (l *LoggerCore) Close() error {
 	return nil
 }
 
-// --- Global graceful shutdown support ---
-var (
-	globalLoggersMu sync.Mutex
-	globalLoggers   []*LoggerCore
-)
-
-// RegisterLoggerForShutdown registers a logger for global shutdown handling
-func RegisterLoggerForShutdown(logger *LoggerCore) {
-	globalLoggersMu.Lock()
-	defer globalLoggersMu.Unlock()
-	globalLoggers = append(globalLoggers, logger)
-}
-
-// InstallExitHandler installs a handler to flush/close all loggers on exit/panic/signals
-func InstallExitHandler() {
-	ch := make(chan os.Signal, 2)
-	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		<-ch
-		FlushAllLoggers()
-		os.Exit(1)
-	}()
-
-	// Recover from panic and flush logs
-	go func() {
-		if r := recover(); r != nil {
-			FlushAllLoggers()
-			panic(r)
-		}
-	}()
-}
-
-// FlushAllLoggers flushes and closes all registered loggers
-func FlushAllLoggers() {
-	globalLoggersMu.Lock()
-	defer globalLoggersMu.Unlock()
-	for _, logger := range globalLoggers {
-		logger.Flush()
-		logger.Close()
-	}
-}
-
 // Convenience methods for different log levels
 func (l *LoggerCore) Trace(msg string, args ...interface{}) {
-	l.Log(TraceLevel, TracePrefix, msg, args...)
+	l.log(1, TraceLevel, TracePrefix, msg, args)
 }
 
 func (l *LoggerCore) Debug(msg string, args ...interface{}) {
-	l.Log(DebugLevel, DebugPrefix, msg, args...)
+	l.log(1, DebugLevel, DebugPrefix, msg, args)
 }
 
 func (l *LoggerCore) Info(msg string, args ...interface{}) {
-	l.Log(InfoLevel, InfoPrefix, msg, args...)
+	l.log(1, InfoLevel, InfoPrefix, msg, args)
 }
 
 func (l *LoggerCore) Success(msg string, args ...interface{}) {
-	l.Log(InfoLevel, SuccessPrefix, msg, args...)
+	l.log(1, InfoLevel, SuccessPrefix, msg, args)
 }
 
 func (l *LoggerCore) Init(msg string, args ...interface{}) {
-	l.Log(InfoLevel, InitPrefix, msg, args...)
+	l.log(1, InfoLevel, InitPrefix, msg, args)
 }
 
 func (l *LoggerCore) Config(msg string, args ...interface{}) {
-	l.Log(InfoLevel, ConfigPrefix, msg, args...)
+	l.log(1, InfoLevel, ConfigPrefix, msg, args)
 }
 
 func (l *LoggerCore) Warning(msg string, args ...interface{}) {
-	l.Log(WarningLevel, WarningPrefix, msg, args...)
+	l.log(1, WarningLevel, WarningPrefix, msg, args)
 }
 
 func (l *LoggerCore) Error(msg string, args ...interface{}) {
-	l.LogWithStackTrace(ErrorLevel, ErrorPrefix, msg, args...)
+	l.logWithStackTrace(1, ErrorLevel, ErrorPrefix, msg, args)
 }
 
+// Panic logs a Panic entry with a stack trace, then panics with a
+// *PanicError holding the entry
 func (l *LoggerCore) Panic(msg string, args ...interface{}) {
-	l.LogWithStackTrace(PanicLevel, PanicPrefix, msg, args...)
-	panic(fmt.Sprintf(msg, args...))
+	var entry CoreLogEntry
+	l.logWithStackTrace(1, PanicLevel, PanicPrefix, msg, append(args[:len(args):len(args)], captureEntry(&entry)))
+	panic(newPanicError(entry, msg, args))
 }
 
 func (l *LoggerCore) Metric(name string, value interface{}, tags ...string) {
@@ -919,31 +1260,16 @@ func (l *LoggerCore) Metric(name string, value interface{}, tags ...string) {
 		tagStr = fmt.Sprintf(" [%s]", strings.Join(tags, ", "))
 	}
 	logMsg := fmt.Sprintf("%s: %v%s", name, value, tagStr)
-	l.Log(InfoLevel, MetricPrefix, logMsg)
+	l.log(1, InfoLevel, MetricPrefix, logMsg, nil)
 }
 
 // WithContext returns a new logger with additional context
 func (l *LoggerCore) WithContext(ctx map[string]interface{}) *LoggerCore {
-	newLogger := &LoggerCore{
-		level:        l.level,
-		writers:      l.writers,
-		hooks:        l.hooks,
-		config:       l.config,
-		context:      make(map[string]interface{}),
-		hostname:     l.hostname,
-		pid:          l.pid,
-		serviceName:  l.serviceName,
-		rateCounters: make(map[LogLevel]int),
-	}
-
-	// Copy existing context
-	for k, v := range l.context {
-		newLogger.context[k] = v
-	}
+	newLogger := l.child()
 
 	// Add new context
 	for k, v := range ctx {
-		newLogger.context[k] = v
+		newLogger.context[l.groupKey(k)] = v
 	}
 
 	return newLogger
@@ -961,62 +1287,62 @@ func (l *LoggerCore) WithFields(fields map[string]interface{}) *LoggerCore {
 
 // InfoWithFields logs an info message with structured fields
 func (l *LoggerCore) InfoWithFields(msg string, fields map[string]interface{}) {
-	l.LogWithContext(InfoLevel, InfoPrefix, msg, fields)
+	l.logWithContext(1, InfoLevel, InfoPrefix, msg, fields, nil)
 }
 
 // DebugWithFields logs a debug message with structured fields
 func (l *LoggerCore) DebugWithFields(msg string, fields map[string]interface{}) {
-	l.LogWithContext(DebugLevel, DebugPrefix, msg, fields)
+	l.logWithContext(1, DebugLevel, DebugPrefix, msg, fields, nil)
 }
 
 // WarningWithFields logs a warning message with structured fields
 func (l *LoggerCore) WarningWithFields(msg string, fields map[string]interface{}) {
-	l.LogWithContext(WarningLevel, WarningPrefix, msg, fields)
+	l.logWithContext(1, WarningLevel, WarningPrefix, msg, fields, nil)
 }
 
 // ErrorWithFields logs an error message with structured fields
 func (l *LoggerCore) ErrorWithFields(msg string, fields map[string]interface{}) {
-	l.LogWithContext(ErrorLevel, ErrorPrefix, msg, fields)
+	l.logWithContext(1, ErrorLevel, ErrorPrefix, msg, fields, nil)
 }
 
 // TraceWithFields logs a trace message with structured fields
 func (l *LoggerCore) TraceWithFields(msg string, fields map[string]interface{}) {
-	l.LogWithContext(TraceLevel, TracePrefix, msg, fields)
+	l.logWithContext(1, TraceLevel, TracePrefix, msg, fields, nil)
 }
 
 // SuccessWithFields logs a success message with structured fields
 func (l *LoggerCore) SuccessWithFields(msg string, fields map[string]interface{}) {
-	l.LogWithContext(InfoLevel, SuccessPrefix, msg, fields)
+	l.logWithContext(1, InfoLevel, SuccessPrefix, msg, fields, nil)
 }
 
 // InfoKV logs an info message with variadic key-value pairs
 func (l *LoggerCore) InfoKV(msg string, kv ...interface{}) {
-	l.LogWithContext(InfoLevel, InfoPrefix, msg, kvToMap(kv...))
+	l.logWithContext(1, InfoLevel, InfoPrefix, msg, kvToMap(kv...), nil)
 }
 
 // DebugKV logs a debug message with variadic key-value pairs
 func (l *LoggerCore) DebugKV(msg string, kv ...interface{}) {
-	l.LogWithContext(DebugLevel, DebugPrefix, msg, kvToMap(kv...))
+	l.logWithContext(1, DebugLevel, DebugPrefix, msg, kvToMap(kv...), nil)
 }
 
 // WarningKV logs a warning message with variadic key-value pairs
 func (l *LoggerCore) WarningKV(msg string, kv ...interface{}) {
-	l.LogWithContext(WarningLevel, WarningPrefix, msg, kvToMap(kv...))
+	l.logWithContext(1, WarningLevel, WarningPrefix, msg, kvToMap(kv...), nil)
 }
 
 // ErrorKV logs an error message with variadic key-value pairs
 func (l *LoggerCore) ErrorKV(msg string, kv ...interface{}) {
-	l.LogWithContext(ErrorLevel, ErrorPrefix, msg, kvToMap(kv...))
+	l.logWithContext(1, ErrorLevel, ErrorPrefix, msg, kvToMap(kv...), nil)
 }
 
 // TraceKV logs a trace message with variadic key-value pairs
 func (l *LoggerCore) TraceKV(msg string, kv ...interface{}) {
-	l.LogWithContext(TraceLevel, TracePrefix, msg, kvToMap(kv...))
+	l.logWithContext(1, TraceLevel, TracePrefix, msg, kvToMap(kv...), nil)
 }
 
 // SuccessKV logs a success message with variadic key-value pairs
 func (l *LoggerCore) SuccessKV(msg string, kv ...interface{}) {
-	l.LogWithContext(InfoLevel, SuccessPrefix, msg, kvToMap(kv...))
+	l.logWithContext(1, InfoLevel, SuccessPrefix, msg, kvToMap(kv...), nil)
 }
 
 // kvToMap converts variadic key-value pairs to a map[string]interface{}
@@ -1032,24 +1358,11 @@ func kvToMap(kv ...interface{}) map[string]interface{} {
 	return fields
 }
 
-// SetLevelFromString sets the log level from a string (e.g., "info", "debug")
+// SetLevelFromString sets the log level from a string (e.g., "info", "debug"),
+// including the names of levels added with RegisterLevel
 func (l *LoggerCore) SetLevelFromString(levelStr string) bool {
-	levelStr = strings.ToLower(strings.TrimSpace(levelStr))
-	var level LogLevel
-	switch levelStr {
-	case "panic":
-		level = PanicLevel
-	case "error":
-		level = ErrorLevel
-	case "warning", "warn":
-		level = WarningLevel
-	case "info":
-		level = InfoLevel
-	case "debug":
-		level = DebugLevel
-	case "trace":
-		level = TraceLevel
-	default:
+	level, err := ParseLevel(levelStr)
+	if err != nil {
 		return false
 	}
 	l.SetLevel(level)
@@ -1193,7 +1506,7 @@ func (l *LoggerCore) GetEnhancedStackTrace(skip int) []CallerInfo {
 		return l.callerFormatter.GetStackTrace(skip)
 	}
 	// Fallback to legacy method
-	legacyFrames := l.getStackTrace(skip)
+	legacyFrames, _ := l.getStackTrace(skip)
 	var frames []CallerInfo
 	for _, frame := range legacyFrames {
 		frames = append(frames, CallerInfo{
@@ -1264,6 +1577,7 @@ func (l *LoggerCore) GetCallerCacheStats() map[string]interface{} {
 // AddEnhancedHook adds an enhanced hook to the logger
 func (l *LoggerCore) AddEnhancedHook(hook EnhancedLogHook) {
 	if l.hookManager != nil {
+		l.restoreHookState(hook)
 		l.hookManager.AddHook(hook)
 	}
 }
@@ -1328,7 +1642,8 @@ func (l *LoggerCore) GetMetricsHook() *MetricsHook {
 	return nil
 }
 
-// GetMetrics returns metrics from the metrics hook
+// GetMetrics returns metrics from the metrics hook. Use Snapshot for typed
+// metrics of the logger itself.
 func (l *LoggerCore) GetMetrics() map[string]interface{} {
 	if metricsHook := l.GetMetricsHook(); metricsHook != nil {
 		return metricsHook.GetMetrics()
@@ -1352,9 +1667,26 @@ func (l *LoggerCore) AddSensitiveDataRedactHook() {
 	l.AddEnhancedHook(NewSensitiveDataRedactHook())
 }
 
+// AddRedactHook validates config and adds a redaction hook created from it;
+// with the logger's FIPSMode only FIPS-approved hash algorithms are allowed
+func (l *LoggerCore) AddRedactHook(config RedactConfig) error {
+	config.Type = HookTypeRedact
+	if err := config.validate(l.config.fipsMode()); err != nil {
+		return err
+	}
+	l.AddEnhancedHook(NewRedactHook(config))
+	return nil
+}
+
+// AddSecretScanHook adds a hook redacting secrets of DefaultSecretRules
+// formats, ahead of the other redaction hooks
+func (l *LoggerCore) AddSecretScanHook() {
+	l.AddEnhancedHook(NewDefaultSecretScanHook())
+}
+
 // AddRequestIDEnrichHook adds a hook to enrich with request IDs
 func (l *LoggerCore) AddRequestIDEnrichHook() {
-	l.AddEnhancedHook(NewRequestIDEnrichHook())
+	l.AddEnhancedHook(NewRequestIDEnrichHookFor(l.config))
 }
 
 // AddMetricsHook adds a hook to collect metrics
@@ -1395,9 +1727,23 @@ func (l *LoggerCore) WithCorrelationID(correlationID string) *LoggerCore {
 	return l.WithContext(map[string]interface{}{"correlation_id": correlationID})
 }
 
-// WithContextFromContext extracts trace/span/request/session IDs from context.Context and returns a new logger with them set
+// WithSessionReplayID returns a new logger with the given frontend session-replay ID set in context
+func (l *LoggerCore) WithSessionReplayID(sessionReplayID string) *LoggerCore {
+	return l.WithContext(map[string]interface{}{"session_replay_id": sessionReplayID})
+}
+
+// WithClientEventID returns a new logger with the given frontend client event ID set in context
+func (l *LoggerCore) WithClientEventID(clientEventID string) *LoggerCore {
+	return l.WithContext(map[string]interface{}{"client_event_id": clientEventID})
+}
+
+// WithContextFromContext extracts trace/span/request/session IDs and any fields attached with
+// ContextWithFields from context.Context and returns a new logger with them set
 func (l *LoggerCore) WithContextFromContext(ctx context.Context) *LoggerCore {
 	fields := map[string]interface{}{}
+	for k, v := range ContextFields(ctx) {
+		fields[k] = v
+	}
 	if v := ctx.Value("trace_id"); v != nil {
 		fields["trace_id"] = v
 	}
@@ -1413,6 +1759,12 @@ func (l *LoggerCore) WithContextFromContext(ctx context.Context) *LoggerCore {
 	if v := ctx.Value("correlation_id"); v != nil {
 		fields["correlation_id"] = v
 	}
+	if v := ctx.Value("session_replay_id"); v != nil {
+		fields["session_replay_id"] = v
+	}
+	if v := ctx.Value("client_event_id"); v != nil {
+		fields["client_event_id"] = v
+	}
 	if len(fields) == 0 {
 		return l
 	}
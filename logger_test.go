@@ -302,9 +302,12 @@ func TestPanic(t *testing.T) {
 		if r := recover(); r == nil {
 			t.Error("Expected Panic function to cause a panic")
 		} else {
-			panicMsg := r.(string)
-			if !strings.Contains(panicMsg, "Critical error: system failure") {
-				t.Errorf("Expected panic message to contain formatted message, got: %s", panicMsg)
+			perr, ok := r.(*PanicError)
+			if !ok {
+				t.Fatalf("Expected a *PanicError, got %T", r)
+			}
+			if perr.Error() != "Critical error: system failure" || perr.Entry.Level != PanicLevel {
+				t.Errorf("Expected the logged message, got %+v", perr.Entry)
 			}
 		}
 	}()
@@ -0,0 +1,237 @@
+package pim
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MaintenanceWindow is a recurring period in which alerting is suppressed
+type MaintenanceWindow struct {
+	// Schedule is a cron expression for the start of the window with the
+	// fields minute, hour, day of month, month and day of week (0 or 7 is
+	// Sunday), e.g. "0 2 * * 6" for Saturdays at 02:00. Fields accept *,
+	// lists, ranges and steps such as "1-5" or "*/15".
+	Schedule string        `json:"schedule"`
+	Duration time.Duration `json:"duration"`
+	TimeZone string        `json:"time_zone"` // IANA name such as "Europe/Berlin" (default: UTC)
+}
+
+// MaintenanceSchedule reports whether a time falls in a maintenance window
+type MaintenanceSchedule struct {
+	windows []scheduledWindow
+
+	mu           sync.Mutex
+	cachedMinute int64 // Unix minute of the cached result
+	cachedActive bool
+}
+
+// scheduledWindow is a parsed MaintenanceWindow
+type scheduledWindow struct {
+	cron     cronSchedule
+	duration time.Duration
+	location *time.Location
+}
+
+// NewMaintenanceSchedule parses the windows
+func NewMaintenanceSchedule(windows ...MaintenanceWindow) (*MaintenanceSchedule, error) {
+	schedule := &MaintenanceSchedule{cachedMinute: -1}
+	for _, window := range windows {
+		cron, err := parseCron(window.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid maintenance schedule %q: %w", window.Schedule, err)
+		}
+		if window.Duration < time.Minute {
+			return nil, fmt.Errorf("maintenance window %q must last at least a minute", window.Schedule)
+		}
+		location := time.UTC
+		if window.TimeZone != "" {
+			if location, err = time.LoadLocation(window.TimeZone); err != nil {
+				return nil, fmt.Errorf("invalid maintenance time zone: %w", err)
+			}
+		}
+		schedule.windows = append(schedule.windows, scheduledWindow{cron: cron, duration: window.Duration, location: location})
+	}
+	return schedule, nil
+}
+
+// Active reports whether t falls in a maintenance window. Windows are
+// resolved to the minute, and results are cached for the current minute.
+func (s *MaintenanceSchedule) Active(t time.Time) bool {
+	minute := t.Unix() / 60
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if minute != s.cachedMinute {
+		s.cachedMinute, s.cachedActive = minute, s.active(t)
+	}
+	return s.cachedActive
+}
+
+// active checks every window for a start within its duration before t
+func (s *MaintenanceSchedule) active(t time.Time) bool {
+	for _, window := range s.windows {
+		local := t.In(window.location).Truncate(time.Minute)
+		for start := local; t.Sub(start) < window.duration; start = start.Add(-time.Minute) {
+			if window.cron.matches(start) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cronSchedule holds the allowed values of each cron field as bit sets
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+// matches reports whether t is a start time of the schedule. As in cron,
+// a time matches either restricted day field when both are restricted.
+func (c cronSchedule) matches(t time.Time) bool {
+	if c.minute&(1<<t.Minute()) == 0 || c.hour&(1<<t.Hour()) == 0 || c.month&(1<<int(t.Month())) == 0 {
+		return false
+	}
+	domMatch := c.dom&(1<<t.Day()) != 0
+	dowMatch := c.dow&(1<<int(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// parseCron parses a five-field cron expression
+func parseCron(expr string) (cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return cronSchedule{}, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+	var c cronSchedule
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return c, fmt.Errorf("minute: %w", err)
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return c, fmt.Errorf("hour: %w", err)
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return c, fmt.Errorf("day of month: %w", err)
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return c, fmt.Errorf("month: %w", err)
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return c, fmt.Errorf("day of week: %w", err)
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1 // 7 is Sunday too
+	}
+	c.domAny = fields[2] == "*"
+	c.dowAny = fields[4] == "*"
+	return c, nil
+}
+
+// parseCronField parses a comma-separated list of values, ranges and steps
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		low, high := min, max
+		if rangePart != "*" {
+			lowPart, highPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = strconv.Atoi(lowPart); err != nil {
+				return 0, fmt.Errorf("invalid value %q", lowPart)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(highPart); err != nil {
+					return 0, fmt.Errorf("invalid value %q", highPart)
+				}
+			} else if hasStep {
+				high = max
+			}
+		}
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, min, max)
+		}
+		for v := low; v <= high; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// QuietAction selects what a quiet writer does during maintenance windows
+type QuietAction string
+
+const (
+	QuietMute      QuietAction = "mute"      // Drop entries (default)
+	QuietDowngrade QuietAction = "downgrade" // Lower the level of severe entries
+)
+
+// QuietHoursConfig configures a writer wrapped with WithQuietHours
+type QuietHoursConfig struct {
+	Schedule    *MaintenanceSchedule `json:"-"`
+	Action      QuietAction          `json:"action"`
+	DowngradeTo LogLevel             `json:"downgrade_to"` // Level for QuietDowngrade; PanicLevel means WarningLevel
+	Now         func() time.Time     `json:"-"`            // Clock (default: time.Now)
+}
+
+// QuietWriter suppresses or downgrades entries during maintenance windows
+type QuietWriter struct {
+	LogWriter
+	config     QuietHoursConfig
+	suppressed atomic.Uint64
+}
+
+// WithQuietHours wraps an alerting writer, such as a Slack or PagerDuty
+// sink, so it is muted or downgraded during the maintenance windows of
+// config.Schedule. Wrap only the writers to silence; file and remote
+// writers keep receiving every entry. Downgrading lowers entries more
+// severe than DowngradeTo to it, for sinks that only page on errors.
+func WithQuietHours(writer LogWriter, config QuietHoursConfig) *QuietWriter {
+	if config.Action == "" {
+		config.Action = QuietMute
+	}
+	if config.DowngradeTo == PanicLevel {
+		config.DowngradeTo = WarningLevel
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &QuietWriter{LogWriter: writer, config: config}
+}
+
+// Write implements LogWriter interface
+func (w *QuietWriter) Write(entry CoreLogEntry) error {
+	if w.config.Schedule == nil || !w.config.Schedule.Active(w.config.Now()) {
+		return w.LogWriter.Write(entry)
+	}
+	if w.config.Action == QuietMute {
+		w.suppressed.Add(1)
+		return nil
+	}
+	if entry.Level < w.config.DowngradeTo {
+		w.suppressed.Add(1)
+		entry.Level = w.config.DowngradeTo
+		entry.LevelString = w.config.DowngradeTo.Name()
+	}
+	return w.LogWriter.Write(entry)
+}
+
+// Suppressed returns the number of entries muted or downgraded so far
+func (w *QuietWriter) Suppressed() uint64 {
+	return w.suppressed.Load()
+}
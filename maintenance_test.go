@@ -0,0 +1,93 @@
+package pim
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaintenanceScheduleActive(t *testing.T) {
+	schedule, err := NewMaintenanceSchedule(MaintenanceWindow{
+		Schedule: "30 22 * * 5", // Fridays 22:30 in New York
+		Duration: 3 * time.Hour,
+		TimeZone: "America/New_York",
+	})
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	newYork, _ := time.LoadLocation("America/New_York")
+	tests := []struct {
+		time   time.Time
+		active bool
+	}{
+		{time.Date(2024, 6, 7, 22, 29, 0, 0, newYork), false}, // Friday before the window
+		{time.Date(2024, 6, 7, 22, 30, 0, 0, newYork), true},
+		{time.Date(2024, 6, 8, 1, 29, 59, 0, newYork), true}, // Past midnight
+		{time.Date(2024, 6, 8, 1, 30, 0, 0, newYork), false},
+		{time.Date(2024, 6, 6, 23, 0, 0, 0, newYork), false}, // Thursday
+		{time.Date(2024, 6, 8, 3, 0, 0, 0, time.UTC), true},  // 23:00 in New York
+	}
+	for _, tt := range tests {
+		if got := schedule.Active(tt.time); got != tt.active {
+			t.Errorf("Active(%v) = %v, want %v", tt.time, got, tt.active)
+		}
+	}
+}
+
+func TestParseCron(t *testing.T) {
+	cron, err := parseCron("*/15 9-17 1,15 * 1-5")
+	if err != nil {
+		t.Fatalf("parseCron failed: %v", err)
+	}
+	// Day fields are combined with OR when both are restricted
+	if !cron.matches(time.Date(2024, 6, 15, 9, 45, 0, 0, time.UTC)) { // Saturday the 15th
+		t.Error("Expected the day of month to match")
+	}
+	if !cron.matches(time.Date(2024, 6, 11, 17, 0, 0, 0, time.UTC)) { // Tuesday
+		t.Error("Expected the weekday to match")
+	}
+	if cron.matches(time.Date(2024, 6, 11, 17, 5, 0, 0, time.UTC)) {
+		t.Error("Expected minute 5 not to match */15")
+	}
+
+	if sunday, _ := parseCron("0 0 * * 7"); !sunday.matches(time.Date(2024, 6, 9, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Expected 7 to mean Sunday")
+	}
+
+	for _, expr := range []string{"* * * *", "60 * * * *", "* * * * 8", "*/0 * * * *", "5-1 * * * *"} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("Expected an error for %q", expr)
+		}
+	}
+}
+
+func TestQuietWriter(t *testing.T) {
+	schedule, err := NewMaintenanceSchedule(MaintenanceWindow{Schedule: "0 2 * * *", Duration: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 6, 7, 2, 30, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	alerts := NewBufferWriter(DefaultLoggerConfig, 10)
+	muted := WithQuietHours(alerts, QuietHoursConfig{Schedule: schedule, Now: clock})
+	muted.Write(CoreLogEntry{Level: ErrorLevel, Message: "disk full"})
+	if alerts.GetBufferSize() != 0 || muted.Suppressed() != 1 {
+		t.Errorf("Expected the entry to be muted during the window")
+	}
+
+	downgraded := WithQuietHours(alerts, QuietHoursConfig{Schedule: schedule, Action: QuietDowngrade, Now: clock})
+	downgraded.Write(CoreLogEntry{Level: ErrorLevel, Message: "disk full"})
+	downgraded.Write(CoreLogEntry{Level: InfoLevel, Message: "heartbeat"})
+	entries := alerts.GetBuffer()
+	if len(entries) != 2 || entries[0].Level != WarningLevel || entries[0].LevelString != "warning" || entries[1].Level != InfoLevel {
+		t.Errorf("Expected only the error to be downgraded, got %+v", entries)
+	}
+
+	now = now.Add(time.Hour) // 03:30, after the window
+	alerts.ClearBuffer()
+	muted.Write(CoreLogEntry{Level: ErrorLevel, Message: "disk full"})
+	if alerts.GetBufferSize() != 1 {
+		t.Error("Expected entries to pass outside the window")
+	}
+}
@@ -0,0 +1,178 @@
+package pim
+
+import (
+	"context"
+	"strings"
+)
+
+// NDCKey is the field the nested diagnostic context is logged under
+const NDCKey = "ndc"
+
+// MappedDiagnosticContext is a Log4j-style MDC, see MDC
+type MappedDiagnosticContext struct{}
+
+// MDC is a Log4j-style mapped diagnostic context for teams migrating JVM
+// logging code. Put, Get and Remove manage fields of the current goroutine,
+// which every entry logged on it carries like PushScope fields:
+//
+//	pim.MDC.Put("user_id", id)
+//	defer pim.MDC.Remove("user_id")
+//
+// Fields left when the goroutine exits are kept until the process ends, so
+// every Put needs a Remove or Clear, or a deferred ClearScope.
+// PutContext, GetContext and RemoveContext manage the fields of a
+// context.Context instead, see ContextWithFields. MDC fields sit below
+// pushed scopes, so PushScope fields override them.
+var MDC MappedDiagnosticContext
+
+// Put sets key to value for the current goroutine
+func (MappedDiagnosticContext) Put(key string, value interface{}) {
+	updateScope(func(stack *scopeStack) {
+		if stack.mdc == nil {
+			stack.mdc = make(map[string]interface{})
+		}
+		stack.mdc[key] = value
+	})
+}
+
+// Get returns the value of key for the current goroutine, or nil if unset
+func (MappedDiagnosticContext) Get(key string) interface{} {
+	var value interface{}
+	readScope(func(stack *scopeStack) {
+		value = stack.mdc[key]
+	})
+	return value
+}
+
+// Remove removes key from the current goroutine
+func (MappedDiagnosticContext) Remove(key string) {
+	updateScope(func(stack *scopeStack) {
+		delete(stack.mdc, key)
+	})
+}
+
+// Clear removes all MDC fields of the current goroutine
+func (MappedDiagnosticContext) Clear() {
+	updateScope(func(stack *scopeStack) {
+		stack.mdc = nil
+	})
+}
+
+// CopyOfContextMap returns a copy of the current goroutine's MDC fields,
+// or nil if none are set
+func (MappedDiagnosticContext) CopyOfContextMap() map[string]interface{} {
+	var fields map[string]interface{}
+	readScope(func(stack *scopeStack) {
+		if len(stack.mdc) == 0 {
+			return
+		}
+		fields = make(map[string]interface{}, len(stack.mdc))
+		for k, v := range stack.mdc {
+			fields[k] = v
+		}
+	})
+	return fields
+}
+
+// PutContext returns a copy of ctx with key set to value
+func (MappedDiagnosticContext) PutContext(ctx context.Context, key string, value interface{}) context.Context {
+	return ContextWithFields(ctx, map[string]interface{}{key: value})
+}
+
+// GetContext returns the value of key attached to ctx
+func (MappedDiagnosticContext) GetContext(ctx context.Context, key string) (interface{}, bool) {
+	value, ok := ContextFields(ctx)[key]
+	return value, ok
+}
+
+// RemoveContext returns a copy of ctx without key
+func (MappedDiagnosticContext) RemoveContext(ctx context.Context, key string) context.Context {
+	fields := ContextFields(ctx)
+	if _, ok := fields[key]; !ok {
+		return ctx
+	}
+	remaining := make(map[string]interface{}, len(fields)-1)
+	for k, v := range fields {
+		if k != key {
+			remaining[k] = v
+		}
+	}
+	return context.WithValue(ctx, contextFieldsKey{}, remaining)
+}
+
+// NestedDiagnosticContext is a Log4j-style NDC, see NDC
+type NestedDiagnosticContext struct{}
+
+// NDC is a Log4j-style nested diagnostic context. Push and Pop manage a
+// stack of messages for the current goroutine; entries logged on it carry
+// the messages joined by spaces in the NDCKey field, e.g. "order=7 payment":
+//
+//	pim.NDC.Push("order=7")
+//	defer pim.NDC.Pop()
+//
+// PushContext appends a message to the NDC field of a context.Context
+// instead; there is no pop, callers keep using the parent context.
+var NDC NestedDiagnosticContext
+
+// Push adds message to the current goroutine's stack
+func (NestedDiagnosticContext) Push(message string) {
+	updateScope(func(stack *scopeStack) {
+		stack.ndc = append(stack.ndc, message)
+	})
+}
+
+// Pop removes and returns the innermost message, or "" if the stack is empty
+func (NestedDiagnosticContext) Pop() string {
+	var message string
+	updateScope(func(stack *scopeStack) {
+		if n := len(stack.ndc); n > 0 {
+			message = stack.ndc[n-1]
+			stack.ndc = stack.ndc[:n-1]
+		}
+	})
+	return message
+}
+
+// Peek returns the innermost message, or "" if the stack is empty
+func (NestedDiagnosticContext) Peek() string {
+	var message string
+	readScope(func(stack *scopeStack) {
+		if n := len(stack.ndc); n > 0 {
+			message = stack.ndc[n-1]
+		}
+	})
+	return message
+}
+
+// Depth returns the number of messages on the current goroutine's stack
+func (NestedDiagnosticContext) Depth() int {
+	var depth int
+	readScope(func(stack *scopeStack) {
+		depth = len(stack.ndc)
+	})
+	return depth
+}
+
+// Clear removes all messages of the current goroutine
+func (NestedDiagnosticContext) Clear() {
+	updateScope(func(stack *scopeStack) {
+		stack.ndc = nil
+	})
+}
+
+// Get returns the messages of the current goroutine joined by spaces
+func (NestedDiagnosticContext) Get() string {
+	var ndc string
+	readScope(func(stack *scopeStack) {
+		ndc = strings.Join(stack.ndc, " ")
+	})
+	return ndc
+}
+
+// PushContext returns a copy of ctx with message appended to its NDC field
+func (NestedDiagnosticContext) PushContext(ctx context.Context, message string) context.Context {
+	if outer, ok := ContextFields(ctx)[NDCKey].(string); ok && outer != "" {
+		message = outer + " " + message
+	}
+	return ContextWithFields(ctx, map[string]interface{}{NDCKey: message})
+}
@@ -0,0 +1,144 @@
+package pim
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestMDCPutGetRemove(t *testing.T) {
+	MDC.Put("user_id", 42)
+	MDC.Put("tenant", "acme")
+	if MDC.Get("user_id") != 42 {
+		t.Errorf("Expected user_id 42, got %v", MDC.Get("user_id"))
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if value := MDC.Get("user_id"); value != nil {
+			t.Errorf("Expected MDC fields to stay on their goroutine, got %v", value)
+		}
+	}()
+	wg.Wait()
+
+	MDC.Remove("user_id")
+	if copied := MDC.CopyOfContextMap(); len(copied) != 1 || copied["tenant"] != "acme" {
+		t.Errorf("Expected only tenant after Remove, got %v", copied)
+	}
+
+	MDC.Clear()
+	if fields := ScopeFields(); fields != nil {
+		t.Errorf("Expected no scope fields after Clear, got %v", fields)
+	}
+}
+
+func TestNDCPushPop(t *testing.T) {
+	NDC.Push("order=7")
+	NDC.Push("payment")
+	if NDC.Depth() != 2 || NDC.Peek() != "payment" || NDC.Get() != "order=7 payment" {
+		t.Errorf("Expected two messages, got %q", NDC.Get())
+	}
+	if fields := ScopeFields(); fields[NDCKey] != "order=7 payment" {
+		t.Errorf("Expected the NDC field, got %v", fields)
+	}
+
+	if message := NDC.Pop(); message != "payment" {
+		t.Errorf("Expected payment, got %q", message)
+	}
+	NDC.Pop()
+	if NDC.Pop() != "" || NDC.Depth() != 0 {
+		t.Error("Expected popping an empty stack to return nothing")
+	}
+	if fields := ScopeFields(); fields != nil {
+		t.Errorf("Expected no scope fields after the pops, got %v", fields)
+	}
+}
+
+func TestMDCInLoggerCore(t *testing.T) {
+	logger, writer := newTestLogger(t, nil)
+
+	MDC.Put("request_id", "r1")
+	MDC.Put("user", "alice")
+	NDC.Push("checkout")
+	pop := PushScope(map[string]interface{}{"user": "bob"})
+	logger.Info("scoped")
+	pop()
+	NDC.Clear()
+	MDC.Clear()
+	logger.Info("unscoped")
+
+	entries := writer.GetBuffer()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	scoped := entries[0]
+	if scoped.RequestID != "r1" || scoped.Context[NDCKey] != "checkout" {
+		t.Errorf("Expected MDC and NDC fields, got %v", scoped.Context)
+	}
+	if scoped.Context["user"] != "bob" {
+		t.Errorf("Expected scopes to override MDC fields, got %v", scoped.Context["user"])
+	}
+	if _, ok := entries[1].Context["request_id"]; ok {
+		t.Errorf("Expected no MDC fields after Clear, got %v", entries[1].Context)
+	}
+}
+
+func TestMDCContext(t *testing.T) {
+	ctx := MDC.PutContext(context.Background(), "user_id", 42)
+	ctx = MDC.PutContext(ctx, "tenant", "acme")
+	ctx = NDC.PushContext(ctx, "order=7")
+	ctx = NDC.PushContext(ctx, "payment")
+
+	if value, ok := MDC.GetContext(ctx, "user_id"); !ok || value != 42 {
+		t.Errorf("Expected user_id 42, got %v", value)
+	}
+	if value, _ := MDC.GetContext(ctx, NDCKey); value != "order=7 payment" {
+		t.Errorf("Expected nested messages, got %v", value)
+	}
+
+	removed := MDC.RemoveContext(ctx, "user_id")
+	if _, ok := MDC.GetContext(removed, "user_id"); ok {
+		t.Error("Expected user_id to be removed")
+	}
+	if _, ok := MDC.GetContext(ctx, "user_id"); !ok {
+		t.Error("Expected the parent context to keep user_id")
+	}
+	if fields := ContextFields(removed); fields["tenant"] != "acme" {
+		t.Errorf("Expected other fields to remain, got %v", fields)
+	}
+}
+
+func TestClearScopeReleasesGoroutine(t *testing.T) {
+	before := activeScopes.Load()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer ClearScope()
+		MDC.Put("job", "import")
+		NDC.Push("batch=1")
+		PushScope(map[string]interface{}{"attempt": 1})
+	}()
+	<-done
+	if active := activeScopes.Load(); active != before {
+		t.Errorf("Expected the goroutine's scope released, got %d active (was %d)", active, before)
+	}
+
+	// Pop functions may run on another goroutine while this one updates
+	for i := 0; i < 100; i++ {
+		pop := PushScope(map[string]interface{}{"i": i})
+		popped := make(chan struct{})
+		go func() {
+			pop()
+			close(popped)
+		}()
+		MDC.Put("key", i)
+		MDC.Remove("key")
+		<-popped
+	}
+	if active := activeScopes.Load(); active != before {
+		t.Errorf("Expected every scope released, got %d active (was %d)", active, before)
+	}
+}
@@ -235,22 +235,7 @@ func sendWebhookRequest(jsonData []byte) error {
 }
 
 func getLevelString(level LogLevel) string {
-	switch level {
-	case PanicLevel:
-		return "panic"
-	case ErrorLevel:
-		return "error"
-	case WarningLevel:
-		return "warning"
-	case InfoLevel:
-		return "info"
-	case DebugLevel:
-		return "debug"
-	case TraceLevel:
-		return "trace"
-	default:
-		return "unknown"
-	}
+	return level.Name()
 }
 
 func openJaegerLogFile(level LogLevel) error {
@@ -0,0 +1,164 @@
+package pim
+
+import (
+	"fmt"
+	"strings"
+)
+
+// templateHole is a named hole of a message template
+type templateHole struct {
+	start, end int    // Byte range of the hole, braces included
+	name       string // Field name, without a leading @ or $
+	stringify  bool   // $ prefix: store the value as a string
+}
+
+// parseMessageTemplate returns the holes of a message template such as
+// "user {user_id} bought {item}". Names start with a letter or underscore
+// and may contain letters, digits, underscores and dots; "{{" and "}}" are
+// literal braces. It returns nil if message has no holes.
+func parseMessageTemplate(message string) []templateHole {
+	var holes []templateHole
+	for i := 0; i < len(message); i++ {
+		if message[i] != '{' {
+			continue
+		}
+		if i+1 < len(message) && message[i+1] == '{' {
+			i++
+			continue
+		}
+		end := strings.IndexByte(message[i+1:], '}')
+		if end < 0 {
+			break
+		}
+		end += i + 1
+		hole := templateHole{start: i, end: end + 1, name: message[i+1 : end]}
+		switch {
+		case strings.HasPrefix(hole.name, "@"):
+			hole.name = hole.name[1:]
+		case strings.HasPrefix(hole.name, "$"):
+			hole.name, hole.stringify = hole.name[1:], true
+		}
+		if validTemplateName(hole.name) {
+			holes = append(holes, hole)
+			i = end
+		}
+	}
+	return holes
+}
+
+// validTemplateName reports whether name can name a template hole
+func validTemplateName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case i > 0 && (c == '.' || c >= '0' && c <= '9'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// renderMessageTemplate binds args to the holes of a message template in
+// order of first appearance, so a name used twice takes one argument, and
+// returns the rendered message and the bound values as fields. Holes
+// without an argument are left as they are, and extra arguments are
+// ignored. ok is false if message is not a template: it has no holes, or
+// it has printf verbs and is formatted with fmt.Sprintf instead. Other %
+// signs, e.g. in "disk {disk} 90% full", are literal text.
+func renderMessageTemplate(message string, args []interface{}) (rendered string, fields map[string]interface{}, ok bool) {
+	if len(args) == 0 || strings.IndexByte(message, '{') < 0 || hasPrintfVerbs(message) {
+		return "", nil, false
+	}
+	holes := parseMessageTemplate(message)
+	if len(holes) == 0 {
+		return "", nil, false
+	}
+
+	fields = make(map[string]interface{}, len(holes))
+	next := 0
+	for _, hole := range holes {
+		if _, bound := fields[hole.name]; bound || next >= len(args) {
+			continue
+		}
+		value := args[next]
+		if hole.stringify {
+			value = fmt.Sprint(value)
+		}
+		fields[hole.name] = value
+		next++
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, hole := range holes {
+		b.WriteString(unescapeTemplateBraces(message[last:hole.start]))
+		if value, bound := fields[hole.name]; bound {
+			b.WriteString(fmt.Sprint(value))
+		} else {
+			b.WriteString(message[hole.start:hole.end])
+		}
+		last = hole.end
+	}
+	b.WriteString(unescapeTemplateBraces(message[last:]))
+	return b.String(), fields, true
+}
+
+// hasPrintfVerbs reports whether message has a fmt verb such as %s, %5.2f
+// or %[1]v. "%%" and a % followed by a space or the end of the message are
+// not counted, so messages like "90% full" are not mistaken for formats.
+func hasPrintfVerbs(message string) bool {
+	for i := 0; i < len(message); i++ {
+		if message[i] != '%' {
+			continue
+		}
+		j := i + 1
+		for j < len(message) && strings.IndexByte("+-#0123456789.*[]", message[j]) >= 0 {
+			j++
+		}
+		switch {
+		case j == len(message):
+			return false
+		case message[j] == '%':
+			i = j
+		case strings.IndexByte("vTtbcdoOqxXUeEfFgGsp", message[j]) >= 0:
+			return true
+		}
+	}
+	return false
+}
+
+// unescapeTemplateBraces replaces the "{{" and "}}" escapes of literal text
+func unescapeTemplateBraces(s string) string {
+	if !strings.Contains(s, "{{") && !strings.Contains(s, "}}") {
+		return s
+	}
+	return strings.NewReplacer("{{", "{", "}}", "}").Replace(s)
+}
+
+// formatMessage renders message with args: as a message template if it
+// has named holes (see renderMessageTemplate), or with fmt.Sprintf. It
+// returns the template fields, if any.
+func formatMessage(message string, args []interface{}) (string, map[string]interface{}) {
+	if len(args) == 0 {
+		return message, nil
+	}
+	args = resolveLazyArgs(args)
+	if rendered, fields, ok := renderMessageTemplate(message, args); ok {
+		return rendered, fields
+	}
+	return fmt.Sprintf(message, args...), nil
+}
+
+// addTemplate records the template and fields of a message rendered as a
+// message template; it does nothing if fields is nil
+func (e *CoreLogEntry) addTemplate(template string, fields map[string]interface{}) {
+	if fields == nil {
+		return
+	}
+	e.MessageTemplate = template
+	e.addFields(fields)
+}
@@ -0,0 +1,74 @@
+package pim
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRenderMessageTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		args     []interface{}
+		rendered string
+		fields   map[string]interface{}
+		ok       bool
+	}{
+		{"holes", "user {user_id} bought {item}", []interface{}{42, "book"},
+			"user 42 bought book", map[string]interface{}{"user_id": 42, "item": "book"}, true},
+		{"repeated name", "{a} and {a} then {b}", []interface{}{1, 2},
+			"1 and 1 then 2", map[string]interface{}{"a": 1, "b": 2}, true},
+		{"missing arg", "{a} and {b}", []interface{}{1},
+			"1 and {b}", map[string]interface{}{"a": 1}, true},
+		{"stringify and destructure", "{$code} {@order}", []interface{}{404, []int{1}},
+			"404 [1]", map[string]interface{}{"code": "404", "order": []int{1}}, true},
+		{"escaped braces", "{{literal}} {name}", []interface{}{"x"},
+			"{literal} x", map[string]interface{}{"name": "x"}, true},
+		{"dotted name", "{http.status}", []interface{}{200},
+			"200", map[string]interface{}{"http.status": 200}, true},
+		{"printf verbs", "{user} took %dms", []interface{}{5}, "", nil, false},
+		{"indexed verb", "{user} took %[1]5.2fs", []interface{}{5.0}, "", nil, false},
+		{"literal percent", "disk {disk} 90% full", []interface{}{"sda"},
+			"disk sda 90% full", map[string]interface{}{"disk": "sda"}, true},
+		{"no holes", `payload {"a": 1}`, []interface{}{1}, "", nil, false},
+		{"no args", "user {user_id}", nil, "", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered, fields, ok := renderMessageTemplate(tt.message, tt.args)
+			if ok != tt.ok || rendered != tt.rendered || !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("Got %q %v %v, expected %q %v %v", rendered, fields, ok, tt.rendered, tt.fields, tt.ok)
+			}
+		})
+	}
+}
+
+func TestLoggerMessageTemplate(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+
+	logger.Info("user {user_id} bought {item}", 42, "book", Fields(map[string]interface{}{"item": "override"}))
+	logger.Info("took %dms", 5)
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	entry := entries[0]
+	if entry.Message != "user 42 bought book" || entry.MessageTemplate != "user {user_id} bought {item}" {
+		t.Errorf("Unexpected message %q and template %q", entry.Message, entry.MessageTemplate)
+	}
+	if entry.Context["user_id"] != 42 || entry.Context["item"] != "override" {
+		t.Errorf("Expected template fields with per-call fields taking precedence, got %v", entry.Context)
+	}
+	if entries[1].Message != "took 5ms" || entries[1].MessageTemplate != "" {
+		t.Errorf("Expected printf formatting without a template, got %+v", entries[1])
+	}
+}
+
+func TestKeyByTemplateUsesMessageTemplate(t *testing.T) {
+	a := KeyByTemplate(CoreLogEntry{Message: "user alice bought book", MessageTemplate: "user {user} bought {item}"})
+	b := KeyByTemplate(CoreLogEntry{Message: "user bob bought pen", MessageTemplate: "user {user} bought {item}"})
+	if a != b {
+		t.Errorf("Expected entries of one template to share a key, got %q and %q", a, b)
+	}
+}
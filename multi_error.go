@@ -0,0 +1,161 @@
+package pim
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// ErrorInfo is the structured form of one error in a multi-error
+type ErrorInfo struct {
+	Message string       `json:"message"`
+	Type    string       `json:"type"`
+	Stack   []StackFrame `json:"stack,omitempty"`
+}
+
+// String returns the error as "type: message"
+func (e ErrorInfo) String() string {
+	return fmt.Sprintf("%s: %s", e.Type, e.Message)
+}
+
+// multiError is implemented by errors.Join, fmt.Errorf with several %w and
+// multierr (Go 1.20+ versions)
+type multiError interface {
+	Unwrap() []error
+}
+
+// errorList is implemented by older multi-error packages such as multierr
+type errorList interface {
+	Errors() []error
+}
+
+// stackFramer is implemented by errors that carry pim stack frames
+type stackFramer interface {
+	StackFrames() []StackFrame
+}
+
+// callersError is implemented by errors that record program counters
+// (e.g. github.com/go-errors/errors)
+type callersError interface {
+	Callers() []uintptr
+}
+
+// childErrors returns the errors combined by err, or nil if it is not a multi-error
+func childErrors(err error) []error {
+	switch e := err.(type) {
+	case multiError:
+		return e.Unwrap()
+	case errorList:
+		return e.Errors()
+	}
+	return nil
+}
+
+// IsMultiError reports whether err combines several errors
+func IsMultiError(err error) bool {
+	return err != nil && len(childErrors(err)) > 1
+}
+
+// FlattenErrors returns the individual errors combined by err, depth first.
+// Nested multi-errors are flattened and nil errors skipped; a plain error
+// yields a single element.
+func FlattenErrors(err error) []ErrorInfo {
+	if err == nil {
+		return nil
+	}
+
+	children := childErrors(err)
+	if children == nil {
+		return []ErrorInfo{newErrorInfo(err)}
+	}
+
+	var infos []ErrorInfo
+	for _, child := range children {
+		infos = append(infos, FlattenErrors(child)...)
+	}
+	return infos
+}
+
+// newErrorInfo describes a single error, including its stack if it has one
+func newErrorInfo(err error) ErrorInfo {
+	info := ErrorInfo{
+		Message: err.Error(),
+		Type:    fmt.Sprintf("%T", err),
+	}
+
+	var framer stackFramer
+	var callers callersError
+	if errors.As(err, &framer) {
+		info.Stack = framer.StackFrames()
+	} else if errors.As(err, &callers) {
+		info.Stack = framesFromPCs(callers.Callers())
+	}
+	return info
+}
+
+// framesFromPCs converts program counters to stack frames
+func framesFromPCs(pcs []uintptr) []StackFrame {
+	if len(pcs) == 0 {
+		return nil
+	}
+
+	var stack []StackFrame
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		packageName, functionName := "", frame.Function
+		if i := strings.LastIndex(frame.Function, "."); i > 0 {
+			packageName, functionName = frame.Function[:i], frame.Function[i+1:]
+		}
+		file := frame.File
+		if !showFullPath {
+			file = filepath.Base(file)
+		}
+		stack = append(stack, StackFrame{
+			File:     file,
+			Line:     frame.Line,
+			Function: functionName,
+			Package:  packageName,
+		})
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+// normalizeFieldValue flattens multi-errors into structured error lists so
+// they serialize as arrays rather than one concatenated string
+func normalizeFieldValue(value interface{}) interface{} {
+	if err, ok := value.(error); ok && IsMultiError(err) {
+		return FlattenErrors(err)
+	}
+	return value
+}
+
+// errorListKeys returns the sorted context keys holding flattened error lists
+func errorListKeys(context map[string]interface{}) []string {
+	var keys []string
+	for k, v := range context {
+		if _, ok := v.([]ErrorInfo); ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatErrorList renders flattened errors as an indented list for console output
+func formatErrorList(key string, infos []ErrorInfo) string {
+	lines := []string{fmt.Sprintf("  %s:", key)}
+	for _, info := range infos {
+		lines = append(lines, fmt.Sprintf("    - %s", info))
+		for _, frame := range info.Stack {
+			lines = append(lines, fmt.Sprintf("        ↳ %s:%s:L%d", frame.File, frame.Function, frame.Line))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
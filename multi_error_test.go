@@ -0,0 +1,86 @@
+package pim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+// framedError is an error carrying its own stack frames
+type framedError struct{}
+
+func (framedError) Error() string { return "framed" }
+
+func (framedError) StackFrames() []StackFrame {
+	return []StackFrame{{File: "worker.go", Line: 7, Function: "run"}}
+}
+
+func TestFlattenErrors(t *testing.T) {
+	pathErr := &fs.PathError{Op: "open", Path: "a.txt", Err: fs.ErrNotExist}
+	err := errors.Join(
+		errors.New("disk full"),
+		nil,
+		errors.Join(pathErr, framedError{}),
+	)
+
+	infos := FlattenErrors(err)
+	if len(infos) != 3 {
+		t.Fatalf("Expected 3 flattened errors, got %d: %v", len(infos), infos)
+	}
+	if infos[0].Message != "disk full" || infos[0].Type != "*errors.errorString" {
+		t.Errorf("Unexpected first error: %+v", infos[0])
+	}
+	if infos[1].Type != "*fs.PathError" {
+		t.Errorf("Expected nested error type, got %q", infos[1].Type)
+	}
+	if len(infos[2].Stack) != 1 || infos[2].Stack[0].Function != "run" {
+		t.Errorf("Expected stack frames from the error, got %+v", infos[2].Stack)
+	}
+
+	if IsMultiError(fmt.Errorf("wrapped: %w", pathErr)) {
+		t.Error("Expected a singly wrapped error not to be a multi-error")
+	}
+}
+
+func TestMultiErrorFieldsAreStructured(t *testing.T) {
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.Level = InfoLevel
+	})
+
+	joined := errors.Join(errors.New("first"), errors.New("second"))
+	single := errors.New("alone")
+	logger.ErrorKV("batch failed", "errors", joined, "cause", single)
+
+	entry := buffer.GetBuffer()[0]
+	if _, ok := entry.Context["errors"].([]ErrorInfo); !ok {
+		t.Fatalf("Expected multi-error to be flattened, got %T", entry.Context["errors"])
+	}
+	if entry.Context["cause"] != single {
+		t.Errorf("Expected single error to be kept as is, got %v", entry.Context["cause"])
+	}
+
+	data, _ := json.Marshal(entry)
+	if !strings.Contains(string(data), `"errors":[{"message":"first","type":"*errors.errorString"},{"message":"second"`) {
+		t.Errorf("Expected errors to serialize as an array, got %s", data)
+	}
+}
+
+func TestConsoleWriterRendersErrorList(t *testing.T) {
+	writer := NewConsoleWriter(LoggerConfig{Level: InfoLevel})
+	entry := CoreLogEntry{Message: "batch failed", Context: map[string]interface{}{}}
+	entry.addFields(map[string]interface{}{"errors": errors.Join(errors.New("first"), errors.New("second"))})
+
+	output := captureOutput(func() {
+		writer.Write(entry)
+	})
+
+	if strings.Contains(output, "{") {
+		t.Errorf("Expected error lists to be left out of the inline context, got %q", output)
+	}
+	if !strings.Contains(output, "  errors:\n    - *errors.errorString: first\n    - *errors.errorString: second") {
+		t.Errorf("Expected an indented error list, got %q", output)
+	}
+}
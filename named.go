@@ -0,0 +1,74 @@
+package pim
+
+// Named returns a child logger whose name is this logger's name extended
+// with name, e.g. logger.Named("http").Named("server") is "http.server".
+// The name is recorded in each entry's LoggerName. The child shares the
+// parent's hooks and starts with its writers, level, context and theme,
+// which can then be changed on the child alone. Use SetLoggerLevel to set
+// the level of a whole subtree of named loggers.
+func (l *LoggerCore) Named(name string) *LoggerCore {
+	child := l.child()
+	child.name = joinLoggerName(l.name, name)
+	return child
+}
+
+// child returns a copy of l for Named, WithContext and WithGroup. The copy
+// shares l's hooks, caller formatter and async buffer, and starts with its
+// writers, level, context and theme.
+func (l *LoggerCore) child() *LoggerCore {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	child := &LoggerCore{
+		level:           l.level,
+		writers:         append([]LogWriter(nil), l.writers...),
+		hooks:           l.hooks,
+		hookManager:     l.hookManager,
+		config:          l.config,
+		context:         make(map[string]interface{}, len(l.context)),
+		hostname:        l.hostname,
+		pid:             l.pid,
+		serviceName:     l.serviceName,
+		rateCounters:    make(map[LogLevel]int),
+		callerFormatter: l.callerFormatter,
+		startupOnce:     l.startupOnce,
+		observer:        l.observer,
+		name:            l.name,
+		group:           l.group,
+		provenance:      l.provenance,
+		samplingReport:  l.samplingReport,
+		counters:        l.counters,
+		asyncOwner:      l.asyncOwner,
+	}
+	if l.themeManager != nil {
+		child.themeManager = l.themeManager.clone()
+	}
+
+	for k, v := range l.context {
+		child.context[k] = v
+	}
+	if l.namedWriters != nil {
+		child.namedWriters = make(map[string]LogWriter, len(l.namedWriters))
+		for name, writer := range l.namedWriters {
+			child.namedWriters[name] = writer
+		}
+	}
+	return child
+}
+
+// Name returns the hierarchical name of the logger, empty for root loggers
+func (l *LoggerCore) Name() string {
+	return l.name
+}
+
+// joinLoggerName appends a name segment to a parent logger name
+func joinLoggerName(parent, name string) string {
+	switch {
+	case name == "":
+		return parent
+	case parent == "":
+		return name
+	default:
+		return parent + "." + name
+	}
+}
@@ -0,0 +1,121 @@
+package pim
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNamedLoggerName(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+
+	server := logger.Named("http").Named("server")
+	if server.Name() != "http.server" {
+		t.Errorf("Expected hierarchical name, got %q", server.Name())
+	}
+	if logger.Named("").Name() != "" {
+		t.Error("Expected empty segment to keep the parent name")
+	}
+
+	server.WithField("path", "/").Info("request")
+	entries := buffer.GetBuffer()
+	if len(entries) != 1 || entries[0].LoggerName != "http.server" {
+		t.Fatalf("Expected entry from http.server, got %+v", entries)
+	}
+	if entries[0].Context["path"] != "/" {
+		t.Errorf("Expected context on named child, got %v", entries[0].Context)
+	}
+}
+
+func TestNamedLoggerIndependentLevelAndTheme(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+
+	db := logger.Named("db")
+	db.SetLevel(DebugLevel)
+	if err := db.SetTheme("dark"); err != nil {
+		t.Fatalf("SetTheme failed: %v", err)
+	}
+
+	logger.Debug("parent debug")
+	db.Debug("db debug")
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 1 || entries[0].Message != "db debug" {
+		t.Fatalf("Expected only the child's debug entry, got %+v", entries)
+	}
+	if logger.GetLevel() != InfoLevel {
+		t.Errorf("Expected parent level to be unchanged, got %v", logger.GetLevel())
+	}
+	if logger.GetTheme() == db.GetTheme() {
+		t.Error("Expected child theme to be independent of the parent")
+	}
+}
+
+func TestNamedLoggerSharesHooks(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+	child := logger.Named("worker")
+
+	logger.AddEnhancedHook(NewEnrichHook(EnrichConfig{
+		HookConfig: HookConfig{Type: HookTypeEnrich, Name: "region", Enabled: true},
+		Fields:     map[string]interface{}{"region": "eu"},
+	}))
+	child.Info("job done")
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 1 || entries[0].Context["region"] != "eu" {
+		t.Fatalf("Expected parent hook to apply to child, got %+v", entries)
+	}
+}
+
+func TestNamedLoggerOwnsWriters(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+	child := logger.Named("worker")
+
+	childBuffer := NewBufferWriter(DefaultLoggerConfig, 10)
+	child.AddWriter(childBuffer)
+	parentBuffer := NewBufferWriter(DefaultLoggerConfig, 10)
+	logger.AddNamedWriter("audit", parentBuffer)
+
+	logger.Info("parent")
+	child.Info("child")
+	child.Info("targeted", ToWritersOnly("audit"))
+
+	if entries := buffer.GetBuffer(); len(entries) != 2 {
+		t.Errorf("Expected both loggers to keep the shared writer, got %d entries", len(entries))
+	}
+	if entries := childBuffer.GetBuffer(); len(entries) != 1 || entries[0].Message != "child" {
+		t.Errorf("Expected the child writer to receive only child entries, got %+v", entries)
+	}
+	if entries := parentBuffer.GetBuffer(); len(entries) != 1 || entries[0].Message != "parent" {
+		t.Errorf("Expected the parent writer to receive only parent entries, got %+v", entries)
+	}
+}
+
+func TestSetLoggerLevelAppliesToSubtree(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+	SetLoggerLevel("http", DebugLevel)
+
+	logger.Named("http").Named("client").Debug("client debug")
+	logger.Named("grpc").Debug("grpc debug")
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 1 || entries[0].LoggerName != "http.client" {
+		t.Fatalf("Expected only the http subtree to log at debug, got %+v", entries)
+	}
+}
+
+func TestFileWriterShowsLoggerName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	writer, err := NewFileWriter(path, DefaultLoggerConfig, RotationConfig{})
+	if err != nil {
+		t.Fatalf("NewFileWriter failed: %v", err)
+	}
+	writer.Write(CoreLogEntry{Level: InfoLevel, LevelString: "info", LoggerName: "http.server", Message: "hi"})
+	writer.Close()
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "[http.server] hi") {
+		t.Errorf("Expected logger name in text output, got %q", data)
+	}
+}
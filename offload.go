@@ -0,0 +1,197 @@
+package pim
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ValueStore stores oversized field values offloaded from log entries
+type ValueStore interface {
+	StoreValue(id string, data []byte) error
+}
+
+// ValueStoreFunc is a function adapter for ValueStore
+type ValueStoreFunc func(id string, data []byte) error
+
+// StoreValue implements ValueStore interface
+func (f ValueStoreFunc) StoreValue(id string, data []byte) error {
+	return f(id, data)
+}
+
+// FileValueStore stores each offloaded value as a file named after its reference ID
+type FileValueStore struct {
+	dir string
+}
+
+// NewFileValueStore creates a file-backed value store in dir
+func NewFileValueStore(dir string) (*FileValueStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create value store directory: %w", err)
+	}
+	return &FileValueStore{dir: dir}, nil
+}
+
+// StoreValue implements ValueStore interface
+func (s *FileValueStore) StoreValue(id string, data []byte) error {
+	return os.WriteFile(s.Path(id), data, 0644)
+}
+
+// Path returns the file holding the value with the given reference ID
+func (s *FileValueStore) Path(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+// OffloadedValue replaces an oversized field value in the entry context
+type OffloadedValue struct {
+	Ref  string `json:"ref"`  // Reference ID in the value store
+	Size int    `json:"size"` // Size of the original value in bytes
+}
+
+// OffloadConfig holds configuration for oversized value offloading
+type OffloadConfig struct {
+	HookConfig
+	MaxValueSize int             `json:"max_value_size"` // Values larger than this many bytes are offloaded (default: 64 KiB)
+	Store        ValueStore      `json:"-"`              // Side store for offloaded values
+	IDFunc       func() string   `json:"-"`              // Reference ID generator (default: random hex)
+	OnError      func(err error) `json:"-"`              // Called when storing fails; the value is then dropped
+}
+
+// OffloadHook detects oversized context values and moves them to a side store,
+// replacing them with an OffloadedValue reference, so large payloads never
+// reach the writers. Give it a higher priority number than the redaction
+// hooks, so the values it stores are redacted.
+type OffloadHook struct {
+	config OffloadConfig
+}
+
+// NewOffloadHook creates a new value offloading hook
+func NewOffloadHook(config OffloadConfig) *OffloadHook {
+	if config.MaxValueSize <= 0 {
+		config.MaxValueSize = 64 << 10
+	}
+	if config.IDFunc == nil {
+		config.IDFunc = newRandomID
+	}
+	return &OffloadHook{config: config}
+}
+
+// newRandomID returns a random reference ID
+func newRandomID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Process implements LogHook interface
+func (h *OffloadHook) Process(entry CoreLogEntry) (CoreLogEntry, error) {
+	if !h.config.Enabled || h.config.Store == nil || entry.Context == nil {
+		return entry, nil
+	}
+
+	var context map[string]interface{}
+	for key, value := range entry.Context {
+		data, ok := h.oversized(value)
+		if !ok {
+			continue
+		}
+
+		if context == nil {
+			// Copy on first change so the caller's context map is left intact
+			context = make(map[string]interface{}, len(entry.Context))
+			for k, v := range entry.Context {
+				context[k] = v
+			}
+		}
+
+		id := h.config.IDFunc()
+		if err := h.config.Store.StoreValue(id, data); err != nil {
+			if h.config.OnError != nil {
+				h.config.OnError(fmt.Errorf("failed to offload field %q: %w", key, err))
+			}
+			id = ""
+		}
+		context[key] = OffloadedValue{Ref: id, Size: len(data)}
+	}
+
+	if context != nil {
+		entry.Context = context
+	}
+	return entry, nil
+}
+
+// oversized returns the encoded value if it exceeds the configured size
+func (h *OffloadHook) oversized(value interface{}) ([]byte, bool) {
+	var data []byte
+	switch v := value.(type) {
+	case nil, bool, int, int64, float64, OffloadedValue:
+		return nil, false
+	case string:
+		if len(v) <= h.config.MaxValueSize {
+			return nil, false
+		}
+		data = []byte(v)
+	case []byte:
+		if len(v) <= h.config.MaxValueSize {
+			return nil, false
+		}
+		data = v
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil || len(encoded) <= h.config.MaxValueSize {
+			return nil, false
+		}
+		data = encoded
+	}
+	return data, true
+}
+
+// GetConfig implements EnhancedLogHook interface
+func (h *OffloadHook) GetConfig() HookConfig {
+	return h.config.HookConfig
+}
+
+// GetType implements EnhancedLogHook interface
+func (h *OffloadHook) GetType() HookType {
+	return h.config.Type
+}
+
+// IsEnabled implements EnhancedLogHook interface
+func (h *OffloadHook) IsEnabled() bool {
+	return h.config.Enabled
+}
+
+// SetEnabled implements EnhancedLogHook interface
+func (h *OffloadHook) SetEnabled(enabled bool) {
+	h.config.Enabled = enabled
+}
+
+// GetPriority implements EnhancedLogHook interface
+func (h *OffloadHook) GetPriority() int {
+	return h.config.Priority
+}
+
+// SetPriority implements EnhancedLogHook interface
+func (h *OffloadHook) SetPriority(priority int) {
+	h.config.Priority = priority
+}
+
+// AddOffloadHook adds a hook that offloads context values larger than
+// maxValueSize bytes to store. It runs after the redaction and other
+// default-priority hooks, so stored values are redacted.
+func (l *LoggerCore) AddOffloadHook(store ValueStore, maxValueSize int) {
+	l.AddEnhancedHook(NewOffloadHook(OffloadConfig{
+		HookConfig: HookConfig{
+			Type:        HookTypeTransform,
+			Name:        "value_offload",
+			Description: "Offloads oversized context values to a side store",
+			Enabled:     true,
+			Priority:    90,
+		},
+		MaxValueSize: maxValueSize,
+		Store:        store,
+	}))
+}
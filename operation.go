@@ -0,0 +1,159 @@
+package pim
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Context keys correlating the attempts of one logical operation
+const (
+	OperationIDKey      = "operation_id"
+	OperationNameKey    = "operation"
+	AttemptKey          = "attempt"
+	HedgeKey            = "hedge"
+	AttemptDurationKey  = "attempt_duration_ms"
+	OperationElapsedKey = "elapsed_ms"
+	AttemptsKey         = "attempts"
+)
+
+// Operation groups the retries and hedged attempts of one logical call
+// under a single operation ID, so their latency can be analyzed from logs
+//
+//	op := logger.StartOperation("charge card")
+//	for {
+//		attempt := op.Attempt()
+//		err := charge(attempt.Context(ctx))
+//		attempt.End(err)
+//		if err == nil || !retryable(err) {
+//			op.End(err)
+//			break
+//		}
+//	}
+type Operation struct {
+	ID    string
+	Name  string
+	start time.Time
+
+	logger   *LoggerCore
+	mu       sync.Mutex
+	attempts int
+	now      func() time.Time
+}
+
+// StartOperation starts timing a logical operation with a new random ID
+func (l *LoggerCore) StartOperation(name string) *Operation {
+	return l.StartOperationWithID(name, newRandomID())
+}
+
+// StartOperationWithID starts timing a logical operation with the given ID,
+// e.g. an idempotency key shared with the callee
+func (l *LoggerCore) StartOperationWithID(name, id string) *Operation {
+	return &Operation{
+		ID:     id,
+		Name:   name,
+		start:  time.Now(),
+		logger: l,
+		now:    time.Now,
+	}
+}
+
+// Fields returns the fields identifying the operation
+func (op *Operation) Fields() map[string]interface{} {
+	return map[string]interface{}{
+		OperationIDKey:   op.ID,
+		OperationNameKey: op.Name,
+	}
+}
+
+// Elapsed returns the time since the operation started
+func (op *Operation) Elapsed() time.Duration {
+	return op.now().Sub(op.start)
+}
+
+// Attempt starts the next sequential attempt (1 for the first call, 2 for
+// the first retry, ...)
+func (op *Operation) Attempt() *OperationAttempt {
+	return op.newAttempt(-1)
+}
+
+// Hedge starts a hedged attempt running concurrently with others; index
+// distinguishes the parallel requests (0 for the primary)
+func (op *Operation) Hedge(index int) *OperationAttempt {
+	return op.newAttempt(index)
+}
+
+// newAttempt numbers and starts an attempt
+func (op *Operation) newAttempt(hedge int) *OperationAttempt {
+	op.mu.Lock()
+	op.attempts++
+	number := op.attempts
+	op.mu.Unlock()
+
+	return &OperationAttempt{op: op, Number: number, HedgeIndex: hedge, start: op.now()}
+}
+
+// End logs the outcome of the operation with the total elapsed time and
+// number of attempts. Failed operations are logged as errors.
+func (op *Operation) End(err error) {
+	op.mu.Lock()
+	attempts := op.attempts
+	op.mu.Unlock()
+
+	fields := op.Fields()
+	fields[AttemptsKey] = attempts
+	fields[OperationElapsedKey] = op.Elapsed().Milliseconds()
+
+	if err != nil {
+		fields["error"] = err.Error()
+		op.logger.logWithContext(1, ErrorLevel, ErrorPrefix, "operation failed", fields, nil)
+		return
+	}
+	op.logger.logWithContext(1, InfoLevel, InfoPrefix, "operation completed", fields, nil)
+}
+
+// OperationAttempt is one try of an Operation
+type OperationAttempt struct {
+	op         *Operation
+	Number     int // 1-based, in order of start
+	HedgeIndex int // Index among hedged requests, or -1 for sequential attempts
+	start      time.Time
+}
+
+// Fields returns the operation fields plus the attempt number and hedge index
+func (a *OperationAttempt) Fields() map[string]interface{} {
+	fields := a.op.Fields()
+	fields[AttemptKey] = a.Number
+	if a.HedgeIndex >= 0 {
+		fields[HedgeKey] = a.HedgeIndex
+	}
+	return fields
+}
+
+// Logger returns a child of the operation's logger that tags entries with
+// the attempt fields; it keeps the parent's hooks, so redaction still applies
+func (a *OperationAttempt) Logger() *LoggerCore {
+	return a.op.logger.WithContext(a.Fields())
+}
+
+// Context returns ctx carrying the attempt fields (see ContextWithFields),
+// so entries logged further down the call stack are correlated too
+func (a *OperationAttempt) Context(ctx context.Context) context.Context {
+	return ContextWithFields(ctx, a.Fields())
+}
+
+// End logs the outcome of the attempt with its own duration and the time
+// elapsed since the operation started. Failed attempts are logged as warnings.
+func (a *OperationAttempt) End(err error) {
+	now := a.op.now()
+	fields := a.Fields()
+	fields[AttemptDurationKey] = now.Sub(a.start).Milliseconds()
+	fields[OperationElapsedKey] = now.Sub(a.op.start).Milliseconds()
+
+	if err != nil {
+		fields["error"] = err.Error()
+		a.op.logger.logWithContext(1, WarningLevel, WarningPrefix, "operation attempt failed", fields, nil)
+		return
+	}
+	a.op.logger.logWithContext(1, DebugLevel, DebugPrefix, "operation attempt succeeded", fields, nil)
+}
@@ -0,0 +1,95 @@
+package pim
+
+import "errors"
+
+// PanicError is the value LoggerCore.Panic, LocalizedLogger.TPanic and the
+// package-level Panic panic with. It carries the
+// entry that was logged, so recover handlers can read its fields:
+//
+//	defer func() {
+//		var perr *pim.PanicError
+//		if r := recover(); r != nil {
+//			if err, ok := r.(error); ok && errors.As(err, &perr) {
+//				orderID := perr.Fields()["order_id"]
+//				...
+//			}
+//		}
+//	}()
+type PanicError struct {
+	Entry CoreLogEntry // The entry as written, after hooks
+	errs  []error      // Errors among the arguments and fields
+}
+
+// Error returns the formatted panic message
+func (e *PanicError) Error() string {
+	return e.Entry.Message
+}
+
+// Fields returns the structured fields of the entry
+func (e *PanicError) Fields() map[string]interface{} {
+	return e.Entry.Context
+}
+
+// Unwrap returns the errors passed as formatting arguments or fields, so
+// errors.Is and errors.As see through the panic
+func (e *PanicError) Unwrap() []error {
+	return e.errs
+}
+
+// captureEntry stores the entry built for this call in dst, even if hooks
+// filter it
+func captureEntry(dst *CoreLogEntry) CallOption {
+	return func(o *callOptions) {
+		o.capture = dst
+	}
+}
+
+// newPanicError builds the PanicError for a Panic call with msg and args.
+// entry is the captured entry, or empty if sampling or a hook dropped it,
+// in which case msg is rendered like a logged message.
+func newPanicError(entry CoreLogEntry, msg string, args []interface{}) *PanicError {
+	opts, args := extractCallOptions(args)
+	if entry.Message == "" {
+		message, templateFields := formatMessage(msg, args)
+		entry = CoreLogEntry{Level: PanicLevel, LevelString: PanicLevel.Name(), Message: message}
+		entry.addTemplate(msg, templateFields)
+		entry.addFields(opts.fields)
+	}
+	return panicErrorOf(entry, args)
+}
+
+// panicErrorOf returns the PanicError for entry, wrapping the errors among
+// args and the entry fields
+func panicErrorOf(entry CoreLogEntry, args []interface{}) *PanicError {
+	perr := &PanicError{Entry: entry}
+	for _, arg := range args {
+		if err, ok := arg.(error); ok {
+			perr.errs = append(perr.errs, err)
+		}
+	}
+	for _, value := range entry.Context {
+		if err, ok := value.(error); ok && !containsError(perr.errs, err) {
+			perr.errs = append(perr.errs, err)
+		}
+	}
+	return perr
+}
+
+// containsError reports whether errs holds err itself
+func containsError(errs []error, err error) bool {
+	for _, e := range errs {
+		if e == err {
+			return true
+		}
+	}
+	return false
+}
+
+// panicFields returns the fields of a PanicError among the causes of r
+func panicFields(r interface{}) map[string]interface{} {
+	var perr *PanicError
+	if err, ok := r.(error); ok && errors.As(err, &perr) {
+		return perr.Fields()
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package pim
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestPanicError(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+
+	defer func() {
+		r := recover()
+		err, ok := r.(error)
+		if !ok {
+			t.Fatalf("Expected an error panic value, got %T", r)
+		}
+		var perr *PanicError
+		if !errors.As(err, &perr) {
+			t.Fatalf("Expected a *PanicError, got %T", r)
+		}
+		if err.Error() != "read failed: EOF" {
+			t.Errorf("Unexpected message %q", err.Error())
+		}
+		if perr.Fields()["order_id"] != 42 || perr.Entry.Level != PanicLevel {
+			t.Errorf("Expected the entry fields, got %+v", perr.Entry)
+		}
+		if !errors.Is(err, io.EOF) {
+			t.Error("Expected the panic to wrap the argument error")
+		}
+		if buffer.GetBufferSize() != 1 {
+			t.Errorf("Expected the entry to be logged, got %d entries", buffer.GetBufferSize())
+		}
+	}()
+	logger.Panic("read failed: %v", io.EOF, Fields(map[string]interface{}{"order_id": 42}))
+}
+
+func TestPanicErrorFilteredEntry(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+	logger.AddHookFunc(func(entry CoreLogEntry) (CoreLogEntry, error) { return CoreLogEntry{}, nil })
+
+	defer func() {
+		perr, ok := recover().(*PanicError)
+		if !ok {
+			t.Fatal("Expected a *PanicError")
+		}
+		if perr.Error() != "lost 3" || perr.Fields()["shard"] != "a" {
+			t.Errorf("Expected the message and fields without the entry, got %+v", perr.Entry)
+		}
+		if buffer.GetBufferSize() != 0 {
+			t.Error("Expected the filtered entry not to be written")
+		}
+	}()
+	logger.Panic("lost %d", 3, Fields(map[string]interface{}{"shard": "a"}))
+}
+
+func TestPanicErrorFilteredTemplate(t *testing.T) {
+	logger, _ := newTestLogger(t, nil)
+	logger.AddHookFunc(func(entry CoreLogEntry) (CoreLogEntry, error) { return CoreLogEntry{}, nil })
+
+	defer func() {
+		perr, ok := recover().(*PanicError)
+		if !ok {
+			t.Fatal("Expected a *PanicError")
+		}
+		if perr.Error() != "lost 3 on a" || perr.Entry.MessageTemplate != "lost {count} on {shard}" {
+			t.Errorf("Expected the template rendered like a logged message, got %+v", perr.Entry)
+		}
+		if perr.Fields()["count"] != 3 {
+			t.Errorf("Expected the template fields, got %v", perr.Fields())
+		}
+	}()
+	logger.Panic("lost {count} on {shard}", 3, "a")
+}
+
+func TestRecoverAndLogIncludesPanicFields(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+
+	func() {
+		defer RecoverAndLog(logger)
+		logger.Panic("invariant broken", Fields(map[string]interface{}{"invariant": "balance"}))
+	}()
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 2 || entries[1].Context["invariant"] != "balance" {
+		t.Errorf("Expected the recovered entry to carry the panic fields, got %+v", entries)
+	}
+}
+
+func TestTPanicError(t *testing.T) {
+	config := DefaultLoggerConfig
+	config.EnableConsole = false
+	logger := NewLocalizedLogger(config, Locale{Language: "en", Region: "US"})
+	defer logger.Close()
+
+	defer func() {
+		var perr *PanicError
+		err, _ := recover().(error)
+		if !errors.As(err, &perr) {
+			t.Fatalf("Expected a *PanicError, got %v", err)
+		}
+		if perr.Entry.Localized == nil || perr.Entry.Localized.Key != "task_failed" {
+			t.Errorf("Expected the logged entry, got %+v", perr.Entry)
+		}
+		if !errors.Is(err, io.EOF) {
+			t.Error("Expected the panic to wrap the argument error")
+		}
+	}()
+	logger.TPanic("task_failed", "sync", io.EOF)
+}
@@ -0,0 +1,577 @@
+package pim
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyncPolicy selects when a persistent queue fsyncs its segment files
+type SyncPolicy string
+
+const (
+	SyncAlways   SyncPolicy = "always"   // After every entry
+	SyncInterval SyncPolicy = "interval" // At most once per SyncInterval (default)
+	SyncNever    SyncPolicy = "never"    // Leave it to the operating system
+)
+
+// PersistentQueueConfig configures the disk-backed queue of an async logger
+type PersistentQueueConfig struct {
+	Dir          string        `json:"dir"`           // Directory for segment files, created if missing
+	SegmentSize  int64         `json:"segment_size"`  // Size at which a new segment file is started (default: 16 MiB)
+	MaxSize      int64         `json:"max_size"`      // Total size above which new entries are not persisted (default: 256 MiB)
+	Sync         SyncPolicy    `json:"sync"`          // Default: SyncInterval
+	SyncInterval time.Duration `json:"sync_interval"` // Default: 1 second
+}
+
+// PersistentQueueStats describes the disk-backed queue of a logger
+type PersistentQueueStats struct {
+	Size     int64  `json:"size"`     // Bytes in segment files
+	Segments int    `json:"segments"` // Segment files on disk
+	Pending  int    `json:"pending"`  // Entries left by a previous run, awaiting ReplayPersistentQueue
+	Skipped  uint64 `json:"skipped"`  // Entries not persisted because MaxSize was reached or a write failed
+}
+
+// queueRecordHeader is the size of a record header: payload length and CRC-32
+const queueRecordHeader = 8
+
+// checkpointFile names the file holding the acknowledged queue position
+const checkpointFile = "checkpoint"
+
+// queuePosition is a position in the queue: a segment and an offset in it
+type queuePosition struct {
+	Segment uint64 `json:"segment"`
+	Offset  int64  `json:"offset"`
+}
+
+// before reports whether p comes before other
+func (p queuePosition) before(other queuePosition) bool {
+	return p.Segment < other.Segment || (p.Segment == other.Segment && p.Offset < other.Offset)
+}
+
+// persistentQueue is a write-ahead log of async entries. Each entry is
+// appended as a record (big-endian payload length, CRC-32 of the payload,
+// JSON payload) to the newest segment file before it is queued in memory.
+// The position up to which entries were written is saved in the checkpoint
+// file, and segments before it are removed.
+type persistentQueue struct {
+	config PersistentQueueConfig
+
+	mu             sync.Mutex
+	file           *os.File
+	head           queuePosition // Where the next record is appended
+	acked          queuePosition // Records before it were written
+	recovered      queuePosition // End of the records left by the previous run
+	done           queuePosition // Records of this run before it were written, checkpointed once replayed
+	replayed       bool          // Records left by the previous run were replayed
+	replayMu       sync.Mutex    // Serializes replays
+	pending        int           // Records left by the previous run
+	inflight       []queuedRecord
+	firstSeq       uint64 // Sequence number of inflight[0]
+	size           int64
+	segments       int
+	skipped        uint64
+	full           bool // MaxSize was reached; reported once until entries fit again
+	lastSync       time.Time
+	lastCheckpoint time.Time
+}
+
+// queuedRecord is a record appended in this run and queued in memory
+type queuedRecord struct {
+	end     queuePosition // Position after the record
+	written bool          // The entry was written or dropped by the worker
+}
+
+// openPersistentQueue opens or creates the queue in config.Dir. A record
+// torn by a crash at the end of the last segment is truncated away.
+func openPersistentQueue(config PersistentQueueConfig) (*persistentQueue, error) {
+	if config.Dir == "" {
+		return nil, fmt.Errorf("persistent queue directory is required")
+	}
+	if config.SegmentSize <= 0 {
+		config.SegmentSize = 16 << 20
+	}
+	if config.MaxSize <= 0 {
+		config.MaxSize = 256 << 20
+	}
+	if config.Sync == "" {
+		config.Sync = SyncInterval
+	}
+	if config.SyncInterval <= 0 {
+		config.SyncInterval = time.Second
+	}
+	if err := os.MkdirAll(config.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create persistent queue directory: %w", err)
+	}
+
+	q := &persistentQueue{config: config, lastSync: time.Now(), firstSeq: 1}
+	if data, err := os.ReadFile(filepath.Join(config.Dir, checkpointFile)); err == nil {
+		if err := json.Unmarshal(data, &q.acked); err != nil {
+			return nil, fmt.Errorf("invalid persistent queue checkpoint: %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read persistent queue checkpoint: %w", err)
+	}
+
+	ids, err := q.segmentIDs()
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		info, err := os.Stat(q.segmentPath(id))
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat queue segment: %w", err)
+		}
+		q.size += info.Size()
+	}
+	q.segments = len(ids)
+
+	// Append to the last segment after its last complete record
+	q.head = queuePosition{Segment: q.acked.Segment}
+	if len(ids) > 0 && ids[len(ids)-1] >= q.head.Segment {
+		last := ids[len(ids)-1]
+		end, err := validSegmentEnd(q.segmentPath(last))
+		if err != nil {
+			return nil, err
+		}
+		q.head = queuePosition{Segment: last, Offset: end}
+	}
+	if q.head.before(q.acked) {
+		// The checkpointed segment is gone or shorter than recorded
+		q.acked = q.head
+	}
+	if err := q.openHead(); err != nil {
+		return nil, err
+	}
+
+	q.recovered = q.head
+	err = q.read(q.acked, q.recovered, func(CoreLogEntry) error {
+		q.pending++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	q.replayed = q.pending == 0
+	return q, nil
+}
+
+// append writes entry to the log, then calls enqueue with the queue
+// locked, so an acknowledgement never covers a record that is persisted
+// but not yet queued in memory. Persisted entries are passed to enqueue
+// with their sequence number, to be reported to written once written.
+// enqueue reports whether it queued the entry and, if not, whether its
+// lane was full; append then returns the entry and true, and the caller
+// writes it after the queue is unlocked.
+func (q *persistentQueue) append(entry CoreLogEntry, enqueue func(CoreLogEntry) (queued, full bool)) (CoreLogEntry, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	switch err := q.write(entry); {
+	case err == nil:
+		q.full = false
+		entry.persistSeq = q.firstSeq + uint64(len(q.inflight))
+		q.inflight = append(q.inflight, queuedRecord{end: q.head})
+	case errors.Is(err, errQueueFull):
+		q.skipped++
+		if !q.full {
+			q.full = true
+			fmt.Fprintf(os.Stderr, "Persistent queue reached its maximum size of %d bytes; entries are not persisted until it is acknowledged\n", q.config.MaxSize)
+		}
+	default:
+		q.skipped++
+		fmt.Fprintf(os.Stderr, "Failed to persist log entry: %v\n", err)
+	}
+	queued, full := enqueue(entry)
+	if !queued && !full && entry.persistSeq != 0 {
+		// Dropped
+		q.inflight[entry.persistSeq-q.firstSeq].written = true
+	}
+	return entry, full
+}
+
+// written records that the entry with sequence number seq was written or
+// dropped, so the checkpoint can move past it
+func (q *persistentQueue) written(seq uint64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if seq < q.firstSeq || seq-q.firstSeq >= uint64(len(q.inflight)) {
+		return
+	}
+	q.inflight[seq-q.firstSeq].written = true
+}
+
+// errQueueFull is returned by write when MaxSize is reached
+var errQueueFull = errors.New("persistent queue is full")
+
+// write appends one record; the caller holds q.mu
+func (q *persistentQueue) write(entry CoreLogEntry) error {
+	payload, err := json.Marshal(withErrorChains(entry))
+	if err != nil {
+		return fmt.Errorf("failed to encode entry: %w", err)
+	}
+	size := int64(queueRecordHeader + len(payload))
+	if q.file == nil {
+		return fmt.Errorf("persistent queue is closed")
+	}
+	if q.size+size > q.config.MaxSize {
+		return errQueueFull
+	}
+
+	if q.head.Offset > 0 && q.head.Offset+size > q.config.SegmentSize {
+		if err := q.rotate(); err != nil {
+			return err
+		}
+	}
+
+	record := make([]byte, size)
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	copy(record[queueRecordHeader:], payload)
+	if _, err := q.file.Write(record); err != nil {
+		return fmt.Errorf("failed to write queue segment: %w", err)
+	}
+	q.head.Offset += size
+	q.size += size
+
+	switch q.config.Sync {
+	case SyncAlways:
+		return q.file.Sync()
+	case SyncInterval:
+		if time.Since(q.lastSync) >= q.config.SyncInterval {
+			q.lastSync = time.Now()
+			return q.file.Sync()
+		}
+	}
+	return nil
+}
+
+// rotate starts a new segment; the caller holds q.mu
+func (q *persistentQueue) rotate() error {
+	if q.config.Sync != SyncNever {
+		q.file.Sync()
+	}
+	q.file.Close()
+	q.head = queuePosition{Segment: q.head.Segment + 1}
+	return q.openHead()
+}
+
+// openHead opens the head segment for appending
+func (q *persistentQueue) openHead() error {
+	path := q.segmentPath(q.head.Segment)
+	_, statErr := os.Stat(path)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open queue segment: %w", err)
+	}
+	if err := file.Truncate(q.head.Offset); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to truncate queue segment: %w", err)
+	}
+	if _, err := file.Seek(q.head.Offset, io.SeekStart); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to seek queue segment: %w", err)
+	}
+	if errors.Is(statErr, os.ErrNotExist) {
+		q.segments++
+	}
+	q.file = file
+	return nil
+}
+
+// ack checkpoints the position up to which every record was written. Entries
+// are written out of order by the priority lane, so it is the end of the
+// longest run of written records. Unless force is set, it checkpoints at
+// most once per SyncInterval, so steady load doesn't rewrite the checkpoint
+// after every batch. While entries of the previous run await replay, the
+// position is only remembered, and replay checkpoints it.
+func (q *persistentQueue) ack(force bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.replayed && !force && time.Since(q.lastCheckpoint) < q.config.SyncInterval {
+		return
+	}
+
+	n := 0
+	for n < len(q.inflight) && q.inflight[n].written {
+		n++
+	}
+	if n == 0 {
+		return
+	}
+	position := q.inflight[n-1].end
+	q.inflight = q.inflight[n:]
+	q.firstSeq += uint64(n)
+	q.done = position
+	if !q.replayed || !q.acked.before(position) {
+		return
+	}
+	q.lastCheckpoint = time.Now()
+	if err := q.checkpoint(position); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to checkpoint persistent queue: %v\n", err)
+	}
+}
+
+// checkpoint saves position as acknowledged and removes the segments
+// before it; the caller holds q.mu
+func (q *persistentQueue) checkpoint(position queuePosition) error {
+	data, err := json.Marshal(position)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(q.config.Dir, checkpointFile), data); err != nil {
+		return err
+	}
+	q.acked = position
+
+	ids, err := q.segmentIDs()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if id >= position.Segment {
+			break
+		}
+		path := q.segmentPath(id)
+		if info, err := os.Stat(path); err == nil {
+			q.size -= info.Size()
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove queue segment: %w", err)
+		}
+		q.segments--
+	}
+	return nil
+}
+
+// replay calls write for each entry left by the previous run and then
+// acknowledges them together with the entries of this run written so far
+func (q *persistentQueue) replay(write func(CoreLogEntry)) (int, error) {
+	q.replayMu.Lock()
+	defer q.replayMu.Unlock()
+
+	q.mu.Lock()
+	if q.replayed {
+		q.mu.Unlock()
+		return 0, nil
+	}
+	from, to := q.acked, q.recovered
+	q.mu.Unlock()
+
+	replayed := 0
+	err := q.read(from, to, func(entry CoreLogEntry) error {
+		write(entry)
+		replayed++
+		return nil
+	})
+	if err != nil {
+		return replayed, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.replayed = true
+	q.pending = 0
+	if to.before(q.done) {
+		to = q.done
+	}
+	if q.acked.before(to) {
+		if err := q.checkpoint(to); err != nil {
+			return replayed, err
+		}
+	}
+	return replayed, nil
+}
+
+// read decodes the records between from and to
+func (q *persistentQueue) read(from, to queuePosition, fn func(CoreLogEntry) error) error {
+	for segment := from.Segment; segment <= to.Segment; segment++ {
+		file, err := os.Open(q.segmentPath(segment))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed to open queue segment: %w", err)
+		}
+
+		offset := int64(0)
+		if segment == from.Segment {
+			offset = from.Offset
+		}
+		end := int64(-1)
+		if segment == to.Segment {
+			end = to.Offset
+		}
+		err = readSegment(file, offset, end, fn)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSegment decodes records of file from offset up to end (or the last
+// complete record if end is negative)
+func readSegment(file *os.File, offset, end int64, fn func(CoreLogEntry) error) error {
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat queue segment: %w", err)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek queue segment: %w", err)
+	}
+	reader := bufio.NewReader(file)
+	for end < 0 || offset < end {
+		payload, err := readRecord(reader, info.Size()-offset)
+		if err != nil {
+			if end < 0 {
+				return nil // Torn or missing record at the end of a segment
+			}
+			return fmt.Errorf("corrupt queue segment %s at offset %d: %w", file.Name(), offset, err)
+		}
+		offset += int64(queueRecordHeader + len(payload))
+
+		var entry CoreLogEntry
+		if err := json.Unmarshal(payload, &entry); err != nil {
+			return fmt.Errorf("corrupt queue entry in %s: %w", file.Name(), err)
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readRecord reads one record of at most remaining bytes, the rest of the
+// segment file, and verifies its checksum. A length beyond the end of the
+// file is corruption, so it is rejected before the payload is allocated.
+func readRecord(reader io.Reader, remaining int64) ([]byte, error) {
+	var header [queueRecordHeader]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[0:4])
+	if int64(length) > remaining-queueRecordHeader {
+		return nil, fmt.Errorf("record length %d exceeds the segment", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, fmt.Errorf("checksum mismatch")
+	}
+	return payload, nil
+}
+
+// validSegmentEnd returns the offset after the last complete record of the
+// segment at path
+func validSegmentEnd(path string) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open queue segment: %w", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat queue segment: %w", err)
+	}
+
+	reader := bufio.NewReader(file)
+	end := int64(0)
+	for {
+		payload, err := readRecord(reader, info.Size()-end)
+		if err != nil {
+			return end, nil
+		}
+		end += int64(queueRecordHeader + len(payload))
+	}
+}
+
+// segmentIDs returns the IDs of the segment files in ascending order
+func (q *persistentQueue) segmentIDs() ([]uint64, error) {
+	names, err := filepath.Glob(filepath.Join(q.config.Dir, "*.seg"))
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint64, 0, len(names))
+	for _, name := range names {
+		id, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(name), ".seg"), 10, 64)
+		if err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+// segmentPath returns the path of segment id
+func (q *persistentQueue) segmentPath(id uint64) string {
+	return filepath.Join(q.config.Dir, fmt.Sprintf("%020d.seg", id))
+}
+
+// sync flushes the head segment to disk unless the policy is SyncNever
+func (q *persistentQueue) sync() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.file != nil && q.config.Sync != SyncNever {
+		q.file.Sync()
+		q.lastSync = time.Now()
+	}
+}
+
+// close syncs and closes the head segment
+func (q *persistentQueue) close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.file == nil {
+		return nil
+	}
+	if q.config.Sync != SyncNever {
+		q.file.Sync()
+	}
+	err := q.file.Close()
+	q.file = nil
+	return err
+}
+
+// stats returns the queue statistics
+func (q *persistentQueue) stats() PersistentQueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return PersistentQueueStats{Size: q.size, Segments: q.segments, Pending: q.pending, Skipped: q.skipped}
+}
+
+// ReplayPersistentQueue writes the entries that a previous run persisted
+// but did not write before it stopped, e.g. because it crashed. The async
+// worker replays them before it writes the first entry of this run; call
+// it at startup after adding the writers to replay them earlier. Until
+// then nothing new is acknowledged, so no entry is lost. Entries are delivered at least once: those written
+// shortly before a crash may be written again. It returns the number of
+// entries replayed, 0 without a persistent queue.
+func (l *LoggerCore) ReplayPersistentQueue() (int, error) {
+	if l.persistQueue == nil {
+		return 0, nil
+	}
+	return l.persistQueue.replay(l.writeToWriters)
+}
+
+// PersistentQueueStats returns the statistics of the disk-backed queue;
+// ok is false without one
+func (l *LoggerCore) PersistentQueueStats() (stats PersistentQueueStats, ok bool) {
+	if l.persistQueue == nil {
+		return PersistentQueueStats{}, false
+	}
+	return l.persistQueue.stats(), true
+}
@@ -0,0 +1,263 @@
+package pim
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newPersistentLogger(t *testing.T, queue PersistentQueueConfig) *LoggerCore {
+	t.Helper()
+	config := DefaultLoggerConfig
+	config.EnableConsole = false
+	config.Async = true
+	config.BufferSize = 16
+	config.FlushInterval = time.Hour
+	config.PersistentQueue = &queue
+	logger := NewLoggerCore(config)
+	if logger.persistQueue == nil {
+		t.Fatal("Expected the persistent queue to be open")
+	}
+	return logger
+}
+
+func TestPersistentQueueReplaysAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	queue := PersistentQueueConfig{Dir: dir, Sync: SyncAlways}
+
+	writer := newGatedWriter()
+	logger := newPersistentLogger(t, queue)
+	logger.AddWriter(writer)
+	logger.Info("first")
+	<-writer.started // The worker is now blocked inside Write
+	logger.Info("second")
+	logger.Info("third")
+
+	// Simulate a crash: the process stops before anything is acknowledged
+	logger.persistQueue.close()
+
+	restarted := newPersistentLogger(t, queue)
+	defer restarted.Close()
+	if stats, _ := restarted.PersistentQueueStats(); stats.Pending != 3 {
+		t.Errorf("Expected 3 pending entries, got %+v", stats)
+	}
+
+	buffer := NewBufferWriter(restarted.config, 10)
+	restarted.AddWriter(buffer)
+	replayed, err := restarted.ReplayPersistentQueue()
+	if err != nil {
+		t.Fatalf("ReplayPersistentQueue failed: %v", err)
+	}
+	if replayed != 3 {
+		t.Fatalf("Expected 3 replayed entries, got %d", replayed)
+	}
+	var messages []string
+	for _, entry := range buffer.GetBuffer() {
+		messages = append(messages, entry.Message)
+	}
+	if want := []string{"first", "second", "third"}; !reflect.DeepEqual(messages, want) {
+		t.Errorf("Expected %v, got %v", want, messages)
+	}
+	if replayed, _ := restarted.ReplayPersistentQueue(); replayed != 0 {
+		t.Errorf("Expected a second replay to write nothing, got %d", replayed)
+	}
+
+	// Let the crashed logger finish before the directory is removed
+	close(writer.gate)
+	logger.Close()
+}
+
+func TestPersistentQueueReplaysBeforeNewEntries(t *testing.T) {
+	dir := t.TempDir()
+	queue := PersistentQueueConfig{Dir: dir, Sync: SyncAlways}
+
+	writer := newGatedWriter()
+	logger := newPersistentLogger(t, queue)
+	logger.AddWriter(writer)
+	logger.Info("first")
+	<-writer.started
+	logger.Info("second")
+	logger.persistQueue.close()
+
+	restarted := newPersistentLogger(t, queue)
+	buffer := NewBufferWriter(restarted.config, 10)
+	restarted.AddWriter(buffer)
+	defer restarted.Close()
+	restarted.Info("third")
+	restarted.Flush()
+
+	var messages []string
+	for _, entry := range buffer.GetBuffer() {
+		messages = append(messages, entry.Message)
+	}
+	if want := []string{"first", "second", "third"}; !reflect.DeepEqual(messages, want) {
+		t.Errorf("Expected the previous run replayed first, got %v", messages)
+	}
+
+	close(writer.gate)
+	logger.Close()
+}
+
+func TestPersistentQueueReleasesWrittenEntriesBeforeReplay(t *testing.T) {
+	queue, err := openPersistentQueue(PersistentQueueConfig{Dir: t.TempDir(), Sync: SyncNever})
+	if err != nil {
+		t.Fatalf("openPersistentQueue failed: %v", err)
+	}
+	defer queue.close()
+	queue.replayed = false
+
+	for i := 0; i < 5; i++ {
+		entry, _ := queue.append(CoreLogEntry{Message: "entry"}, func(CoreLogEntry) (bool, bool) { return true, false })
+		queue.written(entry.persistSeq)
+	}
+	queue.ack(true)
+	if len(queue.inflight) != 0 || queue.acked == queue.head {
+		t.Fatalf("Expected written entries released but not checkpointed, got %d in flight", len(queue.inflight))
+	}
+
+	if _, err := queue.replay(func(CoreLogEntry) {}); err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if queue.acked != queue.head {
+		t.Errorf("Expected replay to checkpoint the written entries, got %+v of %+v", queue.acked, queue.head)
+	}
+}
+
+func TestPersistentQueueAcknowledgesWrittenEntries(t *testing.T) {
+	dir := t.TempDir()
+	queue := PersistentQueueConfig{Dir: dir, SegmentSize: 512}
+
+	logger := newPersistentLogger(t, queue)
+	buffer := NewBufferWriter(logger.config, 100)
+	logger.AddWriter(buffer)
+	for i := 0; i < 20; i++ {
+		logger.Info("entry", i)
+	}
+	logger.Flush()
+	if size := buffer.GetBufferSize(); size != 20 {
+		t.Fatalf("Expected 20 written entries, got %d", size)
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	restarted := newPersistentLogger(t, queue)
+	defer restarted.Close()
+	stats, _ := restarted.PersistentQueueStats()
+	if stats.Pending != 0 {
+		t.Errorf("Expected no pending entries, got %d", stats.Pending)
+	}
+	if stats.Segments != 1 {
+		t.Errorf("Expected acknowledged segments to be removed, got %d segments", stats.Segments)
+	}
+}
+
+func TestPersistentQueueTruncatesTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	config := PersistentQueueConfig{Dir: dir, Sync: SyncNever}
+
+	queue, err := openPersistentQueue(config)
+	if err != nil {
+		t.Fatalf("openPersistentQueue failed: %v", err)
+	}
+	queue.append(CoreLogEntry{Message: "complete"}, func(CoreLogEntry) (bool, bool) { return true, false })
+	queue.close()
+
+	// A record cut short by a crash mid-write
+	segment := filepath.Join(dir, "00000000000000000000.seg")
+	file, err := os.OpenFile(segment, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte{0, 0, 1, 0, 9, 9})
+	file.Close()
+
+	queue, err = openPersistentQueue(config)
+	if err != nil {
+		t.Fatalf("openPersistentQueue failed: %v", err)
+	}
+	queue.append(CoreLogEntry{Message: "after restart"}, func(CoreLogEntry) (bool, bool) { return true, false })
+
+	var messages []string
+	_, err = queue.replay(func(entry CoreLogEntry) { messages = append(messages, entry.Message) })
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if want := []string{"complete"}; !reflect.DeepEqual(messages, want) {
+		t.Errorf("Expected %v, got %v", want, messages)
+	}
+
+	// The entry appended after the restart follows the complete record
+	var rest []string
+	err = queue.read(queue.acked, queue.head, func(entry CoreLogEntry) error {
+		rest = append(rest, entry.Message)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if want := []string{"after restart"}; !reflect.DeepEqual(rest, want) {
+		t.Errorf("Expected %v, got %v", want, rest)
+	}
+	queue.close()
+}
+
+func TestPersistentQueueMaxSize(t *testing.T) {
+	queue, err := openPersistentQueue(PersistentQueueConfig{Dir: t.TempDir(), MaxSize: 300})
+	if err != nil {
+		t.Fatalf("openPersistentQueue failed: %v", err)
+	}
+	defer queue.close()
+
+	queued := 0
+	for i := 0; i < 10; i++ {
+		queue.append(CoreLogEntry{Message: "entry"}, func(CoreLogEntry) (bool, bool) { queued++; return true, false })
+	}
+	stats := queue.stats()
+	if queued != 10 {
+		t.Errorf("Expected every entry to be queued in memory, got %d", queued)
+	}
+	if stats.Skipped == 0 || stats.Size > 300 {
+		t.Errorf("Expected entries beyond MaxSize to be skipped, got %+v", stats)
+	}
+}
+
+func TestPersistentQueueAcknowledgesUnderLoad(t *testing.T) {
+	queue, err := openPersistentQueue(PersistentQueueConfig{Dir: t.TempDir(), Sync: SyncNever})
+	if err != nil {
+		t.Fatalf("openPersistentQueue failed: %v", err)
+	}
+	defer queue.close()
+	queue.replayed = true
+
+	var entries []CoreLogEntry
+	for _, message := range []string{"first", "second", "third"} {
+		queue.append(CoreLogEntry{Message: message}, func(entry CoreLogEntry) (bool, bool) { entries = append(entries, entry); return true, false })
+	}
+	first := queue.inflight[0].end
+
+	// The third entry was written first by the priority lane
+	queue.written(entries[2].persistSeq)
+	queue.written(entries[0].persistSeq)
+	queue.ack(true)
+	if queue.acked != first {
+		t.Errorf("Expected the checkpoint after the first entry, got %+v", queue.acked)
+	}
+
+	queue.written(entries[1].persistSeq)
+	queue.ack(true)
+	if queue.acked != queue.head || len(queue.inflight) != 0 {
+		t.Errorf("Expected every entry to be acknowledged, got %+v of %+v", queue.acked, queue.head)
+	}
+}
+
+func TestPersistentQueueRejectsOversizedRecord(t *testing.T) {
+	// A corrupt header claiming a 4 GiB payload
+	record := []byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0, '{', '}'}
+	if _, err := readRecord(bytes.NewReader(record), int64(len(record))); err == nil {
+		t.Error("Expected a length beyond the segment to be rejected")
+	}
+}
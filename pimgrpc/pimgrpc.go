@@ -0,0 +1,316 @@
+// Package pimgrpc provides gRPC client and server interceptors that log
+// calls through pim and carry correlation IDs across service boundaries.
+package pimgrpc
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/refactorroom/pim"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Config configures the interceptors
+type Config struct {
+	// CorrelationMetadata maps metadata keys to context fields, e.g.
+	// "x-trace-id" -> "trace_id". Servers extract these fields from incoming
+	// metadata and clients send them from the call context. Defaults to
+	// DefaultCorrelationMetadata.
+	CorrelationMetadata map[string]string `json:"correlation_metadata"`
+
+	LogCalls bool         `json:"log_calls"` // Log one entry per completed call
+	Level    pim.LogLevel `json:"level"`     // Level for successful calls (default: info; client errors log as warnings, server errors as errors)
+}
+
+// DefaultCorrelationMetadata are the metadata keys mapped to context fields by default
+var DefaultCorrelationMetadata = map[string]string{
+	"x-request-id":        "request_id",
+	"x-trace-id":          "trace_id",
+	"x-span-id":           "span_id",
+	"x-session-id":        "session_id",
+	"x-correlation-id":    "correlation_id",
+	"x-session-replay-id": "session_replay_id",
+	"x-client-event-id":   "client_event_id",
+}
+
+// DefaultConfig provides sensible defaults
+var DefaultConfig = Config{
+	CorrelationMetadata: DefaultCorrelationMetadata,
+	LogCalls:            true,
+	Level:               pim.InfoLevel,
+}
+
+// withDefaults fills unset config fields
+func withDefaults(config Config) Config {
+	if config.CorrelationMetadata == nil {
+		config.CorrelationMetadata = DefaultCorrelationMetadata
+	}
+	// The zero level is PanicLevel, which would hide successful calls
+	if config.Level == pim.PanicLevel {
+		config.Level = pim.InfoLevel
+	}
+	return config
+}
+
+// UnaryServerInterceptor extracts correlation fields from incoming metadata,
+// attaches a request-scoped logger to the context (see pim.FromContext) and
+// optionally logs each completed call
+func UnaryServerInterceptor(logger *pim.LoggerCore, config Config) grpc.UnaryServerInterceptor {
+	config = withDefaults(config)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		ctx, requestLogger := serverContext(ctx, logger, config)
+
+		resp, err := handler(ctx, req)
+
+		if config.LogCalls {
+			logCall(requestLogger, config, "gRPC request", info.FullMethod, peerAddr(ctx), start, err)
+		}
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor
+func StreamServerInterceptor(logger *pim.LoggerCore, config Config) grpc.StreamServerInterceptor {
+	config = withDefaults(config)
+
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx, requestLogger := serverContext(stream.Context(), logger, config)
+
+		err := handler(srv, &serverStream{ServerStream: stream, ctx: ctx})
+
+		if config.LogCalls {
+			logCall(requestLogger, config, "gRPC stream", info.FullMethod, peerAddr(ctx), start, err)
+		}
+		return err
+	}
+}
+
+// serverStream overrides the context of a server stream
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context carrying the request-scoped logger
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// serverContext builds the request-scoped logger and context for an incoming call
+func serverContext(ctx context.Context, logger *pim.LoggerCore, config Config) (context.Context, *pim.LoggerCore) {
+	fields := make(map[string]interface{})
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for key, field := range config.CorrelationMetadata {
+			if values := md.Get(key); len(values) > 0 && values[0] != "" {
+				fields[field] = values[0]
+			}
+		}
+		// W3C trace context, unless the IDs were sent explicitly
+		if values := md.Get("traceparent"); len(values) > 0 {
+			if traceID, spanID, ok := parseTraceparent(values[0]); ok {
+				if _, exists := fields["trace_id"]; !exists {
+					fields["trace_id"] = traceID
+				}
+				if _, exists := fields["span_id"]; !exists {
+					fields["span_id"] = spanID
+				}
+			}
+		}
+	}
+
+	requestLogger := logger
+	if len(fields) > 0 {
+		requestLogger = logger.WithContext(fields)
+	}
+	return pim.NewContext(pim.ContextWithFields(ctx, fields), requestLogger), requestLogger
+}
+
+// parseTraceparent extracts trace and span IDs from a W3C traceparent header
+func parseTraceparent(value string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+// UnaryClientInterceptor sends the correlation fields of the call context as
+// outgoing metadata and optionally logs each completed call. Calls made with
+// a context from the server interceptors are logged through its request logger.
+func UnaryClientInterceptor(logger *pim.LoggerCore, config Config) grpc.UnaryClientInterceptor {
+	config = withDefaults(config)
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		ctx = outgoingContext(ctx, config)
+
+		var p peer.Peer
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Peer(&p))...)
+
+		if config.LogCalls {
+			logCall(callLogger(ctx, logger), config, "gRPC client request", method, addrString(&p), start, err)
+		}
+		return err
+	}
+}
+
+// StreamClientInterceptor is the streaming counterpart of UnaryClientInterceptor.
+// The call is logged when the stream ends or fails to open.
+func StreamClientInterceptor(logger *pim.LoggerCore, config Config) grpc.StreamClientInterceptor {
+	config = withDefaults(config)
+
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		ctx = outgoingContext(ctx, config)
+
+		p := &peer.Peer{}
+		stream, err := streamer(ctx, desc, cc, method, append(opts, grpc.Peer(p))...)
+		if !config.LogCalls {
+			return stream, err
+		}
+
+		logger := callLogger(ctx, logger)
+		if err != nil {
+			logCall(logger, config, "gRPC client stream", method, addrString(p), start, err)
+			return stream, err
+		}
+
+		return &clientStream{ClientStream: stream, done: func(err error) {
+			logCall(logger, config, "gRPC client stream", method, addrString(p), start, err)
+		}}, nil
+	}
+}
+
+// clientStream logs the call once the stream ends
+type clientStream struct {
+	grpc.ClientStream
+	done func(error)
+	once sync.Once
+}
+
+// RecvMsg implements grpc.ClientStream, logging when the stream ends
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.finish(err)
+	}
+	return err
+}
+
+// SendMsg implements grpc.ClientStream, logging when the stream fails
+func (s *clientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err != nil && err != io.EOF {
+		s.finish(err)
+	}
+	return err
+}
+
+// finish logs the call once; io.EOF marks a successful end of stream
+func (s *clientStream) finish(err error) {
+	if err == io.EOF {
+		err = nil
+	}
+	s.once.Do(func() { s.done(err) })
+}
+
+// outgoingContext appends the correlation fields of ctx to its outgoing metadata
+func outgoingContext(ctx context.Context, config Config) context.Context {
+	fields := pim.ContextFields(ctx)
+	if len(fields) == 0 {
+		return ctx
+	}
+
+	var pairs []string
+	for key, field := range config.CorrelationMetadata {
+		if value, ok := fields[field].(string); ok && value != "" {
+			pairs = append(pairs, key, value)
+		}
+	}
+	if len(pairs) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, pairs...)
+}
+
+// callLogger returns the request-scoped logger of ctx, or logger
+func callLogger(ctx context.Context, logger *pim.LoggerCore) *pim.LoggerCore {
+	if requestLogger := pim.FromContext(ctx); requestLogger != nil {
+		return requestLogger
+	}
+	return logger
+}
+
+// peerAddr returns the address of the remote peer of an incoming call
+func peerAddr(ctx context.Context) string {
+	p, _ := peer.FromContext(ctx)
+	return addrString(p)
+}
+
+// addrString returns the peer address, or "" if unknown
+func addrString(p *peer.Peer) string {
+	if p == nil || p.Addr == nil {
+		return ""
+	}
+	return p.Addr.String()
+}
+
+// logCall logs a completed call with its status code and duration
+func logCall(logger *pim.LoggerCore, config Config, message, method, peerAddress string, start time.Time, err error) {
+	code := status.Code(err)
+	level := levelForCode(code, config.Level)
+
+	fields := map[string]interface{}{
+		"method":      method,
+		"code":        code.String(),
+		"duration_ms": time.Since(start).Milliseconds(),
+	}
+	if peerAddress != "" {
+		fields["peer"] = peerAddress
+	}
+	if err != nil {
+		fields["error"] = status.Convert(err).Message()
+	}
+
+	logger.LogWithContext(level, prefixForLevel(level), message, fields)
+}
+
+// levelForCode maps a status code to a log level: server-side failures are
+// errors, caller mistakes are warnings and everything else uses level
+func levelForCode(code codes.Code, level pim.LogLevel) pim.LogLevel {
+	switch code {
+	case codes.OK, codes.Canceled:
+		return level
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal,
+		codes.Unavailable, codes.DataLoss:
+		return pim.ErrorLevel
+	default:
+		return pim.WarningLevel
+	}
+}
+
+// prefixForLevel returns the default prefix for level
+func prefixForLevel(level pim.LogLevel) string {
+	switch level {
+	case pim.ErrorLevel:
+		return pim.ErrorPrefix
+	case pim.WarningLevel:
+		return pim.WarningPrefix
+	case pim.DebugLevel:
+		return pim.DebugPrefix
+	case pim.TraceLevel:
+		return pim.TracePrefix
+	default:
+		return pim.InfoPrefix
+	}
+}
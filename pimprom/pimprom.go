@@ -0,0 +1,230 @@
+// Package pimprom exports pim logging pipeline metrics to Prometheus.
+package pimprom
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/refactorroom/pim"
+)
+
+// DefaultLatencyBuckets are the histogram buckets for hook and writer
+// latency, in seconds (10µs to about 1s)
+var DefaultLatencyBuckets = prometheus.ExponentialBuckets(0.00001, 4, 9)
+
+// Config configures the collector
+type Config struct {
+	Namespace      string    `json:"namespace"`       // Metric name prefix (default: pim)
+	LatencyBuckets []float64 `json:"latency_buckets"` // Default: DefaultLatencyBuckets
+}
+
+// Collector is a prometheus.Collector for logging metrics. It counts
+// entries by level and service, records hook and writer latency and
+// reports async buffer usage and dropped entries of the loggers it observes.
+type Collector struct {
+	entries       *prometheus.CounterVec
+	hookLatency   *prometheus.HistogramVec
+	writerLatency *prometheus.HistogramVec
+	writerErrors  *prometheus.CounterVec
+
+	bufferLength   *prometheus.Desc
+	bufferCapacity *prometheus.Desc
+	asyncDropped   *prometheus.Desc
+
+	breakerState    *prometheus.Desc
+	breakerOpens    *prometheus.Desc
+	breakerDiverted *prometheus.Desc
+
+	mu       sync.RWMutex
+	loggers  []*pim.LoggerCore
+	breakers map[string]*pim.CircuitBreakerWriter
+}
+
+// NewCollector creates a collector and starts observing loggers
+func NewCollector(config Config, loggers ...*pim.LoggerCore) *Collector {
+	if config.Namespace == "" {
+		config.Namespace = "pim"
+	}
+	if config.LatencyBuckets == nil {
+		config.LatencyBuckets = DefaultLatencyBuckets
+	}
+
+	c := &Collector{
+		entries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: config.Namespace,
+			Name:      "log_entries_total",
+			Help:      "Log entries written, by level and service.",
+		}, []string{"level", "service"}),
+		hookLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: config.Namespace,
+			Name:      "hook_duration_seconds",
+			Help:      "Time spent in each enhanced hook.",
+			Buckets:   config.LatencyBuckets,
+		}, []string{"hook"}),
+		writerLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: config.Namespace,
+			Name:      "writer_duration_seconds",
+			Help:      "Time spent writing an entry, by writer type.",
+			Buckets:   config.LatencyBuckets,
+		}, []string{"writer"}),
+		writerErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: config.Namespace,
+			Name:      "writer_errors_total",
+			Help:      "Failed writes, by writer type.",
+		}, []string{"writer"}),
+		bufferLength: prometheus.NewDesc(
+			prometheus.BuildFQName(config.Namespace, "", "async_buffer_length"),
+			"Entries queued in the async buffer.",
+			[]string{"service"}, nil),
+		bufferCapacity: prometheus.NewDesc(
+			prometheus.BuildFQName(config.Namespace, "", "async_buffer_capacity"),
+			"Capacity of the async buffer.",
+			[]string{"service"}, nil),
+		asyncDropped: prometheus.NewDesc(
+			prometheus.BuildFQName(config.Namespace, "", "async_dropped_total"),
+			"Entries dropped because the async buffer was full.",
+			[]string{"service"}, nil),
+		breakerState: prometheus.NewDesc(
+			prometheus.BuildFQName(config.Namespace, "", "circuit_breaker_state"),
+			"Circuit breaker state: 0 closed, 1 open, 2 half-open.",
+			[]string{"writer"}, nil),
+		breakerOpens: prometheus.NewDesc(
+			prometheus.BuildFQName(config.Namespace, "", "circuit_breaker_opens_total"),
+			"Times the circuit breaker opened.",
+			[]string{"writer"}, nil),
+		breakerDiverted: prometheus.NewDesc(
+			prometheus.BuildFQName(config.Namespace, "", "circuit_breaker_diverted_total"),
+			"Entries not sent to the writer while the circuit was open.",
+			[]string{"writer"}, nil),
+		breakers: make(map[string]*pim.CircuitBreakerWriter),
+	}
+
+	for _, logger := range loggers {
+		c.Observe(logger)
+	}
+	return c
+}
+
+// Observe starts collecting metrics from logger. It replaces any pipeline
+// observer previously set on the logger.
+func (c *Collector) Observe(logger *pim.LoggerCore) {
+	c.mu.Lock()
+	c.loggers = append(c.loggers, logger)
+	c.mu.Unlock()
+
+	logger.SetPipelineObserver(c)
+}
+
+// ObserveCircuitBreaker reports the state of breaker under the writer label name
+func (c *Collector) ObserveCircuitBreaker(name string, breaker *pim.CircuitBreakerWriter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.breakers[name] = breaker
+}
+
+// EntryLogged implements pim.PipelineObserver
+func (c *Collector) EntryLogged(entry pim.CoreLogEntry) {
+	c.entries.WithLabelValues(entry.Level.Name(), entry.ServiceName).Inc()
+}
+
+// HookProcessed implements pim.PipelineObserver
+func (c *Collector) HookProcessed(hook string, duration time.Duration) {
+	c.hookLatency.WithLabelValues(hook).Observe(duration.Seconds())
+}
+
+// WriterWritten implements pim.PipelineObserver
+func (c *Collector) WriterWritten(writer string, duration time.Duration, err error) {
+	c.writerLatency.WithLabelValues(writer).Observe(duration.Seconds())
+	if err != nil {
+		c.writerErrors.WithLabelValues(writer).Inc()
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.entries.Describe(ch)
+	c.hookLatency.Describe(ch)
+	c.writerLatency.Describe(ch)
+	c.writerErrors.Describe(ch)
+	ch <- c.bufferLength
+	ch <- c.bufferCapacity
+	ch <- c.asyncDropped
+	ch <- c.breakerState
+	ch <- c.breakerOpens
+	ch <- c.breakerDiverted
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.entries.Collect(ch)
+	c.hookLatency.Collect(ch)
+	c.writerLatency.Collect(ch)
+	c.writerErrors.Collect(ch)
+
+	c.mu.RLock()
+	loggers := make([]*pim.LoggerCore, len(c.loggers))
+	copy(loggers, c.loggers)
+	breakers := make(map[string]*pim.CircuitBreakerWriter, len(c.breakers))
+	for name, breaker := range c.breakers {
+		breakers[name] = breaker
+	}
+	c.mu.RUnlock()
+
+	for name, breaker := range breakers {
+		stats := breaker.Stats()
+		ch <- prometheus.MustNewConstMetric(c.breakerState, prometheus.GaugeValue, float64(stats.State), name)
+		ch <- prometheus.MustNewConstMetric(c.breakerOpens, prometheus.CounterValue, float64(stats.Opens), name)
+		ch <- prometheus.MustNewConstMetric(c.breakerDiverted, prometheus.CounterValue, float64(stats.Diverted), name)
+	}
+
+	// Loggers of the same service are summed so label sets stay unique
+	lengths := make(map[string]int)
+	capacities := make(map[string]int)
+	dropped := make(map[string]uint64)
+	for _, logger := range loggers {
+		length, capacity := logger.AsyncBufferStats()
+		if capacity == 0 {
+			continue
+		}
+		lengths[logger.ServiceName()] += length
+		capacities[logger.ServiceName()] += capacity
+		dropped[logger.ServiceName()] += logger.AsyncDropped()
+	}
+	for service, length := range lengths {
+		ch <- prometheus.MustNewConstMetric(c.bufferLength, prometheus.GaugeValue, float64(length), service)
+		ch <- prometheus.MustNewConstMetric(c.bufferCapacity, prometheus.GaugeValue, float64(capacities[service]), service)
+		ch <- prometheus.MustNewConstMetric(c.asyncDropped, prometheus.CounterValue, float64(dropped[service]), service)
+	}
+}
+
+// Handler returns an http.Handler serving the collector's metrics on its
+// own registry, for services that do not already expose /metrics. Scrapers
+// that ask for OpenMetrics get it; others get the Prometheus text format.
+func Handler(c *Collector) http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{EnableOpenMetrics: true})
+}
+
+// AdminHandler returns the admin handler of logger (see pim.NewAdminHandler)
+// with the collector's metrics added at /metrics, so one mount gives both
+// control and observability of the logging pipeline. The logger is observed
+// by the collector if it is not already.
+func AdminHandler(c *Collector, logger *pim.LoggerCore) *pim.AdminHandler {
+	c.mu.RLock()
+	observed := false
+	for _, l := range c.loggers {
+		observed = observed || l == logger
+	}
+	c.mu.RUnlock()
+	if !observed {
+		c.Observe(logger)
+	}
+
+	handler := pim.NewAdminHandler(logger)
+	handler.Handle("GET /metrics", Handler(c))
+	return handler
+}
@@ -0,0 +1,129 @@
+package pimprom
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/refactorroom/pim"
+)
+
+// failingWriter is a writer whose writes always fail
+type failingWriter struct{}
+
+func (failingWriter) Write(pim.CoreLogEntry) error { return errors.New("disk full") }
+func (failingWriter) Close() error                 { return nil }
+func (failingWriter) Flush() error                 { return nil }
+
+func TestCollectorCountsEntriesAndLatency(t *testing.T) {
+	logger := pim.NewLoggerCore(pim.LoggerConfig{Level: pim.InfoLevel, ServiceName: "billing"})
+	logger.AddWriter(pim.NewBufferWriter(pim.LoggerConfig{}, 10))
+	logger.AddWriter(failingWriter{})
+	logger.AddEnhancedHook(pim.NewRequestIDEnrichHook())
+
+	collector := NewCollector(Config{}, logger)
+
+	logger.Info("one")
+	logger.Info("two")
+	logger.Warning("three")
+	logger.Debug("filtered by level")
+
+	if got := testutil.ToFloat64(collector.entries.WithLabelValues("info", "billing")); got != 2 {
+		t.Errorf("Expected 2 info entries, got %v", got)
+	}
+	if got := testutil.ToFloat64(collector.entries.WithLabelValues("warning", "billing")); got != 1 {
+		t.Errorf("Expected 1 warning entry, got %v", got)
+	}
+	if got := testutil.ToFloat64(collector.writerErrors.WithLabelValues("pimprom.failingWriter")); got != 3 {
+		t.Errorf("Expected 3 writer errors, got %v", got)
+	}
+	if got := testutil.CollectAndCount(collector.hookLatency); got != 1 {
+		t.Errorf("Expected one hook latency series, got %d", got)
+	}
+	if got := testutil.CollectAndCount(collector.writerLatency); got != 2 {
+		t.Errorf("Expected a latency series per writer, got %d", got)
+	}
+}
+
+func TestHandlerServesAsyncBufferGauges(t *testing.T) {
+	logger := pim.NewLoggerCore(pim.LoggerConfig{Level: pim.InfoLevel, ServiceName: "api", Async: true, BufferSize: 64, FlushInterval: time.Second})
+	defer logger.Close()
+
+	server := httptest.NewServer(Handler(NewCollector(Config{Namespace: "app"}, logger)))
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to scrape metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if !strings.Contains(string(body), `app_async_buffer_capacity{service="api"} 64`) {
+		t.Errorf("Expected async buffer capacity gauge, got:\n%s", body)
+	}
+	if !strings.Contains(string(body), `app_async_buffer_length{service="api"}`) {
+		t.Errorf("Expected async buffer length gauge, got:\n%s", body)
+	}
+}
+
+func TestAdminHandlerServesMetrics(t *testing.T) {
+	logger := pim.NewLoggerCore(pim.LoggerConfig{Level: pim.InfoLevel, ServiceName: "api", Async: true, BufferSize: 64, FlushInterval: time.Second})
+	defer logger.Close()
+	collector := NewCollector(Config{})
+
+	server := httptest.NewServer(AdminHandler(collector, logger))
+	defer server.Close()
+	logger.Info("observed")
+	logger.Flush()
+
+	resp, err := server.Client().Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("Failed to scrape metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	for _, want := range []string{
+		`pim_log_entries_total{level="info",service="api"} 1`,
+		`pim_async_dropped_total{service="api"} 0`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Expected %s, got:\n%s", want, body)
+		}
+	}
+
+	resp, err = server.Client().Get(server.URL + "/level")
+	if err != nil {
+		t.Fatalf("Failed to get level: %v", err)
+	}
+	level, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(level), `"info"`) {
+		t.Errorf("Expected info level, got %s", level)
+	}
+}
+
+func TestCollectorReportsCircuitBreakers(t *testing.T) {
+	breaker := pim.NewCircuitBreakerWriter(failingWriter{}, pim.CircuitBreakerConfig{FailureThreshold: 1})
+	breaker.Write(pim.CoreLogEntry{})
+	breaker.Write(pim.CoreLogEntry{})
+
+	collector := NewCollector(Config{})
+	collector.ObserveCircuitBreaker("remote", breaker)
+
+	expected := `
+# HELP pim_circuit_breaker_state Circuit breaker state: 0 closed, 1 open, 2 half-open.
+# TYPE pim_circuit_breaker_state gauge
+pim_circuit_breaker_state{writer="remote"} 1
+# HELP pim_circuit_breaker_diverted_total Entries not sent to the writer while the circuit was open.
+# TYPE pim_circuit_breaker_diverted_total counter
+pim_circuit_breaker_diverted_total{writer="remote"} 1
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "pim_circuit_breaker_state", "pim_circuit_breaker_diverted_total"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,21 @@
+module github.com/refactorroom/pim/pimsentry
+
+go 1.23.5
+
+require (
+	github.com/getsentry/sentry-go v0.35.3
+	github.com/refactorroom/pim v0.0.0
+)
+
+require (
+	github.com/fatih/color v1.18.0 // indirect
+	github.com/fxamacker/cbor/v2 v2.9.0 // indirect
+	github.com/mattn/go-colorable v0.1.13 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
+	golang.org/x/sys v0.34.0 // indirect
+	golang.org/x/text v0.23.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/refactorroom/pim => ../
@@ -0,0 +1,227 @@
+// Package pimsentry reports pim error entries to Sentry.
+package pimsentry
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/refactorroom/pim"
+)
+
+// Config configures the writer
+type Config struct {
+	Level      pim.LogLevel                `json:"level"`       // Most verbose level reported (default config: ErrorLevel)
+	SampleRate float64                     `json:"sample_rate"` // Fraction of entries reported, 0 to 1 (0 means all)
+	Sampler    func(pim.CoreLogEntry) bool `json:"-"`           // Optional per-entry sampling decision, applied after SampleRate
+
+	// Context keys whose values become the event environment and release.
+	// These are usually set once with LoggerCore.SetContext.
+	EnvironmentKey string `json:"environment_key"` // Default: "environment"
+	ReleaseKey     string `json:"release_key"`     // Default: "version"
+
+	TagKeys      []string      `json:"tag_keys"`      // Context keys sent as tags instead of extra data
+	FlushTimeout time.Duration `json:"flush_timeout"` // How long Flush and Close wait for delivery (default: 2 seconds)
+}
+
+// DefaultConfig provides sensible defaults
+var DefaultConfig = Config{
+	Level:          pim.ErrorLevel,
+	EnvironmentKey: "environment",
+	ReleaseKey:     "version",
+	FlushTimeout:   2 * time.Second,
+}
+
+// withDefaults fills unset config fields
+func withDefaults(config Config) Config {
+	if config.EnvironmentKey == "" {
+		config.EnvironmentKey = DefaultConfig.EnvironmentKey
+	}
+	if config.ReleaseKey == "" {
+		config.ReleaseKey = DefaultConfig.ReleaseKey
+	}
+	if config.FlushTimeout <= 0 {
+		config.FlushTimeout = DefaultConfig.FlushTimeout
+	}
+	return config
+}
+
+// Writer is a pim.LogWriter that converts error and panic entries into
+// Sentry events, including their stack traces, context fields and user and
+// request IDs, and sends them through a sentry-go client's transport.
+// Entries above the configured level are ignored.
+type Writer struct {
+	client *sentry.Client
+	config Config
+}
+
+// NewWriter creates a writer sending events through client
+func NewWriter(client *sentry.Client, config Config) *Writer {
+	return &Writer{client: client, config: withDefaults(config)}
+}
+
+// New creates a sentry-go client from options and a writer using it
+func New(options sentry.ClientOptions, config Config) (*Writer, error) {
+	client, err := sentry.NewClient(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sentry client: %w", err)
+	}
+	return NewWriter(client, config), nil
+}
+
+// Write implements pim.LogWriter
+func (w *Writer) Write(entry pim.CoreLogEntry) error {
+	if entry.Level > w.config.Level || !w.sampled(entry) {
+		return nil
+	}
+	w.client.CaptureEvent(w.Event(entry), nil, nil)
+	return nil
+}
+
+// sampled decides whether an entry is reported
+func (w *Writer) sampled(entry pim.CoreLogEntry) bool {
+	if w.config.SampleRate > 0 && w.config.SampleRate < 1 && rand.Float64() >= w.config.SampleRate {
+		return false
+	}
+	return w.config.Sampler == nil || w.config.Sampler(entry)
+}
+
+// Event converts an entry into a Sentry event
+func (w *Writer) Event(entry pim.CoreLogEntry) *sentry.Event {
+	event := sentry.NewEvent()
+	event.Level = sentryLevel(entry.Level)
+	event.Message = entry.Message
+	event.Timestamp = entry.Timestamp
+	event.Logger = entry.ServiceName
+	event.ServerName = entry.Hostname
+	event.User = sentry.User{ID: entry.UserID}
+
+	setTag(event, "service", entry.ServiceName)
+	setTag(event, "request_id", entry.RequestID)
+	setTag(event, "trace_id", entry.TraceID)
+	setTag(event, "span_id", entry.SpanID)
+	setTag(event, "session_id", entry.SessionID)
+
+	tagKeys := make(map[string]bool, len(w.config.TagKeys))
+	for _, key := range w.config.TagKeys {
+		tagKeys[key] = true
+	}
+
+	for key, value := range entry.Context {
+		switch {
+		case key == w.config.EnvironmentKey:
+			event.Environment = fmt.Sprint(value)
+		case key == w.config.ReleaseKey:
+			event.Release = fmt.Sprint(value)
+		case tagKeys[key]:
+			setTag(event, key, fmt.Sprint(value))
+		default:
+			if infos, ok := value.([]pim.ErrorInfo); ok {
+				for _, info := range infos {
+					event.Exception = append(event.Exception, sentry.Exception{
+						Type:       info.Type,
+						Value:      info.Message,
+						Stacktrace: stacktrace(info.Stack),
+					})
+				}
+				continue
+			}
+			event.Extra[key] = value
+		}
+	}
+
+	// Sentry titles the issue after the last exception, so the entry itself goes last
+	event.Exception = append(event.Exception, sentry.Exception{
+		Type:       exceptionType(entry),
+		Value:      entry.Message,
+		Module:     entry.Package,
+		Stacktrace: stacktrace(entry.StackTrace),
+	})
+	if entry.Level == pim.PanicLevel {
+		mechanism := &sentry.Mechanism{Type: "pim"}
+		mechanism.SetUnhandled()
+		event.Exception[len(event.Exception)-1].Mechanism = mechanism
+	}
+
+	return event
+}
+
+// exceptionType names the main exception after the error class when the
+// entry has been classified, or its level otherwise
+func exceptionType(entry pim.CoreLogEntry) string {
+	if class, ok := entry.Context[pim.ErrorClassKey].(string); ok && class != "" {
+		return class + " error"
+	}
+	return entry.Level.Name()
+}
+
+// setTag sets a tag if value is non-empty
+func setTag(event *sentry.Event, key, value string) {
+	if value != "" {
+		event.Tags[key] = value
+	}
+}
+
+// sentryLevel maps a pim level to a Sentry level
+func sentryLevel(level pim.LogLevel) sentry.Level {
+	switch level {
+	case pim.PanicLevel:
+		return sentry.LevelFatal
+	case pim.ErrorLevel:
+		return sentry.LevelError
+	case pim.WarningLevel:
+		return sentry.LevelWarning
+	case pim.InfoLevel:
+		return sentry.LevelInfo
+	default:
+		return sentry.LevelDebug
+	}
+}
+
+// stacktrace converts pim frames (innermost first) to a Sentry stack trace
+// (outermost first)
+func stacktrace(frames []pim.StackFrame) *sentry.Stacktrace {
+	if len(frames) == 0 {
+		return nil
+	}
+
+	trace := &sentry.Stacktrace{Frames: make([]sentry.Frame, 0, len(frames))}
+	for i := len(frames) - 1; i >= 0; i-- {
+		frame := frames[i]
+		sf := sentry.Frame{
+			Function: frame.Function,
+			Module:   frame.Package,
+			Filename: frame.File,
+			Lineno:   frame.Line,
+			InApp:    true,
+		}
+		for _, line := range frame.Source {
+			switch {
+			case line.Current:
+				sf.ContextLine = line.Code
+			case sf.ContextLine == "":
+				sf.PreContext = append(sf.PreContext, line.Code)
+			default:
+				sf.PostContext = append(sf.PostContext, line.Code)
+			}
+		}
+		trace.Frames = append(trace.Frames, sf)
+	}
+	return trace
+}
+
+// Flush implements pim.LogWriter
+func (w *Writer) Flush() error {
+	if !w.client.Flush(w.config.FlushTimeout) {
+		return fmt.Errorf("timed out flushing sentry events")
+	}
+	return nil
+}
+
+// Close implements pim.LogWriter
+func (w *Writer) Close() error {
+	err := w.Flush()
+	w.client.Close()
+	return err
+}
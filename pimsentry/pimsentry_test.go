@@ -0,0 +1,129 @@
+package pimsentry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/refactorroom/pim"
+)
+
+// recordingTransport captures events instead of sending them
+type recordingTransport struct {
+	mu     sync.Mutex
+	events []*sentry.Event
+}
+
+func (t *recordingTransport) Flush(time.Duration) bool              { return true }
+func (t *recordingTransport) FlushWithContext(context.Context) bool { return true }
+func (t *recordingTransport) Configure(sentry.ClientOptions)        {}
+func (t *recordingTransport) Close()                                {}
+
+func (t *recordingTransport) SendEvent(event *sentry.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, event)
+}
+
+func (t *recordingTransport) Events() []*sentry.Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*sentry.Event(nil), t.events...)
+}
+
+func newTestWriter(t *testing.T, config Config) (*Writer, *recordingTransport) {
+	t.Helper()
+	transport := &recordingTransport{}
+	writer, err := New(sentry.ClientOptions{Transport: transport}, config)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return writer, transport
+}
+
+func TestWriterConvertsErrorEntries(t *testing.T) {
+	writer, transport := newTestWriter(t, Config{Level: pim.ErrorLevel, TagKeys: []string{"region"}})
+
+	entry := pim.CoreLogEntry{
+		Timestamp:   time.Now(),
+		Level:       pim.ErrorLevel,
+		Message:     "payment failed",
+		ServiceName: "billing",
+		UserID:      "user-1",
+		RequestID:   "req-1",
+		StackTrace: []pim.StackFrame{
+			{File: "charge.go", Line: 42, Function: "Charge", Package: "billing",
+				Source: []pim.SourceLine{{Line: 41, Code: "a"}, {Line: 42, Code: "b", Current: true}, {Line: 43, Code: "c"}}},
+			{File: "main.go", Line: 10, Function: "main", Package: "main"},
+		},
+		Context: map[string]interface{}{
+			"environment": "staging",
+			"version":     "1.2.3",
+			"region":      "eu",
+			"order_id":    "o-9",
+			"errors":      pim.FlattenErrors(errors.Join(errors.New("card declined"), errors.New("retry exhausted"))),
+		},
+	}
+	if err := writer.Write(entry); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	writer.Write(pim.CoreLogEntry{Level: pim.InfoLevel, Message: "ignored"})
+
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	event := events[0]
+
+	if event.Level != sentry.LevelError || event.Message != "payment failed" {
+		t.Errorf("Unexpected level/message: %s %q", event.Level, event.Message)
+	}
+	if event.Environment != "staging" || event.Release != "1.2.3" {
+		t.Errorf("Expected environment and release from context, got %q %q", event.Environment, event.Release)
+	}
+	if event.User.ID != "user-1" || event.Tags["request_id"] != "req-1" || event.Tags["region"] != "eu" {
+		t.Errorf("Unexpected user/tags: %+v %v", event.User, event.Tags)
+	}
+	if event.Extra["order_id"] != "o-9" {
+		t.Errorf("Expected order_id in extra, got %v", event.Extra)
+	}
+
+	if len(event.Exception) != 3 {
+		t.Fatalf("Expected 2 joined errors plus the entry, got %d exceptions", len(event.Exception))
+	}
+	main := event.Exception[2]
+	if main.Value != "payment failed" || main.Stacktrace == nil {
+		t.Fatalf("Unexpected main exception: %+v", main)
+	}
+	frames := main.Stacktrace.Frames
+	if len(frames) != 2 || frames[0].Function != "main" || frames[1].Function != "Charge" {
+		t.Errorf("Expected outermost frame first, got %+v", frames)
+	}
+	if frames[1].ContextLine != "b" || len(frames[1].PreContext) != 1 || len(frames[1].PostContext) != 1 {
+		t.Errorf("Expected source context on frame, got %+v", frames[1])
+	}
+}
+
+func TestWriterSampling(t *testing.T) {
+	writer, transport := newTestWriter(t, Config{
+		Level:   pim.ErrorLevel,
+		Sampler: func(entry pim.CoreLogEntry) bool { return entry.Message != "noisy" },
+	})
+
+	writer.Write(pim.CoreLogEntry{Level: pim.ErrorLevel, Message: "noisy"})
+	writer.Write(pim.CoreLogEntry{Level: pim.PanicLevel, Message: "crash"})
+
+	events := transport.Events()
+	if len(events) != 1 || events[0].Level != sentry.LevelFatal {
+		t.Fatalf("Expected only the panic event, got %d events", len(events))
+	}
+	if handled := events[0].Exception[0].Mechanism.Handled; handled == nil || *handled {
+		t.Error("Expected panic entries to be marked unhandled")
+	}
+	if err := writer.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package pimtest
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Clock is a manually advanced pim.Clock for deterministic timestamps,
+// sampling and rotation in tests:
+//
+//	clock := pimtest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+//	config.Clock = clock
+//	clock.Advance(time.Hour)
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a clock stopped at start
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// Now implements pim.Clock
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set moves the clock to t
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+// SequentialIDs is a pim.IDGenerator returning prefix followed by 1, 2, 3...
+type SequentialIDs struct {
+	Prefix string
+	next   atomic.Uint64
+}
+
+// NewID implements pim.IDGenerator
+func (g *SequentialIDs) NewID() string {
+	return fmt.Sprintf("%s%d", g.Prefix, g.next.Add(1))
+}
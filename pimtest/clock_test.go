@@ -0,0 +1,43 @@
+package pimtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/refactorroom/pim"
+)
+
+func TestClockAndSequentialIDs(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewClock(start)
+	config := pim.DefaultLoggerConfig
+	config.EnableConsole = false
+	config.Clock = clock
+	config.IDGenerator = &SequentialIDs{Prefix: "req-"}
+
+	logger := pim.NewLoggerCore(config)
+	defer logger.Close()
+	buffer := pim.NewBufferWriter(config, 10)
+	logger.AddWriter(buffer)
+	logger.AddRequestIDEnrichHook()
+
+	logger.Info("one")
+	clock.Advance(time.Second)
+	logger.Info("two")
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if !entries[0].Timestamp.Equal(start) || !entries[1].Timestamp.Equal(start.Add(time.Second)) {
+		t.Errorf("expected the clock's times, got %v and %v", entries[0].Timestamp, entries[1].Timestamp)
+	}
+	if entries[0].Context["request_id"] != "req-1" || entries[1].Context["request_id"] != "req-2" {
+		t.Errorf("expected sequential IDs, got %v and %v", entries[0].Context["request_id"], entries[1].Context["request_id"])
+	}
+
+	clock.Set(start)
+	if !clock.Now().Equal(start) {
+		t.Errorf("expected Set to move the clock, got %v", clock.Now())
+	}
+}
@@ -0,0 +1,258 @@
+package pimtest
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/refactorroom/pim"
+)
+
+// Batch is one request received by a FakeIngestServer
+type Batch struct {
+	Header  http.Header
+	Entries []pim.CoreLogEntry
+}
+
+// FakeIngestServer is an httptest server standing in for a log ingest
+// endpoint, so a RemoteWriter shipping configuration can be tested end to
+// end. It records the batches it accepts and can fail or delay requests:
+//
+//	server := pimtest.NewFakeIngestServer()
+//	defer server.Close()
+//	writer := pim.NewRemoteWriter(config, server.RemoteConfig())
+//	...
+//	server.WaitForEntries(t, 3, time.Second)
+//
+// JSON and CBOR batches are decoded like receiver.Handler does. Text
+// batches, sent without EnableJSON or EnableCBOR, are recorded with each
+// line as the Message of an entry.
+type FakeIngestServer struct {
+	URL string // Base URL of the server, e.g. "http://127.0.0.1:54321"
+
+	server *httptest.Server
+
+	mu         sync.Mutex
+	received   *sync.Cond // Broadcast when a batch is recorded
+	batches    []Batch
+	requests   int
+	failures   int // Requests left to fail with failStatus
+	failStatus int
+	latency    time.Duration
+	keyring    *pim.BatchKeyring
+}
+
+// NewFakeIngestServer starts a fake ingest server; call Close when done
+func NewFakeIngestServer() *FakeIngestServer {
+	s := &FakeIngestServer{}
+	s.received = sync.NewCond(&s.mu)
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.server.URL
+	return s
+}
+
+// Close shuts the server down
+func (s *FakeIngestServer) Close() {
+	s.server.Close()
+}
+
+// RemoteConfig returns a RemoteWriterConfig sending to the server in
+// small batches without delay, to adjust for the configuration under test
+func (s *FakeIngestServer) RemoteConfig() pim.RemoteWriterConfig {
+	return pim.RemoteWriterConfig{
+		Endpoint:   s.URL,
+		Timeout:    5 * time.Second,
+		BatchSize:  1,
+		BatchDelay: 10 * time.Millisecond,
+	}
+}
+
+// SetKeyring decrypts batches from senders with encryption enabled
+func (s *FakeIngestServer) SetKeyring(keyring *pim.BatchKeyring) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keyring = keyring
+}
+
+// FailNext makes the next n requests fail with status without recording
+// them, e.g. to test retries and dead-letter queues
+func (s *FakeIngestServer) FailNext(n int, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = n
+	s.failStatus = status
+}
+
+// SetLatency delays every response by d, e.g. to test sender timeouts
+func (s *FakeIngestServer) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// serveHTTP records a batch, or fails or delays it as configured
+func (s *FakeIngestServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests++
+	latency, keyring := s.latency, s.keyring
+	fail := s.failures > 0
+	status := s.failStatus
+	if fail {
+		s.failures--
+	}
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if fail {
+		http.Error(w, "injected failure", status)
+		return
+	}
+
+	entries, err := decodeIngestRequest(r, keyring)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, pim.ErrUnsupportedBatchFormat) {
+			status = http.StatusUnsupportedMediaType
+			w.Header().Set("Accept", pim.AcceptedBatchFormats)
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	s.mu.Lock()
+	s.batches = append(s.batches, Batch{Header: r.Header.Clone(), Entries: entries})
+	s.received.Broadcast()
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// decodeIngestRequest decodes a JSON or CBOR batch, or splits a text batch
+// into entries by line
+func decodeIngestRequest(r *http.Request, keyring *pim.BatchKeyring) ([]pim.CoreLogEntry, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "text/plain" {
+		return pim.DecodeBatchRequest(r, keyring)
+	}
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == pim.BatchEncryptionEncoding {
+		if keyring == nil {
+			return nil, errors.New("encrypted batch but no keyring configured")
+		}
+		envelope, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err := keyring.Decrypt(envelope)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(plaintext)
+	}
+
+	var entries []pim.CoreLogEntry
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(nil, 10<<20)
+	for scanner.Scan() {
+		entries = append(entries, pim.CoreLogEntry{Message: scanner.Text()})
+	}
+	return entries, scanner.Err()
+}
+
+// Requests returns the number of requests received, including failed ones
+func (s *FakeIngestServer) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// Batches returns the recorded batches
+func (s *FakeIngestServer) Batches() []Batch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batches := make([]Batch, len(s.batches))
+	copy(batches, s.batches)
+	return batches
+}
+
+// Entries returns the entries of all recorded batches in arrival order
+func (s *FakeIngestServer) Entries() []pim.CoreLogEntry {
+	return s.EntriesMatching(func(pim.CoreLogEntry) bool { return true })
+}
+
+// ReceivedCount returns the number of entries recorded
+func (s *FakeIngestServer) ReceivedCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.receivedCountLocked()
+}
+
+// receivedCountLocked counts the recorded entries; s.mu must be held
+func (s *FakeIngestServer) receivedCountLocked() int {
+	count := 0
+	for _, batch := range s.batches {
+		count += len(batch.Entries)
+	}
+	return count
+}
+
+// EntriesMatching returns the recorded entries for which match returns true
+func (s *FakeIngestServer) EntriesMatching(match func(pim.CoreLogEntry) bool) []pim.CoreLogEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var entries []pim.CoreLogEntry
+	for _, batch := range s.batches {
+		for _, entry := range batch.Entries {
+			if match(entry) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries
+}
+
+// Reset forgets the recorded batches and requests
+func (s *FakeIngestServer) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = nil
+	s.requests = 0
+}
+
+// WaitForEntries waits until at least n entries are recorded and fails the
+// test if that takes longer than timeout
+func (s *FakeIngestServer) WaitForEntries(t testing.TB, n int, timeout time.Duration) []pim.CoreLogEntry {
+	t.Helper()
+	timer := time.AfterFunc(timeout, func() {
+		s.mu.Lock()
+		s.received.Broadcast()
+		s.mu.Unlock()
+	})
+	defer timer.Stop()
+
+	deadline := time.Now().Add(timeout)
+	s.mu.Lock()
+	for s.receivedCountLocked() < n && time.Now().Before(deadline) {
+		s.received.Wait()
+	}
+	count := s.receivedCountLocked()
+	s.mu.Unlock()
+
+	if count < n {
+		t.Errorf("expected %d entries within %v, got %d", n, timeout, count)
+	}
+	return s.Entries()
+}
@@ -0,0 +1,98 @@
+package pimtest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/refactorroom/pim"
+)
+
+func TestFakeIngestServerRecordsBatches(t *testing.T) {
+	server := NewFakeIngestServer()
+	defer server.Close()
+
+	config := pim.DefaultLoggerConfig
+	config.EnableConsole = false
+	config.EnableJSON = true
+	remote := server.RemoteConfig()
+	remote.BatchSize = 2
+	writer := pim.NewRemoteWriter(config, remote)
+	defer writer.Close()
+
+	writer.Write(pim.CoreLogEntry{Level: pim.InfoLevel, Message: "order placed", Context: map[string]interface{}{"order_id": "o1"}})
+	writer.Write(pim.CoreLogEntry{Level: pim.ErrorLevel, Message: "payment failed"})
+	writer.Write(pim.CoreLogEntry{Level: pim.InfoLevel, Message: "order shipped"})
+
+	entries := server.WaitForEntries(t, 3, 2*time.Second)
+	if server.ReceivedCount() != 3 || len(entries) != 3 || entries[0].Context["order_id"] != "o1" {
+		t.Errorf("expected 3 decoded entries, got %+v", entries)
+	}
+	if batches := server.Batches(); len(batches) != 2 || len(batches[0].Entries) != 2 {
+		t.Errorf("expected a full batch and the rest, got %d batches", len(batches))
+	}
+	errs := server.EntriesMatching(func(entry pim.CoreLogEntry) bool { return entry.Level == pim.ErrorLevel })
+	if len(errs) != 1 || errs[0].Message != "payment failed" {
+		t.Errorf("expected the error entry, got %+v", errs)
+	}
+}
+
+func TestFakeIngestServerTextBatches(t *testing.T) {
+	server := NewFakeIngestServer()
+	defer server.Close()
+
+	config := pim.LoggerConfig{Level: pim.InfoLevel}
+	writer := pim.NewRemoteWriter(config, server.RemoteConfig())
+	defer writer.Close()
+
+	writer.Write(pim.CoreLogEntry{Message: "plain line"})
+	entries := server.WaitForEntries(t, 1, 2*time.Second)
+	if len(entries) != 1 || !strings.Contains(entries[0].Message, "plain line") {
+		t.Errorf("expected the raw line, got %+v", entries)
+	}
+}
+
+func TestFakeIngestServerFailures(t *testing.T) {
+	server := NewFakeIngestServer()
+	defer server.Close()
+	server.FailNext(1, http.StatusServiceUnavailable)
+
+	config := pim.LoggerConfig{Level: pim.InfoLevel, EnableJSON: true}
+	writer := pim.NewRemoteWriter(config, server.RemoteConfig())
+	defer writer.Close()
+
+	// The writer retries after the injected failure
+	writer.Write(pim.CoreLogEntry{Message: "retried"})
+	server.WaitForEntries(t, 1, 5*time.Second)
+	if server.Requests() != 2 {
+		t.Errorf("expected the failed request and its retry, got %d requests", server.Requests())
+	}
+
+	server.Reset()
+	if server.ReceivedCount() != 0 || server.Requests() != 0 {
+		t.Error("expected Reset to forget recorded batches")
+	}
+}
+
+func TestFakeIngestServerLatency(t *testing.T) {
+	server := NewFakeIngestServer()
+	defer server.Close()
+	server.SetLatency(200 * time.Millisecond)
+
+	client := &http.Client{Timeout: 50 * time.Millisecond}
+	_, err := client.Post(server.URL, "application/json", strings.NewReader("[]"))
+	if err == nil {
+		t.Error("expected the client to time out")
+	}
+
+	server.SetLatency(0)
+	resp, err := http.Post(server.URL, "application/x-unknown", strings.NewReader("x"))
+	if err != nil {
+		t.Fatalf("Post failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType || resp.Header.Get("Accept") == "" {
+		t.Errorf("expected unsupported formats to be rejected with 415, got %d", resp.StatusCode)
+	}
+}
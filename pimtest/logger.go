@@ -0,0 +1,241 @@
+package pimtest
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/refactorroom/pim"
+)
+
+// DefaultCapacity is the number of entries a TestLogger keeps by default
+const DefaultCapacity = 10000
+
+// TestLogger is a logger capturing its entries in memory for assertions:
+//
+//	logger := pimtest.NewTestLogger(t, pimtest.FailOnError())
+//	service := NewService(logger.LoggerCore)
+//	service.Charge(order)
+//	logger.AssertLogged(t, pim.InfoLevel, "charged", "order_id", order.ID)
+//
+// It is closed when the test ends.
+type TestLogger struct {
+	*pim.LoggerCore
+
+	buffer *pim.BufferWriter
+	errors *errorWatcher // Set by FailOnError
+}
+
+// TestLoggerOption configures a TestLogger
+type TestLoggerOption func(*testLoggerOptions)
+
+// testLoggerOptions holds the settings collected from TestLoggerOptions
+type testLoggerOptions struct {
+	config      pim.LoggerConfig
+	capacity    int
+	failOnError bool
+}
+
+// WithConfig creates the logger from config instead of a console-less
+// DefaultLoggerConfig at TraceLevel; console output stays disabled
+func WithConfig(config pim.LoggerConfig) TestLoggerOption {
+	return func(o *testLoggerOptions) {
+		o.config = config
+	}
+}
+
+// WithCapacity keeps the last n entries instead of DefaultCapacity
+func WithCapacity(n int) TestLoggerOption {
+	return func(o *testLoggerOptions) {
+		o.capacity = n
+	}
+}
+
+// FailOnError fails the test when it ends if an Error or Panic entry was
+// logged that no AssertLogged call matched
+func FailOnError() TestLoggerOption {
+	return func(o *testLoggerOptions) {
+		o.failOnError = true
+	}
+}
+
+// NewTestLogger creates a logger capturing its entries in memory
+func NewTestLogger(t testing.TB, opts ...TestLoggerOption) *TestLogger {
+	t.Helper()
+	options := testLoggerOptions{config: pim.DefaultLoggerConfig, capacity: DefaultCapacity}
+	options.config.Level = pim.TraceLevel
+	for _, opt := range opts {
+		opt(&options)
+	}
+	options.config.EnableConsole = false
+
+	logger := &TestLogger{
+		LoggerCore: pim.NewLoggerCore(options.config),
+		buffer:     pim.NewBufferWriter(options.config, options.capacity),
+	}
+	logger.AddWriter(logger.buffer)
+	if options.failOnError {
+		logger.errors = &errorWatcher{}
+		logger.AddWriter(logger.errors)
+	}
+
+	t.Cleanup(func() {
+		logger.Flush()
+		for _, entry := range logger.errors.unexpected() {
+			t.Errorf("unexpected %s entry: %s", entry.LevelString, entry.Message)
+		}
+		logger.Close()
+	})
+	return logger
+}
+
+// Entries returns the captured entries
+func (l *TestLogger) Entries() []pim.CoreLogEntry {
+	return l.buffer.GetBuffer()
+}
+
+// Reset forgets the captured entries
+func (l *TestLogger) Reset() {
+	l.buffer.ClearBuffer()
+	l.errors.reset()
+}
+
+// Find returns the captured entries at level whose message contains
+// msgContains and whose context has the key/value pairs of fields
+func (l *TestLogger) Find(level pim.LogLevel, msgContains string, fields ...interface{}) []pim.CoreLogEntry {
+	var matches []pim.CoreLogEntry
+	for _, entry := range l.Entries() {
+		if entryMatches(entry, level, msgContains, fields) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches
+}
+
+// AssertLogged fails the test unless an entry at level was captured whose
+// message contains msgContains and whose context has the key/value pairs
+// of fields, e.g. AssertLogged(t, pim.InfoLevel, "charged", "amount", 10).
+// It returns the first match, and matched Error and Panic entries no
+// longer fail a FailOnError test.
+func (l *TestLogger) AssertLogged(t testing.TB, level pim.LogLevel, msgContains string, fields ...interface{}) pim.CoreLogEntry {
+	t.Helper()
+	matches := l.Find(level, msgContains, fields...)
+	if len(matches) == 0 {
+		t.Errorf("expected a %s entry containing %q with fields %v, got:\n%s", levelName(level), msgContains, fields, l.describe())
+		return pim.CoreLogEntry{}
+	}
+	l.errors.expect(matches)
+	return matches[0]
+}
+
+// AssertNotLogged fails the test if an entry at level was captured whose
+// message contains msgContains and whose context has the key/value pairs
+// of fields
+func (l *TestLogger) AssertNotLogged(t testing.TB, level pim.LogLevel, msgContains string, fields ...interface{}) {
+	t.Helper()
+	for _, entry := range l.Find(level, msgContains, fields...) {
+		t.Errorf("expected no %s entry containing %q with fields %v, got %q %v", levelName(level), msgContains, fields, entry.Message, entry.Context)
+	}
+}
+
+// describe lists the captured entries for failure messages
+func (l *TestLogger) describe() string {
+	entries := l.Entries()
+	if len(entries) == 0 {
+		return "  (no entries)"
+	}
+	var b strings.Builder
+	for _, entry := range entries {
+		fmt.Fprintf(&b, "  [%s] %s %v\n", levelName(entry.Level), entry.Message, entry.Context)
+	}
+	return b.String()
+}
+
+// entryMatches reports whether entry is at level, contains msgContains and
+// has the key/value pairs of fields in its context
+func entryMatches(entry pim.CoreLogEntry, level pim.LogLevel, msgContains string, fields []interface{}) bool {
+	if entry.Level != level || !strings.Contains(entry.Message, msgContains) {
+		return false
+	}
+	for i := 0; i+1 < len(fields); i += 2 {
+		actual, ok := entry.Context[fmt.Sprint(fields[i])]
+		if !ok || !reflect.DeepEqual(actual, fields[i+1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// levelName returns the upper-case name of level
+func levelName(level pim.LogLevel) string {
+	return strings.ToUpper(level.Name())
+}
+
+// errorWatcher records Error and Panic entries until AssertLogged expects
+// them. Methods are safe on a nil watcher, which records nothing.
+type errorWatcher struct {
+	mu      sync.Mutex
+	entries []pim.CoreLogEntry
+}
+
+// Write implements pim.LogWriter
+func (w *errorWatcher) Write(entry pim.CoreLogEntry) error {
+	if entry.Level > pim.ErrorLevel {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.entries = append(w.entries, entry)
+	return nil
+}
+
+// Flush implements pim.LogWriter
+func (w *errorWatcher) Flush() error { return nil }
+
+// Close implements pim.LogWriter
+func (w *errorWatcher) Close() error { return nil }
+
+// expect forgets the recorded entries among matches
+func (w *errorWatcher) expect(matches []pim.CoreLogEntry) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	remaining := w.entries[:0]
+	for _, entry := range w.entries {
+		expected := false
+		for _, match := range matches {
+			if reflect.DeepEqual(entry, match) {
+				expected = true
+				break
+			}
+		}
+		if !expected {
+			remaining = append(remaining, entry)
+		}
+	}
+	w.entries = remaining
+}
+
+// unexpected returns the recorded entries no AssertLogged call expected
+func (w *errorWatcher) unexpected() []pim.CoreLogEntry {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]pim.CoreLogEntry(nil), w.entries...)
+}
+
+// reset forgets the recorded entries
+func (w *errorWatcher) reset() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.entries = nil
+}
@@ -0,0 +1,73 @@
+package pimtest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/refactorroom/pim"
+)
+
+// recordingT records the failures of the helpers under test
+type recordingT struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, format)
+}
+
+func TestTestLoggerAssertions(t *testing.T) {
+	logger := NewTestLogger(t)
+	logger.Debug("cache warmed")
+	logger.InfoWithFields("charged card", map[string]interface{}{"amount": 10, "currency": "EUR"})
+
+	entry := logger.AssertLogged(t, pim.InfoLevel, "charged", "amount", 10, "currency", "EUR")
+	if entry.Message != "charged card" {
+		t.Errorf("expected the matching entry, got %q", entry.Message)
+	}
+	logger.AssertLogged(t, pim.DebugLevel, "warmed")
+	logger.AssertNotLogged(t, pim.InfoLevel, "refunded")
+	logger.AssertNotLogged(t, pim.InfoLevel, "charged", "amount", 20)
+
+	recorder := &recordingT{TB: t}
+	logger.AssertLogged(recorder, pim.ErrorLevel, "charged")
+	logger.AssertNotLogged(recorder, pim.InfoLevel, "charged")
+	if len(recorder.errors) != 2 {
+		t.Errorf("expected both assertions to fail, got %v", recorder.errors)
+	}
+
+	logger.Reset()
+	if len(logger.Entries()) != 0 {
+		t.Errorf("expected Reset to clear the entries, got %d", len(logger.Entries()))
+	}
+}
+
+func TestTestLoggerFailOnError(t *testing.T) {
+	logger := NewTestLogger(t, FailOnError(), WithCapacity(10))
+	logger.Error("payment declined: %v", errors.New("insufficient funds"))
+	logger.Error("database unreachable")
+	logger.Warning("retrying")
+	logger.AssertLogged(t, pim.ErrorLevel, "payment declined")
+
+	unexpected := logger.errors.unexpected()
+	if len(unexpected) != 1 || unexpected[0].Message != "database unreachable" {
+		t.Errorf("expected only the unasserted error to be reported, got %+v", unexpected)
+	}
+	logger.errors.reset() // Keep the cleanup check from failing this test
+}
+
+func TestTestLoggerWithConfig(t *testing.T) {
+	config := pim.DefaultLoggerConfig
+	config.Level = pim.WarningLevel
+	config.EnableConsole = true
+	logger := NewTestLogger(t, WithConfig(config))
+
+	logger.Info("below the level")
+	logger.Warning("disk almost full")
+	if entries := logger.Entries(); len(entries) != 1 || entries[0].Message != "disk almost full" {
+		t.Errorf("expected only the warning, got %+v", entries)
+	}
+}
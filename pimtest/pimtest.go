@@ -0,0 +1,99 @@
+// Package pimtest provides helpers for asserting the behavior of pim hooks
+// in unit tests, so redaction and filter configurations can be validated in CI,
+// a TestLogger capturing entries for assertions on application logging, and
+// a controllable Clock and a FakeIngestServer for testing shipping
+// configurations end to end.
+package pimtest
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/refactorroom/pim"
+)
+
+// NewHookManager returns a hook manager with the given hooks added
+func NewHookManager(hooks ...pim.EnhancedLogHook) *pim.HookManager {
+	manager := pim.NewHookManager()
+	for _, hook := range hooks {
+		manager.AddHook(hook)
+	}
+	return manager
+}
+
+// Entry builds an entry at InfoLevel with the given message and key/value context
+func Entry(msg string, kv ...interface{}) pim.CoreLogEntry {
+	entry := pim.CoreLogEntry{Level: pim.InfoLevel, LevelString: "info", Message: msg}
+	if len(kv) > 0 {
+		entry.Context = make(map[string]interface{}, len(kv)/2)
+		for i := 0; i+1 < len(kv); i += 2 {
+			entry.Context[fmt.Sprint(kv[i])] = kv[i+1]
+		}
+	}
+	return entry
+}
+
+// Run dry-runs the manager on one entry and returns the result
+func Run(t testing.TB, manager *pim.HookManager, entry pim.CoreLogEntry) pim.DryRunResult {
+	t.Helper()
+	return manager.DryRun([]pim.CoreLogEntry{entry})[0]
+}
+
+// AssertFiltered fails the test unless the entry is filtered by the pipeline
+func AssertFiltered(t testing.TB, manager *pim.HookManager, entry pim.CoreLogEntry) pim.DryRunResult {
+	t.Helper()
+	result := Run(t, manager, entry)
+	if !result.Filtered {
+		t.Errorf("expected entry %q to be filtered, but it passed all hooks", entry.Message)
+	}
+	return result
+}
+
+// AssertNotFiltered fails the test if the entry is filtered and returns the processed entry
+func AssertNotFiltered(t testing.TB, manager *pim.HookManager, entry pim.CoreLogEntry) pim.CoreLogEntry {
+	t.Helper()
+	result := Run(t, manager, entry)
+	if result.Filtered {
+		t.Errorf("expected entry %q to pass, but it was filtered by hook %q", entry.Message, result.FilteredBy)
+	}
+	return result.Output
+}
+
+// AssertField fails the test unless the processed entry has the expected context value
+func AssertField(t testing.TB, manager *pim.HookManager, entry pim.CoreLogEntry, key string, expected interface{}) {
+	t.Helper()
+	output := AssertNotFiltered(t, manager, entry)
+	actual, ok := output.Context[key]
+	if !ok {
+		t.Errorf("expected context field %q to be set, but it is missing", key)
+		return
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected context field %q to be %#v, got %#v", key, expected, actual)
+	}
+}
+
+// AssertNoField fails the test if the processed entry has the context field
+func AssertNoField(t testing.TB, manager *pim.HookManager, entry pim.CoreLogEntry, key string) {
+	t.Helper()
+	output := AssertNotFiltered(t, manager, entry)
+	if value, ok := output.Context[key]; ok {
+		t.Errorf("expected context field %q to be absent, got %#v", key, value)
+	}
+}
+
+// AssertUnchanged fails the test if any hook modified the entry
+func AssertUnchanged(t testing.TB, manager *pim.HookManager, entry pim.CoreLogEntry) {
+	t.Helper()
+	result := Run(t, manager, entry)
+	if result.Filtered {
+		t.Errorf("expected entry %q to pass unchanged, but it was filtered by hook %q", entry.Message, result.FilteredBy)
+		return
+	}
+	for _, decision := range result.Decisions {
+		for _, change := range decision.Changes {
+			t.Errorf("expected entry to pass unchanged, but hook %q %s %s", decision.Hook, change.Kind, change.Field)
+		}
+	}
+}
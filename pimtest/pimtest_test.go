@@ -0,0 +1,39 @@
+package pimtest
+
+import (
+	"testing"
+
+	"github.com/refactorroom/pim"
+)
+
+func TestHelpersWithRedactAndFilterHooks(t *testing.T) {
+	dropDebug := pim.NewFilterHook(pim.FilterConfig{
+		HookConfig: pim.HookConfig{Type: pim.HookTypeFilter, Name: "drop_debug", Enabled: true},
+		CustomFunc: func(entry pim.CoreLogEntry) bool { return entry.Level == pim.DebugLevel },
+	})
+	manager := NewHookManager(pim.NewSensitiveDataRedactHook(), dropDebug)
+
+	AssertField(t, manager, Entry("login", "password", "hunter2"), "password", "[REDACTED]")
+	AssertUnchanged(t, manager, Entry("health check", "status", "ok"))
+
+	debug := Entry("cache miss")
+	debug.Level = pim.DebugLevel
+	AssertFiltered(t, manager, debug)
+}
+
+func TestDryRunDecisions(t *testing.T) {
+	manager := NewHookManager(pim.NewSensitiveDataRedactHook())
+	entry := Entry("login", "password", "hunter2", "user", "alice")
+
+	result := Run(t, manager, entry)
+	if len(result.Decisions) != 1 || len(result.Decisions[0].Changes) != 1 {
+		t.Fatalf("Expected one decision with one change, got: %+v", result.Decisions)
+	}
+	change := result.Decisions[0].Changes[0]
+	if change.Field != "context.password" || change.Kind != "modified" || change.Before != "hunter2" {
+		t.Errorf("Unexpected change: %+v", change)
+	}
+	if entry.Context["password"] != "hunter2" {
+		t.Error("Expected dry run to leave the input entry intact")
+	}
+}
@@ -0,0 +1,53 @@
+package pim
+
+import (
+	"time"
+)
+
+// PipelineObserver receives measurements from a logger's processing
+// pipeline, e.g. to export them as metrics. Methods are called on the
+// logging path and must be fast and safe for concurrent use.
+type PipelineObserver interface {
+	// EntryLogged is called once per entry that passed level checks,
+	// sampling and filters, before it is written
+	EntryLogged(entry CoreLogEntry)
+	// HookProcessed reports how long an enhanced hook took
+	HookProcessed(hook string, duration time.Duration)
+	// WriterWritten reports how long a writer took and whether it failed
+	WriterWritten(writer string, duration time.Duration, err error)
+}
+
+// SetPipelineObserver sets the observer for this logger and its hook
+// manager, or removes it if observer is nil. Child loggers created
+// afterwards inherit it.
+func (l *LoggerCore) SetPipelineObserver(observer PipelineObserver) {
+	l.mu.Lock()
+	l.observer = observer
+	l.mu.Unlock()
+
+	if l.hookManager != nil {
+		l.hookManager.mu.Lock()
+		l.hookManager.observer = observer
+		l.hookManager.mu.Unlock()
+	}
+}
+
+// AsyncBufferStats returns the number of queued entries and the queue
+// capacity of an async logger, including the priority lane; both are zero
+// for synchronous loggers
+func (l *LoggerCore) AsyncBufferStats() (length, capacity int) {
+	if l.asyncBuffer == nil {
+		return 0, 0
+	}
+	length, capacity = l.asyncBuffer.Len(), l.asyncBuffer.Cap()
+	if l.asyncPriority != nil {
+		length += l.asyncPriority.Len()
+		capacity += l.asyncPriority.Cap()
+	}
+	return length, capacity
+}
+
+// ServiceName returns the service name of the logger
+func (l *LoggerCore) ServiceName() string {
+	return l.serviceName
+}
@@ -0,0 +1,239 @@
+package pim
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultProvenanceSize is the number of traces kept by EnableProvenance
+const DefaultProvenanceSize = 100
+
+// Provenance stages
+const (
+	StageLevel    = "level"    // Logger level check
+	StageSampling = "sampling" // Sampling
+	StageHook     = "hook"     // Legacy or enhanced hook
+	StageAsync    = "async"    // Async buffer
+	StageWriter   = "writer"   // Writer
+)
+
+// Provenance outcomes
+const (
+	OutcomeDropped   = "dropped"   // Level, sampling or a full async buffer stopped the entry
+	OutcomeModified  = "modified"  // A hook changed the entry
+	OutcomeUnchanged = "unchanged" // A hook passed the entry on as is
+	OutcomeFiltered  = "filtered"  // A hook filtered the entry
+	OutcomeError     = "error"     // A hook failed; the entry went on unchanged
+	OutcomeAccepted  = "accepted"  // A writer wrote the entry
+	OutcomeRejected  = "rejected"  // A writer failed to write the entry
+)
+
+// ProvenanceStep records what one pipeline stage did to an entry
+type ProvenanceStep struct {
+	Stage   string        `json:"stage"`
+	Name    string        `json:"name,omitempty"` // Hook or writer name
+	Outcome string        `json:"outcome"`
+	Error   string        `json:"error,omitempty"`
+	Changes []FieldChange `json:"changes,omitempty"` // Fields changed by a hook
+}
+
+// Provenance is the trace of one entry through the logging pipeline: which
+// hooks modified or filtered it and which writers accepted or rejected it.
+// Writer steps of async loggers are added when the worker writes the entry.
+type Provenance struct {
+	Timestamp time.Time        `json:"timestamp"`
+	Level     string           `json:"level"`
+	Message   string           `json:"message,omitempty"` // Message as written; empty if never written
+	Steps     []ProvenanceStep `json:"steps"`
+}
+
+// provenanceTrace is the trace carried by an entry through the pipeline
+type provenanceTrace struct {
+	mu     sync.Mutex
+	record Provenance
+}
+
+// add appends a step to the trace; it does nothing on a nil trace
+func (t *provenanceTrace) add(step ProvenanceStep) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.record.Steps = append(t.record.Steps, step)
+	t.mu.Unlock()
+}
+
+// settle replaces the values recorded by hook steps with the values the
+// entry left the hooks with, so a secret removed by a later redaction hook
+// is not kept in the trace. Recorded values the entry no longer has are
+// left out. written is nil for entries filtered by a hook; all values are
+// left out then, as redaction may not have run.
+func (t *provenanceTrace) settle(written *CoreLogEntry) {
+	if t == nil {
+		return
+	}
+	final := make(map[string]interface{})
+	if written != nil {
+		for _, change := range diffEntries(CoreLogEntry{}, *written) {
+			final[change.Field] = change.After
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if written != nil {
+		t.record.Message = written.Message
+	}
+	for i := range t.record.Steps {
+		for j := range t.record.Steps[i].Changes {
+			change := &t.record.Steps[i].Changes[j]
+			value, ok := final[change.Field]
+			if !ok || !reflect.DeepEqual(change.Before, value) {
+				change.Before = nil
+			}
+			if !ok || !reflect.DeepEqual(change.After, value) {
+				change.After = nil
+			}
+		}
+	}
+}
+
+// snapshot returns a copy of the trace
+func (t *provenanceTrace) snapshot() Provenance {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	record := t.record
+	record.Steps = make([]ProvenanceStep, len(t.record.Steps))
+	for i, step := range t.record.Steps {
+		step.Changes = append([]FieldChange(nil), step.Changes...)
+		record.Steps[i] = step
+	}
+	return record
+}
+
+// provenanceLog keeps the most recent traces of a logger and its children
+type provenanceLog struct {
+	enabled atomic.Bool
+	mu      sync.Mutex
+	traces  []*provenanceTrace // Ring of the most recent traces
+	next    int
+}
+
+// start begins the trace of an entry, or returns nil if provenance is off.
+// The message is recorded by settle once the hooks have run.
+func (p *provenanceLog) start(level LogLevel) *provenanceTrace {
+	if p == nil || !p.enabled.Load() {
+		return nil
+	}
+	trace := &provenanceTrace{record: Provenance{
+		Timestamp: time.Now().UTC(),
+		Level:     level.Name(),
+	}}
+
+	p.mu.Lock()
+	if len(p.traces) > 0 {
+		p.traces[p.next] = trace
+		p.next = (p.next + 1) % len(p.traces)
+	}
+	p.mu.Unlock()
+	return trace
+}
+
+// dropped records an entry stopped at stage before it was created. Its
+// message is not recorded, as no redaction hook has seen it.
+func (p *provenanceLog) dropped(level LogLevel, stage string) {
+	p.start(level).add(ProvenanceStep{Stage: stage, Outcome: OutcomeDropped})
+}
+
+// EnableProvenance turns on provenance mode: each entry records which hooks
+// modified or filtered it and which writers accepted or rejected it, and the
+// last size traces are kept for Provenance (default: DefaultProvenanceSize).
+// Entries dropped by the level check or sampling are traced too, so this is
+// meant for debugging sessions rather than production traffic. The setting
+// is shared with child loggers.
+func (l *LoggerCore) EnableProvenance(size int) {
+	if size <= 0 {
+		size = DefaultProvenanceSize
+	}
+	l.provenance.mu.Lock()
+	l.provenance.traces = make([]*provenanceTrace, size)
+	l.provenance.next = 0
+	l.provenance.mu.Unlock()
+	l.provenance.enabled.Store(true)
+}
+
+// DisableProvenance turns off provenance mode and discards the kept traces
+func (l *LoggerCore) DisableProvenance() {
+	l.provenance.enabled.Store(false)
+	l.provenance.mu.Lock()
+	l.provenance.traces = nil
+	l.provenance.next = 0
+	l.provenance.mu.Unlock()
+}
+
+// Provenance returns the kept traces, oldest first
+func (l *LoggerCore) Provenance() []Provenance {
+	if l.provenance == nil {
+		return nil
+	}
+	l.provenance.mu.Lock()
+	traces := make([]*provenanceTrace, 0, len(l.provenance.traces))
+	for i := range l.provenance.traces {
+		trace := l.provenance.traces[(l.provenance.next+i)%len(l.provenance.traces)]
+		if trace != nil {
+			traces = append(traces, trace)
+		}
+	}
+	l.provenance.mu.Unlock()
+
+	records := make([]Provenance, len(traces))
+	for i, trace := range traces {
+		records[i] = trace.snapshot()
+	}
+	return records
+}
+
+// traceHook records the outcome of a hook. before must be a copy taken
+// with copyEntry so in-place changes to the context are seen.
+func traceHook(trace *provenanceTrace, name string, before, after CoreLogEntry, err error) {
+	if trace == nil {
+		return
+	}
+	step := ProvenanceStep{Stage: StageHook, Name: name}
+	switch {
+	case err != nil && strings.Contains(err.Error(), "filtered by hook"):
+		step.Outcome = OutcomeFiltered
+	case err != nil:
+		step.Outcome = OutcomeError
+		step.Error = err.Error()
+	default:
+		step.Changes = diffEntries(before, after)
+		step.Outcome = OutcomeUnchanged
+		if len(step.Changes) > 0 {
+			step.Outcome = OutcomeModified
+		}
+	}
+	trace.add(step)
+}
+
+// traceWriter records the outcome of a writer
+func traceWriter(trace *provenanceTrace, name string, err error) {
+	if trace == nil {
+		return
+	}
+	step := ProvenanceStep{Stage: StageWriter, Name: name, Outcome: OutcomeAccepted}
+	if err != nil {
+		step.Outcome = OutcomeRejected
+		step.Error = err.Error()
+	}
+	trace.add(step)
+}
+
+// hookTypeName returns the name of a legacy hook for traces
+func hookTypeName(hook LogHook) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", hook), "*")
+}
@@ -0,0 +1,314 @@
+package pim
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Context keys of rate limit summary entries
+const (
+	RateLimitKeyField        = "rate_limit_key"
+	RateLimitSuppressedField = "suppressed"
+	RateLimitSampleField     = "sample_message" // Message of the first suppressed entry
+)
+
+// RateLimitKeyFunc returns the key an entry is rate limited by; entries
+// with the same key share a token bucket
+type RateLimitKeyFunc func(entry CoreLogEntry) string
+
+var (
+	templateQuotedRegex = regexp.MustCompile(`"[^"]*"|'[^']*'`)
+	templateNumberRegex = regexp.MustCompile(`[0-9a-fA-F]{8,}|\d+`)
+)
+
+// KeyByTemplate keys entries by level and message template: the
+// MessageTemplate of entries logged with one, or the message with quoted
+// values and numbers replaced, so "user 42 failed" and "user 7 failed"
+// share a bucket
+func KeyByTemplate(entry CoreLogEntry) string {
+	if entry.MessageTemplate != "" {
+		return strconv.Itoa(int(entry.Level)) + " " + entry.MessageTemplate
+	}
+	template := templateQuotedRegex.ReplaceAllString(entry.Message, `"*"`)
+	return strconv.Itoa(int(entry.Level)) + " " + templateNumberRegex.ReplaceAllString(template, "#")
+}
+
+// KeyByCaller keys entries by the file and line that logged them
+func KeyByCaller(entry CoreLogEntry) string {
+	return entry.File + ":" + strconv.Itoa(entry.Line)
+}
+
+// KeyByField keys entries by the value of a context field or correlation
+// field such as user_id, see ValidationConfig.Fields for the names
+func KeyByField(name string) RateLimitKeyFunc {
+	return func(entry CoreLogEntry) string {
+		value, ok := entryField(entry, name)
+		if !ok {
+			return ""
+		}
+		return fmt.Sprint(value)
+	}
+}
+
+// RateLimitConfig configures a RateLimitedWriter
+type RateLimitConfig struct {
+	Rate            float64          `json:"rate"`             // Entries per second per key
+	Burst           int              `json:"burst"`            // Entries a key may write at once (default: Rate, at least 1)
+	MaxKeys         int              `json:"max_keys"`         // Keys tracked at once; further keys share one bucket (default: 10000)
+	SummaryInterval time.Duration    `json:"summary_interval"` // How often suppression summaries are written (default: 10 seconds)
+	Key             RateLimitKeyFunc `json:"-"`                // Extracts the bucket key; all entries share one bucket if nil
+}
+
+// RateLimitStats is a snapshot of a rate-limited writer for metrics
+type RateLimitStats struct {
+	Keys       int    `json:"keys"`       // Tracked keys
+	Suppressed uint64 `json:"suppressed"` // Entries not written
+	Summaries  uint64 `json:"summaries"`  // Summary entries written
+}
+
+// tokenBucket limits one key
+type tokenBucket struct {
+	tokens     float64
+	last       time.Time
+	suppressed uint64
+	sample     CoreLogEntry  // First entry suppressed since the last summary
+	key        string        // Key in RateLimitedWriter.buckets
+	use        *list.Element // Position in RateLimitedWriter.lru
+}
+
+// RateLimitedWriter protects a writer from bursts with a token bucket per
+// key: each key may write Burst entries at once and Rate per second after
+// that, and excess entries are dropped. Every SummaryInterval, and on Flush
+// and Close, one summary entry per key with dropped entries is written, e.g.
+// "suppressed 1243 similar messages", with the level and prefix of the
+// first dropped entry and the RateLimit fields in its context. Summaries
+// and sampling report entries are not rate limited, and dropped entries are
+// counted in the sampling report of the logger the writer is added to, see
+// ReportTo.
+type RateLimitedWriter struct {
+	writer LogWriter
+	config RateLimitConfig
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	lru     *list.List // Buckets from least to most recently used
+	stats   RateLimitStats
+	now     func() time.Time
+	report  atomic.Pointer[samplingReporter] // Counts dropped entries, see ReportTo
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewRateLimitedWriter creates a writer letting through maxPerSecond
+// entries per second, with bursts of up to maxPerSecond
+func NewRateLimitedWriter(writer LogWriter, maxPerSecond int) *RateLimitedWriter {
+	return NewKeyedRateLimitedWriter(writer, RateLimitConfig{Rate: float64(maxPerSecond)})
+}
+
+// NewKeyedRateLimitedWriter creates a writer rate limiting entries per key.
+// A goroutine writes suppression summaries until Close is called.
+func NewKeyedRateLimitedWriter(writer LogWriter, config RateLimitConfig) *RateLimitedWriter {
+	if config.Rate <= 0 {
+		config.Rate = 1
+	}
+	if config.Burst <= 0 {
+		config.Burst = max(int(config.Rate), 1)
+	}
+	if config.MaxKeys <= 0 {
+		config.MaxKeys = 10000
+	}
+	if config.SummaryInterval <= 0 {
+		config.SummaryInterval = 10 * time.Second
+	}
+	w := &RateLimitedWriter{
+		writer:  writer,
+		config:  config,
+		buckets: make(map[string]*tokenBucket),
+		lru:     list.New(),
+		now:     time.Now,
+		stopCh:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// run writes summaries every SummaryInterval until the writer is closed
+func (w *RateLimitedWriter) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.config.SummaryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stopCh:
+			return
+		case <-ticker.C:
+			if err := w.summarize(); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to write rate limit summary: %v\n", err)
+			}
+		}
+	}
+}
+
+// Write implements LogWriter interface. Dropped entries return nil.
+func (w *RateLimitedWriter) Write(entry CoreLogEntry) error {
+	if entry.Prefix != SamplingReportPrefix && !w.allow(entry) {
+		w.report.Load().dropped(entry.Level, entryTemplate(entry), DropReasonRateLimit)
+		return nil
+	}
+	return w.writer.Write(entry)
+}
+
+// Flush implements LogWriter interface. Pending summaries are written first.
+func (w *RateLimitedWriter) Flush() error {
+	return errors.Join(w.summarize(), w.writer.Flush())
+}
+
+// Close implements LogWriter interface
+func (w *RateLimitedWriter) Close() error {
+	w.stopOnce.Do(func() { close(w.stopCh) })
+	<-w.done
+	return errors.Join(w.summarize(), w.writer.Close())
+}
+
+// ReportTo counts the entries the writer drops in the sampling report of
+// logger, see ReportSampling; it does nothing if the logger has no
+// SamplingReportInterval. AddWriter and AddNamedWriter call it when they
+// add the writer itself, so it is only needed for wrapped writers.
+func (w *RateLimitedWriter) ReportTo(logger *LoggerCore) {
+	if logger.samplingReport != nil {
+		w.report.Store(logger.samplingReport)
+	}
+}
+
+// entryTemplate returns the MessageTemplate of entry, or its message
+func entryTemplate(entry CoreLogEntry) string {
+	if entry.MessageTemplate != "" {
+		return entry.MessageTemplate
+	}
+	return entry.Message
+}
+
+// Stats returns a snapshot of the rate limit counters
+func (w *RateLimitedWriter) Stats() RateLimitStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	stats := w.stats
+	stats.Keys = len(w.buckets)
+	return stats
+}
+
+// allow takes a token from the entry's bucket, or counts the entry as
+// suppressed if the bucket is empty
+func (w *RateLimitedWriter) allow(entry CoreLogEntry) bool {
+	var key string
+	if w.config.Key != nil {
+		key = w.config.Key(entry)
+	}
+	now := w.now()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	bucket := w.bucket(key, now)
+	bucket.tokens = min(bucket.tokens+now.Sub(bucket.last).Seconds()*w.config.Rate, float64(w.config.Burst))
+	bucket.last = now
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true
+	}
+
+	if bucket.suppressed == 0 {
+		bucket.sample = entry
+	}
+	bucket.suppressed++
+	w.stats.Suppressed++
+	return false
+}
+
+// bucket returns the bucket of key, creating a full one if needed. When
+// MaxKeys are tracked, the least recently used buckets are forgotten while
+// they have refilled, and further keys share the empty key's bucket. The
+// caller holds w.mu.
+func (w *RateLimitedWriter) bucket(key string, now time.Time) *tokenBucket {
+	if bucket, ok := w.buckets[key]; ok {
+		w.lru.MoveToBack(bucket.use)
+		return bucket
+	}
+	for len(w.buckets) >= w.config.MaxKeys {
+		oldest := w.lru.Front().Value.(*tokenBucket)
+		refilled := oldest.tokens+now.Sub(oldest.last).Seconds()*w.config.Rate >= float64(w.config.Burst)
+		if !refilled || oldest.suppressed > 0 {
+			break
+		}
+		w.lru.Remove(oldest.use)
+		delete(w.buckets, oldest.key)
+	}
+	if len(w.buckets) >= w.config.MaxKeys {
+		key = ""
+		if bucket, ok := w.buckets[key]; ok {
+			w.lru.MoveToBack(bucket.use)
+			return bucket
+		}
+	}
+	bucket := &tokenBucket{tokens: float64(w.config.Burst), last: now, key: key}
+	bucket.use = w.lru.PushBack(bucket)
+	w.buckets[key] = bucket
+	return bucket
+}
+
+// summarize writes one summary entry per key with suppressed entries
+func (w *RateLimitedWriter) summarize() error {
+	now := w.now()
+	var summaries []CoreLogEntry
+
+	w.mu.Lock()
+	keys := make([]string, 0, len(w.buckets))
+	for key, bucket := range w.buckets {
+		if bucket.suppressed > 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		bucket := w.buckets[key]
+		summaries = append(summaries, CoreLogEntry{
+			Timestamp:   now,
+			Level:       bucket.sample.Level,
+			LevelString: bucket.sample.LevelString,
+			Prefix:      bucket.sample.Prefix,
+			Message:     fmt.Sprintf("suppressed %d similar messages", bucket.suppressed),
+			ServiceName: bucket.sample.ServiceName,
+			LoggerName:  bucket.sample.LoggerName,
+			Hostname:    bucket.sample.Hostname,
+			PID:         bucket.sample.PID,
+			Context: map[string]interface{}{
+				RateLimitKeyField:        key,
+				RateLimitSuppressedField: bucket.suppressed,
+				RateLimitSampleField:     bucket.sample.Message,
+			},
+		})
+		bucket.suppressed = 0
+		bucket.sample = CoreLogEntry{}
+		w.stats.Summaries++
+	}
+	w.mu.Unlock()
+
+	var errs []error
+	for _, summary := range summaries {
+		if err := w.writer.Write(summary); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
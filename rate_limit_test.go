@@ -0,0 +1,152 @@
+package pim
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestRateLimitedWriter returns a rate-limited writer over a buffer with
+// a clock the test advances
+func newTestRateLimitedWriter(config RateLimitConfig) (*RateLimitedWriter, *BufferWriter, *time.Time) {
+	buffer := NewBufferWriter(LoggerConfig{}, 1000)
+	writer := NewKeyedRateLimitedWriter(buffer, config)
+	now := time.Now()
+	writer.now = func() time.Time { return now }
+	return writer, buffer, &now
+}
+
+func TestRateLimitedWriterBurstAndRefill(t *testing.T) {
+	writer, buffer, now := newTestRateLimitedWriter(RateLimitConfig{Rate: 2, Burst: 5, SummaryInterval: time.Hour})
+	defer writer.Close()
+
+	for i := 0; i < 10; i++ {
+		writer.Write(CoreLogEntry{Message: "burst"})
+	}
+	if size := buffer.GetBufferSize(); size != 5 {
+		t.Fatalf("Expected the burst size to be written, got %d entries", size)
+	}
+
+	*now = now.Add(time.Second)
+	for i := 0; i < 10; i++ {
+		writer.Write(CoreLogEntry{Message: "burst"})
+	}
+	if size := buffer.GetBufferSize(); size != 7 {
+		t.Errorf("Expected 2 more entries after a second at rate 2, got %d entries", size)
+	}
+	if stats := writer.Stats(); stats.Suppressed != 13 || stats.Keys != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestRateLimitedWriterSummary(t *testing.T) {
+	writer, buffer, _ := newTestRateLimitedWriter(RateLimitConfig{Rate: 1, SummaryInterval: time.Hour, Key: KeyByTemplate})
+	defer writer.Close()
+
+	writer.Write(CoreLogEntry{Level: ErrorLevel, LevelString: "error", Prefix: ErrorPrefix, Message: "user 1 failed"})
+	for i := 2; i <= 4; i++ {
+		writer.Write(CoreLogEntry{Level: ErrorLevel, LevelString: "error", Prefix: ErrorPrefix, Message: "user " + string(rune('0'+i)) + " failed"})
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 2 {
+		t.Fatalf("Expected first entry and summary, got %d entries", len(entries))
+	}
+	summary := entries[1]
+	if summary.Message != "suppressed 3 similar messages" || summary.Level != ErrorLevel || summary.Prefix != ErrorPrefix {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	if summary.Context[RateLimitSuppressedField] != uint64(3) || summary.Context[RateLimitSampleField] != "user 2 failed" ||
+		summary.Context[RateLimitKeyField] != "1 user # failed" {
+		t.Errorf("Unexpected summary fields: %v", summary.Context)
+	}
+
+	writer.Flush()
+	if size := buffer.GetBufferSize(); size != 2 {
+		t.Errorf("Expected no summary without new suppressions, got %d entries", size)
+	}
+}
+
+func TestRateLimitedWriterKeys(t *testing.T) {
+	writer, buffer, _ := newTestRateLimitedWriter(RateLimitConfig{Rate: 1, SummaryInterval: time.Hour, Key: KeyByField("user_id")})
+	defer writer.Close()
+
+	writer.Write(CoreLogEntry{Message: "a", UserID: "alice"})
+	writer.Write(CoreLogEntry{Message: "b", UserID: "alice"})
+	writer.Write(CoreLogEntry{Message: "c", Context: map[string]interface{}{"user_id": "bob"}})
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 2 || entries[0].Message != "a" || entries[1].Message != "c" {
+		t.Errorf("Expected one entry per user, got %+v", entries)
+	}
+}
+
+func TestRateLimitedWriterMaxKeys(t *testing.T) {
+	writer, buffer, now := newTestRateLimitedWriter(RateLimitConfig{Rate: 1, MaxKeys: 1, SummaryInterval: time.Hour, Key: KeyByCaller})
+	defer writer.Close()
+
+	writer.Write(CoreLogEntry{File: "a.go", Line: 1})
+	writer.Write(CoreLogEntry{File: "a.go", Line: 1}) // Suppressed, so a.go:1 is kept
+	writer.Write(CoreLogEntry{File: "b.go", Line: 2}) // Shares the overflow bucket
+	writer.Write(CoreLogEntry{File: "c.go", Line: 3}) // Suppressed by the overflow bucket
+	if size := buffer.GetBufferSize(); size != 2 {
+		t.Fatalf("Expected 2 entries, got %d", size)
+	}
+
+	writer.Flush()
+	*now = now.Add(time.Minute)
+	writer.Write(CoreLogEntry{File: "d.go", Line: 4}) // Refilled buckets are forgotten
+	if stats := writer.Stats(); stats.Keys != 1 {
+		t.Errorf("Expected refilled buckets to be forgotten, got %d keys", stats.Keys)
+	}
+}
+
+func TestRateLimitedWriterForgetsLeastRecentlyUsed(t *testing.T) {
+	writer, _, now := newTestRateLimitedWriter(RateLimitConfig{Rate: 1, Burst: 5, MaxKeys: 2, SummaryInterval: time.Hour, Key: KeyByCaller})
+	defer writer.Close()
+
+	writer.Write(CoreLogEntry{File: "a.go", Line: 1})
+	writer.Write(CoreLogEntry{File: "b.go", Line: 2})
+	*now = now.Add(time.Minute)
+	writer.Write(CoreLogEntry{File: "a.go", Line: 1})
+	writer.Write(CoreLogEntry{File: "c.go", Line: 3})
+
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+	_, a := writer.buckets["a.go:1"]
+	_, b := writer.buckets["b.go:2"]
+	if !a || b || len(writer.buckets) != 2 || writer.lru.Len() != 2 {
+		t.Errorf("Expected b.go:2 to be forgotten first, got %v", writer.buckets)
+	}
+}
+
+func TestRateLimitedWriterPeriodicSummary(t *testing.T) {
+	buffer := NewBufferWriter(LoggerConfig{}, 100)
+	writer := NewKeyedRateLimitedWriter(buffer, RateLimitConfig{Rate: 1, SummaryInterval: 20 * time.Millisecond})
+	defer writer.Close()
+
+	writer.Write(CoreLogEntry{Message: "a"})
+	writer.Write(CoreLogEntry{Message: "a"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for buffer.GetBufferSize() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if size := buffer.GetBufferSize(); size != 2 {
+		t.Errorf("Expected a summary after the interval, got %d entries", size)
+	}
+}
+
+func TestKeyByTemplate(t *testing.T) {
+	a := KeyByTemplate(CoreLogEntry{Level: ErrorLevel, Message: `request 4821 for "alice" failed after 3 retries`})
+	b := KeyByTemplate(CoreLogEntry{Level: ErrorLevel, Message: `request 17 for "bob" failed after 5 retries`})
+	c := KeyByTemplate(CoreLogEntry{Level: WarningLevel, Message: `request 17 for "bob" failed after 5 retries`})
+	if a != b {
+		t.Errorf("Expected the same template, got %q and %q", a, b)
+	}
+	if b == c {
+		t.Error("Expected levels to be distinguished")
+	}
+}
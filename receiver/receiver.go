@@ -0,0 +1,108 @@
+// Package receiver provides an http.Handler that ingests batches sent by
+// pim's RemoteWriter, so custom collectors can speak pim's wire format
+// without re-implementing parsing.
+package receiver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/refactorroom/pim"
+)
+
+// Callback receives each decoded batch. Returning an error makes the handler
+// respond with a server error so the sender retries the batch.
+type Callback func(entries []pim.CoreLogEntry) error
+
+// Config configures the receiver handler
+type Config struct {
+	Callback    Callback              // Called with every decoded batch
+	Writers     []pim.LogWriter       // Writer chain that receives every decoded entry
+	Keyring     *pim.BatchKeyring     // Decrypts batches from senders with encryption enabled
+	Hooks       []pim.EnhancedLogHook // Applied to every entry before delivery (e.g. pim.ClockSkewHook)
+	MaxBodySize int64                 // Maximum accepted batch size in bytes (default: 10 MiB)
+}
+
+// Handler decodes RemoteWriter batches and hands them to a callback and/or writer chain
+type Handler struct {
+	config Config
+	hooks  *pim.HookManager
+	writer pim.LogWriter
+}
+
+// NewHandler creates a new receiver handler
+func NewHandler(config Config) *Handler {
+	if config.MaxBodySize == 0 {
+		config.MaxBodySize = 10 << 20
+	}
+
+	handler := &Handler{config: config}
+	if len(config.Hooks) > 0 {
+		handler.hooks = pim.NewHookManager()
+		for _, hook := range config.Hooks {
+			handler.hooks.AddHook(hook)
+		}
+	}
+	if len(config.Writers) > 0 {
+		handler.writer = pim.NewMultiWriter(config.Writers...)
+	}
+	return handler
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.config.MaxBodySize)
+	entries, err := pim.DecodeBatchRequest(r, h.config.Keyring)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, pim.ErrUnsupportedBatchFormat) {
+			status = http.StatusUnsupportedMediaType
+			w.Header().Set("Accept", pim.AcceptedBatchFormats)
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if err := h.Deliver(entries); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Deliver applies the configured hooks and hands decoded entries to the callback and writer chain
+func (h *Handler) Deliver(entries []pim.CoreLogEntry) error {
+	if h.hooks != nil {
+		processed := make([]pim.CoreLogEntry, 0, len(entries))
+		for _, entry := range entries {
+			if entry, err := h.hooks.ProcessHooks(entry); err == nil {
+				processed = append(processed, entry)
+			}
+		}
+		entries = processed
+	}
+
+	if h.config.Callback != nil {
+		if err := h.config.Callback(entries); err != nil {
+			return fmt.Errorf("callback failed: %w", err)
+		}
+	}
+
+	if h.writer != nil {
+		for _, entry := range entries {
+			if err := h.writer.Write(entry); err != nil {
+				return fmt.Errorf("writer chain failed: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
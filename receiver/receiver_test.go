@@ -0,0 +1,96 @@
+package receiver
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/refactorroom/pim"
+)
+
+func TestHandlerDeliversToCallbackAndWriters(t *testing.T) {
+	var received []pim.CoreLogEntry
+	buffer := pim.NewBufferWriter(pim.LoggerConfig{}, 10)
+
+	handler := NewHandler(Config{
+		Callback: func(entries []pim.CoreLogEntry) error {
+			received = append(received, entries...)
+			return nil
+		},
+		Writers: []pim.LogWriter{buffer},
+	})
+
+	body := `[{"level":3,"level_string":"info","message":"hello","context":{"k":"v"}}]`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(received) != 1 || received[0].Message != "hello" || received[0].Level != pim.InfoLevel {
+		t.Errorf("Expected decoded entry, got: %+v", received)
+	}
+	if buffer.GetBufferSize() != 1 {
+		t.Errorf("Expected writer chain to receive 1 entry, got %d", buffer.GetBufferSize())
+	}
+}
+
+func TestHandlerCallbackError(t *testing.T) {
+	handler := NewHandler(Config{
+		Callback: func(entries []pim.CoreLogEntry) error {
+			return errors.New("storage unavailable")
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`[]`))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", rec.Code)
+	}
+}
+
+func TestHandlerRejectsMalformedBatches(t *testing.T) {
+	handler := NewHandler(Config{})
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{not json`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestHandlerAppliesHooks(t *testing.T) {
+	var received []pim.CoreLogEntry
+	handler := NewHandler(Config{
+		Callback: func(entries []pim.CoreLogEntry) error {
+			received = entries
+			return nil
+		},
+		Hooks: []pim.EnhancedLogHook{
+			pim.NewClockSkewHook(pim.ClockSkewConfig{
+				HookConfig: pim.HookConfig{Type: pim.HookTypeEnrich, Name: "clock_skew", Enabled: true},
+			}),
+		},
+	})
+
+	body := `[{"timestamp":"2000-01-01T00:00:00Z","level":3,"message":"old device"}]`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if len(received) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(received))
+	}
+	if received[0].Context[pim.ClockSkewDetectedKey] != true {
+		t.Errorf("Expected skewed entry to be flagged, got: %v", received[0].Context)
+	}
+}
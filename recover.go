@@ -0,0 +1,149 @@
+package pim
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// PanicRecoveredMessage is the message of the entry logged for a recovered panic
+const PanicRecoveredMessage = "panic recovered"
+
+// RecoverConfig configures RecoverAndLogWithConfig and RecoverMiddleware
+type RecoverConfig struct {
+	RePanic       bool `json:"re_panic"`       // Panic again after logging, after flushing the logger
+	GoroutineDump bool `json:"goroutine_dump"` // Add the stacks of all goroutines
+	MaxDumpSize   int  `json:"max_dump_size"`  // Limit of the goroutine dump in bytes (default: 1 MiB)
+}
+
+// DefaultRecoverConfig logs a goroutine dump and does not panic again
+var DefaultRecoverConfig = RecoverConfig{
+	GoroutineDump: true,
+	MaxDumpSize:   1 << 20,
+}
+
+// RecoverAndLog recovers a panic and logs it with DefaultRecoverConfig. It
+// must be deferred directly, since recover only works there:
+//
+//	go func() {
+//		defer pim.RecoverAndLog(logger)
+//		work()
+//	}()
+func RecoverAndLog(logger *LoggerCore) {
+	if r := recover(); r != nil {
+		logPanic(logger, r, DefaultRecoverConfig, nil)
+	}
+}
+
+// RecoverAndLogWithConfig recovers a panic and logs it as a Panic entry
+// with the panic value, the stack of the panicking goroutine, optionally a
+// dump of all goroutines, and runtime statistics; the fields of a
+// PanicError from LoggerCore.Panic are included. With RePanic queued
+// entries are written and the writers flushed before the panic continues;
+// async logging keeps running. Like RecoverAndLog it must be
+// deferred directly.
+func RecoverAndLogWithConfig(logger *LoggerCore, config RecoverConfig) {
+	if r := recover(); r != nil {
+		logPanic(logger, r, config, nil)
+		if config.RePanic {
+			panic(r)
+		}
+	}
+}
+
+// RecoverMiddleware returns HTTP middleware that recovers panics in
+// handlers, logs them like RecoverAndLogWithConfig with the request method
+// and path, and responds with 500 Internal Server Error if the handler did
+// not write a response yet. http.ErrAbortHandler is passed through without
+// logging. Place it inside HTTPMiddleware to log with the request logger.
+func RecoverMiddleware(logger *LoggerCore, config RecoverConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			recorder := &statusRecorder{ResponseWriter: w}
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(rec)
+				}
+
+				requestLogger := logger
+				if scoped := RequestLogger(r); scoped != nil {
+					requestLogger = scoped
+				}
+				logPanic(requestLogger, rec, config, map[string]interface{}{
+					"method": r.Method,
+					"path":   r.URL.Path,
+				})
+				if config.RePanic {
+					panic(rec)
+				}
+				if recorder.status == 0 {
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				}
+			}()
+			next.ServeHTTP(recorder, r)
+		})
+	}
+}
+
+// logPanic logs a recovered panic value
+func logPanic(logger *LoggerCore, r interface{}, config RecoverConfig, extra map[string]interface{}) {
+	fields := make(map[string]interface{}, len(extra)+6)
+	for k, v := range panicFields(r) {
+		fields[k] = v
+	}
+	for k, v := range extra {
+		fields[k] = v
+	}
+	fields["panic"] = fmt.Sprint(r)
+	if err, ok := r.(error); ok {
+		fields["error"] = err
+	}
+	fields["stack"] = string(debug.Stack())
+	if config.GoroutineDump {
+		fields["goroutines"] = goroutineDump(config.MaxDumpSize)
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	fields["runtime"] = map[string]interface{}{
+		"goroutine_count": runtime.NumGoroutine(),
+		"heap_alloc":      mem.HeapAlloc,
+		"heap_sys":        mem.HeapSys,
+		"heap_objects":    mem.HeapObjects,
+		"sys":             mem.Sys,
+		"num_gc":          mem.NumGC,
+		"pause_total_ns":  mem.PauseTotalNs,
+	}
+
+	// Panic entries pass every level, so only sampling could drop it
+	logger.LogWithContext(PanicLevel, PanicPrefix, PanicRecoveredMessage, fields, BypassSampling())
+	if config.RePanic {
+		// The process may be about to exit; write the entry without
+		// stopping async logging in case the panic is recovered further up
+		logger.drain()
+	}
+}
+
+// goroutineDump returns the stacks of all goroutines, cut at maxSize bytes
+func goroutineDump(maxSize int) string {
+	if maxSize <= 0 {
+		maxSize = DefaultRecoverConfig.MaxDumpSize
+	}
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= maxSize {
+			if n > maxSize {
+				n = maxSize
+			}
+			return string(buf[:n])
+		}
+		buf = make([]byte, min(2*len(buf), maxSize))
+	}
+}
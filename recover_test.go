@@ -0,0 +1,134 @@
+package pim
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecoverAndLog(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer RecoverAndLog(logger)
+		panic(errors.New("boom"))
+	}()
+	<-done
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	entry := entries[0]
+	if entry.Level != PanicLevel || entry.Message != PanicRecoveredMessage {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	if entry.Context["panic"] != "boom" || entry.Context["error"] == nil {
+		t.Errorf("Expected the panic value, got %v", entry.Context)
+	}
+	if stack, _ := entry.Context["stack"].(string); !strings.Contains(stack, "TestRecoverAndLog") {
+		t.Errorf("Expected the panicking stack, got %q", stack)
+	}
+	if dump, _ := entry.Context["goroutines"].(string); !strings.Contains(dump, "goroutine ") {
+		t.Errorf("Expected a goroutine dump, got %q", dump)
+	}
+	stats, _ := entry.Context["runtime"].(map[string]interface{})
+	if stats["goroutine_count"] == nil || stats["heap_alloc"] == nil {
+		t.Errorf("Expected runtime stats, got %v", entry.Context["runtime"])
+	}
+}
+
+func TestRecoverAndLogWithConfigRePanics(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+
+	defer func() {
+		if r := recover(); r != "again" {
+			t.Errorf("Expected the panic to continue, got %v", r)
+		}
+		if buffer.GetBufferSize() != 1 {
+			t.Errorf("Expected the panic to be logged first")
+		}
+	}()
+	func() {
+		defer RecoverAndLogWithConfig(logger, RecoverConfig{RePanic: true})
+		panic("again")
+	}()
+}
+
+func TestRecoverRePanicKeepsAsyncLogging(t *testing.T) {
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.Async = true
+	})
+
+	func() {
+		defer func() { recover() }()
+		defer RecoverAndLogWithConfig(logger, RecoverConfig{RePanic: true})
+		logger.Info("before")
+		panic("again")
+	}()
+	if size := buffer.GetBufferSize(); size != 2 {
+		t.Fatalf("Expected the queued entries written before the panic continues, got %d", size)
+	}
+	if logger.asyncCtx.Err() != nil {
+		t.Error("Expected async logging to keep running")
+	}
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+
+	handler := RecoverMiddleware(logger, DefaultRecoverConfig)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("handler failed")
+	}))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/orders", nil))
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500, got %d", recorder.Code)
+	}
+	entries := buffer.GetBuffer()
+	if len(entries) != 1 || entries[0].Context["path"] != "/orders" || entries[0].Context["method"] != "GET" {
+		t.Errorf("Expected one entry with the request, got %+v", entries)
+	}
+}
+
+func TestRecoverMiddlewareSupportsResponseController(t *testing.T) {
+	logger, _ := newTestLogger(t, nil)
+
+	var flushErr error
+	var unwrapped http.ResponseWriter
+	recorder := httptest.NewRecorder()
+	handler := RecoverMiddleware(logger, DefaultRecoverConfig)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flushErr = http.NewResponseController(w).Flush()
+		unwrapped = w.(interface{ Unwrap() http.ResponseWriter }).Unwrap()
+	}))
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	if flushErr != nil || !recorder.Flushed {
+		t.Errorf("Expected the flush to reach the response writer, got %v", flushErr)
+	}
+	if unwrapped != recorder {
+		t.Errorf("Expected Unwrap to return the response writer, got %T", unwrapped)
+	}
+}
+
+func TestRecoverMiddlewarePassesAbortHandler(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+
+	handler := RecoverMiddleware(logger, DefaultRecoverConfig)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if r := recover(); r != http.ErrAbortHandler {
+			t.Errorf("Expected ErrAbortHandler to pass through, got %v", r)
+		}
+		if buffer.GetBufferSize() != 0 {
+			t.Error("Expected ErrAbortHandler not to be logged")
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
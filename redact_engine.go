@@ -0,0 +1,393 @@
+package pim
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// PIIDetector names a built-in detector of personal data in free text
+type PIIDetector string
+
+const (
+	DetectEmail      PIIDetector = "email"
+	DetectCreditCard PIIDetector = "credit_card" // Validated with the Luhn check
+	DetectIBAN       PIIDetector = "iban"        // Validated with the ISO 13616 mod-97 check
+	DetectNationalID PIIDetector = "national_id" // US social security and UK national insurance numbers
+	DetectJWT        PIIDetector = "jwt"
+	DetectIP         PIIDetector = "ip" // IPv4 and IPv6 addresses
+)
+
+// AllPIIDetectors lists every built-in detector
+var AllPIIDetectors = []PIIDetector{
+	DetectEmail, DetectCreditCard, DetectIBAN, DetectNationalID, DetectJWT, DetectIP,
+}
+
+// piiDetector is a compiled detector. valid, if set, rejects candidates
+// that only look right; bounded detectors also reject matches that are part
+// of a longer word or address, which Go regexps cannot look around for.
+type piiDetector struct {
+	regex   *regexp.Regexp
+	valid   func(string) bool
+	bounded bool
+}
+
+var piiDetectors = map[PIIDetector]piiDetector{
+	DetectEmail: {
+		regex: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`),
+	},
+	DetectCreditCard: {
+		regex: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		valid: luhnValid,
+	},
+	DetectIBAN: {
+		regex: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`),
+		valid: ibanValid,
+	},
+	DetectNationalID: {
+		regex: regexp.MustCompile(`\b(?:\d{3}-\d{2}-\d{4}|[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z] ?\d{2} ?\d{2} ?\d{2} ?[A-D])\b`),
+		valid: nationalIDValid,
+	},
+	DetectJWT: {
+		regex: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`),
+	},
+	DetectIP: {
+		regex:   regexp.MustCompile(`(?:\d{1,3}\.){3}\d{1,3}|[0-9A-Fa-f]{0,4}(?::[0-9A-Fa-f]{0,4}){2,7}`),
+		valid:   func(s string) bool { return net.ParseIP(s) != nil },
+		bounded: true,
+	},
+}
+
+// luhnValid reports whether the digits of s pass the Luhn check
+func luhnValid(s string) bool {
+	digits := 0
+	sum := 0
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+		digits++
+	}
+	return digits >= 13 && digits <= 19 && sum%10 == 0
+}
+
+// ibanValid reports whether s is an IBAN with a correct check number
+func ibanValid(s string) bool {
+	s = strings.ReplaceAll(s, " ", "")
+	if len(s) < 15 || len(s) > 34 {
+		return false
+	}
+	// Move the country code and check digits to the end and read letters
+	// as numbers (A=10 ... Z=35); the result mod 97 must be 1
+	var numeric strings.Builder
+	for _, c := range s[4:] + s[:4] {
+		if c >= 'A' && c <= 'Z' {
+			fmt.Fprintf(&numeric, "%d", c-'A'+10)
+		} else {
+			numeric.WriteRune(c)
+		}
+	}
+	n, ok := new(big.Int).SetString(numeric.String(), 10)
+	return ok && n.Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// nationalIDValid rejects SSNs in the ranges never issued
+func nationalIDValid(s string) bool {
+	if len(s) != 11 || s[3] != '-' {
+		return true // National insurance number, validated by the regex
+	}
+	area, group, serial := s[:3], s[4:6], s[7:]
+	return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+}
+
+// RedactStrategy is how a redaction hook replaces the values it redacts
+type RedactStrategy string
+
+const (
+	// RedactReplace replaces values with RedactConfig.Replacement (default)
+	RedactReplace RedactStrategy = "replace"
+	// RedactMaskLast4 masks all but the last 4 characters with '*'
+	RedactMaskLast4 RedactStrategy = "mask_last4"
+	// RedactHash replaces values with "<algorithm>:<hex>" of a salted hash,
+	// so equal values stay correlatable without being readable
+	RedactHash RedactStrategy = "hash"
+	// RedactTokenize replaces values with tokens from a TokenVault, which
+	// can map them back to the original values
+	RedactTokenize RedactStrategy = "tokenize"
+)
+
+// TokenVault issues tokens for redacted values. Tokenize must return the
+// same token for the same value.
+type TokenVault interface {
+	Tokenize(value string) string
+}
+
+// DefaultTokenVaultSize is the number of values kept by NewMemoryTokenVault
+const DefaultTokenVaultSize = 100000
+
+// MemoryTokenVault is an in-memory TokenVault issuing random "tok_" tokens.
+// It keeps a bounded number of values: when full, the oldest value is
+// evicted, its token can no longer be detokenized and the value gets a new
+// token if seen again.
+type MemoryTokenVault struct {
+	mu     sync.RWMutex
+	tokens map[string]string
+	values map[string]string
+	order  []string // Ring of values in the order they were tokenized
+	next   int      // Index of the oldest value once the ring is full
+	size   int
+}
+
+// NewMemoryTokenVault creates an empty in-memory token vault keeping
+// DefaultTokenVaultSize values
+func NewMemoryTokenVault() *MemoryTokenVault {
+	return NewBoundedMemoryTokenVault(DefaultTokenVaultSize)
+}
+
+// NewBoundedMemoryTokenVault creates an empty in-memory token vault keeping
+// at most size values (default: DefaultTokenVaultSize)
+func NewBoundedMemoryTokenVault(size int) *MemoryTokenVault {
+	if size <= 0 {
+		size = DefaultTokenVaultSize
+	}
+	return &MemoryTokenVault{
+		tokens: make(map[string]string),
+		values: make(map[string]string),
+		order:  make([]string, 0, min(size, 1024)),
+		size:   size,
+	}
+}
+
+// Tokenize implements TokenVault interface
+func (v *MemoryTokenVault) Tokenize(value string) string {
+	v.mu.RLock()
+	token, ok := v.tokens[value]
+	v.mu.RUnlock()
+	if ok {
+		return token
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if token, ok := v.tokens[value]; ok {
+		return token
+	}
+	token = "tok_" + newRandomID()
+	if len(v.order) < v.size {
+		v.order = append(v.order, value)
+	} else {
+		oldest := v.order[v.next]
+		delete(v.values, v.tokens[oldest])
+		delete(v.tokens, oldest)
+		v.order[v.next] = value
+		v.next = (v.next + 1) % v.size
+	}
+	v.tokens[value] = token
+	v.values[token] = value
+	return token
+}
+
+// Detokenize returns the value a token was issued for
+func (v *MemoryTokenVault) Detokenize(token string) (string, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	value, ok := v.values[token]
+	return value, ok
+}
+
+// Validate checks the detectors, strategy, hash algorithm and patterns
+func (c RedactConfig) Validate() error {
+	return c.validate(FIPSMode())
+}
+
+// validate is Validate, requiring a FIPS-approved hash algorithm if fips is
+// set
+func (c RedactConfig) validate(fips bool) error {
+	for _, detector := range c.Detectors {
+		if _, ok := piiDetectors[detector]; !ok {
+			return fmt.Errorf("unknown PII detector %q", detector)
+		}
+	}
+	switch c.Strategy {
+	case "", RedactReplace, RedactMaskLast4, RedactTokenize:
+	case RedactHash:
+		if err := c.HashAlgorithm.validate(fips); err != nil {
+			return err
+		}
+		if c.hashSalt() == "" {
+			return errors.New("hash redaction requires a salt")
+		}
+	default:
+		return fmt.Errorf("unknown redact strategy %q", c.Strategy)
+	}
+	for field, pattern := range c.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid redact pattern for %s: %w", field, err)
+		}
+	}
+	return nil
+}
+
+// hashSalt returns HashSalt, or the value of HashSaltEnv if it is empty
+func (c RedactConfig) hashSalt() string {
+	if c.HashSalt == "" && c.HashSaltEnv != "" {
+		return os.Getenv(c.HashSaltEnv)
+	}
+	return c.HashSalt
+}
+
+// redactor applies the strategy of a RedactHook
+type redactor struct {
+	strategy    RedactStrategy
+	replacement string
+	salt        []byte
+	algorithm   HashAlgorithm
+	vault       TokenVault
+	detectors   []piiDetector
+}
+
+// newRedactor compiles the strategy and detectors of config. An invalid
+// configuration falls back to the static replacement so values are never
+// leaked; it is reported on stderr.
+func newRedactor(config RedactConfig) *redactor {
+	r := &redactor{
+		strategy:    config.Strategy,
+		replacement: config.Replacement,
+		salt:        []byte(config.hashSalt()),
+		algorithm:   config.HashAlgorithm.orDefault(),
+		vault:       config.TokenVault,
+	}
+	if err := config.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid redact hook %s, using static replacement: %v\n", config.Name, err)
+		r.strategy = RedactReplace
+	}
+	for _, name := range config.Detectors {
+		if detector, ok := piiDetectors[name]; ok {
+			r.detectors = append(r.detectors, detector)
+		}
+	}
+	if r.strategy == RedactTokenize && r.vault == nil {
+		r.vault = NewMemoryTokenVault()
+	}
+	return r
+}
+
+// replace returns the replacement for a redacted value
+func (r *redactor) replace(value string) string {
+	switch r.strategy {
+	case RedactMaskLast4:
+		n := utf8.RuneCountInString(value)
+		if n <= 4 {
+			return strings.Repeat("*", n)
+		}
+		runes := []rune(value)
+		return strings.Repeat("*", n-4) + string(runes[n-4:])
+	case RedactHash:
+		h, err := r.algorithm.New()
+		if err != nil {
+			return r.replacement // FIPS mode was turned on after the hook was created
+		}
+		h.Write(r.salt)
+		h.Write([]byte(value))
+		return string(r.algorithm) + ":" + hex.EncodeToString(h.Sum(nil))
+	case RedactTokenize:
+		return r.vault.Tokenize(value)
+	default:
+		return r.replacement
+	}
+}
+
+// replaceValue returns the replacement for a value of a redacted field;
+// values other than strings are redacted in their JSON form
+func (r *redactor) replaceValue(value interface{}) interface{} {
+	if r.strategy == "" || r.strategy == RedactReplace {
+		return r.replacement
+	}
+	switch v := value.(type) {
+	case string:
+		return r.replace(v)
+	case nil:
+		return r.replace("")
+	case error:
+		return r.replace(v.Error())
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return r.replace(fmt.Sprint(value))
+	}
+	return r.replace(string(data))
+}
+
+// scan replaces the values found by the detectors in s
+func (r *redactor) scan(s string) (string, bool) {
+	changed := false
+	for _, detector := range r.detectors {
+		matches := detector.regex.FindAllStringIndex(s, -1)
+		if len(matches) == 0 {
+			continue
+		}
+
+		var b strings.Builder
+		last := 0
+		for _, match := range matches {
+			start, end := match[0], match[1]
+			if detector.bounded && !standsAlone(s, start, end) {
+				continue
+			}
+			if detector.valid != nil && !detector.valid(s[start:end]) {
+				continue
+			}
+			b.WriteString(s[last:start])
+			b.WriteString(r.replace(s[start:end]))
+			last = end
+		}
+		if last > 0 {
+			b.WriteString(s[last:])
+			s, changed = b.String(), true
+		}
+	}
+	return s, changed
+}
+
+// standsAlone reports whether s[start:end] is not part of a longer word,
+// number or address; a trailing dot ending a sentence and the port after an
+// IPv4 address are allowed
+func standsAlone(s string, start, end int) bool {
+	if start > 0 && (isWordChar(s[start-1]) || s[start-1] == ':' || s[start-1] == '.') {
+		return false
+	}
+	if end < len(s) {
+		switch c := s[end]; {
+		case isWordChar(c):
+			return false
+		case c == ':':
+			return !strings.Contains(s[start:end], ":") && end+1 < len(s) && s[end+1] >= '0' && s[end+1] <= '9'
+		case c == '.':
+			return end+1 == len(s) || !isWordChar(s[end+1])
+		}
+	}
+	return true
+}
+
+// isWordChar reports whether c is an ASCII letter or digit
+func isWordChar(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,187 @@
+package pim
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// redactRules holds the compiled field rules of a RedactHook.
+// Rules without dots match a key at any depth; dotted rules such as
+// "request.headers.authorization" match a key path from the context root.
+// Slice elements do not add a path segment and "*" matches any single key.
+type redactRules struct {
+	names map[string]bool
+	paths [][]string
+}
+
+// compileRedactRules splits field rules into name and path rules
+func compileRedactRules(fields []string) redactRules {
+	rules := redactRules{names: make(map[string]bool)}
+	for _, field := range fields {
+		field = strings.ToLower(field)
+		if strings.Contains(field, ".") {
+			rules.paths = append(rules.paths, strings.Split(field, "."))
+		} else {
+			rules.names[field] = true
+		}
+	}
+	return rules
+}
+
+// matches reports whether the key at path should be redacted
+func (r redactRules) matches(path []string) bool {
+	if len(path) == 0 {
+		return false
+	}
+	if r.names[path[len(path)-1]] {
+		return true
+	}
+	for _, rule := range r.paths {
+		if len(rule) != len(path) {
+			continue
+		}
+		matched := true
+		for i, segment := range rule {
+			if segment != "*" && segment != path[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactValue returns a copy of value with all keys matched by the hook's
+// field rules and all detected values replaced, traversing nested maps,
+// slices and structs. Values
+// that contain nothing to redact are returned unchanged; the input is never mutated.
+func (h *RedactHook) RedactValue(value interface{}) interface{} {
+	redacted, _ := h.redactValue(nil, value)
+	return redacted
+}
+
+// redactValue walks value and reports whether anything was redacted
+func (h *RedactHook) redactValue(path []string, value interface{}) (interface{}, bool) {
+	if value == nil {
+		return nil, false
+	}
+
+	switch v := value.(type) {
+	case string:
+		return h.redactor.scan(v)
+	case error:
+		if scanned, changed := h.redactor.scan(v.Error()); changed {
+			return scanned, true
+		}
+		return value, false
+	case bool, int, int64, int32, uint, uint64, uint32, float64, float32, json.Number:
+		return value, false
+	case map[string]interface{}:
+		return h.redactMap(path, v)
+	case []interface{}:
+		return h.redactSlice(path, v)
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return value, false
+		}
+		generic := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			generic[iter.Key().String()] = iter.Value().Interface()
+		}
+		if redacted, changed := h.redactMap(path, generic); changed {
+			return redacted, true
+		}
+		return value, false
+
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			return value, false // []byte
+		}
+		generic := make([]interface{}, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			generic[i] = rv.Index(i).Interface()
+		}
+		if redacted, changed := h.redactSlice(path, generic); changed {
+			return redacted, true
+		}
+		return value, false
+
+	case reflect.Struct, reflect.Ptr:
+		// Traverse structs through their serialized form so json tags define the key names
+		data, err := json.Marshal(value)
+		if err != nil {
+			return value, false
+		}
+		var generic interface{}
+		if err := json.Unmarshal(data, &generic); err != nil {
+			return value, false
+		}
+		if redacted, changed := h.redactValue(path, generic); changed {
+			return redacted, true
+		}
+		return value, false
+	}
+
+	return value, false
+}
+
+// redactMap redacts a generic map, copying it only if something changed
+func (h *RedactHook) redactMap(path []string, m map[string]interface{}) (interface{}, bool) {
+	var result map[string]interface{}
+
+	for key, value := range m {
+		keyPath := append(append([]string(nil), path...), strings.ToLower(key))
+
+		var replacement interface{}
+		changed := false
+		if h.rules.matches(keyPath) {
+			replacement, changed = h.redactor.replaceValue(value), true
+		} else {
+			replacement, changed = h.redactValue(keyPath, value)
+		}
+
+		if changed {
+			if result == nil {
+				result = make(map[string]interface{}, len(m))
+				for k, v := range m {
+					result[k] = v
+				}
+			}
+			result[key] = replacement
+		}
+	}
+
+	if result == nil {
+		return m, false
+	}
+	return result, true
+}
+
+// redactSlice redacts a generic slice, copying it only if something changed
+func (h *RedactHook) redactSlice(path []string, s []interface{}) (interface{}, bool) {
+	var result []interface{}
+
+	for i, value := range s {
+		if replacement, changed := h.redactValue(path, value); changed {
+			if result == nil {
+				result = make([]interface{}, len(s))
+				copy(result, s)
+			}
+			result[i] = replacement
+		}
+	}
+
+	if result == nil {
+		return s, false
+	}
+	return result, true
+}
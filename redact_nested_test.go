@@ -0,0 +1,108 @@
+package pim
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func newTestNestedRedactHook(fields ...string) *RedactHook {
+	return NewRedactHook(RedactConfig{
+		HookConfig: HookConfig{
+			Type:    HookTypeRedact,
+			Name:    "nested_redact",
+			Enabled: true,
+		},
+		Fields:      fields,
+		Replacement: "[REDACTED]",
+	})
+}
+
+func TestRedactHookNestedMapsAndSlices(t *testing.T) {
+	hook := newTestNestedRedactHook("password", "request.headers.authorization")
+
+	headers := map[string]interface{}{"Authorization": "Bearer abc", "Accept": "*/*"}
+	entry := CoreLogEntry{
+		Message: "login",
+		Context: map[string]interface{}{
+			"request": map[string]interface{}{"headers": headers},
+			"users": []interface{}{
+				map[string]interface{}{"name": "alice", "password": "hunter2"},
+			},
+			"authorization": "top-level is not a path match",
+		},
+	}
+
+	result, err := hook.Process(entry)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	request := result.Context["request"].(map[string]interface{})
+	redactedHeaders := request["headers"].(map[string]interface{})
+	if redactedHeaders["Authorization"] != "[REDACTED]" {
+		t.Errorf("Expected nested authorization header to be redacted, got: %v", redactedHeaders)
+	}
+	if redactedHeaders["Accept"] != "*/*" {
+		t.Errorf("Expected unrelated header to be kept, got: %v", redactedHeaders)
+	}
+	if headers["Authorization"] != "Bearer abc" {
+		t.Error("Expected caller's nested map not to be mutated")
+	}
+
+	user := result.Context["users"].([]interface{})[0].(map[string]interface{})
+	if user["password"] != "[REDACTED]" || user["name"] != "alice" {
+		t.Errorf("Expected password inside slice to be redacted, got: %v", user)
+	}
+	if result.Context["authorization"] != "top-level is not a path match" {
+		t.Errorf("Expected path rule to only match its path, got: %v", result.Context["authorization"])
+	}
+}
+
+func TestRedactHookStructsAndTypedMaps(t *testing.T) {
+	type credentials struct {
+		Username string `json:"username"`
+		APIKey   string `json:"api_key"`
+	}
+	type owner struct {
+		Team string `json:"team"`
+	}
+
+	hook := newTestNestedRedactHook("api_key", "req.header.*")
+	entry := CoreLogEntry{
+		Context: map[string]interface{}{
+			"creds": credentials{Username: "svc", APIKey: "k-123"},
+			"req":   map[string]interface{}{"header": http.Header{"Cookie": {"session=1"}}},
+			"owner": owner{Team: "platform"},
+		},
+	}
+
+	result, _ := hook.Process(entry)
+
+	creds := result.Context["creds"].(map[string]interface{})
+	if creds["api_key"] != "[REDACTED]" || creds["username"] != "svc" {
+		t.Errorf("Expected struct field to be redacted via its json name, got: %v", creds)
+	}
+	header := result.Context["req"].(map[string]interface{})["header"].(map[string]interface{})
+	if header["Cookie"] != "[REDACTED]" {
+		t.Errorf("Expected wildcard path to redact typed map values, got: %v", header)
+	}
+	if _, ok := result.Context["owner"].(owner); !ok {
+		t.Error("Expected struct without matches to be left untouched")
+	}
+}
+
+func TestJsonSerializerRedactor(t *testing.T) {
+	serializer := NewJsonSerializer()
+	serializer.SetRedactor(newTestNestedRedactHook("token"))
+
+	out, err := serializer.MarshalToString(map[string]interface{}{
+		"session": map[string]interface{}{"token": "secret-token", "id": 1},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Contains(out, "secret-token") || !strings.Contains(out, "[REDACTED]") {
+		t.Errorf("Expected serialized output to be redacted, got: %s", out)
+	}
+}
@@ -0,0 +1,175 @@
+package pim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrUnsupportedBatchFormat is returned when a batch payload is not in a decodable format
+var ErrUnsupportedBatchFormat = errors.New("unsupported batch format")
+
+// DecodeBatch parses a RemoteWriter batch payload back into log entries.
+// Only JSON and CBOR batches (RemoteWriter with EnableJSON or EnableCBOR)
+// carry enough structure to be decoded; the Content-Type selects the
+// encoding and wire format version.
+func DecodeBatch(contentType string, body io.Reader) ([]CoreLogEntry, error) {
+	version, err := wireVersion(contentType)
+	if err != nil {
+		return nil, err
+	}
+	if isCBORBatch(contentType) {
+		return decodeCBORBatch(version, body)
+	}
+	return decodeVersionedBatch(version, body)
+}
+
+// RelayServer accepts batches from other pim instances, applies its own hook
+// pipeline and fans entries out to its writers
+type RelayServer struct {
+	addr        string
+	server      *http.Server
+	hookManager *HookManager
+	writers     []LogWriter
+	keyring     *BatchKeyring
+	maxBodySize int64
+	mu          sync.RWMutex
+}
+
+// NewRelayServer creates a new relay server listening on addr
+func NewRelayServer(addr string) *RelayServer {
+	relay := &RelayServer{
+		addr:        addr,
+		hookManager: NewHookManager(),
+		writers:     make([]LogWriter, 0),
+		maxBodySize: 10 << 20, // 10 MiB
+	}
+	relay.server = &http.Server{
+		Addr:              addr,
+		Handler:           relay,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return relay
+}
+
+// AddWriter adds a writer that receives relayed entries
+func (s *RelayServer) AddWriter(writer LogWriter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writers = append(s.writers, writer)
+}
+
+// AddHook adds a hook to the relay's own pipeline
+func (s *RelayServer) AddHook(hook EnhancedLogHook) {
+	s.hookManager.AddHook(hook)
+}
+
+// SetMaxBodySize sets the maximum accepted batch size in bytes
+func (s *RelayServer) SetMaxBodySize(size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxBodySize = size
+}
+
+// SetKeyring sets the keyring used to decrypt encrypted batches
+func (s *RelayServer) SetKeyring(keyring *BatchKeyring) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keyring = keyring
+}
+
+// ServeHTTP implements http.Handler so the relay can also be mounted on an existing mux
+func (s *RelayServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.RLock()
+	maxBodySize := s.maxBodySize
+	keyring := s.keyring
+	s.mu.RUnlock()
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+	entries, err := DecodeBatchRequest(r, keyring)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrUnsupportedBatchFormat) {
+			status = http.StatusUnsupportedMediaType
+			w.Header().Set("Accept", AcceptedBatchFormats)
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	s.Relay(entries)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Relay applies the relay hook pipeline to entries and writes them to all writers
+func (s *RelayServer) Relay(entries []CoreLogEntry) {
+	s.mu.RLock()
+	writers := make([]LogWriter, len(s.writers))
+	copy(writers, s.writers)
+	s.mu.RUnlock()
+
+	for _, entry := range entries {
+		processed, err := s.hookManager.ProcessHooks(entry)
+		if err != nil {
+			// Filtered by a relay hook
+			continue
+		}
+
+		for _, writer := range writers {
+			if err := writer.Write(processed); err != nil {
+				// Log writer errors to stderr to avoid infinite loops
+				fmt.Fprintf(os.Stderr, "Relay failed to write log entry: %v\n", err)
+			}
+		}
+	}
+}
+
+// ListenAndServe starts the relay and blocks until it is shut down
+func (s *RelayServer) ListenAndServe() error {
+	if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Addr returns the address the relay listens on
+func (s *RelayServer) Addr() string {
+	return s.addr
+}
+
+// Shutdown gracefully stops accepting batches, then flushes and closes all writers
+func (s *RelayServer) Shutdown(ctx context.Context) error {
+	err := s.server.Shutdown(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, writer := range s.writers {
+		if err := writer.Flush(); err != nil {
+			errs = append(errs, err)
+		}
+		if err := writer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("errors shutting down relay: %v", errs)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package pim
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRelayServerFromRemoteWriter(t *testing.T) {
+	relay := NewRelayServer("127.0.0.1:0")
+	buffer := NewBufferWriter(LoggerConfig{}, 10)
+	relay.AddWriter(buffer)
+	relay.AddHook(NewFilterHook(FilterConfig{
+		HookConfig: HookConfig{
+			Type:     HookTypeFilter,
+			Name:     "relay_filter",
+			Enabled:  true,
+			Priority: 1,
+		},
+		CustomFunc: func(entry CoreLogEntry) bool {
+			return entry.Level == DebugLevel
+		},
+	}))
+
+	server := httptest.NewServer(relay)
+	defer server.Close()
+
+	remote := NewRemoteWriter(LoggerConfig{EnableJSON: true}, RemoteWriterConfig{
+		Endpoint:   server.URL,
+		BatchSize:  10,
+		BatchDelay: time.Hour,
+	})
+	defer remote.Close()
+
+	remote.Write(CoreLogEntry{Level: InfoLevel, LevelString: "info", Message: "edge info"})
+	remote.Write(CoreLogEntry{Level: DebugLevel, LevelString: "debug", Message: "edge debug"})
+	if err := remote.Flush(); err != nil {
+		t.Fatalf("Failed to flush remote writer: %v", err)
+	}
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 relayed entry, got %d", len(entries))
+	}
+	if entries[0].Message != "edge info" {
+		t.Errorf("Expected relayed message 'edge info', got: %s", entries[0].Message)
+	}
+}
+
+func TestRelayServerRejectsTextBatches(t *testing.T) {
+	relay := NewRelayServer("127.0.0.1:0")
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("[INFO] plain text\n"))
+	req.Header.Set("Content-Type", "text/plain")
+	rec := httptest.NewRecorder()
+	relay.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status 415, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	rec = httptest.NewRecorder()
+	relay.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,192 @@
+package pim
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Retention is a storage tier hint of an entry, set by hooks and used by
+// writers to route entries, see RetentionWriter
+type Retention string
+
+const (
+	RetentionDefault Retention = ""      // No hint; the default tier
+	RetentionShort   Retention = "short" // Debugging detail that can expire early
+	RetentionLong    Retention = "long"  // Audit or incident data kept for long
+)
+
+// RetentionRule assigns a retention tier to the entries it matches
+type RetentionRule struct {
+	Retention Retention               `json:"retention"`
+	Levels    []LogLevel              `json:"levels,omitempty"` // Matching levels (default: all)
+	Match     func(CoreLogEntry) bool `json:"-"`                // Optional additional match condition
+}
+
+// RetentionConfig holds configuration for retention hooks
+type RetentionConfig struct {
+	HookConfig
+	Rules   []RetentionRule `json:"rules"`   // Evaluated in order, the first match wins
+	Default Retention       `json:"default"` // Tier of entries no rule matches
+}
+
+// RetentionHook sets the Retention hint of entries by rule, e.g. short
+// retention for debug entries and long retention for errors. Entries that
+// already carry a hint, e.g. from an earlier hook, keep it.
+type RetentionHook struct {
+	config RetentionConfig
+}
+
+// NewRetentionHook creates a new retention hook
+func NewRetentionHook(config RetentionConfig) *RetentionHook {
+	return &RetentionHook{config: config}
+}
+
+// Process implements LogHook interface
+func (h *RetentionHook) Process(entry CoreLogEntry) (CoreLogEntry, error) {
+	if !h.config.Enabled || entry.Retention != RetentionDefault {
+		return entry, nil
+	}
+	entry.Retention = h.Classify(entry)
+	return entry, nil
+}
+
+// Classify returns the tier of the first rule matching entry, or the default
+func (h *RetentionHook) Classify(entry CoreLogEntry) Retention {
+	for _, rule := range h.config.Rules {
+		if rule.matches(entry) {
+			return rule.Retention
+		}
+	}
+	return h.config.Default
+}
+
+// matches reports whether the rule applies to entry
+func (r RetentionRule) matches(entry CoreLogEntry) bool {
+	if len(r.Levels) > 0 {
+		found := false
+		for _, level := range r.Levels {
+			if level == entry.Level {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return r.Match == nil || r.Match(entry)
+}
+
+// GetConfig implements EnhancedLogHook interface
+func (h *RetentionHook) GetConfig() HookConfig {
+	return h.config.HookConfig
+}
+
+// GetType implements EnhancedLogHook interface
+func (h *RetentionHook) GetType() HookType {
+	return h.config.Type
+}
+
+// IsEnabled implements EnhancedLogHook interface
+func (h *RetentionHook) IsEnabled() bool {
+	return h.config.Enabled
+}
+
+// SetEnabled implements EnhancedLogHook interface
+func (h *RetentionHook) SetEnabled(enabled bool) {
+	h.config.Enabled = enabled
+}
+
+// GetPriority implements EnhancedLogHook interface
+func (h *RetentionHook) GetPriority() int {
+	return h.config.Priority
+}
+
+// SetPriority implements EnhancedLogHook interface
+func (h *RetentionHook) SetPriority(priority int) {
+	h.config.Priority = priority
+}
+
+// RetentionWriter routes entries to a writer per retention tier, e.g. files
+// with different MaxAge or remote endpoints with different storage classes.
+// Entries whose tier has no writer go to the RetentionDefault writer, and
+// are dropped if there is none.
+type RetentionWriter struct {
+	tiers map[Retention]LogWriter
+}
+
+// NewRetentionWriter creates a writer routing entries by their Retention hint
+func NewRetentionWriter(tiers map[Retention]LogWriter) *RetentionWriter {
+	routed := make(map[Retention]LogWriter, len(tiers))
+	for tier, writer := range tiers {
+		routed[tier] = writer
+	}
+	return &RetentionWriter{tiers: routed}
+}
+
+// NewTieredFileWriter creates a RetentionWriter with a FileWriter per tier
+// of rotations. The default tier writes to filename and other tiers to
+// filename with the tier before the extension, e.g. "app.long.log", each
+// rotated and cleaned up with its own RotationConfig.
+func NewTieredFileWriter(filename string, config LoggerConfig, rotations map[Retention]RotationConfig) (*RetentionWriter, error) {
+	tiers := make(map[Retention]LogWriter, len(rotations))
+	for tier, rotation := range rotations {
+		path := filename
+		if tier != RetentionDefault {
+			ext := filepath.Ext(filename)
+			path = strings.TrimSuffix(filename, ext) + "." + string(tier) + ext
+		}
+		writer, err := NewFileWriter(path, config, rotation)
+		if err != nil {
+			for _, opened := range tiers {
+				opened.Close()
+			}
+			return nil, fmt.Errorf("failed to create %q retention file: %w", tier, err)
+		}
+		tiers[tier] = writer
+	}
+	return &RetentionWriter{tiers: tiers}, nil
+}
+
+// Write implements LogWriter interface
+func (w *RetentionWriter) Write(entry CoreLogEntry) error {
+	writer, ok := w.tiers[entry.Retention]
+	if !ok {
+		writer, ok = w.tiers[RetentionDefault]
+	}
+	if !ok {
+		return nil
+	}
+	return writer.Write(entry)
+}
+
+// Tiers returns the tiers that have a writer, sorted
+func (w *RetentionWriter) Tiers() []Retention {
+	tiers := make([]Retention, 0, len(w.tiers))
+	for tier := range w.tiers {
+		tiers = append(tiers, tier)
+	}
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i] < tiers[j] })
+	return tiers
+}
+
+// Flush implements LogWriter interface
+func (w *RetentionWriter) Flush() error {
+	var errs []error
+	for _, writer := range w.tiers {
+		errs = append(errs, writer.Flush())
+	}
+	return errors.Join(errs...)
+}
+
+// Close implements LogWriter interface
+func (w *RetentionWriter) Close() error {
+	var errs []error
+	for _, writer := range w.tiers {
+		errs = append(errs, writer.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,146 @@
+package pim
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRetentionHook(t *testing.T) {
+	hook := NewRetentionHook(RetentionConfig{
+		HookConfig: HookConfig{Name: "retention", Type: HookTypeEnrich, Enabled: true},
+		Rules: []RetentionRule{
+			{Retention: RetentionLong, Levels: []LogLevel{PanicLevel, ErrorLevel}},
+			{Retention: RetentionLong, Match: func(entry CoreLogEntry) bool { return entry.Context["audit"] == true }},
+			{Retention: RetentionShort, Levels: []LogLevel{DebugLevel, TraceLevel}},
+		},
+	})
+
+	tests := []struct {
+		name     string
+		entry    CoreLogEntry
+		expected Retention
+	}{
+		{"error", CoreLogEntry{Level: ErrorLevel}, RetentionLong},
+		{"audit", CoreLogEntry{Level: InfoLevel, Context: map[string]interface{}{"audit": true}}, RetentionLong},
+		{"debug", CoreLogEntry{Level: DebugLevel}, RetentionShort},
+		{"info", CoreLogEntry{Level: InfoLevel}, RetentionDefault},
+		{"preset", CoreLogEntry{Level: DebugLevel, Retention: RetentionLong}, RetentionLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := hook.Process(tt.entry)
+			if err != nil {
+				t.Fatalf("Process failed: %v", err)
+			}
+			if entry.Retention != tt.expected {
+				t.Errorf("expected retention %q, got %q", tt.expected, entry.Retention)
+			}
+		})
+	}
+
+	hook.SetEnabled(false)
+	entry, _ := hook.Process(CoreLogEntry{Level: ErrorLevel})
+	if entry.Retention != RetentionDefault {
+		t.Errorf("expected a disabled hook to leave the retention, got %q", entry.Retention)
+	}
+}
+
+func TestRetentionWriter(t *testing.T) {
+	config := DefaultLoggerConfig
+	short, long, fallback := NewBufferWriter(config, 10), NewBufferWriter(config, 10), NewBufferWriter(config, 10)
+	writer := NewRetentionWriter(map[Retention]LogWriter{
+		RetentionShort:   short,
+		RetentionLong:    long,
+		RetentionDefault: fallback,
+	})
+
+	for _, entry := range []CoreLogEntry{
+		{Message: "a", Retention: RetentionShort},
+		{Message: "b", Retention: RetentionLong},
+		{Message: "c"},
+		{Message: "d", Retention: "archive"}, // No tier writer, so the default one
+	} {
+		if err := writer.Write(entry); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	if got := len(short.GetBuffer()); got != 1 {
+		t.Errorf("expected 1 short entry, got %d", got)
+	}
+	if got := len(long.GetBuffer()); got != 1 {
+		t.Errorf("expected 1 long entry, got %d", got)
+	}
+	if got := len(fallback.GetBuffer()); got != 2 {
+		t.Errorf("expected 2 default entries, got %d", got)
+	}
+	if tiers := writer.Tiers(); len(tiers) != 3 || tiers[0] != RetentionDefault {
+		t.Errorf("unexpected tiers %v", tiers)
+	}
+
+	// Without a default writer, entries of other tiers are dropped
+	dropping := NewRetentionWriter(map[Retention]LogWriter{RetentionLong: long})
+	if err := dropping.Write(CoreLogEntry{Message: "e"}); err != nil {
+		t.Errorf("expected dropped entries to succeed, got %v", err)
+	}
+	if got := len(long.GetBuffer()); got != 1 {
+		t.Errorf("expected the dropped entry not to reach the long tier, got %d entries", got)
+	}
+}
+
+func TestTieredFileWriter(t *testing.T) {
+	dir := t.TempDir()
+	config := DefaultLoggerConfig
+	config.EnableConsole = false
+
+	writer, err := NewTieredFileWriter(filepath.Join(dir, "app.log"), config, map[Retention]RotationConfig{
+		RetentionDefault: {},
+		RetentionLong:    {MaxFiles: 30},
+	})
+	if err != nil {
+		t.Fatalf("NewTieredFileWriter failed: %v", err)
+	}
+
+	logger := NewLoggerCore(config)
+	logger.AddWriter(writer)
+	logger.AddHook(NewRetentionHook(RetentionConfig{
+		HookConfig: HookConfig{Name: "retention", Type: HookTypeEnrich, Enabled: true},
+		Rules:      []RetentionRule{{Retention: RetentionLong, Levels: []LogLevel{ErrorLevel}}},
+	}))
+	logger.Info("served request")
+	logger.Error("payment failed")
+	logger.Close()
+
+	data, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	if err != nil {
+		t.Fatalf("failed to read default tier: %v", err)
+	}
+	if !strings.Contains(string(data), "served request") || strings.Contains(string(data), "payment failed") {
+		t.Errorf("unexpected default tier contents: %s", data)
+	}
+	data, err = os.ReadFile(filepath.Join(dir, "app.long.log"))
+	if err != nil {
+		t.Fatalf("failed to read long tier: %v", err)
+	}
+	if !strings.Contains(string(data), "payment failed") || strings.Contains(string(data), "served request") {
+		t.Errorf("unexpected long tier contents: %s", data)
+	}
+}
+
+func TestRetentionJSON(t *testing.T) {
+	data, err := json.Marshal(CoreLogEntry{Message: "m", Retention: RetentionShort})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"retention":"short"`) {
+		t.Errorf("expected the retention hint in %s", data)
+	}
+
+	data, _ = json.Marshal(CoreLogEntry{Message: "m"})
+	if strings.Contains(string(data), "retention") {
+		t.Errorf("expected no retention hint in %s", data)
+	}
+}
@@ -0,0 +1,156 @@
+package pim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Exit codes used by RunMain
+const (
+	ExitSuccess  = 0
+	ExitFailure  = 1  // Errors without a registered code
+	ExitUsage    = 2  // Command-line usage errors (see ErrUsage)
+	ExitPanic    = 70 // fn panicked (EX_SOFTWARE)
+	ExitCanceled = 130
+)
+
+// RunOutcomeMessage is the message of the final entry logged by RunMain
+const RunOutcomeMessage = "run finished"
+
+// ErrUsage marks command-line usage errors; RunMain exits with ExitUsage
+// for errors wrapping it
+var ErrUsage = errors.New("usage error")
+
+// ExitCoder is implemented by errors that choose their own exit code
+type ExitCoder interface {
+	ExitCode() int
+}
+
+// exitCodes is the registry of error to exit code mappings
+var exitCodes struct {
+	mu       sync.RWMutex
+	mappings []exitCodeMapping
+}
+
+// exitCodeMapping maps errors matching target to code
+type exitCodeMapping struct {
+	target error
+	code   int
+}
+
+// CLILoggerConfig is the logger configuration used by RunMain: text without
+// caller details on stderr, so stdout stays free for the program's output.
+// PIM_LOG_LEVEL, PIM_LOG_FORMAT and PIM_LOG_FILE override it.
+var CLILoggerConfig = LoggerConfig{
+	Level:           InfoLevel,
+	TimestampFormat: "15:04:05.000",
+	StackDepth:      3,
+	EnableColors:    true,
+	ThemeName:       "default",
+	FormatName:      "colorful",
+	SampleRate:      1.0,
+}
+
+// RegisterExitCode makes RunMain exit with code for errors matching target
+// with errors.Is. Later registrations take precedence.
+func RegisterExitCode(target error, code int) {
+	exitCodes.mu.Lock()
+	defer exitCodes.mu.Unlock()
+	exitCodes.mappings = append(exitCodes.mappings, exitCodeMapping{target: target, code: code})
+}
+
+// ExitCodeFor returns the exit code RunMain uses for err: ExitSuccess for
+// nil, the code of an ExitCoder in the chain, a registered code, ExitUsage
+// for ErrUsage, ExitCanceled for context.Canceled, otherwise ExitFailure
+func ExitCodeFor(err error) int {
+	if err == nil {
+		return ExitSuccess
+	}
+	var coder ExitCoder
+	if errors.As(err, &coder) {
+		return coder.ExitCode()
+	}
+
+	exitCodes.mu.RLock()
+	defer exitCodes.mu.RUnlock()
+	for i := len(exitCodes.mappings) - 1; i >= 0; i-- {
+		if errors.Is(err, exitCodes.mappings[i].target) {
+			return exitCodes.mappings[i].code
+		}
+	}
+
+	switch {
+	case errors.Is(err, ErrUsage):
+		return ExitUsage
+	case errors.Is(err, context.Canceled):
+		return ExitCanceled
+	}
+	return ExitFailure
+}
+
+// RunMain runs the main function of a CLI and exits. It creates a logger
+// from CLILoggerConfig, runs fn, logs a final RunOutcomeMessage entry with
+// the outcome ("success", "failure" or "panic"), exit code, duration and
+// error, closes the logger and exits with ExitCodeFor the returned error.
+// A panic in fn is logged with its stack and exits with ExitPanic.
+//
+//	func main() {
+//		pim.RunMain(func(logger *pim.LoggerCore) error {
+//			return run(logger)
+//		})
+//	}
+func RunMain(fn func(logger *LoggerCore) error) {
+	config := CLILoggerConfig
+	flags := &LogFlags{Config: &config}
+	if err := flags.applyEnv(); err != nil {
+		fmt.Fprintf(os.Stderr, "pim: %v\n", err)
+	}
+
+	logger, err := flags.NewLogger()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pim: failed to create logger: %v\n", err)
+		os.Exit(ExitFailure)
+	}
+	logger.AddWriter(NewStderrWriter(config))
+
+	code := runMain(logger, fn)
+	logger.Close()
+	os.Exit(code)
+}
+
+// runMain runs fn, logs its outcome and returns the exit code
+func runMain(logger *LoggerCore, fn func(logger *LoggerCore) error) (code int) {
+	start := time.Now()
+	var err error
+	defer func() {
+		outcome := "success"
+		fields := make(map[string]interface{})
+		if r := recover(); r != nil {
+			outcome, code = "panic", ExitPanic
+			fields["error"] = fmt.Sprint(r)
+			fields["stack"] = string(debug.Stack())
+		} else if err != nil {
+			outcome = "failure"
+			fields["error"] = err
+		}
+		fields["outcome"] = outcome
+		fields["exit_code"] = code
+		fields["duration_ms"] = float64(time.Since(start).Microseconds()) / 1000
+
+		level, prefix := InfoLevel, SuccessPrefix
+		if outcome != "success" {
+			level, prefix = ErrorLevel, ErrorPrefix
+		}
+		// The outcome is logged whatever the level, so every run ends with it
+		logger.LogWithContext(level, prefix, RunOutcomeMessage, fields, WithLevelOverride(InfoLevel), BypassSampling())
+		logger.Flush()
+	}()
+
+	err = fn(logger)
+	return ExitCodeFor(err)
+}
@@ -0,0 +1,89 @@
+package pim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// exitError chooses its own exit code
+type exitError struct{ code int }
+
+func (e exitError) Error() string { return fmt.Sprintf("exit %d", e.code) }
+func (e exitError) ExitCode() int { return e.code }
+
+func TestExitCodeFor(t *testing.T) {
+	errNotFound := errors.New("not found")
+	RegisterExitCode(errNotFound, 3)
+	defer func() {
+		exitCodes.mu.Lock()
+		exitCodes.mappings = nil
+		exitCodes.mu.Unlock()
+	}()
+
+	cases := map[string]struct {
+		err  error
+		code int
+	}{
+		"nil":        {nil, ExitSuccess},
+		"plain":      {errors.New("boom"), ExitFailure},
+		"usage":      {fmt.Errorf("bad flag: %w", ErrUsage), ExitUsage},
+		"canceled":   {fmt.Errorf("stopped: %w", context.Canceled), ExitCanceled},
+		"registered": {fmt.Errorf("load config: %w", errNotFound), 3},
+		"exit coder": {fmt.Errorf("wrapped: %w", exitError{code: 9}), 9},
+	}
+	for name, tc := range cases {
+		if got := ExitCodeFor(tc.err); got != tc.code {
+			t.Errorf("%s: expected %d, got %d", name, tc.code, got)
+		}
+	}
+}
+
+func TestRunMainOutcomeEntry(t *testing.T) {
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.Level = ErrorLevel
+	})
+	if code := runMain(logger, func(*LoggerCore) error { return nil }); code != ExitSuccess {
+		t.Fatalf("Expected success, got %d", code)
+	}
+	entries := buffer.GetBuffer()
+	if len(entries) != 1 || entries[0].Message != RunOutcomeMessage {
+		t.Fatalf("Expected the outcome entry even below the logger level, got %+v", entries)
+	}
+	if entries[0].Context["outcome"] != "success" || entries[0].Context["exit_code"] != 0 {
+		t.Errorf("Unexpected outcome fields %v", entries[0].Context)
+	}
+	if _, ok := entries[0].Context["duration_ms"].(float64); !ok {
+		t.Error("Expected a duration")
+	}
+
+	logger, buffer = newTestLogger(t, func(config *LoggerConfig) {
+		config.Level = InfoLevel
+	})
+	failure := fmt.Errorf("sync: %w", ErrUsage)
+	if code := runMain(logger, func(*LoggerCore) error { return failure }); code != ExitUsage {
+		t.Fatalf("Expected usage exit code, got %d", code)
+	}
+	entry := buffer.GetBuffer()[0]
+	if entry.Level != ErrorLevel || entry.Context["outcome"] != "failure" || entry.Context["error"] != failure {
+		t.Errorf("Unexpected failure entry %+v", entry)
+	}
+}
+
+func TestRunMainRecoversPanic(t *testing.T) {
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.Level = InfoLevel
+	})
+	code := runMain(logger, func(*LoggerCore) error { panic("nil map") })
+	if code != ExitPanic {
+		t.Fatalf("Expected panic exit code, got %d", code)
+	}
+	entry := buffer.GetBuffer()[0]
+	if entry.Context["outcome"] != "panic" || entry.Context["error"] != "nil map" || entry.Context["exit_code"] != ExitPanic {
+		t.Errorf("Unexpected panic entry %v", entry.Context)
+	}
+	if stack, _ := entry.Context["stack"].(string); stack == "" {
+		t.Error("Expected the panic stack")
+	}
+}
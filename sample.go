@@ -0,0 +1,135 @@
+package pim
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sampleEncoders are the encoders RenderSample accepts as formats, in
+// addition to the formatters and templates of the theme manager
+var sampleEncoders = map[string]func(config LoggerConfig) Encoder{
+	"json":      func(config LoggerConfig) Encoder { return NewJSONEncoder(config) },
+	"logfmt":    func(config LoggerConfig) Encoder { return NewLogfmtEncoder(config) },
+	"text":      func(config LoggerConfig) Encoder { return NewTextEncoder(config, ConsoleTextFields) },
+	"text-file": func(config LoggerConfig) Encoder { return NewTextEncoder(config, FileTextFields) },
+	"gcp":       func(config LoggerConfig) Encoder { return NewGCPEncoder(GCPConfig{ErrorReporting: true}) },
+}
+
+// SampleEntries returns the fixed entries rendered by RenderSample: one per
+// level with caller information, a context field, a correlation ID and an
+// error. Each entry has at most one context field, so every format renders
+// them the same way on every run.
+func SampleEntries() []CoreLogEntry {
+	at := time.Date(2024, 3, 15, 9, 30, 0, 0, time.UTC)
+	entry := func(offset time.Duration, level LogLevel, prefix, message string, context map[string]interface{}) CoreLogEntry {
+		return CoreLogEntry{
+			Timestamp:   at.Add(offset),
+			Level:       level,
+			LevelString: level.Name(),
+			Message:     message,
+			Prefix:      prefix,
+			ServiceName: "checkout",
+			LoggerName:  "orders",
+			File:        "orders.go",
+			Line:        42,
+			Function:    "PlaceOrder",
+			Package:     "shop",
+			Context:     context,
+		}
+	}
+
+	entries := []CoreLogEntry{
+		entry(0, TraceLevel, TracePrefix, "loading cart", nil),
+		entry(time.Millisecond, DebugLevel, DebugPrefix, "cart loaded", map[string]interface{}{"items": 3}),
+		entry(250*time.Millisecond, InfoLevel, InfoPrefix, "order placed", map[string]interface{}{"order_id": "o-1001"}),
+		entry(time.Second, WarningLevel, WarningPrefix, "payment slow", map[string]interface{}{"latency_ms": 1850}),
+		entry(2*time.Second, ErrorLevel, ErrorPrefix, "payment failed", map[string]interface{}{"error": errors.New("card declined")}),
+		entry(3*time.Second, PanicLevel, PanicPrefix, "inventory corrupted", nil),
+	}
+	entries[2].RequestID = "req-7"
+	entries[4].StackTrace = []StackFrame{
+		{File: "payments.go", Line: 88, Function: "Charge", Package: "shop"},
+		{File: "orders.go", Line: 42, Function: "PlaceOrder", Package: "shop"},
+	}
+	return entries
+}
+
+// RenderSample renders SampleEntries with a built-in theme and a format,
+// one entry per line, so customized formats and templates can be
+// snapshot-tested against golden files:
+//
+//	pim.RegisterGlobalTemplate("ops", "{{.Level}} {{.Service}}: {{.Message}}")
+//	got := pim.RenderSample("dark", "ops")
+//
+// The format is a formatter or template registered globally or built in
+// (see RegisterGlobalTemplate), or an encoder: "json", "logfmt", "text",
+// "text-file" or "gcp", which ignore the theme. Other names use the
+// theme's default format. Colors follow color.NoColor. An unknown theme
+// renders the error instead of the entries.
+func RenderSample(theme, format string) string {
+	var render func(entry CoreLogEntry) string
+	if newEncoder, ok := sampleEncoders[format]; ok {
+		encoder := newEncoder(DefaultLoggerConfig)
+		render = func(entry CoreLogEntry) string {
+			data, err := encoder.EncodeEntry(entry)
+			if err != nil {
+				return "error: " + err.Error()
+			}
+			return string(data)
+		}
+	} else {
+		tm := globalThemeManager.clone()
+		tm.SetTemplateErrorHandler(nil)
+		if err := tm.SetTheme(theme); err != nil {
+			return err.Error() + "\n"
+		}
+		render = func(entry CoreLogEntry) string {
+			return tm.Format(entry, format)
+		}
+	}
+
+	var b strings.Builder
+	for _, entry := range SampleEntries() {
+		b.WriteString(render(entry))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// SampleThemes returns the names of the built-in themes, sorted
+func SampleThemes() []string {
+	names := make([]string, 0, len(builtinThemes))
+	for name := range builtinThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SampleFormats returns the formats RenderSample accepts, sorted: the
+// global formatters and templates, "default" and the encoders
+func SampleFormats() []string {
+	return sampleFormats(globalThemeManager)
+}
+
+// sampleFormats returns the formats of tm, "default" and the encoders, sorted
+func sampleFormats(tm *ThemeManager) []string {
+	seen := map[string]bool{"default": true}
+	for name := range tm.formatters {
+		seen[name] = true
+	}
+	for name := range tm.templates {
+		seen[name] = true
+	}
+	for name := range sampleEncoders {
+		seen[name] = true
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
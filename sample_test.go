@@ -0,0 +1,77 @@
+package pim
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// assertGolden compares got with the golden file at path, or rewrites the
+// file with -update
+func assertGolden(t *testing.T, path, got string) {
+	t.Helper()
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatalf("Failed to write golden file: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("Output differs from %s (run with -update to accept it)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestRenderSampleGolden(t *testing.T) {
+	originalNoColor := color.NoColor
+	color.NoColor = false // Snapshot the theme colors too
+	defer func() { color.NoColor = originalNoColor }()
+
+	// Built-in formats only, whatever other tests registered globally
+	for _, format := range sampleFormats(NewThemeManager()) {
+		if _, isEncoder := sampleEncoders[format]; isEncoder {
+			t.Run(format, func(t *testing.T) {
+				assertGolden(t, filepath.Join("testdata", "render", format+".golden"), RenderSample("default", format))
+			})
+			continue
+		}
+		for _, theme := range SampleThemes() {
+			t.Run(theme+"/"+format, func(t *testing.T) {
+				path := filepath.Join("testdata", "render", theme+"_"+format+".golden")
+				assertGolden(t, path, RenderSample(theme, format))
+			})
+		}
+	}
+}
+
+func TestRenderSampleCustomTemplate(t *testing.T) {
+	if err := RegisterGlobalTemplate("sample-test", "{{.Level}} {{.Service}}: {{.Message}}"); err != nil {
+		t.Fatalf("RegisterGlobalTemplate failed: %v", err)
+	}
+	defer delete(globalThemeManager.templates, "sample-test")
+
+	got := RenderSample("default", "sample-test")
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	if len(lines) != len(SampleEntries()) || lines[2] != "info checkout: order placed" {
+		t.Errorf("Expected one line per sample entry, got:\n%s", got)
+	}
+	if got != RenderSample("default", "sample-test") {
+		t.Error("Expected the same output on every call")
+	}
+
+	if got := RenderSample("missing", "plain"); !strings.Contains(got, "not found") {
+		t.Errorf("Expected the theme error, got %q", got)
+	}
+}
@@ -0,0 +1,158 @@
+package pim
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// SamplingReportMessage is the message of the entries reporting dropped entries
+const SamplingReportMessage = "Log entries dropped"
+
+// SamplingReportPrefix is the prefix of sampling report entries
+const SamplingReportPrefix = "SAMPLING"
+
+// maxSamplingTemplates bounds the distinct templates counted per interval;
+// further templates are counted under the empty template
+const maxSamplingTemplates = 1000
+
+// Reasons reported in sampling report entries
+const (
+	DropReasonSampling  = "sampling"   // Dropped by per-level or global sampling
+	DropReasonAsyncFull = "async_full" // Dropped because its async lane was full
+	DropReasonRateLimit = "rate_limit" // Dropped by a RateLimitedWriter
+)
+
+// samplingKey groups dropped entries in a sampling report
+type samplingKey struct {
+	level    LogLevel
+	template string
+	reason   string
+}
+
+// samplingReporter counts entries dropped by sampling, full async lanes and
+// rate-limited writers and emits one report entry per level, message template and reason. It is
+// shared with child loggers; the logger that created it emits the reports.
+type samplingReporter struct {
+	owner    *LoggerCore // Emits the reports and stops them on Close
+	interval time.Duration
+	mu       sync.Mutex
+	counts   map[samplingKey]uint64
+	since    time.Time
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// newSamplingReporter creates a reporter emitting through owner every
+// interval once started, until stop is called
+func newSamplingReporter(owner *LoggerCore, interval time.Duration) *samplingReporter {
+	return &samplingReporter{
+		owner:    owner,
+		interval: interval,
+		counts:   make(map[samplingKey]uint64),
+		since:    time.Now(),
+		stopCh:   make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// start starts the periodic reports. It must be called once the owner is
+// fully constructed, since the reports are logged through it.
+func (r *samplingReporter) start() {
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.stopCh:
+				return
+			case <-ticker.C:
+				r.owner.emitSamplingReport(r)
+			}
+		}
+	}()
+}
+
+// dropped counts a dropped entry; it does nothing on a nil reporter
+func (r *samplingReporter) dropped(level LogLevel, template, reason string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := samplingKey{level: level, template: template, reason: reason}
+	if _, ok := r.counts[key]; !ok && len(r.counts) >= maxSamplingTemplates {
+		key.template = ""
+	}
+	r.counts[key]++
+}
+
+// take returns and resets the counts of the current interval
+func (r *samplingReporter) take() (map[samplingKey]uint64, time.Time, time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts, since, now := r.counts, r.since, time.Now()
+	r.counts, r.since = make(map[samplingKey]uint64), now
+	return counts, since, now
+}
+
+// stop ends the periodic reports and emits the last one if logger created
+// the reporter; it does nothing for child loggers sharing it
+func (r *samplingReporter) stop(logger *LoggerCore) {
+	if r == nil || r.owner != logger {
+		return
+	}
+	r.stopOnce.Do(func() { close(r.stopCh) })
+	<-r.done
+	logger.emitSamplingReport(r)
+}
+
+// ReportSampling emits the sampling report for the entries dropped since
+// the last report: one info entry per level, message template and reason
+// with the fields sampled_level, template, reason, dropped and
+// interval_ms, so dashboards can correct counts for sampling. The template
+// is the unformatted message, or the formatted one for entries dropped by
+// a full async lane or a RateLimitedWriter without a MessageTemplate; see
+// RateLimitedWriter.ReportTo. Report entries bypass the level and sampling. It is
+// called every SamplingReportInterval and on Close; it does nothing if the
+// interval is not set or nothing was dropped.
+func (l *LoggerCore) ReportSampling() {
+	l.emitSamplingReport(l.samplingReport)
+}
+
+// emitSamplingReport emits the report of r through l
+func (l *LoggerCore) emitSamplingReport(r *samplingReporter) {
+	if r == nil {
+		return
+	}
+	counts, since, now := r.take()
+	if len(counts) == 0 {
+		return
+	}
+
+	keys := make([]samplingKey, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].level != keys[j].level {
+			return keys[i].level < keys[j].level
+		}
+		if keys[i].template != keys[j].template {
+			return keys[i].template < keys[j].template
+		}
+		return keys[i].reason < keys[j].reason
+	})
+
+	for _, key := range keys {
+		l.LogWithContext(InfoLevel, SamplingReportPrefix, SamplingReportMessage, map[string]interface{}{
+			"sampled_level": key.level.Name(),
+			"template":      key.template,
+			"reason":        key.reason,
+			"dropped":       counts[key],
+			"interval_ms":   now.Sub(since).Milliseconds(),
+		}, WithLevelOverride(InfoLevel), BypassSampling())
+	}
+}
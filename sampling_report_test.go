@@ -0,0 +1,98 @@
+package pim
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReportSampling(t *testing.T) {
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.Level = DebugLevel
+		config.SamplingReportInterval = time.Hour // Reports are triggered below
+		config.SamplingByLevel = map[LogLevel]SamplingConfig{
+			DebugLevel: {EnableSampling: true, Rate: 4},
+		}
+	})
+
+	for i := 0; i < 8; i++ {
+		logger.Debug("cache miss for %d", i)
+	}
+	logger.Named("child").Debug("evicted") // Children count separately but share the report
+	logger.Debug("evicted")
+	logger.ReportSampling()
+
+	var reports []CoreLogEntry
+	for _, entry := range buffer.GetBuffer() {
+		if entry.Message == SamplingReportMessage {
+			reports = append(reports, entry)
+		}
+	}
+	if len(reports) != 2 {
+		t.Fatalf("expected 2 reports, got %d: %+v", len(reports), buffer.GetBuffer())
+	}
+
+	first := reports[0].Context
+	if first["template"] != "cache miss for %d" || first["dropped"] != uint64(6) {
+		t.Errorf("unexpected report: %v", first)
+	}
+	if first["sampled_level"] != "debug" || first["reason"] != DropReasonSampling || reports[0].Level != InfoLevel {
+		t.Errorf("unexpected report: %v", first)
+	}
+	if second := reports[1].Context; second["template"] != "evicted" || second["dropped"] != uint64(2) {
+		t.Errorf("unexpected report: %v", second)
+	}
+
+	// Counts are reset after each report
+	before := buffer.GetBufferSize()
+	logger.ReportSampling()
+	if buffer.GetBufferSize() != before {
+		t.Error("report emitted without dropped entries")
+	}
+}
+
+func TestReportSamplingInterval(t *testing.T) {
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.EnableSampling = true
+		config.SampleRate = 0.01
+		config.SamplingReportInterval = 20 * time.Millisecond
+	})
+	logger.Named("child").Close() // Children don't stop the shared reports
+
+	for i := 0; i < 200; i++ {
+		logger.Info("request handled")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, entry := range buffer.GetBuffer() {
+			if entry.Message == SamplingReportMessage {
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("no sampling report emitted")
+}
+
+func TestReportSamplingCountsRateLimitedEntries(t *testing.T) {
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.SamplingReportInterval = time.Hour
+	})
+	limited := NewBufferWriter(logger.config, 10)
+	writer := NewKeyedRateLimitedWriter(limited, RateLimitConfig{Rate: 1, Burst: 1, SummaryInterval: time.Hour})
+	logger.AddWriter(writer)
+
+	for i := 0; i < 3; i++ {
+		logger.Info("retrying")
+	}
+	logger.ReportSampling()
+
+	entries := buffer.GetBuffer()
+	report := entries[len(entries)-1]
+	if report.Message != SamplingReportMessage || report.Context["reason"] != DropReasonRateLimit || report.Context["dropped"] != uint64(2) {
+		t.Fatalf("expected a rate limit report of 2 entries, got %+v", report)
+	}
+	if last := limited.GetBuffer()[limited.GetBufferSize()-1]; last.Message != SamplingReportMessage {
+		t.Errorf("expected the report to pass the rate limit, got %q", last.Message)
+	}
+}
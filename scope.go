@@ -0,0 +1,205 @@
+package pim
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// scopes maps goroutine IDs to their stack of scope fields
+var scopes sync.Map // uint64 -> *scopeStack
+
+// activeScopes counts goroutines with pushed scopes, so logging skips the
+// goroutine lookup while no scope is in use
+var activeScopes atomic.Int64
+
+// scopeStack is the stack of field sets pushed by one goroutine. Only its
+// goroutine changes it, but other goroutines never read it either; the
+// mutex keeps the race detector informed of the handoff through the map.
+type scopeStack struct {
+	mu     sync.Mutex
+	frames []map[string]interface{}
+	mdc    map[string]interface{} // Fields set with MDC.Put, below all frames
+	ndc    []string               // Messages pushed with NDC.Push
+
+	released bool // Removed from scopes; a new stack must be created
+}
+
+// empty reports whether the stack holds nothing and can be released
+func (s *scopeStack) empty() bool {
+	return len(s.frames) == 0 && len(s.mdc) == 0 && len(s.ndc) == 0
+}
+
+// releaseIfEmpty removes the stack of goroutine id from scopes once it
+// holds nothing; the caller holds s.mu
+func (s *scopeStack) releaseIfEmpty(id uint64) {
+	if s.empty() && !s.released {
+		s.released = true
+		scopes.Delete(id)
+		activeScopes.Add(-1)
+	}
+}
+
+// updateScope calls fn with the current goroutine's scope stack, creating
+// it if needed, and releases the stack if fn leaves it empty
+func updateScope(fn func(stack *scopeStack)) {
+	id := currentGoroutineID()
+	for {
+		value, loaded := scopes.LoadOrStore(id, &scopeStack{})
+		if !loaded {
+			activeScopes.Add(1)
+		}
+		stack := value.(*scopeStack)
+		stack.mu.Lock()
+		if stack.released {
+			// Released by a pop function called on another goroutine
+			// after the lookup
+			stack.mu.Unlock()
+			continue
+		}
+		fn(stack)
+		stack.releaseIfEmpty(id)
+		stack.mu.Unlock()
+		return
+	}
+}
+
+// readScope calls fn with the current goroutine's scope stack locked,
+// unless the goroutine has none
+func readScope(fn func(stack *scopeStack)) {
+	if activeScopes.Load() == 0 {
+		return
+	}
+	value, ok := scopes.Load(currentGoroutineID())
+	if !ok {
+		return
+	}
+	stack := value.(*scopeStack)
+	stack.mu.Lock()
+	defer stack.mu.Unlock()
+	fn(stack)
+}
+
+// PushScope binds fields to the current goroutine until the returned
+// function, or PopScope, removes them. Every LoggerCore entry, and the
+// package-level functions such as Info, created on this goroutine in the
+// meantime carries the fields, so libraries logging without a logger of
+// their own still pick up request fields set by middleware:
+//
+//	defer pim.PushScope(map[string]interface{}{"request_id": id})()
+//
+// Scopes nest; inner fields override outer ones, scope fields override
+// logger context, and per-call fields override both. Scopes do not follow new
+// goroutines, use GoScope for that, and must be popped on the goroutine
+// that pushed them; a scope left pushed stays with its goroutine ID.
+func PushScope(fields map[string]interface{}) (pop func()) {
+	id := currentGoroutineID()
+	frame := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		frame[k] = v
+	}
+	updateScope(func(stack *scopeStack) {
+		stack.frames = append(stack.frames, frame)
+	})
+
+	var once sync.Once
+	return func() { once.Do(func() { popScope(id) }) }
+}
+
+// PopScope removes the innermost scope of the current goroutine
+func PopScope() {
+	popScope(currentGoroutineID())
+}
+
+// popScope removes the innermost scope of goroutine id
+func popScope(id uint64) {
+	value, ok := scopes.Load(id)
+	if !ok {
+		return
+	}
+	stack := value.(*scopeStack)
+	stack.mu.Lock()
+	defer stack.mu.Unlock()
+	if len(stack.frames) > 0 {
+		stack.frames = stack.frames[:len(stack.frames)-1]
+	}
+	stack.releaseIfEmpty(id)
+}
+
+// ClearScope removes every scope, MDC field and NDC message of the current
+// goroutine. Fields left on a goroutine that exits are never released, so
+// goroutines that set fields without removing them, e.g. pool workers,
+// should defer it:
+//
+//	go func() {
+//		defer pim.ClearScope()
+//		...
+//	}()
+func ClearScope() {
+	updateScope(func(stack *scopeStack) {
+		stack.frames, stack.mdc, stack.ndc = nil, nil, nil
+	})
+}
+
+// WithScope calls fn with fields pushed for its duration
+func WithScope(fields map[string]interface{}, fn func()) {
+	defer PushScope(fields)()
+	fn()
+}
+
+// GoScope starts fn in a new goroutine carrying the scope fields of the
+// current goroutine
+func GoScope(fn func()) {
+	fields := ScopeFields()
+	go func() {
+		if fields != nil {
+			defer PushScope(fields)()
+		}
+		fn()
+	}()
+}
+
+// ScopeFields returns the merged fields of the current goroutine's scopes,
+// MDC and NDC, or nil if none are set
+func ScopeFields() map[string]interface{} {
+	var fields map[string]interface{}
+	readScope(func(stack *scopeStack) {
+		fields = make(map[string]interface{})
+		for k, v := range stack.mdc {
+			fields[k] = v
+		}
+		if len(stack.ndc) > 0 {
+			fields[NDCKey] = strings.Join(stack.ndc, " ")
+		}
+		for _, frame := range stack.frames {
+			for k, v := range frame {
+				fields[k] = v
+			}
+		}
+	})
+	return fields
+}
+
+// scopeSuffix formats the current scope fields for the package-level
+// logging functions, e.g. " {request_id=r1}", and returns them as tags
+func scopeSuffix() (string, map[string]string) {
+	fields := ScopeFields()
+	if len(fields) == 0 {
+		return "", nil
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	tags := make(map[string]string, len(fields))
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		tags[k] = fmt.Sprint(fields[k])
+		pairs[i] = k + "=" + tags[k]
+	}
+	return " {" + strings.Join(pairs, ", ") + "}", tags
+}
@@ -0,0 +1,109 @@
+package pim
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPushScopeNesting(t *testing.T) {
+	if fields := ScopeFields(); fields != nil {
+		t.Fatalf("Expected no scope fields, got %v", fields)
+	}
+
+	popOuter := PushScope(map[string]interface{}{"request_id": "r1", "tenant": "acme"})
+	popInner := PushScope(map[string]interface{}{"tenant": "globex"})
+
+	fields := ScopeFields()
+	if fields["request_id"] != "r1" || fields["tenant"] != "globex" {
+		t.Errorf("Expected inner fields to override outer ones, got %v", fields)
+	}
+
+	popInner()
+	popInner() // Popping twice must not remove the outer scope
+	if fields := ScopeFields(); fields["tenant"] != "acme" {
+		t.Errorf("Expected outer scope after pop, got %v", fields)
+	}
+
+	popOuter()
+	if fields := ScopeFields(); fields != nil {
+		t.Errorf("Expected no scope fields after pops, got %v", fields)
+	}
+
+	PushScope(map[string]interface{}{"a": 1})
+	PopScope()
+	PopScope() // Popping an empty stack is a no-op
+	if fields := ScopeFields(); fields != nil {
+		t.Errorf("Expected no scope fields after PopScope, got %v", fields)
+	}
+}
+
+func TestScopeFieldsInLoggerCore(t *testing.T) {
+	logger, writer := newTestLogger(t, nil)
+	logger.SetContext("tenant", "acme")
+
+	WithScope(map[string]interface{}{"request_id": "r1", "tenant": "globex", "user": "alice"}, func() {
+		logger.InfoWithFields("scoped", map[string]interface{}{"user": "bob"})
+	})
+	logger.Info("unscoped")
+
+	entries := writer.GetBuffer()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+
+	scoped := entries[0]
+	if scoped.RequestID != "r1" || scoped.Context["request_id"] != "r1" {
+		t.Errorf("Expected request_id from scope, got %q and %v", scoped.RequestID, scoped.Context["request_id"])
+	}
+	if scoped.Context["tenant"] != "globex" {
+		t.Errorf("Expected scope to override logger context, got %v", scoped.Context["tenant"])
+	}
+	if scoped.Context["user"] != "bob" {
+		t.Errorf("Expected call fields to override scope, got %v", scoped.Context["user"])
+	}
+
+	unscoped := entries[1]
+	if _, ok := unscoped.Context["request_id"]; ok || unscoped.Context["tenant"] != "acme" {
+		t.Errorf("Expected only logger context after the scope ended, got %v", unscoped.Context)
+	}
+}
+
+func TestScopeIsPerGoroutine(t *testing.T) {
+	defer PushScope(map[string]interface{}{"request_id": "r1"})()
+
+	var other map[string]interface{}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		other = ScopeFields()
+	}()
+	wg.Wait()
+	if other != nil {
+		t.Errorf("Expected no scope fields on another goroutine, got %v", other)
+	}
+
+	var inherited map[string]interface{}
+	wg.Add(1)
+	GoScope(func() {
+		defer wg.Done()
+		inherited = ScopeFields()
+	})
+	wg.Wait()
+	if inherited["request_id"] != "r1" {
+		t.Errorf("Expected GoScope to carry scope fields, got %v", inherited)
+	}
+}
+
+func TestScopeFieldsInGlobalLogging(t *testing.T) {
+	output := captureOutput(func() {
+		WithScope(map[string]interface{}{"request_id": "r1", "tenant": "acme"}, func() {
+			Info("handled")
+		})
+	})
+
+	if !strings.Contains(output, "handled {request_id=r1, tenant=acme}") {
+		t.Errorf("Expected scope fields in output, got %q", output)
+	}
+}
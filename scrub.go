@@ -0,0 +1,416 @@
+package pim
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultScrubReplacement replaces a forgotten subject identifier
+const DefaultScrubReplacement = "[FORGOTTEN]"
+
+// ScrubConfig describes a "right to be forgotten" request
+type ScrubConfig struct {
+	Subject     string `json:"-"`           // Identifier to remove, e.g. a user ID or email address
+	Replacement string `json:"replacement"` // Default: DefaultScrubReplacement
+	DryRun      bool   `json:"dry_run"`     // Report matches without rewriting anything
+
+	// HashAlgorithm identifies the subject in the report (default: sha256)
+	// by a hash of HashSalt and the identifier. Non-approved algorithms are
+	// rejected in FIPS mode. The salt is required, so the hash of a
+	// guessable identifier such as an email address cannot be looked up;
+	// keep it secret, with the same salt for all requests so reports of one
+	// subject can be matched.
+	HashAlgorithm HashAlgorithm `json:"hash_algorithm"`
+	HashSalt      string        `json:"-"`
+}
+
+// ScrubReport is the audit record of a scrub run. It identifies the subject
+// only by a salted hash of the identifier so the report itself can be kept.
+type ScrubReport struct {
+	SubjectHash   string            `json:"subject_hash"`
+	HashAlgorithm HashAlgorithm     `json:"hash_algorithm"`
+	DryRun        bool              `json:"dry_run"`
+	StartedAt     time.Time         `json:"started_at"`
+	FinishedAt    time.Time         `json:"finished_at"`
+	Files         []ScrubFileReport `json:"files"`
+}
+
+// ScrubFileReport records the outcome for one file or archive object
+type ScrubFileReport struct {
+	Path         string `json:"path"`
+	Entries      int    `json:"entries"`
+	Modified     int    `json:"modified"`
+	SHA256Before string `json:"sha256_before"`
+	SHA256After  string `json:"sha256_after,omitempty"` // Set when the file was rewritten
+	Error        string `json:"error,omitempty"`
+}
+
+// ModifiedFiles returns the number of files containing the subject
+func (r *ScrubReport) ModifiedFiles() int {
+	n := 0
+	for _, file := range r.Files {
+		if file.Modified > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *ScrubReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// ArchiveStore gives the scrubber access to archived log objects, e.g. in an
+// object store. Objects are JSON lines, gzip-compressed if the key ends in ".gz".
+type ArchiveStore interface {
+	List(prefix string) ([]string, error)
+	Get(key string) ([]byte, error)
+	Put(key string, data []byte) error
+}
+
+// Scrubber rewrites stored JSON log files so they no longer contain a
+// subject identifier
+type Scrubber struct {
+	config  ScrubConfig
+	subject []byte
+	report  *ScrubReport
+}
+
+// NewScrubber creates a scrubber for the subject in config
+func NewScrubber(config ScrubConfig) (*Scrubber, error) {
+	if config.Subject == "" {
+		return nil, fmt.Errorf("scrub subject is required")
+	}
+	if config.Replacement == "" {
+		config.Replacement = DefaultScrubReplacement
+	}
+	if strings.Contains(config.Replacement, config.Subject) {
+		return nil, fmt.Errorf("scrub replacement must not contain the subject")
+	}
+	if config.HashSalt == "" {
+		return nil, fmt.Errorf("scrub hash salt is required")
+	}
+
+	if config.HashAlgorithm == "" {
+		config.HashAlgorithm = HashSHA256
+	}
+	h, err := config.HashAlgorithm.New()
+	if err != nil {
+		return nil, err
+	}
+	h.Write([]byte(config.HashSalt))
+	h.Write([]byte(config.Subject))
+
+	return &Scrubber{
+		config:  config,
+		subject: []byte(config.Subject),
+		report: &ScrubReport{
+			SubjectHash:   hex.EncodeToString(h.Sum(nil)),
+			HashAlgorithm: config.HashAlgorithm,
+			DryRun:        config.DryRun,
+			StartedAt:     time.Now().UTC(),
+		},
+	}, nil
+}
+
+// Report returns the audit report of everything scrubbed so far
+func (s *Scrubber) Report() *ScrubReport {
+	s.report.FinishedAt = time.Now().UTC()
+	return s.report
+}
+
+// ScrubDir scrubs every log file under dir: *.log, *.json and *.jsonl files
+// and their gzip-compressed rotations. Pass the writers still writing to
+// files under dir as active; their files are scrubbed with ScrubWriter, as
+// entries written to a file while it is rewritten would be lost.
+func (s *Scrubber) ScrubDir(dir string, active ...*FileWriter) error {
+	writers := make(map[string]*FileWriter, len(active))
+	for _, writer := range active {
+		if path, err := filepath.Abs(writer.filePath); err == nil {
+			writers[path] = writer
+		}
+	}
+
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !isScrubbableLog(path) {
+			return nil
+		}
+		if abs, err := filepath.Abs(path); err == nil && writers[abs] != nil {
+			s.ScrubWriter(writers[abs])
+			return nil
+		}
+		s.ScrubFile(path)
+		return nil
+	})
+}
+
+// ScrubWriter scrubs the current file of a running writer. The writer is
+// locked while the file is rewritten and then reopens it, so no entry is
+// written to the replaced file. Signatures of a signed file no longer
+// verify once it was rewritten.
+func (s *Scrubber) ScrubWriter(w *FileWriter) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.buf != nil {
+		if err := w.buf.Flush(); err != nil {
+			s.report.Files = append(s.report.Files, ScrubFileReport{Path: w.filePath, Error: err.Error()})
+			return
+		}
+	}
+
+	s.ScrubFile(w.filePath)
+	if w.file != nil && s.report.Files[len(s.report.Files)-1].SHA256After != "" {
+		if err := w.openFileLocked(); err != nil {
+			// Log reopen errors to stderr to avoid infinite loops
+			fmt.Fprintf(os.Stderr, "Failed to reopen scrubbed log file: %v\n", err)
+		}
+	}
+}
+
+// isScrubbableLog reports whether a file name looks like a JSON log file
+func isScrubbableLog(path string) bool {
+	name := strings.TrimSuffix(path, ".gz")
+	for _, ext := range []string{".log", ".json", ".jsonl"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// ScrubFile scrubs one file in place. The file is replaced atomically and
+// only if it contained the subject. Failures are recorded in the report.
+func (s *Scrubber) ScrubFile(path string) {
+	s.scrubObject(path, func() ([]byte, error) {
+		return os.ReadFile(path)
+	}, func(data []byte) error {
+		return writeFileAtomic(path, data)
+	})
+}
+
+// ScrubArchives scrubs every object under prefix in store
+func (s *Scrubber) ScrubArchives(store ArchiveStore, prefix string) error {
+	keys, err := store.List(prefix)
+	if err != nil {
+		return fmt.Errorf("failed to list archives: %w", err)
+	}
+	for _, key := range keys {
+		s.scrubObject(key, func() ([]byte, error) {
+			return store.Get(key)
+		}, func(data []byte) error {
+			return store.Put(key, data)
+		})
+	}
+	return nil
+}
+
+// scrubObject reads, scrubs and rewrites one file or object
+func (s *Scrubber) scrubObject(path string, read func() ([]byte, error), write func([]byte) error) {
+	result := ScrubFileReport{Path: path}
+	defer func() { s.report.Files = append(s.report.Files, result) }()
+
+	raw, err := read()
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+	sum := sha256.Sum256(raw)
+	result.SHA256Before = hex.EncodeToString(sum[:])
+
+	compressed := strings.HasSuffix(path, ".gz")
+	data := raw
+	if compressed {
+		if data, err = gunzip(raw); err != nil {
+			result.Error = err.Error()
+			return
+		}
+	}
+
+	scrubbed, entries, modified, err := s.scrubLines(data)
+	result.Entries, result.Modified = entries, modified
+	if err != nil {
+		// Never write back a file that was not read to the end
+		result.Error = err.Error()
+		return
+	}
+	if modified == 0 || s.config.DryRun {
+		return
+	}
+
+	if compressed {
+		if scrubbed, err = gzipBytes(scrubbed); err != nil {
+			result.Error = err.Error()
+			return
+		}
+	}
+	if err := write(scrubbed); err != nil {
+		result.Error = err.Error()
+		return
+	}
+	sum = sha256.Sum256(scrubbed)
+	result.SHA256After = hex.EncodeToString(sum[:])
+}
+
+// scrubLines scrubs JSON lines, leaving lines without the subject untouched.
+// It fails on lines longer than the scanner allows.
+func (s *Scrubber) scrubLines(data []byte) ([]byte, int, int, error) {
+	var out bytes.Buffer
+	out.Grow(len(data))
+	entries, modified := 0, 0
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64<<10), 64<<20)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) > 0 {
+			entries++
+		}
+		if bytes.Contains(line, s.subject) {
+			line = s.scrubLine(line)
+			modified++
+		}
+		out.Write(line)
+		out.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, entries, modified, fmt.Errorf("failed to read log line %d: %w", entries+1, err)
+	}
+	return out.Bytes(), entries, modified, nil
+}
+
+// scrubLine redacts the subject from the keys and string values of a JSON
+// line. Lines that are not JSON are redacted as plain text.
+func (s *Scrubber) scrubLine(line []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+	var tree interface{}
+	if err := decoder.Decode(&tree); err == nil {
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(s.scrubValue(tree)); err == nil {
+			if scrubbed := bytes.TrimSuffix(buf.Bytes(), []byte("\n")); !bytes.Contains(scrubbed, s.subject) {
+				return scrubbed
+			}
+		}
+	}
+	// Not JSON, or the subject appears in an escaped form
+	return bytes.ReplaceAll(line, s.subject, []byte(s.config.Replacement))
+}
+
+// scrubValue redacts the subject in a decoded JSON value
+func (s *Scrubber) scrubValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return strings.ReplaceAll(v, s.config.Subject, s.config.Replacement)
+	case json.Number:
+		if strings.Contains(v.String(), s.config.Subject) {
+			return s.config.Replacement
+		}
+		return v
+	case map[string]interface{}:
+		scrubbed := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			scrubbed[strings.ReplaceAll(key, s.config.Subject, s.config.Replacement)] = s.scrubValue(child)
+		}
+		return scrubbed
+	case []interface{}:
+		for i, child := range v {
+			v[i] = s.scrubValue(child)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// gunzip decompresses gzip data
+func gunzip(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open compressed log: %w", err)
+	}
+	defer reader.Close()
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress log: %w", err)
+	}
+	return decompressed, nil
+}
+
+// gzipBytes compresses data with gzip
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress log: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress log: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeFileAtomic replaces path with data, keeping its permissions
+func writeFileAtomic(path string, data []byte) error {
+	mode := fs.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".scrub-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("failed to set file mode: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ScrubSubject scrubs subject from all log files under dir and returns the
+// audit report, identifying the subject by a hash with salt. Files still
+// being written to must be scrubbed with Scrubber.ScrubDir instead.
+func ScrubSubject(dir, subject, salt string) (*ScrubReport, error) {
+	scrubber, err := NewScrubber(ScrubConfig{Subject: subject, HashSalt: salt})
+	if err != nil {
+		return nil, err
+	}
+	if err := scrubber.ScrubDir(dir); err != nil {
+		return scrubber.Report(), err
+	}
+	return scrubber.Report(), nil
+}
@@ -0,0 +1,198 @@
+package pim
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// memoryArchive is an in-memory ArchiveStore
+type memoryArchive map[string][]byte
+
+func (m memoryArchive) List(prefix string) ([]string, error) {
+	var keys []string
+	for key := range m {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (m memoryArchive) Get(key string) ([]byte, error) { return m[key], nil }
+
+func (m memoryArchive) Put(key string, data []byte) error {
+	m[key] = data
+	return nil
+}
+
+func TestScrubSubject(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	content := `{"message":"login","context":{"user_id":"u-42","tags":["u-42","x"]}}
+{"message":"other user","context":{"user_id":"u-7"}}
+[2024-01-01] [INFO] text line for u-42
+`
+	if err := os.WriteFile(logPath, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var gz bytes.Buffer
+	writer := gzip.NewWriter(&gz)
+	writer.Write([]byte(`{"message":"archived u-42"}` + "\n"))
+	writer.Close()
+	gzPath := filepath.Join(dir, "app.2024-01-01_00-00-00.log.gz")
+	if err := os.WriteFile(gzPath, gz.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	untouched := filepath.Join(dir, "clean.log")
+	os.WriteFile(untouched, []byte(`{"message":"nothing here"}`+"\n"), 0644)
+
+	report, err := ScrubSubject(dir, "u-42", "pepper")
+	if err != nil {
+		t.Fatalf("ScrubSubject failed: %v", err)
+	}
+	if report.ModifiedFiles() != 2 {
+		t.Errorf("expected 2 modified files, got %d", report.ModifiedFiles())
+	}
+
+	data, _ := os.ReadFile(logPath)
+	if strings.Contains(string(data), "u-42") {
+		t.Errorf("subject still present: %s", data)
+	}
+	if !strings.Contains(string(data), `"user_id":"u-7"`) {
+		t.Errorf("unrelated entry changed: %s", data)
+	}
+	if !strings.Contains(string(data), "text line for "+DefaultScrubReplacement) {
+		t.Errorf("text line not scrubbed: %s", data)
+	}
+	if info, _ := os.Stat(logPath); info.Mode().Perm() != 0600 {
+		t.Errorf("file mode not kept: %v", info.Mode())
+	}
+
+	compressed, _ := os.ReadFile(gzPath)
+	decompressed, err := gunzip(compressed)
+	if err != nil {
+		t.Fatalf("archive not valid gzip: %v", err)
+	}
+	if strings.Contains(string(decompressed), "u-42") {
+		t.Errorf("subject still present in archive: %s", decompressed)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "u-42") {
+		t.Error("report must not contain the subject")
+	}
+	for _, file := range report.Files {
+		if file.Path == untouched && (file.Modified != 0 || file.SHA256After != "") {
+			t.Errorf("clean file reported as modified: %+v", file)
+		}
+		if file.Path == logPath && (file.Entries != 3 || file.Modified != 2 || file.SHA256After == "") {
+			t.Errorf("unexpected report for log file: %+v", file)
+		}
+	}
+}
+
+func TestScrubberDryRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	content := `{"message":"hello alice@example.com"}` + "\n"
+	os.WriteFile(path, []byte(content), 0644)
+
+	scrubber, err := NewScrubber(ScrubConfig{Subject: "alice@example.com", HashSalt: "pepper", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scrubber.ScrubFile(path)
+
+	report := scrubber.Report()
+	if len(report.Files) != 1 || report.Files[0].Modified != 1 {
+		t.Fatalf("unexpected report: %+v", report.Files)
+	}
+	if data, _ := os.ReadFile(path); string(data) != content {
+		t.Errorf("dry run modified file: %s", data)
+	}
+}
+
+func TestScrubberArchives(t *testing.T) {
+	store := memoryArchive{
+		"logs/2024/app.jsonl": []byte(`{"message":"hi","context":{"email_bob@x.io":true}}` + "\n"),
+		"other/app.jsonl":     []byte(`{"message":"bob@x.io"}` + "\n"),
+	}
+	scrubber, err := NewScrubber(ScrubConfig{Subject: "bob@x.io", HashSalt: "pepper", Replacement: "<gone>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := scrubber.ScrubArchives(store, "logs/"); err != nil {
+		t.Fatalf("ScrubArchives failed: %v", err)
+	}
+
+	if got := string(store["logs/2024/app.jsonl"]); !strings.Contains(got, "email_<gone>") {
+		t.Errorf("archive key not scrubbed: %s", got)
+	}
+	if got := string(store["other/app.jsonl"]); !strings.Contains(got, "bob@x.io") {
+		t.Errorf("object outside prefix changed: %s", got)
+	}
+}
+
+func TestNewScrubberValidation(t *testing.T) {
+	if _, err := NewScrubber(ScrubConfig{}); err == nil {
+		t.Error("expected error for empty subject")
+	}
+	if _, err := NewScrubber(ScrubConfig{Subject: "id", HashSalt: "pepper", Replacement: "[id]"}); err == nil {
+		t.Error("expected error for replacement containing subject")
+	}
+	if _, err := NewScrubber(ScrubConfig{Subject: "id"}); err == nil {
+		t.Error("expected error for a missing salt")
+	}
+
+	first, _ := NewScrubber(ScrubConfig{Subject: "id", HashSalt: "pepper"})
+	second, _ := NewScrubber(ScrubConfig{Subject: "id", HashSalt: "salt"})
+	if first.Report().SubjectHash == second.Report().SubjectHash {
+		t.Error("expected the salt to change the subject hash")
+	}
+}
+
+func TestScrubberKeepsFileWithOverlongLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	content := `{"message":"u-42"}` + "\n" + strings.Repeat("x", 65<<20) + "\n" + `{"message":"u-42 again"}` + "\n"
+	os.WriteFile(path, []byte(content), 0644)
+
+	scrubber, _ := NewScrubber(ScrubConfig{Subject: "u-42", HashSalt: "pepper"})
+	scrubber.ScrubFile(path)
+
+	if report := scrubber.Report(); report.Files[0].Error == "" {
+		t.Errorf("expected the overlong line to be reported, got %+v", report.Files[0])
+	}
+	if data, _ := os.ReadFile(path); len(data) != len(content) {
+		t.Errorf("expected the file to be left alone, got %d of %d bytes", len(data), len(content))
+	}
+}
+
+func TestScrubDirActiveWriter(t *testing.T) {
+	dir := t.TempDir()
+	config := DefaultLoggerConfig
+	config.EnableJSON = true
+	writer, err := NewFileWriter(filepath.Join(dir, "app.log"), config, RotationConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+	writer.Write(CoreLogEntry{Message: "login u-42"})
+
+	scrubber, _ := NewScrubber(ScrubConfig{Subject: "u-42", HashSalt: "pepper"})
+	if err := scrubber.ScrubDir(dir, writer); err != nil {
+		t.Fatalf("ScrubDir failed: %v", err)
+	}
+	writer.Write(CoreLogEntry{Message: "after scrub"})
+
+	data, _ := os.ReadFile(filepath.Join(dir, "app.log"))
+	if strings.Contains(string(data), "u-42") || !strings.Contains(string(data), "after scrub") {
+		t.Errorf("expected the scrubbed file to be written to after the scrub, got %s", data)
+	}
+}
@@ -0,0 +1,501 @@
+package pim
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SecretRule describes one secret format, in the spirit of gitleaks rules.
+// Keywords are a cheap case-insensitive prefilter: the regex only runs on
+// text containing one of them (or always, if there are none). SecretGroup
+// selects the capture group holding the secret, so e.g. the key name in
+// "aws_secret_access_key=..." is kept; 0 redacts the whole match.
+type SecretRule struct {
+	ID          string   `json:"id"`
+	Description string   `json:"description,omitempty"`
+	Regex       string   `json:"regex"`
+	Keywords    []string `json:"keywords,omitempty"`
+	SecretGroup int      `json:"secret_group,omitempty"`
+}
+
+// DefaultSecretRules is the built-in rule pack of well-known secret formats
+var DefaultSecretRules = []SecretRule{
+	{
+		ID:          "aws-access-key-id",
+		Description: "AWS access key ID",
+		Regex:       `\b(?:A3T[A-Z0-9]|AKIA|ASIA|ABIA|ACCA)[A-Z2-7]{16}\b`,
+		Keywords:    []string{"a3t", "akia", "asia", "abia", "acca"},
+	},
+	{
+		ID:          "aws-secret-access-key",
+		Description: "AWS secret access key",
+		Regex:       `(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+]{40})(?:[^A-Za-z0-9/+]|$)`,
+		Keywords:    []string{"secret"},
+		SecretGroup: 1,
+	},
+	{
+		ID:          "github-pat",
+		Description: "GitHub personal access token",
+		Regex:       `\bghp_[0-9A-Za-z]{36}\b`,
+		Keywords:    []string{"ghp_"},
+	},
+	{
+		ID:          "github-fine-grained-pat",
+		Description: "GitHub fine-grained personal access token",
+		Regex:       `\bgithub_pat_[0-9A-Za-z_]{82}\b`,
+		Keywords:    []string{"github_pat_"},
+	},
+	{
+		ID:          "github-oauth",
+		Description: "GitHub OAuth access token",
+		Regex:       `\bgho_[0-9A-Za-z]{36}\b`,
+		Keywords:    []string{"gho_"},
+	},
+	{
+		ID:          "github-app-token",
+		Description: "GitHub app user-to-server or server-to-server token",
+		Regex:       `\b(?:ghu|ghs)_[0-9A-Za-z]{36}\b`,
+		Keywords:    []string{"ghu_", "ghs_"},
+	},
+	{
+		ID:          "github-refresh-token",
+		Description: "GitHub refresh token",
+		Regex:       `\bghr_[0-9A-Za-z]{36}\b`,
+		Keywords:    []string{"ghr_"},
+	},
+	{
+		ID:          "slack-token",
+		Description: "Slack bot, user or app token",
+		Regex:       `\bxox[abposr]-[0-9A-Za-z-]{10,}`,
+		Keywords:    []string{"xox"},
+	},
+	{
+		ID:          "jwt",
+		Description: "JSON Web Token",
+		Regex:       `\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`,
+		Keywords:    []string{"eyj"},
+	},
+	{
+		ID:          "private-key",
+		Description: "PEM private key block, including truncated ones",
+		Regex:       `-----BEGIN[ A-Z0-9_-]{0,100}PRIVATE KEY(?: BLOCK)?-----[\s\S]*?(?:-----END[ A-Z0-9_-]{0,100}PRIVATE KEY(?: BLOCK)?-----|$)`,
+		Keywords:    []string{"-----begin"},
+	},
+}
+
+// SecretRuleFile is the content of a JSON or YAML secret rules file, e.g.
+//
+//	use_default: true
+//	rules:
+//	  - id: internal-api-key
+//	    regex: '\bik_[0-9a-f]{32}\b'
+//	    keywords: [ik_]
+type SecretRuleFile struct {
+	UseDefault bool         `json:"use_default"` // Also apply DefaultSecretRules
+	Rules      []SecretRule `json:"rules"`
+}
+
+// SecretScanConfig holds configuration for secret scanning hooks
+type SecretScanConfig struct {
+	HookConfig
+	Rules       []SecretRule `json:"rules,omitempty"`       // Rules to apply; DefaultSecretRules if empty
+	RulesFile   string       `json:"rules_file,omitempty"`  // Rules file replacing Rules, see SecretRuleFile
+	Replacement string       `json:"replacement,omitempty"` // Replacement; "[REDACTED:<rule id>]" if empty
+}
+
+// compiledSecretRule is a SecretRule with its regex compiled
+type compiledSecretRule struct {
+	id          string
+	regex       *regexp.Regexp
+	keywords    []string
+	secretGroup int
+}
+
+// SecretScanHook redacts secrets recognised by their format in the message,
+// its localized arguments and string values anywhere in the context,
+// catching secrets that field-name-based redaction misses. It is a redaction hook that should
+// run before the others, so its default priority is 0.
+type SecretScanHook struct {
+	config SecretScanConfig
+
+	mu         sync.RWMutex
+	rules      []compiledSecretRule
+	rulesHash  [sha256.Size]byte
+	detections map[string]int64
+}
+
+// NewSecretScanHook creates a secret scanning hook, loading config.RulesFile
+// if set
+func NewSecretScanHook(config SecretScanConfig) (*SecretScanHook, error) {
+	hook := &SecretScanHook{config: config, detections: make(map[string]int64)}
+	if config.RulesFile != "" {
+		if _, err := hook.ReloadRules(); err != nil {
+			return nil, err
+		}
+		return hook, nil
+	}
+
+	rules := config.Rules
+	if len(rules) == 0 {
+		rules = DefaultSecretRules
+	}
+	compiled, err := compileSecretRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	hook.rules = compiled
+	return hook, nil
+}
+
+// NewDefaultSecretScanHook creates an enabled secret scanning hook with
+// DefaultSecretRules
+func NewDefaultSecretScanHook() *SecretScanHook {
+	hook, err := NewSecretScanHook(SecretScanConfig{
+		HookConfig: HookConfig{
+			Type:        HookTypeRedact,
+			Name:        "secret_scan",
+			Description: "Redacts known secret formats such as cloud keys, tokens and private keys",
+			Enabled:     true,
+			Priority:    0,
+		},
+	})
+	if err != nil {
+		panic(err) // The default rules always compile
+	}
+	return hook
+}
+
+// LoadSecretRules reads a JSON or YAML (.yaml, .yml) secret rules file
+func LoadSecretRules(path string) ([]SecretRule, error) {
+	file, _, err := loadSecretRuleFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return file.rules(), nil
+}
+
+// loadSecretRuleFile reads and parses a rules file and returns its hash
+func loadSecretRuleFile(path string) (*SecretRuleFile, [sha256.Size]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, [sha256.Size]byte{}, fmt.Errorf("failed to read secret rules: %w", err)
+	}
+	hash := sha256.Sum256(data)
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var raw interface{}
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, hash, fmt.Errorf("failed to parse YAML secret rules: %w", err)
+		}
+		if data, err = json.Marshal(raw); err != nil {
+			return nil, hash, fmt.Errorf("failed to convert YAML secret rules: %w", err)
+		}
+	}
+
+	var file SecretRuleFile
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, hash, fmt.Errorf("failed to parse secret rules: %w", err)
+	}
+	return &file, hash, nil
+}
+
+// rules returns the rules of the file, including the defaults if requested
+func (f *SecretRuleFile) rules() []SecretRule {
+	if !f.UseDefault {
+		return f.Rules
+	}
+	return append(append([]SecretRule(nil), DefaultSecretRules...), f.Rules...)
+}
+
+// compileSecretRules validates and compiles rules
+func compileSecretRules(rules []SecretRule) ([]compiledSecretRule, error) {
+	compiled := make([]compiledSecretRule, 0, len(rules))
+	ids := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if rule.ID == "" {
+			return nil, fmt.Errorf("secret rule without id")
+		}
+		if ids[rule.ID] {
+			return nil, fmt.Errorf("duplicate secret rule %q", rule.ID)
+		}
+		ids[rule.ID] = true
+
+		regex, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex of secret rule %q: %w", rule.ID, err)
+		}
+		if rule.SecretGroup < 0 || rule.SecretGroup > regex.NumSubexp() {
+			return nil, fmt.Errorf("secret rule %q has no group %d", rule.ID, rule.SecretGroup)
+		}
+
+		keywords := make([]string, len(rule.Keywords))
+		for i, keyword := range rule.Keywords {
+			keywords[i] = strings.ToLower(keyword)
+		}
+		compiled = append(compiled, compiledSecretRule{
+			id:          rule.ID,
+			regex:       regex,
+			keywords:    keywords,
+			secretGroup: rule.SecretGroup,
+		})
+	}
+	return compiled, nil
+}
+
+// ReloadRules loads config.RulesFile if it changed since the last load and
+// reports whether new rules were applied. On error the current rules stay
+// active.
+func (h *SecretScanHook) ReloadRules() (bool, error) {
+	if h.config.RulesFile == "" {
+		return false, fmt.Errorf("secret scan hook %s has no rules file", h.config.Name)
+	}
+
+	file, hash, err := loadSecretRuleFile(h.config.RulesFile)
+	if err != nil {
+		return false, err
+	}
+	h.mu.RLock()
+	unchanged := hash == h.rulesHash && h.rules != nil
+	h.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	compiled, err := compileSecretRules(file.rules())
+	if err != nil {
+		return false, err
+	}
+	h.mu.Lock()
+	h.rules, h.rulesHash = compiled, hash
+	h.mu.Unlock()
+	return true, nil
+}
+
+// WatchRules polls config.RulesFile for changes until stopCh is closed;
+// reload errors are written to stderr
+func (h *SecretScanHook) WatchRules(pollInterval time.Duration, stopCh <-chan struct{}) {
+	go func() {
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-time.After(pollInterval):
+				if _, err := h.ReloadRules(); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to reload secret rules %s: %v\n", h.config.RulesFile, err)
+				}
+			}
+		}
+	}()
+}
+
+// Detections returns the number of secrets redacted per rule ID
+func (h *SecretScanHook) Detections() map[string]int64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	detections := make(map[string]int64, len(h.detections))
+	for id, count := range h.detections {
+		detections[id] = count
+	}
+	return detections
+}
+
+// Process implements LogHook interface
+func (h *SecretScanHook) Process(entry CoreLogEntry) (CoreLogEntry, error) {
+	if !h.config.Enabled {
+		return entry, nil
+	}
+
+	h.mu.RLock()
+	rules := h.rules
+	h.mu.RUnlock()
+
+	found := make(map[string]int64)
+	entry.Message = h.scanString(rules, entry.Message, found)
+	entry.Localized = entry.Localized.redactArgs(func(s string) string {
+		return h.scanString(rules, s, found)
+	})
+	if entry.Context != nil {
+		if scanned, changed := h.scanValue(rules, entry.Context, found); changed {
+			entry.Context = scanned.(map[string]interface{})
+		}
+	}
+
+	if len(found) > 0 {
+		h.mu.Lock()
+		for id, count := range found {
+			h.detections[id] += count
+		}
+		h.mu.Unlock()
+	}
+	return entry, nil
+}
+
+// scanString redacts the secrets in s, counting them in found
+func (h *SecretScanHook) scanString(rules []compiledSecretRule, s string, found map[string]int64) string {
+	if s == "" {
+		return s
+	}
+	var lower string
+	for _, rule := range rules {
+		if len(rule.keywords) > 0 {
+			if lower == "" {
+				lower = strings.ToLower(s)
+			}
+			if !containsAny(lower, rule.keywords) {
+				continue
+			}
+		}
+
+		matches := rule.regex.FindAllStringSubmatchIndex(s, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		replacement := h.config.Replacement
+		if replacement == "" {
+			replacement = "[REDACTED:" + rule.id + "]"
+		}
+
+		var b strings.Builder
+		last := 0
+		for _, match := range matches {
+			start, end := match[2*rule.secretGroup], match[2*rule.secretGroup+1]
+			if start < 0 {
+				continue // Optional group did not participate
+			}
+			b.WriteString(s[last:start])
+			b.WriteString(replacement)
+			last = end
+			found[rule.id]++
+		}
+		b.WriteString(s[last:])
+		s, lower = b.String(), ""
+	}
+	return s
+}
+
+// containsAny reports whether s contains any of the substrings
+func containsAny(s string, substrings []string) bool {
+	for _, substring := range substrings {
+		if strings.Contains(s, substring) {
+			return true
+		}
+	}
+	return false
+}
+
+// scanValue redacts secrets in strings, errors, maps and slices, copying
+// containers only if something changed
+func (h *SecretScanHook) scanValue(rules []compiledSecretRule, value interface{}, found map[string]int64) (interface{}, bool) {
+	switch v := value.(type) {
+	case string:
+		scanned := h.scanString(rules, v, found)
+		return scanned, scanned != v
+	case error:
+		message := v.Error()
+		if scanned := h.scanString(rules, message, found); scanned != message {
+			return scanned, true
+		}
+	case []string:
+		var result []string
+		for i, s := range v {
+			if scanned := h.scanString(rules, s, found); scanned != s {
+				if result == nil {
+					result = append([]string(nil), v...)
+				}
+				result[i] = scanned
+			}
+		}
+		if result != nil {
+			return result, true
+		}
+	case map[string]string:
+		var result map[string]string
+		for key, s := range v {
+			if scanned := h.scanString(rules, s, found); scanned != s {
+				if result == nil {
+					result = make(map[string]string, len(v))
+					for k, val := range v {
+						result[k] = val
+					}
+				}
+				result[key] = scanned
+			}
+		}
+		if result != nil {
+			return result, true
+		}
+	case map[string]interface{}:
+		var result map[string]interface{}
+		for key, val := range v {
+			if scanned, changed := h.scanValue(rules, val, found); changed {
+				if result == nil {
+					result = make(map[string]interface{}, len(v))
+					for k, original := range v {
+						result[k] = original
+					}
+				}
+				result[key] = scanned
+			}
+		}
+		if result != nil {
+			return result, true
+		}
+	case []interface{}:
+		var result []interface{}
+		for i, val := range v {
+			if scanned, changed := h.scanValue(rules, val, found); changed {
+				if result == nil {
+					result = append([]interface{}(nil), v...)
+				}
+				result[i] = scanned
+			}
+		}
+		if result != nil {
+			return result, true
+		}
+	}
+	return value, false
+}
+
+// GetConfig implements EnhancedLogHook interface
+func (h *SecretScanHook) GetConfig() HookConfig {
+	return h.config.HookConfig
+}
+
+// GetType implements EnhancedLogHook interface
+func (h *SecretScanHook) GetType() HookType {
+	return h.config.Type
+}
+
+// IsEnabled implements EnhancedLogHook interface
+func (h *SecretScanHook) IsEnabled() bool {
+	return h.config.Enabled
+}
+
+// SetEnabled implements EnhancedLogHook interface
+func (h *SecretScanHook) SetEnabled(enabled bool) {
+	h.config.Enabled = enabled
+}
+
+// GetPriority implements EnhancedLogHook interface
+func (h *SecretScanHook) GetPriority() int {
+	return h.config.Priority
+}
+
+// SetPriority implements EnhancedLogHook interface
+func (h *SecretScanHook) SetPriority(priority int) {
+	h.config.Priority = priority
+}
@@ -40,6 +40,12 @@ type SerializerOptions struct {
 	// Output options
 	Colored bool // Whether to use colors in output
 	Raw     bool // Whether to print raw (unformatted) JSON
+
+	// Redaction
+	Redactor *RedactHook // Redacts matching fields (at any depth) from serialized output
+
+	// Limits bounds the output for huge values (zero value: unlimited)
+	Limits PrettyLimits
 }
 
 // FieldTransformer is a function that transforms a field value before serialization
@@ -65,6 +71,7 @@ var DefaultSerializerOptions = SerializerOptions{
 	EscapeHTML:        true,
 	Colored:           true,
 	Raw:               false,
+	Limits:            DefaultPrettyLimits,
 }
 
 // NewJsonSerializer creates a new JSON serializer with the given options
@@ -96,11 +103,22 @@ func (js *JsonSerializer) Marshal(value interface{}) ([]byte, error) {
 		return nil, fmt.Errorf("transformation error: %w", err)
 	}
 
+	// Redact sensitive fields, including nested ones
+	if js.options.Redactor != nil {
+		transformedValue = js.options.Redactor.RedactValue(transformedValue)
+	}
+
 	// Use standard json.Marshal with custom options
 	var jsonData []byte
 	var err2 error
 
-	if js.options.Indent > 0 && js.options.PrettyPrint {
+	if js.options.Limits.enabled() {
+		indent := ""
+		if js.options.Indent > 0 && js.options.PrettyPrint {
+			indent = strings.Repeat(" ", js.options.Indent)
+		}
+		jsonData, err2 = MarshalBounded(transformedValue, indent, js.options.Limits)
+	} else if js.options.Indent > 0 && js.options.PrettyPrint {
 		jsonData, err2 = json.MarshalIndent(transformedValue, "", strings.Repeat(" ", js.options.Indent))
 	} else {
 		jsonData, err2 = json.Marshal(transformedValue)
@@ -148,9 +166,15 @@ func (js *JsonSerializer) transformValue(value interface{}) (interface{}, error)
 	v := reflect.ValueOf(value)
 
 	// Handle nil values
-	if !v.IsValid() || v.IsNil() {
+	if !v.IsValid() {
 		return value, nil
 	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return value, nil
+		}
+	}
 
 	// Handle different types
 	switch v.Kind() {
@@ -398,6 +422,16 @@ func (js *JsonSerializer) SetEscapeHTML(escape bool) {
 	js.options.EscapeHTML = escape
 }
 
+// SetRedactor sets the redaction hook applied to serialized output
+func (js *JsonSerializer) SetRedactor(redactor *RedactHook) {
+	js.options.Redactor = redactor
+}
+
+// SetLimits bounds the output for huge values, see PrettyLimits
+func (js *JsonSerializer) SetLimits(limits PrettyLimits) {
+	js.options.Limits = limits
+}
+
 // SetIncludeUnexported enables or disables including unexported fields
 func (js *JsonSerializer) SetIncludeUnexported(include bool) {
 	js.options.IncludeUnexported = include
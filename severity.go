@@ -0,0 +1,164 @@
+package pim
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Context keys used for severity scoring and alert routing metadata
+const (
+	SeverityScoreKey    = "severity_score"
+	AlertTeamKey        = "alert_team"
+	EscalationPolicyKey = "escalation_policy"
+)
+
+// DefaultLevelScores provides the base severity score for each log level
+var DefaultLevelScores = map[LogLevel]float64{
+	PanicLevel:   100,
+	ErrorLevel:   70,
+	WarningLevel: 40,
+	InfoLevel:    10,
+	DebugLevel:   0,
+	TraceLevel:   0,
+}
+
+// AlertRoute maps a severity score range to an owning team and escalation policy
+type AlertRoute struct {
+	MinScore         float64                 `json:"min_score"`         // Minimum score for this route to apply
+	Team             string                  `json:"team"`              // Team that owns matching entries
+	EscalationPolicy string                  `json:"escalation_policy"` // Escalation policy for pagers
+	Match            func(CoreLogEntry) bool `json:"-"`                 // Optional additional match condition
+}
+
+// AlertRouting is the routing metadata attached to a scored entry
+type AlertRouting struct {
+	Score            float64 `json:"score"`
+	Team             string  `json:"team,omitempty"`
+	EscalationPolicy string  `json:"escalation_policy,omitempty"`
+}
+
+// SeverityScoringConfig holds configuration for severity scoring hooks
+type SeverityScoringConfig struct {
+	HookConfig
+	LevelScores  map[LogLevel]float64          `json:"level_scores,omitempty"`  // Base score per level (default: DefaultLevelScores)
+	FieldWeights map[string]map[string]float64 `json:"field_weights,omitempty"` // Score added per context field value, e.g. customer_tier -> enterprise -> 20
+	Routes       []AlertRoute                  `json:"routes,omitempty"`        // Routing rules, highest MinScore wins
+	CustomFunc   func(CoreLogEntry) float64    `json:"-"`                       // Additional score contribution
+}
+
+// SeverityScoringHook computes a numeric severity score and attaches alert routing metadata
+type SeverityScoringHook struct {
+	config SeverityScoringConfig
+}
+
+// NewSeverityScoringHook creates a new severity scoring hook
+func NewSeverityScoringHook(config SeverityScoringConfig) *SeverityScoringHook {
+	if config.LevelScores == nil {
+		config.LevelScores = DefaultLevelScores
+	}
+
+	// Evaluate the most severe routes first
+	routes := make([]AlertRoute, len(config.Routes))
+	copy(routes, config.Routes)
+	sort.SliceStable(routes, func(i, j int) bool {
+		return routes[i].MinScore > routes[j].MinScore
+	})
+	config.Routes = routes
+
+	return &SeverityScoringHook{config: config}
+}
+
+// Process implements LogHook interface
+func (h *SeverityScoringHook) Process(entry CoreLogEntry) (CoreLogEntry, error) {
+	if !h.config.Enabled {
+		return entry, nil
+	}
+
+	score := h.Score(entry)
+
+	if entry.Context == nil {
+		entry.Context = make(map[string]interface{})
+	}
+	entry.Context[SeverityScoreKey] = score
+
+	for _, route := range h.config.Routes {
+		if score < route.MinScore {
+			continue
+		}
+		if route.Match != nil && !route.Match(entry) {
+			continue
+		}
+		if route.Team != "" {
+			entry.Context[AlertTeamKey] = route.Team
+		}
+		if route.EscalationPolicy != "" {
+			entry.Context[EscalationPolicyKey] = route.EscalationPolicy
+		}
+		break
+	}
+
+	return entry, nil
+}
+
+// Score computes the severity score of an entry without modifying it
+func (h *SeverityScoringHook) Score(entry CoreLogEntry) float64 {
+	score := h.config.LevelScores[entry.Level]
+
+	for field, weights := range h.config.FieldWeights {
+		value, exists := entry.Context[field]
+		if !exists {
+			continue
+		}
+		score += weights[fmt.Sprintf("%v", value)]
+	}
+
+	if h.config.CustomFunc != nil {
+		score += h.config.CustomFunc(entry)
+	}
+
+	return score
+}
+
+// GetAlertRouting extracts routing metadata attached by a SeverityScoringHook.
+// It returns false if the entry has not been scored.
+func GetAlertRouting(entry CoreLogEntry) (AlertRouting, bool) {
+	score, ok := entry.Context[SeverityScoreKey].(float64)
+	if !ok {
+		return AlertRouting{}, false
+	}
+
+	routing := AlertRouting{Score: score}
+	routing.Team, _ = entry.Context[AlertTeamKey].(string)
+	routing.EscalationPolicy, _ = entry.Context[EscalationPolicyKey].(string)
+	return routing, true
+}
+
+// GetConfig implements EnhancedLogHook interface
+func (h *SeverityScoringHook) GetConfig() HookConfig {
+	return h.config.HookConfig
+}
+
+// GetType implements EnhancedLogHook interface
+func (h *SeverityScoringHook) GetType() HookType {
+	return h.config.Type
+}
+
+// IsEnabled implements EnhancedLogHook interface
+func (h *SeverityScoringHook) IsEnabled() bool {
+	return h.config.Enabled
+}
+
+// SetEnabled implements EnhancedLogHook interface
+func (h *SeverityScoringHook) SetEnabled(enabled bool) {
+	h.config.Enabled = enabled
+}
+
+// GetPriority implements EnhancedLogHook interface
+func (h *SeverityScoringHook) GetPriority() int {
+	return h.config.Priority
+}
+
+// SetPriority implements EnhancedLogHook interface
+func (h *SeverityScoringHook) SetPriority(priority int) {
+	h.config.Priority = priority
+}
@@ -0,0 +1,63 @@
+package pim
+
+import "testing"
+
+func TestSeverityScoringHook(t *testing.T) {
+	hook := NewSeverityScoringHook(SeverityScoringConfig{
+		HookConfig: HookConfig{
+			Type:     HookTypeEnrich,
+			Name:     "severity",
+			Enabled:  true,
+			Priority: 50,
+		},
+		FieldWeights: map[string]map[string]float64{
+			"customer_tier": {"enterprise": 20},
+			"error_class":   {"permanent": 10, "transient": -30},
+		},
+		Routes: []AlertRoute{
+			{MinScore: 50, Team: "backend", EscalationPolicy: "business-hours"},
+			{MinScore: 90, Team: "sre", EscalationPolicy: "page-immediately"},
+		},
+	})
+
+	entry := CoreLogEntry{
+		Level:   ErrorLevel,
+		Message: "payment failed",
+		Context: map[string]interface{}{
+			"customer_tier": "enterprise",
+			"error_class":   "permanent",
+		},
+	}
+
+	result, err := hook.Process(entry)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	routing, ok := GetAlertRouting(result)
+	if !ok {
+		t.Fatal("Expected routing metadata to be attached")
+	}
+	if routing.Score != 100 {
+		t.Errorf("Expected score 100, got %v", routing.Score)
+	}
+	if routing.Team != "sre" || routing.EscalationPolicy != "page-immediately" {
+		t.Errorf("Expected sre/page-immediately routing, got %+v", routing)
+	}
+
+	entry.Context = map[string]interface{}{"error_class": "transient"}
+	result, _ = hook.Process(entry)
+	routing, _ = GetAlertRouting(result)
+	if routing.Score != 40 {
+		t.Errorf("Expected score 40, got %v", routing.Score)
+	}
+	if routing.Team != "" {
+		t.Errorf("Expected no team below route thresholds, got %s", routing.Team)
+	}
+}
+
+func TestGetAlertRoutingUnscored(t *testing.T) {
+	if _, ok := GetAlertRouting(CoreLogEntry{Message: "plain"}); ok {
+		t.Error("Expected unscored entry to have no routing")
+	}
+}
@@ -0,0 +1,180 @@
+package pim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultShutdownTimeout bounds how long shutdown waits for one logger to
+// flush and close
+const DefaultShutdownTimeout = 5 * time.Second
+
+// ShutdownOptions configures how a registered logger is shut down
+type ShutdownOptions struct {
+	// Order sorts loggers during shutdown, lowest first; loggers with the
+	// same Order are shut down in registration order. Give loggers that
+	// write to other loggers (e.g. through a LineWriter) a lower Order.
+	Order int
+
+	// Timeout bounds the flush and close of this logger (default:
+	// DefaultShutdownTimeout). Shutdown moves on to the next logger when
+	// it expires, leaving the logger to finish in the background.
+	Timeout time.Duration
+}
+
+// shutdownRegistration is one registered logger
+type shutdownRegistration struct {
+	logger  *LoggerCore
+	options ShutdownOptions
+	seq     uint64
+}
+
+// shutdownRegistry holds the loggers flushed and closed on shutdown
+var shutdownRegistry struct {
+	mu            sync.Mutex
+	registrations []*shutdownRegistration
+	seq           uint64
+}
+
+// RegisterShutdown sets how the logger is flushed and closed by
+// ShutdownContext, FlushAllLoggers and the InstallExitHandler signal
+// handler. NewLoggerCore registers every logger with default options, so
+// this is only needed to change them or to register a logger again after
+// unregistering it. Calling the returned function unregisters the logger;
+// Close also unregisters it, so it is never closed twice.
+func (l *LoggerCore) RegisterShutdown(options ShutdownOptions) (unregister func()) {
+	shutdownRegistry.mu.Lock()
+	defer shutdownRegistry.mu.Unlock()
+
+	var registration *shutdownRegistration
+	for _, r := range shutdownRegistry.registrations {
+		if r.logger == l {
+			registration = r
+			break
+		}
+	}
+	if registration == nil {
+		shutdownRegistry.seq++
+		registration = &shutdownRegistration{logger: l, seq: shutdownRegistry.seq}
+		shutdownRegistry.registrations = append(shutdownRegistry.registrations, registration)
+	}
+	registration.options = options
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			removeShutdownRegistrations(func(r *shutdownRegistration) bool { return r == registration })
+		})
+	}
+}
+
+// RegisterLoggerForShutdown registers a logger for global shutdown handling
+// with default options, see RegisterShutdown
+func RegisterLoggerForShutdown(logger *LoggerCore) {
+	logger.RegisterShutdown(ShutdownOptions{})
+}
+
+// unregisterShutdown removes every registration of the logger
+func (l *LoggerCore) unregisterShutdown() {
+	removeShutdownRegistrations(func(r *shutdownRegistration) bool { return r.logger == l })
+}
+
+// removeShutdownRegistrations removes the registrations matching remove
+func removeShutdownRegistrations(remove func(*shutdownRegistration) bool) {
+	shutdownRegistry.mu.Lock()
+	defer shutdownRegistry.mu.Unlock()
+
+	kept := shutdownRegistry.registrations[:0]
+	for _, r := range shutdownRegistry.registrations {
+		if !remove(r) {
+			kept = append(kept, r)
+		}
+	}
+	clear(shutdownRegistry.registrations[len(kept):])
+	shutdownRegistry.registrations = kept
+}
+
+// ShutdownContext flushes and closes every registered logger in order and
+// unregisters them. Each logger gets its Timeout, cut short when ctx is
+// done; loggers not reached by then are not closed. The returned error
+// joins the close errors and timeouts, which wrap context.DeadlineExceeded
+// or the ctx error.
+func ShutdownContext(ctx context.Context) error {
+	shutdownRegistry.mu.Lock()
+	registrations := make([]shutdownRegistration, len(shutdownRegistry.registrations))
+	for i, r := range shutdownRegistry.registrations {
+		registrations[i] = *r
+	}
+	shutdownRegistry.registrations = nil
+	shutdownRegistry.mu.Unlock()
+
+	sort.SliceStable(registrations, func(i, j int) bool {
+		a, b := registrations[i], registrations[j]
+		if a.options.Order != b.options.Order {
+			return a.options.Order < b.options.Order
+		}
+		return a.seq < b.seq
+	})
+
+	var errs []error
+	for _, r := range registrations {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, fmt.Errorf("logger %q not shut down: %w", r.logger.name, err))
+			continue
+		}
+		if err := shutdownLogger(ctx, r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// shutdownLogger flushes and closes one logger within its timeout
+func shutdownLogger(ctx context.Context, r shutdownRegistration) error {
+	timeout := r.options.Timeout
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- r.logger.Close() // Close flushes first
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("logger %q: %w", r.logger.name, err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("logger %q shutdown: %w", r.logger.name, ctx.Err())
+	}
+}
+
+// FlushAllLoggers flushes and closes all registered loggers, see
+// ShutdownContext. Errors are reported on stderr.
+func FlushAllLoggers() {
+	if err := ShutdownContext(context.Background()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error shutting down loggers: %v\n", err)
+	}
+}
+
+// InstallExitHandler installs a handler to flush/close all loggers on exit
+// signals. Panics can only be recovered in the goroutine that panics; defer
+// RecoverAndLog or RecoverAndLogWithConfig with RePanic there.
+func InstallExitHandler() {
+	ch := make(chan os.Signal, 2)
+	notifyExitSignals(ch)
+	go func() {
+		<-ch
+		FlushAllLoggers()
+		os.Exit(1)
+	}()
+}
@@ -0,0 +1,138 @@
+package pim
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// closeRecorder records the order in which writers are closed
+type closeRecorder struct {
+	mu     sync.Mutex
+	closed []string
+}
+
+func (r *closeRecorder) add(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = append(r.closed, name)
+}
+
+// recordingWriter reports its Close to a closeRecorder, optionally blocking
+type recordingWriter struct {
+	name     string
+	recorder *closeRecorder
+	block    chan struct{}
+}
+
+func (w *recordingWriter) Write(entry CoreLogEntry) error { return nil }
+func (w *recordingWriter) Flush() error                   { return nil }
+func (w *recordingWriter) Close() error {
+	if w.block != nil {
+		<-w.block
+	}
+	w.recorder.add(w.name)
+	return nil
+}
+
+// isolateShutdown empties the shutdown registry for the test, so loggers of
+// other tests are not closed, and restores it afterwards
+func isolateShutdown(t *testing.T) {
+	shutdownRegistry.mu.Lock()
+	saved := shutdownRegistry.registrations
+	shutdownRegistry.registrations = nil
+	shutdownRegistry.mu.Unlock()
+	t.Cleanup(func() {
+		shutdownRegistry.mu.Lock()
+		shutdownRegistry.registrations = append(saved, shutdownRegistry.registrations...)
+		shutdownRegistry.mu.Unlock()
+	})
+}
+
+func newShutdownLogger(name string, recorder *closeRecorder, block chan struct{}) *LoggerCore {
+	config := DefaultLoggerConfig
+	config.EnableConsole = false
+	logger := NewLoggerCore(config)
+	logger.AddWriter(&recordingWriter{name: name, recorder: recorder, block: block})
+	return logger
+}
+
+func TestShutdownContextOrder(t *testing.T) {
+	isolateShutdown(t)
+	recorder := &closeRecorder{}
+	a := newShutdownLogger("a", recorder, nil)
+	newShutdownLogger("b", recorder, nil) // Default options
+	c := newShutdownLogger("c", recorder, nil)
+	a.RegisterShutdown(ShutdownOptions{Order: 1})
+	c.RegisterShutdown(ShutdownOptions{Order: 1})
+	c.RegisterShutdown(ShutdownOptions{Order: 2}) // Replaces the options
+
+	if err := ShutdownContext(context.Background()); err != nil {
+		t.Fatalf("ShutdownContext failed: %v", err)
+	}
+	if want := []string{"b", "a", "c"}; !reflect.DeepEqual(recorder.closed, want) {
+		t.Errorf("Expected close order %v, got %v", want, recorder.closed)
+	}
+
+	// Loggers are unregistered by shutdown
+	ShutdownContext(context.Background())
+	if len(recorder.closed) != 3 {
+		t.Errorf("Expected no second close, got %v", recorder.closed)
+	}
+}
+
+func TestShutdownUnregister(t *testing.T) {
+	isolateShutdown(t)
+	recorder := &closeRecorder{}
+	a := newShutdownLogger("a", recorder, nil)
+	b := newShutdownLogger("b", recorder, nil)
+	unregister := a.RegisterShutdown(ShutdownOptions{})
+
+	unregister()
+	unregister() // Idempotent
+	b.Close()    // Close unregisters too
+
+	if err := ShutdownContext(context.Background()); err != nil {
+		t.Fatalf("ShutdownContext failed: %v", err)
+	}
+	if want := []string{"b"}; !reflect.DeepEqual(recorder.closed, want) {
+		t.Errorf("Expected only the explicit Close, got %v", recorder.closed)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	isolateShutdown(t)
+	recorder := &closeRecorder{}
+	block := make(chan struct{})
+	defer close(block)
+	slow := newShutdownLogger("slow", recorder, block)
+	fast := newShutdownLogger("fast", recorder, nil)
+	slow.RegisterShutdown(ShutdownOptions{Timeout: 20 * time.Millisecond})
+	fast.RegisterShutdown(ShutdownOptions{Order: 1})
+
+	err := ShutdownContext(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a timeout error, got %v", err)
+	}
+	if want := []string{"fast"}; !reflect.DeepEqual(recorder.closed, want) {
+		t.Errorf("Expected shutdown to move on after the timeout, got %v", recorder.closed)
+	}
+}
+
+func TestShutdownContextCanceled(t *testing.T) {
+	isolateShutdown(t)
+	recorder := &closeRecorder{}
+	newShutdownLogger("a", recorder, nil) // Registered by NewLoggerCore
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := ShutdownContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if len(recorder.closed) != 0 {
+		t.Errorf("Expected no logger to be closed, got %v", recorder.closed)
+	}
+}
@@ -0,0 +1,219 @@
+package pim
+
+import (
+	"sync/atomic"
+)
+
+// pipelineCounters counts entries through a logger's pipeline for
+// Snapshot; it is shared with child loggers. Methods do nothing on a nil
+// receiver.
+type pipelineCounters struct {
+	entries     [TraceLevel + 1]atomic.Uint64 // Written entries by level
+	sampled     atomic.Uint64
+	filtered    atomic.Uint64
+	writeErrors atomic.Uint64
+}
+
+// written counts an entry passed to the writers
+func (c *pipelineCounters) written(level LogLevel) {
+	if c == nil || level < PanicLevel || level > TraceLevel {
+		return
+	}
+	c.entries[level].Add(1)
+}
+
+// sampledOut counts an entry dropped by sampling
+func (c *pipelineCounters) sampledOut() {
+	if c != nil {
+		c.sampled.Add(1)
+	}
+}
+
+// filteredOut counts an entry dropped by a hook
+func (c *pipelineCounters) filteredOut() {
+	if c != nil {
+		c.filtered.Add(1)
+	}
+}
+
+// writeFailed counts a failed writer call
+func (c *pipelineCounters) writeFailed() {
+	if c != nil {
+		c.writeErrors.Add(1)
+	}
+}
+
+// LevelCounts holds a count per level
+type LevelCounts struct {
+	Panic   uint64 `json:"panic"`
+	Error   uint64 `json:"error"`
+	Warning uint64 `json:"warning"`
+	Info    uint64 `json:"info"`
+	Debug   uint64 `json:"debug"`
+	Trace   uint64 `json:"trace"`
+}
+
+// Total returns the sum of the counts
+func (c LevelCounts) Total() uint64 {
+	return c.Panic + c.Error + c.Warning + c.Info + c.Debug + c.Trace
+}
+
+// MetricsSnapshot is a typed view of a logger's internals for monitoring
+// agents. Field and JSON names are stable: fields may be added, but are
+// never renamed or removed. Counters are cumulative since the logger was
+// created and shared with its child loggers (see Named and WithContext);
+// the other fields are gauges.
+type MetricsSnapshot struct {
+	Service string `json:"service"` // ServiceName of the logger
+	Name    string `json:"name"`    // Hierarchical name, see Named
+	Level   string `json:"level"`   // Current minimum level
+
+	Entries     LevelCounts `json:"entries"`      // Entries passed to the writers
+	SampledOut  uint64      `json:"sampled_out"`  // Entries dropped by sampling
+	Filtered    uint64      `json:"filtered"`     // Entries dropped by hooks
+	WriteErrors uint64      `json:"write_errors"` // Failed writer calls; an entry counts once per failing writer
+
+	AsyncDropped        uint64 `json:"async_dropped"`         // Entries dropped on a full async buffer
+	AsyncBufferLength   int    `json:"async_buffer_length"`   // Queued entries, zero for synchronous loggers
+	AsyncBufferCapacity int    `json:"async_buffer_capacity"` // Queue capacity, zero for synchronous loggers
+
+	Writers int `json:"writers"` // Registered writers
+	Hooks   int `json:"hooks"`   // Registered hooks, legacy and enhanced
+
+	// PersistentQueue describes the disk-backed queue, if configured
+	PersistentQueue *PersistentQueueStats `json:"persistent_queue,omitempty"`
+}
+
+// Snapshot returns the current metrics of the logger
+func (l *LoggerCore) Snapshot() MetricsSnapshot {
+	l.mu.RLock()
+	writers := len(l.writers)
+	l.mu.RUnlock()
+
+	snapshot := MetricsSnapshot{
+		Service:      l.serviceName,
+		Name:         l.name,
+		Level:        l.GetLevel().Name(),
+		AsyncDropped: l.AsyncDropped(),
+		Writers:      writers,
+		Hooks:        l.GetHookCount(),
+	}
+	snapshot.AsyncBufferLength, snapshot.AsyncBufferCapacity = l.AsyncBufferStats()
+	if stats, ok := l.PersistentQueueStats(); ok {
+		snapshot.PersistentQueue = &stats
+	}
+	if c := l.counters; c != nil {
+		snapshot.Entries = LevelCounts{
+			Panic:   c.entries[PanicLevel].Load(),
+			Error:   c.entries[ErrorLevel].Load(),
+			Warning: c.entries[WarningLevel].Load(),
+			Info:    c.entries[InfoLevel].Load(),
+			Debug:   c.entries[DebugLevel].Load(),
+			Trace:   c.entries[TraceLevel].Load(),
+		}
+		snapshot.SampledOut = c.sampled.Load()
+		snapshot.Filtered = c.filtered.Load()
+		snapshot.WriteErrors = c.writeErrors.Load()
+	}
+	return snapshot
+}
+
+// Snapshot returns the metrics of every logger registered for shutdown,
+// which includes all loggers created by NewLoggerCore that are not closed,
+// in the order they were registered
+func Snapshot() []MetricsSnapshot {
+	shutdownRegistry.mu.Lock()
+	loggers := make([]*LoggerCore, len(shutdownRegistry.registrations))
+	for i, r := range shutdownRegistry.registrations {
+		loggers[i] = r.logger
+	}
+	shutdownRegistry.mu.Unlock()
+
+	snapshots := make([]MetricsSnapshot, len(loggers))
+	for i, logger := range loggers {
+		snapshots[i] = logger.Snapshot()
+	}
+	return snapshots
+}
+
+// MetricKind tells how a metric's value changes
+type MetricKind int
+
+const (
+	// MetricCounter values only grow
+	MetricCounter MetricKind = iota
+	// MetricGauge values go up and down
+	MetricGauge
+)
+
+// MetricDescription describes one metric of a MetricsSnapshot, in the style
+// of runtime/metrics. Names have the form /pim/<path>:<unit> and are stable.
+type MetricDescription struct {
+	Name        string
+	Description string
+	Kind        MetricKind
+	value       func(MetricsSnapshot) uint64
+}
+
+// MetricSample is the value of one metric
+type MetricSample struct {
+	Name  string
+	Value uint64
+}
+
+// metricDescriptions are the metrics returned by AllMetrics, sorted by name
+var metricDescriptions = []MetricDescription{
+	{"/pim/async/buffer/capacity:entries", "Capacity of the async queue.", MetricGauge,
+		func(s MetricsSnapshot) uint64 { return uint64(s.AsyncBufferCapacity) }},
+	{"/pim/async/buffer/length:entries", "Entries in the async queue.", MetricGauge,
+		func(s MetricsSnapshot) uint64 { return uint64(s.AsyncBufferLength) }},
+	{"/pim/async/dropped:entries", "Entries dropped on a full async queue.", MetricCounter,
+		func(s MetricsSnapshot) uint64 { return s.AsyncDropped }},
+	{"/pim/entries/debug:entries", "Debug entries written.", MetricCounter,
+		func(s MetricsSnapshot) uint64 { return s.Entries.Debug }},
+	{"/pim/entries/error:entries", "Error entries written.", MetricCounter,
+		func(s MetricsSnapshot) uint64 { return s.Entries.Error }},
+	{"/pim/entries/info:entries", "Info entries written.", MetricCounter,
+		func(s MetricsSnapshot) uint64 { return s.Entries.Info }},
+	{"/pim/entries/panic:entries", "Panic entries written.", MetricCounter,
+		func(s MetricsSnapshot) uint64 { return s.Entries.Panic }},
+	{"/pim/entries/total:entries", "Entries written at any level.", MetricCounter,
+		func(s MetricsSnapshot) uint64 { return s.Entries.Total() }},
+	{"/pim/entries/trace:entries", "Trace entries written.", MetricCounter,
+		func(s MetricsSnapshot) uint64 { return s.Entries.Trace }},
+	{"/pim/entries/warning:entries", "Warning entries written.", MetricCounter,
+		func(s MetricsSnapshot) uint64 { return s.Entries.Warning }},
+	{"/pim/filtered:entries", "Entries dropped by hooks.", MetricCounter,
+		func(s MetricsSnapshot) uint64 { return s.Filtered }},
+	{"/pim/hooks:hooks", "Registered hooks.", MetricGauge,
+		func(s MetricsSnapshot) uint64 { return uint64(s.Hooks) }},
+	{"/pim/persistent-queue/size:bytes", "Bytes in persistent queue segment files.", MetricGauge,
+		func(s MetricsSnapshot) uint64 {
+			if s.PersistentQueue == nil {
+				return 0
+			}
+			return uint64(s.PersistentQueue.Size)
+		}},
+	{"/pim/sampled-out:entries", "Entries dropped by sampling.", MetricCounter,
+		func(s MetricsSnapshot) uint64 { return s.SampledOut }},
+	{"/pim/write-errors:errors", "Failed writer calls.", MetricCounter,
+		func(s MetricsSnapshot) uint64 { return s.WriteErrors }},
+	{"/pim/writers:writers", "Registered writers.", MetricGauge,
+		func(s MetricsSnapshot) uint64 { return uint64(s.Writers) }},
+}
+
+// AllMetrics returns the descriptions of the metrics in Samples, sorted by
+// name
+func AllMetrics() []MetricDescription {
+	return append([]MetricDescription(nil), metricDescriptions...)
+}
+
+// Samples returns the snapshot as named samples, one per AllMetrics entry
+// in the same order, for agents that poll metrics by name
+func (s MetricsSnapshot) Samples() []MetricSample {
+	samples := make([]MetricSample, len(metricDescriptions))
+	for i, description := range metricDescriptions {
+		samples[i] = MetricSample{Name: description.Name, Value: description.value(s)}
+	}
+	return samples
+}
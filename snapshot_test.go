@@ -0,0 +1,112 @@
+package pim
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// failingWriter is a writer whose writes fail
+type failingWriter struct{}
+
+func (failingWriter) Write(entry CoreLogEntry) error { return errors.New("disk full") }
+func (failingWriter) Flush() error                   { return nil }
+func (failingWriter) Close() error                   { return nil }
+
+func TestLoggerSnapshot(t *testing.T) {
+	config := DefaultLoggerConfig
+	config.EnableConsole = false
+	config.ServiceName = "snapshot-test"
+	config.Level = TraceLevel
+	config.SamplingByLevel = map[LogLevel]SamplingConfig{TraceLevel: {EnableSampling: true, Rate: 10}}
+	logger := NewLoggerCore(config)
+	defer logger.Close()
+	logger.AddWriter(NewBufferWriter(config, 10))
+
+	logger.Info("one")
+	logger.Named("child").Error("two") // Counters are shared with children
+	logger.Debug("three")
+
+	logger.AddEnhancedHook(NewFilterHook(FilterConfig{
+		HookConfig: HookConfig{Name: "drop", Type: HookTypeFilter, Enabled: true},
+		CustomFunc: func(entry CoreLogEntry) bool { return entry.Message == "secret" },
+	}))
+	logger.Info("secret")
+	for i := 0; i < 5; i++ {
+		logger.Trace("sampled")
+	}
+
+	logger.AddWriter(failingWriter{})
+	logger.Warning("four")
+
+	snapshot := logger.Snapshot()
+	want := LevelCounts{Error: 1, Warning: 1, Info: 1, Debug: 1}
+	if snapshot.Entries != want || snapshot.Entries.Total() != 4 {
+		t.Errorf("Expected entries %+v, got %+v", want, snapshot.Entries)
+	}
+	if snapshot.Filtered != 1 || snapshot.SampledOut != 5 || snapshot.WriteErrors != 1 {
+		t.Errorf("Unexpected drop counters: %+v", snapshot)
+	}
+	if snapshot.Service != "snapshot-test" || snapshot.Level != "trace" || snapshot.Writers != 2 || snapshot.Hooks != 1 {
+		t.Errorf("Unexpected gauges: %+v", snapshot)
+	}
+	if snapshot.PersistentQueue != nil {
+		t.Error("Expected no persistent queue stats")
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"entries":{"panic":0,"error":1,"warning":1,"info":1,"debug":1,"trace":0}`) {
+		t.Errorf("Unexpected JSON: %s", data)
+	}
+}
+
+func TestSnapshotAllLoggers(t *testing.T) {
+	config := DefaultLoggerConfig
+	config.EnableConsole = false
+	config.ServiceName = "snapshot-all"
+	logger := NewLoggerCore(config)
+
+	found := func() bool {
+		for _, snapshot := range Snapshot() {
+			if snapshot.Service == "snapshot-all" {
+				return true
+			}
+		}
+		return false
+	}
+	if !found() {
+		t.Error("Expected the logger in the snapshots")
+	}
+	logger.Close()
+	if found() {
+		t.Error("Expected closed loggers to be left out")
+	}
+}
+
+func TestMetricsSnapshotSamples(t *testing.T) {
+	snapshot := MetricsSnapshot{Entries: LevelCounts{Error: 2, Info: 3}, AsyncDropped: 7, Writers: 1}
+	samples := snapshot.Samples()
+	descriptions := AllMetrics()
+	if len(samples) != len(descriptions) {
+		t.Fatalf("Expected one sample per metric, got %d and %d", len(samples), len(descriptions))
+	}
+
+	values := make(map[string]uint64)
+	for i, sample := range samples {
+		if sample.Name != descriptions[i].Name {
+			t.Errorf("Expected sample %d to be %s, got %s", i, descriptions[i].Name, sample.Name)
+		}
+		if i > 0 && samples[i-1].Name >= sample.Name {
+			t.Errorf("Expected names sorted, got %s before %s", samples[i-1].Name, sample.Name)
+		}
+		values[sample.Name] = sample.Value
+	}
+	if values["/pim/entries/total:entries"] != 5 || values["/pim/entries/error:entries"] != 2 ||
+		values["/pim/async/dropped:entries"] != 7 || values["/pim/writers:writers"] != 1 {
+		t.Errorf("Unexpected samples: %v", values)
+	}
+}
@@ -0,0 +1,121 @@
+package pim
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// StackTraceConfig controls stack trace capture
+type StackTraceConfig struct {
+	IncludeSource      bool `json:"include_source"`       // Attach source code around each frame
+	SourceContextLines int  `json:"source_context_lines"` // Lines before and after the frame line (default: 3)
+}
+
+// SourceLine is one line of source code around a stack frame
+type SourceLine struct {
+	Line    int    `json:"line"`
+	Code    string `json:"code"`
+	Current bool   `json:"current,omitempty"` // The line the frame is executing
+}
+
+// maxCachedSourceFiles bounds the number of source files kept in memory
+const maxCachedSourceFiles = 64
+
+// sourceCache caches source files read for stack trace snippets. Files that
+// cannot be read (e.g. binaries deployed without sources) are cached as nil.
+var sourceCache = struct {
+	sync.Mutex
+	files map[string][]string
+	order []string
+}{files: make(map[string][]string)}
+
+// sourceLines returns the lines of a source file, reading it at most once
+func sourceLines(path string) []string {
+	sourceCache.Lock()
+	defer sourceCache.Unlock()
+
+	if lines, ok := sourceCache.files[path]; ok {
+		return lines
+	}
+
+	var lines []string
+	if file, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		file.Close()
+		if scanner.Err() != nil {
+			lines = nil
+		}
+	}
+
+	// Evict the oldest file once the cache is full
+	if len(sourceCache.order) >= maxCachedSourceFiles {
+		delete(sourceCache.files, sourceCache.order[0])
+		sourceCache.order = sourceCache.order[1:]
+	}
+	sourceCache.files[path] = lines
+	sourceCache.order = append(sourceCache.order, path)
+	return lines
+}
+
+// sourceSnippet returns the lines around line in path, or nil if the
+// source is not available
+func sourceSnippet(path string, line, context int) []SourceLine {
+	lines := sourceLines(path)
+	if line < 1 || line > len(lines) {
+		return nil
+	}
+
+	first, last := line-context, line+context
+	if first < 1 {
+		first = 1
+	}
+	if last > len(lines) {
+		last = len(lines)
+	}
+
+	snippet := make([]SourceLine, 0, last-first+1)
+	for n := first; n <= last; n++ {
+		snippet = append(snippet, SourceLine{Line: n, Code: lines[n-1], Current: n == line})
+	}
+	return snippet
+}
+
+// attachSource adds source snippets to frames if enabled in the config.
+// fullPaths holds the absolute file path of each frame.
+func (c StackTraceConfig) attachSource(frames []StackFrame, fullPaths []string) {
+	if !c.IncludeSource {
+		return
+	}
+	context := c.SourceContextLines
+	if context <= 0 {
+		context = 3
+	}
+	for i := range frames {
+		frames[i].Source = sourceSnippet(fullPaths[i], frames[i].Line, context)
+	}
+}
+
+// formatSource renders a source snippet for text output, marking the
+// current line with ">"
+func formatSource(source []SourceLine, indent string) []string {
+	if len(source) == 0 {
+		return nil
+	}
+
+	width := len(fmt.Sprint(source[len(source)-1].Line))
+	lines := make([]string, 0, len(source))
+	for _, line := range source {
+		marker := " "
+		if line.Current {
+			marker = ">"
+		}
+		lines = append(lines, fmt.Sprintf("%s%s %*d | %s", indent, marker, width, line.Line, strings.TrimRight(line.Code, " \t")))
+	}
+	return lines
+}
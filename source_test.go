@@ -0,0 +1,62 @@
+package pim
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStackTraceIncludesSource(t *testing.T) {
+	logger := NewLoggerCore(LoggerConfig{
+		Level:      InfoLevel,
+		StackDepth: 3,
+		StackTrace: StackTraceConfig{IncludeSource: true, SourceContextLines: 1},
+	})
+	logger.callerFormatter = nil // Use the legacy capture, which keeps test frames
+	buffer := NewBufferWriter(LoggerConfig{}, 10)
+	logger.AddWriter(buffer)
+
+	logErrorFromHelper(logger) // source marker
+
+	var found *StackFrame
+	for i, frame := range buffer.GetBuffer()[0].StackTrace {
+		if strings.HasSuffix(frame.File, "source_test.go") {
+			found = &buffer.GetBuffer()[0].StackTrace[i]
+			break
+		}
+	}
+	if found == nil {
+		t.Fatalf("Expected a frame in this file, got %+v", buffer.GetBuffer()[0].StackTrace)
+	}
+	if len(found.Source) != 3 {
+		t.Fatalf("Expected 1 line of context around the frame, got %+v", found.Source)
+	}
+	if !found.Source[1].Current || !strings.Contains(found.Source[1].Code, "// source marker") {
+		t.Errorf("Expected the current line to be the logging call, got %+v", found.Source[1])
+	}
+}
+
+// logErrorFromHelper logs an error one call below the test
+func logErrorFromHelper(logger *LoggerCore) {
+	logger.Error("boom")
+}
+
+func TestSourceSnippetAndFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	os.WriteFile(path, []byte("package main\n\nfunc main() {\n\tpanic(\"x\")\n}\n"), 0644)
+
+	snippet := sourceSnippet(path, 4, 2)
+	if len(snippet) != 4 || snippet[0].Line != 2 || snippet[3].Line != 5 {
+		t.Fatalf("Expected lines 2-5 clamped to the file, got %+v", snippet)
+	}
+
+	lines := formatSource(snippet, "  ")
+	if lines[2] != "  > 4 | \tpanic(\"x\")" || lines[3] != "    5 | }" {
+		t.Errorf("Unexpected rendering: %q", lines)
+	}
+
+	if sourceSnippet(filepath.Join(t.TempDir(), "missing.go"), 1, 2) != nil {
+		t.Error("Expected no snippet for a missing file")
+	}
+}
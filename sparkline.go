@@ -0,0 +1,196 @@
+package pim
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// sparkBars are the bars of sparklines, from the lowest value to the highest
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// SparklineWidth is the number of bars of a sparkline. Longer series are
+// averaged down to this width.
+var SparklineWidth = 60
+
+// RenderSparkline renders values as a line of bars scaled between their
+// minimum and maximum, e.g. "▁▂▃▅▇" for an increasing series. Series longer
+// than SparklineWidth are averaged down to its width; NaN and infinite
+// values are shown as spaces.
+func RenderSparkline(values []float64) string {
+	var b strings.Builder
+	for _, bar := range sparkLevels(values) {
+		if bar < 0 {
+			b.WriteByte(' ')
+		} else {
+			b.WriteRune(sparkBars[bar])
+		}
+	}
+	return b.String()
+}
+
+// sparkLevels returns the bar index of each column of the sparkline of
+// values, or -1 for columns without finite values
+func sparkLevels(values []float64) []int {
+	columns := sparkColumns(values, SparklineWidth)
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, v := range columns {
+		if !math.IsNaN(v) {
+			low, high = math.Min(low, v), math.Max(high, v)
+		}
+	}
+
+	levels := make([]int, len(columns))
+	top := len(sparkBars) - 1
+	for i, v := range columns {
+		switch {
+		case math.IsNaN(v):
+			levels[i] = -1
+		case high == low:
+			levels[i] = top / 2
+		default:
+			levels[i] = int(math.Round((v - low) / (high - low) * float64(top)))
+		}
+	}
+	return levels
+}
+
+// sparkColumns averages values down to at most width columns, skipping
+// NaN and infinite values. Columns without finite values are NaN.
+func sparkColumns(values []float64, width int) []float64 {
+	if width <= 0 || len(values) <= width {
+		width = len(values)
+	}
+	columns := make([]float64, width)
+	for i := range columns {
+		start, end := i*len(values)/width, (i+1)*len(values)/width
+		sum, n := 0.0, 0
+		for _, v := range values[start:end] {
+			if !math.IsNaN(v) && !math.IsInf(v, 0) {
+				sum += v
+				n++
+			}
+		}
+		columns[i] = math.NaN()
+		if n > 0 {
+			columns[i] = sum / float64(n)
+		}
+	}
+	return columns
+}
+
+// colorSparkline renders the sparkline of values like RenderSparkline,
+// colored like a heat map: low bars in the theme's Success color, middle
+// bars in its Warning color and high bars in its Error color
+func colorSparkline(values []float64, theme *Theme) string {
+	var b strings.Builder
+	for _, bar := range sparkLevels(values) {
+		var c *color.Color
+		switch {
+		case bar < 0:
+			b.WriteByte(' ')
+			continue
+		case bar < 3:
+			c = theme.Colors.Success
+		case bar < 6:
+			c = theme.Colors.Warning
+		default:
+			c = theme.Colors.Error
+		}
+		b.WriteString(colorize(c, string(sparkBars[bar])))
+	}
+	return b.String()
+}
+
+// sparkStats returns the summary of values shown after a sparkline, e.g.
+// "min=1.5 max=12 last=3 n=20"
+func sparkStats(values []float64) string {
+	low, high, last, n := math.Inf(1), math.Inf(-1), math.NaN(), 0
+	for _, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		low, high, last = math.Min(low, v), math.Max(high, v), v
+		n++
+	}
+	if n == 0 {
+		return "n=" + strconv.Itoa(len(values))
+	}
+	format := func(v float64) string { return strconv.FormatFloat(v, 'g', 4, 64) }
+	return "min=" + format(low) + " max=" + format(high) + " last=" + format(last) + " n=" + strconv.Itoa(len(values))
+}
+
+// Series is the field value of a series of numbers logged with Sparkline.
+// Console layouts with TextSparklines leave it out of the context, since
+// the message shows it as a sparkline.
+type Series []float64
+
+// MarshalJSON implements json.Marshaler. JSON has no NaN or infinities, so
+// they are written as null.
+func (s Series) MarshalJSON() ([]byte, error) {
+	for _, v := range s {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			values := make([]interface{}, len(s))
+			for i, v := range s {
+				if !math.IsNaN(v) && !math.IsInf(v, 0) {
+					values[i] = v
+				}
+			}
+			return json.Marshal(values)
+		}
+	}
+	return json.Marshal([]float64(s))
+}
+
+// defaultSparklineTheme colors the sparklines of loggers without a theme
+var defaultSparklineTheme = NewThemeManager().GetTheme()
+
+// withColoredMessage records the colored rendering of the message of a log
+// call, see CoreLogEntry.coloredMessage
+func withColoredMessage(message string) CallOption {
+	return func(o *callOptions) {
+		o.coloredMessage = message
+	}
+}
+
+// Sparkline logs a series of numbers, such as latency samples, as an info
+// metric with a sparkline and a summary, e.g.
+//
+//	📊 METRIC    latency_ms ▁▁▂▁▃▅▇▃▂▁ min=1.2 max=48 last=3.1 n=10
+//
+// Console and stderr output color the bars with the logger's theme, like a
+// heat map. The raw series is added as a Series field named name for
+// machine sinks such as JSON files. Options such as ToWritersOnly apply as
+// usual.
+func (l *LoggerCore) Sparkline(name string, values []float64, opts ...CallOption) {
+	stats := sparkStats(values)
+	message := name + " " + stats
+	theme := defaultSparklineTheme
+	if l.themeManager != nil {
+		theme = l.themeManager.GetTheme()
+	}
+	colored := colorize(theme.Colors.Key, name) + " " + stats
+	if len(values) > 0 {
+		message = name + " " + RenderSparkline(values) + " " + stats
+		colored = colorize(theme.Colors.Key, name) + " " + colorSparkline(values, theme) + " " + stats
+	}
+
+	args := make([]interface{}, 0, len(opts)+2)
+	args = append(args, Fields(map[string]interface{}{name: append(Series(nil), values...)}), withColoredMessage(colored))
+	for _, opt := range opts {
+		args = append(args, opt)
+	}
+	l.log(1, InfoLevel, MetricPrefix, message, args)
+}
+
+// consoleMessage returns the colored rendering of the entry's message if
+// it has one and hooks haven't changed the message, or the message
+func (e *CoreLogEntry) consoleMessage() string {
+	if e.coloredMessage == "" || string(appendPlain(nil, []byte(e.coloredMessage))) != e.Message {
+		return e.Message
+	}
+	return e.coloredMessage
+}
@@ -0,0 +1,119 @@
+package pim
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+func TestRenderSparkline(t *testing.T) {
+	tests := []struct {
+		values   []float64
+		expected string
+	}{
+		{[]float64{1, 2, 3, 4, 5, 6, 7, 8}, "▁▂▃▄▅▆▇█"},
+		{[]float64{8, 1}, "█▁"},
+		{[]float64{5, 5, 5}, "▄▄▄"},
+		{[]float64{1, math.NaN(), 3}, "▁ █"},
+		{[]float64{1, math.Inf(1), 3}, "▁ █"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := RenderSparkline(tt.values); got != tt.expected {
+			t.Errorf("RenderSparkline(%v) = %q, expected %q", tt.values, got, tt.expected)
+		}
+	}
+
+	long := make([]float64, 1000)
+	for i := range long {
+		long[i] = float64(i)
+	}
+	line := RenderSparkline(long)
+	if n := utf8.RuneCountInString(line); n != SparklineWidth {
+		t.Errorf("expected %d bars for a long series, got %d", SparklineWidth, n)
+	}
+	if !strings.HasPrefix(line, "▁") || !strings.HasSuffix(line, "█") {
+		t.Errorf("expected the averaged series to keep its shape, got %q", line)
+	}
+}
+
+func TestSeriesMarshalJSON(t *testing.T) {
+	data, err := json.Marshal(Series{1.5, math.NaN(), 3})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(data) != "[1.5,null,3]" {
+		t.Errorf("unexpected JSON %s", data)
+	}
+}
+
+func TestLoggerSparkline(t *testing.T) {
+	originalNoColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = originalNoColor }()
+
+	config := DefaultLoggerConfig
+	config.EnableConsole = false
+	logger := NewLoggerCore(config)
+	defer logger.Close()
+	buffer := NewBufferWriter(config, 10)
+	logger.AddWriter(buffer)
+
+	samples := []float64{12, 15, 11, 48, 13}
+	logger.Sparkline("latency_ms", samples)
+	logger.Sparkline("empty", nil)
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	entry := entries[0]
+	if entry.Message != "latency_ms ▁▂▁█▁ min=11 max=48 last=13 n=5" {
+		t.Errorf("unexpected message %q", entry.Message)
+	}
+	if series, ok := entry.Context["latency_ms"].(Series); !ok || len(series) != len(samples) {
+		t.Errorf("expected the raw series as a field, got %#v", entry.Context["latency_ms"])
+	}
+	if entries[1].Message != "empty n=0" {
+		t.Errorf("unexpected message for an empty series %q", entries[1].Message)
+	}
+
+	// Console layouts color the bars and leave the series out of the context
+	console, _ := NewTextEncoder(config, TextSparklines).EncodeEntry(entry)
+	if !strings.Contains(string(console), "\033[") || strings.Contains(string(console), "{") {
+		t.Errorf("expected a colored sparkline without the series, got %q", console)
+	}
+	if string(appendPlain(nil, console)) != entry.Message {
+		t.Errorf("expected the colors to wrap the message, got %q", console)
+	}
+
+	// Other layouts write the plain message and the series
+	file, _ := NewTextEncoder(config, TextLevel).EncodeEntry(entry)
+	if strings.Contains(string(file), "\033[") || !strings.Contains(string(file), "latency_ms=[12 15 11 48 13]") {
+		t.Errorf("expected the plain message and the series, got %q", file)
+	}
+
+	// Messages changed by hooks are written as they are
+	entry.Message = "redacted"
+	console, _ = NewTextEncoder(config, TextSparklines).EncodeEntry(entry)
+	if !bytes.HasPrefix(console, []byte("redacted")) {
+		t.Errorf("expected the changed message, got %q", console)
+	}
+}
+
+func TestLoggerSparklineOnDerivedLogger(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+
+	logger.WithField("host", "a").Sparkline("latency_ms", []float64{1, 2})
+	logger.With(String("host", "b")).Sparkline("latency_ms", []float64{2, 1})
+	logger.WithGroup("db").Sparkline("latency_ms", []float64{3})
+
+	if size := buffer.GetBufferSize(); size != 3 {
+		t.Errorf("expected 3 entries from derived loggers, got %d", size)
+	}
+}
@@ -0,0 +1,170 @@
+package pim
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// stateVersion is the version of the state file format
+const stateVersion = 1
+
+// LoggerState is the sampler and metrics state persisted across restarts
+type LoggerState struct {
+	Version      int                     `json:"version"`
+	SavedAt      time.Time               `json:"saved_at"`
+	RateCounters map[LogLevel]int        `json:"rate_counters,omitempty"`
+	Metrics      map[string]MetricsState `json:"metrics,omitempty"` // Keyed by MetricsHook name
+}
+
+// MetricsState is the persisted state of one MetricsHook
+type MetricsState struct {
+	Counters map[string]int           `json:"counters,omitempty"`
+	Timers   map[string]time.Duration `json:"timers,omitempty"`
+}
+
+// State returns the current rate-sampling counters and metrics hook values
+func (l *LoggerCore) State() LoggerState {
+	state := LoggerState{
+		Version:      stateVersion,
+		SavedAt:      time.Now().UTC(),
+		RateCounters: make(map[LogLevel]int),
+		Metrics:      make(map[string]MetricsState),
+	}
+
+	l.mu.RLock()
+	for level, count := range l.rateCounters {
+		state.RateCounters[level] = count
+	}
+	// Keep restored values of hooks that were not registered this run
+	for name, metrics := range l.pendingMetrics {
+		state.Metrics[name] = metrics
+	}
+	l.mu.RUnlock()
+
+	if l.hookManager != nil {
+		for _, hook := range l.hookManager.GetHooksByType(HookTypeMetrics) {
+			if metricsHook, ok := hook.(*MetricsHook); ok {
+				state.Metrics[metricsHook.GetConfig().Name] = metricsHook.state()
+			}
+		}
+	}
+	return state
+}
+
+// SaveState writes the logger state to path. The file is replaced
+// atomically so a crash never leaves a truncated state file.
+func (l *LoggerCore) SaveState(path string) error {
+	data, err := json.MarshalIndent(l.State(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal logger state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write logger state: %w", err)
+	}
+	if err := os.Rename(temp, path); err != nil {
+		os.Remove(temp)
+		return fmt.Errorf("failed to write logger state: %w", err)
+	}
+	return nil
+}
+
+// LoadState restores state saved by SaveState. Rate counters are restored
+// immediately; metrics are added to MetricsHooks with the same name now or
+// when they are added later. A missing file is not an error.
+func (l *LoggerCore) LoadState(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read logger state: %w", err)
+	}
+
+	var state LoggerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to parse logger state: %w", err)
+	}
+	if state.Version != stateVersion {
+		return fmt.Errorf("unsupported logger state version %d", state.Version)
+	}
+
+	l.mu.Lock()
+	if l.rateCounters == nil {
+		l.rateCounters = make(map[LogLevel]int)
+	}
+	for level, count := range state.RateCounters {
+		l.rateCounters[level] += count
+	}
+	if l.pendingMetrics == nil {
+		l.pendingMetrics = make(map[string]MetricsState)
+	}
+	for name, metrics := range state.Metrics {
+		l.pendingMetrics[name] = metrics
+	}
+	l.mu.Unlock()
+
+	// Hooks that are already registered get their values now
+	if l.hookManager != nil {
+		for _, hook := range l.hookManager.GetHooksByType(HookTypeMetrics) {
+			l.restoreHookState(hook)
+		}
+	}
+	return nil
+}
+
+// restoreHookState applies pending restored metrics to a newly added hook
+func (l *LoggerCore) restoreHookState(hook EnhancedLogHook) {
+	metricsHook, ok := hook.(*MetricsHook)
+	if !ok {
+		return
+	}
+
+	name := metricsHook.GetConfig().Name
+	l.mu.Lock()
+	metrics, exists := l.pendingMetrics[name]
+	delete(l.pendingMetrics, name)
+	l.mu.Unlock()
+
+	if exists {
+		metricsHook.RestoreMetrics(metrics.Counters, metrics.Timers)
+	}
+}
+
+// state returns a copy of the hook's counters and timers
+func (m *MetricsHook) state() MetricsState {
+	m.config.mu.RLock()
+	defer m.config.mu.RUnlock()
+
+	state := MetricsState{
+		Counters: make(map[string]int, len(m.config.Counters)),
+		Timers:   make(map[string]time.Duration, len(m.config.Timers)),
+	}
+	for k, v := range m.config.Counters {
+		state.Counters[k] = v
+	}
+	for k, v := range m.config.Timers {
+		state.Timers[k] = v
+	}
+	return state
+}
+
+// RestoreMetrics adds previously saved counters and timers to the hook's values
+func (m *MetricsHook) RestoreMetrics(counters map[string]int, timers map[string]time.Duration) {
+	m.config.mu.Lock()
+	defer m.config.mu.Unlock()
+
+	for k, v := range counters {
+		m.config.Counters[k] += v
+	}
+	for k, v := range timers {
+		m.config.Timers[k] += v
+	}
+}
@@ -0,0 +1,84 @@
+package pim
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newCountingMetricsHook returns a metrics hook named "requests"
+func newCountingMetricsHook() *MetricsHook {
+	return NewMetricsHook(MetricsConfig{
+		HookConfig: HookConfig{Type: HookTypeMetrics, Name: "requests", Enabled: true},
+	})
+}
+
+func TestStatePersistsAcrossRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "pim.json")
+	config := LoggerConfig{
+		Level:           InfoLevel,
+		StateFile:       path,
+		SamplingByLevel: map[LogLevel]SamplingConfig{InfoLevel: {EnableSampling: true, Rate: 3}},
+	}
+
+	// First run: two entries, neither sampled in yet
+	logger := NewLoggerCore(config)
+	buffer := NewBufferWriter(LoggerConfig{}, 10)
+	logger.AddWriter(buffer)
+	hook := newCountingMetricsHook()
+	logger.AddEnhancedHook(hook)
+	logger.Info("one")
+	logger.Info("two")
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close logger: %v", err)
+	}
+	if len(buffer.GetBuffer()) != 0 {
+		t.Fatalf("Expected the first two entries to be sampled out, got %d", len(buffer.GetBuffer()))
+	}
+
+	// Second run: the counter continues, so the first entry is the third overall
+	logger = NewLoggerCore(config)
+	buffer = NewBufferWriter(LoggerConfig{}, 10)
+	logger.AddWriter(buffer)
+	hook = newCountingMetricsHook()
+	logger.AddEnhancedHook(hook)
+	logger.Info("three")
+
+	if len(buffer.GetBuffer()) != 1 {
+		t.Errorf("Expected the restored rate counter to sample the third entry, got %d entries", len(buffer.GetBuffer()))
+	}
+	counters := hook.GetMetrics()["counters"].(map[string]int)
+	if counters["total"] != 1 {
+		t.Errorf("Expected metrics of sampled entries only, got %v", counters)
+	}
+}
+
+func TestStateRestoresMetricsHooks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pim.json")
+
+	logger := NewLoggerCore(LoggerConfig{Level: InfoLevel})
+	hook := newCountingMetricsHook()
+	logger.AddEnhancedHook(hook)
+	logger.Info("a")
+	logger.Info("b")
+	if err := logger.SaveState(path); err != nil {
+		t.Fatalf("Failed to save state: %v", err)
+	}
+
+	restored := NewLoggerCore(LoggerConfig{Level: InfoLevel, StateFile: path})
+	restoredHook := newCountingMetricsHook()
+	restored.AddEnhancedHook(restoredHook)
+	restored.Info("c")
+
+	if got := restoredHook.GetMetrics()["counters"].(map[string]int)["total"]; got != 3 {
+		t.Errorf("Expected restored counters to continue at 3, got %d", got)
+	}
+
+	os.WriteFile(path, []byte(`{"version": 99}`), 0644)
+	if err := restored.LoadState(path); err == nil {
+		t.Error("Expected an unsupported state version to be rejected")
+	}
+	if err := restored.LoadState(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Errorf("Expected a missing state file to be ignored, got %v", err)
+	}
+}
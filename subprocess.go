@@ -0,0 +1,146 @@
+package pim
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// CommandExitedMessage is the message of the entry logged when a command
+// run through LogCommand exits
+const CommandExitedMessage = "command exited"
+
+// CommandConfig configures how the output of a subprocess is logged
+type CommandConfig struct {
+	Name        string                 // Defaults to the base name of cmd.Path
+	StdoutLevel LogLevel               // Level of lines written to stdout
+	StderrLevel LogLevel               // Level of lines written to stderr
+	Fields      map[string]interface{} // Added to every entry of the command
+}
+
+// DefaultCommandConfig logs stdout at Info and stderr at Warning
+var DefaultCommandConfig = CommandConfig{
+	StdoutLevel: InfoLevel,
+	StderrLevel: WarningLevel,
+}
+
+// Command is an exec.Cmd whose output is logged line by line, see
+// LogCommand
+type Command struct {
+	Cmd *exec.Cmd
+
+	logger  *LoggerCore
+	config  CommandConfig
+	stdout  io.ReadCloser
+	stderr  io.ReadCloser
+	copying sync.WaitGroup
+	start   time.Time
+}
+
+// LogCommand prepares cmd so its stdout and stderr are logged through
+// logger: each line becomes an entry at the stream's level, prefixed with
+// the command name and PID and carrying the fields command, pid and stream.
+// When the command exits, Wait logs a CommandExitedMessage entry with its
+// exit code, duration and error, at Info on success and Error otherwise.
+// cmd.Stdout and cmd.Stderr must not be set.
+//
+//	cmd := pim.LogCommand(logger, exec.Command("git", "fetch"), pim.DefaultCommandConfig)
+//	err := cmd.Run()
+func LogCommand(logger *LoggerCore, cmd *exec.Cmd, config CommandConfig) *Command {
+	if config.Name == "" {
+		config.Name = filepath.Base(cmd.Path)
+	}
+	return &Command{Cmd: cmd, logger: logger, config: config}
+}
+
+// Start starts the command and the goroutines logging its output
+func (c *Command) Start() error {
+	var err error
+	if c.stdout, err = c.Cmd.StdoutPipe(); err != nil {
+		return err
+	}
+	if c.stderr, err = c.Cmd.StderrPipe(); err != nil {
+		return err
+	}
+	c.start = time.Now()
+	if err := c.Cmd.Start(); err != nil {
+		c.logExit(err)
+		return err
+	}
+
+	pid := c.Cmd.Process.Pid
+	c.copying.Add(2)
+	go c.copy(c.stdout, "stdout", c.config.StdoutLevel, pid)
+	go c.copy(c.stderr, "stderr", c.config.StderrLevel, pid)
+	return nil
+}
+
+// copy logs the lines of one output stream until it is closed
+func (c *Command) copy(r io.Reader, stream string, level LogLevel, pid int) {
+	defer c.copying.Done()
+
+	fields := make(map[string]interface{}, len(c.config.Fields)+3)
+	for k, v := range c.config.Fields {
+		fields[k] = v
+	}
+	fields["command"] = c.config.Name
+	fields["pid"] = pid
+	fields["stream"] = stream
+
+	writer := NewLineWriter(c.logger, level, fields)
+	writer.prefix += fmt.Sprintf("%s[%d] ", c.config.Name, pid)
+	io.Copy(writer, r)
+	writer.Close()
+}
+
+// Wait waits for the output to be logged and the command to exit, then
+// logs its exit status
+func (c *Command) Wait() error {
+	c.copying.Wait() // Wait closes the pipes, so read them to the end first
+	err := c.Cmd.Wait()
+	c.logExit(err)
+	return err
+}
+
+// Run starts the command and waits for it
+func (c *Command) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+// logExit logs the CommandExitedMessage entry
+func (c *Command) logExit(err error) {
+	fields := make(map[string]interface{}, len(c.config.Fields)+6)
+	for k, v := range c.config.Fields {
+		fields[k] = v
+	}
+	fields["command"] = c.config.Name
+	fields["duration_ms"] = float64(time.Since(c.start).Microseconds()) / 1000
+
+	exitCode := -1
+	if state := c.Cmd.ProcessState; state != nil {
+		fields["pid"] = state.Pid()
+		exitCode = state.ExitCode()
+		if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			fields["signal"] = status.Signal().String()
+		}
+	}
+	fields["exit_code"] = exitCode
+
+	level := InfoLevel
+	if err != nil {
+		level = ErrorLevel
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			fields["error"] = err
+		}
+	}
+	c.logger.LogWithContext(level, getPrefixForLevel(level), CommandExitedMessage, fields)
+}
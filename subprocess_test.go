@@ -0,0 +1,61 @@
+package pim
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestLogCommand(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	logger, buffer := newTestLogger(t, nil)
+
+	cmdConfig := DefaultCommandConfig
+	cmdConfig.Fields = map[string]interface{}{"job": "build"}
+	cmd := LogCommand(logger, exec.Command("sh", "-c", "echo out; echo err >&2; exit 3"), cmdConfig)
+	err := cmd.Run()
+	if err == nil {
+		t.Fatal("Expected the exit status as error")
+	}
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+	pid := cmd.Cmd.ProcessState.Pid()
+	levels := map[string]LogLevel{"out": InfoLevel, "err": WarningLevel}
+	for _, entry := range entries[:2] {
+		if entry.Level != levels[entry.Message] {
+			t.Errorf("Unexpected level %v for %q", entry.Level, entry.Message)
+		}
+		if entry.Context["command"] != "sh" || entry.Context["pid"] != pid || entry.Context["job"] != "build" {
+			t.Errorf("Unexpected fields: %v", entry.Context)
+		}
+		if !strings.Contains(entry.Prefix, "sh[") {
+			t.Errorf("Expected the command name in the prefix, got %q", entry.Prefix)
+		}
+	}
+
+	exit := entries[2]
+	if exit.Message != CommandExitedMessage || exit.Level != ErrorLevel {
+		t.Errorf("Unexpected exit entry: %+v", exit)
+	}
+	if exit.Context["exit_code"] != 3 || exit.Context["pid"] != pid {
+		t.Errorf("Unexpected exit fields: %v", exit.Context)
+	}
+}
+
+func TestLogCommandStartFailure(t *testing.T) {
+	logger, buffer := newTestLogger(t, nil)
+
+	cmd := LogCommand(logger, exec.Command("/nonexistent/tool"), DefaultCommandConfig)
+	if err := cmd.Run(); err == nil {
+		t.Fatal("Expected a start error")
+	}
+	entries := buffer.GetBuffer()
+	if len(entries) != 1 || entries[0].Context["exit_code"] != -1 || entries[0].Context["error"] == nil {
+		t.Errorf("Expected one exit entry with the start error, got %+v", entries)
+	}
+}
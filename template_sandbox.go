@@ -0,0 +1,180 @@
+package pim
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+)
+
+// ErrTemplateTimeout is returned when a template does not finish within the sandbox timeout
+var ErrTemplateTimeout = errors.New("template execution timed out")
+
+// errTemplateOutputLimit aborts template execution once the output cap is reached
+var errTemplateOutputLimit = errors.New("template output limit reached")
+
+// TemplateSandboxConfig limits what format templates can do
+type TemplateSandboxConfig struct {
+	Timeout         time.Duration    `json:"timeout"`           // Maximum execution time per entry (default: 100ms)
+	MaxOutputLength int              `json:"max_output_length"` // Output is truncated beyond this many bytes (default: 16 KiB)
+	Funcs           template.FuncMap `json:"-"`                 // Additional functions made available to templates
+}
+
+// DefaultTemplateSandboxConfig provides sensible defaults
+var DefaultTemplateSandboxConfig = TemplateSandboxConfig{
+	Timeout:         100 * time.Millisecond,
+	MaxOutputLength: 16 << 10,
+}
+
+// templateTruncatedSuffix marks output cut off at MaxOutputLength
+const templateTruncatedSuffix = "…[truncated]"
+
+// SandboxFuncs returns the functions available to format templates. The
+// builtin "call" is disabled so templates cannot invoke function values.
+func SandboxFuncs() template.FuncMap {
+	return template.FuncMap{
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"trim":  strings.TrimSpace,
+		"truncate": func(n int, s string) string {
+			if n >= 0 && len(s) > n {
+				return s[:n]
+			}
+			return s
+		},
+		"default": func(def, value interface{}) interface{} {
+			if value == nil || value == "" {
+				return def
+			}
+			return value
+		},
+		"call": func(interface{}, ...interface{}) (interface{}, error) {
+			return nil, errors.New("call is not allowed in log templates")
+		},
+	}
+}
+
+// withDefaults fills unset limits from DefaultTemplateSandboxConfig
+func (c TemplateSandboxConfig) withDefaults() TemplateSandboxConfig {
+	if c.Timeout == 0 {
+		c.Timeout = DefaultTemplateSandboxConfig.Timeout
+	}
+	if c.MaxOutputLength == 0 {
+		c.MaxOutputLength = DefaultTemplateSandboxConfig.MaxOutputLength
+	}
+	return c
+}
+
+// funcs returns the sandbox function set including configured extras
+func (c TemplateSandboxConfig) funcs() template.FuncMap {
+	funcs := SandboxFuncs()
+	for name, fn := range c.Funcs {
+		if name != "call" {
+			funcs[name] = fn
+		}
+	}
+	return funcs
+}
+
+// cappedBuilder collects template output up to a limit and stops accepting
+// writes once the limit is hit or the execution was abandoned
+type cappedBuilder struct {
+	buf       strings.Builder
+	limit     int
+	truncated bool
+	abandoned atomic.Bool
+}
+
+// Write implements io.Writer
+func (b *cappedBuilder) Write(p []byte) (int, error) {
+	if b.abandoned.Load() {
+		return 0, ErrTemplateTimeout
+	}
+	if remaining := b.limit - b.buf.Len(); len(p) > remaining {
+		b.buf.Write(p[:remaining])
+		b.truncated = true
+		return remaining, errTemplateOutputLimit
+	}
+	return b.buf.Write(p)
+}
+
+// executeSandboxed runs tmpl with the sandbox limits applied. A template that
+// times out keeps running in the background until its next write, where it
+// is aborted.
+func executeSandboxed(tmpl *template.Template, data interface{}, config TemplateSandboxConfig) (string, error) {
+	out := &cappedBuilder{limit: config.MaxOutputLength}
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("template panicked: %v", r)
+			}
+		}()
+		done <- tmpl.Execute(out, data)
+	}()
+
+	timer := time.NewTimer(config.Timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		if out.truncated {
+			return out.buf.String() + templateTruncatedSuffix, nil
+		}
+		return out.buf.String(), err
+	case <-timer.C:
+		out.abandoned.Store(true)
+		return "", ErrTemplateTimeout
+	}
+}
+
+// sandboxValue converts context values to plain data so templates cannot
+// call methods on arbitrary application types
+func sandboxValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, time.Time, time.Duration:
+		return v
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = sandboxValue(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = sandboxValue(item)
+		}
+		return result
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			result := make(map[string]interface{}, rv.Len())
+			iter := rv.MapRange()
+			for iter.Next() {
+				result[iter.Key().String()] = sandboxValue(iter.Value().Interface())
+			}
+			return result
+		}
+	case reflect.Slice, reflect.Array:
+		result := make([]interface{}, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			result[i] = sandboxValue(rv.Index(i).Interface())
+		}
+		return result
+	}
+	return fmt.Sprint(value)
+}
+
+// SetTemplateSandbox sets the limits applied when executing format templates.
+// Templates registered afterwards use the configured function set.
+func (tm *ThemeManager) SetTemplateSandbox(config TemplateSandboxConfig) {
+	tm.sandbox = config.withDefaults()
+}
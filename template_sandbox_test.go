@@ -0,0 +1,75 @@
+package pim
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type sideEffect struct{ calls *int }
+
+func (s sideEffect) Trigger() string {
+	*s.calls++
+	return "triggered"
+}
+
+func TestTemplateSandboxFuncsAndContext(t *testing.T) {
+	tm := NewThemeManager()
+	tm.SetTemplateErrorHandler(nil)
+	if err := tm.RegisterTemplate("upper", `{{upper .Message}} {{default "n/a" .Service}}`); err != nil {
+		t.Fatalf("Failed to register template: %v", err)
+	}
+	if got := tm.Format(CoreLogEntry{Message: "hello"}, "upper"); got != "HELLO n/a" {
+		t.Errorf("Unexpected output: %q", got)
+	}
+
+	calls := 0
+	tm.RegisterTemplate("method", `{{.Context.obj.Trigger}}`)
+	out := tm.Format(CoreLogEntry{Context: map[string]interface{}{"obj": sideEffect{calls: &calls}}}, "method")
+	if calls != 0 || strings.Contains(out, "triggered") {
+		t.Errorf("Expected methods on context values to be unavailable, got %q (%d calls)", out, calls)
+	}
+
+	tm.RegisterTemplate("call", `{{call .Context.fn}}`)
+	out = tm.Format(CoreLogEntry{Context: map[string]interface{}{"fn": func() string { return "ran" }}}, "call")
+	if strings.Contains(out, "ran") {
+		t.Errorf("Expected call to be disabled, got %q", out)
+	}
+}
+
+func TestTemplateSandboxLimits(t *testing.T) {
+	tm := NewThemeManager()
+	tm.SetTemplateSandbox(TemplateSandboxConfig{Timeout: 20 * time.Millisecond, MaxOutputLength: 10})
+	var failure error
+	tm.SetTemplateErrorHandler(func(name string, err error) { failure = err })
+
+	tm.RegisterTemplate("long", `{{.Message}}{{.Message}}`)
+	out := tm.Format(CoreLogEntry{Message: "abcdefgh"}, "long")
+	if out != "abcdefghab"+templateTruncatedSuffix {
+		t.Errorf("Expected output to be capped, got %q", out)
+	}
+
+	// A template that blocks must not block the caller
+	tm.SetTemplateSandbox(TemplateSandboxConfig{
+		Timeout: 20 * time.Millisecond,
+		Funcs: map[string]interface{}{
+			"slow": func() string {
+				time.Sleep(200 * time.Millisecond)
+				return "done"
+			},
+		},
+	})
+	tm.RegisterTemplate("slow", `{{slow}}`)
+	start := time.Now()
+	out = tm.Format(CoreLogEntry{Message: "still logged"}, "slow")
+	if time.Since(start) > 100*time.Millisecond {
+		t.Errorf("Expected template to time out quickly, took %v", time.Since(start))
+	}
+	if !strings.Contains(out, "still logged") {
+		t.Errorf("Expected fallback output with the message, got %q", out)
+	}
+	if !errors.Is(failure, ErrTemplateTimeout) {
+		t.Errorf("Expected timeout to be reported, got: %v", failure)
+	}
+}
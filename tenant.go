@@ -0,0 +1,194 @@
+package pim
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// TenantIDKey is the context key holding the tenant of an entry
+const TenantIDKey = "tenant_id"
+
+// TenantPlaceholder is replaced by the tenant ID in TenantWriterConfig.PathTemplate
+const TenantPlaceholder = "{tenant}"
+
+// ErrMissingTenantID is returned when an entry without a tenant ID is rejected
+var ErrMissingTenantID = errors.New("log entry is missing tenant_id")
+
+// tenantIDPattern restricts tenant IDs so they are safe to use in file paths
+var tenantIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// TenantID returns the tenant ID of an entry, if any
+func TenantID(entry CoreLogEntry) (string, bool) {
+	if entry.Context == nil {
+		return "", false
+	}
+	tenant, ok := entry.Context[TenantIDKey].(string)
+	return tenant, ok && tenant != ""
+}
+
+// TenantWriterConfig configures tenant-scoped writers
+type TenantWriterConfig struct {
+	// PathTemplate is the log file path for each tenant, e.g.
+	// "/var/log/app/{tenant}/app.log". Ignored when Factory is set.
+	PathTemplate   string         `json:"path_template"`
+	LoggerConfig   LoggerConfig   `json:"logger_config"`
+	RotationConfig RotationConfig `json:"rotation_config"`
+
+	// Factory creates the writer for a tenant, for sinks other than files
+	Factory func(tenantID string) (LogWriter, error) `json:"-"`
+
+	// Fallback receives entries without a tenant ID (dropped if nil)
+	Fallback LogWriter `json:"-"`
+
+	// Strict rejects entries without a tenant ID with ErrMissingTenantID
+	Strict bool `json:"strict"`
+}
+
+// TenantWriter routes entries to a per-tenant writer selected by the
+// tenant_id context field, creating writers on first use
+type TenantWriter struct {
+	config  TenantWriterConfig
+	writers map[string]LogWriter
+	mu      sync.RWMutex
+}
+
+// NewTenantWriter creates a new tenant-routing writer
+func NewTenantWriter(config TenantWriterConfig) (*TenantWriter, error) {
+	if config.Factory == nil {
+		if !strings.Contains(config.PathTemplate, TenantPlaceholder) {
+			return nil, fmt.Errorf("tenant path template %q must contain %s", config.PathTemplate, TenantPlaceholder)
+		}
+		config.Factory = func(tenantID string) (LogWriter, error) {
+			path := strings.ReplaceAll(config.PathTemplate, TenantPlaceholder, tenantID)
+			return NewFileWriter(path, config.LoggerConfig, config.RotationConfig)
+		}
+	}
+
+	return &TenantWriter{
+		config:  config,
+		writers: make(map[string]LogWriter),
+	}, nil
+}
+
+// Write implements LogWriter interface
+func (w *TenantWriter) Write(entry CoreLogEntry) error {
+	tenant, ok := TenantID(entry)
+	if !ok {
+		if w.config.Strict {
+			return ErrMissingTenantID
+		}
+		if w.config.Fallback != nil {
+			return w.config.Fallback.Write(entry)
+		}
+		return nil
+	}
+
+	writer, err := w.writerFor(tenant)
+	if err != nil {
+		return err
+	}
+	return writer.Write(entry)
+}
+
+// writerFor returns the writer for a tenant, creating it if needed
+func (w *TenantWriter) writerFor(tenant string) (LogWriter, error) {
+	w.mu.RLock()
+	writer, exists := w.writers[tenant]
+	w.mu.RUnlock()
+	if exists {
+		return writer, nil
+	}
+
+	if !tenantIDPattern.MatchString(tenant) {
+		return nil, fmt.Errorf("invalid tenant ID %q", tenant)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if writer, exists := w.writers[tenant]; exists {
+		return writer, nil
+	}
+
+	writer, err := w.config.Factory(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create writer for tenant %s: %w", tenant, err)
+	}
+	w.writers[tenant] = writer
+	return writer, nil
+}
+
+// Tenants returns the IDs of tenants that have a writer
+func (w *TenantWriter) Tenants() []string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	tenants := make([]string, 0, len(w.writers))
+	for tenant := range w.writers {
+		tenants = append(tenants, tenant)
+	}
+	return tenants
+}
+
+// Close implements LogWriter interface
+func (w *TenantWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var errs []error
+	for _, writer := range w.writers {
+		if err := writer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	w.writers = make(map[string]LogWriter)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("errors closing tenant writers: %v", errs)
+	}
+	return nil
+}
+
+// Flush implements LogWriter interface
+func (w *TenantWriter) Flush() error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	var errs []error
+	for _, writer := range w.writers {
+		if err := writer.Flush(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("errors flushing tenant writers: %v", errs)
+	}
+	return nil
+}
+
+// WithTenant returns a new logger with the given tenant ID set in context
+func (l *LoggerCore) WithTenant(tenantID string) *LoggerCore {
+	return l.WithContext(map[string]interface{}{TenantIDKey: tenantID})
+}
+
+// RequireTenantID enables strict multi-tenant mode: entries without a
+// tenant_id are filtered before reaching any hook or writer
+func (l *LoggerCore) RequireTenantID() {
+	l.AddEnhancedHook(NewFilterHook(FilterConfig{
+		HookConfig: HookConfig{
+			Type:        HookTypeFilter,
+			Name:        "require_tenant_id",
+			Description: "Rejects entries without a tenant_id",
+			Enabled:     true,
+			Priority:    -1000,
+		},
+		CustomFunc: func(entry CoreLogEntry) bool {
+			_, ok := TenantID(entry)
+			return !ok
+		},
+	}))
+}
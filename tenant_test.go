@@ -0,0 +1,88 @@
+package pim
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTenantWriterRoutesToTenantFiles(t *testing.T) {
+	dir := t.TempDir()
+	fallback := NewBufferWriter(LoggerConfig{}, 10)
+	writer, err := NewTenantWriter(TenantWriterConfig{
+		PathTemplate: filepath.Join(dir, TenantPlaceholder, "app.log"),
+		Fallback:     fallback,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tenant writer: %v", err)
+	}
+	defer writer.Close()
+
+	writer.Write(CoreLogEntry{Level: InfoLevel, Message: "acme event", Context: map[string]interface{}{TenantIDKey: "acme"}})
+	writer.Write(CoreLogEntry{Level: InfoLevel, Message: "globex event", Context: map[string]interface{}{TenantIDKey: "globex"}})
+	writer.Write(CoreLogEntry{Level: InfoLevel, Message: "no tenant"})
+	writer.Flush()
+
+	acme, err := os.ReadFile(filepath.Join(dir, "acme", "app.log"))
+	if err != nil {
+		t.Fatalf("Expected acme log file: %v", err)
+	}
+	if !strings.Contains(string(acme), "acme event") || strings.Contains(string(acme), "globex event") {
+		t.Errorf("Expected acme file to contain only acme entries, got: %s", acme)
+	}
+	if len(writer.Tenants()) != 2 {
+		t.Errorf("Expected 2 tenant writers, got: %v", writer.Tenants())
+	}
+	if fallback.GetBufferSize() != 1 {
+		t.Errorf("Expected entry without tenant to go to fallback, got %d", fallback.GetBufferSize())
+	}
+
+	// Tenant IDs must not escape the directory template
+	err = writer.Write(CoreLogEntry{Message: "escape", Context: map[string]interface{}{TenantIDKey: "../other"}})
+	if err == nil {
+		t.Error("Expected invalid tenant ID to be rejected")
+	}
+}
+
+func TestTenantWriterStrictAndFactory(t *testing.T) {
+	sinks := make(map[string]*BufferWriter)
+	writer, err := NewTenantWriter(TenantWriterConfig{
+		Strict: true,
+		Factory: func(tenantID string) (LogWriter, error) {
+			sinks[tenantID] = NewBufferWriter(LoggerConfig{}, 10)
+			return sinks[tenantID], nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tenant writer: %v", err)
+	}
+
+	if err := writer.Write(CoreLogEntry{Message: "anonymous"}); !errors.Is(err, ErrMissingTenantID) {
+		t.Errorf("Expected ErrMissingTenantID in strict mode, got: %v", err)
+	}
+	writer.Write(CoreLogEntry{Message: "hello", Context: map[string]interface{}{TenantIDKey: "acme"}})
+	if sinks["acme"] == nil || sinks["acme"].GetBufferSize() != 1 {
+		t.Error("Expected entry to be written to the acme sink")
+	}
+
+	if _, err := NewTenantWriter(TenantWriterConfig{PathTemplate: "/var/log/app.log"}); err == nil {
+		t.Error("Expected path template without placeholder to be rejected")
+	}
+}
+
+func TestLoggerRequireTenantID(t *testing.T) {
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.Level = InfoLevel
+	})
+	logger.RequireTenantID()
+
+	logger.Info("missing tenant")
+	logger.WithTenant("acme").Info("tenant scoped")
+
+	entries := buffer.GetBuffer()
+	if len(entries) != 1 || entries[0].Message != "tenant scoped" {
+		t.Errorf("Expected only the tenant-scoped entry to be logged, got: %+v", entries)
+	}
+}
@@ -0,0 +1,239 @@
+package pim
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+)
+
+// ColorDepth is the number of colors an output supports
+type ColorDepth int
+
+const (
+	ColorDepthNone      ColorDepth = iota // No colors
+	ColorDepthBasic                       // The 16 ANSI colors
+	ColorDepth256                         // ANSI 256-color indexes
+	ColorDepthTrueColor                   // 24-bit RGB colors
+)
+
+// String returns the name of the color depth
+func (d ColorDepth) String() string {
+	switch d {
+	case ColorDepthNone:
+		return "none"
+	case ColorDepthBasic:
+		return "16"
+	case ColorDepth256:
+		return "256"
+	case ColorDepthTrueColor:
+		return "truecolor"
+	}
+	return fmt.Sprintf("ColorDepth(%d)", int(d))
+}
+
+// ConsoleMode selects how console and stderr writers render entries
+type ConsoleMode string
+
+// Console modes. The zero value renders entries as configured.
+const (
+	ConsoleModeAuto  ConsoleMode = "auto"  // Detected from the output, see Terminal.ConsoleMode
+	ConsoleModeColor ConsoleMode = "color" // As configured, with colors and emoji prefixes
+	ConsoleModePlain ConsoleMode = "plain" // Text without colors or emoji, [LEVEL] instead of prefixes
+	ConsoleModeJSON  ConsoleMode = "json"  // JSON lines without colors
+)
+
+// Validate reports whether m is a supported console mode
+func (m ConsoleMode) Validate() error {
+	switch m {
+	case "", ConsoleModeAuto, ConsoleModeColor, ConsoleModePlain, ConsoleModeJSON:
+		return nil
+	}
+	return fmt.Errorf("unknown console mode %q", string(m))
+}
+
+// Terminal describes the capabilities of an output, see DetectTerminal
+type Terminal struct {
+	IsTTY      bool       // The output is a terminal
+	ColorDepth ColorDepth // Colors to use, after NO_COLOR and FORCE_COLOR
+	CI         bool       // Running in CI, per the CI environment variable
+	Unicode    bool       // The output renders UTF-8 text such as emoji
+}
+
+// DetectTerminal detects the capabilities of out from whether it is a
+// terminal and the environment:
+//
+//   - FORCE_COLOR forces colors even when out is not a terminal: "1" or
+//     "true" for at least 16 colors, "2" for 256, "3" for truecolor, and
+//     "0" or "false" disables them. It takes precedence over NO_COLOR.
+//   - NO_COLOR, when not empty, disables colors (https://no-color.org).
+//   - TERM=dumb disables colors; COLORTERM=truecolor or 24bit, or a TERM
+//     ending in 256color, raise the depth.
+//
+// Unicode is false for Windows consoles without the UTF-8 code page (chcp
+// 65001) outside Windows Terminal, for locales (LC_ALL, LC_CTYPE, LANG)
+// that are not UTF-8, and for the Linux virtual console. Outputs that are
+// not *os.File, e.g. buffers, are not terminals.
+func DetectTerminal(out io.Writer) Terminal {
+	tty, utf8Console := false, true
+	if f, ok := out.(*os.File); ok && f != nil {
+		tty = isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+		utf8Console = consoleUTF8(f, tty)
+	}
+	return detectTerminal(tty, utf8Console, os.LookupEnv)
+}
+
+// detectTerminal detects the capabilities of an output from tty, whether
+// its console renders UTF-8 and the environment variables returned by
+// lookup
+func detectTerminal(tty, utf8Console bool, lookup func(string) (string, bool)) Terminal {
+	getenv := func(key string) string {
+		value, _ := lookup(key)
+		return value
+	}
+	ci := getenv("CI")
+	terminal := Terminal{
+		IsTTY:   tty,
+		CI:      ci != "" && ci != "false" && ci != "0",
+		Unicode: utf8Console && utf8Locale(getenv) && getenv("TERM") != "linux",
+	}
+
+	if depth, ok := forcedColorDepth(lookup); ok {
+		if depth == ColorDepthBasic {
+			depth = max(depth, termColorDepth(getenv))
+		}
+		terminal.ColorDepth = depth
+		return terminal
+	}
+	if getenv("NO_COLOR") != "" || !tty || getenv("TERM") == "dumb" {
+		return terminal
+	}
+	terminal.ColorDepth = termColorDepth(getenv)
+	return terminal
+}
+
+// forcedColorDepth returns the color depth forced by FORCE_COLOR, if set
+func forcedColorDepth(lookup func(string) (string, bool)) (ColorDepth, bool) {
+	value, ok := lookup("FORCE_COLOR")
+	if !ok || value == "" {
+		return ColorDepthNone, false
+	}
+	switch strings.ToLower(value) {
+	case "0", "false":
+		return ColorDepthNone, true
+	case "2":
+		return ColorDepth256, true
+	case "3":
+		return ColorDepthTrueColor, true
+	}
+	return ColorDepthBasic, true
+}
+
+// utf8Locale reports whether the locale is UTF-8, or unset
+func utf8Locale(getenv func(string) string) bool {
+	for _, key := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := strings.ToLower(getenv(key)); locale != "" {
+			return strings.Contains(locale, "utf-8") || strings.Contains(locale, "utf8")
+		}
+	}
+	return true
+}
+
+// termColorDepth returns the color depth advertised by COLORTERM and TERM
+func termColorDepth(getenv func(string) string) ColorDepth {
+	switch colorTerm := strings.ToLower(getenv("COLORTERM")); {
+	case colorTerm == "truecolor" || colorTerm == "24bit":
+		return ColorDepthTrueColor
+	case strings.HasSuffix(getenv("TERM"), "256color"):
+		return ColorDepth256
+	}
+	return ColorDepthBasic
+}
+
+// ConsoleMode returns the mode for the output: colors when it supports
+// them, plain text for terminals and CI logs without colors, and JSON
+// otherwise, e.g. when piped into a file or a log collector
+func (t Terminal) ConsoleMode() ConsoleMode {
+	switch {
+	case t.ColorDepth > ColorDepthNone:
+		return ConsoleModeColor
+	case t.IsTTY || t.CI:
+		return ConsoleModePlain
+	}
+	return ConsoleModeJSON
+}
+
+// IconMode returns the icons for the output: emoji if it renders them,
+// ASCII otherwise
+func (t Terminal) IconMode() IconMode {
+	if t.Unicode {
+		return IconModeEmoji
+	}
+	return IconModeASCII
+}
+
+// applyColorEnv sets color.NoColor from FORCE_COLOR, which fatih/color
+// doesn't honor (it handles NO_COLOR, TERM=dumb and non-terminal stdout)
+func applyColorEnv() bool {
+	if depth, ok := forcedColorDepth(os.LookupEnv); ok {
+		color.NoColor = depth == ColorDepthNone
+	}
+	return color.NoColor
+}
+
+// consoleConfig returns config adjusted to the console and icon modes for
+// out, and whether the writer must strip colors from its output. Plain
+// mode uses the "plain" theme format instead of FormatName, and text
+// fields with [LEVEL] instead of the emoji prefix.
+func consoleConfig(config LoggerConfig, out io.Writer, fields TextFields) (LoggerConfig, TextFields, bool) {
+	var terminal *Terminal
+	detect := func() Terminal {
+		if terminal == nil {
+			detected := DetectTerminal(out)
+			terminal = &detected
+		}
+		return *terminal
+	}
+	if config.IconMode == IconModeAuto {
+		config.IconMode = detect().IconMode()
+	}
+
+	mode := config.ConsoleMode
+	if mode == ConsoleModeAuto {
+		mode = detect().ConsoleMode()
+	}
+	switch mode {
+	case ConsoleModeJSON:
+		config.EnableJSON = true
+		config.StrictJSON = true
+	case ConsoleModePlain:
+		config.StrictJSON = true
+		if config.FormatName != "" {
+			config.FormatName = "plain"
+		}
+		if fields&TextPrefix != 0 {
+			fields = fields&^TextPrefix | TextLevel
+		}
+		return config, fields, true
+	}
+	return config, fields, false
+}
+
+// appendPlain appends src to dst without ANSI escape sequences
+func appendPlain(dst, src []byte) []byte {
+	for i := 0; i < len(src); i++ {
+		if src[i] != 0x1b || i+1 >= len(src) || src[i+1] != '[' {
+			dst = append(dst, src[i])
+			continue
+		}
+		// Skip the CSI sequence up to its final byte, e.g. "m" of "\033[1;31m"
+		i += 2
+		for i < len(src) && (src[i] < 0x40 || src[i] > 0x7e) {
+			i++
+		}
+	}
+	return dst
+}
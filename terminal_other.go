@@ -0,0 +1,11 @@
+//go:build !windows
+
+package pim
+
+import "os"
+
+// consoleUTF8 reports whether f renders UTF-8. Outside Windows it depends
+// on the locale only, see utf8Locale.
+func consoleUTF8(f *os.File, tty bool) bool {
+	return true
+}
@@ -0,0 +1,129 @@
+package pim
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDetectTerminal(t *testing.T) {
+	tests := []struct {
+		name     string
+		tty      bool
+		env      map[string]string
+		expected Terminal
+	}{
+		{"tty", true, map[string]string{"TERM": "xterm"}, Terminal{IsTTY: true, ColorDepth: ColorDepthBasic, Unicode: true}},
+		{"tty 256", true, map[string]string{"TERM": "xterm-256color"}, Terminal{IsTTY: true, ColorDepth: ColorDepth256, Unicode: true}},
+		{"tty truecolor", true, map[string]string{"TERM": "xterm-256color", "COLORTERM": "truecolor"}, Terminal{IsTTY: true, ColorDepth: ColorDepthTrueColor, Unicode: true}},
+		{"dumb", true, map[string]string{"TERM": "dumb"}, Terminal{IsTTY: true, Unicode: true}},
+		{"no color", true, map[string]string{"NO_COLOR": "1"}, Terminal{IsTTY: true, Unicode: true}},
+		{"empty no color", true, map[string]string{"NO_COLOR": ""}, Terminal{IsTTY: true, ColorDepth: ColorDepthBasic, Unicode: true}},
+		{"pipe", false, map[string]string{"TERM": "xterm-256color"}, Terminal{Unicode: true}},
+		{"ci", false, map[string]string{"CI": "true"}, Terminal{CI: true, Unicode: true}},
+		{"forced", false, map[string]string{"FORCE_COLOR": "1"}, Terminal{ColorDepth: ColorDepthBasic, Unicode: true}},
+		{"forced with term", false, map[string]string{"FORCE_COLOR": "true", "TERM": "xterm-256color"}, Terminal{ColorDepth: ColorDepth256, Unicode: true}},
+		{"forced truecolor", false, map[string]string{"FORCE_COLOR": "3"}, Terminal{ColorDepth: ColorDepthTrueColor, Unicode: true}},
+		{"forced over no color", true, map[string]string{"FORCE_COLOR": "2", "NO_COLOR": "1"}, Terminal{IsTTY: true, ColorDepth: ColorDepth256, Unicode: true}},
+		{"forced off", true, map[string]string{"FORCE_COLOR": "0"}, Terminal{IsTTY: true, Unicode: true}},
+		{"utf-8 locale", true, map[string]string{"LANG": "en_US.UTF-8"}, Terminal{IsTTY: true, ColorDepth: ColorDepthBasic, Unicode: true}},
+		{"c locale", true, map[string]string{"LANG": "C"}, Terminal{IsTTY: true, ColorDepth: ColorDepthBasic}},
+		{"lc_all first", true, map[string]string{"LC_ALL": "POSIX", "LANG": "en_US.utf8"}, Terminal{IsTTY: true, ColorDepth: ColorDepthBasic}},
+		{"linux console", true, map[string]string{"TERM": "linux"}, Terminal{IsTTY: true, ColorDepth: ColorDepthBasic}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookup := func(key string) (string, bool) {
+				value, ok := tt.env[key]
+				return value, ok
+			}
+			if got := detectTerminal(tt.tty, true, lookup); got != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+
+	// Windows consoles without the UTF-8 code page
+	if got := detectTerminal(true, false, func(string) (string, bool) { return "", false }); got.Unicode {
+		t.Errorf("expected no Unicode without a UTF-8 console, got %+v", got)
+	}
+}
+
+func TestTerminalConsoleMode(t *testing.T) {
+	tests := []struct {
+		terminal Terminal
+		expected ConsoleMode
+	}{
+		{Terminal{IsTTY: true, ColorDepth: ColorDepth256}, ConsoleModeColor},
+		{Terminal{ColorDepth: ColorDepthBasic}, ConsoleModeColor},
+		{Terminal{IsTTY: true}, ConsoleModePlain},
+		{Terminal{CI: true}, ConsoleModePlain},
+		{Terminal{}, ConsoleModeJSON},
+	}
+	for _, tt := range tests {
+		if got := tt.terminal.ConsoleMode(); got != tt.expected {
+			t.Errorf("%+v: expected %q, got %q", tt.terminal, tt.expected, got)
+		}
+	}
+}
+
+func TestAppendPlain(t *testing.T) {
+	input := "\033[36mℹ️  INFO\033[0m \033[1;31mfailed\033[0m [ok]\033"
+	if got := string(appendPlain(nil, []byte(input))); got != "ℹ️  INFO failed [ok]\033" {
+		t.Errorf("unexpected plain output %q", got)
+	}
+}
+
+func TestConsoleWriterModes(t *testing.T) {
+	entry := CoreLogEntry{Level: InfoLevel, LevelString: "info", Message: "served", Prefix: InfoPrefix}
+
+	render := func(mode ConsoleMode) string {
+		config := DefaultLoggerConfig
+		config.ConsoleMode = mode
+		var out bytes.Buffer
+		if err := NewConsoleWriterTo(&out, config).Write(entry); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		return out.String()
+	}
+
+	plain := render(ConsoleModePlain)
+	if !strings.Contains(plain, "[INFO] ") || !strings.Contains(plain, "served") {
+		t.Errorf("expected a plain line with the level, got %q", plain)
+	}
+	if strings.Contains(plain, "\033") || strings.Contains(plain, "ℹ️") {
+		t.Errorf("expected no colors or emoji in plain output, got %q", plain)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(render(ConsoleModeJSON)), &decoded); err != nil {
+		t.Errorf("expected a JSON line: %v", err)
+	}
+
+	// A buffer is not a terminal, so auto mode writes JSON outside CI
+	t.Setenv("FORCE_COLOR", "")
+	t.Setenv("CI", "")
+	if err := json.Unmarshal([]byte(render(ConsoleModeAuto)), &decoded); err != nil {
+		t.Errorf("expected auto mode to write JSON to a buffer: %v", err)
+	}
+	t.Setenv("CI", "true")
+	if got := render(ConsoleModeAuto); got != plain {
+		t.Errorf("expected auto mode to write plain text in CI, got %q", got)
+	}
+
+	if !strings.Contains(render(""), "ℹ️") {
+		t.Errorf("expected the default mode to keep the prefix")
+	}
+}
+
+func TestConsoleModeValidate(t *testing.T) {
+	for _, mode := range []ConsoleMode{"", ConsoleModeAuto, ConsoleModeColor, ConsoleModePlain, ConsoleModeJSON} {
+		if err := mode.Validate(); err != nil {
+			t.Errorf("expected %q to be valid: %v", mode, err)
+		}
+	}
+	if err := ConsoleMode("fancy").Validate(); err == nil {
+		t.Error("expected an unknown mode to be invalid")
+	}
+}
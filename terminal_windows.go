@@ -0,0 +1,23 @@
+//go:build windows
+
+package pim
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// utf8CodePage is the Windows code page of UTF-8
+const utf8CodePage = 65001
+
+// consoleUTF8 reports whether f renders UTF-8: files and pipes store the
+// bytes as they are, Windows Terminal renders them, and other consoles
+// need the UTF-8 output code page (chcp 65001)
+func consoleUTF8(f *os.File, tty bool) bool {
+	if !tty || os.Getenv("WT_SESSION") != "" {
+		return true
+	}
+	cp, err := windows.GetConsoleOutputCP()
+	return err == nil && cp == utf8CodePage
+}
@@ -0,0 +1,314 @@
+package pim
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+)
+
+// ThemeFile is the JSON or YAML file format of a theme, see
+// LoadThemeFromFile. Colors maps elements, named like the ThemeColors
+// fields in lower case ("error", "timestamp", "key", ...), to color specs,
+// see ParseColorSpec:
+//
+//	name: ocean
+//	extends: dark
+//	colors:
+//	  error: "bold #ff5f5f"
+//	  timestamp: "244"
+//	  key: "bg:236 cyan"
+//	icons:
+//	  error: "✖"
+//	ascii_icons:
+//	  error: "(x)"
+//
+// Quote specs in YAML, where " #" starts a comment and bare numbers are
+// not strings. A theme extending another starts from its colors, styles,
+// icons and custom values.
+type ThemeFile struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Extends     string            `json:"extends,omitempty"` // Built-in or registered theme to start from
+	Colors      map[string]string `json:"colors,omitempty"`
+	Styles      ThemeStyles       `json:"styles"`
+	Icons       ThemeIcons        `json:"icons"`
+	ASCIIIcons  ThemeIcons        `json:"ascii_icons"` // Icons for consoles without emoji, see IconMode
+	Custom      map[string]string `json:"custom,omitempty"`
+}
+
+// themeColorFields maps the element names of theme files to ThemeColors fields
+var themeColorFields = map[string]func(c *ThemeColors) **color.Color{
+	"panic":      func(c *ThemeColors) **color.Color { return &c.Panic },
+	"error":      func(c *ThemeColors) **color.Color { return &c.Error },
+	"warning":    func(c *ThemeColors) **color.Color { return &c.Warning },
+	"info":       func(c *ThemeColors) **color.Color { return &c.Info },
+	"success":    func(c *ThemeColors) **color.Color { return &c.Success },
+	"debug":      func(c *ThemeColors) **color.Color { return &c.Debug },
+	"trace":      func(c *ThemeColors) **color.Color { return &c.Trace },
+	"config":     func(c *ThemeColors) **color.Color { return &c.Config },
+	"timestamp":  func(c *ThemeColors) **color.Color { return &c.Timestamp },
+	"service":    func(c *ThemeColors) **color.Color { return &c.Service },
+	"file":       func(c *ThemeColors) **color.Color { return &c.File },
+	"function":   func(c *ThemeColors) **color.Color { return &c.Function },
+	"package":    func(c *ThemeColors) **color.Color { return &c.Package },
+	"goroutine":  func(c *ThemeColors) **color.Color { return &c.Goroutine },
+	"message":    func(c *ThemeColors) **color.Color { return &c.Message },
+	"context":    func(c *ThemeColors) **color.Color { return &c.Context },
+	"key":        func(c *ThemeColors) **color.Color { return &c.Key },
+	"value":      func(c *ThemeColors) **color.Color { return &c.Value },
+	"bracket":    func(c *ThemeColors) **color.Color { return &c.Bracket },
+	"separator":  func(c *ThemeColors) **color.Color { return &c.Separator },
+	"background": func(c *ThemeColors) **color.Color { return &c.Background },
+	"highlight":  func(c *ThemeColors) **color.Color { return &c.Highlight },
+}
+
+// namedColors are the color names of color specs, as offsets from the
+// foreground base (30 for normal, 90 for high intensity)
+var namedColors = map[string]int{
+	"black": 0, "red": 1, "green": 2, "yellow": 3,
+	"blue": 4, "magenta": 5, "purple": 5, "cyan": 6, "white": 7,
+}
+
+// colorAttributes are the attribute names of color specs
+var colorAttributes = map[string]color.Attribute{
+	"bold":        color.Bold,
+	"faint":       color.Faint,
+	"dim":         color.Faint,
+	"italic":      color.Italic,
+	"underline":   color.Underline,
+	"blink":       color.BlinkSlow,
+	"reverse":     color.ReverseVideo,
+	"crossed-out": color.CrossedOut,
+}
+
+// ParseColorSpec parses a color spec of theme files: space-separated
+// attributes (bold, faint or dim, italic, underline, blink, reverse,
+// crossed-out) and colors, where a color is a name (red, hi-red, gray,
+// ...), an ANSI 256-color index such as "208", or a truecolor hex value
+// such as "#ff8800" or "#f80". A color prefixed with "bg:" sets the
+// background, e.g. "bold #ffffff bg:red".
+func ParseColorSpec(spec string) (*color.Color, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty color spec")
+	}
+
+	c := color.New()
+	for _, field := range fields {
+		token := strings.ToLower(field)
+		if attr, ok := colorAttributes[token]; ok {
+			c.Add(attr)
+			continue
+		}
+		background := strings.HasPrefix(token, "bg:")
+		attrs, err := parseColorToken(strings.TrimPrefix(token, "bg:"), background)
+		if err != nil {
+			return nil, fmt.Errorf("invalid color spec %q: %w", spec, err)
+		}
+		c.Add(attrs...)
+	}
+	return c, nil
+}
+
+// parseColorToken returns the SGR attributes of one color of a color spec
+func parseColorToken(token string, background bool) ([]color.Attribute, error) {
+	base, extended := 30, color.Attribute(38)
+	if background {
+		base, extended = 40, 48
+	}
+
+	switch {
+	case strings.HasPrefix(token, "#"):
+		r, g, b, err := parseHexColor(token[1:])
+		if err != nil {
+			return nil, err
+		}
+		// The parameters of color.RGB and color.BgRGB
+		return []color.Attribute{extended, 2, color.Attribute(r), color.Attribute(g), color.Attribute(b)}, nil
+	case token != "" && token[0] >= '0' && token[0] <= '9':
+		index, err := strconv.Atoi(token)
+		if err != nil || index > 255 {
+			return nil, fmt.Errorf("256-color index %q is not between 0 and 255", token)
+		}
+		return []color.Attribute{extended, 5, color.Attribute(index)}, nil
+	case token == "gray" || token == "grey":
+		return []color.Attribute{color.Attribute(base + 60)}, nil
+	}
+
+	name, high := token, false
+	if rest, ok := strings.CutPrefix(token, "hi-"); ok {
+		name, high = rest, true
+	} else if rest, ok := strings.CutPrefix(token, "bright-"); ok {
+		name, high = rest, true
+	}
+	offset, ok := namedColors[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown color %q", token)
+	}
+	if high {
+		base += 60
+	}
+	return []color.Attribute{color.Attribute(base + offset)}, nil
+}
+
+// parseHexColor parses "rrggbb" or "rgb"
+func parseHexColor(hex string) (r, g, b uint8, err error) {
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return 0, 0, 0, fmt.Errorf("hex color %q is not #rrggbb or #rgb", "#"+hex)
+	}
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("hex color %q is not #rrggbb or #rgb", "#"+hex)
+	}
+	return uint8(value >> 16), uint8(value >> 8), uint8(value), nil
+}
+
+// LoadThemeFromFile reads a JSON or YAML (.yaml, .yml) theme file, see
+// ThemeFile. Register the theme with RegisterTheme to select it by name.
+func LoadThemeFromFile(path string) (*Theme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read theme: %w", err)
+	}
+	return ParseTheme(data, filepath.Ext(path))
+}
+
+// ParseTheme parses a theme in the format given by the file extension ext
+// (".json", ".yaml" or ".yml")
+func ParseTheme(data []byte, ext string) (*Theme, error) {
+	data, err := configJSON(data, ext)
+	if err != nil {
+		return nil, err
+	}
+
+	var file ThemeFile
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to parse theme: %w", err)
+	}
+	return file.Theme()
+}
+
+// Theme builds the theme described by the file
+func (f ThemeFile) Theme() (*Theme, error) {
+	if f.Name == "" {
+		return nil, fmt.Errorf("theme without name")
+	}
+
+	var theme Theme
+	if f.Extends != "" {
+		base, ok := lookupTheme(f.Extends)
+		if !ok {
+			return nil, fmt.Errorf("theme %q extends unknown theme %q", f.Name, f.Extends)
+		}
+		theme = base
+	}
+	theme.Name = f.Name
+	theme.Description = f.Description
+
+	elements := make([]string, 0, len(f.Colors))
+	for element := range f.Colors {
+		elements = append(elements, element)
+	}
+	sort.Strings(elements) // Report the first invalid element consistently
+	for _, element := range elements {
+		field, ok := themeColorFields[strings.ToLower(element)]
+		if !ok {
+			return nil, fmt.Errorf("theme %q: unknown color element %q", f.Name, element)
+		}
+		c, err := ParseColorSpec(f.Colors[element])
+		if err != nil {
+			return nil, fmt.Errorf("theme %q: %s: %w", f.Name, element, err)
+		}
+		*field(&theme.Colors) = c
+	}
+
+	mergeStrings(&theme.Styles, f.Styles)
+	mergeStrings(&theme.Icons, f.Icons)
+	mergeStrings(&theme.ASCIIIcons, f.ASCIIIcons)
+	if len(f.Custom) > 0 {
+		custom := make(map[string]string, len(theme.Custom)+len(f.Custom))
+		for k, v := range theme.Custom {
+			custom[k] = v
+		}
+		theme.Custom = custom
+		for k, v := range f.Custom {
+			theme.Custom[k] = v
+		}
+	}
+	return &theme, nil
+}
+
+// mergeStrings sets the non-empty fields of override on dst, for the
+// string-only ThemeStyles and ThemeIcons
+func mergeStrings(dst, override interface{}) {
+	d, o := reflect.ValueOf(dst).Elem(), reflect.ValueOf(override)
+	for i := 0; i < d.NumField(); i++ {
+		if value := o.Field(i).String(); value != "" {
+			d.Field(i).SetString(value)
+		}
+	}
+}
+
+// registeredThemes holds the themes added with RegisterTheme
+var (
+	registeredThemes   = map[string]Theme{}
+	registeredThemesMu sync.RWMutex
+)
+
+// RegisterTheme adds a theme that SetTheme, LoggerConfig.ThemeName and
+// theme files extending it can select by its name. A registered theme
+// replaces a built-in theme of the same name.
+func RegisterTheme(theme *Theme) error {
+	if theme == nil || theme.Name == "" {
+		return fmt.Errorf("theme without name")
+	}
+	registeredThemesMu.Lock()
+	defer registeredThemesMu.Unlock()
+	registeredThemes[theme.Name] = *theme
+	return nil
+}
+
+// lookupTheme returns the registered or built-in theme name
+func lookupTheme(name string) (Theme, bool) {
+	registeredThemesMu.RLock()
+	theme, ok := registeredThemes[name]
+	registeredThemesMu.RUnlock()
+	if ok {
+		return theme, true
+	}
+	theme, ok = builtinThemes[name]
+	return theme, ok
+}
+
+// applyConfigTheme selects the theme of config: CustomTheme, the theme of
+// ThemeFile, or ThemeName. A theme file that fails to load is reported on
+// stderr and the current theme is kept.
+func (tm *ThemeManager) applyConfigTheme(config LoggerConfig) {
+	switch {
+	case config.CustomTheme != nil:
+		tm.currentTheme = config.CustomTheme
+	case config.ThemeFile != "":
+		theme, err := LoadThemeFromFile(config.ThemeFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "pim: failed to load theme file, keeping the current theme: %v\n", err)
+			return
+		}
+		tm.currentTheme = theme
+	case config.ThemeName != "":
+		tm.SetTheme(config.ThemeName)
+	}
+}
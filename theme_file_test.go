@@ -0,0 +1,137 @@
+package pim
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestParseColorSpec(t *testing.T) {
+	originalNoColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = originalNoColor }()
+
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"red", "\033[31m"},
+		{"hi-cyan", "\033[96m"},
+		{"bold gray", "\033[1;90m"},
+		{"208", "\033[38;5;208m"},
+		{"#ff8800", "\033[38;2;255;136;0m"},
+		{"#f80 bg:236", "\033[38;2;255;136;0;48;5;236m"},
+		{"underline bg:blue", "\033[4;44m"},
+		{"bg:#000000", "\033[48;2;0;0;0m"},
+	}
+	for _, tt := range tests {
+		c, err := ParseColorSpec(tt.spec)
+		if err != nil {
+			t.Errorf("ParseColorSpec(%q) failed: %v", tt.spec, err)
+			continue
+		}
+		if got := c.Sprint("x"); !strings.HasPrefix(got, tt.want+"x\033[") {
+			t.Errorf("ParseColorSpec(%q) renders %q, expected it to start with %q", tt.spec, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"", "pink", "256", "#12345", "#gggggg", "bg:"} {
+		if _, err := ParseColorSpec(spec); err == nil {
+			t.Errorf("Expected ParseColorSpec(%q) to fail", spec)
+		}
+	}
+}
+
+func TestLoadThemeFromFile(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "ocean.yaml")
+	os.WriteFile(yamlPath, []byte(`name: ocean
+extends: dark
+colors:
+  error: "bold #ff5f5f"
+  timestamp: "244"
+icons:
+  error: "✖"
+custom:
+  team: infra
+`), 0644)
+
+	theme, err := LoadThemeFromFile(yamlPath)
+	if err != nil {
+		t.Fatalf("LoadThemeFromFile failed: %v", err)
+	}
+	dark := builtinThemes["dark"]
+	if theme.Name != "ocean" || theme.Icons.Error != "✖" || theme.Icons.Info != dark.Icons.Info {
+		t.Errorf("Expected icons merged onto the dark theme, got %+v", theme.Icons)
+	}
+	if theme.Colors.Error == dark.Colors.Error || theme.Colors.Info != dark.Colors.Info {
+		t.Error("Expected only the listed colors to be replaced")
+	}
+	if theme.Custom["team"] != "infra" {
+		t.Errorf("Expected custom values, got %v", theme.Custom)
+	}
+
+	jsonPath := filepath.Join(dir, "bad.json")
+	for _, content := range []string{
+		`{"colors": {"error": "red"}}`,
+		`{"name": "bad", "colors": {"shadow": "red"}}`,
+		`{"name": "bad", "colors": {"error": "pink"}}`,
+		`{"name": "bad", "extends": "missing"}`,
+		`{"name": "bad", "colour": {}}`,
+	} {
+		os.WriteFile(jsonPath, []byte(content), 0644)
+		if _, err := LoadThemeFromFile(jsonPath); err == nil {
+			t.Errorf("Expected %s to be rejected", content)
+		}
+	}
+}
+
+func TestRegisterTheme(t *testing.T) {
+	theme, err := ParseTheme([]byte(`{"name": "test-ocean", "colors": {"message": "cyan"}, "icons": {"info": "i"}}`), ".json")
+	if err != nil {
+		t.Fatalf("ParseTheme failed: %v", err)
+	}
+	if err := RegisterTheme(theme); err != nil {
+		t.Fatalf("RegisterTheme failed: %v", err)
+	}
+	defer func() {
+		registeredThemesMu.Lock()
+		delete(registeredThemes, "test-ocean")
+		registeredThemesMu.Unlock()
+	}()
+
+	tm := NewThemeManager()
+	if err := tm.SetTheme("test-ocean"); err != nil || tm.GetTheme().Icons.Info != "i" {
+		t.Errorf("Expected the registered theme, got %v", err)
+	}
+	if err := RegisterTheme(&Theme{}); err == nil {
+		t.Error("Expected a theme without name to be rejected")
+	}
+}
+
+func TestThemeFileConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "theme.json")
+	os.WriteFile(path, []byte(`{"name": "file-theme", "icons": {"info": ">>"}}`), 0644)
+
+	config := DefaultLoggerConfig
+	config.EnableConsole = false
+	config.ThemeFile = path
+	logger := NewLoggerCore(config)
+	defer logger.Close()
+	if theme := logger.GetTheme(); theme.Name != "file-theme" {
+		t.Errorf("Expected the theme of the file, got %q", theme.Name)
+	}
+
+	encoder := NewTemplateEncoder(config)
+	if name := encoder.ThemeManager().GetTheme().Name; name != "file-theme" {
+		t.Errorf("Expected the template encoder to use the theme file, got %q", name)
+	}
+
+	_, err := ParseConfig([]byte(`{"logger": {"theme_file": "`+filepath.Join(t.TempDir(), "missing.json")+`"}}`), ".json")
+	if err == nil || !strings.Contains(err.Error(), "theme") {
+		t.Errorf("Expected a missing theme file to be rejected, got %v", err)
+	}
+}
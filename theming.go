@@ -2,7 +2,9 @@ package pim
 
 import (
 	"fmt"
+	"os"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 
@@ -16,6 +18,7 @@ type Theme struct {
 	Colors      ThemeColors       `json:"colors"`
 	Styles      ThemeStyles       `json:"styles"`
 	Icons       ThemeIcons        `json:"icons"`
+	ASCIIIcons  ThemeIcons        `json:"ascii_icons"` // Icons for consoles without emoji (default: DefaultASCIIIcons), see IconMode
 	Custom      map[string]string `json:"custom,omitempty"`
 }
 
@@ -101,13 +104,37 @@ type TemplateData struct {
 	UserID      string
 	RequestID   string
 	SessionID   string
+
+	SessionReplayID string
+	ClientEventID   string
+
+	EventID       string
+	ParentEventID string
+
+	MessageTemplate string
 }
 
+// themeTimestamps formats the timestamps of the built-in formatters
+var themeTimestamps = NewTimestampFormatter("2006-01-02 15:04:05", time.Second)
+
 // ThemeManager manages themes and formatting
 type ThemeManager struct {
 	currentTheme *Theme
 	templates    map[string]*template.Template
 	formatters   map[string]LogFormatter
+	sandbox      TemplateSandboxConfig
+
+	// Level labels replacing the theme icon and level name, see
+	// LoggerConfig.LevelPrefixes
+	levelPrefixes map[LogLevel]string
+
+	// Icons used by the built-in formatters, see SetIconMode
+	iconMode IconMode
+
+	// Templates that failed at runtime, reported once each
+	brokenTemplates map[string]bool
+	onTemplateError func(name string, err error)
+	brokenMu        sync.Mutex
 }
 
 // LogFormatter is a function that formats a log entry
@@ -118,6 +145,10 @@ func NewThemeManager() *ThemeManager {
 	tm := &ThemeManager{
 		templates:  make(map[string]*template.Template),
 		formatters: make(map[string]LogFormatter),
+		sandbox:    DefaultTemplateSandboxConfig,
+
+		brokenTemplates: make(map[string]bool),
+		onTemplateError: warnTemplateError,
 	}
 
 	// Register built-in themes
@@ -135,9 +166,31 @@ func NewThemeManager() *ThemeManager {
 	return tm
 }
 
-// SetTheme sets the current theme
+// clone returns a copy whose theme, templates and formatters can be changed
+// without affecting tm
+func (tm *ThemeManager) clone() *ThemeManager {
+	c := &ThemeManager{
+		currentTheme:    tm.currentTheme,
+		templates:       make(map[string]*template.Template, len(tm.templates)),
+		formatters:      make(map[string]LogFormatter, len(tm.formatters)),
+		sandbox:         tm.sandbox,
+		levelPrefixes:   tm.levelPrefixes,
+		iconMode:        tm.iconMode,
+		brokenTemplates: make(map[string]bool),
+		onTemplateError: tm.onTemplateError,
+	}
+	for name, tmpl := range tm.templates {
+		c.templates[name] = tmpl
+	}
+	for name, formatter := range tm.formatters {
+		c.formatters[name] = formatter
+	}
+	return c
+}
+
+// SetTheme sets the current theme to a registered or built-in theme
 func (tm *ThemeManager) SetTheme(name string) error {
-	theme, exists := builtinThemes[name]
+	theme, exists := lookupTheme(name)
 	if !exists {
 		return fmt.Errorf("theme '%s' not found", name)
 	}
@@ -152,11 +205,16 @@ func (tm *ThemeManager) GetTheme() *Theme {
 
 // RegisterTemplate registers a custom template
 func (tm *ThemeManager) RegisterTemplate(name, templateStr string) error {
-	tmpl, err := template.New(name).Parse(templateStr)
+	tmpl, err := template.New(name).Funcs(tm.sandbox.funcs()).Parse(templateStr)
 	if err != nil {
 		return fmt.Errorf("failed to parse template '%s': %w", name, err)
 	}
 	tm.templates[name] = tmpl
+
+	// A re-registered template gets a fresh warning if it breaks again
+	tm.brokenMu.Lock()
+	delete(tm.brokenTemplates, name)
+	tm.brokenMu.Unlock()
 	return nil
 }
 
@@ -175,11 +233,14 @@ func (tm *ThemeManager) Format(entry CoreLogEntry, formatName string) string {
 	// Check if we have a template
 	if tmpl, exists := tm.templates[formatName]; exists {
 		data := tm.entryToTemplateData(entry)
-		var buf strings.Builder
-		if err := tmpl.Execute(&buf, data); err != nil {
-			return fmt.Sprintf("template error: %v", err)
+		data.Context, _ = sandboxValue(entry.Context).(map[string]interface{})
+		output, err := executeSandboxed(tmpl, data, tm.sandbox)
+		if err != nil {
+			// Never lose the entry to a broken template
+			tm.reportTemplateError(formatName, err)
+			return tm.plainFormatter(entry, nil)
 		}
-		return buf.String()
+		return output
 	}
 
 	// Use default formatter
@@ -207,6 +268,14 @@ func (tm *ThemeManager) entryToTemplateData(entry CoreLogEntry) TemplateData {
 		UserID:      entry.UserID,
 		RequestID:   entry.RequestID,
 		SessionID:   entry.SessionID,
+
+		SessionReplayID: entry.SessionReplayID,
+		ClientEventID:   entry.ClientEventID,
+
+		EventID:       entry.EventID,
+		ParentEventID: entry.ParentEventID,
+
+		MessageTemplate: entry.MessageTemplate,
 	}
 }
 
@@ -216,15 +285,14 @@ func (tm *ThemeManager) defaultFormatter(entry CoreLogEntry, theme *Theme) strin
 
 	// Add timestamp
 	if theme.Colors.Timestamp != nil {
-		parts = append(parts, theme.Colors.Timestamp.Sprintf("[%s]", entry.Timestamp.Format("2006-01-02 15:04:05")))
+		parts = append(parts, theme.Colors.Timestamp.Sprintf("[%s]", themeTimestamps.Format(entry.Timestamp)))
 	} else {
-		parts = append(parts, fmt.Sprintf("[%s]", entry.Timestamp.Format("2006-01-02 15:04:05")))
+		parts = append(parts, fmt.Sprintf("[%s]", themeTimestamps.Format(entry.Timestamp)))
 	}
 
 	// Add level with icon
 	levelColor := tm.getLevelColor(entry.Level, theme)
-	icon := tm.getLevelIcon(entry.Level, theme)
-	levelStr := fmt.Sprintf("%s %s", icon, strings.ToUpper(entry.LevelString))
+	levelStr := tm.levelLabel(entry, theme)
 	if levelColor != nil {
 		parts = append(parts, levelColor.Sprintf("%-10s", levelStr))
 	} else {
@@ -295,29 +363,58 @@ func (tm *ThemeManager) getLevelColor(level LogLevel, theme *Theme) *color.Color
 		return theme.Colors.Debug
 	case TraceLevel:
 		return theme.Colors.Trace
-	default:
-		return theme.Colors.Info
 	}
+	if custom, ok := lookupLevel(level); ok && custom.Color != nil {
+		return custom.Color
+	}
+	return theme.Colors.Info
 }
 
-// getLevelIcon returns the icon for a log level
+// getLevelIcon returns the icon for a log level in the icon mode
 func (tm *ThemeManager) getLevelIcon(level LogLevel, theme *Theme) string {
-	switch level {
-	case PanicLevel:
-		return theme.Icons.Panic
-	case ErrorLevel:
-		return theme.Icons.Error
-	case WarningLevel:
-		return theme.Icons.Warning
-	case InfoLevel:
-		return theme.Icons.Info
-	case DebugLevel:
-		return theme.Icons.Debug
-	case TraceLevel:
-		return theme.Icons.Trace
-	default:
-		return theme.Icons.Info
+	if tm.iconMode == IconModeNone {
+		return ""
+	}
+	custom, ok := lookupLevel(level)
+	switch {
+	case ok && tm.iconMode == IconModeASCII:
+		return custom.ASCIIIcon
+	case ok:
+		return custom.Icon
+	case tm.iconMode == IconModeASCII:
+		return theme.asciiIcons().forLevel(level)
+	}
+	return theme.Icons.forLevel(level)
+}
+
+// SetIconMode selects the icons of the built-in formatters: the theme's
+// Icons for IconModeEmoji and IconModeAuto, its ASCIIIcons for
+// IconModeASCII, or none. It must be called before formatting.
+func (tm *ThemeManager) SetIconMode(mode IconMode) {
+	tm.iconMode = mode
+}
+
+// SetLevelPrefixes replaces the icon and level name of the built-in
+// formatters with the given labels, e.g. "[ERR]" for ErrorLevel. Levels
+// without a label keep the theme's. It must be called before formatting.
+func (tm *ThemeManager) SetLevelPrefixes(prefixes map[LogLevel]string) {
+	tm.levelPrefixes = make(map[LogLevel]string, len(prefixes))
+	for level, prefix := range prefixes {
+		tm.levelPrefixes[level] = prefix
+	}
+}
+
+// levelLabel returns the label override of the entry's level, or the
+// theme icon followed by the level name
+func (tm *ThemeManager) levelLabel(entry CoreLogEntry, theme *Theme) string {
+	if prefix, ok := tm.levelPrefixes[entry.Level]; ok {
+		return prefix
+	}
+	icon := tm.getLevelIcon(entry.Level, theme)
+	if icon == "" {
+		return strings.ToUpper(entry.LevelString)
 	}
+	return fmt.Sprintf("%s %s", icon, strings.ToUpper(entry.LevelString))
 }
 
 // formatContext formats context fields with theme colors
@@ -331,7 +428,7 @@ func (tm *ThemeManager) formatContext(context map[string]interface{}, theme *The
 		keyStr := fmt.Sprintf("%s", k)
 		valueStr := fmt.Sprintf("%v", v)
 
-		if theme.Colors.Key != nil && theme.Colors.Value != nil {
+		if theme != nil && theme.Colors.Key != nil && theme.Colors.Value != nil {
 			pairs = append(pairs, fmt.Sprintf("%s=%s",
 				theme.Colors.Key.Sprintf(keyStr),
 				theme.Colors.Value.Sprintf(valueStr)))
@@ -341,7 +438,7 @@ func (tm *ThemeManager) formatContext(context map[string]interface{}, theme *The
 	}
 
 	contextStr := strings.Join(pairs, ", ")
-	if theme.Colors.Bracket != nil {
+	if theme != nil && theme.Colors.Bracket != nil {
 		return theme.Colors.Bracket.Sprintf("{%s}", contextStr)
 	}
 	return fmt.Sprintf("{%s}", contextStr)
@@ -372,10 +469,9 @@ func (tm *ThemeManager) registerBuiltinFormatters() {
 	// Compact formatter
 	tm.RegisterFormatter("compact", func(entry CoreLogEntry, theme *Theme) string {
 		levelColor := tm.getLevelColor(entry.Level, theme)
-		icon := tm.getLevelIcon(entry.Level, theme)
 
 		var parts []string
-		parts = append(parts, fmt.Sprintf("%s %s", icon, strings.ToUpper(entry.LevelString)))
+		parts = append(parts, tm.levelLabel(entry, theme))
 		parts = append(parts, entry.Message)
 
 		if len(entry.Context) > 0 {
@@ -395,24 +491,57 @@ func (tm *ThemeManager) registerBuiltinFormatters() {
 	})
 
 	// Plain formatter (no colors)
-	tm.RegisterFormatter("plain", func(entry CoreLogEntry, theme *Theme) string {
-		var parts []string
-		parts = append(parts, fmt.Sprintf("[%s]", entry.Timestamp.Format("2006-01-02 15:04:05")))
-		parts = append(parts, fmt.Sprintf("[%s]", strings.ToUpper(entry.LevelString)))
+	tm.RegisterFormatter("plain", tm.plainFormatter)
+}
 
-		if entry.ServiceName != "" {
-			parts = append(parts, fmt.Sprintf("[%s]", entry.ServiceName))
-		}
+// plainFormatter formats an entry without colors. It is also the fallback
+// used when a template fails at runtime.
+func (tm *ThemeManager) plainFormatter(entry CoreLogEntry, theme *Theme) string {
+	var parts []string
+	parts = append(parts, fmt.Sprintf("[%s]", themeTimestamps.Format(entry.Timestamp)))
+	parts = append(parts, fmt.Sprintf("[%s]", strings.ToUpper(entry.LevelString)))
 
-		parts = append(parts, entry.Message)
+	if entry.ServiceName != "" {
+		parts = append(parts, fmt.Sprintf("[%s]", entry.ServiceName))
+	}
 
-		if len(entry.Context) > 0 {
-			contextStr := tm.formatContext(entry.Context, nil) // No colors
-			parts = append(parts, contextStr)
-		}
+	parts = append(parts, entry.Message)
 
-		return strings.Join(parts, " ")
-	})
+	if len(entry.Context) > 0 {
+		contextStr := tm.formatContext(entry.Context, nil) // No colors
+		parts = append(parts, contextStr)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// reportTemplateError notifies the template error handler the first time a template fails
+func (tm *ThemeManager) reportTemplateError(name string, err error) {
+	tm.brokenMu.Lock()
+	if tm.brokenTemplates[name] {
+		tm.brokenMu.Unlock()
+		return
+	}
+	tm.brokenTemplates[name] = true
+	handler := tm.onTemplateError
+	tm.brokenMu.Unlock()
+
+	if handler != nil {
+		handler(name, err)
+	}
+}
+
+// warnTemplateError is the default template error handler
+func warnTemplateError(name string, err error) {
+	fmt.Fprintf(os.Stderr, "pim: template '%s' failed, using fallback format: %v\n", name, err)
+}
+
+// SetTemplateErrorHandler sets the function told once about each template
+// that fails at runtime (default: a warning on stderr). Nil disables reporting.
+func (tm *ThemeManager) SetTemplateErrorHandler(handler func(name string, err error)) {
+	tm.brokenMu.Lock()
+	defer tm.brokenMu.Unlock()
+	tm.onTemplateError = handler
 }
 
 // Built-in themes
@@ -605,6 +734,16 @@ var builtinThemes = map[string]Theme{
 			Trace:   "T",
 			Config:  "C",
 		},
+		ASCIIIcons: ThemeIcons{
+			Panic:   "!",
+			Error:   "E",
+			Warning: "W",
+			Info:    "I",
+			Success: "S",
+			Debug:   "D",
+			Trace:   "T",
+			Config:  "C",
+		},
 	},
 }
 
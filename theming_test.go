@@ -1,6 +1,7 @@
 package pim
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -136,6 +137,55 @@ func TestThemeIcons(t *testing.T) {
 	}
 }
 
+func TestThemeManagerLevelPrefixes(t *testing.T) {
+	tm := NewThemeManager()
+	tm.SetLevelPrefixes(map[LogLevel]string{ErrorLevel: "[ERR]"})
+	theme := tm.GetTheme()
+
+	for _, format := range []string{"colorful", "compact"} {
+		output := tm.Format(CoreLogEntry{Level: ErrorLevel, LevelString: "error", Message: "failed"}, format)
+		if !strings.Contains(output, "[ERR]") || strings.Contains(output, theme.Icons.Error) {
+			t.Errorf("%s: expected the label instead of the icon, got %q", format, output)
+		}
+
+		output = tm.Format(CoreLogEntry{Level: InfoLevel, LevelString: "info", Message: "started"}, format)
+		if !strings.Contains(output, theme.Icons.Info+" INFO") {
+			t.Errorf("%s: expected levels without a label to keep the icon, got %q", format, output)
+		}
+	}
+}
+
+func TestLoggerLevelPrefixes(t *testing.T) {
+	config := DefaultLoggerConfig
+	config.EnableConsole = false
+	config.LevelPrefixes = map[LogLevel]string{InfoLevel: "[INFO]"}
+	logger := NewLoggerCore(config)
+	defer logger.Close()
+
+	writer := NewBufferWriter(config, 10)
+	logger.AddWriter(writer)
+
+	logger.Info("started")
+	logger.Success("done")
+	logger.Warning("slow")
+	logger.LogWithContext(InfoLevel, "AUDIT", "custom", nil)
+
+	entries := writer.GetBuffer()
+	if len(entries) != 4 {
+		t.Fatalf("Expected 4 entries, got %d", len(entries))
+	}
+	for i, want := range []string{"[INFO]", "[INFO]", WarningPrefix, "AUDIT"} {
+		if entries[i].Prefix != want {
+			t.Errorf("Entry %d: expected prefix %q, got %q", i, want, entries[i].Prefix)
+		}
+	}
+
+	text, err := NewTextEncoder(config, ConsoleTextFields).EncodeEntry(entries[0])
+	if err != nil || !strings.HasPrefix(string(text), "[INFO] ") {
+		t.Errorf("Expected text output to start with the label, got %q", text)
+	}
+}
+
 func TestGlobalThemeManager(t *testing.T) {
 	// Test global theme manager functions
 	err := SetGlobalTheme("dark")
@@ -152,3 +202,34 @@ func TestGlobalThemeManager(t *testing.T) {
 		t.Errorf("Expected global theme 'dark', got '%s'", theme.Name)
 	}
 }
+
+func TestThemeManagerTemplateErrorFallback(t *testing.T) {
+	tm := NewThemeManager()
+	var reports []string
+	tm.SetTemplateErrorHandler(func(name string, err error) {
+		reports = append(reports, name)
+	})
+
+	// Indexing a missing nested map fails at runtime, not at parse time
+	if err := tm.RegisterTemplate("broken", `{{index .Context.request "id"}}`); err != nil {
+		t.Fatalf("Failed to register template: %v", err)
+	}
+
+	entry := CoreLogEntry{Level: ErrorLevel, LevelString: "error", Message: "payment failed", Context: map[string]interface{}{"request": 42}}
+	for i := 0; i < 3; i++ {
+		out := tm.Format(entry, "broken")
+		if !strings.Contains(out, "payment failed") || !strings.Contains(out, "[ERROR]") {
+			t.Errorf("Expected fallback format to keep message and level, got: %q", out)
+		}
+	}
+	if len(reports) != 1 || reports[0] != "broken" {
+		t.Errorf("Expected a single report for the broken template, got: %v", reports)
+	}
+
+	// Re-registering re-arms the warning
+	tm.RegisterTemplate("broken", `{{index .Context.request "id"}}`)
+	tm.Format(entry, "broken")
+	if len(reports) != 2 {
+		t.Errorf("Expected re-registered template to be reported again, got: %v", reports)
+	}
+}
@@ -0,0 +1,70 @@
+package pim
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// TimestampFormatter formats timestamps with a layout and reuses the last
+// result for timestamps in the same interval of its resolution, so entries
+// logged within one millisecond or second share one formatted string. It is
+// safe for concurrent use.
+type TimestampFormatter struct {
+	layout     string
+	resolution time.Duration
+	last       atomic.Pointer[formattedTimestamp]
+}
+
+// formattedTimestamp is the cached result of a TimestampFormatter
+type formattedTimestamp struct {
+	interval int64 // Timestamp in units of the resolution
+	location *time.Location
+	text     string
+}
+
+// NewTimestampFormatter creates a formatter for layout. Timestamps are
+// truncated to resolution before they are compared, so layout should not
+// show finer digits than resolution; if resolution is zero, the finest unit
+// layout shows is used, e.g. a millisecond for "15:04:05.000".
+func NewTimestampFormatter(layout string, resolution time.Duration) *TimestampFormatter {
+	if resolution <= 0 {
+		resolution = layoutResolution(layout)
+	}
+	return &TimestampFormatter{layout: layout, resolution: resolution}
+}
+
+// Format returns t formatted with the layout
+func (f *TimestampFormatter) Format(t time.Time) string {
+	interval := t.UnixNano() / int64(f.resolution)
+	if last := f.last.Load(); last != nil && last.interval == interval && last.location == t.Location() {
+		return last.text
+	}
+	text := t.Truncate(f.resolution).Format(f.layout)
+	f.last.Store(&formattedTimestamp{interval: interval, location: t.Location(), text: text})
+	return text
+}
+
+// layoutResolution returns the finest unit shown by a time layout: the
+// fractional second digits after "05", a second, or a minute. Layouts
+// without seconds or minutes get a second, as their coarser units depend
+// on the time zone.
+func layoutResolution(layout string) time.Duration {
+	seconds := strings.Index(layout, "05")
+	if seconds < 0 {
+		if strings.Contains(layout, "04") {
+			return time.Minute
+		}
+		return time.Second
+	}
+
+	rest := layout[seconds+2:]
+	if len(rest) < 2 || (rest[0] != '.' && rest[0] != ',') || (rest[1] != '0' && rest[1] != '9') {
+		return time.Second
+	}
+	resolution := time.Second
+	for i := 1; i < len(rest) && rest[i] == rest[1] && resolution > time.Nanosecond; i++ {
+		resolution /= 10
+	}
+	return resolution
+}
@@ -0,0 +1,82 @@
+package pim
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimestampFormatter(t *testing.T) {
+	formatter := NewTimestampFormatter("15:04:05.000", 0)
+	base := time.Date(2024, 1, 2, 3, 4, 5, 123_000_000, time.UTC)
+
+	if got := formatter.Format(base); got != "03:04:05.123" {
+		t.Errorf("Expected 03:04:05.123, got %s", got)
+	}
+	if got := formatter.Format(base.Add(500 * time.Microsecond)); got != "03:04:05.123" {
+		t.Errorf("Expected the cached string within the millisecond, got %s", got)
+	}
+	if got := formatter.Format(base.Add(time.Millisecond)); got != "03:04:05.124" {
+		t.Errorf("Expected a new string in the next millisecond, got %s", got)
+	}
+
+	local := time.FixedZone("UTC+1", 3600)
+	if got := formatter.Format(base.Add(time.Millisecond).In(local)); got != "04:04:05.124" {
+		t.Errorf("Expected a new string for another location, got %s", got)
+	}
+}
+
+func TestTimestampFormatterCoarseResolution(t *testing.T) {
+	formatter := NewTimestampFormatter("15:04:05.000", time.Second)
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	formatter.Format(base)
+	if got := formatter.Format(base.Add(999 * time.Millisecond)); got != "03:04:05.000" {
+		t.Errorf("Expected timestamps truncated to the second, got %s", got)
+	}
+}
+
+func TestLayoutResolution(t *testing.T) {
+	tests := []struct {
+		layout string
+		want   time.Duration
+	}{
+		{DefaultLoggerConfig.TimestampFormat, time.Millisecond},
+		{time.RFC3339, time.Second},
+		{time.RFC3339Nano, time.Nanosecond},
+		{"15:04:05,000000", time.Microsecond},
+		{time.Kitchen, time.Minute},
+		{time.DateOnly, time.Second},
+	}
+	for _, tt := range tests {
+		if got := layoutResolution(tt.layout); got != tt.want {
+			t.Errorf("layoutResolution(%q) = %v, expected %v", tt.layout, got, tt.want)
+		}
+	}
+}
+
+func TestTextEncoderTimestampResolution(t *testing.T) {
+	config := LoggerConfig{TimestampFormat: time.RFC3339Nano, TimestampResolution: time.Second}
+	encoder := NewTextEncoder(config, TextTimestamp)
+	entry := CoreLogEntry{Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 678, time.UTC)}
+
+	data, _ := encoder.EncodeEntry(entry)
+	if !strings.HasPrefix(string(data), "[2024-01-02T03:04:05Z]") {
+		t.Errorf("Expected the timestamp truncated to the resolution, got %s", data)
+	}
+}
+
+func BenchmarkTimestampFormatter(b *testing.B) {
+	formatter := NewTimestampFormatter(DefaultLoggerConfig.TimestampFormat, 0)
+	now := time.Now()
+	for i := 0; i < b.N; i++ {
+		formatter.Format(now)
+	}
+}
+
+func BenchmarkTimestampFormat(b *testing.B) {
+	now := time.Now()
+	for i := 0; i < b.N; i++ {
+		now.Format(DefaultLoggerConfig.TimestampFormat)
+	}
+}
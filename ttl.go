@@ -0,0 +1,208 @@
+package pim
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ExpiresAtKey is the context key holding the expiry time of an entry
+const ExpiresAtKey = "expires_at"
+
+// WithTTL marks the entry as expiring after ttl so ephemeral sinks purge it
+func WithTTL(ttl time.Duration) CallOption {
+	return func(o *callOptions) {
+		if o.fields == nil {
+			o.fields = make(map[string]interface{})
+		}
+		o.fields[ExpiresAtKey] = time.Now().Add(ttl).UTC().Format(time.RFC3339Nano)
+	}
+}
+
+// EntryExpiry returns when an entry expires, if it has a TTL
+func EntryExpiry(entry CoreLogEntry) (time.Time, bool) {
+	switch v := entry.Context[ExpiresAtKey].(type) {
+	case time.Time:
+		return v, true
+	case string:
+		expiresAt, err := time.Parse(time.RFC3339Nano, v)
+		return expiresAt, err == nil
+	}
+	return time.Time{}, false
+}
+
+// Purgeable is a sink that can delete its expired entries
+type Purgeable interface {
+	PurgeExpired(now time.Time) (int, error)
+}
+
+// PurgeExpired removes buffered entries whose TTL has passed
+func (w *BufferWriter) PurgeExpired(now time.Time) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	kept := w.buffer[:0]
+	for _, entry := range w.buffer {
+		if expiresAt, ok := EntryExpiry(entry); ok && !now.Before(expiresAt) {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+
+	purged := len(w.buffer) - len(kept)
+	// Clear the tail so purged entries can be garbage collected
+	for i := len(kept); i < len(w.buffer); i++ {
+		w.buffer[i] = CoreLogEntry{}
+	}
+	w.buffer = kept
+	return purged, nil
+}
+
+// EphemeralFileWriter stores each entry as its own JSON file in a directory,
+// for verbose debug artifacts such as request dumps. File names carry the
+// expiry time so PurgeExpired can delete them without reading them.
+type EphemeralFileWriter struct {
+	dir        string
+	defaultTTL time.Duration
+	seq        atomic.Uint64
+}
+
+// NewEphemeralFileWriter creates an ephemeral sink in dir. Entries without
+// a TTL of their own expire after defaultTTL.
+func NewEphemeralFileWriter(dir string, defaultTTL time.Duration) (*EphemeralFileWriter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create ephemeral log directory: %w", err)
+	}
+	return &EphemeralFileWriter{dir: dir, defaultTTL: defaultTTL}, nil
+}
+
+// Write implements LogWriter interface
+func (w *EphemeralFileWriter) Write(entry CoreLogEntry) error {
+	expiresAt, ok := EntryExpiry(entry)
+	if !ok {
+		expiresAt = time.Now().Add(w.defaultTTL)
+		// The context is shared with the other writers
+		entry = copyEntry(entry)
+		entry.addFields(map[string]interface{}{ExpiresAtKey: expiresAt.UTC().Format(time.RFC3339Nano)})
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal log entry: %w", err)
+	}
+
+	name := fmt.Sprintf("%d-%d.json", expiresAt.UnixNano(), w.seq.Add(1))
+	return os.WriteFile(filepath.Join(w.dir, name), data, 0644)
+}
+
+// PurgeExpired deletes artifact files whose TTL has passed
+func (w *EphemeralFileWriter) PurgeExpired(now time.Time) (int, error) {
+	files, err := os.ReadDir(w.dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read ephemeral log directory: %w", err)
+	}
+
+	purged := 0
+	var errs []error
+	for _, file := range files {
+		expiry, _, found := strings.Cut(file.Name(), "-")
+		if !found {
+			continue
+		}
+		expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+		if err != nil || now.UnixNano() < expiresAt {
+			continue
+		}
+		if err := os.Remove(filepath.Join(w.dir, file.Name())); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+			continue
+		}
+		purged++
+	}
+
+	if len(errs) > 0 {
+		return purged, fmt.Errorf("failed to purge artifacts: %v", errs)
+	}
+	return purged, nil
+}
+
+// Close implements LogWriter interface
+func (w *EphemeralFileWriter) Close() error {
+	return nil
+}
+
+// Flush implements LogWriter interface
+func (w *EphemeralFileWriter) Flush() error {
+	return nil
+}
+
+// Purger periodically deletes expired entries from ephemeral sinks
+type Purger struct {
+	sinks    []Purgeable
+	interval time.Duration
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// StartPurger starts purging the sinks every interval (default: a minute)
+// until Stop is called
+func StartPurger(interval time.Duration, sinks ...Purgeable) *Purger {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	p := &Purger{
+		sinks:    sinks,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+
+	p.wg.Add(1)
+	go p.run()
+	return p
+}
+
+// run is the purge loop
+func (p *Purger) run() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			p.PurgeNow(now)
+		case <-p.stopCh:
+			return
+		}
+	}
+}
+
+// PurgeNow purges all sinks immediately and returns the number of removed entries
+func (p *Purger) PurgeNow(now time.Time) int {
+	total := 0
+	for _, sink := range p.sinks {
+		purged, err := sink.PurgeExpired(now)
+		if err != nil {
+			// Log purge errors to stderr to avoid infinite loops
+			fmt.Fprintf(os.Stderr, "Failed to purge expired log entries: %v\n", err)
+		}
+		total += purged
+	}
+	return total
+}
+
+// Stop stops the purger and waits for the current purge to finish
+func (p *Purger) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.stopCh)
+	})
+	p.wg.Wait()
+}
@@ -0,0 +1,73 @@
+package pim
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestBufferWriterPurgesExpiredEntries(t *testing.T) {
+	logger, buffer := newTestLogger(t, func(config *LoggerConfig) {
+		config.Level = TraceLevel
+	})
+
+	logger.Debug("request dump", WithTTL(time.Minute))
+	logger.Info("kept forever")
+
+	if _, ok := EntryExpiry(buffer.GetBuffer()[0]); !ok {
+		t.Fatal("Expected TTL entry to carry an expiry")
+	}
+
+	purged, _ := buffer.PurgeExpired(time.Now())
+	if purged != 0 {
+		t.Errorf("Expected nothing to expire yet, purged %d", purged)
+	}
+
+	purged, _ = buffer.PurgeExpired(time.Now().Add(2 * time.Minute))
+	entries := buffer.GetBuffer()
+	if purged != 1 || len(entries) != 1 || entries[0].Message != "kept forever" {
+		t.Errorf("Expected only the TTL entry to be purged, got %d purged and %+v", purged, entries)
+	}
+}
+
+func TestEphemeralFileWriterAndPurger(t *testing.T) {
+	dir := t.TempDir()
+	writer, err := NewEphemeralFileWriter(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+
+	short := CoreLogEntry{Message: "short", Context: map[string]interface{}{ExpiresAtKey: time.Now().Add(-time.Second)}}
+	writer.Write(short)
+	writer.Write(CoreLogEntry{Message: "default ttl"})
+
+	purger := StartPurger(time.Hour, writer)
+	defer purger.Stop()
+
+	if purged := purger.PurgeNow(time.Now()); purged != 1 {
+		t.Errorf("Expected the expired artifact to be purged, got %d", purged)
+	}
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("Expected one artifact left, got %d", len(files))
+	}
+
+	if purged := purger.PurgeNow(time.Now().Add(2 * time.Hour)); purged != 1 {
+		t.Errorf("Expected default TTL artifact to expire, got %d", purged)
+	}
+}
+
+func TestEphemeralFileWriterLeavesContextAlone(t *testing.T) {
+	writer, err := NewEphemeralFileWriter(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	context := map[string]interface{}{"user_id": "u1"}
+	writer.Write(CoreLogEntry{Message: "dump", Context: context})
+	if _, ok := context[ExpiresAtKey]; ok || len(context) != 1 {
+		t.Errorf("Expected the shared context to be left alone, got %v", context)
+	}
+
+	// A zero interval falls back to the default instead of panicking
+	StartPurger(0, writer).Stop()
+}
@@ -0,0 +1,169 @@
+package pim
+
+import (
+	"time"
+
+	"github.com/refactorroom/pim/core"
+)
+
+// Field is a typed key/value pair for the *Fields logging methods and With,
+// e.g.
+//
+//	logger.InfoFields("request done", pim.String("path", path), pim.Int("status", 200))
+//
+// Unlike map fields and format arguments, typed fields other than Any are not
+// boxed into interfaces, so calls below the logger level do not allocate.
+// The map-based WithFields and *KV methods remain available.
+type Field = core.Field
+
+// String creates a string field
+func String(key, value string) Field {
+	return core.String(key, value)
+}
+
+// Int creates an integer field
+func Int(key string, value int) Field {
+	return core.Int(key, int64(value))
+}
+
+// Int64 creates a 64-bit integer field
+func Int64(key string, value int64) Field {
+	return core.Int(key, value)
+}
+
+// Float64 creates a floating-point field
+func Float64(key string, value float64) Field {
+	return core.Float(key, value)
+}
+
+// Bool creates a boolean field
+func Bool(key string, value bool) Field {
+	return core.Bool(key, value)
+}
+
+// Duration creates a field holding the duration in its string form, e.g. "1.5s"
+func Duration(key string, value time.Duration) Field {
+	return core.String(key, value.String())
+}
+
+// Time creates a time field; the value is logged in UTC
+func Time(key string, value time.Time) Field {
+	return core.Time(key, value)
+}
+
+// Err creates an "error" field from err
+func Err(err error) Field {
+	return core.Err(err)
+}
+
+// Any creates a field from an arbitrary value, using the typed constructor
+// for the value's type where there is one
+func Any(key string, value interface{}) Field {
+	switch v := value.(type) {
+	case string:
+		return String(key, v)
+	case int:
+		return Int(key, v)
+	case int64:
+		return Int64(key, v)
+	case int32:
+		return Int64(key, int64(v))
+	case uint32:
+		return Int64(key, int64(v))
+	case float64:
+		return Float64(key, v)
+	case float32:
+		return Float64(key, float64(v))
+	case bool:
+		return Bool(key, v)
+	case time.Duration:
+		return Duration(key, v)
+	case time.Time:
+		return Time(key, v)
+	default:
+		return core.Any(key, value)
+	}
+}
+
+// fieldValue returns the context value of a typed field
+func fieldValue(field Field) interface{} {
+	switch field.Kind {
+	case core.IntField:
+		return field.Int
+	case core.BoolField:
+		return field.Int != 0
+	case core.FloatField:
+		return field.Float()
+	case core.TimeField:
+		return field.Time()
+	case core.AnyField:
+		return normalizeFieldValue(field.Any)
+	default:
+		return field.Str
+	}
+}
+
+// With returns a new logger that adds typed fields to every entry
+func (l *LoggerCore) With(fields ...Field) *LoggerCore {
+	ctx := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		ctx[field.Key] = fieldValue(field)
+	}
+	return l.WithContext(ctx)
+}
+
+// LogFields creates and writes a log entry with typed fields. The level is
+// checked before anything is allocated.
+func (l *LoggerCore) LogFields(level LogLevel, prefix, message string, fields ...Field) {
+	l.logFields(1, level, prefix, message, fields)
+}
+
+// logFields implements LogFields for the call site skip frames above its
+// caller, see log
+func (l *LoggerCore) logFields(skip int, level LogLevel, prefix, message string, fields []Field) {
+	if !l.enabled(skip+1, level, message, callOptions{}) {
+		return
+	}
+
+	entry := l.createLogEntry(skip+1, level, prefix, message)
+	if len(fields) > 0 {
+		if entry.Context == nil {
+			entry.Context = make(map[string]interface{}, len(fields))
+		}
+		for _, field := range fields {
+			entry.Context[l.groupKey(field.Key)] = fieldValue(field)
+		}
+	}
+
+	entry = l.applyHooks(entry)
+	if entry.Message == "" && entry.Level == 0 {
+		return // Entry was filtered, don't log
+	}
+
+	l.dispatch(entry, callOptions{})
+}
+
+// TraceFields logs a trace message with typed fields
+func (l *LoggerCore) TraceFields(msg string, fields ...Field) {
+	l.logFields(1, TraceLevel, TracePrefix, msg, fields)
+}
+
+// DebugFields logs a debug message with typed fields
+func (l *LoggerCore) DebugFields(msg string, fields ...Field) {
+	l.logFields(1, DebugLevel, DebugPrefix, msg, fields)
+}
+
+// InfoFields logs an info message with typed fields
+func (l *LoggerCore) InfoFields(msg string, fields ...Field) {
+	l.logFields(1, InfoLevel, InfoPrefix, msg, fields)
+}
+
+// WarningFields logs a warning message with typed fields
+func (l *LoggerCore) WarningFields(msg string, fields ...Field) {
+	l.logFields(1, WarningLevel, WarningPrefix, msg, fields)
+}
+
+// ErrorFields logs an error message with typed fields
+func (l *LoggerCore) ErrorFields(msg string, fields ...Field) {
+	l.logFields(1, ErrorLevel, ErrorPrefix, msg, fields)
+}
@@ -0,0 +1,293 @@
+package pim
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// SchemaViolationsKey is the context key listing the violations of an
+// entry annotated by a ValidationHook
+const SchemaViolationsKey = "schema_violations"
+
+// ValidationAction is what a ValidationHook does with non-conforming entries
+type ValidationAction string
+
+const (
+	ValidationAnnotate   ValidationAction = "annotate"   // Keep the entry and list its violations (default)
+	ValidationDrop       ValidationAction = "drop"       // Filter the entry out
+	ValidationQuarantine ValidationAction = "quarantine" // Write the annotated entry to the quarantine writer only, after the remaining hooks
+)
+
+// Field types checked by FieldRule.Type
+const (
+	FieldTypeString  = "string"
+	FieldTypeNumber  = "number"
+	FieldTypeInteger = "integer"
+	FieldTypeBool    = "bool"
+	FieldTypeObject  = "object"
+	FieldTypeArray   = "array"
+)
+
+// FieldRule constrains one field of a log entry
+type FieldRule struct {
+	Required  bool     `json:"required,omitempty"`
+	Type      string   `json:"type,omitempty"`       // One of the FieldType constants; any type if empty
+	Allowed   []string `json:"allowed,omitempty"`    // Allowed values, compared as strings
+	Pattern   string   `json:"pattern,omitempty"`    // Regex string values must match
+	MaxLength int      `json:"max_length,omitempty"` // Maximum length of string values in characters
+}
+
+// ValidationConfig holds configuration for validation hooks
+type ValidationConfig struct {
+	HookConfig
+
+	// Fields maps field names to their rules. Names are context keys, with
+	// dots for nested keys such as "http.status", or the entry fields
+	// service_name, logger, trace_id, span_id, user_id, request_id and
+	// session_id.
+	Fields map[string]FieldRule `json:"fields,omitempty"`
+
+	MaxMessageLength int              `json:"max_message_length,omitempty"` // In characters; 0 for no limit
+	Action           ValidationAction `json:"action,omitempty"`
+	Quarantine       LogWriter        `json:"-"` // Receives entries for ValidationQuarantine
+}
+
+// ValidationHook checks entries against a field schema to enforce logging
+// standards, e.g. that every entry has an env field with a known value
+type ValidationHook struct {
+	config     ValidationConfig
+	patterns   map[string]*regexp.Regexp
+	names      []string // Sorted field names, so violations are listed in a stable order
+	violations atomic.Uint64
+}
+
+// NewValidationHook creates a validation hook. It returns an error for
+// unknown types or actions, invalid patterns, and ValidationQuarantine
+// without a quarantine writer.
+func NewValidationHook(config ValidationConfig) (*ValidationHook, error) {
+	switch config.Action {
+	case "":
+		config.Action = ValidationAnnotate
+	case ValidationAnnotate, ValidationDrop:
+	case ValidationQuarantine:
+		if config.Quarantine == nil {
+			return nil, fmt.Errorf("validation hook %s: quarantine action needs a quarantine writer", config.Name)
+		}
+	default:
+		return nil, fmt.Errorf("validation hook %s: unknown action %q", config.Name, config.Action)
+	}
+
+	hook := &ValidationHook{config: config, patterns: make(map[string]*regexp.Regexp)}
+	for name, rule := range config.Fields {
+		switch rule.Type {
+		case "", FieldTypeString, FieldTypeNumber, FieldTypeInteger, FieldTypeBool, FieldTypeObject, FieldTypeArray:
+		default:
+			return nil, fmt.Errorf("validation hook %s: unknown type %q for field %s", config.Name, rule.Type, name)
+		}
+		if rule.Pattern != "" {
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("validation hook %s: invalid pattern for field %s: %w", config.Name, name, err)
+			}
+			hook.patterns[name] = pattern
+		}
+		hook.names = append(hook.names, name)
+	}
+	sort.Strings(hook.names)
+	return hook, nil
+}
+
+// Violations returns the number of non-conforming entries seen
+func (h *ValidationHook) Violations() uint64 {
+	return h.violations.Load()
+}
+
+// Validate returns the violations of entry, or nil if it conforms
+func (h *ValidationHook) Validate(entry CoreLogEntry) []string {
+	var violations []string
+	if h.config.MaxMessageLength > 0 {
+		if n := utf8.RuneCountInString(entry.Message); n > h.config.MaxMessageLength {
+			violations = append(violations, fmt.Sprintf("message: length %d exceeds %d", n, h.config.MaxMessageLength))
+		}
+	}
+
+	for _, name := range h.names {
+		rule := h.config.Fields[name]
+		value, ok := entryField(entry, name)
+		if !ok {
+			if rule.Required {
+				violations = append(violations, name+": required")
+			}
+			continue
+		}
+
+		if rule.Type != "" && !fieldTypeMatches(rule.Type, value) {
+			violations = append(violations, fmt.Sprintf("%s: expected %s, got %T", name, rule.Type, value))
+			continue
+		}
+		if len(rule.Allowed) > 0 {
+			allowed := false
+			s := fmt.Sprint(value)
+			for _, candidate := range rule.Allowed {
+				if s == candidate {
+					allowed = true
+					break
+				}
+			}
+			if !allowed {
+				violations = append(violations, fmt.Sprintf("%s: %q is not one of %v", name, s, rule.Allowed))
+			}
+		}
+		if s, ok := value.(string); ok {
+			if rule.MaxLength > 0 && utf8.RuneCountInString(s) > rule.MaxLength {
+				violations = append(violations, fmt.Sprintf("%s: length %d exceeds %d", name, utf8.RuneCountInString(s), rule.MaxLength))
+			}
+			if pattern := h.patterns[name]; pattern != nil && !pattern.MatchString(s) {
+				violations = append(violations, fmt.Sprintf("%s: does not match %s", name, rule.Pattern))
+			}
+		}
+	}
+	return violations
+}
+
+// entryField looks up a context field by dotted path, falling back to the
+// entry's correlation fields
+func entryField(entry CoreLogEntry, name string) (interface{}, bool) {
+	// Dotted keys such as the fields of a WithGroup logger are stored flat
+	if value, ok := entry.Context[name]; ok {
+		return value, true
+	}
+
+	var current interface{} = entry.Context
+	found := entry.Context != nil
+	for _, key := range strings.Split(name, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			found = false
+			break
+		}
+		if current, ok = m[key]; !ok {
+			found = false
+			break
+		}
+	}
+	if found {
+		return current, true
+	}
+
+	fields := map[string]string{
+		"service_name": entry.ServiceName,
+		"logger":       entry.LoggerName,
+		"trace_id":     entry.TraceID,
+		"span_id":      entry.SpanID,
+		"user_id":      entry.UserID,
+		"request_id":   entry.RequestID,
+		"session_id":   entry.SessionID,
+	}
+	if value := fields[name]; value != "" {
+		return value, true
+	}
+	return nil, false
+}
+
+// fieldTypeMatches reports whether value has the field type kind
+func fieldTypeMatches(kind string, value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	v := reflect.ValueOf(value)
+	switch kind {
+	case FieldTypeString:
+		return v.Kind() == reflect.String
+	case FieldTypeBool:
+		return v.Kind() == reflect.Bool
+	case FieldTypeInteger:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return true
+		case reflect.Float32, reflect.Float64:
+			return v.Float() == float64(int64(v.Float()))
+		}
+		return false
+	case FieldTypeNumber:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			return true
+		}
+		return false
+	case FieldTypeObject:
+		return v.Kind() == reflect.Map || v.Kind() == reflect.Struct ||
+			v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Struct
+	case FieldTypeArray:
+		return v.Kind() == reflect.Slice || v.Kind() == reflect.Array
+	}
+	return true
+}
+
+// Process implements LogHook interface
+func (h *ValidationHook) Process(entry CoreLogEntry) (CoreLogEntry, error) {
+	if !h.config.Enabled {
+		return entry, nil
+	}
+	violations := h.Validate(entry)
+	if len(violations) == 0 {
+		return entry, nil
+	}
+	h.violations.Add(1)
+
+	if h.config.Action == ValidationDrop {
+		return CoreLogEntry{}, fmt.Errorf("entry filtered by hook: %s", h.config.Name)
+	}
+
+	context := make(map[string]interface{}, len(entry.Context)+1)
+	for k, v := range entry.Context {
+		context[k] = v
+	}
+	context[SchemaViolationsKey] = violations
+	entry.Context = context
+
+	if h.config.Action == ValidationQuarantine {
+		// The logger writes it once the remaining hooks, such as
+		// redaction, have run
+		entry.quarantine = h.config.Quarantine
+	}
+	return entry, nil
+}
+
+// GetConfig implements EnhancedLogHook interface
+func (h *ValidationHook) GetConfig() HookConfig {
+	return h.config.HookConfig
+}
+
+// GetType implements EnhancedLogHook interface
+func (h *ValidationHook) GetType() HookType {
+	return h.config.Type
+}
+
+// IsEnabled implements EnhancedLogHook interface
+func (h *ValidationHook) IsEnabled() bool {
+	return h.config.Enabled
+}
+
+// SetEnabled implements EnhancedLogHook interface
+func (h *ValidationHook) SetEnabled(enabled bool) {
+	h.config.Enabled = enabled
+}
+
+// GetPriority implements EnhancedLogHook interface
+func (h *ValidationHook) GetPriority() int {
+	return h.config.Priority
+}
+
+// SetPriority implements EnhancedLogHook interface
+func (h *ValidationHook) SetPriority(priority int) {
+	h.config.Priority = priority
+}
@@ -0,0 +1,277 @@
+// Package viewer serves a small web UI for browsing, filtering and tailing
+// the log files pim writes to a directory, for servers without centralized
+// logging. The UI is embedded, so the program serving it is all that needs
+// to be deployed:
+//
+//	log.Fatal(viewer.Serve("/var/log/myapp", "localhost:8080"))
+//
+// Files are read with pim.ReadLogFile and tailed with pim.Follow, so JSON
+// and text files and compressed rotations are all supported. The viewer has
+// no authentication; bind it to localhost or mount it behind one.
+package viewer
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/refactorroom/pim"
+)
+
+// DefaultLimit is the number of entries returned when no limit is given
+const DefaultLimit = 1000
+
+//go:embed index.html
+var indexHTML []byte
+
+// Handler serves the viewer for the log files of one directory. Mount it
+// under a prefix with http.StripPrefix; the UI uses relative URLs.
+//
+// Endpoints:
+//
+//	GET /                 the web UI
+//	GET /api/files        the files of the directory, newest first
+//	GET /api/entries      the last entries of ?file= matching the filter
+//	GET /api/tail         entries written to ?file= from now on, as server-sent events
+//
+// The entries and tail endpoints filter with ?level= (the most verbose level
+// shown) and ?q= (case-insensitive text in the message, prefix, logger or
+// fields); entries also takes ?limit=.
+type Handler struct {
+	dir string
+	mux *http.ServeMux
+}
+
+// FileInfo describes a log file in GET /api/files
+type FileInfo struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// NewHandler creates a viewer for the log files in dir
+func NewHandler(dir string) *Handler {
+	h := &Handler{dir: dir, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /{$}", h.getIndex)
+	h.mux.HandleFunc("GET /api/files", h.getFiles)
+	h.mux.HandleFunc("GET /api/entries", h.getEntries)
+	h.mux.HandleFunc("GET /api/tail", h.getTail)
+	return h
+}
+
+// Serve serves the viewer for the log files in dir on addr until it fails
+func Serve(dir, addr string) error {
+	if info, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("failed to open log directory: %w", err)
+	} else if !info.IsDir() {
+		return fmt.Errorf("log directory %s is not a directory", dir)
+	}
+	return http.ListenAndServe(addr, NewHandler(dir))
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// getIndex serves GET /
+func (h *Handler) getIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(indexHTML)
+}
+
+// getFiles serves GET /api/files
+func (h *Handler) getFiles(w http.ResponseWriter, r *http.Request) {
+	dirEntries, err := os.ReadDir(h.dir)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to read log directory: %w", err))
+		return
+	}
+
+	files := []FileInfo{}
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.Type().IsRegular() || strings.HasPrefix(dirEntry.Name(), ".") {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue // Removed by a rotation since ReadDir
+		}
+		files = append(files, FileInfo{Name: info.Name(), Size: info.Size(), ModTime: info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].ModTime.Equal(files[j].ModTime) {
+			return files[i].ModTime.After(files[j].ModTime)
+		}
+		return files[i].Name < files[j].Name
+	})
+	writeJSON(w, http.StatusOK, files)
+}
+
+// getEntries serves GET /api/entries
+func (h *Handler) getEntries(w http.ResponseWriter, r *http.Request) {
+	path, err := h.filePath(r)
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	match, err := parseFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	limit := DefaultLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", s))
+			return
+		}
+	}
+
+	// Keep the last limit matches in a ring, so large files need no more
+	// memory than the response
+	ring := make([]pim.CoreLogEntry, 0, min(limit, DefaultLimit))
+	next := 0
+	for entry, err := range pim.ReadLogFile(path) {
+		if err != nil && entry.Message == "" {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if !match(entry) {
+			continue
+		}
+		if len(ring) < limit {
+			ring = append(ring, entry)
+			continue
+		}
+		ring[next] = entry
+		next = (next + 1) % limit
+	}
+	writeJSON(w, http.StatusOK, append(ring[next:], ring[:next]...))
+}
+
+// getTail serves GET /api/tail
+func (h *Handler) getTail(w http.ResponseWriter, r *http.Request) {
+	path, err := h.filePath(r)
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	match, err := parseFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for entry, err := range pim.Follow(r.Context(), path, true) {
+		if err != nil && entry.Message == "" {
+			fmt.Fprintf(w, "event: error\ndata: %s\n\n", strings.ReplaceAll(err.Error(), "\n", " "))
+			flusher.Flush()
+			return
+		}
+		if !match(entry) {
+			continue
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		flusher.Flush()
+	}
+}
+
+// filePath returns the path of the file named by ?file=, which must be a
+// file directly in the directory
+func (h *Handler) filePath(r *http.Request) (string, error) {
+	name := r.URL.Query().Get("file")
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid file name %q", name)
+	}
+	path := filepath.Join(h.dir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open log file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("invalid file name %q", name)
+	}
+	return path, nil
+}
+
+// statusFor returns the response status for a filePath error
+func statusFor(err error) int {
+	if errors.Is(err, fs.ErrNotExist) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+// parseFilter returns the entry filter of ?level= and ?q=
+func parseFilter(r *http.Request) (func(pim.CoreLogEntry) bool, error) {
+	query := r.URL.Query()
+	var threshold *pim.LogLevel
+	if s := query.Get("level"); s != "" {
+		level, err := pim.ParseLevel(s)
+		if err != nil {
+			return nil, err
+		}
+		threshold = &level
+	}
+	text := strings.ToLower(query.Get("q"))
+
+	return func(entry pim.CoreLogEntry) bool {
+		if threshold != nil && entry.Level > *threshold {
+			return false
+		}
+		return text == "" || entryContains(entry, text)
+	}, nil
+}
+
+// entryContains reports whether the message, prefix, logger or fields of
+// entry contain the lowercase text
+func entryContains(entry pim.CoreLogEntry, text string) bool {
+	for _, s := range []string{entry.Message, entry.Prefix, entry.LoggerName} {
+		if strings.Contains(strings.ToLower(s), text) {
+			return true
+		}
+	}
+	if len(entry.Context) == 0 {
+		return false
+	}
+	data, err := json.Marshal(entry.Context)
+	return err == nil && strings.Contains(strings.ToLower(string(data)), text)
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write viewer response: %v\n", err)
+	}
+}
+
+// writeError writes err as a JSON error response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
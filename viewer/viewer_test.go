@@ -0,0 +1,195 @@
+package viewer
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/refactorroom/pim"
+)
+
+// writeLines writes a log file of lines to dir
+func writeLines(t *testing.T, dir, name string, lines ...string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// getJSON requests path from handler and decodes the response into v
+func getJSON(t *testing.T, handler http.Handler, path string, v interface{}) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("Failed to decode %s response %q: %v", path, rec.Body.String(), err)
+	}
+	return rec.Code
+}
+
+func TestIndex(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewHandler(t.TempDir()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("Expected HTML page, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), "api/entries") {
+		t.Error("Expected the page to use the entries endpoint")
+	}
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeLines(t, dir, "app.log", `{"level":3,"message":"new"}`)
+	writeLines(t, dir, "app.1.log", `{"level":3,"message":"old"}`)
+	writeLines(t, dir, ".hidden", "x")
+	os.Mkdir(filepath.Join(dir, "archive"), 0755)
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(dir, "app.1.log"), old, old)
+
+	var files []FileInfo
+	if code := getJSON(t, NewHandler(dir), "/api/files", &files); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if len(files) != 2 || files[0].Name != "app.log" || files[1].Name != "app.1.log" {
+		t.Errorf("Expected log files newest first, got %+v", files)
+	}
+}
+
+func TestEntriesFilters(t *testing.T) {
+	dir := t.TempDir()
+	writeLines(t, dir, "app.log",
+		`{"level":3,"level_string":"info","message":"server started"}`,
+		`{"level":4,"level_string":"debug","message":"cache miss","context":{"key":"users"}}`,
+		`{"level":1,"level_string":"error","message":"request failed","context":{"user":"alice"}}`,
+		`[2024-01-02 15:04:05.000] [WARNING] disk almost full`,
+		`{"level":3,"level_string":"info","message":"request handled","context":{"user":"Alice"}}`,
+	)
+	handler := NewHandler(dir)
+
+	tests := []struct {
+		query    string
+		messages []string
+	}{
+		{"", []string{"server started", "cache miss", "request failed", "disk almost full", "request handled"}},
+		{"&level=warn", []string{"request failed", "disk almost full"}},
+		{"&q=ALICE", []string{"request failed", "request handled"}},
+		{"&level=info&q=users", nil},
+		{"&limit=2", []string{"disk almost full", "request handled"}},
+	}
+	for _, tt := range tests {
+		var entries []pim.CoreLogEntry
+		if code := getJSON(t, handler, "/api/entries?file=app.log"+tt.query, &entries); code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", tt.query, code)
+		}
+		var messages []string
+		for _, entry := range entries {
+			messages = append(messages, entry.Message)
+		}
+		if strings.Join(messages, "|") != strings.Join(tt.messages, "|") {
+			t.Errorf("%s: expected %v, got %v", tt.query, tt.messages, messages)
+		}
+	}
+}
+
+func TestEntriesCompressed(t *testing.T) {
+	dir := t.TempDir()
+	file, err := os.Create(filepath.Join(dir, "app.1.log.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(file)
+	gz.Write([]byte(`{"level":3,"message":"rotated"}` + "\n"))
+	gz.Close()
+	file.Close()
+
+	var entries []pim.CoreLogEntry
+	getJSON(t, NewHandler(dir), "/api/entries?file=app.1.log.gz", &entries)
+	if len(entries) != 1 || entries[0].Message != "rotated" {
+		t.Errorf("Expected entry of compressed file, got %+v", entries)
+	}
+}
+
+func TestEntriesRejectsBadRequests(t *testing.T) {
+	dir := t.TempDir()
+	writeLines(t, dir, "app.log", `{"level":3,"message":"ok"}`)
+	handler := NewHandler(dir)
+
+	tests := []struct {
+		query  string
+		status int
+	}{
+		{"", http.StatusBadRequest},
+		{"file=../secret.log", http.StatusBadRequest},
+		{"file=.", http.StatusBadRequest},
+		{"file=missing.log", http.StatusNotFound},
+		{"file=app.log&level=loud", http.StatusBadRequest},
+		{"file=app.log&limit=0", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		var body map[string]string
+		if code := getJSON(t, handler, "/api/entries?"+tt.query, &body); code != tt.status || body["error"] == "" {
+			t.Errorf("%s: expected status %d with an error, got %d %v", tt.query, tt.status, code, body)
+		}
+	}
+}
+
+func TestTail(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	writeLines(t, dir, "app.log", `{"level":3,"message":"before tail"}`)
+
+	server := httptest.NewServer(NewHandler(dir))
+	defer server.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/tail?file=app.log&level=error", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected event stream, got %s", resp.Header.Get("Content-Type"))
+	}
+
+	// Keep appending until the tail has started and sends an event
+	go func() {
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return
+		}
+		defer file.Close()
+		for ctx.Err() == nil {
+			file.WriteString(`{"level":3,"message":"filtered"}` + "\n" + `{"level":1,"message":"tailed"}` + "\n")
+			time.Sleep(50 * time.Millisecond)
+		}
+	}()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var entry pim.CoreLogEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			t.Fatalf("Failed to decode event %q: %v", data, err)
+		}
+		if entry.Message != "tailed" {
+			t.Fatalf("Expected only entries matching the filter, got %q", entry.Message)
+		}
+		return
+	}
+	t.Fatalf("Expected a tailed entry, stream ended: %v", scanner.Err())
+}
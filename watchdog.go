@@ -0,0 +1,187 @@
+package pim
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultWatchdogPatterns are fatal conditions watched for by default
+var DefaultWatchdogPatterns = []string{
+	"out of memory",
+	"too many open files",
+	"no space left on device",
+}
+
+// WatchdogConfig configures the fatal condition watchdog
+type WatchdogConfig struct {
+	Patterns        []string                                 `json:"patterns"`         // Case-insensitive substrings (default: DefaultWatchdogPatterns)
+	FlushWriters    bool                                     `json:"flush_writers"`    // Flush and fsync all logger writers on trigger
+	DumpDiagnostics bool                                     `json:"dump_diagnostics"` // Write goroutine and memory diagnostics on trigger
+	DiagnosticsPath string                                   `json:"diagnostics_path"` // File for diagnostics (default: stderr)
+	Callback        func(entry CoreLogEntry, pattern string) `json:"-"`                // Called on trigger
+}
+
+// Watchdog watches log entries for fatal patterns and acts on the first
+// occurrence of each. It is a LogWriter so it observes entries after the
+// writers added before it have written them; add it as the last writer.
+type Watchdog struct {
+	config    WatchdogConfig
+	logger    *LoggerCore
+	patterns  []string
+	triggered map[string]bool
+	mu        sync.Mutex
+}
+
+// NewWatchdog creates a watchdog for the given logger
+func NewWatchdog(logger *LoggerCore, config WatchdogConfig) *Watchdog {
+	if len(config.Patterns) == 0 {
+		config.Patterns = DefaultWatchdogPatterns
+	}
+
+	patterns := make([]string, len(config.Patterns))
+	for i, pattern := range config.Patterns {
+		patterns[i] = strings.ToLower(pattern)
+	}
+
+	return &Watchdog{
+		config:    config,
+		logger:    logger,
+		patterns:  patterns,
+		triggered: make(map[string]bool),
+	}
+}
+
+// Write implements LogWriter interface
+func (w *Watchdog) Write(entry CoreLogEntry) error {
+	pattern, ok := w.match(entry)
+	if !ok {
+		return nil
+	}
+
+	w.mu.Lock()
+	if w.triggered[pattern] {
+		w.mu.Unlock()
+		return nil
+	}
+	w.triggered[pattern] = true
+	w.mu.Unlock()
+
+	return w.trigger(entry, pattern)
+}
+
+// match returns the first pattern found in the entry message or string context values
+func (w *Watchdog) match(entry CoreLogEntry) (string, bool) {
+	message := strings.ToLower(entry.Message)
+	for _, pattern := range w.patterns {
+		if strings.Contains(message, pattern) {
+			return pattern, true
+		}
+		for _, value := range entry.Context {
+			if s, ok := value.(string); ok && strings.Contains(strings.ToLower(s), pattern) {
+				return pattern, true
+			}
+			if err, ok := value.(error); ok && strings.Contains(strings.ToLower(err.Error()), pattern) {
+				return pattern, true
+			}
+		}
+	}
+	return "", false
+}
+
+// trigger runs the configured actions
+func (w *Watchdog) trigger(entry CoreLogEntry, pattern string) error {
+	var errors []error
+
+	if w.config.FlushWriters && w.logger != nil {
+		w.logger.mu.RLock()
+		writers := make([]LogWriter, 0, len(w.logger.writers))
+		for _, writer := range w.logger.writers {
+			if writer != LogWriter(w) {
+				writers = append(writers, writer)
+			}
+		}
+		w.logger.mu.RUnlock()
+
+		for _, writer := range writers {
+			if err := writer.Flush(); err != nil {
+				errors = append(errors, err)
+			}
+		}
+	}
+
+	if w.config.DumpDiagnostics {
+		if err := w.dumpDiagnostics(entry, pattern); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	if w.config.Callback != nil {
+		w.config.Callback(entry, pattern)
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("watchdog actions failed: %v", errors)
+	}
+	return nil
+}
+
+// dumpDiagnostics writes goroutine stacks and memory statistics
+func (w *Watchdog) dumpDiagnostics(entry CoreLogEntry, pattern string) error {
+	var out io.Writer = os.Stderr
+	if w.config.DiagnosticsPath != "" {
+		file, err := os.OpenFile(w.config.DiagnosticsPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open diagnostics file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	buf := make([]byte, 1<<20)
+	n := runtime.Stack(buf, true)
+
+	_, err := fmt.Fprintf(out,
+		"=== pim watchdog: %q detected at %s ===\nmessage: %s\ngoroutines: %d\nheap_alloc: %d\nheap_sys: %d\nnum_gc: %d\n\n%s\n",
+		pattern, time.Now().UTC().Format(time.RFC3339Nano), entry.Message,
+		runtime.NumGoroutine(), mem.HeapAlloc, mem.HeapSys, mem.NumGC, buf[:n])
+	return err
+}
+
+// Triggered reports whether the given pattern has fired
+func (w *Watchdog) Triggered(pattern string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.triggered[strings.ToLower(pattern)]
+}
+
+// Reset re-arms all patterns
+func (w *Watchdog) Reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.triggered = make(map[string]bool)
+}
+
+// Close implements LogWriter interface
+func (w *Watchdog) Close() error {
+	return nil
+}
+
+// Flush implements LogWriter interface
+func (w *Watchdog) Flush() error {
+	return nil
+}
+
+// AddWatchdog creates a watchdog for this logger and adds it as a writer
+func (l *LoggerCore) AddWatchdog(config WatchdogConfig) *Watchdog {
+	watchdog := NewWatchdog(l, config)
+	l.AddWriter(watchdog)
+	return watchdog
+}
@@ -0,0 +1,74 @@
+package pim
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type flushCountingWriter struct {
+	NullWriter
+	flushes int
+}
+
+func (w *flushCountingWriter) Flush() error {
+	w.flushes++
+	return nil
+}
+
+func TestWatchdogTriggersOnce(t *testing.T) {
+	logger := NewLoggerCore(LoggerConfig{Level: InfoLevel})
+	sink := &flushCountingWriter{}
+	logger.AddWriter(sink)
+
+	var fired []string
+	watchdog := logger.AddWatchdog(WatchdogConfig{
+		FlushWriters: true,
+		Callback: func(entry CoreLogEntry, pattern string) {
+			fired = append(fired, pattern)
+		},
+	})
+
+	logger.Info("request handled")
+	logger.Warning("accept failed: Too Many Open Files")
+	logger.Warning("accept failed: too many open files")
+	logger.InfoWithFields("write failed", map[string]interface{}{"error": "no space left on device"})
+
+	if len(fired) != 2 {
+		t.Fatalf("Expected 2 triggers, got %d: %v", len(fired), fired)
+	}
+	if sink.flushes != 2 {
+		t.Errorf("Expected writers to be flushed on each trigger, got %d flushes", sink.flushes)
+	}
+	if !watchdog.Triggered("too many open files") {
+		t.Error("Expected pattern to be marked as triggered")
+	}
+
+	watchdog.Reset()
+	logger.Warning("too many open files")
+	if len(fired) != 3 {
+		t.Errorf("Expected watchdog to re-arm after Reset, got %d triggers", len(fired))
+	}
+}
+
+func TestWatchdogDumpDiagnostics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "diag.txt")
+	watchdog := NewWatchdog(nil, WatchdogConfig{
+		Patterns:        []string{"fatal: out of memory"},
+		DumpDiagnostics: true,
+		DiagnosticsPath: path,
+	})
+
+	if err := watchdog.Write(CoreLogEntry{Message: "runtime: FATAL: out of memory"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected diagnostics file: %v", err)
+	}
+	if !strings.Contains(string(data), "goroutines:") || !strings.Contains(string(data), "goroutine ") {
+		t.Errorf("Expected goroutine diagnostics, got: %s", data)
+	}
+}
@@ -0,0 +1,165 @@
+package pim
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Wire format versions of RemoteWriter JSON batches
+const (
+	// WireFormatV1 is a bare JSON array of entries sent as application/json
+	WireFormatV1 = 1
+	// WireFormatV2 wraps entries in an envelope carrying the schema version
+	WireFormatV2 = 2
+
+	// CurrentWireVersion is the version senders use unless told otherwise
+	CurrentWireVersion = WireFormatV2
+)
+
+// BatchMediaType is the media type of versioned batches; the version is
+// carried as a parameter, e.g. "application/vnd.pim.batch+json; version=2"
+const BatchMediaType = "application/vnd.pim.batch+json"
+
+// SupportedWireVersions lists the versions this build can decode, newest first
+var SupportedWireVersions = []int{WireFormatV2, WireFormatV1}
+
+// AcceptedBatchFormats is sent in the Accept header of 415 responses so
+// senders can downgrade to a version the receiver understands
+var AcceptedBatchFormats = acceptedBatchFormats()
+
+// batchEnvelope is the WireFormatV2 payload
+type batchEnvelope struct {
+	Version int            `json:"version"`
+	SentAt  time.Time      `json:"sent_at"`
+	Count   int            `json:"count"`
+	Entries []CoreLogEntry `json:"entries"`
+}
+
+// acceptedBatchFormats builds the Accept header value for SupportedWireVersions
+func acceptedBatchFormats() string {
+	types := make([]string, len(SupportedWireVersions), len(SupportedWireVersions)+1)
+	for i, version := range SupportedWireVersions {
+		types[i] = WireContentType(version)
+	}
+	types = append(types, CBORContentType(CurrentWireVersion))
+	return strings.Join(types, ", ")
+}
+
+// WireContentType returns the Content-Type of a batch in the given version
+func WireContentType(version int) string {
+	if version <= WireFormatV1 {
+		return "application/json"
+	}
+	return fmt.Sprintf("%s; version=%d", BatchMediaType, version)
+}
+
+// EncodeBatch encodes entries in the given wire format version and returns
+// the payload with its Content-Type
+func EncodeBatch(version int, entries []CoreLogEntry) ([]byte, string, error) {
+	entries = batchWithErrorChains(entries)
+
+	var data []byte
+	var err error
+	if version <= WireFormatV1 {
+		data, err = json.Marshal(entries)
+	} else {
+		data, err = json.Marshal(batchEnvelope{
+			Version: version,
+			SentAt:  time.Now().UTC(),
+			Count:   len(entries),
+			Entries: entries,
+		})
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal batch: %w", err)
+	}
+	return data, WireContentType(version), nil
+}
+
+// batchWithErrorChains returns entries with wrapped errors encoded as
+// ErrorChains, copying the batch only if needed
+func batchWithErrorChains(entries []CoreLogEntry) []CoreLogEntry {
+	for i := range entries {
+		if len(causeChainKeys(entries[i].Context)) == 0 {
+			continue
+		}
+		converted := make([]CoreLogEntry, len(entries))
+		for j := range entries {
+			converted[j] = withErrorChains(entries[j])
+		}
+		return converted
+	}
+	return entries
+}
+
+// wireVersion returns the wire format version of a Content-Type
+func wireVersion(contentType string) (int, error) {
+	if contentType == "" {
+		return WireFormatV1, nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return 0, fmt.Errorf("invalid content type %q: %w", contentType, err)
+	}
+
+	switch mediaType {
+	case "application/json":
+		return WireFormatV1, nil
+	case BatchMediaType, BatchCBORMediaType:
+		version, err := strconv.Atoi(params["version"])
+		if err != nil {
+			return 0, fmt.Errorf("%w: missing or invalid version in %q", ErrUnsupportedBatchFormat, contentType)
+		}
+		for _, supported := range SupportedWireVersions {
+			if version == supported {
+				return version, nil
+			}
+		}
+		return 0, fmt.Errorf("%w: wire format version %d", ErrUnsupportedBatchFormat, version)
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrUnsupportedBatchFormat, mediaType)
+	}
+}
+
+// decodeVersionedBatch decodes a batch body in the given wire format version
+func decodeVersionedBatch(version int, body io.Reader) ([]CoreLogEntry, error) {
+	if version == WireFormatV1 {
+		var entries []CoreLogEntry
+		if err := json.NewDecoder(body).Decode(&entries); err != nil {
+			return nil, fmt.Errorf("failed to decode batch: %w", err)
+		}
+		return entries, nil
+	}
+
+	var envelope batchEnvelope
+	if err := json.NewDecoder(body).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode batch: %w", err)
+	}
+	if envelope.Version != version {
+		return nil, fmt.Errorf("batch version %d does not match content type version %d", envelope.Version, version)
+	}
+	return envelope.Entries, nil
+}
+
+// NegotiateWireVersion picks the newest supported version from an Accept
+// header sent by a receiver. Receivers that predate versioning send no
+// Accept header and only understand WireFormatV1.
+func NegotiateWireVersion(accept string) int {
+	best := 0
+	for _, part := range strings.Split(accept, ",") {
+		version, err := wireVersion(strings.TrimSpace(part))
+		if err == nil && version > best {
+			best = version
+		}
+	}
+	if best == 0 {
+		return WireFormatV1
+	}
+	return best
+}
@@ -0,0 +1,102 @@
+package pim
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWireFormatRoundTrip(t *testing.T) {
+	entries := []CoreLogEntry{{Level: InfoLevel, Message: "hello"}}
+
+	for _, version := range SupportedWireVersions {
+		data, contentType, err := EncodeBatch(version, entries)
+		if err != nil {
+			t.Fatalf("Failed to encode v%d: %v", version, err)
+		}
+		decoded, err := DecodeBatch(contentType, bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Failed to decode v%d: %v", version, err)
+		}
+		if len(decoded) != 1 || decoded[0].Message != "hello" {
+			t.Errorf("Unexpected entries for v%d: %+v", version, decoded)
+		}
+	}
+
+	_, err := DecodeBatch(BatchMediaType+"; version=99", strings.NewReader(`{}`))
+	if !errors.Is(err, ErrUnsupportedBatchFormat) {
+		t.Errorf("Expected unknown version to be unsupported, got: %v", err)
+	}
+}
+
+func TestNegotiateWireVersion(t *testing.T) {
+	tests := map[string]int{
+		"":                   WireFormatV1,
+		"application/json":   WireFormatV1,
+		AcceptedBatchFormats: CurrentWireVersion,
+		BatchMediaType + "; version=99, application/json": WireFormatV1,
+	}
+	for accept, expected := range tests {
+		if got := NegotiateWireVersion(accept); got != expected {
+			t.Errorf("NegotiateWireVersion(%q) = %d, expected %d", accept, got, expected)
+		}
+	}
+}
+
+func TestRemoteWriterDowngradesForOldReceiver(t *testing.T) {
+	var received []CoreLogEntry
+	// A receiver from before versioning: plain JSON only, no Accept header on 415
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != "application/json" {
+			http.Error(w, "unsupported", http.StatusUnsupportedMediaType)
+			return
+		}
+		entries, err := DecodeBatch("application/json", r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		received = append(received, entries...)
+	}))
+	defer server.Close()
+
+	remote := NewRemoteWriter(LoggerConfig{EnableJSON: true}, RemoteWriterConfig{
+		Endpoint:   server.URL,
+		BatchDelay: time.Hour,
+	})
+	defer remote.Close()
+
+	remote.Write(CoreLogEntry{Level: InfoLevel, Message: "rolling upgrade"})
+	if err := remote.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+
+	if remote.WireVersion() != WireFormatV1 {
+		t.Errorf("Expected writer to downgrade to v1, got v%d", remote.WireVersion())
+	}
+	if len(received) != 1 || received[0].Message != "rolling upgrade" {
+		t.Errorf("Expected batch to be delivered after downgrade, got: %+v", received)
+	}
+}
+
+func TestRelayAdvertisesAcceptedFormats(t *testing.T) {
+	relay := NewRelayServer("127.0.0.1:0")
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", BatchMediaType+"; version=99")
+	rec := httptest.NewRecorder()
+	relay.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("Expected 415, got %d", rec.Code)
+	}
+	if rec.Header().Get("Accept") != AcceptedBatchFormats {
+		t.Errorf("Expected Accept header %q, got %q", AcceptedBatchFormats, rec.Header().Get("Accept"))
+	}
+}
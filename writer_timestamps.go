@@ -0,0 +1,74 @@
+package pim
+
+import "time"
+
+// WrittenAtKey is the context key for the time an entry was written
+const WrittenAtKey = "written_at"
+
+// TimestampMode selects the timestamp a writer records for an entry
+type TimestampMode string
+
+const (
+	TimestampOriginal  TimestampMode = "original"   // When the entry was logged (default)
+	TimestampWriteTime TimestampMode = "write_time" // When the writer writes the entry
+)
+
+// WriterTimestampConfig configures the timestamps of a wrapped writer
+type WriterTimestampConfig struct {
+	// Mode selects the entry timestamp. With TimestampWriteTime the logged
+	// time is kept in the OriginalTimestampKey context field.
+	Mode TimestampMode `json:"mode"`
+
+	// AddWrittenAt adds the write time as the WrittenAtKey context field,
+	// e.g. so backfilled entries keep their occurrence time as the
+	// timestamp and record when they were shipped
+	AddWrittenAt bool `json:"add_written_at"`
+
+	Now func() time.Time `json:"-"` // Write clock (default: time.Now)
+}
+
+// timestampWriter applies a WriterTimestampConfig before writing
+type timestampWriter struct {
+	LogWriter
+	config WriterTimestampConfig
+}
+
+// WithTimestamps wraps writer so it records entries with the timestamps
+// selected by config. Entries keep the time they were logged by default,
+// which is what replay and backfill tools need for downstream systems to
+// index events at their true occurrence time; TimestampWriteTime stamps them
+// at write time instead.
+func WithTimestamps(writer LogWriter, config WriterTimestampConfig) LogWriter {
+	if config.Mode == "" {
+		config.Mode = TimestampOriginal
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &timestampWriter{LogWriter: writer, config: config}
+}
+
+// Write implements LogWriter interface
+func (w *timestampWriter) Write(entry CoreLogEntry) error {
+	if w.config.Mode == TimestampOriginal && !w.config.AddWrittenAt {
+		return w.LogWriter.Write(entry)
+	}
+
+	// Other writers share the context, so change a copy
+	entry = copyEntry(entry)
+	if entry.Context == nil {
+		entry.Context = make(map[string]interface{})
+	}
+
+	now := w.config.Now().UTC()
+	if w.config.Mode == TimestampWriteTime {
+		if !entry.Timestamp.IsZero() {
+			entry.Context[OriginalTimestampKey] = entry.Timestamp.UTC().Format(time.RFC3339Nano)
+		}
+		entry.Timestamp = now
+	}
+	if w.config.AddWrittenAt {
+		entry.Context[WrittenAtKey] = now.Format(time.RFC3339Nano)
+	}
+	return w.LogWriter.Write(entry)
+}
@@ -0,0 +1,34 @@
+package pim
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithTimestamps(t *testing.T) {
+	logged := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	written := logged.Add(time.Hour)
+	now := func() time.Time { return written }
+	entry := CoreLogEntry{Timestamp: logged, Message: "backfilled", Context: map[string]interface{}{"user": "u1"}}
+
+	original := NewBufferWriter(LoggerConfig{}, 1)
+	WithTimestamps(original, WriterTimestampConfig{AddWrittenAt: true, Now: now}).Write(entry)
+	got := original.GetBuffer()[0]
+	if !got.Timestamp.Equal(logged) || got.Context[WrittenAtKey] != "2024-01-02T04:04:05Z" {
+		t.Errorf("Expected the original timestamp and written_at, got %v %v", got.Timestamp, got.Context)
+	}
+
+	stamped := NewBufferWriter(LoggerConfig{}, 1)
+	WithTimestamps(stamped, WriterTimestampConfig{Mode: TimestampWriteTime, Now: now}).Write(entry)
+	got = stamped.GetBuffer()[0]
+	if !got.Timestamp.Equal(written) || got.Context[OriginalTimestampKey] != "2024-01-02T03:04:05Z" {
+		t.Errorf("Expected the write time with the original kept, got %v %v", got.Timestamp, got.Context)
+	}
+	if _, ok := got.Context[WrittenAtKey]; ok {
+		t.Error("Expected no written_at unless requested")
+	}
+
+	if len(entry.Context) != 1 {
+		t.Errorf("Expected the shared context left intact, got %v", entry.Context)
+	}
+}
@@ -1,9 +1,9 @@
 package pim
 
 import (
+	"bufio"
 	"bytes"
 	"compress/gzip"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,6 +13,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/fatih/color"
 )
 
 // RotationConfig configures log file rotation
@@ -24,153 +26,139 @@ type RotationConfig struct {
 	RotateTime      time.Duration `json:"rotate_time"`      // Time-based rotation interval
 	CleanupInterval time.Duration `json:"cleanup_interval"` // How often to run cleanup (default: 1 hour)
 	VerboseCleanup  bool          `json:"verbose_cleanup"`  // Whether to log cleanup operations
+	CloseTimeout    time.Duration `json:"close_timeout"`    // How long Close waits for compression (default: 30 seconds)
 }
 
+// compressTempSuffix marks a compressed file that is still being written
+const compressTempSuffix = ".tmp"
+
 // ConsoleWriter writes log entries to the console
 type ConsoleWriter struct {
 	config       LoggerConfig
+	encoder      Encoder
 	themeManager *ThemeManager
+	out          io.Writer  // Destination; nil writes to the current os.Stdout
+	mu           sync.Mutex // Serializes writes to out
+	colorJSON    bool       // Color JSON lines by level, see LoggerConfig.StrictJSON
+	plain        bool       // Strip colors, see ConsoleModePlain
 }
 
-// NewConsoleWriter creates a new console writer
+// NewConsoleWriter creates a new console writer. Entries are formatted with
+// config.Encoder if set, as JSON with EnableJSON, with the theme format
+// FormatName if set, or as plain text otherwise, adjusted to the
+// ConsoleMode of config for os.Stdout.
 func NewConsoleWriter(config LoggerConfig) *ConsoleWriter {
-	writer := &ConsoleWriter{
-		config:       config,
-		themeManager: NewThemeManager(),
-	}
-
-	// Initialize theme manager
-	if config.CustomTheme != nil {
-		writer.themeManager.currentTheme = config.CustomTheme
-	} else if config.ThemeName != "" {
-		writer.themeManager.SetTheme(config.ThemeName)
-	}
-
-	// Register custom format if provided
-	if config.CustomFormat != "" {
-		writer.themeManager.RegisterTemplate("custom", config.CustomFormat)
-	}
-
-	return writer
+	return newConsoleWriter(nil, config)
 }
 
-// Write implements LogWriter interface for console output
-func (w *ConsoleWriter) Write(entry CoreLogEntry) error {
-	if w.config.EnableJSON {
-		return w.writeJSON(entry)
-	}
-
-	// Use theming if available
-	if w.themeManager != nil && w.config.FormatName != "" {
-		formatted := w.themeManager.Format(entry, w.config.FormatName)
-		fmt.Println(formatted)
-		return nil
-	}
-
-	return w.writeFormatted(entry)
+// NewConsoleWriterTo creates a console writer writing to out instead of
+// os.Stdout, e.g. a bytes.Buffer in tests
+func NewConsoleWriterTo(out io.Writer, config LoggerConfig) *ConsoleWriter {
+	return newConsoleWriter(out, config)
 }
 
-// writeFormatted writes a formatted log entry to console
-func (w *ConsoleWriter) writeFormatted(entry CoreLogEntry) error {
-	var parts []string
-
-	// Add prefix
-	if entry.Prefix != "" {
-		parts = append(parts, entry.Prefix)
-	}
-
-	// Add timestamp
-	timestamp := entry.Timestamp.Format(w.config.TimestampFormat)
-	parts = append(parts, fmt.Sprintf("[%s]", timestamp))
-
-	// Add file/line info
-	if entry.File != "" {
-		fileInfo := entry.File
-		if entry.Function != "" {
-			if w.config.ShowPackageName && entry.Package != "" {
-				fileInfo += fmt.Sprintf(":%s.%s", entry.Package, entry.Function)
-			} else {
-				fileInfo += fmt.Sprintf(":%s", entry.Function)
-			}
-		}
-		fileInfo += fmt.Sprintf(":L%d", entry.Line)
-		parts = append(parts, fmt.Sprintf("[%s]", fileInfo))
+// newConsoleWriter creates a console writer writing to out, or os.Stdout if nil
+func newConsoleWriter(out io.Writer, config LoggerConfig) *ConsoleWriter {
+	detectOn := out
+	if detectOn == nil {
+		detectOn = os.Stdout
 	}
+	config, fields, plain := consoleConfig(config, detectOn, ConsoleTextFields)
 
-	// Add goroutine ID
-	if entry.GoroutineID != "" {
-		parts = append(parts, entry.GoroutineID)
+	template := NewTemplateEncoder(config)
+	writer := &ConsoleWriter{
+		config:       config,
+		encoder:      encoderFor(config, fields),
+		themeManager: template.ThemeManager(),
+		out:          out,
+		plain:        plain,
 	}
-
-	// Add message
-	parts = append(parts, entry.Message)
-
-	// Add context if present
-	if len(entry.Context) > 0 {
-		contextStr := w.formatContext(entry.Context)
-		parts = append(parts, fmt.Sprintf("{%s}", contextStr))
+	if config.Encoder == nil && !config.EnableJSON && config.FormatName != "" {
+		writer.encoder = template
 	}
-
-	// Join and print
-	logLine := strings.Join(parts, " ")
-	fmt.Println(logLine)
-
-	// Print stack trace if present
-	if len(entry.StackTrace) > 0 {
-		stackStr := w.formatStackTrace(entry.StackTrace)
-		if stackStr != "" {
-			fmt.Println(stackStr)
-		}
-	}
-
-	return nil
+	writer.colorJSON = config.Encoder == nil && config.EnableJSON && config.EnableColors && !config.StrictJSON
+	return writer
 }
 
-// writeJSON writes a JSON log entry to console
-func (w *ConsoleWriter) writeJSON(entry CoreLogEntry) error {
-	jsonData, err := json.Marshal(entry)
-	if err != nil {
-		return fmt.Errorf("failed to marshal log entry: %w", err)
+// jsonLineColor returns the ANSI color of a JSON console line at level:
+// dim for debug and trace, yellow for warnings and red for errors
+func jsonLineColor(level LogLevel) string {
+	switch {
+	case level <= PanicLevel:
+		return "\033[1;31m" // Bold red
+	case level == ErrorLevel:
+		return ColorRed
+	case level == WarningLevel:
+		return ColorYellow
+	case level >= DebugLevel:
+		return "\033[2m" // Dim
 	}
-
-	fmt.Println(string(jsonData))
-	return nil
+	return ""
 }
 
-// formatContext formats context fields for console output
-func (w *ConsoleWriter) formatContext(context map[string]interface{}) string {
-	var pairs []string
-	for k, v := range context {
-		pairs = append(pairs, fmt.Sprintf("%s=%v", k, v))
-	}
-	return strings.Join(pairs, ", ")
+// appendEncoder is implemented by encoders that can encode into a caller's
+// buffer, see TextEncoder.AppendEntry
+type appendEncoder interface {
+	AppendEntry(dst []byte, entry CoreLogEntry) ([]byte, error)
 }
 
-// formatStackTrace formats stack trace for console output
-func (w *ConsoleWriter) formatStackTrace(frames []StackFrame) string {
-	if len(frames) == 0 {
-		return ""
-	}
+// maxPooledBufferSize is the largest buffer returned to consoleBufferPool,
+// so one huge entry doesn't pin its memory
+const maxPooledBufferSize = 64 << 10
 
-	var lines []string
-	for i, frame := range frames {
-		indent := strings.Repeat("  ", i)
-		parts := []string{frame.File}
+// consoleBufferPool holds the buffers entries are encoded into
+var consoleBufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 0, 512)
+		return &buf
+	},
+}
 
-		if w.config.ShowFunctionName && frame.Function != "" {
-			if w.config.ShowPackageName && frame.Package != "" {
-				parts = append(parts, fmt.Sprintf("%s.%s", frame.Package, frame.Function))
-			} else {
-				parts = append(parts, frame.Function)
-			}
+// Write implements LogWriter interface for console output. The entry is
+// encoded into a pooled buffer and written with a single write call.
+func (w *ConsoleWriter) Write(entry CoreLogEntry) error {
+	bufPtr := consoleBufferPool.Get().(*[]byte)
+	defer func() {
+		if cap(*bufPtr) <= maxPooledBufferSize {
+			consoleBufferPool.Put(bufPtr)
 		}
-		parts = append(parts, fmt.Sprintf("L%d", frame.Line))
-
-		line := fmt.Sprintf("%s↳ %s", indent, strings.Join(parts, ":"))
-		lines = append(lines, line)
+	}()
+
+	buf := (*bufPtr)[:0]
+	lineColor := ""
+	if w.colorJSON && !color.NoColor {
+		lineColor = jsonLineColor(entry.Level)
+	}
+	buf = append(buf, lineColor...)
+	if encoder, ok := w.encoder.(appendEncoder); ok {
+		var err error
+		if buf, err = encoder.AppendEntry(buf, entry); err != nil {
+			return err
+		}
+	} else {
+		data, err := w.encoder.EncodeEntry(entry)
+		if err != nil {
+			return err
+		}
+		buf = append(buf, data...)
+	}
+	if w.plain {
+		buf = appendPlain(buf[:0], buf) // In place, the output is never longer
 	}
+	if lineColor != "" {
+		buf = append(buf, ColorReset...)
+	}
+	buf = append(buf, '\n')
+	*bufPtr = buf
 
-	return strings.Join(lines, "\n")
+	out := w.out
+	if out == nil {
+		out = os.Stdout // Resolved per write so redirecting os.Stdout takes effect
+	}
+	w.mu.Lock()
+	_, err := out.Write(buf)
+	w.mu.Unlock()
+	return err
 }
 
 // Close implements LogWriter interface
@@ -188,26 +176,47 @@ func (w *ConsoleWriter) Flush() error {
 type FileWriter struct {
 	file           *os.File
 	config         LoggerConfig
+	encoder        Encoder
 	rotationConfig RotationConfig
 	filePath       string
 	fileSize       int64
 	lastRotate     time.Time
 	mu             sync.Mutex
+	compressing    sync.WaitGroup // Tracks in-flight compression workers
+
+	// Buffered mode, see NewBufferedFileWriter
+	buf          *bufio.Writer
+	bufferConfig FileBufferConfig
+	stopFlush    chan struct{}
+	flushDone    sync.WaitGroup
+
+	cipher *fileCipher // Encrypts entries at rest, see NewEncryptedFileWriter
+	signer *fileSigner // Appends signatures, see NewSignedFileWriter
+
+	readOnlyRotated bool // Rotated files are made read-only, see NewWORMFileWriter
 }
 
-// NewFileWriter creates a new file writer with rotation
+// NewFileWriter creates a new file writer with rotation. Tokens such as
+// {hostname} and {pid} in filename are expanded, see ExpandFileName;
+// rotated files are named and cleaned up after the expanded name.
 func NewFileWriter(filename string, config LoggerConfig, rotationConfig RotationConfig) (*FileWriter, error) {
 	writer := &FileWriter{
 		config:         config,
+		encoder:        encoderFor(config, FileTextFields),
 		rotationConfig: rotationConfig,
-		filePath:       filename,
-		lastRotate:     time.Now(),
+		filePath:       ExpandFileName(filename, config),
+		lastRotate:     config.now(),
 	}
 
 	if err := writer.openFile(); err != nil {
 		return nil, err
 	}
 
+	// Finish compression interrupted by a previous shutdown or crash
+	if rotationConfig.Compress {
+		writer.recoverCompression()
+	}
+
 	// Start cleanup goroutine if max age or max files is set
 	if rotationConfig.MaxAge > 0 || rotationConfig.MaxFiles > 0 {
 		go writer.cleanupOldFiles()
@@ -220,7 +229,11 @@ func NewFileWriter(filename string, config LoggerConfig, rotationConfig Rotation
 func (w *FileWriter) openFile() error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
+	return w.openFileLocked()
+}
 
+// openFileLocked opens the log file; the caller must hold w.mu
+func (w *FileWriter) openFileLocked() error {
 	// Close existing file if open
 	if w.file != nil {
 		w.file.Close()
@@ -239,6 +252,12 @@ func (w *FileWriter) openFile() error {
 	}
 
 	w.file = file
+	if w.buf != nil {
+		w.buf.Reset(file)
+	}
+	if w.cipher != nil {
+		w.cipher.reset() // Each file starts with a header
+	}
 
 	// Get current file size
 	if stat, err := file.Stat(); err == nil {
@@ -256,7 +275,7 @@ func (w *FileWriter) shouldRotate() bool {
 	}
 
 	// Time-based rotation
-	if w.rotationConfig.RotateTime > 0 && time.Since(w.lastRotate) >= w.rotationConfig.RotateTime {
+	if w.rotationConfig.RotateTime > 0 && w.config.now().Sub(w.lastRotate) >= w.rotationConfig.RotateTime {
 		return true
 	}
 
@@ -272,11 +291,20 @@ func (w *FileWriter) rotateFile() error {
 		return nil
 	}
 
-	// Close current file
+	if err := w.sealLocked(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to seal log file before rotation: %v\n", err)
+	}
+
+	// Write out buffered entries, then close current file
+	if w.buf != nil {
+		if err := w.buf.Flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to flush log file before rotation: %v\n", err)
+		}
+	}
 	w.file.Close()
 
 	// Generate rotated filename with timestamp
-	timestamp := time.Now().Format("2006-01-02_15-04-05")
+	timestamp := w.config.now().Format(rotationTimeFormat)
 	ext := filepath.Ext(w.filePath)
 	base := strings.TrimSuffix(w.filePath, ext)
 	rotatedPath := fmt.Sprintf("%s.%s%s", base, timestamp, ext)
@@ -286,23 +314,42 @@ func (w *FileWriter) rotateFile() error {
 		return fmt.Errorf("failed to rotate log file: %w", err)
 	}
 
+	if w.readOnlyRotated {
+		makeReadOnly(rotatedPath)
+	}
+
 	// Compress if enabled
 	if w.rotationConfig.Compress {
-		go w.compressFile(rotatedPath)
+		w.startCompression(rotatedPath)
 	}
 
 	// Open new file
-	if err := w.openFile(); err != nil {
+	if err := w.openFileLocked(); err != nil {
 		return err
 	}
 
-	w.lastRotate = time.Now()
+	w.lastRotate = w.config.now()
 	w.fileSize = 0
+	if w.signer != nil {
+		w.signer.reset()
+	}
 
 	return nil
 }
 
-// compressFile compresses a log file using gzip
+// startCompression compresses a rotated file in a worker that Close waits for
+func (w *FileWriter) startCompression(filePath string) {
+	w.compressing.Add(1)
+	go func() {
+		defer w.compressing.Done()
+		w.compressFile(filePath)
+	}()
+}
+
+// compressFile compresses a log file using gzip. The output is written to a
+// temporary file and renamed into place once complete, and the original is
+// only removed afterwards, so an interrupted compression never leaves a
+// truncated .gz behind and never loses the original.
 func (w *FileWriter) compressFile(filePath string) {
 	// Open the original file
 	file, err := os.Open(filePath)
@@ -312,25 +359,34 @@ func (w *FileWriter) compressFile(filePath string) {
 	}
 	defer file.Close()
 
-	// Create the compressed file
+	// Create the temporary compressed file
 	compressedPath := filePath + ".gz"
-	compressedFile, err := os.Create(compressedPath)
+	tempPath := compressedPath + compressTempSuffix
+	compressedFile, err := os.Create(tempPath)
 	if err != nil {
-		fmt.Printf("Failed to create compressed file %s: %v\n", compressedPath, err)
+		fmt.Printf("Failed to create compressed file %s: %v\n", tempPath, err)
 		return
 	}
-	defer compressedFile.Close()
-
-	// Create gzip writer
-	gzipWriter := gzip.NewWriter(compressedFile)
-	defer gzipWriter.Close()
 
 	// Copy data from original to compressed file
+	gzipWriter := gzip.NewWriter(compressedFile)
 	_, err = io.Copy(gzipWriter, file)
+	if err == nil {
+		err = gzipWriter.Close()
+	}
+	if err == nil {
+		err = compressedFile.Sync()
+	}
+	if closeErr := compressedFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempPath, compressedPath)
+	}
 	if err != nil {
 		fmt.Printf("Failed to compress file %s: %v\n", filePath, err)
 		// Clean up the partial compressed file
-		os.Remove(compressedPath)
+		os.Remove(tempPath)
 		return
 	}
 
@@ -340,6 +396,65 @@ func (w *FileWriter) compressFile(filePath string) {
 	}
 }
 
+// rotationTimeFormat is the timestamp rotateFile inserts before the
+// extension of rotated files
+const rotationTimeFormat = "2006-01-02_15-04-05"
+
+// rotationTimeGlob matches the timestamps of rotationTimeFormat
+const rotationTimeGlob = "[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]_[0-9][0-9]-[0-9][0-9]-[0-9][0-9]"
+
+// quoteGlob escapes the glob metacharacters of s as one-character classes,
+// which unlike backslashes work on Windows too
+func quoteGlob(s string) string {
+	return strings.NewReplacer("*", "[*]", "?", "[?]", "[", "[[]").Replace(s)
+}
+
+// recoverCompression cleans up after compression workers that did not finish:
+// partial temporary files are removed and rotated files that still have no
+// complete .gz are compressed again. Only names with a rotation timestamp
+// match, so other files sharing the base name, such as app.audit.log next
+// to app.log, are left alone.
+func (w *FileWriter) recoverCompression() {
+	dir := filepath.Dir(w.filePath)
+	base := strings.TrimSuffix(filepath.Base(w.filePath), filepath.Ext(w.filePath))
+	ext := filepath.Ext(w.filePath)
+	rotatedGlob := filepath.Join(dir, quoteGlob(base)+"."+rotationTimeGlob+quoteGlob(ext))
+
+	partials, _ := filepath.Glob(rotatedGlob + ".gz" + compressTempSuffix)
+	for _, partial := range partials {
+		os.Remove(partial)
+	}
+
+	rotated, _ := filepath.Glob(rotatedGlob)
+	for _, path := range rotated {
+		// The original is removed only after the .gz is complete, so a
+		// remaining .gz next to its original is a leftover to be redone
+		os.Remove(path + ".gz")
+		w.startCompression(path)
+	}
+}
+
+// waitForCompression waits up to the close timeout for compression workers
+func (w *FileWriter) waitForCompression() error {
+	timeout := w.rotationConfig.CloseTimeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	done := make(chan struct{})
+	go func() {
+		w.compressing.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("timed out after %v waiting for log compression", timeout)
+	}
+}
+
 // cleanupOldFiles removes old log files based on age and count
 func (w *FileWriter) cleanupOldFiles() {
 	interval := w.rotationConfig.CleanupInterval
@@ -394,7 +509,7 @@ func (w *FileWriter) cleanupFiles() {
 
 	// Remove files based on age
 	if w.rotationConfig.MaxAge > 0 {
-		cutoff := time.Now().Add(-w.rotationConfig.MaxAge)
+		cutoff := w.config.now().Add(-w.rotationConfig.MaxAge)
 		for _, file := range files {
 			if file.modTime.Before(cutoff) {
 				if err := os.Remove(file.path); err != nil {
@@ -439,140 +554,119 @@ func (w *FileWriter) Write(entry CoreLogEntry) error {
 		}
 	}
 
+	// Encode before taking the lock so concurrent writers only serialize on I/O
+	data, err := w.encode(entry)
+	if err != nil {
+		return err
+	}
+
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
 	if w.file == nil {
 		return fmt.Errorf("log file is not open")
 	}
+	if w.cipher != nil {
+		if data, err = w.cipher.seal(data); err != nil {
+			return fmt.Errorf("failed to encrypt log entry: %w", err)
+		}
+	}
 
-	var data []byte
-	var err error
-
-	if w.config.EnableJSON {
-		data, err = json.Marshal(entry)
-		if err != nil {
-			return fmt.Errorf("failed to marshal log entry: %w", err)
+	if err := w.writeLocked(data); err != nil {
+		return err
+	}
+	if w.signer != nil {
+		if signature := w.signer.add(data); signature != nil {
+			return w.writeLocked(signature)
 		}
-		data = append(data, '\n')
-	} else {
-		data = []byte(w.formatLogEntry(entry) + "\n")
 	}
+	return nil
+}
 
-	// Write data
-	n, err := w.file.Write(data)
+// writeLocked writes data, through the buffer in buffered mode; the caller
+// must hold w.mu
+func (w *FileWriter) writeLocked(data []byte) error {
+	var n int
+	var err error
+	if w.buf != nil {
+		n, err = w.buf.Write(data)
+	} else {
+		n, err = w.file.Write(data)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to write to log file: %w", err)
 	}
 
 	// Update file size
 	w.fileSize += int64(n)
-
 	return nil
 }
 
-// formatLogEntry formats a log entry for text output
-func (w *FileWriter) formatLogEntry(entry CoreLogEntry) string {
-	var parts []string
-
-	// Add timestamp
-	timestamp := entry.Timestamp.Format(w.config.TimestampFormat)
-	parts = append(parts, fmt.Sprintf("[%s]", timestamp))
-
-	// Add level
-	parts = append(parts, fmt.Sprintf("[%s]", strings.ToUpper(entry.LevelString)))
-
-	// Add service name
-	if entry.ServiceName != "" {
-		parts = append(parts, fmt.Sprintf("[%s]", entry.ServiceName))
-	}
-
-	// Add file/line info
-	if entry.File != "" {
-		fileInfo := entry.File
-		if entry.Function != "" {
-			if w.config.ShowPackageName && entry.Package != "" {
-				fileInfo += fmt.Sprintf(":%s.%s", entry.Package, entry.Function)
-			} else {
-				fileInfo += fmt.Sprintf(":%s", entry.Function)
-			}
-		}
-		fileInfo += fmt.Sprintf(":L%d", entry.Line)
-		parts = append(parts, fmt.Sprintf("[%s]", fileInfo))
-	}
-
-	// Add goroutine ID
-	if entry.GoroutineID != "" {
-		parts = append(parts, entry.GoroutineID)
-	}
-
-	// Add message
-	parts = append(parts, entry.Message)
-
-	// Add context if present
-	if len(entry.Context) > 0 {
-		contextStr := w.formatContext(entry.Context)
-		parts = append(parts, fmt.Sprintf("{%s}", contextStr))
+// sealLocked appends the signature sealing the current file; the caller
+// must hold w.mu
+func (w *FileWriter) sealLocked() error {
+	if w.signer == nil || w.file == nil {
+		return nil
 	}
-
-	return strings.Join(parts, " ")
+	return w.writeLocked(w.signer.sign(true))
 }
 
-// formatContext formats context fields for file output
-func (w *FileWriter) formatContext(context map[string]interface{}) string {
-	var pairs []string
-	for k, v := range context {
-		pairs = append(pairs, fmt.Sprintf("%s=%v", k, v))
+// encode serializes an entry in the configured output format
+func (w *FileWriter) encode(entry CoreLogEntry) ([]byte, error) {
+	if w.config.EnableCBOR {
+		// Entries are written back to back as a CBOR sequence
+		return EncodeEntryCBOR(entry)
 	}
-	return strings.Join(pairs, ", ")
-}
-
-// formatStackTrace formats stack trace for file output
-func (w *FileWriter) formatStackTrace(frames []StackFrame) string {
-	if len(frames) == 0 {
-		return ""
+	data, err := w.encoder.EncodeEntry(entry)
+	if err != nil {
+		return nil, err
 	}
+	return append(data, '\n'), nil
+}
 
-	var lines []string
-	for i, frame := range frames {
-		indent := strings.Repeat("  ", i)
-		parts := []string{frame.File}
+// Close implements LogWriter interface. It waits for in-flight compression
+// of rotated files; anything still unfinished is recovered on next startup.
+func (w *FileWriter) Close() error {
+	w.stopFlusher()
 
-		if w.config.ShowFunctionName && frame.Function != "" {
-			if w.config.ShowPackageName && frame.Package != "" {
-				parts = append(parts, fmt.Sprintf("%s.%s", frame.Package, frame.Function))
-			} else {
-				parts = append(parts, frame.Function)
+	w.mu.Lock()
+	err := w.sealLocked()
+	if w.file != nil {
+		if w.buf != nil {
+			if flushErr := w.buf.Flush(); err == nil {
+				err = flushErr
 			}
 		}
-		parts = append(parts, fmt.Sprintf("L%d", frame.Line))
-
-		line := fmt.Sprintf("%s↳ %s", indent, strings.Join(parts, ":"))
-		lines = append(lines, line)
+		if closeErr := w.file.Close(); err == nil {
+			err = closeErr
+		}
 	}
+	w.mu.Unlock()
 
-	return strings.Join(lines, "\n")
-}
-
-// Close implements LogWriter interface
-func (w *FileWriter) Close() error {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-
-	if w.file != nil {
-		return w.file.Close()
+	if waitErr := w.waitForCompression(); err == nil {
+		err = waitErr
 	}
-	return nil
+	return err
 }
 
 // Flush implements LogWriter interface
 func (w *FileWriter) Flush() error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
-	if w.file != nil {
-		return w.file.Sync()
+	if w.file == nil {
+		return nil
 	}
-	return nil
+	if w.signer != nil && w.signer.pending() {
+		if err := w.writeLocked(w.signer.sign(false)); err != nil {
+			return err
+		}
+	}
+	if w.buf != nil {
+		if err := w.buf.Flush(); err != nil {
+			return fmt.Errorf("failed to write to log file: %w", err)
+		}
+	}
+	return w.file.Sync()
 }
 
 // MultiWriter writes to multiple writers
@@ -637,125 +731,33 @@ func (w *MultiWriter) Flush() error {
 
 // StderrWriter writes log entries to stderr
 type StderrWriter struct {
-	config LoggerConfig
+	config  LoggerConfig
+	encoder Encoder
+	plain   bool // Strip colors, see ConsoleModePlain
 }
 
-// NewStderrWriter creates a new stderr writer
+// NewStderrWriter creates a new stderr writer, adjusted to the ConsoleMode
+// of config for os.Stderr
 func NewStderrWriter(config LoggerConfig) *StderrWriter {
+	config, fields, plain := consoleConfig(config, os.Stderr, StderrTextFields)
 	return &StderrWriter{
-		config: config,
+		config:  config,
+		encoder: encoderFor(config, fields),
+		plain:   plain,
 	}
 }
 
 // Write implements LogWriter interface for stderr output
 func (w *StderrWriter) Write(entry CoreLogEntry) error {
-	if w.config.EnableJSON {
-		return w.writeJSON(entry)
-	}
-	return w.writeFormatted(entry)
-}
-
-// writeFormatted writes a formatted log entry to stderr
-func (w *StderrWriter) writeFormatted(entry CoreLogEntry) error {
-	var parts []string
-
-	// Add prefix
-	if entry.Prefix != "" {
-		parts = append(parts, entry.Prefix)
-	}
-
-	// Add timestamp
-	timestamp := entry.Timestamp.Format(w.config.TimestampFormat)
-	parts = append(parts, fmt.Sprintf("[%s]", timestamp))
-
-	// Add file/line info
-	if entry.File != "" {
-		fileInfo := entry.File
-		if entry.Function != "" {
-			if w.config.ShowPackageName && entry.Package != "" {
-				fileInfo += fmt.Sprintf(":%s.%s", entry.Package, entry.Function)
-			} else {
-				fileInfo += fmt.Sprintf(":%s", entry.Function)
-			}
-		}
-		fileInfo += fmt.Sprintf(":L%d", entry.Line)
-		parts = append(parts, fmt.Sprintf("[%s]", fileInfo))
-	}
-
-	// Add goroutine ID
-	if entry.GoroutineID != "" {
-		parts = append(parts, entry.GoroutineID)
-	}
-
-	// Add message
-	parts = append(parts, entry.Message)
-
-	// Add context if present
-	if len(entry.Context) > 0 {
-		contextStr := w.formatContext(entry.Context)
-		parts = append(parts, fmt.Sprintf("{%s}", contextStr))
-	}
-
-	// Join and print to stderr
-	logLine := strings.Join(parts, " ")
-	fmt.Fprintln(os.Stderr, logLine)
-
-	// Print stack trace if present
-	if len(entry.StackTrace) > 0 {
-		stackStr := w.formatStackTrace(entry.StackTrace)
-		if stackStr != "" {
-			fmt.Fprintln(os.Stderr, stackStr)
-		}
-	}
-
-	return nil
-}
-
-// writeJSON writes a JSON log entry to stderr
-func (w *StderrWriter) writeJSON(entry CoreLogEntry) error {
-	jsonData, err := json.Marshal(entry)
+	data, err := w.encoder.EncodeEntry(entry)
 	if err != nil {
-		return fmt.Errorf("failed to marshal log entry: %w", err)
-	}
-
-	fmt.Fprintln(os.Stderr, string(jsonData))
-	return nil
-}
-
-// formatContext formats context fields for stderr output
-func (w *StderrWriter) formatContext(context map[string]interface{}) string {
-	var pairs []string
-	for k, v := range context {
-		pairs = append(pairs, fmt.Sprintf("%s=%v", k, v))
-	}
-	return strings.Join(pairs, ", ")
-}
-
-// formatStackTrace formats stack trace for stderr output
-func (w *StderrWriter) formatStackTrace(frames []StackFrame) string {
-	if len(frames) == 0 {
-		return ""
+		return err
 	}
-
-	var lines []string
-	for i, frame := range frames {
-		indent := strings.Repeat("  ", i)
-		parts := []string{frame.File}
-
-		if w.config.ShowFunctionName && frame.Function != "" {
-			if w.config.ShowPackageName && frame.Package != "" {
-				parts = append(parts, fmt.Sprintf("%s.%s", frame.Package, frame.Function))
-			} else {
-				parts = append(parts, frame.Function)
-			}
-		}
-		parts = append(parts, fmt.Sprintf("L%d", frame.Line))
-
-		line := fmt.Sprintf("%s↳ %s", indent, strings.Join(parts, ":"))
-		lines = append(lines, line)
+	if w.plain {
+		data = appendPlain(nil, data)
 	}
-
-	return strings.Join(lines, "\n")
+	fmt.Fprintln(os.Stderr, string(data))
+	return nil
 }
 
 // Close implements LogWriter interface
@@ -816,7 +818,7 @@ func (w *BufferWriter) GetBuffer() []CoreLogEntry {
 func (w *BufferWriter) ClearBuffer() {
 	w.mu.Lock()
 	defer w.mu.Unlock()
-	w.buffer = w.buffer[:0]
+	w.buffer = make([]CoreLogEntry, 0, w.maxSize) // Iterators may still hold the old one
 }
 
 // GetBufferSize returns the current buffer size
@@ -843,12 +845,17 @@ func (w *BufferWriter) Flush() error {
 // RemoteWriter writes log entries to a remote HTTP endpoint
 type RemoteWriter struct {
 	config     LoggerConfig
+	encoder    Encoder // Encodes text batches
 	client     *http.Client
 	endpoint   string
 	headers    map[string]string
 	batchSize  int
 	batchDelay time.Duration
 	buffer     []CoreLogEntry
+	keyring    *BatchKeyring
+	wireVer    int
+	cbor       bool // Sending CBOR batches; cleared if the receiver rejects them
+	deadLetter *DeadLetterQueue
 	mu         sync.Mutex
 	stopCh     chan struct{}
 }
@@ -862,6 +869,13 @@ type RemoteWriterConfig struct {
 	BatchDelay    time.Duration     `json:"batch_delay"`    // Delay between batches
 	RetryAttempts int               `json:"retry_attempts"` // Number of retry attempts
 	RetryDelay    time.Duration     `json:"retry_delay"`    // Delay between retries
+	Keyring       *BatchKeyring     `json:"-"`              // Encrypts batches end-to-end when set
+	WireVersion   int               `json:"wire_version"`   // Batch format version (default: CurrentWireVersion, downgraded on 415)
+
+	// DeadLetter receives batches that still fail after all retries, which
+	// are then dropped from the buffer. Without it they stay buffered and
+	// are resent with the next batch.
+	DeadLetter *DeadLetterQueue `json:"-"`
 }
 
 // NewRemoteWriter creates a new remote writer
@@ -881,15 +895,28 @@ func NewRemoteWriter(config LoggerConfig, remoteConfig RemoteWriterConfig) *Remo
 	if remoteConfig.RetryDelay == 0 {
 		remoteConfig.RetryDelay = 1 * time.Second
 	}
+	if remoteConfig.WireVersion == 0 {
+		remoteConfig.WireVersion = CurrentWireVersion
+	}
+
+	encoder := config.Encoder
+	if encoder == nil {
+		encoder = NewTextEncoder(config, RemoteTextFields)
+	}
 
 	writer := &RemoteWriter{
 		config:     config,
+		encoder:    encoder,
 		client:     &http.Client{Timeout: remoteConfig.Timeout},
 		endpoint:   remoteConfig.Endpoint,
 		headers:    remoteConfig.Headers,
 		batchSize:  remoteConfig.BatchSize,
 		batchDelay: remoteConfig.BatchDelay,
 		buffer:     make([]CoreLogEntry, 0, remoteConfig.BatchSize),
+		keyring:    remoteConfig.Keyring,
+		wireVer:    remoteConfig.WireVersion,
+		cbor:       config.EnableCBOR,
+		deadLetter: remoteConfig.DeadLetter,
 		stopCh:     make(chan struct{}),
 	}
 
@@ -939,51 +966,32 @@ func (w *RemoteWriter) batchProcessor() {
 	}
 }
 
-// sendBatch sends the current batch to the remote endpoint
+// sendBatch sends the current batch to the remote endpoint, handing it to
+// the dead-letter queue if that fails
 func (w *RemoteWriter) sendBatch() error {
-	if len(w.buffer) == 0 {
-		return nil
-	}
-
-	// Prepare batch data
-	var data []byte
-	var err error
-
-	if w.config.EnableJSON {
-		data, err = json.Marshal(w.buffer)
-	} else {
-		// Convert to text format
-		var lines []string
-		for _, entry := range w.buffer {
-			lines = append(lines, w.formatLogEntry(entry))
-		}
-		data = []byte(strings.Join(lines, "\n") + "\n")
-	}
-
-	if err != nil {
-		return fmt.Errorf("failed to marshal batch: %w", err)
-	}
-
-	// Create request
-	req, err := http.NewRequest("POST", w.endpoint, bytes.NewBuffer(data))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	if w.config.EnableJSON {
-		req.Header.Set("Content-Type", "application/json")
-	} else {
-		req.Header.Set("Content-Type", "text/plain")
+	err := w.trySendBatch()
+	if err != nil && w.deadLetter != nil {
+		failed := make([]CoreLogEntry, len(w.buffer))
+		copy(failed, w.buffer)
+		w.buffer = w.buffer[:0]
+		w.deadLetter.Add(writerTypeName(w), failed, err)
 	}
+	return err
+}
 
-	for k, v := range w.headers {
-		req.Header.Set(k, v)
+// trySendBatch sends the current batch to the remote endpoint with retries
+func (w *RemoteWriter) trySendBatch() error {
+	if len(w.buffer) == 0 {
+		return nil
 	}
 
 	// Send request with retries
 	for attempt := 0; attempt < 3; attempt++ {
+		req, err := w.newBatchRequest()
+		if err != nil {
+			return err
+		}
+
 		resp, err := w.client.Do(req)
 		if err != nil {
 			if attempt < 2 {
@@ -998,6 +1006,20 @@ func (w *RemoteWriter) sendBatch() error {
 			break
 		}
 
+		// Downgrade to a wire format the receiver accepts and resend right away
+		if resp.StatusCode == http.StatusUnsupportedMediaType && w.cbor && !acceptsCBOR(resp.Header.Get("Accept")) {
+			w.cbor = false
+			attempt--
+			continue
+		}
+		if resp.StatusCode == http.StatusUnsupportedMediaType && (w.config.EnableJSON || w.config.EnableCBOR) {
+			if version := NegotiateWireVersion(resp.Header.Get("Accept")); version < w.wireVer {
+				w.wireVer = version
+				attempt--
+				continue
+			}
+		}
+
 		if attempt < 2 {
 			time.Sleep(time.Duration(attempt+1) * time.Second)
 			continue
@@ -1011,41 +1033,69 @@ func (w *RemoteWriter) sendBatch() error {
 	return nil
 }
 
-// formatLogEntry formats a log entry for remote text output
-func (w *RemoteWriter) formatLogEntry(entry CoreLogEntry) string {
-	var parts []string
-
-	// Add timestamp
-	timestamp := entry.Timestamp.Format(w.config.TimestampFormat)
-	parts = append(parts, fmt.Sprintf("[%s]", timestamp))
+// newBatchRequest encodes the current batch into a new request
+func (w *RemoteWriter) newBatchRequest() (*http.Request, error) {
+	// Prepare batch data
+	var data []byte
+	var contentType string
+	var err error
 
-	// Add level
-	parts = append(parts, fmt.Sprintf("[%s]", strings.ToUpper(entry.LevelString)))
+	if w.cbor {
+		data, contentType, err = EncodeCBORBatch(w.wireVer, w.buffer)
+		if err != nil {
+			return nil, err
+		}
+	} else if w.config.EnableJSON || w.config.EnableCBOR {
+		data, contentType, err = EncodeBatch(w.wireVer, w.buffer)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// One encoded entry per line
+		var buf bytes.Buffer
+		for _, entry := range w.buffer {
+			line, err := w.encoder.EncodeEntry(entry)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+		data = buf.Bytes()
+		contentType = "text/plain"
+	}
 
-	// Add service name
-	if entry.ServiceName != "" {
-		parts = append(parts, fmt.Sprintf("[%s]", entry.ServiceName))
+	// Encrypt payload if a keyring is configured
+	if w.keyring != nil {
+		data, err = w.keyring.Encrypt(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt batch: %w", err)
+		}
 	}
 
-	// Add message
-	parts = append(parts, entry.Message)
+	// Create request
+	req, err := http.NewRequest("POST", w.endpoint, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
 
-	// Add context if present
-	if len(entry.Context) > 0 {
-		contextStr := w.formatContext(entry.Context)
-		parts = append(parts, fmt.Sprintf("{%s}", contextStr))
+	// Set headers
+	req.Header.Set("Content-Type", contentType)
+	if w.keyring != nil {
+		req.Header.Set("Content-Encoding", BatchEncryptionEncoding)
 	}
 
-	return strings.Join(parts, " ")
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+	return req, nil
 }
 
-// formatContext formats context fields for remote output
-func (w *RemoteWriter) formatContext(context map[string]interface{}) string {
-	var pairs []string
-	for k, v := range context {
-		pairs = append(pairs, fmt.Sprintf("%s=%v", k, v))
-	}
-	return strings.Join(pairs, ", ")
+// WireVersion returns the JSON batch format version currently in use
+func (w *RemoteWriter) WireVersion() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.wireVer
 }
 
 // Close implements LogWriter interface
@@ -1066,22 +1116,31 @@ func (w *RemoteWriter) Flush() error {
 
 // SyslogWriter writes log entries to syslog (Unix systems only)
 type SyslogWriter struct {
-	config LoggerConfig
-	tag    string
+	config  LoggerConfig
+	encoder Encoder
+	tag     string
 }
 
 // NewSyslogWriter creates a new syslog writer
 func NewSyslogWriter(config LoggerConfig, tag string) *SyslogWriter {
+	encoder := config.Encoder
+	if encoder == nil {
+		encoder = NewTextEncoder(config, SyslogTextFields)
+	}
 	return &SyslogWriter{
-		config: config,
-		tag:    tag,
+		config:  config,
+		encoder: encoder,
+		tag:     tag,
 	}
 }
 
 // Write implements LogWriter interface for syslog output
 func (w *SyslogWriter) Write(entry CoreLogEntry) error {
 	// Format message
-	message := w.formatSyslogMessage(entry)
+	message, err := w.encoder.EncodeEntry(entry)
+	if err != nil {
+		return err
+	}
 
 	// On Windows, just print to stderr with syslog format
 	// On Unix systems, this would use the actual syslog
@@ -1090,36 +1149,6 @@ func (w *SyslogWriter) Write(entry CoreLogEntry) error {
 	return nil
 }
 
-// formatSyslogMessage formats a log entry for syslog
-func (w *SyslogWriter) formatSyslogMessage(entry CoreLogEntry) string {
-	var parts []string
-
-	// Add service name if available
-	if entry.ServiceName != "" {
-		parts = append(parts, fmt.Sprintf("[%s]", entry.ServiceName))
-	}
-
-	// Add message
-	parts = append(parts, entry.Message)
-
-	// Add context if present
-	if len(entry.Context) > 0 {
-		contextStr := w.formatContext(entry.Context)
-		parts = append(parts, fmt.Sprintf("{%s}", contextStr))
-	}
-
-	return strings.Join(parts, " ")
-}
-
-// formatContext formats context fields for syslog output
-func (w *SyslogWriter) formatContext(context map[string]interface{}) string {
-	var pairs []string
-	for k, v := range context {
-		pairs = append(pairs, fmt.Sprintf("%s=%v", k, v))
-	}
-	return strings.Join(pairs, ", ")
-}
-
 // Close implements LogWriter interface
 func (w *SyslogWriter) Close() error {
 	// Syslog writer doesn't need to close anything
@@ -1187,43 +1216,3 @@ func (w *ConditionalWriter) Close() error {
 func (w *ConditionalWriter) Flush() error {
 	return w.writer.Flush()
 }
-
-// RateLimitedWriter limits the rate of log writes
-type RateLimitedWriter struct {
-	writer      LogWriter
-	rateLimiter *time.Ticker
-	lastWrite   time.Time
-	minInterval time.Duration
-}
-
-// NewRateLimitedWriter creates a new rate-limited writer
-func NewRateLimitedWriter(writer LogWriter, maxPerSecond int) *RateLimitedWriter {
-	interval := time.Second / time.Duration(maxPerSecond)
-	return &RateLimitedWriter{
-		writer:      writer,
-		rateLimiter: time.NewTicker(interval),
-		minInterval: interval,
-	}
-}
-
-// Write implements LogWriter interface for rate-limited output
-func (w *RateLimitedWriter) Write(entry CoreLogEntry) error {
-	// Check if enough time has passed since last write
-	if time.Since(w.lastWrite) < w.minInterval {
-		<-w.rateLimiter.C
-	}
-
-	w.lastWrite = time.Now()
-	return w.writer.Write(entry)
-}
-
-// Close implements LogWriter interface
-func (w *RateLimitedWriter) Close() error {
-	w.rateLimiter.Stop()
-	return w.writer.Close()
-}
-
-// Flush implements LogWriter interface
-func (w *RateLimitedWriter) Flush() error {
-	return w.writer.Flush()
-}
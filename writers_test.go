@@ -1,11 +1,17 @@
 package pim
 
 import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/fatih/color"
 )
 
 const (
@@ -91,7 +97,7 @@ func TestConsoleWriterWriteJSON(t *testing.T) {
 }
 
 func TestConsoleWriterFormatContext(t *testing.T) {
-	writer := NewConsoleWriter(LoggerConfig{})
+	encoder := NewTextEncoder(LoggerConfig{}, ConsoleTextFields)
 
 	context := map[string]interface{}{
 		"string_key": "string_value",
@@ -100,7 +106,7 @@ func TestConsoleWriterFormatContext(t *testing.T) {
 		"float_key":  3.14,
 	}
 
-	result := writer.formatContext(context)
+	result := encoder.formatContext(context)
 
 	if result == "" {
 		t.Error("Expected formatContext to return non-empty string")
@@ -116,7 +122,7 @@ func TestConsoleWriterFormatContext(t *testing.T) {
 }
 
 func TestConsoleWriterFormatStackTrace(t *testing.T) {
-	writer := NewConsoleWriter(LoggerConfig{})
+	encoder := NewTextEncoder(LoggerConfig{}, ConsoleTextFields)
 
 	frames := []StackFrame{
 		{
@@ -133,7 +139,7 @@ func TestConsoleWriterFormatStackTrace(t *testing.T) {
 		},
 	}
 
-	result := writer.formatStackTrace(frames)
+	result := encoder.formatStackTrace(frames)
 
 	if result == "" {
 		t.Error("Expected formatStackTrace to return non-empty string")
@@ -285,7 +291,8 @@ func TestFileWriterFormatLogEntry(t *testing.T) {
 		},
 	}
 
-	result := writer.formatLogEntry(entry)
+	data, _ := writer.encoder.EncodeEntry(entry)
+	result := string(data)
 
 	if result == "" {
 		t.Error("Expected formatLogEntry to return non-empty string")
@@ -473,19 +480,235 @@ func TestFileWriterClose(t *testing.T) {
 	}
 }
 
-func BenchmarkConsoleWriterWrite(b *testing.B) {
-	writer := NewConsoleWriter(LoggerConfig{Level: InfoLevel})
+func TestFileWriterCloseWaitsForCompression(t *testing.T) {
+	tempDir := t.TempDir()
+	filename := filepath.Join(tempDir, "test-compress.log")
+
+	writer, err := NewFileWriter(filename, LoggerConfig{}, RotationConfig{MaxSize: 1, Compress: true})
+	if err != nil {
+		t.Fatalf(failedToCreateFileWriter, err)
+	}
+
+	entry := CoreLogEntry{Timestamp: time.Now(), Level: InfoLevel, Message: "Test compress message"}
+	writer.Write(entry)
+	// The second write rotates the first file and compresses it in the background
+	if err := writer.Write(entry); err != nil {
+		t.Fatalf("Expected Write with rotation to succeed, got error: %v", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Expected Close to succeed, got error: %v", err)
+	}
+
+	compressed, _ := filepath.Glob(filepath.Join(tempDir, "test-compress.*.log.gz"))
+	uncompressed, _ := filepath.Glob(filepath.Join(tempDir, "test-compress.*.log"))
+	partial, _ := filepath.Glob(filepath.Join(tempDir, "*.tmp"))
+	if len(compressed) != 1 || len(uncompressed) != 0 || len(partial) != 0 {
+		t.Errorf("Expected one finished .gz after Close, got gz=%v log=%v tmp=%v", compressed, uncompressed, partial)
+	}
+}
+
+func TestFileWriterRecoversInterruptedCompression(t *testing.T) {
+	tempDir := t.TempDir()
+	filename := filepath.Join(tempDir, "test-recover.log")
+	rotated := filepath.Join(tempDir, "test-recover.2024-01-01_00-00-00.log")
+
+	// Simulate a crash mid-compression: the original, a truncated .gz and a temp file
+	os.WriteFile(rotated, []byte("rotated entry\n"), 0644)
+	os.WriteFile(rotated+".gz", []byte("truncated"), 0644)
+	os.WriteFile(rotated+".gz.tmp", []byte("partial"), 0644)
+
+	writer, err := NewFileWriter(filename, LoggerConfig{}, RotationConfig{Compress: true})
+	if err != nil {
+		t.Fatalf(failedToCreateFileWriter, err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Expected Close to succeed, got error: %v", err)
+	}
+
+	if _, err := os.Stat(rotated); !os.IsNotExist(err) {
+		t.Error("Expected the rotated file to be compressed and removed")
+	}
+	if _, err := os.Stat(rotated + ".gz.tmp"); !os.IsNotExist(err) {
+		t.Error("Expected the partial temp file to be removed")
+	}
+
+	file, err := os.Open(rotated + ".gz")
+	if err != nil {
+		t.Fatalf("Expected a recompressed file: %v", err)
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("Expected a valid gzip file: %v", err)
+	}
+	content, _ := io.ReadAll(reader)
+	if string(content) != "rotated entry\n" {
+		t.Errorf("Expected recompressed content, got %q", content)
+	}
+}
+
+func TestFileWriterRecoveryLeavesOtherFilesAlone(t *testing.T) {
+	tempDir := t.TempDir()
+	filename := filepath.Join(tempDir, "app.log")
+	other := filepath.Join(tempDir, "app.audit.log")
+	os.WriteFile(other, []byte("audit entry\n"), 0644)
+
+	writer, err := NewFileWriter(filename, LoggerConfig{}, RotationConfig{Compress: true})
+	if err != nil {
+		t.Fatalf(failedToCreateFileWriter, err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Expected Close to succeed, got error: %v", err)
+	}
+
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("Expected a file sharing the base name to be kept: %v", err)
+	}
+	if _, err := os.Stat(other + ".gz"); !os.IsNotExist(err) {
+		t.Error("Expected a file sharing the base name not to be compressed")
+	}
+}
+
+func TestConsoleWriterTo(t *testing.T) {
+	config := LoggerConfig{Level: InfoLevel, TimestampFormat: "15:04:05"}
+	var out bytes.Buffer
+	writer := NewConsoleWriterTo(&out, config)
+
 	entry := CoreLogEntry{
-		Timestamp:   time.Now(),
-		Level:       InfoLevel,
-		Message:     "Benchmark message",
-		ServiceName: "benchmark",
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Message:   "hello",
+		File:      "main.go",
+		Function:  "main",
+		Line:      7,
+		Context:   map[string]interface{}{"n": 1},
+	}
+	if err := writer.Write(entry); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := writer.Write(CoreLogEntry{Timestamp: entry.Timestamp, Message: "bye"}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	expected := "[03:04:05] [main.go:main:L7] hello {n=1}\n[03:04:05] bye\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestConsoleWriterLargeEntry(t *testing.T) {
+	var out bytes.Buffer
+	writer := NewConsoleWriterTo(&out, LoggerConfig{Level: InfoLevel})
+	large := strings.Repeat("x", 2*maxPooledBufferSize)
+
+	writer.Write(CoreLogEntry{Message: large})
+	writer.Write(CoreLogEntry{Message: "small"})
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], large) || !strings.HasSuffix(lines[1], " small") {
+		t.Errorf("Expected both entries on their own lines, got %d lines", len(lines))
+	}
+}
+
+func TestConsoleWriterColorsJSONByLevel(t *testing.T) {
+	originalNoColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = originalNoColor }()
+
+	config := LoggerConfig{Level: TraceLevel, EnableJSON: true, EnableColors: true}
+	var out bytes.Buffer
+	writer := NewConsoleWriterTo(&out, config)
+	writer.Write(CoreLogEntry{Level: ErrorLevel, Message: "failed"})
+	writer.Write(CoreLogEntry{Level: InfoLevel, Message: "started"})
+	writer.Write(CoreLogEntry{Level: DebugLevel, Message: "details"})
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %q", out.String())
+	}
+	if !strings.HasPrefix(lines[0], ColorRed+"{") || !strings.HasSuffix(lines[0], "}"+ColorReset) {
+		t.Errorf("Expected a red error line, got %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "{") {
+		t.Errorf("Expected an uncolored info line, got %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "\033[2m{") {
+		t.Errorf("Expected a dim debug line, got %q", lines[2])
+	}
+
+	config.StrictJSON = true
+	out.Reset()
+	NewConsoleWriterTo(&out, config).Write(CoreLogEntry{Level: ErrorLevel, Message: "failed"})
+	if !strings.HasPrefix(out.String(), "{") {
+		t.Errorf("Expected plain JSON with StrictJSON, got %q", out.String())
+	}
+
+	color.NoColor = true
+	config.StrictJSON = false
+	out.Reset()
+	NewConsoleWriterTo(&out, config).Write(CoreLogEntry{Level: ErrorLevel, Message: "failed"})
+	if !strings.HasPrefix(out.String(), "{") {
+		t.Errorf("Expected plain JSON without a color terminal, got %q", out.String())
 	}
+}
+
+// benchmarkConsoleEntry is a typical entry for the console writer benchmarks
+var benchmarkConsoleEntry = CoreLogEntry{
+	Timestamp:   time.Now(),
+	Level:       InfoLevel,
+	LevelString: "info",
+	Message:     "Benchmark message",
+	ServiceName: "benchmark",
+	File:        "handler.go",
+	Function:    "ServeHTTP",
+	Line:        42,
+	Context:     map[string]interface{}{"user_id": 12345, "path": "/api/orders"},
+}
 
+func BenchmarkConsoleWriterWrite(b *testing.B) {
+	writer := NewConsoleWriterTo(io.Discard, LoggerConfig{Level: InfoLevel, TimestampFormat: "2006-01-02 15:04:05"})
+
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		writer.Write(entry)
+		writer.Write(benchmarkConsoleEntry)
+	}
+}
+
+// BenchmarkConsoleWriterWriteLegacy measures the previous output path,
+// joining string slices and printing each entry with fmt.Println, as a
+// baseline for BenchmarkConsoleWriterWrite
+func BenchmarkConsoleWriterWriteLegacy(b *testing.B) {
+	config := LoggerConfig{Level: InfoLevel, TimestampFormat: "2006-01-02 15:04:05"}
+	encoder := NewTextEncoder(config, ConsoleTextFields)
+	legacyFormat := func(entry CoreLogEntry) string {
+		parts := []string{
+			fmt.Sprintf("[%s]", encoder.timestamps.Format(entry.Timestamp)),
+			fmt.Sprintf("[%s]", entry.File+fmt.Sprintf(":%s", entry.Function)+fmt.Sprintf(":L%d", entry.Line)),
+			entry.Message,
+		}
+		if contextStr := encoder.formatContext(entry.Context); contextStr != "" {
+			parts = append(parts, fmt.Sprintf("{%s}", contextStr))
+		}
+		return strings.Join([]string{strings.Join(parts, " ")}, "\n")
 	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data := []byte(legacyFormat(benchmarkConsoleEntry))
+		fmt.Fprintln(io.Discard, string(data))
+	}
+}
+
+func BenchmarkConsoleWriterWriteParallel(b *testing.B) {
+	writer := NewConsoleWriterTo(io.Discard, LoggerConfig{Level: InfoLevel, TimestampFormat: "2006-01-02 15:04:05"})
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			writer.Write(benchmarkConsoleEntry)
+		}
+	})
 }
 
 func BenchmarkFileWriterWrite(b *testing.B) {